@@ -0,0 +1,147 @@
+// Package chaos injects faults (store latency, Redis errors, dropped SSE
+// messages) so operators can exercise alerting-on-alerting and client retries
+// in staging. Faults are off until set through the admin API and always expire.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultDuration = 10 * time.Minute
+	MaxDuration     = time.Hour
+	MaxLatency      = 30 * time.Second
+)
+
+// ErrInjected is returned by Redis commands failed on purpose
+var ErrInjected = errors.New("chaos: injected redis failure")
+
+// Faults is the active fault configuration
+type Faults struct {
+	StoreLatencyMS int       `json:"store_latency_ms"` // Added to every Redis command
+	RedisErrorRate float64   `json:"redis_error_rate"` // 0-1 share of Redis commands that fail
+	SSEDropRate    float64   `json:"sse_drop_rate"`    // 0-1 share of live SSE messages dropped
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// Validate checks rates and latency are in range
+func (f Faults) Validate() error {
+	if f.StoreLatencyMS < 0 || time.Duration(f.StoreLatencyMS)*time.Millisecond > MaxLatency {
+		return fmt.Errorf("store_latency_ms must be between 0 and %d", MaxLatency.Milliseconds())
+	}
+	if f.RedisErrorRate < 0 || f.RedisErrorRate > 1 {
+		return errors.New("redis_error_rate must be between 0 and 1")
+	}
+	if f.SSEDropRate < 0 || f.SSEDropRate > 1 {
+		return errors.New("sse_drop_rate must be between 0 and 1")
+	}
+	return nil
+}
+
+type Injector struct {
+	mu     sync.RWMutex
+	faults Faults
+}
+
+func NewInjector() *Injector {
+	return &Injector{}
+}
+
+// Set replaces the active faults; they switch off by themselves after d
+func (i *Injector) Set(f Faults, d time.Duration) Faults {
+	f.ExpiresAt = time.Now().Add(d).UTC()
+	i.mu.Lock()
+	i.faults = f
+	i.mu.Unlock()
+	return f
+}
+
+// Clear switches all faults off
+func (i *Injector) Clear() {
+	i.mu.Lock()
+	i.faults = Faults{}
+	i.mu.Unlock()
+}
+
+// Current returns the active faults, if any
+func (i *Injector) Current() (Faults, bool) {
+	if i == nil {
+		return Faults{}, false
+	}
+	i.mu.RLock()
+	f := i.faults
+	i.mu.RUnlock()
+	if f.ExpiresAt.IsZero() || time.Now().After(f.ExpiresAt) {
+		return Faults{}, false
+	}
+	return f, true
+}
+
+// DropSSE reports whether to drop the next live SSE message
+func (i *Injector) DropSSE() bool {
+	f, ok := i.Current()
+	return ok && f.SSEDropRate > 0 && rand.Float64() < f.SSEDropRate
+}
+
+// before applies latency and decides whether the command fails
+func (i *Injector) before(ctx context.Context) error {
+	f, ok := i.Current()
+	if !ok {
+		return nil
+	}
+	if f.StoreLatencyMS > 0 {
+		select {
+		case <-time.After(time.Duration(f.StoreLatencyMS) * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if f.RedisErrorRate > 0 && rand.Float64() < f.RedisErrorRate {
+		return ErrInjected
+	}
+	return nil
+}
+
+// RedisHook returns a go-redis hook that applies the faults to every command
+// and pipeline
+func (i *Injector) RedisHook() redis.Hook {
+	return redisHook{i}
+}
+
+type redisHook struct{ i *Injector }
+
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.i.before(ctx); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := h.i.before(ctx); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
@@ -0,0 +1,38 @@
+// Package clientinfo carries who sent an HTTP request, the client's address
+// and user agent, to the code that records what the request did: the audit
+// trail, several calls away from the handler.
+package clientinfo
+
+import (
+	"context"
+	"unicode/utf8"
+)
+
+// maxUserAgent caps a user agent as the client sent it; longer ones are cut
+const maxUserAgent = 512
+
+// Info is the request's sender
+type Info struct {
+	IP        string // After trusted proxies, as handlers.IPAllowlist.ClientIP sees it
+	UserAgent string
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying info, with the user agent cut to 512 bytes
+func NewContext(ctx context.Context, info Info) context.Context {
+	if len(info.UserAgent) > maxUserAgent {
+		ua := info.UserAgent[:maxUserAgent]
+		for !utf8.ValidString(ua) {
+			ua = ua[:len(ua)-1]
+		}
+		info.UserAgent = ua
+	}
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext is the Info ctx carries; it is empty outside a request
+func FromContext(ctx context.Context) Info {
+	info, _ := ctx.Value(contextKey{}).(Info)
+	return info
+}
@@ -0,0 +1,186 @@
+// Package depcheck probes the external dependencies registered for a service
+// when one of its alerts opens, and attaches the results (HTTP status and
+// latency of each) to the alert as a dependency snapshot annotation.
+package depcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	// DefaultTimeout applies to checks without their own timeout
+	DefaultTimeout = 5 * time.Second
+
+	// MaxTimeout caps a check's timeout, so a snapshot is ready while the
+	// alert is still being triaged
+	MaxTimeout = 30 * time.Second
+
+	// annotationAuthor marks the snapshot annotation
+	annotationAuthor = "dependency-check"
+
+	maxInFlight = 8 // Alerts being snapshotted at once
+	userAgent   = "Sentinel-DependencyCheck/1"
+)
+
+type Prober struct {
+	alerts store.AlertStore
+	admin  store.AdminStore
+	http   *http.Client
+	slots  chan struct{}
+}
+
+func NewProber(alerts store.AlertStore, admin store.AdminStore) *Prober {
+	return &Prober{
+		alerts: alerts,
+		admin:  admin,
+		http: &http.Client{
+			// Report redirects as they are rather than probing somewhere else
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		slots: make(chan struct{}, maxInFlight),
+	}
+}
+
+// Run snapshots the dependencies of newly opened alerts until ch is closed
+func (p *Prober) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		if a.Status == models.AlertStatusResolved || a.Level == models.LevelSuccess {
+			continue
+		}
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		go func() {
+			defer func() { <-p.slots }()
+			if err := p.Snapshot(ctx, a); err != nil {
+				log.Printf("dependency snapshot failed for alert %d: %v", a.ID, err)
+			}
+		}()
+	}
+}
+
+// Snapshot probes the checks matching a and annotates it with the results,
+// once per alert across all instances
+func (p *Prober) Snapshot(ctx context.Context, a models.Alert) error {
+	for _, an := range a.Annotations {
+		if an.Author == annotationAuthor {
+			return nil
+		}
+	}
+
+	checks, err := p.admin.GetDependencyChecks(ctx)
+	if err != nil {
+		return err
+	}
+	var matching []models.DependencyCheck
+	for _, d := range checks {
+		if d.Matches(a) {
+			matching = append(matching, d)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
+	// Repeats of the alert are published too; only the first gets a snapshot
+	first, err := p.alerts.ClaimNonce(ctx, fmt.Sprintf("depcheck:%d", a.ID), 24*time.Hour)
+	if err != nil || !first {
+		return err
+	}
+
+	results := p.ProbeAll(ctx, matching)
+	_, err = p.alerts.AddAnnotation(ctx, a.ID, annotation(results))
+	return err
+}
+
+// ProbeAll probes checks concurrently and returns the results by name
+func (p *Prober) ProbeAll(ctx context.Context, checks []models.DependencyCheck) []models.DependencyResult {
+	results := make([]models.DependencyResult, len(checks))
+	var wg sync.WaitGroup
+	for i, d := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = p.Probe(ctx, d)
+		}()
+	}
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+// Probe GETs a check's URL once
+func (p *Prober) Probe(ctx context.Context, d models.DependencyCheck) models.DependencyResult {
+	timeout := DefaultTimeout
+	if d.TimeoutMs > 0 {
+		timeout = min(time.Duration(d.TimeoutMs)*time.Millisecond, MaxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res := models.DependencyResult{Name: d.Name}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.URL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	start := time.Now()
+	resp, err := p.http.Do(req)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			res.Error = fmt.Sprintf("timed out after %s", timeout)
+		} else {
+			res.Error = err.Error()
+		}
+		return res
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Lets the connection be reused
+	resp.Body.Close()
+	res.StatusCode = resp.StatusCode
+	return res
+}
+
+func annotation(results []models.DependencyResult) models.AlertAnnotation {
+	unhealthy := 0
+	fields := make(map[string]string, len(results))
+	for _, r := range results {
+		if !r.Healthy() {
+			unhealthy++
+		}
+		if r.Error != "" {
+			fields[r.Name] = fmt.Sprintf("error: %s (%dms)", r.Error, r.LatencyMs)
+		} else {
+			fields[r.Name] = fmt.Sprintf("%d %s (%dms)", r.StatusCode, http.StatusText(r.StatusCode), r.LatencyMs)
+		}
+	}
+	title := "Dependency snapshot: all healthy"
+	if unhealthy > 0 {
+		title = fmt.Sprintf("Dependency snapshot: %d of %d unhealthy", unhealthy, len(results))
+	}
+	return models.AlertAnnotation{Title: title, Fields: fields, Author: annotationAuthor}
+}
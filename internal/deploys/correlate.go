@@ -0,0 +1,145 @@
+// Package deploys flags alerts that start shortly after a deploy of the same
+// service as possibly deploy-related.
+package deploys
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	// DefaultWindow is how long after a deploy new alerts are flagged
+	DefaultWindow = 30 * time.Minute
+
+	// LabelDeployRelated is set to "true" on flagged alerts
+	LabelDeployRelated = "deploy_related"
+
+	// annotationAuthor marks the annotation the correlator adds, so an alert
+	// is only flagged once
+	annotationAuthor = "deploy-correlation"
+)
+
+// WindowFromEnv reads DEPLOY_CORRELATION_WINDOW (e.g. "15m"; "0" disables)
+func WindowFromEnv() (time.Duration, error) {
+	v := os.Getenv("DEPLOY_CORRELATION_WINDOW")
+	if v == "" {
+		return DefaultWindow, nil
+	}
+	if v == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 || d > store.DeployRetention {
+		return 0, fmt.Errorf("invalid DEPLOY_CORRELATION_WINDOW %q: use a duration up to %s", v, store.DeployRetention)
+	}
+	return d, nil
+}
+
+// Services returns the names an alert's service may go by: its service,
+// repo or project label, then its source
+func Services(a models.Alert) []string {
+	var names []string
+	for _, key := range []string{"service", "repo", "project"} {
+		if v := a.Labels[key]; v != "" {
+			names = append(names, v)
+		}
+	}
+	return append(names, a.Source)
+}
+
+type Correlator struct {
+	store  store.AlertStore
+	window time.Duration
+}
+
+func NewCorrelator(s store.AlertStore, window time.Duration) *Correlator {
+	return &Correlator{store: s, window: window}
+}
+
+// Run checks new alerts against recent deploys until ch is closed
+func (c *Correlator) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		if err := c.Correlate(ctx, a); err != nil {
+			log.Printf("deploy correlation failed for alert %d: %v", a.ID, err)
+		}
+	}
+}
+
+// Correlate flags a if a deploy of its service happened within the window
+// before it started
+func (c *Correlator) Correlate(ctx context.Context, a models.Alert) error {
+	if a.Status == models.AlertStatusResolved || a.Level == models.LevelSuccess || a.Source == "github" || a.Source == "gitlab" {
+		// Resolutions and the CI/CD integrations' own alerts aren't symptoms of a deploy
+		return nil
+	}
+	for _, an := range a.Annotations {
+		if an.Author == annotationAuthor {
+			// Already flagged; an upsert may have replaced the labels since
+			if a.Labels[LabelDeployRelated] == "" {
+				_, err := c.store.SetAlertLabels(ctx, a.ID, map[string]string{LabelDeployRelated: "true"})
+				return err
+			}
+			return nil
+		}
+	}
+
+	deploys, err := c.store.GetDeploys(ctx, a.CreatedAt.Add(-c.window), a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	// Newest deploy first: the likeliest culprit
+	var match *models.DeployEvent
+	for i := len(deploys) - 1; i >= 0 && match == nil; i-- {
+		for _, name := range Services(a) {
+			if deploys[i].MatchesService(name) {
+				match = &deploys[i]
+				break
+			}
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	fields := map[string]string{
+		"service":     match.Service,
+		"deployed_at": match.DeployedAt.Format(time.RFC3339),
+		"before":      a.CreatedAt.Sub(match.DeployedAt).Round(time.Second).String(),
+		"provider":    match.Provider,
+	}
+	for k, v := range map[string]string{"environment": match.Environment, "version": match.Version, "actor": match.Actor} {
+		if v != "" {
+			fields[k] = v
+		}
+	}
+	an := models.AlertAnnotation{
+		Title:  "Possible deploy-related",
+		Fields: fields,
+		Author: annotationAuthor,
+	}
+	if match.URL != "" {
+		an.Links = []models.AnnotationLink{{Title: "Deploy", URL: match.URL}}
+	}
+
+	if _, err := c.store.AddAnnotation(ctx, a.ID, an); err != nil {
+		return err
+	}
+	_, err = c.store.SetAlertLabels(ctx, a.ID, map[string]string{LabelDeployRelated: "true"})
+	return err
+}
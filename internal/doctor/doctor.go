@@ -0,0 +1,278 @@
+// Package doctor checks a deployment's configuration: that Redis and
+// PostgreSQL answer, that secrets aren't guessable and that nothing is left
+// at a setting that only suits a first try. `sentinel doctor` prints every
+// check; at startup the problems are logged before traffic is served.
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"incident-viewer-go/internal/secrets"
+	"incident-viewer-go/internal/store"
+)
+
+// Finding severities
+const (
+	OK   = "ok"
+	Warn = "warn"
+	Fail = "fail"
+)
+
+// pingTimeout bounds each reachability check
+const pingTimeout = 3 * time.Second
+
+// Finding is the outcome of one check
+type Finding struct {
+	Check    string
+	Severity string
+	Detail   string
+	Fix      string // What to do about it; empty when OK
+}
+
+// Deps are the connections the checks use; a nil Database is reported as
+// not configured
+type Deps struct {
+	Redis interface {
+		Ping(ctx context.Context) error
+	}
+	Database store.AdminStore
+}
+
+// weakSecrets are values that turn up in examples and first tries
+var weakSecrets = []string{
+	"secret", "changeme", "change-me", "password", "admin", "admin123",
+	"sentinel", "test", "example", "12345678", "replace-me",
+}
+
+// minSecretLen is the shortest shared secret or token not reported as weak
+const minSecretLen = 16
+
+// Run performs every check, in a fixed order
+func Run(ctx context.Context, d Deps) []Finding {
+	var findings []Finding
+	add := func(f Finding) { findings = append(findings, f) }
+
+	add(checkRedis(ctx, d.Redis))
+	db := checkDatabase(ctx, d.Database)
+	add(db)
+	if db.Severity == OK {
+		add(checkAdminPassword(ctx, d.Database))
+	}
+	add(checkEncryptionKey())
+	add(checkVAPID())
+	for _, key := range []string{"WEBHOOK_SECRET", "FEDERATION_SECRET", "SETUP_TOKEN", "JWT_SECRET"} {
+		if f, ok := checkSecret(key); ok {
+			add(f)
+		}
+	}
+	add(checkPublicURL())
+	add(checkTemplates())
+	add(checkTempDir())
+	return findings
+}
+
+func checkRedis(ctx context.Context, redis interface {
+	Ping(ctx context.Context) error
+}) Finding {
+	f := Finding{Check: "redis"}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := redis.Ping(ctx); err != nil {
+		f.Severity, f.Detail = Fail, fmt.Sprintf("not reachable: %v", err)
+		f.Fix = "Check REDIS_ADDR, REDIS_PASSWORD and REDIS_DB; alerts can't be stored or shown without Redis"
+		return f
+	}
+	f.Severity, f.Detail = OK, "reachable"
+	return f
+}
+
+func checkDatabase(ctx context.Context, db store.AdminStore) Finding {
+	f := Finding{Check: "database"}
+	if db == nil {
+		f.Severity, f.Detail, f.Fix = Fail, "DATABASE_URL is not set", "Set DATABASE_URL to the PostgreSQL connection string"
+		return f
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		f.Severity, f.Detail = Fail, fmt.Sprintf("not reachable: %v", err)
+		f.Fix = "Check DATABASE_URL; until it answers, admin features and logins are unavailable"
+		return f
+	}
+	f.Severity, f.Detail = OK, "reachable"
+	return f
+}
+
+// checkAdminPassword looks for admins still signing in with
+// INITIAL_ADMIN_PASSWORD or a well-known default
+func checkAdminPassword(ctx context.Context, db store.AdminStore) Finding {
+	f := Finding{Check: "admin password"}
+	users, err := db.GetUsers(ctx)
+	if err != nil {
+		f.Severity, f.Detail, f.Fix = Warn, fmt.Sprintf("can't list users: %v", err), "Check that migrations have run"
+		return f
+	}
+	candidates := slices.Clone(weakSecrets)
+	initial := os.Getenv("INITIAL_ADMIN_PASSWORD")
+	if initial != "" {
+		candidates = append(candidates, initial)
+	}
+
+	admins := 0
+	var weak []string
+	for _, u := range users {
+		if u.Role != "admin" {
+			continue
+		}
+		admins++
+		for _, pw := range candidates {
+			if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(pw)) == nil {
+				weak = append(weak, u.Username)
+				break
+			}
+		}
+	}
+
+	switch {
+	case admins == 0:
+		f.Severity, f.Detail = Warn, "no admin account yet"
+		f.Fix = "Create one at /admin/setup with SETUP_TOKEN, or set INITIAL_ADMIN_PASSWORD"
+	case len(weak) > 0:
+		f.Severity = Fail
+		f.Detail = fmt.Sprintf("%s still use the initial or a default password", strings.Join(weak, ", "))
+		f.Fix = "Change the password under Profile"
+		if initial != "" {
+			f.Fix += ", then remove INITIAL_ADMIN_PASSWORD from the environment"
+		}
+	default:
+		f.Severity, f.Detail = OK, fmt.Sprintf("%d admin(s), none with a default password", admins)
+	}
+	return f
+}
+
+func checkEncryptionKey() Finding {
+	f := Finding{Check: "encryption key"}
+	if _, ok, err := secrets.FromEnv(); err != nil {
+		f.Severity, f.Detail, f.Fix = Fail, err.Error(), "Generate one with: openssl rand -base64 32"
+	} else if !ok {
+		f.Severity, f.Detail = Warn, "SENTINEL_ENCRYPTION_KEY is not set, so TOTP secrets and bot tokens are stored unencrypted and integration credentials such as Opsgenie API keys can't be stored"
+		f.Fix = "Generate one with: openssl rand -base64 32"
+	} else {
+		f.Severity, f.Detail = OK, "set"
+	}
+	return f
+}
+
+func checkVAPID() Finding {
+	f := Finding{Check: "push keys"}
+	if os.Getenv("VAPID_PRIVATE_KEY") == "" || os.Getenv("VAPID_PUBLIC_KEY") == "" {
+		f.Severity = Warn
+		f.Detail = "VAPID keys are not set, so new ones are generated on every start and browsers lose their push subscriptions"
+		f.Fix = "Copy the VAPID_PRIVATE_KEY and VAPID_PUBLIC_KEY printed at startup into the environment"
+		return f
+	}
+	f.Severity, f.Detail = OK, "set"
+	return f
+}
+
+// checkSecret reports a shared secret that is short or well known; ok is
+// false when it isn't set, which turns its feature off and isn't a problem
+func checkSecret(key string) (Finding, bool) {
+	v := os.Getenv(key)
+	if v == "" {
+		return Finding{}, false
+	}
+	f := Finding{Check: strings.ToLower(key)}
+	switch {
+	case slices.Contains(weakSecrets, strings.ToLower(v)):
+		f.Severity, f.Detail = Fail, key+" is a well-known value"
+	case len(v) < minSecretLen:
+		f.Severity, f.Detail = Warn, fmt.Sprintf("%s is only %d characters", key, len(v))
+	default:
+		f.Severity, f.Detail = OK, "set"
+		return f, true
+	}
+	f.Fix = fmt.Sprintf("Use a random value of at least %d characters, e.g. from: openssl rand -hex 32", minSecretLen)
+	return f, true
+}
+
+func checkPublicURL() Finding {
+	f := Finding{Check: "public URL"}
+	if os.Getenv("SENTINEL_PUBLIC_URL") == "" {
+		f.Severity, f.Detail = Warn, "SENTINEL_PUBLIC_URL is not set, so notifications carry no links to alerts"
+		f.Fix = "Set it to the address users open Sentinel at, e.g. https://sentinel.example.com"
+		return f
+	}
+	f.Severity, f.Detail = OK, os.Getenv("SENTINEL_PUBLIC_URL")
+	return f
+}
+
+// checkTemplates catches a start outside the directory holding web/
+func checkTemplates() Finding {
+	f := Finding{Check: "templates"}
+	path := filepath.Join("web", "templates", "index.html")
+	if _, err := os.Stat(path); err != nil {
+		wd, _ := os.Getwd()
+		f.Severity, f.Detail = Fail, fmt.Sprintf("%s not found in %s", path, wd)
+		f.Fix = "Start Sentinel from the directory containing web/"
+		return f
+	}
+	f.Severity, f.Detail = OK, "found"
+	return f
+}
+
+// checkTempDir checks the directory large request bodies spill into
+func checkTempDir() Finding {
+	f := Finding{Check: "temp dir"}
+	tmp, err := os.CreateTemp("", "sentinel-doctor-*")
+	if err != nil {
+		f.Severity, f.Detail = Warn, fmt.Sprintf("%s is not writable: %v", os.TempDir(), err)
+		f.Fix = "Make it writable or point TMPDIR at a writable directory"
+		return f
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	f.Severity, f.Detail = OK, os.TempDir()+" is writable"
+	return f
+}
+
+// Print writes every finding with its fix, and reports whether any failed
+func Print(w io.Writer, findings []Finding) (failed bool) {
+	problems := 0
+	for _, f := range findings {
+		fmt.Fprintf(w, "%-6s %-16s %s\n", "["+f.Severity+"]", f.Check, f.Detail)
+		if f.Fix != "" {
+			fmt.Fprintf(w, "%23s %s\n", "fix:", f.Fix)
+		}
+		if f.Severity != OK {
+			problems++
+		}
+		failed = failed || f.Severity == Fail
+	}
+	if problems == 0 {
+		fmt.Fprintln(w, "No problems found")
+	} else {
+		fmt.Fprintf(w, "%d problem(s) found\n", problems)
+	}
+	return failed
+}
+
+// Log logs the findings that aren't OK, for the startup preflight
+func Log(findings []Finding) {
+	for _, f := range findings {
+		if f.Severity == OK {
+			continue
+		}
+		log.Printf("Preflight %s: %s: %s. %s", f.Severity, f.Check, f.Detail, f.Fix)
+	}
+}
@@ -0,0 +1,327 @@
+// Package email sends alert notifications over SMTP: a connection-pooled
+// sender, HTML templates per severity, and a notifier for the email routes
+// admins configure per chat and source.
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/sandbox"
+)
+
+const (
+	// DefaultPoolSize is how many idle SMTP connections are kept open
+	DefaultPoolSize = 4
+	dialTimeout     = 10 * time.Second
+)
+
+// Config is how to reach the SMTP relay
+type Config struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+	PoolSize int
+}
+
+// ConfigFromEnv reads SMTP_HOST, SMTP_PORT (default 587; 465 means implicit
+// TLS), SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM and SMTP_POOL_SIZE. ok is
+// false without SMTP_HOST.
+func ConfigFromEnv() (cfg Config, ok bool) {
+	cfg = Config{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		PoolSize: DefaultPoolSize,
+	}
+	if cfg.Host == "" {
+		return cfg, false
+	}
+	if cfg.Port == "" {
+		cfg.Port = "587"
+	}
+	if cfg.From == "" {
+		cfg.From = "sentinel@" + cfg.Host
+	}
+	if n, err := strconv.Atoi(os.Getenv("SMTP_POOL_SIZE")); err == nil && n > 0 {
+		cfg.PoolSize = n
+	}
+	return cfg, true
+}
+
+// Message is an email with a plain-text body and an optional HTML
+// alternative
+type Message struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+}
+
+// NotificationTarget names the recipients in the delivery history
+func (m Message) NotificationTarget() string { return strings.Join(m.To, ", ") }
+
+// queueKind names email in the notification queue
+const queueKind = "email"
+
+// Sender delivers messages through the relay, reusing connections between
+// messages instead of dialing and authenticating every time
+type Sender struct {
+	cfg  Config
+	idle chan *smtp.Client
+
+	sandboxed bool
+	sandboxTo string // Where sandboxed messages go; empty drops them
+
+	queue *notifyqueue.Queue // nil makes SendQueued send right away
+}
+
+func NewSender(cfg Config) *Sender {
+	return &Sender{cfg: cfg, idle: make(chan *smtp.Client, cfg.PoolSize)}
+}
+
+// Sandbox sends every message to to instead of its recipients, labeled
+// with who it was meant for; an empty to drops messages
+func (s *Sender) Sandbox(to string) {
+	s.sandboxed = true
+	s.sandboxTo = to
+}
+
+// Queue makes SendQueued go through q, which retries failed messages and
+// keeps the ones that keep failing for inspection
+func (s *Sender) Queue(q *notifyqueue.Queue) {
+	s.queue = q
+	q.Register(queueKind, s.deliver)
+}
+
+// SendQueued queues m for delivery, for notifications that shouldn't be lost
+// when the relay is down; it sends right away without a queue
+func (s *Sender) SendQueued(ctx context.Context, m Message) error {
+	return s.queue.Send(ctx, queueKind, m, s.deliver)
+}
+
+func (s *Sender) deliver(ctx context.Context, payload json.RawMessage) error {
+	var m Message
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return notifyqueue.Permanent(err)
+	}
+	err := s.Send(m)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return notifyqueue.Permanent(err) // Rejected by the relay, not a hiccup
+	}
+	return err
+}
+
+// Send delivers m to all its recipients in one transaction
+func (s *Sender) Send(m Message) error {
+	if len(m.To) == 0 {
+		return notifyqueue.Permanent(errors.New("no recipients"))
+	}
+	if s.sandboxed {
+		if s.sandboxTo == "" {
+			log.Printf("email: sandbox: dropped %q for %s", m.Subject, strings.Join(m.To, ", "))
+			return nil
+		}
+		m = sandboxMessage(m, s.sandboxTo)
+	}
+	data, err := s.build(m)
+	if err != nil {
+		return err
+	}
+
+	c, err := s.get()
+	if err != nil {
+		return err
+	}
+	if err := s.transact(c, m.To, data); err != nil {
+		c.Close()
+		return err
+	}
+	s.put(c)
+	return nil
+}
+
+// sandboxMessage readdresses m to to, with the label in the subject and the
+// original recipients at the top of the body
+func sandboxMessage(m Message, to string) Message {
+	recipients := strings.Join(m.To, ", ")
+	m.To = []string{to}
+	m.Subject = sandbox.Label + " " + m.Subject
+	m.Text = fmt.Sprintf("%s Sandbox copy; originally for: %s\n\n%s", sandbox.Label, recipients, m.Text)
+	if m.HTML != "" {
+		banner := fmt.Sprintf(`<div style="background:#fef3c7;color:#92400e;padding:8px 12px;font-family:sans-serif;font-size:13px">%s Sandbox copy; originally for: %s</div>`,
+			sandbox.Label, html.EscapeString(recipients))
+		// Right after <body ...> when there is one, so the document stays well-formed
+		if i := strings.Index(m.HTML, "<body"); i >= 0 {
+			if j := strings.Index(m.HTML[i:], ">"); j >= 0 {
+				at := i + j + 1
+				m.HTML = m.HTML[:at] + banner + m.HTML[at:]
+				return m
+			}
+		}
+		m.HTML = banner + m.HTML
+	}
+	return m
+}
+
+func (s *Sender) transact(c *smtp.Client, to []string, data []byte) error {
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("%s: %w", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// get takes an idle connection that still answers, or dials a new one
+func (s *Sender) get() (*smtp.Client, error) {
+	for {
+		select {
+		case c := <-s.idle:
+			if c.Reset() == nil {
+				return c, nil
+			}
+			c.Close() // Timed out on the server's side
+		default:
+			return s.dial()
+		}
+	}
+}
+
+// put keeps c for the next message, or closes it when the pool is full
+func (s *Sender) put(c *smtp.Client) {
+	select {
+	case s.idle <- c:
+	default:
+		c.Quit()
+	}
+}
+
+func (s *Sender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, s.cfg.Port)
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.Port == "465" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// build renders m as a MIME message, multipart/alternative when it has HTML
+func (s *Sender) build(m Message) ([]byte, error) {
+	var b strings.Builder
+	header := func(k, v string) {
+		// Values come from alert titles; never let them start a new header
+		b.WriteString(k + ": " + strings.NewReplacer("\r", " ", "\n", " ").Replace(v) + "\r\n")
+	}
+	header("From", s.cfg.From)
+	header("To", strings.Join(m.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+
+	if m.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		if err := writeQP(&b, m.Text); err != nil {
+			return nil, err
+		}
+		return []byte(b.String()), nil
+	}
+
+	boundary, err := newBoundary()
+	if err != nil {
+		return nil, err
+	}
+	header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	b.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString("Content-Type: " + part.contentType + "\r\n")
+		b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQP(&b, part.body); err != nil {
+			return nil, err
+		}
+		b.WriteString("\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return []byte(b.String()), nil
+}
+
+func writeQP(b *strings.Builder, body string) error {
+	w := quotedprintable.NewWriter(b)
+	body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := w.Write([]byte(body)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func newBoundary() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "sentinel-" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,115 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	escalationCheckInterval = time.Minute    // How often to look for unacknowledged alerts
+	escalationHorizon       = 24 * time.Hour // Alerts older than this aren't escalated further
+)
+
+// RunEscalations mails the next responder of a route's schedule each time an
+// open alert it matches goes another escalate_after minutes without being
+// acknowledged. Each step is claimed in Redis, so only one replica sends it.
+func (n *Notifier) RunEscalations(ctx context.Context, alerts store.AlertStore) {
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		n.escalate(ctx, alerts)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (n *Notifier) escalate(ctx context.Context, alerts store.AlertStore) {
+	routes, err := n.admin.GetEmailRoutes(ctx)
+	if err != nil {
+		log.Printf("email: failed to load routes for escalation: %v", err)
+		return
+	}
+	var escalating []models.EmailRoute
+	for _, route := range routes {
+		if route.Enabled && route.ScheduleID != 0 && route.EscalateAfter > 0 {
+			escalating = append(escalating, route)
+		}
+	}
+	if len(escalating) == 0 {
+		return
+	}
+
+	now := time.Now()
+	recent, err := alerts.GetAlertsSince(ctx, now.Add(-escalationHorizon))
+	if err != nil {
+		log.Printf("email: failed to load alerts for escalation: %v", err)
+		return
+	}
+	calendars := store.CalendarsByID(ctx, n.admin)
+	schedules := map[int]models.Schedule{}
+	for _, a := range recent {
+		if a.Status != models.AlertStatusOpen || a.AckedAt != nil || a.SilenceID != 0 {
+			continue
+		}
+		for _, route := range escalating {
+			if !route.Matches(a) || !route.AllowsAt(a, calendars, now) {
+				continue
+			}
+			step := int(now.Sub(a.CreatedAt) / (time.Duration(route.EscalateAfter) * time.Minute))
+			if step < 1 {
+				continue
+			}
+			sc, ok := schedules[route.ScheduleID]
+			if !ok {
+				if sc, err = n.admin.GetSchedule(ctx, route.ScheduleID); err != nil {
+					log.Printf("email: failed to load schedule %d of route %s: %v", route.ScheduleID, route.Name, err)
+					continue
+				}
+				schedules[route.ScheduleID] = sc
+			}
+			// The chain is the one on call when the alert opened; once it
+			// runs out, everyone has been mailed
+			responders := sc.RespondersAt(a.CreatedAt)
+			if step >= len(responders) {
+				continue
+			}
+			first, err := alerts.ClaimNonce(ctx, fmt.Sprintf("escalation:%d:%d:%d", a.ID, route.ID, step), escalationHorizon)
+			if err != nil || !first {
+				continue
+			}
+			n.sendEscalation(ctx, a, route, sc, responders[step])
+		}
+	}
+}
+
+// sendEscalation mails a to the responder it escalated to
+func (n *Notifier) sendEscalation(ctx context.Context, a models.Alert, route models.EmailRoute, sc models.Schedule, userID int) {
+	addr := n.userEmail(ctx, userID)
+	if addr == "" {
+		log.Printf("email: can't escalate alert %d to user %d of schedule %s: no email address", a.ID, userID, sc.Name)
+		return
+	}
+	m, err := n.message(ctx, a, route)
+	if err != nil {
+		log.Printf("email: failed to render alert %d: %v", a.ID, err)
+		return
+	}
+	m.To = []string{addr}
+	m.Subject = "[ESCALATED] " + m.Subject
+	// Plain text only, so the note on top isn't hidden behind the HTML part
+	m.Text = fmt.Sprintf("Nobody has acknowledged this alert since %s. You are next in line on the %s schedule.\n\n%s",
+		a.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"), sc.Name, m.Text)
+	m.HTML = ""
+	if err := n.sender.SendQueued(ctx, m); err != nil {
+		log.Printf("email: failed to escalate alert %d to user %d: %v", a.ID, userID, err)
+	}
+}
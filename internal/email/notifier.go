@@ -0,0 +1,182 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/floodcontrol"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/msgtemplate"
+	"incident-viewer-go/internal/store"
+)
+
+type Notifier struct {
+	admin     store.AdminStore
+	sender    *Sender
+	publicURL string
+	templates *msgtemplate.Renderer // nil sends the built-in templates
+	throttle  *floodcontrol.Limiter // nil sends every email
+}
+
+// NewNotifier mails alerts to the recipients of matching email routes;
+// publicURL (SENTINEL_PUBLIC_URL) adds a link to the alert when set
+func NewNotifier(admin store.AdminStore, sender *Sender, publicURL string) *Notifier {
+	return &Notifier{admin: admin, sender: sender, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// Templates lets admin-defined email templates replace the built-in ones;
+// a templated email is plain text
+func (n *Notifier) Templates(r *msgtemplate.Renderer) {
+	n.templates = r
+}
+
+// Throttle holds back emails about an alert whose check already notified within
+// l's window
+func (n *Notifier) Throttle(l *floodcontrol.Limiter) {
+	n.throttle = l
+}
+
+// Run mails alert lifecycle events until ch is closed
+func (n *Notifier) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		n.Notify(ctx, a)
+	}
+}
+
+// Notify mails a to every matching route
+func (n *Notifier) Notify(ctx context.Context, a models.Alert) {
+	routes, err := n.admin.GetEmailRoutes(ctx)
+	if err != nil {
+		log.Printf("email: failed to load routes: %v", err)
+		return
+	}
+	calendars := store.CalendarsByID(ctx, n.admin)
+	now := time.Now()
+	allowed := false
+	for _, route := range routes {
+		if !route.Matches(a) || !route.AllowsAt(a, calendars, now) {
+			continue
+		}
+		if !allowed {
+			if !n.throttle.Allow(ctx, floodcontrol.ChannelEmail, a) {
+				return
+			}
+			allowed = true
+		}
+		m, err := n.message(ctx, a, route)
+		if err != nil {
+			log.Printf("email: failed to render alert %d: %v", a.ID, err)
+			return
+		}
+		if m.To = n.recipients(ctx, route); len(m.To) == 0 {
+			continue
+		}
+		if err := n.sender.SendQueued(ctx, m); err != nil {
+			log.Printf("email: failed to send alert %d for route %s: %v", a.ID, route.Name, err)
+		}
+	}
+}
+
+// Targets lists the email routes by ID, for channel tests
+func (n *Notifier) Targets(ctx context.Context) ([]string, error) {
+	routes, err := n.admin.GetEmailRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(routes))
+	for _, route := range routes {
+		ids = append(ids, strconv.Itoa(route.ID))
+	}
+	return ids, nil
+}
+
+// Test mails a to the route's recipients right away, whatever its filters,
+// and returns the relay's answer instead of queueing the message
+func (n *Notifier) Test(ctx context.Context, target string, a models.Alert) error {
+	routes, err := n.admin.GetEmailRoutes(ctx)
+	if err != nil {
+		return err
+	}
+	for _, route := range routes {
+		if strconv.Itoa(route.ID) != target {
+			continue
+		}
+		m, err := n.message(ctx, a, route)
+		if err != nil {
+			return err
+		}
+		if m.To = n.recipients(ctx, route); len(m.To) == 0 {
+			return fmt.Errorf("email route %q has no recipients and nobody with an email address is on call", route.Name)
+		}
+		return n.sender.Send(m)
+	}
+	return fmt.Errorf("unknown email route %q", target)
+}
+
+// recipients are the route's addresses and, with a schedule, whoever is on
+// call now
+func (n *Notifier) recipients(ctx context.Context, route models.EmailRoute) []string {
+	to := route.Recipients
+	if route.ScheduleID == 0 {
+		return to
+	}
+	sc, err := n.admin.GetSchedule(ctx, route.ScheduleID)
+	if err != nil {
+		log.Printf("email: failed to load schedule %d of route %s: %v", route.ScheduleID, route.Name, err)
+		return to
+	}
+	if shift, ok := sc.OnCallAt(time.Now()); ok {
+		if addr := n.userEmail(ctx, shift.UserID); addr != "" && !slices.Contains(to, addr) {
+			to = append(slices.Clip(to), addr)
+		}
+	}
+	return to
+}
+
+// userEmail is the user's address, "" when they have none
+func (n *Notifier) userEmail(ctx context.Context, userID int) string {
+	user, err := n.admin.GetUser(ctx, userID)
+	if err != nil {
+		return ""
+	}
+	return user.Email
+}
+
+// message is the email for a on route, without recipients, from the admin's
+// template when there is one
+func (n *Notifier) message(ctx context.Context, a models.Alert, route models.EmailRoute) (Message, error) {
+	m, err := Render(a, route.Name, n.publicURL)
+	if err != nil {
+		return Message{}, err
+	}
+	if subject, body, ok := n.templates.Render(ctx, models.ChannelEmail, msgtemplate.Data{Alert: a, Headline: headline(a)}); ok {
+		if subject != "" {
+			m.Subject = subject
+		}
+		m.Text, m.HTML = body, ""
+	}
+	return m, nil
+}
+
+// headline is what happened to a, for templates
+func headline(a models.Alert) string {
+	if a.Status == models.AlertStatusResolved || models.NormalizeLevel(a.Level) == models.LevelSuccess {
+		return "Resolved"
+	}
+	return "Incident opened"
+}
@@ -0,0 +1,78 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// templates holds one HTML template per severity, plus "resolved"
+var templates = func() map[string]*template.Template {
+	t := make(map[string]*template.Template)
+	for _, name := range []string{models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo, "resolved"} {
+		t[name] = template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+	return t
+}()
+
+type templateData struct {
+	Alert    models.Alert
+	Started  string
+	Resolved string
+	Link     string
+	Route    string
+}
+
+// Render builds the notification for a: the subject, and the plain-text and
+// HTML bodies in the template for its severity. route names the email route
+// in the footer; publicURL, when set, adds a link to the alert.
+func Render(a models.Alert, route, publicURL string) (Message, error) {
+	name := models.NormalizeLevel(a.Level)
+	subject := fmt.Sprintf("[%s] %s", strings.ToUpper(name), a.Title)
+	if a.Status == models.AlertStatusResolved || name == models.LevelSuccess {
+		name = "resolved"
+		subject = "[RESOLVED] " + a.Title
+	}
+	tmpl, ok := templates[name]
+	if !ok {
+		tmpl = templates[models.LevelInfo]
+	}
+
+	data := templateData{
+		Alert:   a,
+		Started: a.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"),
+		Route:   route,
+	}
+	if a.ResolvedAt != nil {
+		data.Resolved = a.ResolvedAt.UTC().Format("2006-01-02 15:04 UTC")
+	}
+	if publicURL != "" {
+		data.Link = fmt.Sprintf("%s/?alert=%d", strings.TrimRight(publicURL, "/"), a.ID)
+	}
+
+	var html bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, err
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s\n\nLevel: %s\nSource: %s\nStarted: %s\n", a.Title, a.Level, a.Source, data.Started)
+	if data.Resolved != "" {
+		fmt.Fprintf(&text, "Resolved: %s\n", data.Resolved)
+	}
+	if a.Message != "" {
+		fmt.Fprintf(&text, "\n%s\n", a.Message)
+	}
+	if data.Link != "" {
+		fmt.Fprintf(&text, "\n%s\n", data.Link)
+	}
+
+	return Message{Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
@@ -0,0 +1,232 @@
+// Package federation forwards selected alerts to other Sentinel instances,
+// e.g. per-team instances feeding a central NOC.
+package federation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/requestid"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	// IngestPath is where an instance accepts forwarded alerts
+	IngestPath = "/api/federation/alerts"
+	// MaxHops bounds how many instances an alert may pass through
+	MaxHops = 4
+
+	// Labels the receiving instance sets on federated alerts
+	LabelPath   = "federation_path"   // Instances the alert came through, comma separated, origin first
+	LabelSource = "federation_source" // Source on the originating instance
+
+	forwardAttempts = 3
+)
+
+// Target is another instance to forward matching alerts to
+type Target struct {
+	Name         string   `json:"name"`
+	URL          string   `json:"url"`    // Base URL of the other instance
+	Secret       string   `json:"secret"` // Its FEDERATION_SECRET
+	MinLevel     string   `json:"min_level"`
+	Sources      []string `json:"sources"`        // Glob patterns on the local source; empty = all
+	ChatIDs      []string `json:"chat_ids"`       // Local chats to forward; empty = all
+	RemoteChatID string   `json:"remote_chat_id"` // Chat on the receiving side to file the alerts under
+}
+
+func (t Target) matches(a models.Alert) bool {
+	if t.MinLevel != "" && models.LevelRank(a.Level) < models.LevelRank(t.MinLevel) {
+		return false
+	}
+	if len(t.ChatIDs) > 0 && !slices.Contains(t.ChatIDs, models.ChatIDFromSource(a.Source)) {
+		return false
+	}
+	if len(t.Sources) == 0 {
+		return true
+	}
+	for _, pattern := range t.Sources {
+		if ok, _ := path.Match(pattern, a.Source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Envelope is the body POSTed to IngestPath, signed with X-Sentinel-Signature
+// (hex HMAC-SHA256 of the body, keyed with the receiver's secret)
+type Envelope struct {
+	Path   []string     `json:"path"` // Instances traversed, origin first; the sender is last
+	ChatID string       `json:"chat_id,omitempty"`
+	Alert  models.Alert `json:"alert"`
+}
+
+// Sender is the instance that sent the envelope
+func (e Envelope) Sender() string {
+	if len(e.Path) == 0 {
+		return ""
+	}
+	return e.Path[len(e.Path)-1]
+}
+
+// Sign returns the X-Sentinel-Signature for body sent with the given
+// X-Sentinel-Timestamp and X-Sentinel-Nonce
+func Sign(body []byte, secret, timestamp, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// InstanceID names this instance in federation paths: FEDERATION_INSTANCE_ID,
+// or the hostname
+func InstanceID() string {
+	if id := os.Getenv("FEDERATION_INSTANCE_ID"); id != "" {
+		return id
+	}
+	host, _ := os.Hostname()
+	return host
+}
+
+// LoadTargets reads the JSON array of targets in FEDERATION_TARGETS_FILE.
+// No file means no forwarding.
+func LoadTargets() ([]Target, error) {
+	file := os.Getenv("FEDERATION_TARGETS_FILE")
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	for i, t := range targets {
+		if t.URL == "" || t.Secret == "" {
+			return nil, fmt.Errorf("%s: target %q needs url and secret", file, t.Name)
+		}
+		targets[i].URL = strings.TrimRight(t.URL, "/")
+	}
+	return targets, nil
+}
+
+type Forwarder struct {
+	instanceID string
+	targets    []Target
+	http       *http.Client
+}
+
+func NewForwarder(instanceID string, targets []Target) *Forwarder {
+	return &Forwarder{
+		instanceID: instanceID,
+		targets:    targets,
+		http:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Run forwards alert lifecycle events until ch is closed
+func (f *Forwarder) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		f.Forward(ctx, a)
+	}
+}
+
+// Forward sends a new, updated or resolved alert to every matching target
+func (f *Forwarder) Forward(ctx context.Context, a models.Alert) {
+	var pathSoFar []string
+	if p := a.Labels[LabelPath]; p != "" {
+		pathSoFar = strings.Split(p, ",")
+	}
+	// Never hand an alert back to an instance it came through; the receiver
+	// checks too, this just saves the round trip
+	if slices.Contains(pathSoFar, f.instanceID) || len(pathSoFar) >= MaxHops {
+		return
+	}
+
+	for _, t := range f.targets {
+		if !t.matches(a) {
+			continue
+		}
+		env := Envelope{Path: append(slices.Clone(pathSoFar), f.instanceID), ChatID: t.RemoteChatID, Alert: a}
+		if err := f.send(ctx, t, env); err != nil {
+			log.Printf("federation: failed to forward alert %d to %s: %v", a.ID, t.Name, err)
+		}
+	}
+}
+
+func (f *Forwarder) send(ctx context.Context, t Target, env Envelope) error {
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < forwardAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL+IngestPath, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		// Fresh timestamp and nonce per attempt: the receiver rejects reused nonces
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		ts, nonce := time.Now().UTC().Format(time.RFC3339), hex.EncodeToString(b)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Sentinel-Timestamp", ts)
+		req.Header.Set("X-Sentinel-Nonce", nonce)
+		req.Header.Set("X-Sentinel-Signature", Sign(body, t.Secret, ts, nonce))
+		requestid.Set(req, env.Alert.RequestID)
+
+		resp, err := f.http.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode < 500:
+			// Bad signature, loop detected, ...: retrying won't help
+			return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		default:
+			lastErr = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		}
+	}
+	return lastErr
+}
@@ -0,0 +1,119 @@
+// Package floodcontrol keeps a flapping check from sending a notification on
+// every flap: each channel sends at most one notification per alert
+// fingerprint per window, and counts the ones it holds back on the alert.
+// Slots are claimed in Redis, so the limit holds across replicas.
+package floodcontrol
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// Channels that can be throttled, as named in NOTIFY_THROTTLE_{CHANNEL}
+const (
+	ChannelPush         = "push"
+	ChannelSMS          = "sms"
+	ChannelEmail        = "email"
+	ChannelTeams        = "teams"
+	ChannelTelegram     = "telegram"
+	ChannelStakeholders = "stakeholders"
+	ChannelWebhook      = "webhook"
+)
+
+var channels = []string{ChannelPush, ChannelSMS, ChannelEmail, ChannelTeams, ChannelTelegram, ChannelStakeholders, ChannelWebhook}
+
+type Limiter struct {
+	store   store.AlertStore
+	windows map[string]time.Duration // Channel -> window; missing or 0 sends everything
+}
+
+// FromEnv reads NOTIFY_THROTTLE, the window for every channel (e.g. "5m"),
+// and NOTIFY_THROTTLE_{CHANNEL} (e.g. NOTIFY_THROTTLE_PUSH) to override it
+// for one. Both default to 0, which turns flood control off.
+func FromEnv(s store.AlertStore) (*Limiter, error) {
+	l := &Limiter{store: s, windows: map[string]time.Duration{}}
+	def, err := parseWindow("NOTIFY_THROTTLE")
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range channels {
+		l.windows[ch] = def
+		key := "NOTIFY_THROTTLE_" + strings.ToUpper(ch)
+		if os.Getenv(key) == "" {
+			continue
+		}
+		if l.windows[ch], err = parseWindow(key); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func parseWindow(key string) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: want a duration like 5m", key, v)
+	}
+	return d, nil
+}
+
+// Window is the channel's throttle window; 0 when it isn't throttled
+func (l *Limiter) Window(channel string) time.Duration {
+	if l == nil {
+		return 0
+	}
+	return l.windows[channel]
+}
+
+// Allow reports whether a notification about a may go out on channel. When
+// it may not, the held-back send is counted on the alert. Firing and resolved
+// notifications are limited separately, so a flap still reports its latest
+// recovery once per window. Errors let the notification through.
+func (l *Limiter) Allow(ctx context.Context, channel string, a models.Alert) bool {
+	window := l.Window(channel)
+	if window <= 0 {
+		return true
+	}
+	state := "firing"
+	if a.Status == models.AlertStatusResolved {
+		state = "resolved"
+	}
+	ok, err := l.store.ClaimNotification(ctx, channel, Key(a)+":"+state, window)
+	if err != nil {
+		log.Printf("floodcontrol: failed to check %s throttle for alert %d, sending: %v", channel, a.ID, err)
+		return true
+	}
+	if ok {
+		return true
+	}
+	if a.ID != 0 {
+		if err := l.store.CountSuppressedNotification(ctx, a.ID); err != nil {
+			log.Printf("floodcontrol: failed to count suppressed %s notification for alert %d: %v", channel, a.ID, err)
+		}
+	}
+	return false
+}
+
+// Key identifies the check an alert comes from: its fingerprint, or for
+// alerts without one a hash of the source and title, which repeat on every
+// flap
+func Key(a models.Alert) string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	sum := sha256.Sum256([]byte(a.Source + "\x00" + a.Title))
+	return hex.EncodeToString(sum[:12])
+}
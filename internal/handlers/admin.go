@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,6 +11,9 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
 )
 
 var (
@@ -45,6 +50,10 @@ func allowBotToken(token string, limit int) bool {
 // === User Management ===
 
 func (h *Handler) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
 	users, err := h.AdminStore.GetUsers(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to get users", http.StatusInternalServerError)
@@ -57,12 +66,29 @@ func (h *Handler) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 		Name   string `json:"name"`
 		BotID  int    `json:"bot_id"`
 	}
+	type teamView struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	userTeams := map[int][]teamView{}
+	if teams, err := h.AdminStore.GetTeams(r.Context()); err == nil {
+		for _, t := range teams {
+			for _, userID := range t.UserIDs {
+				userTeams[userID] = append(userTeams[userID], teamView{ID: t.ID, Name: t.Name})
+			}
+		}
+	}
 
 	respUsers := make([]map[string]any, 0, len(users))
 	for _, u := range users {
+		if !scope.has(u.OrgID) {
+			continue
+		}
+		// The chats given to the user themselves; those of their teams are
+		// the teams'
 		chats := []chatView{}
 		if u.Role != "admin" && u.Role != "developer" {
-			if assigned, err := h.AdminStore.GetUserChats(r.Context(), u.ID); err == nil {
+			if assigned, err := h.AdminStore.GetUserAssignedChats(r.Context(), u.ID); err == nil {
 				for _, c := range assigned {
 					chats = append(chats, chatView{
 						ID:     c.ID,
@@ -73,14 +99,27 @@ func (h *Handler) GetUsersHandler(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		}
+		var lockedUntil *time.Time
+		if until, err := h.AlertStore.LoginLockedUntil(r.Context(), u.ID); err == nil && until.After(time.Now()) {
+			lockedUntil = &until
+		}
+		teams := userTeams[u.ID]
+		if teams == nil {
+			teams = []teamView{}
+		}
 		respUsers = append(respUsers, map[string]any{
 			"id":            u.ID,
 			"username":      u.Username,
 			"role":          u.Role,
 			"totp_enabled":  u.TOTPEnabled,
 			"chats":         chats,
+			"teams":         teams,
 			"created_at":    u.CreatedAt,
 			"last_password": u.LastPasswordChange,
+			"locked_until":  lockedUntil,
+			"email":         u.Email,
+			"org_id":        u.OrgID,
+			"saml_linked":   u.SAMLLinked,
 		})
 	}
 
@@ -94,27 +133,39 @@ func (h *Handler) CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		Password string `json:"password"`
 		Role     string `json:"role"`
 		ChatIDs  []int  `json:"chat_ids"` // New: chat permissions
+		OrgID    int    `json:"org_id"`   // The admin's own when 0
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	orgID, ok := h.newMemberOrg(w, r, req.OrgID)
+	if !ok {
+		return
+	}
 
 	// Validate role
 	if req.Role != "admin" && req.Role != "developer" && req.Role != "user" {
 		http.Error(w, "Invalid role", http.StatusBadRequest)
 		return
 	}
+	if err := h.Passwords.Validate(req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Role != "admin" && !h.checkOrgChats(w, r, orgID, req.ChatIDs) {
+		return
+	}
 
-	user, err := h.AdminStore.CreateUser(r.Context(), req.Username, req.Password, req.Role)
+	user, err := h.AdminStore.CreateUser(r.Context(), req.Username, req.Password, req.Role, orgID)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
-		meta, _ := json.Marshal(map[string]any{"username": req.Username, "role": req.Role, "chat_ids": req.ChatIDs})
+		meta, _ := json.Marshal(map[string]any{"username": req.Username, "role": req.Role, "chat_ids": req.ChatIDs, "org_id": orgID})
 		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_user", "user", user.ID, string(meta))
 	}
 
@@ -154,15 +205,26 @@ func (h *Handler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid role", http.StatusBadRequest)
 		return
 	}
+	user, ok := h.scopedUser(w, r, id)
+	if !ok {
+		return
+	}
+	if req.Role != "admin" && !h.checkOrgChats(w, r, user.OrgID, req.ChatIDs) {
+		return
+	}
 
 	if err := h.AdminStore.UpdateUser(r.Context(), id, req.Username, req.Role); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	// Signed-in sessions get the new role on their next request
+	if err := sessionStore.UpdateUserSessions(r.Context(), id, req.Username, req.Role); err != nil {
+		log.Println("Failed to update sessions:", err)
+	}
 
 	// Manage chat assignments for non-admin roles
 	if req.Role != "admin" && len(req.ChatIDs) > 0 {
-		currentChats, _ := h.AdminStore.GetUserChats(r.Context(), id)
+		currentChats, _ := h.AdminStore.GetUserAssignedChats(r.Context(), id)
 		desired := make(map[int]struct{})
 		for _, cid := range req.ChatIDs {
 			desired[cid] = struct{}{}
@@ -196,56 +258,134 @@ func (h *Handler) DeleteUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.AdminStore.DeleteUser(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	actorID, _, _ := GetCurrentUser(r)
+	if actorID == id {
+		http.Error(w, "You cannot delete your own account", http.StatusConflict)
+		return
+	}
+	reassignTo, ok := reassignParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.scopedUser(w, r, id); !ok {
 		return
 	}
 
-	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
-		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_user", "user", id, "{}")
+	if err := h.AdminStore.DeleteUser(r.Context(), id, reassignTo); err != nil {
+		writeDeleteError(w, err)
+		return
+	}
+	if _, err := sessionStore.RevokeUserSessions(r.Context(), id, ""); err != nil {
+		log.Println("Failed to end sessions:", err)
+	}
+
+	if actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"reassign_to": reassignTo})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_user", "user", id, string(meta))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"success": true})
 }
 
+// RevokeUserSessionsHandler signs a user out everywhere. Access tokens from
+// token login run until they expire.
+// DELETE /api/admin/users/{id}/sessions
+func (h *Handler) RevokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/sessions"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedUser(w, r, id); !ok {
+		return
+	}
+	n, err := sessionStore.RevokeUserSessions(r.Context(), id, "")
+	if err != nil {
+		http.Error(w, "Failed to end sessions", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"session_count": n})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "revoke_sessions", "user", id, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "revoked": n})
+}
+
 // === Bot Management ===
 
 func (h *Handler) GetBotsHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
 	bots, err := h.AdminStore.GetBots(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to get bots", http.StatusInternalServerError)
 		return
 	}
+	visible := bots[:0:0]
+	for _, b := range bots {
+		if scope.has(b.OrgID) {
+			visible = append(visible, b)
+		}
+	}
+	bots = visible
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"bots": bots})
 }
 
+// CreateBotHandler adds a bot, to the admin's organization unless org_id
+// says otherwise. With "signed" it also gets a signing secret, shown in this
+// answer only, that its requests must then be signed with.
+// POST /api/admin/bots {"name": "...", "signed": true, "org_id": 2}
 func (h *Handler) CreateBotHandler(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		Name string `json:"name"`
+		Name   string `json:"name"`
+		Signed bool   `json:"signed"`
+		OrgID  int    `json:"org_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	orgID, ok := h.newMemberOrg(w, r, req.OrgID)
+	if !ok {
+		return
+	}
+
+	var secret string
+	if req.Signed {
+		var err error
+		if secret, err = models.GenerateToken(); err != nil {
+			http.Error(w, "Failed to generate signing secret", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	userID, _, _ := GetCurrentUser(r)
-	bot, err := h.AdminStore.CreateBot(r.Context(), req.Name, userID)
+	bot, err := h.AdminStore.CreateBot(r.Context(), req.Name, userID, orgID, secret)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	if userID != 0 {
-		meta, _ := json.Marshal(map[string]any{"name": req.Name})
+		meta, _ := json.Marshal(map[string]any{"name": req.Name, "signed": bot.Signed, "org_id": orgID})
 		_ = h.AdminStore.InsertAudit(r.Context(), userID, "create_bot", "bot", bot.ID, string(meta))
 	}
 
+	resp := map[string]any{"success": true, "bot": bot}
+	if bot.Signed {
+		resp["signing_secret"] = bot.SigningSecret
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{"success": true, "bot": bot})
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *Handler) DeleteBotHandler(w http.ResponseWriter, r *http.Request) {
@@ -256,27 +396,307 @@ func (h *Handler) DeleteBotHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.AdminStore.DeleteBot(r.Context(), id); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	reassignTo, ok := reassignParam(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := h.scopedBot(w, r, id); !ok {
 		return
 	}
 
+	if err := h.AdminStore.DeleteBot(r.Context(), id, reassignTo); err != nil {
+		writeDeleteError(w, err)
+		return
+	}
+	if err := h.AlertStore.ForgetAPIUsage(r.Context(), models.APIUsage{Kind: models.CredentialBot, ID: id}.Credential()); err != nil {
+		log.Printf("Failed to drop usage of bot %d: %v", id, err)
+	}
+
 	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
-		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_bot", "bot", id, "{}")
+		meta, _ := json.Marshal(map[string]any{"reassign_to": reassignTo})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_bot", "bot", id, string(meta))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"success": true})
 }
 
+// BotSigningSecretHandler gives a bot a new signing secret, which is shown in
+// this answer only, or with DELETE lets it post unsigned again. The old
+// secret stops working right away.
+// PUT/DELETE /api/admin/bots/{id}/secret
+func (h *Handler) BotSigningSecretHandler(w http.ResponseWriter, r *http.Request) {
+	idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/bots/"), "/secret")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedBot(w, r, id); !ok {
+		return
+	}
+
+	var secret, action string
+	switch r.Method {
+	case http.MethodPut:
+		if secret, err = models.GenerateToken(); err != nil {
+			http.Error(w, "Failed to generate signing secret", http.StatusInternalServerError)
+			return
+		}
+		action = "rotate_bot_secret"
+	case http.MethodDelete:
+		action = "clear_bot_secret"
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := h.AdminStore.SetBotSigningSecret(r.Context(), id, secret); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, action, "bot", id, "{}")
+	}
+
+	resp := map[string]any{"success": true, "signed": secret != ""}
+	if secret != "" {
+		resp["signing_secret"] = secret
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// BotAllowedIPsHandler sets the addresses a bot may post from, on top of the
+// allowlists in the environment; an empty list lets it post from anywhere
+// PUT /api/admin/bots/{id}/allowed-ips {"allowed_ips": ["203.0.113.0/24"]}
+func (h *Handler) BotAllowedIPsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/bots/"), "/allowed-ips")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedBot(w, r, id); !ok {
+		return
+	}
+	var req struct {
+		AllowedIPs []string `json:"allowed_ips"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	prefixes, err := ParseCIDRs(strings.Join(req.AllowedIPs, ","))
+	if err != nil {
+		http.Error(w, "allowed_ips: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	cidrs := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		cidrs[i] = p.String()
+	}
+
+	if err := h.AdminStore.SetBotAllowedIPs(r.Context(), id, cidrs); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"allowed_ips": cidrs})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_bot_allowed_ips", "bot", id, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "allowed_ips": cidrs})
+}
+
+// defaultBotTokenGrace and maxBotTokenGrace bound how long a bot's token
+// keeps working after a rotation
+const (
+	defaultBotTokenGrace = 24 * time.Hour
+	maxBotTokenGrace     = 30 * 24 * time.Hour
+)
+
+// RotateBotTokenHandler gives a bot a new token, keeping its chats. The old
+// token keeps working for grace_seconds (default a day), so integrations can
+// move over; 0 revokes it at once.
+// POST /api/admin/bots/{id}/rotate {"grace_seconds": 3600}
+func (h *Handler) RotateBotTokenHandler(w http.ResponseWriter, r *http.Request) {
+	idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/bots/"), "/rotate")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedBot(w, r, id); !ok {
+		return
+	}
+
+	var req struct {
+		GraceSeconds *int `json:"grace_seconds"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+	grace := defaultBotTokenGrace
+	if req.GraceSeconds != nil {
+		grace = time.Duration(*req.GraceSeconds) * time.Second
+		if grace < 0 || grace > maxBotTokenGrace {
+			http.Error(w, fmt.Sprintf("grace_seconds must be between 0 and %d", int(maxBotTokenGrace.Seconds())), http.StatusBadRequest)
+			return
+		}
+	}
+
+	bot, err := h.AdminStore.RotateBotToken(r.Context(), id, grace)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"grace_seconds": int(grace.Seconds())})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "rotate_bot_token", "bot", id, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "bot": bot})
+}
+
+// botMayPost reports whether the bot may post into the chat with ID chatID
+// (the chat's chat_id, as bots send it)
+func (h *Handler) botMayPost(ctx context.Context, botID int, chatID string) (bool, error) {
+	chats, err := h.AdminStore.GetBotChats(ctx, botID)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range chats {
+		if c.ChatID == chatID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// BotChatsHandler shows and sets the chats a bot may post into. A bot always
+// posts into its own chats; PUT sets the others it is assigned.
+// GET/PUT /api/admin/bots/{id}/chats {"chat_ids": [1, 2]}
+func (h *Handler) BotChatsHandler(w http.ResponseWriter, r *http.Request) {
+	idStr, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/bots/"), "/chats")
+	id, err := strconv.Atoi(idStr)
+	if !ok || err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	bot, ok := h.scopedBot(w, r, id)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			ChatIDs []int `json:"chat_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if !h.checkOrgChats(w, r, bot.OrgID, req.ChatIDs) {
+			return
+		}
+		current, err := h.AdminStore.GetBotChats(r.Context(), id)
+		if err != nil {
+			http.Error(w, "Failed to get chats", http.StatusInternalServerError)
+			return
+		}
+		desired := make(map[int]struct{})
+		for _, cid := range req.ChatIDs {
+			desired[cid] = struct{}{}
+		}
+		// Remove missing; the bot's own chats aren't assignments
+		for _, chat := range current {
+			if _, ok := desired[chat.ID]; !ok && chat.BotID != id {
+				if err := h.AdminStore.RemoveChatFromBot(r.Context(), id, chat.ID); err != nil {
+					http.Error(w, "Failed to update chats", http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+		// Add new
+		for cid := range desired {
+			if err := h.AdminStore.AssignChatToBot(r.Context(), id, cid); err != nil {
+				http.Error(w, fmt.Sprintf("Failed to assign chat %d", cid), http.StatusBadRequest)
+				return
+			}
+		}
+		if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+			meta, _ := json.Marshal(map[string]any{"chat_ids": req.ChatIDs})
+			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_bot_chats", "bot", id, string(meta))
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	chats, err := h.AdminStore.GetBotChats(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to get chats", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"chats": chats})
+}
+
+// reassignParam reads the optional ?reassign_to= ID that takes over a deleted
+// user's or bot's dependents
+func reassignParam(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("reassign_to")
+	if v == "" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(v)
+	if err != nil || id <= 0 {
+		http.Error(w, "Invalid reassign_to", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// writeDeleteError reports a refused delete as 409 so the caller can reassign
+// dependents and retry
+func writeDeleteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, store.ErrInUse):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, store.ErrInvalidReassign):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // === Chat Management ===
 
 func (h *Handler) GetChatsHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
 	chats, err := h.AdminStore.GetChats(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to get chats", http.StatusInternalServerError)
 		return
 	}
+	chats = orgChats(chats, scope.filter())
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"chats": chats})
@@ -292,6 +712,10 @@ func (h *Handler) CreateChatHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
+	// The chat goes in the bot's organization
+	if _, ok := h.scopedBot(w, r, req.BotID); !ok {
+		return
+	}
 
 	// Auto-generate unique chat ID
 	chatID := fmt.Sprintf("chat_%d_%d", req.BotID, time.Now().UnixNano())
@@ -318,6 +742,14 @@ func (h *Handler) DeleteChatHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
+	if chat, err := h.AdminStore.GetChat(r.Context(), id); err != nil || !scope.has(chat.OrgID) {
+		http.Error(w, "Chat not found", http.StatusNotFound)
+		return
+	}
 
 	if err := h.AdminStore.DeleteChat(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -332,49 +764,95 @@ func (h *Handler) DeleteChatHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]any{"success": true})
 }
 
-// Audit listing
+// GetAuditLogs lists audit entries newest first, filtered by actor (ID or
+// username), action, target type, time and, for admins of the default
+// organization, organization; other admins see their organization's. A full
+// page comes with next_before, the before of the page after it.
+// GET /api/admin/audit?limit=50&actor_id=3&actor=alice&action=delete_user&target_type=user&since=...&until=...&before=1234&org_id=2
 func (h *Handler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
-	limit := 50
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil && v > 0 {
-			limit = v
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	filter := models.AuditFilter{
+		Actor:      q.Get("actor"),
+		Action:     q.Get("action"),
+		TargetType: q.Get("target_type"),
+		Limit:      50,
+	}
+	for param, n := range map[string]*int{"limit": &filter.Limit, "actor_id": &filter.ActorID, "before": &filter.Before, "org_id": &filter.OrgID} {
+		if v := q.Get(param); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 1 {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*n = parsed
+		}
+	}
+	filter.Limit = min(filter.Limit, store.MaxAuditPage)
+	if !scope.all() {
+		filter.OrgID = scope.org
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+param+": use RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
 		}
 	}
-	logs, err := h.AdminStore.ListAudit(r.Context(), limit)
+
+	logs, err := h.AdminStore.ListAudit(r.Context(), filter)
 	if err != nil {
 		http.Error(w, "Failed to load audit logs", http.StatusInternalServerError)
 		return
 	}
 
+	resp := map[string]any{"logs": logs}
+	if len(logs) == filter.Limit {
+		resp["next_before"] = logs[len(logs)-1].ID
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"logs": logs,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 // === Bot Webhook Handler ===
 
+// botToken extracts the token from a /bot/{token}/sendMessage path
+func botToken(path string) (string, bool) {
+	return strings.CutSuffix(strings.TrimPrefix(path, "/bot/"), "/sendMessage")
+}
+
+// BotSigningSecret is the secret hmacMiddleware checks a bot request's
+// signature with: the signing secret of the bot in the path. Bots without
+// one, and unknown tokens, which BotWebhookHandler turns away, get "".
+func (h *Handler) BotSigningSecret(r *http.Request) string {
+	token, ok := botToken(r.URL.Path)
+	if !ok || token == "" {
+		return ""
+	}
+	bot, err := h.AdminStore.GetBotByToken(r.Context(), token)
+	if err != nil {
+		return ""
+	}
+	return bot.SigningSecret
+}
+
 func (h *Handler) BotWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Extract token from path: /bot/{token}/sendMessage
-	path := r.URL.Path
-
-	// Remove /bot/ prefix to get token/sendMessage
-	path = strings.TrimPrefix(path, "/bot/")
-
-	// Check if it ends with /sendMessage
-	if !strings.HasSuffix(path, "/sendMessage") {
+	token, ok := botToken(r.URL.Path)
+	if !ok {
 		http.Error(w, "Invalid path - must end with /sendMessage", http.StatusNotFound)
 		return
 	}
-
-	// Get token between start and /sendMessage
-	token := strings.TrimSuffix(path, "/sendMessage")
-
 	if token == "" {
 		http.Error(w, "Missing bot token", http.StatusBadRequest)
 		return
@@ -387,8 +865,18 @@ func (h *Handler) BotWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid bot token", http.StatusUnauthorized)
 		return
 	}
+	noteUsage(r.Context(), models.CredentialBot, bot.ID)
 
-	if !allowBotToken(token, bot.RateLimit) {
+	if len(bot.AllowedIPs) > 0 {
+		prefixes, _ := ParseCIDRs(strings.Join(bot.AllowedIPs, ","))
+		if addr := h.Allowlist.ClientIP(r); !addr.IsValid() || !ipAllowed(prefixes, addr) {
+			http.Error(w, "address not allowed for this bot", http.StatusForbidden)
+			return
+		}
+	}
+
+	// By the current token, so the old and new one share a limit
+	if !allowBotToken(bot.TokenHash, bot.RateLimit) {
 		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
 		return
 	}
@@ -405,6 +893,16 @@ func (h *Handler) BotWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "chat_id required", http.StatusBadRequest)
 		return
 	}
+	allowed, err := h.botMayPost(r.Context(), bot.ID, chatID)
+	if err != nil {
+		log.Printf("Failed to get chats of bot %d: %v", bot.ID, err)
+		http.Error(w, "Failed to check chat", http.StatusServiceUnavailable)
+		return
+	}
+	if !allowed {
+		http.Error(w, "chat_id is not assigned to this bot", http.StatusForbidden)
+		return
+	}
 
 	title := getString(payload["title"])
 	if title == "" {
@@ -423,17 +921,11 @@ func (h *Handler) BotWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		msg = string(buf)
 	}
 
-	level := strings.ToLower(getString(payload["level"]))
+	level := models.NormalizeLevel(getString(payload["level"]))
 	if level == "" {
 		level = "info"
 	}
 
-	// Signature validation with per-bot secret
-	if !validateSignature(r, bot.HMACSecret, r.Header.Get("X-Sentinel-Signature")) {
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
-		return
-	}
-
 	// Create alert with chat_id in source for filtering
 	source := fmt.Sprintf("bot:%s:chat:%s", bot.Name, chatID)
 	alert, err := h.AlertStore.AddAlert(r.Context(), source, level, title, msg)
@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+const (
+	defaultAgingUnacked = time.Hour
+	defaultAgingOpen    = 24 * time.Hour
+	defaultAgingNudge   = 9 // Hour of day (UTC) the nudges go out
+
+	agingCheckInterval = 15 * time.Minute
+	agingNudgeListed   = 3 // Alert titles named in a nudge
+)
+
+// AgingThresholds decide when an open alert counts as aging: left
+// unacknowledged for Unacked, or still open after Open
+type AgingThresholds struct {
+	Unacked   time.Duration
+	Open      time.Duration
+	NudgeHour int // -1 disables the daily nudges
+}
+
+// AgingThresholdsFromEnv reads ALERT_AGING_UNACKED (default 1h),
+// ALERT_AGING_OPEN (default 24h) and ALERT_AGING_NUDGE_HOUR (0-23 UTC,
+// default 9; "off" disables the nudges)
+func AgingThresholdsFromEnv() (AgingThresholds, error) {
+	t := AgingThresholds{Unacked: defaultAgingUnacked, Open: defaultAgingOpen, NudgeHour: defaultAgingNudge}
+	for key, d := range map[string]*time.Duration{"ALERT_AGING_UNACKED": &t.Unacked, "ALERT_AGING_OPEN": &t.Open} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			return t, fmt.Errorf("invalid %s %q: use a duration like 12h", key, v)
+		}
+		*d = parsed
+	}
+	switch v := os.Getenv("ALERT_AGING_NUDGE_HOUR"); v {
+	case "":
+	case "off":
+		t.NudgeHour = -1
+	default:
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 23 {
+			return t, fmt.Errorf("invalid ALERT_AGING_NUDGE_HOUR %q: use 0-23 or off", v)
+		}
+		t.NudgeHour = n
+	}
+	return t, nil
+}
+
+// thresholds fills in the defaults for a Handler built without
+// AgingThresholdsFromEnv
+func (t AgingThresholds) thresholds() AgingThresholds {
+	if t.Unacked <= 0 {
+		t.Unacked = defaultAgingUnacked
+	}
+	if t.Open <= 0 {
+		t.Open = defaultAgingOpen
+	}
+	return t
+}
+
+// agingAlert is an aging alert with why it's listed and what to do about it
+type agingAlert struct {
+	models.Alert
+	Age        string `json:"age"`
+	Reason     string `json:"reason"` // "unacked" or "stale"
+	Owner      string `json:"owner,omitempty"`
+	Suggestion string `json:"suggestion"`
+}
+
+// agingGroup is one owner's or chat's aging alerts, oldest first
+type agingGroup struct {
+	Key     string       `json:"key"` // Username, chat_id, or "" for unowned
+	Name    string       `json:"name"`
+	Unacked int          `json:"unacked"`
+	Stale   int          `json:"stale"`
+	Alerts  []agingAlert `json:"alerts"`
+}
+
+// alertOwner is who is on an alert: its incident commander, else the first
+// user to react with 👀
+func alertOwner(a models.Alert) string {
+	if c := a.Roles[models.RoleCommander]; c != "" {
+		return c
+	}
+	if looking := a.Reactions[models.ReactionLooking]; len(looking) > 0 {
+		return looking[0]
+	}
+	return ""
+}
+
+// agingAlerts picks the open alerts past a threshold out of alerts, oldest
+// first
+func agingAlerts(alerts []models.Alert, t AgingThresholds, now time.Time) []agingAlert {
+	var out []agingAlert
+	for _, a := range alerts {
+		if a.Status != models.AlertStatusOpen {
+			continue // Resolved, or without a lifecycle to resolve
+		}
+		age := now.Sub(a.CreatedAt)
+		aa := agingAlert{Alert: a, Age: age.Round(time.Minute).String(), Owner: alertOwner(a)}
+		switch {
+		case age >= t.Open:
+			aa.Reason = "stale"
+			switch {
+			case age >= 7*t.Open && len(a.Comments) == 0 && aa.Owner == "":
+				aa.Suggestion = "Nobody has touched it: likely noise, resolve it and tune the source"
+			case aa.Owner == "":
+				aa.Suggestion = "Resolve it if it's over, or take it with 👀"
+			default:
+				aa.Suggestion = "Resolve it if it's over, or hand it over"
+			}
+		case a.AckedAt == nil && age >= t.Unacked:
+			aa.Reason = "unacked"
+			aa.Suggestion = "Acknowledge it with 👀 or resolve it"
+		default:
+			continue
+		}
+		out = append(out, aa)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// groupAging groups aging alerts by owner or by chat, in the order of their
+// oldest alert; chatNames names the chats
+func groupAging(aging []agingAlert, byOwner bool, chatNames map[string]string) []agingGroup {
+	index := map[string]int{}
+	var groups []agingGroup
+	for _, aa := range aging {
+		key, name := aa.Owner, aa.Owner
+		if byOwner && key == "" {
+			name = "Unowned"
+		}
+		if !byOwner {
+			key = models.ChatIDFromSource(aa.Source)
+			if key == "" {
+				key = "general"
+			}
+			name = chatNames[key]
+			if name == "" {
+				name = key
+			}
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, agingGroup{Key: key, Name: name})
+		}
+		g := &groups[i]
+		if aa.Reason == "stale" {
+			g.Stale++
+		} else {
+			g.Unacked++
+		}
+		g.Alerts = append(g.Alerts, aa)
+	}
+	return groups
+}
+
+// AlertAgingHandler lists open alerts that have gone unacknowledged or
+// unresolved too long, grouped by chat or owner, in the chats the user can see
+// GET /api/summary/aging?group_by=chat|owner&unacked_after=1h&open_after=24h&chat_id=...
+func (h *Handler) AlertAgingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, role := GetCurrentUser(r)
+
+	t := h.Aging.thresholds()
+	for param, d := range map[string]*time.Duration{"unacked_after": &t.Unacked, "open_after": &t.Open} {
+		v := r.URL.Query().Get(param)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil || parsed <= 0 {
+			http.Error(w, fmt.Sprintf("Invalid %s: use a duration like 12h", param), http.StatusBadRequest)
+			return
+		}
+		*d = parsed
+	}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "chat"
+	}
+	if groupBy != "chat" && groupBy != "owner" {
+		http.Error(w, "Invalid group_by: use chat or owner", http.StatusBadRequest)
+		return
+	}
+
+	chats, err := h.reportChats(r.Context(), userID, role)
+	if err != nil {
+		http.Error(w, "Failed to get chats", http.StatusInternalServerError)
+		return
+	}
+	chatNames := map[string]string{}
+	for _, c := range chats {
+		chatNames[c.ChatID] = c.Name
+	}
+	only := r.URL.Query().Get("chat_id")
+	if only != "" && chatNames[only] == "" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	alerts, err := h.AlertStore.GetAlerts(r.Context())
+	if err != nil {
+		log.Println("Failed to get alerts:", err)
+		http.Error(w, "Failed to get alerts", http.StatusInternalServerError)
+		return
+	}
+	visible := alerts[:0:0]
+	for _, a := range alerts {
+		chatID := models.ChatIDFromSource(a.Source)
+		if chatID == "" {
+			chatID = "general"
+		}
+		if chatNames[chatID] != "" && (only == "" || chatID == only) {
+			visible = append(visible, a)
+		}
+	}
+
+	groups := groupAging(agingAlerts(visible, t, time.Now()), groupBy == "owner", chatNames)
+	if groups == nil {
+		groups = []agingGroup{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"unacked_after": t.Unacked.String(),
+		"open_after":    t.Open.String(),
+		"group_by":      groupBy,
+		"groups":        groups,
+	})
+}
+
+// RunAgingNudges sends a daily push at the configured hour: owners about
+// their aging alerts, and each chat's subscribers about its unowned ones
+func (h *Handler) RunAgingNudges(ctx context.Context) {
+	if h.Aging.NudgeHour < 0 {
+		return
+	}
+	ticker := time.NewTicker(agingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now().UTC()
+		if now.Hour() == h.Aging.NudgeHour {
+			// One replica nudges per day
+			first, err := h.AlertStore.ClaimNonce(ctx, "aging-nudge:"+now.Format(time.DateOnly), 48*time.Hour)
+			if err != nil {
+				log.Printf("Failed to claim aging nudge: %v", err)
+			} else if first {
+				h.sendAgingNudges(ctx, now)
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) sendAgingNudges(ctx context.Context, now time.Time) {
+	alerts, err := h.AlertStore.GetAlerts(ctx)
+	if err != nil {
+		log.Printf("Failed to check aging alerts: %v", err)
+		return
+	}
+	aging := agingAlerts(alerts, h.Aging.thresholds(), now)
+
+	var unowned []agingAlert
+	for _, g := range groupAging(aging, true, nil) {
+		if g.Key == "" {
+			unowned = g.Alerts
+			continue
+		}
+		user, err := h.AdminStore.GetUserByUsername(ctx, g.Key)
+		if err != nil {
+			continue // Owner by a name that's no user (e.g. from a ticket system)
+		}
+		h.SendUserPushNotification(user.ID, agingNudge(fmt.Sprintf("You have %d aging alert(s)", len(g.Alerts)), g.Alerts))
+	}
+
+	chatNames := map[string]string{"general": "General"}
+	if chats, err := h.AdminStore.GetChats(ctx); err == nil {
+		for _, c := range chats {
+			chatNames[c.ChatID] = c.Name
+		}
+	}
+	for _, g := range groupAging(unowned, false, chatNames) {
+		chatID := g.Key
+		if chatID == "general" {
+			chatID = ""
+		}
+		h.sendChatPushNotification(chatID, agingNudge(fmt.Sprintf("%d aging alert(s) nobody owns in %s", len(g.Alerts), g.Name), g.Alerts))
+	}
+}
+
+// agingNudge is the push text: a headline and the oldest alerts' titles
+func agingNudge(headline string, alerts []agingAlert) string {
+	titles := make([]string, 0, agingNudgeListed)
+	for _, aa := range alerts[:min(len(alerts), agingNudgeListed)] {
+		titles = append(titles, fmt.Sprintf("%s (%s)", aa.Title, aa.Age))
+	}
+	msg := "🧹 " + headline + ": " + strings.Join(titles, "; ")
+	if more := len(alerts) - len(titles); more > 0 {
+		msg += fmt.Sprintf(" and %d more", more)
+	}
+	return msg
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// allowlistEnvPrefix names the per-integration allowlists:
+// WEBHOOK_ALLOWED_IPS_GITHUB applies to the "github" endpoint
+const allowlistEnvPrefix = "WEBHOOK_ALLOWED_IPS_"
+
+// IPAllowlist limits which addresses may call the ingestion endpoints, as a
+// second line of defense behind tokens and signatures (which some senders,
+// like Gatus, can't do). An integration's own list takes the place of the
+// global one; without either, any address may call.
+type IPAllowlist struct {
+	global         []netip.Prefix
+	integrations   map[string][]netip.Prefix
+	trustedProxies []netip.Prefix
+}
+
+// IPAllowlistFromEnv reads WEBHOOK_ALLOWED_IPS, WEBHOOK_ALLOWED_IPS_{NAME}
+// for the integration NAME (as in the ingestion metrics, e.g. BOT, GITHUB)
+// and TRUSTED_PROXIES, each a comma separated list of CIDRs or addresses.
+// Requests from a trusted proxy are judged by the address it forwarded them
+// for, in X-Forwarded-For; bots' own allowlists go by it too. With none of
+// them set it returns nil.
+func IPAllowlistFromEnv() (*IPAllowlist, error) {
+	a := &IPAllowlist{integrations: map[string][]netip.Prefix{}}
+	var err error
+	if a.global, err = ParseCIDRs(os.Getenv("WEBHOOK_ALLOWED_IPS")); err != nil {
+		return nil, fmt.Errorf("WEBHOOK_ALLOWED_IPS: %w", err)
+	}
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, allowlistEnvPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		prefixes, err := ParseCIDRs(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		a.integrations[strings.ToLower(name)] = prefixes
+	}
+	if a.trustedProxies, err = ParseCIDRs(os.Getenv("TRUSTED_PROXIES")); err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	if len(a.global) == 0 && len(a.integrations) == 0 && len(a.trustedProxies) == 0 {
+		return nil, nil
+	}
+	return a, nil
+}
+
+// ParseCIDRs parses a comma separated list of CIDRs; a bare address stands
+// for itself
+func ParseCIDRs(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if !strings.Contains(part, "/") {
+			addr, err := netip.ParseAddr(part)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not an address or CIDR", part)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(part)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an address or CIDR", part)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// ipAllowed reports whether addr is in one of prefixes; an empty list
+// allows every address
+func ipAllowed(prefixes []netip.Prefix, addr netip.Addr) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP is the address a request came from: the peer or, when the peer
+// is a trusted proxy, the last address in X-Forwarded-For that isn't one
+func (a *IPAllowlist) ClientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	addr = addr.Unmap()
+	if a == nil || len(a.trustedProxies) == 0 || !ipAllowed(a.trustedProxies, addr) {
+		return addr
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		addr = hop.Unmap()
+		if !ipAllowed(a.trustedProxies, addr) {
+			break
+		}
+	}
+	return addr
+}
+
+// Middleware turns away requests to the integration name from addresses its
+// allowlist, or the global one, doesn't have. A nil allowlist lets all in.
+func (a *IPAllowlist) Middleware(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if a == nil {
+			return next
+		}
+		prefixes, ok := a.integrations[name]
+		if !ok {
+			prefixes = a.global
+		}
+		if len(prefixes) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if addr := a.ClientIP(r); !addr.IsValid() || !ipAllowed(prefixes, addr) {
+				http.Error(w, "address not allowed", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// Limits on a single annotation, so automation can't bloat alerts
+const (
+	maxAnnotationFields = 20
+	maxAnnotationLinks  = 10
+	maxAnnotationText   = 16 << 10
+)
+
+// AlertAnnotationsHandler attaches structured context to an alert.
+// POST /api/alerts/{id}/annotations
+//
+//	{"title": "Deploy", "fields": {"sha": "1a2b3c"}, "links": [{"title": "Dashboard", "url": "https://..."}], "text": "..."}
+//
+// Meant for automation authenticated with an ingest token
+// (Authorization: Bearer), but signed-in users may annotate too.
+func (h *Handler) AlertAnnotationsHandler(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	author := ""
+	if t, ok := ingestTokenFromContext(r.Context()); ok {
+		author = t.Name
+	} else if userID, username, _ := GetCurrentUser(r); userID != 0 {
+		author = username
+	}
+	if author == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var an models.AlertAnnotation
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&an); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateAnnotation(&an); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	an.Author = author
+	an.CreatedAt = time.Now().UTC()
+
+	alert, err := h.AlertStore.AddAnnotation(r.Context(), id, an)
+	if err != nil {
+		writeAlertError(w, "Failed to add annotation", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "annotations": alert.Annotations})
+}
+
+func validateAnnotation(an *models.AlertAnnotation) error {
+	an.Title = strings.TrimSpace(an.Title)
+	if an.Title == "" {
+		return errors.New("title is required")
+	}
+	if an.Text == "" && len(an.Fields) == 0 && len(an.Links) == 0 {
+		return errors.New("annotation needs fields, links or text")
+	}
+	if len(an.Fields) > maxAnnotationFields {
+		return errors.New("too many fields")
+	}
+	if len(an.Links) > maxAnnotationLinks {
+		return errors.New("too many links")
+	}
+	if len(an.Text) > maxAnnotationText {
+		return errors.New("text too long")
+	}
+	for i, l := range an.Links {
+		// Links are rendered as anchors in the dashboard: only http(s)
+		u, err := url.Parse(l.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("links must be http(s) URLs")
+		}
+		if strings.TrimSpace(l.Title) == "" {
+			an.Links[i].Title = u.Host
+		}
+	}
+	return nil
+}
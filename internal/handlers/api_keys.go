@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+type apiKeyKey struct{}
+
+// apiKeyFromContext returns the API key a request authenticated with, if any
+func apiKeyFromContext(ctx context.Context) (models.APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey{}).(models.APIKey)
+	return k, ok
+}
+
+// APIKeyMiddleware authenticates "Authorization: Bearer snt_..." API keys.
+// Read and admin keys make the request act as the key's owner, as a session
+// would; read keys only for GET requests. Ingest keys only count on the
+// ingestion endpoints, through IngestTokenMiddleware. Other bearer tokens
+// are left to the endpoints that take them.
+func (h *Handler) APIKeyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !models.IsAPIKey(strings.TrimSpace(bearer)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		k, err := h.AdminStore.AuthenticateAPIKey(r.Context(), strings.TrimSpace(bearer))
+		if err != nil {
+			http.Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if k.Scope == models.APIKeyScopeRead && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "API key is read-only", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyKey{}, k)))
+	})
+}
+
+// apiKeyUser is the user a request's API key acts as; ingest keys act as
+// no one
+func apiKeyUser(r *http.Request) (models.APIKey, bool) {
+	k, ok := apiKeyFromContext(r.Context())
+	return k, ok && k.Scope != models.APIKeyScopeIngest
+}
+
+// === API Key Management ===
+
+// GetAPIKeysHandler lists the signed-in user's API keys
+// GET /api/user/apikeys
+func (h *Handler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	keys, err := h.AdminStore.GetAPIKeys(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get API keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"api_keys": keys})
+}
+
+// CreateAPIKeyHandler issues an API key for the signed-in user. Admin keys
+// are for admins only. The key is in the response and nowhere else.
+// POST /api/user/apikeys {"name": "ci", "scope": "ingest"}
+func (h *Handler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, role := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	// A key can't mint keys: a leaked one would otherwise outlive its revocation
+	if _, ok := apiKeyFromContext(r.Context()); ok {
+		http.Error(w, "API keys can't create API keys; sign in to create one", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.APIKeyScopes, req.Scope) {
+		http.Error(w, "scope must be one of "+strings.Join(models.APIKeyScopes, ", "), http.StatusBadRequest)
+		return
+	}
+	if req.Scope == models.APIKeyScopeAdmin && role != "admin" {
+		http.Error(w, "Only admins can create admin API keys", http.StatusForbidden)
+		return
+	}
+
+	k, key, err := h.AdminStore.CreateAPIKey(r.Context(), userID, req.Name, req.Scope)
+	if err != nil {
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	meta, _ := json.Marshal(map[string]any{"name": k.Name, "scope": k.Scope, "prefix": k.Prefix})
+	_ = h.AdminStore.InsertAudit(r.Context(), userID, "create_api_key", "api_key", k.ID, string(meta))
+
+	// Only the hash is kept, so this is the one chance to copy the key
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "key": key, "api_key": k})
+}
+
+// DeleteAPIKeyHandler revokes one of the signed-in user's API keys
+// DELETE /api/user/apikeys/{id}
+func (h *Handler) DeleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/user/apikeys/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteAPIKey(r.Context(), userID, id); err != nil {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+	_ = h.AdminStore.InsertAudit(r.Context(), userID, "delete_api_key", "api_key", id, "{}")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -1,21 +1,42 @@
 package handlers
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
 	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
 	"log"
 	"net/http"
-
-	"github.com/gorilla/sessions"
 )
 
 var (
-	sessionStore = sessions.NewCookieStore([]byte("secret-key-change-in-production"))
+	sessionStore *store.RedisSessions // Set by UseSessions
 	sessionName  = "sentinel-session"
 )
 
+// UseSessions keeps sessions in s. Call it before serving requests.
+func UseSessions(s *store.RedisSessions) {
+	sessionStore = s
+}
+
+// startSession signs user in on this browser and returns the session's CSRF
+// token. The session gets a new ID, so one planted before sign-in can't be
+// used to ride on it.
+func startSession(w http.ResponseWriter, r *http.Request, user models.User) string {
+	session, _ := sessionStore.Get(r, sessionName)
+	if err := sessionStore.Renew(r.Context(), session); err != nil {
+		log.Println("Failed to renew session:", err)
+	}
+	session.Values["user_id"] = user.ID
+	session.Values["username"] = user.Username
+	session.Values["role"] = user.Role
+	delete(session.Values, sessionPending2FA)
+	token := sessionCSRFToken(session)
+	if err := session.Save(r, w); err != nil {
+		log.Println("Failed to save session:", err)
+	}
+	return token
+}
+
 // LoginHandler handles admin login
 func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -39,30 +60,30 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
+	if h.loginLocked(w, r, user.ID) {
+		return
+	}
 
 	// Check password
 	if !user.CheckPassword(req.Password) {
+		h.loginFailed(r, user, "password")
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
+	if h.passwordExpired(w, r, user) {
+		return
+	}
+
 	// Check if 2FA is enabled
 	if user.TOTPEnabled {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{
-			"success":      true,
-			"requires_2fa": true,
-			"user_id":      user.ID,
-		})
+		h.requireSecondFactor(w, r, user)
 		return
 	}
 
 	// Create session
-	session, _ := sessionStore.Get(r, sessionName)
-	session.Values["user_id"] = user.ID
-	session.Values["username"] = user.Username
-	session.Values["role"] = user.Role
-	session.Save(r, w)
+	startSession(w, r, user)
+	h.loginSucceeded(r, user.ID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
@@ -89,25 +110,24 @@ func (h *Handler) VerifyAdmin2FAHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get user
-	user, err := h.AdminStore.GetUser(r.Context(), req.UserID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, ok := h.pendingSecondFactor(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if h.loginLocked(w, r, user.ID) {
 		return
 	}
 
 	// Verify code
 	if !models.VerifyTOTPCode(user.TOTPSecret, req.Code) {
+		h.loginFailed(r, user, "2fa")
 		http.Error(w, "Invalid verification code", http.StatusUnauthorized)
 		return
 	}
 
 	// Create session
-	session, _ := sessionStore.Get(r, sessionName)
-	session.Values["user_id"] = user.ID
-	session.Values["username"] = user.Username
-	session.Values["role"] = user.Role
-	session.Save(r, w)
+	startSession(w, r, user)
+	h.loginSucceeded(r, user.ID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
@@ -129,9 +149,7 @@ func (h *Handler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 // AuthMiddleware checks if user is authenticated
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := sessionStore.Get(r, sessionName)
-		userID, ok := session.Values["user_id"].(int)
-		if !ok || userID == 0 {
+		if userID, _, _ := GetCurrentUser(r); userID == 0 {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
@@ -142,9 +160,7 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 // AdminMiddleware checks if user is admin
 func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		session, _ := sessionStore.Get(r, sessionName)
-		role, ok := session.Values["role"].(string)
-		if !ok || role != "admin" {
+		if _, _, role := GetCurrentUser(r); role != "admin" {
 			http.Error(w, "Forbidden", http.StatusForbidden)
 			return
 		}
@@ -152,25 +168,21 @@ func AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// GetCurrentUser returns the current user from session
+// GetCurrentUser returns the current user from session, or from the access
+// token or API key the request carries. Read-only keys never act as an admin.
 func GetCurrentUser(r *http.Request) (int, string, string) {
+	if u, ok := jwtUserFromContext(r.Context()); ok {
+		return u.ID, u.Username, u.Role
+	}
+	if k, ok := apiKeyUser(r); ok {
+		if k.Scope == models.APIKeyScopeRead && k.Role == "admin" {
+			return k.UserID, k.Username, "user"
+		}
+		return k.UserID, k.Username, k.Role
+	}
 	session, _ := sessionStore.Get(r, sessionName)
 	userID, _ := session.Values["user_id"].(int)
 	username, _ := session.Values["username"].(string)
 	role, _ := session.Values["role"].(string)
 	return userID, username, role
 }
-
-// InitSession initializes a default admin user if none exists
-func (h *Handler) InitSession(ctx context.Context) {
-	users, err := h.AdminStore.GetUsers(ctx)
-	if err != nil || len(users) == 0 {
-		// Create default admin
-		user, err := h.AdminStore.CreateUser(ctx, "admin", "admin123", "admin")
-		if err != nil {
-			log.Println("Failed to create default admin:", err)
-		} else {
-			fmt.Printf("Created default admin user: %s / admin123\n", user.Username)
-		}
-	}
-}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// maxUnreadWindow caps how far back unread counts look, like SSE replay
+const maxUnreadWindow = maxSSEReplay
+
+// userChats lists the chats a user can see, as visibleChats does; on error
+// it lists none
+func (h *Handler) userChats(ctx context.Context, user models.User) []models.Chat {
+	chats, _ := h.visibleChats(ctx, user)
+	return chats
+}
+
+// chatSummaries is the allowed_chats list of the login and bootstrap responses
+func chatSummaries(chats []models.Chat) []any {
+	allowed := []any{}
+	for _, chat := range chats {
+		allowed = append(allowed, map[string]any{
+			"id":      chat.ID,
+			"chat_id": chat.ChatID,
+			"name":    chat.Name,
+			"bot_id":  chat.BotID,
+		})
+	}
+	return allowed
+}
+
+// BootstrapHandler returns everything the dashboard needs on load in one
+// call: the signed-in user (null when signed out), the chats they can see,
+// unread counts, their preferences, the VAPID key, which optional features
+// the server has and which feature flags are on for them.
+// GET /api/bootstrap?since=2024-01-01T00:00:00Z
+//
+// Unread counts are the alerts per chat ("general" for alerts not bound to
+// one) created after since, the client's last visit, looking back at most
+// 24h; they are only returned to signed-in users who pass since.
+func (h *Handler) BootstrapHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid since: use an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		since = t
+		if oldest := time.Now().Add(-maxUnreadWindow); since.Before(oldest) {
+			since = oldest
+		}
+	}
+
+	ctx, cancel := storeContext(r.Context())
+	defer cancel()
+
+	resp := map[string]any{
+		"user":             nil,
+		"vapid_public_key": vapidPublicKey,
+		"features": map[string]bool{
+			"sms":            h.SMS != nil,
+			"email":          h.Email != nil,
+			"tickets":        h.Tickets != nil,
+			"chaos":          h.Chaos != nil,
+			"sandbox":        h.Sandbox,
+			"password_reset": h.passwordResetAvailable(),
+		},
+	}
+
+	userID, _, _ := GetCurrentUser(r)
+	var user models.User
+	if userID != 0 {
+		var err error
+		if user, err = h.AdminStore.GetUser(ctx, userID); err != nil {
+			userID = 0 // Deleted since the session was issued
+		}
+	}
+	resp["flags"] = h.userFeatureFlags(ctx, userID, user.Role)
+	if userID == 0 {
+		// Signed out: the chat list the sidebar shows locked, the default
+		// organization's
+		chats, err := h.AdminStore.GetChats(ctx)
+		if err != nil {
+			http.Error(w, "Failed to get chats", http.StatusInternalServerError)
+			return
+		}
+		resp["chats"] = orgChats(chats, models.DefaultOrgID)
+		writeBootstrap(w, resp)
+		return
+	}
+
+	resp["user"] = map[string]any{
+		"id":           user.ID,
+		"username":     user.Username,
+		"role":         user.Role,
+		"totp_enabled": user.TOTPEnabled,
+	}
+	chats := h.userChats(ctx, user)
+	resp["allowed_chats"] = chatSummaries(chats)
+
+	pref, err := h.AdminStore.GetSMSPreference(ctx, userID)
+	if err != nil {
+		pref = models.SMSPreference{UserID: userID} // Not set up yet
+	}
+	resp["preferences"] = map[string]any{"sms": pref}
+
+	if !since.IsZero() {
+		resp["unread"] = h.unreadCounts(ctx, chats, user.OrgID == models.DefaultOrgID, since)
+	}
+	writeBootstrap(w, resp)
+}
+
+// unreadCounts counts the alerts since the given time in each of chats and,
+// with general, in General. A failed lookup leaves the counts empty rather
+// than failing the load.
+func (h *Handler) unreadCounts(ctx context.Context, chats []models.Chat, general bool, since time.Time) map[string]int {
+	unread := map[string]int{}
+	alerts, err := h.AlertStore.GetAlertsSince(ctx, since)
+	if err != nil {
+		return unread
+	}
+	visible := map[string]bool{"general": general}
+	for _, c := range chats {
+		visible[c.ChatID] = true
+	}
+	for _, a := range alerts {
+		chatID := models.ChatIDFromSource(a.Source)
+		if chatID == "" {
+			chatID = "general"
+		}
+		if visible[chatID] {
+			unread[chatID]++
+		}
+	}
+	return unread
+}
+
+func writeBootstrap(w http.ResponseWriter, resp map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store") // Per-user
+	json.NewEncoder(w).Encode(resp)
+}
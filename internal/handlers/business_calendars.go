@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+func (h *Handler) validateBusinessCalendar(ctx context.Context, c *models.BusinessCalendar) error {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if c.TeamID != 0 {
+		if _, err := h.AdminStore.GetTeam(ctx, c.TeamID); err != nil {
+			return fmt.Errorf("unknown team %d", c.TeamID)
+		}
+	}
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", c.Timezone)
+	}
+	if c.Days == nil {
+		c.Days = []int{1, 2, 3, 4, 5}
+	}
+	for _, d := range c.Days {
+		if d < 0 || d > 6 {
+			return errors.New("days are weekdays 0-6, 0 = Sunday")
+		}
+	}
+	slices.Sort(c.Days)
+	c.Days = slices.Compact(c.Days)
+	if c.Start == "" {
+		c.Start = "09:00"
+	}
+	if c.End == "" {
+		c.End = "17:00"
+	}
+	for _, t := range []string{c.Start, c.End} {
+		if _, err := time.Parse("15:04", t); err != nil {
+			return errors.New("start and end must be HH:MM")
+		}
+	}
+	for _, d := range c.Holidays {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return fmt.Errorf("invalid holiday %q: use YYYY-MM-DD", d)
+		}
+	}
+	slices.Sort(c.Holidays)
+	c.Holidays = slices.Compact(c.Holidays)
+	return nil
+}
+
+// validateHoursRule checks a routing rule's business-hours branch
+func (h *Handler) validateHoursRule(ctx context.Context, rule *models.HoursRule) error {
+	switch rule.Hours {
+	case models.HoursAlways:
+		rule.CalendarID = 0
+		return nil
+	case models.HoursBusiness, models.HoursAfter:
+	default:
+		return fmt.Errorf("hours must be %q or %q", models.HoursBusiness, models.HoursAfter)
+	}
+	if rule.CalendarID == 0 {
+		return errors.New("hours needs a calendar_id")
+	}
+	if _, err := h.AdminStore.GetBusinessCalendar(ctx, rule.CalendarID); err != nil {
+		return fmt.Errorf("unknown calendar %d", rule.CalendarID)
+	}
+	return nil
+}
+
+// === Business-hours Calendar Management ===
+
+func (h *Handler) GetBusinessCalendarsHandler(w http.ResponseWriter, r *http.Request) {
+	calendars, err := h.AdminStore.GetBusinessCalendars(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get calendars", http.StatusInternalServerError)
+		return
+	}
+
+	// Whether each calendar is open right now, to check the settings
+	type calendarStatus struct {
+		models.BusinessCalendar
+		Open bool `json:"open"`
+	}
+	now := time.Now()
+	out := make([]calendarStatus, 0, len(calendars))
+	for _, c := range calendars {
+		out = append(out, calendarStatus{c, c.OpenAt(now)})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"calendars": out})
+}
+
+// CreateBusinessCalendarHandler adds a calendar routing rules can branch on
+// POST /api/admin/business-calendars {"name": "Payments", "team_id": 2, "timezone": "Europe/Berlin", "days": [1,2,3,4,5], "start": "09:00", "end": "18:00", "holidays": ["2026-12-25"]}
+func (h *Handler) CreateBusinessCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	var c models.BusinessCalendar
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := h.validateBusinessCalendar(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.AdminStore.CreateBusinessCalendar(r.Context(), c)
+	if err != nil {
+		http.Error(w, "Failed to create calendar (names must be unique)", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": c.Name, "team_id": c.TeamID, "timezone": c.Timezone})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_business_calendar", "business_calendar", c.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "calendar": c})
+}
+
+func (h *Handler) UpdateBusinessCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/business-calendars/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var c models.BusinessCalendar
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	c.ID = id
+	if err := h.validateBusinessCalendar(r.Context(), &c); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err = h.AdminStore.UpdateBusinessCalendar(r.Context(), c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": c.Name, "team_id": c.TeamID, "timezone": c.Timezone})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_business_calendar", "business_calendar", c.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "calendar": c})
+}
+
+func (h *Handler) DeleteBusinessCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/business-calendars/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteBusinessCalendar(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_business_calendar", "business_calendar", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// channelTestTimeout bounds one test send, retries included
+const channelTestTimeout = 30 * time.Second
+
+// ChannelTester is an integration whose destinations can be sent a test
+// alert: Teams targets, Telegram chats, outgoing webhooks, email routes and
+// stakeholder lists. A channel's ID is the kind it is registered under and
+// the target, as in "teams:ops" or "webhook:3".
+type ChannelTester interface {
+	// Targets lists the destinations by the names Test takes
+	Targets(ctx context.Context) ([]string, error)
+	// Test sends a to target right away, bypassing its filters and the
+	// notification queue, and reports whether it was delivered
+	Test(ctx context.Context, target string, a models.Alert) error
+}
+
+// splitChannelID splits "kind:target"; the target may itself contain colons
+func splitChannelID(id string) (kind, target string, ok bool) {
+	kind, target, ok = strings.Cut(id, ":")
+	return kind, target, ok && kind != "" && target != ""
+}
+
+// GetChannelsHandler lists the channels that can be tested
+// GET /api/admin/channels
+func (h *Handler) GetChannelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	kinds := make([]string, 0, len(h.Channels))
+	for kind := range h.Channels {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	channels := []map[string]string{}
+	for _, kind := range kinds {
+		targets, err := h.Channels[kind].Targets(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to list "+kind+" channels", http.StatusInternalServerError)
+			return
+		}
+		for _, target := range targets {
+			channels = append(channels, map[string]string{"id": kind + ":" + target, "kind": kind, "target": target})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"channels": channels})
+}
+
+// ChannelTestHandler sends a synthetic alert through one channel and returns
+// whether it was delivered. A failed delivery is still a 200: the result is
+// what was asked for.
+// POST /api/admin/channels/{id}/test {"level": "critical"}
+func (h *Handler) ChannelTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/channels/"), "/test")
+	kind, target, ok := splitChannelID(id)
+	if !ok {
+		http.Error(w, "Invalid channel ID: expected kind:target", http.StatusBadRequest)
+		return
+	}
+	tester := h.Channels[kind]
+	if tester == nil {
+		http.Error(w, "Unknown channel kind", http.StatusNotFound)
+		return
+	}
+	targets, err := tester.Targets(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to list "+kind+" channels", http.StatusInternalServerError)
+		return
+	}
+	if !slices.Contains(targets, target) {
+		http.Error(w, "Channel not found", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		Level string `json:"level"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+	}
+	level := models.NormalizeLevel(req.Level)
+	if req.Level == "" {
+		level = models.LevelCritical
+	}
+
+	actorID, username, _ := GetCurrentUser(r)
+	a := models.Alert{
+		Source:    "sentinel",
+		Level:     level,
+		Title:     "Test notification",
+		Message:   fmt.Sprintf("%s sent this test to check the %s channel. No action is needed.", username, id),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), channelTestTimeout)
+	defer cancel()
+	start := time.Now()
+	err = tester.Test(ctx, target, a)
+	result := map[string]any{
+		"channel":    id,
+		"success":    err == nil,
+		"latency_ms": time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+
+	if actorID != 0 {
+		meta, _ := json.Marshal(result)
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "test_channel", "system", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"incident-viewer-go/internal/chaos"
+)
+
+// ChaosHandler shows, sets and clears injected faults.
+// GET/PUT/DELETE /api/admin/chaos
+// Only available when the server runs with CHAOS_ENABLED=true; meant for
+// staging, to check that alerting-on-alerting fires and clients retry.
+func (h *Handler) ChaosHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Chaos == nil {
+		http.Error(w, "Fault injection is not enabled", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req struct {
+			chaos.Faults
+			DurationSeconds int `json:"duration_seconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if err := req.Faults.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		d := chaos.DefaultDuration
+		if req.DurationSeconds > 0 {
+			d = min(time.Duration(req.DurationSeconds)*time.Second, chaos.MaxDuration)
+		}
+		f := h.Chaos.Set(req.Faults, d)
+
+		if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+			meta, _ := json.Marshal(f)
+			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "set_chaos", "system", 0, string(meta))
+		}
+	case http.MethodDelete:
+		h.Chaos.Clear()
+
+		if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "clear_chaos", "system", 0, "{}")
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	f, active := h.Chaos.Current()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"active": active, "faults": f})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// CloudEvents 1.0 over HTTP, https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md
+const (
+	cloudEventsSpecVersion    = "1.0"
+	cloudEventsStructuredType = "application/cloudevents+json"
+	cloudEventsHeaderPrefix   = "Ce-"
+)
+
+// cloudEvent holds the attributes Sentinel uses; data is left raw
+type cloudEvent struct {
+	SpecVersion string          `json:"specversion"`
+	ID          string          `json:"id"`
+	Source      string          `json:"source"`
+	Type        string          `json:"type"`
+	Subject     string          `json:"subject"`
+	Data        json.RawMessage `json:"data"`
+	DataBase64  string          `json:"data_base64"`
+
+	// Extension attributes, e.g. severity
+	Extensions map[string]string `json:"-"`
+}
+
+// isCloudEvent reports whether r carries a CloudEvent, in binary mode
+// (ce-specversion header) or structured mode (application/cloudevents+json)
+func isCloudEvent(r *http.Request) bool {
+	if r.Header.Get(cloudEventsHeaderPrefix+"Specversion") != "" {
+		return true
+	}
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == cloudEventsStructuredType
+}
+
+// parseCloudEvent reads a binary or structured mode CloudEvent from r
+func parseCloudEvent(r *http.Request) (cloudEvent, error) {
+	var ce cloudEvent
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return ce, err
+	}
+
+	if v := r.Header.Get(cloudEventsHeaderPrefix + "Specversion"); v != "" {
+		// Binary mode: attributes in ce-* headers, the body is the data
+		ce.Extensions = make(map[string]string)
+		for key, values := range r.Header {
+			if !strings.HasPrefix(key, cloudEventsHeaderPrefix) || len(values) == 0 {
+				continue
+			}
+			ce.Extensions[strings.ToLower(strings.TrimPrefix(key, cloudEventsHeaderPrefix))] = values[0]
+		}
+		ce.SpecVersion = v
+		ce.ID = ce.Extensions["id"]
+		ce.Source = ce.Extensions["source"]
+		ce.Type = ce.Extensions["type"]
+		ce.Subject = ce.Extensions["subject"]
+		ce.Data = body
+	} else {
+		// Structured mode: the whole event is the JSON body
+		if err := json.Unmarshal(body, &ce); err != nil {
+			return ce, fmt.Errorf("invalid CloudEvent: %w", err)
+		}
+		var attrs map[string]any
+		_ = json.Unmarshal(body, &attrs)
+		ce.Extensions = make(map[string]string, len(attrs))
+		for k, v := range attrs {
+			if s := getString(v); s != "" {
+				ce.Extensions[k] = s
+			}
+		}
+		if ce.DataBase64 != "" {
+			data, err := base64.StdEncoding.DecodeString(ce.DataBase64)
+			if err != nil {
+				return ce, errors.New("invalid CloudEvent: data_base64 is not base64")
+			}
+			ce.Data = data
+		}
+	}
+
+	if ce.SpecVersion != cloudEventsSpecVersion {
+		return ce, fmt.Errorf("unsupported CloudEvents specversion %q", ce.SpecVersion)
+	}
+	if ce.ID == "" || ce.Source == "" || ce.Type == "" {
+		return ce, errors.New("CloudEvent requires id, source and type")
+	}
+	return ce, nil
+}
+
+// alert maps the event onto an alert: source is the event source, the title
+// is the type (and subject), and level and message come from JSON data the
+// same way as for /webhook, falling back to a severity/level extension
+func (ce cloudEvent) alert() models.Alert {
+	payload := map[string]any{}
+	if len(ce.Data) > 0 {
+		var data any
+		if json.Unmarshal(ce.Data, &data) != nil {
+			data = string(ce.Data)
+		}
+		switch d := data.(type) {
+		case map[string]any:
+			payload = d
+		case string:
+			payload["message"] = d
+		default:
+			payload["message"] = string(ce.Data)
+		}
+	}
+	if getString(payload["level"]) == "" && getString(payload["severity"]) == "" {
+		for _, ext := range []string{"severity", "level"} {
+			if v := ce.Extensions[ext]; v != "" {
+				payload["severity"] = v
+				break
+			}
+		}
+	}
+	_, level, _, message := extractAlertFields(payload)
+	if len(payload) == 0 {
+		message = "No content"
+	}
+
+	title := ce.Type
+	if ce.Subject != "" {
+		title += ": " + ce.Subject
+	}
+
+	labels := map[string]string{
+		"ce_id":   ce.ID,
+		"ce_type": ce.Type,
+	}
+	if ce.Subject != "" {
+		labels["ce_subject"] = ce.Subject
+	}
+
+	return models.Alert{
+		Source:  ce.Source,
+		Level:   level,
+		Title:   title,
+		Message: message,
+		Labels:  labels,
+	}
+}
+
+// cloudEventWebhook stores a CloudEvent posted to /webhook
+func (h *Handler) cloudEventWebhook(w http.ResponseWriter, r *http.Request) {
+	ce, err := parseCloudEvent(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.AlertStore.UpsertAlert(r.Context(), ce.alert())
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":     "ok",
+		"id":         a.ID,
+		"created_at": a.CreatedAt.Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// DatadogWebhookHandler accepts Datadog webhook integration payloads.
+// The webhook payload template is expected to expose the monitor variables, e.g.:
+//
+//	{
+//	  "alert_id": "$ALERT_ID",
+//	  "aggreg_key": "$AGGREG_KEY",
+//	  "alert_title": "$ALERT_TITLE",
+//	  "alert_transition": "$ALERT_TRANSITION",
+//	  "alert_type": "$ALERT_TYPE",
+//	  "event_msg": "$EVENT_MSG",
+//	  "link": "$LINK"
+//	}
+//
+// Triggered/Warn transitions open (or update) an alert and Recovered resolves it.
+func (h *Handler) DatadogWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	title := getString(payload["alert_title"])
+	if title == "" {
+		title = getString(payload["title"])
+	}
+	if title == "" {
+		title = "Datadog Alert"
+	}
+
+	message := getString(payload["event_msg"])
+	if message == "" {
+		message = getString(payload["body"])
+	}
+	if link := getString(payload["link"]); link != "" {
+		message = strings.TrimSpace(message + "\n" + link)
+	}
+	if message == "" {
+		message = "No content"
+	}
+
+	// $AGGREG_KEY is shared by the trigger and recovery events of a monitor group
+	fingerprint := getString(payload["aggreg_key"])
+	if fingerprint == "" {
+		fingerprint = getString(payload["alert_id"])
+		if scope := getString(payload["alert_scope"]); fingerprint != "" && scope != "" {
+			fingerprint += ":" + scope
+		}
+	}
+	if fingerprint != "" {
+		fingerprint = "datadog:" + fingerprint
+	}
+
+	transition := strings.ToLower(getString(payload["alert_transition"]))
+
+	var (
+		a   models.Alert
+		err error
+	)
+	if transition == "recovered" {
+		a, err = h.AlertStore.ResolveAlert(r.Context(), fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			// Nothing open to close; keep the recovery visible on the timeline
+			a, err = h.AlertStore.AddAlert(r.Context(), "datadog", "success", title, message)
+		}
+	} else {
+		level := datadogLevel(transition, getString(payload["alert_type"]))
+		a, err = h.AlertStore.AddFingerprintedAlert(r.Context(), fingerprint, "datadog", level, title, message)
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           a.ID,
+		"alert_status": a.Status,
+	})
+}
+
+// datadogLevel maps $ALERT_TRANSITION (falling back to $ALERT_TYPE) to a level
+func datadogLevel(transition, alertType string) string {
+	switch transition {
+	case "triggered", "re-triggered", "renotify":
+		return "critical"
+	case "warn", "re-warn", "no data", "re-no data":
+		return "warning"
+	}
+
+	switch strings.ToLower(alertType) {
+	case "error":
+		return "critical"
+	case "warning":
+		return "warning"
+	case "success":
+		return "success"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/depcheck"
+	"incident-viewer-go/internal/models"
+)
+
+func validateDependencyCheck(d *models.DependencyCheck) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+	d.URL = strings.TrimSpace(d.URL)
+	u, err := url.Parse(d.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	d.ChatID = strings.TrimSpace(d.ChatID)
+	d.Source = strings.TrimSpace(d.Source)
+	if _, err := path.Match(d.Source, ""); err != nil {
+		return fmt.Errorf("invalid source pattern %q", d.Source)
+	}
+	if d.TimeoutMs == 0 {
+		d.TimeoutMs = int(depcheck.DefaultTimeout.Milliseconds())
+	}
+	if d.TimeoutMs < 100 || d.TimeoutMs > int(depcheck.MaxTimeout.Milliseconds()) {
+		return fmt.Errorf("timeout_ms must be 100-%d", depcheck.MaxTimeout.Milliseconds())
+	}
+	return nil
+}
+
+// === Dependency Check Management ===
+
+func (h *Handler) GetDependencyChecksHandler(w http.ResponseWriter, r *http.Request) {
+	checks, err := h.AdminStore.GetDependencyChecks(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get dependency checks", http.StatusInternalServerError)
+		return
+	}
+	if checks == nil {
+		checks = []models.DependencyCheck{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"dependency_checks": checks})
+}
+
+func (h *Handler) CreateDependencyCheckHandler(w http.ResponseWriter, r *http.Request) {
+	d := models.DependencyCheck{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateDependencyCheck(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err := h.AdminStore.CreateDependencyCheck(r.Context(), d)
+	if err != nil {
+		http.Error(w, "Failed to create dependency check", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": d.Name, "url": d.URL})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_dependency_check", "dependency_check", d.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "dependency_check": d})
+}
+
+func (h *Handler) UpdateDependencyCheckHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/dependency-checks/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var d models.DependencyCheck
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	d.ID = id
+	if err := validateDependencyCheck(&d); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	d, err = h.AdminStore.UpdateDependencyCheck(r.Context(), d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": d.Name, "url": d.URL, "enabled": d.Enabled})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_dependency_check", "dependency_check", d.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "dependency_check": d})
+}
+
+func (h *Handler) DeleteDependencyCheckHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/dependency-checks/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteDependencyCheck(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_dependency_check", "dependency_check", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
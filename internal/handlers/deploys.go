@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// DeploysHandler records a deploy event for deploy correlation.
+// POST /api/deploys {"service": "payments", "environment": "prod", "version": "1a2b3c", "url": "...", "actor": "..."}
+// For CD tools without a built-in integration (Jenkins, Argo CD, scripts);
+// authenticated like /webhook.
+func (h *Handler) DeploysHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var d models.DeployEvent
+	if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	d.Service = strings.TrimSpace(d.Service)
+	if d.Service == "" {
+		http.Error(w, "service is required", http.StatusBadRequest)
+		return
+	}
+	if d.DeployedAt.IsZero() || d.DeployedAt.After(time.Now()) {
+		d.DeployedAt = time.Now().UTC()
+	}
+	d.Provider = "api"
+	if t, ok := ingestTokenFromContext(r.Context()); ok {
+		d.Provider = t.Name
+	}
+
+	if err := h.AlertStore.RecordDeploy(r.Context(), d); err != nil {
+		log.Println("Failed to record deploy:", err)
+		http.Error(w, "Failed to record deploy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"status": "ok"})
+}
+
+// recordDeploy keeps a successful deploy seen by a CI/CD integration;
+// failing to record it must not fail the integration's webhook
+func (h *Handler) recordDeploy(ctx context.Context, d models.DeployEvent) {
+	if err := h.AlertStore.RecordDeploy(ctx, d); err != nil {
+		log.Println("Failed to record deploy:", err)
+	}
+}
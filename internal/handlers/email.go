@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/email"
+	"incident-viewer-go/internal/models"
+)
+
+// parseRecipients normalizes a list of email addresses
+func parseRecipients(list []string) ([]string, error) {
+	var out []string
+	for _, e := range list {
+		addr, err := mail.ParseAddress(strings.TrimSpace(e))
+		if err != nil {
+			return nil, fmt.Errorf("invalid email %q", e)
+		}
+		out = append(out, addr.Address)
+	}
+	return out, nil
+}
+
+// maxEscalateAfter keeps each escalation step to at most a day
+const maxEscalateAfter = 24 * 60
+
+func (h *Handler) validateEmailRoute(ctx context.Context, route *models.EmailRoute) error {
+	route.Name = strings.TrimSpace(route.Name)
+	if route.Name == "" {
+		return errors.New("name is required")
+	}
+	route.ChatID = strings.TrimSpace(route.ChatID)
+	route.Source = strings.TrimSpace(route.Source)
+	if _, err := path.Match(route.Source, ""); err != nil {
+		return fmt.Errorf("invalid source pattern %q", route.Source)
+	}
+	if route.MinLevel == "" {
+		route.MinLevel = models.LevelError
+	}
+	route.MinLevel = models.NormalizeLevel(route.MinLevel)
+	switch route.MinLevel {
+	case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo:
+	default:
+		return fmt.Errorf("unknown level %q", route.MinLevel)
+	}
+	recipients, err := parseRecipients(route.Recipients)
+	if err != nil {
+		return err
+	}
+	if len(recipients) == 0 && route.ScheduleID == 0 {
+		return errors.New("at least one recipient or a schedule_id is required")
+	}
+	route.Recipients = recipients
+	if route.ScheduleID != 0 {
+		if _, err := h.AdminStore.GetSchedule(ctx, route.ScheduleID); err != nil {
+			return fmt.Errorf("unknown schedule %d", route.ScheduleID)
+		}
+	}
+	if route.EscalateAfter < 0 || route.EscalateAfter > maxEscalateAfter {
+		return fmt.Errorf("escalate_after must be 0-%d minutes", maxEscalateAfter)
+	}
+	if route.EscalateAfter > 0 && route.ScheduleID == 0 {
+		return errors.New("escalate_after needs a schedule_id to escalate along")
+	}
+	return h.validateHoursRule(ctx, &route.HoursRule)
+}
+
+// EmailTestHandler sends a sample alert email, to check the SMTP settings
+// and how a severity's template looks.
+// POST /api/admin/email/test {"to": ["ops@example.com"], "level": "critical"}
+func (h *Handler) EmailTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Email == nil {
+		http.Error(w, "Email is not configured: set SMTP_HOST", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		To    []string `json:"to"`
+		Level string   `json:"level"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	to, err := parseRecipients(req.To)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(to) == 0 {
+		http.Error(w, "to is required", http.StatusBadRequest)
+		return
+	}
+	level := models.NormalizeLevel(req.Level)
+	if req.Level == "" {
+		level = models.LevelCritical
+	}
+
+	_, username, _ := GetCurrentUser(r)
+	m, err := email.Render(models.Alert{
+		Source:    "sentinel",
+		Level:     level,
+		Title:     "Test email",
+		Message:   fmt.Sprintf("%s sent this test to check the email settings. No action is needed.", username),
+		CreatedAt: time.Now().UTC(),
+	}, "", os.Getenv("SENTINEL_PUBLIC_URL"))
+	if err != nil {
+		http.Error(w, "Failed to render email", http.StatusInternalServerError)
+		return
+	}
+	m.To = to
+	if err := h.Email.Send(m); err != nil {
+		http.Error(w, "Failed to send: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// === Email Route Management ===
+
+func (h *Handler) GetEmailRoutesHandler(w http.ResponseWriter, r *http.Request) {
+	routes, err := h.AdminStore.GetEmailRoutes(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get email routes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"email_routes": routes, "smtp_configured": h.Email != nil})
+}
+
+func (h *Handler) CreateEmailRouteHandler(w http.ResponseWriter, r *http.Request) {
+	route := models.EmailRoute{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := h.validateEmailRoute(r.Context(), &route); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	route, err := h.AdminStore.CreateEmailRoute(r.Context(), route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": route.Name, "recipients": len(route.Recipients), "schedule_id": route.ScheduleID, "escalate_after": route.EscalateAfter})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_email_route", "email_route", route.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "email_route": route})
+}
+
+func (h *Handler) UpdateEmailRouteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/email-routes/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	route := models.EmailRoute{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&route); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	route.ID = id
+	if err := h.validateEmailRoute(r.Context(), &route); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	route, err = h.AdminStore.UpdateEmailRoute(r.Context(), route)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": route.Name, "recipients": len(route.Recipients), "schedule_id": route.ScheduleID, "escalate_after": route.EscalateAfter, "enabled": route.Enabled})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_email_route", "email_route", route.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "email_route": route})
+}
+
+func (h *Handler) DeleteEmailRouteHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/email-routes/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteEmailRoute(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_email_route", "email_route", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
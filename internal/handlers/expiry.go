@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+const (
+	expiryWarningWindow  = 24 * time.Hour     // Warn this long before an open alert expires
+	expiryCheckInterval  = time.Hour          // How often to look for expiring alerts
+	criticalRetentionExt = 7 * 24 * time.Hour // Extra retention granted to open criticals
+)
+
+// RunExpiryWarnings periodically warns about unresolved alerts that are about to
+// reach their retention TTL. Open criticals get their retention extended instead
+// of disappearing from the timeline.
+func (h *Handler) RunExpiryWarnings(ctx context.Context) {
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		h.warnExpiringAlerts(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) warnExpiringAlerts(ctx context.Context) {
+	alerts, err := h.AlertStore.ExpiringAlerts(ctx, expiryWarningWindow)
+	if err != nil {
+		log.Printf("Failed to check expiring alerts: %v", err)
+		return
+	}
+
+	for _, a := range alerts {
+		if models.LevelRank(a.Level) >= models.LevelRank("critical") {
+			if err := h.AlertStore.ExtendAlertRetention(ctx, a, criticalRetentionExt); err != nil {
+				log.Printf("Failed to extend retention of alert %d: %v", a.ID, err)
+			} else {
+				log.Printf("Extended retention of unresolved critical alert %d by %s", a.ID, criticalRetentionExt)
+				h.SendPushNotification(a, fmt.Sprintf("⏳ Still unresolved, retention extended: %s", a.Title))
+				continue
+			}
+		}
+		h.SendPushNotification(a, fmt.Sprintf("⏳ Unresolved alert expires within %.0fh: %s", expiryWarningWindow.Hours(), a.Title))
+	}
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+// FeatureEnabled reports whether a feature flag is on for a user. Flags an
+// admin hasn't configured (or that fail to load) fall back to their default,
+// off for unknown keys.
+func (h *Handler) FeatureEnabled(ctx context.Context, key string, userID int, role string) bool {
+	f, err := h.AdminStore.GetFeatureFlag(ctx, key)
+	if err != nil {
+		return models.FeatureFlagDefaults[key]
+	}
+	orgID := 0
+	if len(f.OrgIDs) > 0 {
+		orgID = h.userOrg(ctx, userID)
+	}
+	return f.EnabledFor(userID, role, orgID)
+}
+
+// userOrg is the organization of the user with ID userID, or 0 when it
+// can't be looked up
+func (h *Handler) userOrg(ctx context.Context, userID int) int {
+	if userID == 0 {
+		return 0
+	}
+	user, err := h.AdminStore.GetUser(ctx, userID)
+	if err != nil {
+		return 0
+	}
+	return user.OrgID
+}
+
+// FeatureMiddleware answers 404 to users the feature is off for, as if the
+// endpoint didn't exist
+func (h *Handler) FeatureMiddleware(key string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, _, role := GetCurrentUser(r)
+		if !h.FeatureEnabled(r.Context(), key, userID, role) {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// featureFlags lists every configured and known flag, with the known ones an
+// admin hasn't configured at their defaults
+func (h *Handler) featureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	flags, err := h.AdminStore.GetFeatureFlags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	configured := make(map[string]bool, len(flags))
+	for _, f := range flags {
+		configured[f.Key] = true
+	}
+	for key, on := range models.FeatureFlagDefaults {
+		if !configured[key] {
+			flags = append(flags, models.FeatureFlag{Key: key, Enabled: on, UserIDs: []int{}, Roles: []string{}, OrgIDs: []int{}})
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+// userFeatureFlags is which flags are on for a user, for the dashboard
+func (h *Handler) userFeatureFlags(ctx context.Context, userID int, role string) map[string]bool {
+	on := make(map[string]bool)
+	flags, err := h.featureFlags(ctx)
+	if err != nil {
+		for key, enabled := range models.FeatureFlagDefaults {
+			on[key] = enabled
+		}
+		return on
+	}
+	orgID := h.userOrg(ctx, userID)
+	for _, f := range flags {
+		on[f.Key] = f.EnabledFor(userID, role, orgID)
+	}
+	return on
+}
+
+func (h *Handler) validateFeatureFlag(ctx context.Context, f *models.FeatureFlag) error {
+	if !models.ValidFeatureFlagKey(f.Key) {
+		return fmt.Errorf("invalid key %q: use lowercase letters, digits, '_', '.' and '-'", f.Key)
+	}
+	f.Description = strings.TrimSpace(f.Description)
+	for _, role := range f.Roles {
+		if role != "admin" && role != "developer" && role != "user" {
+			return fmt.Errorf("unknown role %q", role)
+		}
+	}
+	for _, id := range f.UserIDs {
+		if _, err := h.AdminStore.GetUser(ctx, id); err != nil {
+			return fmt.Errorf("unknown user %d", id)
+		}
+	}
+	for _, id := range f.OrgIDs {
+		if _, err := h.AdminStore.GetOrganization(ctx, id); err != nil {
+			return fmt.Errorf("unknown organization %d", id)
+		}
+	}
+	return nil
+}
+
+// === Feature Flag Management ===
+
+func (h *Handler) GetFeatureFlagsHandler(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.featureFlags(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get feature flags", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"flags": flags})
+}
+
+// SaveFeatureFlagHandler creates or replaces a flag
+// PUT /api/admin/features/{key}
+func (h *Handler) SaveFeatureFlagHandler(w http.ResponseWriter, r *http.Request) {
+	var f models.FeatureFlag
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	f.Key = strings.TrimPrefix(r.URL.Path, "/api/admin/features/")
+	if err := h.validateFeatureFlag(r.Context(), &f); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f, err := h.AdminStore.SaveFeatureFlag(r.Context(), f)
+	if err != nil {
+		http.Error(w, "Failed to save feature flag", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"flag": f.Key, "enabled": f.Enabled, "user_ids": f.UserIDs, "roles": f.Roles, "org_ids": f.OrgIDs})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "save_feature_flag", "feature_flag", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "flag": f})
+}
+
+// DeleteFeatureFlagHandler removes a flag's configuration; a known flag goes
+// back to its default
+// DELETE /api/admin/features/{key}
+func (h *Handler) DeleteFeatureFlagHandler(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/api/admin/features/")
+	if err := h.AdminStore.DeleteFeatureFlag(r.Context(), key); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"flag": key})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_feature_flag", "feature_flag", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
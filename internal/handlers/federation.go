@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"incident-viewer-go/internal/federation"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// FederationIngestHandler accepts alerts forwarded by another Sentinel instance.
+// POST /api/federation/alerts
+// The signature (X-Sentinel-Signature, keyed with FEDERATION_SECRET) is checked
+// by hmacMiddleware; without FEDERATION_SECRET the endpoint is disabled.
+// Alerts that already passed through this instance, or too many instances,
+// are refused with 409 so forwarding rules can't loop.
+func (h *Handler) FederationIngestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if os.Getenv("FEDERATION_SECRET") == "" {
+		http.Error(w, "Federation is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var env federation.Envelope
+	if err := json.NewDecoder(r.Body).Decode(&env); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	sender := env.Sender()
+	if sender == "" || env.Alert.ID == 0 {
+		http.Error(w, "path and alert are required", http.StatusBadRequest)
+		return
+	}
+	if slices.Contains(env.Path, federation.InstanceID()) {
+		http.Error(w, "loop detected: alert already passed through this instance", http.StatusConflict)
+		return
+	}
+	if len(env.Path) > federation.MaxHops {
+		http.Error(w, "too many hops", http.StatusConflict)
+		return
+	}
+
+	remote := env.Alert
+	source := "federation:" + sender
+	if env.ChatID != "" {
+		source = fmt.Sprintf("federation:%s:chat:%s", sender, env.ChatID)
+	}
+	labels := make(map[string]string, len(remote.Labels)+2)
+	for k, v := range remote.Labels {
+		labels[k] = v
+	}
+	labels[federation.LabelPath] = strings.Join(env.Path, ",")
+	if labels[federation.LabelSource] == "" {
+		labels[federation.LabelSource] = remote.Source
+	}
+
+	fingerprint := fmt.Sprintf("fed:%s:%d", sender, remote.ID)
+	var (
+		a   models.Alert
+		err error
+	)
+	if remote.Status == models.AlertStatusResolved {
+		a, err = h.AlertStore.ResolveAlert(r.Context(), fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			// Never saw it open (or already resolved): nothing to do
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"status": "ignored"})
+			return
+		}
+	} else {
+		a, err = h.AlertStore.UpsertAlert(r.Context(), models.Alert{
+			Source:      source,
+			Level:       remote.Level,
+			Title:       remote.Title,
+			Message:     remote.Message,
+			Labels:      labels,
+			Fingerprint: fingerprint,
+		})
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           a.ID,
+		"alert_status": a.Status,
+	})
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+
+var (
+	googleKeysMu      sync.Mutex
+	googleKeys        map[string]*rsa.PublicKey // kid -> key
+	googleKeysFetched time.Time
+	googleKeysTTL     = time.Hour
+	// googleKeysTried throttles refetches for unknown kids, so pushes with
+	// made-up kids can't keep the endpoint waiting on Google
+	googleKeysTried      time.Time
+	googleKeysRetryAfter = time.Minute
+)
+
+// PubSubHandler accepts Google Cloud Pub/Sub push deliveries. Cloud Monitoring
+// incidents (notification channel payloads) open/resolve alerts by incident ID;
+// any other JSON message goes through the generic field extraction.
+//
+// The push must carry a Google-signed OIDC token (Authorization: Bearer) with
+// the GCP_PUBSUB_AUDIENCE audience; GCP_PUBSUB_SERVICE_ACCOUNT further pins the
+// token's email claim. Without GCP_PUBSUB_AUDIENCE the endpoint is disabled.
+func (h *Handler) PubSubHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	audience := os.Getenv("GCP_PUBSUB_AUDIENCE")
+	if audience == "" {
+		http.Error(w, "Pub/Sub push is not configured (GCP_PUBSUB_AUDIENCE)", http.StatusServiceUnavailable)
+		return
+	}
+	if err := verifyPubSubToken(r, audience, os.Getenv("GCP_PUBSUB_SERVICE_ACCOUNT")); err != nil {
+		log.Printf("Pub/Sub push rejected: %v", err)
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	var envelope struct {
+		Message struct {
+			Data        string            `json:"data"`
+			Attributes  map[string]string `json:"attributes"`
+			MessageID   string            `json:"messageId"`
+			PublishTime string            `json:"publishTime"`
+		} `json:"message"`
+		Subscription string `json:"subscription"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&envelope); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	data, err := base64.StdEncoding.DecodeString(envelope.Message.Data)
+	if err != nil {
+		http.Error(w, "Invalid message data", http.StatusBadRequest)
+		return
+	}
+
+	var msg struct {
+		Incident *struct {
+			IncidentID          string `json:"incident_id"`
+			PolicyName          string `json:"policy_name"`
+			ConditionName       string `json:"condition_name"`
+			State               string `json:"state"`
+			Summary             string `json:"summary"`
+			URL                 string `json:"url"`
+			ResourceDisplayName string `json:"resource_display_name"`
+			Severity            string `json:"severity"`
+		} `json:"incident"`
+	}
+	_ = json.Unmarshal(data, &msg)
+
+	var a models.Alert
+	if inc := msg.Incident; inc != nil && inc.IncidentID != "" {
+		fingerprint := "gcp:" + inc.IncidentID
+		title := inc.PolicyName
+		if inc.ConditionName != "" {
+			title = fmt.Sprintf("%s: %s", inc.PolicyName, inc.ConditionName)
+		}
+		message := strings.TrimSpace(inc.Summary + "\n" + inc.URL)
+
+		if strings.EqualFold(inc.State, "closed") {
+			a, err = h.AlertStore.ResolveAlert(r.Context(), fingerprint)
+			if errors.Is(err, store.ErrAlertNotFound) {
+				a, err = h.AlertStore.AddAlert(r.Context(), "gcp", "success", title, message)
+			}
+		} else {
+			a, err = h.AlertStore.AddFingerprintedAlert(r.Context(), fingerprint, "gcp", gcpSeverityLevel(inc.Severity), title, message)
+		}
+	} else {
+		var payload map[string]any
+		if err := json.Unmarshal(data, &payload); err != nil {
+			payload = map[string]any{"message": string(data)}
+		}
+		for k, v := range envelope.Message.Attributes {
+			if _, ok := payload[k]; !ok {
+				payload[k] = v
+			}
+		}
+		source, level, title, message := extractAlertFields(payload)
+		if source == "unknown" {
+			source = "gcp"
+		}
+		a, err = h.AlertStore.AddAlert(r.Context(), source, level, title, message)
+	}
+	if err != nil {
+		// Non-2xx makes Pub/Sub redeliver
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"status": "ok", "id": a.ID})
+}
+
+// gcpSeverityLevel maps Cloud Monitoring policy severities to levels
+func gcpSeverityLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "error":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "warning" // "No severity": an open incident is still worth attention
+	}
+}
+
+// verifyPubSubToken validates the Google-signed OIDC token on a push request
+func verifyPubSubToken(r *http.Request, audience, serviceAccount string) error {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || raw == "" {
+		return errors.New("missing bearer token")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return googlePublicKey(kid)
+	}, jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(audience), jwt.WithExpirationRequired())
+	if err != nil {
+		return err
+	}
+
+	if iss, _ := claims.GetIssuer(); iss != "accounts.google.com" && iss != "https://accounts.google.com" {
+		return fmt.Errorf("unexpected issuer %q", iss)
+	}
+	if serviceAccount != "" {
+		if email, _ := claims["email"].(string); email != serviceAccount {
+			return fmt.Errorf("unexpected service account %q", email)
+		}
+		if verified, _ := claims["email_verified"].(bool); !verified {
+			return errors.New("service account email not verified")
+		}
+	}
+	return nil
+}
+
+// googlePublicKey returns Google's signing key for kid, refreshing the cached
+// JWKS when it is stale or the kid is unknown (key rotation). Unknown kids
+// refetch at most once per googleKeysRetryAfter.
+func googlePublicKey(kid string) (*rsa.PublicKey, error) {
+	googleKeysMu.Lock()
+	defer googleKeysMu.Unlock()
+
+	fresh := time.Since(googleKeysFetched) < googleKeysTTL
+	if key, ok := googleKeys[kid]; ok && fresh {
+		return key, nil
+	}
+	if fresh && time.Since(googleKeysTried) < googleKeysRetryAfter {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	googleKeysTried = time.Now()
+	keys, err := fetchGoogleKeys()
+	if err != nil {
+		return nil, err
+	}
+	googleKeys = keys
+	googleKeysFetched = time.Now()
+
+	if key, ok := googleKeys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func fetchGoogleKeys() (map[string]*rsa.PublicKey, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(googleCertsURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching Google certs: %s", resp.Status)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
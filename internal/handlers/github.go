@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// GitHubWebhookHandler accepts GitHub repository/organization webhooks:
+//   - workflow_run: failed runs open an alert per workflow and branch; the next
+//     successful run on that branch resolves it
+//   - deployment_status: failure/error opens an alert per environment; success resolves it
+//     and is recorded for deploy correlation
+//   - issues: opened/reopened opens an alert per issue; closed resolves it
+//
+// When GITHUB_WEBHOOK_SECRET is set, X-Hub-Signature-256 must match it.
+func (h *Handler) GitHubWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if secret := os.Getenv("GITHUB_WEBHOOK_SECRET"); secret != "" {
+		if !validHubSignature(r.Header.Get("X-Hub-Signature-256"), body, secret) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var payload struct {
+		Action     string `json:"action"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+		WorkflowRun *struct {
+			WorkflowID int64  `json:"workflow_id"`
+			Name       string `json:"name"`
+			HeadBranch string `json:"head_branch"`
+			HeadSHA    string `json:"head_sha"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+			RunNumber  int    `json:"run_number"`
+		} `json:"workflow_run"`
+		Deployment *struct {
+			Environment string `json:"environment"`
+			Ref         string `json:"ref"`
+			SHA         string `json:"sha"`
+		} `json:"deployment"`
+		DeploymentStatus *struct {
+			State       string `json:"state"`
+			Description string `json:"description"`
+			TargetURL   string `json:"target_url"`
+			LogURL      string `json:"log_url"`
+		} `json:"deployment_status"`
+		Issue *struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+		} `json:"issue"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	repo := payload.Repository.FullName
+	labels := map[string]string{"repo": repo}
+	if payload.Sender.Login != "" {
+		labels["actor"] = payload.Sender.Login
+	}
+
+	var (
+		alert    models.Alert
+		resolved bool
+	)
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "pong"})
+		return
+
+	case "workflow_run":
+		run := payload.WorkflowRun
+		if run == nil || payload.Action != "completed" {
+			break
+		}
+		labels["branch"] = run.HeadBranch
+		labels["workflow"] = run.Name
+		labels["sha"] = run.HeadSHA
+		labels["url"] = run.HTMLURL
+		alert = models.Alert{
+			Fingerprint: fmt.Sprintf("github:%s:workflow:%d:%s", repo, run.WorkflowID, run.HeadBranch),
+			Title:       fmt.Sprintf("%s: %s #%d %s on %s", repo, run.Name, run.RunNumber, run.Conclusion, run.HeadBranch),
+			Message:     run.HTMLURL,
+		}
+		switch run.Conclusion {
+		case "failure", "timed_out", "startup_failure":
+			alert.Level = "error"
+		case "success":
+			resolved = true
+		default: // cancelled, skipped, neutral, action_required
+			alert = models.Alert{}
+		}
+
+	case "deployment_status":
+		dep, st := payload.Deployment, payload.DeploymentStatus
+		if dep == nil || st == nil {
+			break
+		}
+		labels["environment"] = dep.Environment
+		labels["branch"] = dep.Ref
+		labels["sha"] = dep.SHA
+		url := st.LogURL
+		if url == "" {
+			url = st.TargetURL
+		}
+		if url != "" {
+			labels["url"] = url
+		}
+		alert = models.Alert{
+			Fingerprint: fmt.Sprintf("github:%s:deploy:%s", repo, dep.Environment),
+			Title:       fmt.Sprintf("%s: deployment to %s %s", repo, dep.Environment, st.State),
+			Message:     strings.TrimSpace(st.Description + "\n" + url),
+		}
+		switch st.State {
+		case "failure", "error":
+			alert.Level = "critical"
+		case "success":
+			resolved = true
+			h.recordDeploy(r.Context(), models.DeployEvent{
+				Service:     repo,
+				Environment: dep.Environment,
+				Version:     dep.SHA,
+				URL:         url,
+				Actor:       payload.Sender.Login,
+				Provider:    "github",
+			})
+		default: // queued, pending, in_progress, inactive
+			alert = models.Alert{}
+		}
+
+	case "issues":
+		issue := payload.Issue
+		if issue == nil {
+			break
+		}
+		labels["issue"] = strconv.Itoa(issue.Number)
+		labels["url"] = issue.HTMLURL
+		alert = models.Alert{
+			Fingerprint: fmt.Sprintf("github:%s:issue:%d", repo, issue.Number),
+			Title:       fmt.Sprintf("%s#%d: %s", repo, issue.Number, issue.Title),
+			Message:     strings.TrimSpace(issue.Body + "\n" + issue.HTMLURL),
+		}
+		switch payload.Action {
+		case "opened", "reopened":
+			alert.Level = "info"
+		case "closed":
+			resolved = true
+		default:
+			alert = models.Alert{}
+		}
+
+	default:
+		log.Printf("Ignoring GitHub %q event", event)
+	}
+
+	if alert.Fingerprint == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "ignored"})
+		return
+	}
+
+	alert.Source = "github"
+	alert.Labels = labels
+	if alert.Message == "" {
+		alert.Message = "No content"
+	}
+
+	if resolved {
+		alert, err = h.AlertStore.ResolveAlert(r.Context(), alert.Fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			// Routine successes aren't worth an alert of their own
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"status": "ignored"})
+			return
+		}
+	} else {
+		alert, err = h.AlertStore.UpsertAlert(r.Context(), alert)
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           alert.ID,
+		"alert_status": alert.Status,
+	})
+}
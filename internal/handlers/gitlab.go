@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// GitLabWebhookHandler accepts GitLab project/group webhooks:
+//   - Pipeline Hook: failed pipelines open an alert per project and ref; the next
+//     successful pipeline on that ref resolves it
+//   - Deployment Hook: failed deployments open an alert per environment; success resolves it
+//     and is recorded for deploy correlation
+//   - Issue Hook (incidents only): opened/reopened incidents open an alert; closed resolves it
+//
+// When GITLAB_WEBHOOK_TOKEN is set, X-Gitlab-Token must match it.
+func (h *Handler) GitLabWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if secret := os.Getenv("GITLAB_WEBHOOK_TOKEN"); secret != "" {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var payload struct {
+		ObjectKind string `json:"object_kind"`
+		Project    struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+			WebURL            string `json:"web_url"`
+		} `json:"project"`
+		User struct {
+			Username string `json:"username"`
+		} `json:"user"`
+
+		// Pipeline and issue hooks
+		ObjectAttributes struct {
+			ID        int    `json:"id"`
+			IID       int    `json:"iid"`
+			Ref       string `json:"ref"`
+			SHA       string `json:"sha"`
+			Status    string `json:"status"`
+			URL       string `json:"url"`
+			Title     string `json:"title"`
+			State     string `json:"state"`
+			Action    string `json:"action"`
+			IssueType string `json:"issue_type"`
+			Severity  string `json:"severity"`
+		} `json:"object_attributes"`
+
+		// Deployment hooks
+		Status        string `json:"status"`
+		DeploymentID  int    `json:"deployment_id"`
+		Environment   string `json:"environment"`
+		Ref           string `json:"ref"`
+		ShortSHA      string `json:"short_sha"`
+		DeployableURL string `json:"deployable_url"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	project := payload.Project.PathWithNamespace
+	labels := map[string]string{"project": project}
+	if payload.User.Username != "" {
+		labels["actor"] = payload.User.Username
+	}
+
+	var (
+		alert    models.Alert
+		resolved bool
+	)
+	switch payload.ObjectKind {
+	case "pipeline":
+		attrs := payload.ObjectAttributes
+		url := attrs.URL
+		if url == "" {
+			url = fmt.Sprintf("%s/-/pipelines/%d", payload.Project.WebURL, attrs.ID)
+		}
+		labels["branch"] = attrs.Ref
+		labels["sha"] = attrs.SHA
+		labels["pipeline"] = strconv.Itoa(attrs.ID)
+		labels["url"] = url
+		alert = models.Alert{
+			Fingerprint: fmt.Sprintf("gitlab:%s:pipeline:%s", project, attrs.Ref),
+			Title:       fmt.Sprintf("%s: pipeline #%d %s on %s", project, attrs.ID, attrs.Status, attrs.Ref),
+			Message:     url,
+		}
+		switch attrs.Status {
+		case "failed":
+			alert.Level = "error"
+		case "success":
+			resolved = true
+		default: // created, pending, running, canceled, skipped, manual
+			alert = models.Alert{}
+		}
+
+	case "deployment":
+		labels["environment"] = payload.Environment
+		labels["branch"] = payload.Ref
+		labels["sha"] = payload.ShortSHA
+		if payload.DeployableURL != "" {
+			labels["url"] = payload.DeployableURL
+		}
+		alert = models.Alert{
+			Fingerprint: fmt.Sprintf("gitlab:%s:deploy:%s", project, payload.Environment),
+			Title:       fmt.Sprintf("%s: deployment to %s %s", project, payload.Environment, payload.Status),
+			Message:     strings.TrimSpace(payload.DeployableURL + "\n" + payload.Project.WebURL),
+		}
+		switch payload.Status {
+		case "failed":
+			alert.Level = "critical"
+		case "success":
+			resolved = true
+			h.recordDeploy(r.Context(), models.DeployEvent{
+				Service:     project,
+				Environment: payload.Environment,
+				Version:     payload.ShortSHA,
+				URL:         payload.DeployableURL,
+				Actor:       payload.User.Username,
+				Provider:    "gitlab",
+			})
+		default:
+			alert = models.Alert{}
+		}
+
+	case "issue":
+		attrs := payload.ObjectAttributes
+		if attrs.IssueType != "incident" {
+			break
+		}
+		labels["incident"] = strconv.Itoa(attrs.IID)
+		labels["url"] = attrs.URL
+		alert = models.Alert{
+			Fingerprint: fmt.Sprintf("gitlab:%s:incident:%d", project, attrs.IID),
+			Title:       fmt.Sprintf("%s incident #%d: %s", project, attrs.IID, attrs.Title),
+			Message:     attrs.URL,
+		}
+		switch attrs.Action {
+		case "open", "reopen":
+			alert.Level = gitlabIncidentLevel(attrs.Severity)
+		case "close":
+			resolved = true
+		case "update":
+			if attrs.State == "opened" {
+				alert.Level = gitlabIncidentLevel(attrs.Severity)
+			} else {
+				alert = models.Alert{}
+			}
+		default:
+			alert = models.Alert{}
+		}
+
+	default:
+		log.Printf("Ignoring GitLab %q event", payload.ObjectKind)
+	}
+
+	if alert.Fingerprint == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "ignored"})
+		return
+	}
+
+	alert.Source = "gitlab"
+	alert.Labels = labels
+	if alert.Message == "" {
+		alert.Message = "No content"
+	}
+
+	var err error
+	if resolved {
+		alert, err = h.AlertStore.ResolveAlert(r.Context(), alert.Fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			// Routine successes aren't worth an alert of their own
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"status": "ignored"})
+			return
+		}
+	} else {
+		alert, err = h.AlertStore.UpsertAlert(r.Context(), alert)
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           alert.ID,
+		"alert_status": alert.Status,
+	})
+}
+
+// gitlabIncidentLevel maps GitLab incident severities to levels
+func gitlabIncidentLevel(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "critical"
+	case "high":
+		return "error"
+	case "medium", "low":
+		return "warning"
+	default: // unknown
+		return "error"
+	}
+}
@@ -1,23 +1,80 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/tidwall/gjson"
+
+	"incident-viewer-go/internal/chaos"
+	"incident-viewer-go/internal/email"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/msgtemplate"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/saml"
+	"incident-viewer-go/internal/sms"
 	"incident-viewer-go/internal/store"
+	"incident-viewer-go/internal/tickets"
+	"incident-viewer-go/internal/webauthn"
 )
 
+// maxSSEReplay caps how far back an SSE client can ask to replay
+const maxSSEReplay = 24 * time.Hour
+
+// storeCallTimeout bounds a single store operation made on behalf of a
+// long-lived request, so a slow Redis/Postgres can't pin the stream
+const storeCallTimeout = 5 * time.Second
+
+// sseKeepAlive is how often an idle stream sends a comment line; a failed
+// write is how a client that vanished without closing gets noticed
+const sseKeepAlive = 25 * time.Second
+
+// sseChatRefresh is how often a stream re-reads its user's chats, so chats
+// assigned or taken away while it is open take effect
+const sseChatRefresh = time.Minute
+
+// storeContext derives a bounded context for one store call from parent.
+// It stays cancelled with parent, but never lives longer than storeCallTimeout.
+func storeContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, storeCallTimeout)
+}
+
 type Handler struct {
-	AlertStore store.AlertStore
-	AdminStore store.AdminStore
-	Tmpl       *template.Template
-	AdminTmpl  map[string]*template.Template
+	AlertStore    store.AlertStore
+	AdminStore    store.AdminStore
+	Tmpl          *template.Template
+	AdminTmpl     map[string]*template.Template
+	Tickets       *tickets.Service         // nil disables ticket sync
+	Chaos         *chaos.Injector          // nil disables fault injection
+	Email         *email.Sender            // nil without an SMTP relay
+	SMS           sms.Provider             // nil without an SMS provider
+	Sandbox       bool                     // Label push notifications as sandbox messages
+	Aging         AgingThresholds          // When open alerts count as aging
+	Templates     *msgtemplate.Renderer    // nil sends the built-in push messages
+	Brand         Branding                 // Name and logo on the HTML pages
+	Channels      map[string]ChannelTester // Testable integrations by channel kind
+	JWT           *JWTIssuer               // nil leaves login to cookie sessions
+	SAML          *saml.ServiceProvider    // nil disables SAML sign-in
+	WebAuthn      *webauthn.RelyingParty   // nil disables passkeys
+	Lockout       *LoginLockout            // nil never locks accounts
+	Passwords     PasswordPolicy           // What new passwords need, and when they expire
+	PasswordReset *PasswordReset           // nil: only admins reset passwords
+	Invitations   *Invitations             // nil: admins create accounts with passwords
+	Allowlist     *IPAllowlist             // nil: ingestion from any address, peers taken as clients
+	WebhookSecret string                   // Signs the shared-secret endpoints; empty takes unsigned requests
+
+	queue *notifyqueue.Queue // nil sends push notifications once, without retries
+	setup firstRunSetup
 }
 
 func NewHandler(alertStore store.AlertStore, adminStore store.AdminStore, tmpl *template.Template, adminTmpl map[string]*template.Template) *Handler {
@@ -41,15 +98,20 @@ func (h *Handler) RenderAdminPage(w http.ResponseWriter, page string, data any)
 }
 
 func (h *Handler) AdminLoginPage(w http.ResponseWriter, r *http.Request) {
-	h.RenderAdminPage(w, "login", nil)
+	h.RenderAdminPage(w, "login", h.newPageData(w, r))
 }
 
 func (h *Handler) AdminDashboardPage(w http.ResponseWriter, r *http.Request) {
-	userID, username, _ := GetCurrentUser(r)
-	h.RenderAdminPage(w, "dashboard", map[string]any{
-		"UserID":   userID,
-		"Username": username,
-	})
+	h.RenderAdminPage(w, "dashboard", h.newPageData(w, r))
+}
+
+// indexPage is the alert list, flat or grouped by source
+type indexPage struct {
+	pageData
+	Alerts  []models.Alert
+	Summary *models.AlertRollup // Last 24h, for the timeline
+	GroupBy string
+	Groups  []alertGroup
 }
 
 func (h *Handler) IndexHandler(w http.ResponseWriter, r *http.Request) {
@@ -58,40 +120,230 @@ func (h *Handler) IndexHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	alerts, err := h.AlertStore.GetAlerts(r.Context())
+	// Signed out, the page is the sign-in form; signed in, it lists the
+	// alerts of the chats the user may see
+	data := indexPage{pageData: h.newPageData(w, r)}
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != "source" {
+		http.Error(w, "Invalid group_by: only source is supported", http.StatusBadRequest)
+		return
+	}
+	if !data.Can.SignedIn {
+		data.GroupBy = groupBy
+		if err := h.Tmpl.Execute(w, data); err != nil {
+			log.Println("template error:", err)
+		}
+		return
+	}
+	visibility, err := h.chatVisibility(r.Context(), data.User.ID, data.User.Role)
 	if err != nil {
-		log.Println("Failed to get alerts:", err)
-		http.Error(w, "Failed to get alerts", http.StatusInternalServerError)
+		log.Printf("Failed to get chats of user %d: %v", data.User.ID, err)
+		http.Error(w, "Failed to get your chats", http.StatusServiceUnavailable)
 		return
 	}
 
-	if err := h.Tmpl.Execute(w, map[string]any{"Alerts": alerts}); err != nil {
+	switch groupBy {
+	case "":
+		// The timeline starts from the rollup (the last 24h, latest alerts
+		// per chat) rather than every stored alert
+		rollup, err := h.AlertStore.GetAlertRollup(r.Context())
+		if err == nil {
+			rollup = visibility.filterRollup(rollup)
+			data.Alerts, data.Summary = rollup.Latest(), &rollup
+			break
+		}
+		log.Println("Failed to get alert rollup, listing all alerts:", err)
+		alerts, err := h.AlertStore.GetAlerts(r.Context())
+		if err != nil {
+			log.Println("Failed to get alerts:", err)
+			http.Error(w, "Failed to get alerts", http.StatusInternalServerError)
+			return
+		}
+		data.Alerts = visibility.filter(alerts)
+	case "source":
+		alerts, err := h.AlertStore.GetAlerts(r.Context())
+		if err != nil {
+			log.Println("Failed to get alerts:", err)
+			http.Error(w, "Failed to get alerts", http.StatusInternalServerError)
+			return
+		}
+		data.GroupBy, data.Groups = groupBy, groupAlertsBySource(visibility.filter(alerts))
+	}
+
+	if err := h.Tmpl.Execute(w, data); err != nil {
 		log.Println("template error:", err)
 	}
 }
 
+// alertGroup summarizes the alerts of one source for the grouped index view
+type alertGroup struct {
+	Source   string
+	Count    int
+	Open     int       // Unresolved lifecycle alerts
+	MaxLevel string    // Most severe level seen
+	Latest   time.Time // Newest alert
+}
+
+// groupAlertsBySource aggregates alerts per source, most recently active first
+func groupAlertsBySource(alerts []models.Alert) []alertGroup {
+	index := make(map[string]int)
+	var groups []alertGroup
+	for _, a := range alerts {
+		i, ok := index[a.Source]
+		if !ok {
+			i = len(groups)
+			index[a.Source] = i
+			groups = append(groups, alertGroup{Source: a.Source, MaxLevel: a.Level})
+		}
+		g := &groups[i]
+		g.Count++
+		if a.Status == models.AlertStatusOpen {
+			g.Open++
+		}
+		if models.LevelRank(a.Level) > models.LevelRank(g.MaxLevel) {
+			g.MaxLevel = a.Level
+		}
+		if a.CreatedAt.After(g.Latest) {
+			g.Latest = a.CreatedAt
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Latest.After(groups[j].Latest) })
+	return groups
+}
+
+// SSEHandler streams alerts as they are created and change, to signed-in
+// users only, and only the alerts in chats they may see
+// GET /events?replay=15m
 func (h *Handler) SSEHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, role := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Optional ?replay=15m: send recent alerts before going live
+	var replaySince time.Time
+	if v := r.URL.Query().Get("replay"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "Invalid replay: use a duration like 15m", http.StatusBadRequest)
+			return
+		}
+		if d > maxSSEReplay {
+			d = maxSSEReplay
+		}
+		replaySince = time.Now().Add(-d)
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	ctx := r.Context()
+
+	lookupCtx, cancel := storeContext(ctx)
+	visibility, err := h.chatVisibility(lookupCtx, userID, role)
+	cancel()
+	if err != nil {
+		log.Printf("Failed to get chats of user %d for SSE: %v", userID, err)
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	// Subscribe to Redis channel
-	pubsub := h.AlertStore.Subscribe(r.Context())
+	// Subscribe to Redis channel (before replaying, so nothing falls in between;
+	// clients upsert by id, so an alert sent twice is harmless). The
+	// subscription outlives any single call, so it's tied to the request
+	// only through Close: the deferred one on return, and AfterFunc in case
+	// the client goes away while we're blocked elsewhere.
+	pubsub := h.AlertStore.Subscribe(context.Background())
 	defer pubsub.Close()
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer stop()
+
+	subCtx, cancel := storeContext(ctx)
+	_, err = pubsub.Receive(subCtx)
+	cancel()
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println("Failed to subscribe for SSE:", err)
+			http.Error(w, "Live updates unavailable", http.StatusServiceUnavailable)
+		}
+		return
+	}
 
 	ch := pubsub.Channel()
 
 	// Send initial connection message (optional)
 	fmt.Fprintf(w, "data: %s\n\n", "connected")
-	w.(http.Flusher).Flush()
+
+	if !replaySince.IsZero() {
+		replayCtx, cancel := storeContext(ctx)
+		alerts, err := h.AlertStore.GetAlertsSince(replayCtx, replaySince)
+		cancel()
+		if err != nil {
+			log.Println("Failed to replay alerts:", err)
+		}
+		sent := 0
+		for _, a := range alerts {
+			if !visibility.allows(a.Source) {
+				continue
+			}
+			data, err := json.Marshal(a)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			sent++
+		}
+		fmt.Fprintf(w, "event: live\ndata: %d\n\n", sent)
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	refresh := time.NewTicker(sseChatRefresh)
+	defer refresh.Stop()
 
 	for {
 		select {
-		case msg := <-ch:
-			fmt.Fprintf(w, "data: %s\n\n", msg.Payload)
-			w.(http.Flusher).Flush()
-		case <-r.Context().Done():
+		case msg, ok := <-ch:
+			if !ok {
+				// Closed under us (client gone or Redis connection lost)
+				return
+			}
+			if h.Chaos != nil && h.Chaos.DropSSE() {
+				continue
+			}
+			if !visibility.all {
+				var a struct {
+					Source string `json:"source"`
+				}
+				if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil || !visibility.allows(a.Source) {
+					continue
+				}
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", msg.Payload); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-refresh.C:
+			if visibility.all {
+				continue
+			}
+			lookupCtx, cancel := storeContext(ctx)
+			if v, err := h.chatVisibility(lookupCtx, userID, role); err == nil {
+				visibility = v
+			}
+			cancel()
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -103,14 +355,22 @@ func (h *Handler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !validateSharedSecret(r) {
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
+	// A named mapping profile says exactly where the vendor keeps each field
+	if profile := r.URL.Query().Get("profile"); profile != "" {
+		h.mappedWebhook(w, r, profile)
+		return
+	}
+	if isCloudEvent(r) {
+		h.cloudEventWebhook(w, r)
 		return
 	}
 
 	// Try JSON first
 	var payload map[string]any
-	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&payload); err == nil {
+		raw, _ = json.Marshal(payload)
+	} else {
 		// Fallback: form/query
 		if err := r.ParseForm(); err == nil && len(r.Form) > 0 {
 			payload = make(map[string]any)
@@ -124,61 +384,103 @@ func (h *Handler) WebhookHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	source := getString(payload["source"])
-	if source == "" {
-		source = r.URL.Query().Get("source")
-	}
-	if source == "" {
-		source = "unknown"
+	source, level, title, message := extractAlertFields(payload)
+	if source == "unknown" {
+		if qs := r.URL.Query().Get("source"); qs != "" {
+			source = qs
+		} else if t, ok := ingestTokenFromContext(r.Context()); ok {
+			source = t.Name
+		}
 	}
 
-	level := getString(payload["level"])
-	if level == "" {
-		level = getString(payload["severity"])
+	violations := h.checkPayloadSchema(r.Context(), source, payload)
+
+	a, err := h.AlertStore.UpsertAlert(r.Context(), models.Alert{
+		Source:  source,
+		Level:   level,
+		Title:   title,
+		Message: message,
+		Payload: raw,
+	})
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
 	}
-	if level == "" {
-		level = getString(payload["status"])
+	h.flagSchemaViolations(r.Context(), a, violations)
+
+	resp := map[string]any{
+		"status":     "ok",
+		"id":         a.ID,
+		"created_at": a.CreatedAt.Format(time.RFC3339),
 	}
-	if level == "" {
-		level = "info"
+	if len(violations) > 0 {
+		resp["schema_violations"] = violations
 	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
 
-	title := getString(payload["title"])
-	if title == "" {
-		title = getString(payload["alert_name"])
-	}
-	if title == "" {
-		title = getString(payload["event"])
+// mappedWebhook stores a /webhook?profile= payload using the profile's field
+// mapping. A mapped fingerprint dedupes repeats, and resolves the open alert
+// when the level maps to success/resolved.
+func (h *Handler) mappedWebhook(w http.ResponseWriter, r *http.Request, profile string) {
+	m, err := h.AdminStore.GetWebhookMappingByName(r.Context(), profile)
+	if err != nil {
+		http.Error(w, "Unknown mapping profile", http.StatusBadRequest)
+		return
 	}
-	if title == "" {
-		title = "Alert"
+	body, err := io.ReadAll(r.Body)
+	if err != nil || !gjson.ValidBytes(body) {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
 	}
 
-	var message string
-	for _, key := range []string{"message", "description", "detail"} {
-		if v, ok := payload[key]; ok {
-			message = getString(v)
-			if message != "" {
-				break
-			}
+	f := applyWebhookMapping(m, body)
+	var payload any
+	_ = json.Unmarshal(body, &payload)
+	violations := h.checkPayloadSchema(r.Context(), f.Source, payload)
+
+	var a models.Alert
+	switch {
+	case f.Fingerprint == "":
+		a, err = h.AlertStore.UpsertAlert(r.Context(), models.Alert{
+			Source:  f.Source,
+			Level:   f.Level,
+			Title:   f.Title,
+			Message: f.Message,
+			Payload: body,
+		})
+	case f.Level == models.LevelSuccess:
+		a, err = h.AlertStore.ResolveAlert(r.Context(), f.Fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			a, err = h.AlertStore.AddAlert(r.Context(), f.Source, f.Level, f.Title, f.Message)
 		}
+	default:
+		a, err = h.AlertStore.UpsertAlert(r.Context(), models.Alert{
+			Source:      f.Source,
+			Level:       f.Level,
+			Title:       f.Title,
+			Message:     f.Message,
+			Fingerprint: f.Fingerprint,
+			Payload:     body,
+		})
 	}
-	if message == "" {
-		buf, _ := json.MarshalIndent(payload, "", "  ")
-		message = string(buf)
-	}
-
-	a, err := h.AlertStore.AddAlert(r.Context(), source, level, title, message)
 	if err != nil {
 		log.Println("Failed to add alert:", err)
 		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
 		return
 	}
+	h.flagSchemaViolations(r.Context(), a, violations)
 
 	resp := map[string]any{
-		"status":     "ok",
-		"id":         a.ID,
-		"created_at": a.CreatedAt.Format(time.RFC3339),
+		"status":       "ok",
+		"id":           a.ID,
+		"alert_status": a.Status,
+		"created_at":   a.CreatedAt.Format(time.RFC3339),
+	}
+	if len(violations) > 0 {
+		resp["schema_violations"] = violations
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
@@ -270,16 +572,88 @@ func (h *Handler) TelegramHandler(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(resp)
 }
 
+// ClearHandler removes alerts from the timeline. Unlike a purge the alert
+// records are kept until their TTL expires. Without chat_id the whole timeline
+// is cleared (admin only); with chat_id only that chat is cleared and the user
+// must have access to it. chat_id=general clears alerts not bound to a chat.
 func (h *Handler) ClearHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	h.AlertStore.ClearAlerts(r.Context())
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+
+	userID, _, role := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	isJSON := strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+	var chatID string
+	if isJSON {
+		var req struct {
+			ChatID string `json:"chat_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		chatID = req.ChatID
+	} else {
+		chatID = r.FormValue("chat_id")
+	}
+
+	var filter store.PurgeFilter
+	switch {
+	case chatID == "":
+		// Every organization's alerts
+		if role != "admin" || !h.inDefaultOrg(r.Context(), userID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+	case chatID == "general":
+		if !canSeeAllChats(role) || !h.inDefaultOrg(r.Context(), userID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		filter.NoChat = true
+	default:
+		if !h.userCanAccessChat(r.Context(), userID, role, chatID) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		filter.ChatID = chatID
+	}
+
+	count, err := h.AlertStore.ClearAlertsFiltered(r.Context(), filter)
+	if err != nil {
+		log.Println("Failed to clear alerts:", err)
+		http.Error(w, "Failed to clear alerts", http.StatusInternalServerError)
+		return
+	}
+
+	meta, _ := json.Marshal(map[string]any{"chat_id": chatID, "count": count})
+	_ = h.AdminStore.InsertAudit(r.Context(), userID, "clear_alerts", "system", 0, string(meta))
+
+	if !isJSON {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "count": count})
 }
 
+// SearchHandler searches the alerts of the chats the user may see
+// GET /api/search?q=disk&level=critical&source=bot:ci
 func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, role := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	visibility, err := h.chatVisibility(r.Context(), userID, role)
+	if err != nil {
+		log.Printf("Failed to get chats of user %d: %v", userID, err)
+		http.Error(w, "Failed to get your chats", http.StatusServiceUnavailable)
+		return
+	}
 	query := r.URL.Query().Get("q")
 	level := r.URL.Query().Get("level")
 	source := r.URL.Query().Get("source")
@@ -290,6 +664,7 @@ func (h *Handler) SearchHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Search failed", http.StatusInternalServerError)
 		return
 	}
+	alerts = visibility.filter(alerts)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
@@ -304,11 +679,6 @@ func (h *Handler) SlackWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !validateSharedSecret(r) {
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
-		return
-	}
-
 	var payload struct {
 		Text        string `json:"text"`
 		Attachments []struct {
@@ -366,11 +736,6 @@ func (h *Handler) DiscordWebhookHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if !validateSharedSecret(r) {
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
-		return
-	}
-
 	var payload struct {
 		Content string `json:"content"`
 		Embeds  []struct {
@@ -422,6 +787,53 @@ func (h *Handler) DiscordWebhookHandler(w http.ResponseWriter, r *http.Request)
 	fmt.Fprintf(w, "ok: %d", a.ID)
 }
 
+// extractAlertFields guesses source/level/title/message from a free-form
+// payload, falling back to defaults (and the raw payload as message).
+func extractAlertFields(payload map[string]any) (source, level, title, message string) {
+	source = getString(payload["source"])
+	if source == "" {
+		source = "unknown"
+	}
+
+	level = getString(payload["level"])
+	if level == "" {
+		level = getString(payload["severity"])
+	}
+	if level == "" {
+		level = getString(payload["status"])
+	}
+	level = models.NormalizeLevel(level)
+	if level == "" {
+		level = "info"
+	}
+
+	title = getString(payload["title"])
+	if title == "" {
+		title = getString(payload["alert_name"])
+	}
+	if title == "" {
+		title = getString(payload["event"])
+	}
+	if title == "" {
+		title = "Alert"
+	}
+
+	for _, key := range []string{"message", "description", "detail"} {
+		if v, ok := payload[key]; ok {
+			message = getString(v)
+			if message != "" {
+				break
+			}
+		}
+	}
+	if message == "" {
+		buf, _ := json.MarshalIndent(payload, "", "  ")
+		message = string(buf)
+	}
+
+	return source, level, title, message
+}
+
 func getString(v any) string {
 	switch t := v.(type) {
 	case string:
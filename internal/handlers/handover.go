@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/language"
+
+	"incident-viewer-go/internal/models"
+)
+
+const (
+	handoverCheckInterval = 5 * time.Minute // How often to look for rotation handoffs
+	handoverMaxDelay      = time.Hour       // Skip handoffs older than this, e.g. after downtime
+	handoverListLimit     = 10              // Alerts listed per report section
+)
+
+// handoverReport sums up one rotation shift for the people handing over
+type handoverReport struct {
+	ScheduleID    int            `json:"schedule_id"`
+	ScheduleName  string         `json:"schedule_name"`
+	Outgoing      string         `json:"outgoing"`
+	Incoming      string         `json:"incoming"`
+	ShiftStart    time.Time      `json:"shift_start"`
+	ShiftEnd      time.Time      `json:"shift_end"`
+	Handled       int            `json:"handled"`  // Alerts raised during the shift
+	Resolved      int            `json:"resolved"` // Alerts resolved during the shift
+	Critical      int            `json:"critical"`
+	OpenCount     int            `json:"open_count"`
+	FollowUpCount int            `json:"follow_up_count"`
+	Open          []models.Alert `json:"open"`       // Still open now
+	Incidents     []models.Alert `json:"incidents"`  // Notable alerts raised during the shift
+	FollowUps     []models.Alert `json:"follow_ups"` // Resolved during the shift, ticket still open
+}
+
+// RunHandoverReports sends a report to the outgoing and incoming on-call of
+// every schedule whose rotation hands off. Each handoff is claimed in Redis,
+// so only one replica reports it.
+func (h *Handler) RunHandoverReports(ctx context.Context) {
+	ticker := time.NewTicker(handoverCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		h.sendHandoverReports(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) sendHandoverReports(ctx context.Context) {
+	schedules, err := h.AdminStore.GetSchedules(ctx)
+	if err != nil {
+		log.Printf("Failed to check on-call handoffs: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, sc := range schedules {
+		start, _, turn, ok := sc.ShiftAt(now)
+		if !ok || turn == 0 || len(sc.Participants) == 0 || now.Sub(start) > handoverMaxDelay {
+			continue
+		}
+		first, err := h.AlertStore.ClaimNonce(ctx, fmt.Sprintf("handover:%d:%d", sc.ID, start.Unix()), 2*handoverMaxDelay)
+		if err != nil {
+			log.Printf("Failed to claim handoff of schedule %d: %v", sc.ID, err)
+			continue
+		}
+		if !first {
+			continue
+		}
+
+		n := len(sc.Participants)
+		recipients := []int{sc.Participants[(turn-1)%n]}
+		if incoming := sc.Participants[turn%n]; incoming != recipients[0] {
+			recipients = append(recipients, incoming)
+		}
+		for _, userID := range recipients {
+			user, err := h.AdminStore.GetUser(ctx, userID)
+			if err != nil || !h.FeatureEnabled(ctx, models.FeatureOnCall, user.ID, user.Role) {
+				continue
+			}
+			report, err := h.handoverReport(ctx, sc, start, userID)
+			if err != nil {
+				log.Printf("Failed to build handover report of schedule %d: %v", sc.ID, err)
+				break
+			}
+			h.SendUserPushNotification(userID, handoverSummary(report, sc.Timezone))
+		}
+	}
+}
+
+// handoverReport builds the report of the rotation shift that ended at the
+// handoff end, limited to the alerts the user can see
+func (h *Handler) handoverReport(ctx context.Context, sc models.Schedule, end time.Time, userID int) (handoverReport, error) {
+	start, _, turn, ok := sc.ShiftAt(end.Add(-time.Second))
+	if !ok || len(sc.Participants) == 0 {
+		return handoverReport{}, fmt.Errorf("schedule %d has no shift before %s", sc.ID, end)
+	}
+
+	user, err := h.AdminStore.GetUser(ctx, userID)
+	if err != nil {
+		return handoverReport{}, err
+	}
+	visibility, err := h.chatVisibility(ctx, userID, user.Role)
+	if err != nil {
+		return handoverReport{}, err
+	}
+	alerts, err := h.AlertStore.GetAlerts(ctx)
+	if err != nil {
+		return handoverReport{}, err
+	}
+
+	n := len(sc.Participants)
+	report := handoverReport{
+		ScheduleID:   sc.ID,
+		ScheduleName: sc.Name,
+		Outgoing:     h.username(ctx, sc.Participants[turn%n]),
+		Incoming:     h.username(ctx, sc.Participants[(turn+1)%n]),
+		ShiftStart:   start,
+		ShiftEnd:     end,
+		Open:         []models.Alert{},
+		Incidents:    []models.Alert{},
+		FollowUps:    []models.Alert{},
+	}
+	for _, a := range alerts {
+		if !visibility.allows(a.Source) {
+			continue
+		}
+
+		if a.Status == models.AlertStatusOpen {
+			report.OpenCount++
+			report.Open = append(report.Open, a)
+		}
+		if !a.CreatedAt.Before(start) && a.CreatedAt.Before(end) {
+			report.Handled++
+			if models.LevelRank(a.Level) >= models.LevelRank("critical") {
+				report.Critical++
+			}
+			if a.Status == models.AlertStatusOpen || models.LevelRank(a.Level) >= models.LevelRank("critical") {
+				report.Incidents = append(report.Incidents, a)
+			}
+		}
+		if a.ResolvedAt != nil && !a.ResolvedAt.Before(start) && a.ResolvedAt.Before(end) {
+			report.Resolved++
+			if tickets, err := h.AdminStore.GetAlertTickets(ctx, a.ID); err == nil {
+				for _, t := range tickets {
+					if t.Status == "open" {
+						report.FollowUpCount++
+						report.FollowUps = append(report.FollowUps, a)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for _, list := range []*[]models.Alert{&report.Open, &report.Incidents, &report.FollowUps} {
+		sortNotable(*list)
+		if len(*list) > handoverListLimit {
+			*list = (*list)[:handoverListLimit]
+		}
+	}
+	return report, nil
+}
+
+// username is a user's name for reports, or their ID if they're gone
+func (h *Handler) username(ctx context.Context, userID int) string {
+	if u, err := h.AdminStore.GetUser(ctx, userID); err == nil {
+		return u.Username
+	}
+	return fmt.Sprintf("user %d", userID)
+}
+
+// handoverSummary is the push notification version of the report
+func handoverSummary(r handoverReport, timezone string) string {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	rf := newReportFormat(loc, language.Und)
+	msg := fmt.Sprintf("🔁 %s handover %s → %s at %s: %s raised (%s critical), %s resolved, %s still open",
+		r.ScheduleName, r.Outgoing, r.Incoming, rf.Time(r.ShiftEnd),
+		rf.Int(r.Handled), rf.Int(r.Critical), rf.Int(r.Resolved), rf.Int(r.OpenCount))
+	if r.FollowUpCount > 0 {
+		msg += fmt.Sprintf(", %s follow-up(s) with open tickets", rf.Int(r.FollowUpCount))
+	}
+	return msg + fmt.Sprintf(". Full report: /api/oncall/handover?schedule_id=%d", r.ScheduleID)
+}
+
+func formatHandoverText(r handoverReport, rf reportFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s handover: %s → %s*\n", r.ScheduleName, r.Outgoing, r.Incoming)
+	fmt.Fprintf(&b, "Shift %s to %s: %s raised, %s resolved, %s critical\n",
+		rf.Time(r.ShiftStart), rf.Time(r.ShiftEnd), rf.Int(r.Handled), rf.Int(r.Resolved), rf.Int(r.Critical))
+
+	sections := []struct {
+		title  string
+		alerts []models.Alert
+		total  int
+	}{
+		{fmt.Sprintf("Open alerts (%s)", rf.Int(r.OpenCount)), r.Open, r.OpenCount},
+		{"Incidents during the shift", r.Incidents, len(r.Incidents)},
+		{"Pending follow-ups (resolved, ticket still open)", r.FollowUps, r.FollowUpCount},
+	}
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n*%s*\n", s.title)
+		if len(s.alerts) == 0 {
+			b.WriteString("None.\n")
+			continue
+		}
+		for _, a := range s.alerts {
+			fmt.Fprintf(&b, "• [%s] %s, %s\n", strings.ToUpper(a.Level), a.Title, rf.Time(a.CreatedAt))
+		}
+		if more := s.total - len(s.alerts); more > 0 {
+			fmt.Fprintf(&b, "_and %d more_\n", more)
+		}
+	}
+	return b.String()
+}
+
+// HandoverReportHandler shows the report of a schedule's latest handoff, as
+// the current user would have received it.
+// GET /api/oncall/handover?schedule_id=...&format=text|json&tz=...&locale=...
+// Times are in the schedule's time zone unless tz is given.
+func (h *Handler) HandoverReportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _, _ := GetCurrentUser(r)
+	id, err := strconv.Atoi(r.URL.Query().Get("schedule_id"))
+	if err != nil {
+		http.Error(w, "Invalid schedule_id", http.StatusBadRequest)
+		return
+	}
+	sc, err := h.AdminStore.GetSchedule(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	rf, err := reportFormatFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("tz") == "" {
+		if loc, err := time.LoadLocation(sc.Timezone); err == nil {
+			rf = newReportFormat(loc, rf.Locale)
+		}
+	}
+
+	start, _, turn, ok := sc.ShiftAt(time.Now())
+	if !ok || turn == 0 {
+		http.Error(w, "No handoff yet", http.StatusNotFound)
+		return
+	}
+	report, err := h.handoverReport(r.Context(), sc, start, userID)
+	if err != nil {
+		http.Error(w, "Failed to build report", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, formatHandoverText(report, rf))
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	heartbeatCheckInterval = 30 * time.Second
+	minHeartbeatInterval   = 60 // seconds; the worker only looks every 30s
+)
+
+// HeartbeatCheckInHandler records a check-in for a heartbeat monitor and
+// resolves its missed check-in alert, if any.
+// POST /api/heartbeat/{token} (GET works too, for plain cron + curl)
+func (h *Handler) HeartbeatCheckInHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, "/api/heartbeat/")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	hb, err := h.AdminStore.CheckInHeartbeat(r.Context(), token)
+	if err != nil {
+		http.Error(w, "Unknown heartbeat", http.StatusNotFound)
+		return
+	}
+
+	if _, err := h.AlertStore.ResolveAlert(r.Context(), hb.Fingerprint()); err != nil && !errors.Is(err, store.ErrAlertNotFound) {
+		log.Printf("Failed to resolve heartbeat alert for %q: %v", hb.Name, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"status": "ok", "next_due": hb.Deadline().Format(time.RFC3339)})
+}
+
+// RunHeartbeats raises an alert for every heartbeat monitor whose check-in is
+// overdue. The next check-in resolves it (HeartbeatCheckInHandler).
+func (h *Handler) RunHeartbeats(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.checkHeartbeats(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (h *Handler) checkHeartbeats(ctx context.Context) {
+	heartbeats, err := h.AdminStore.GetHeartbeats(ctx)
+	if err != nil {
+		log.Printf("Failed to check heartbeats: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, hb := range heartbeats {
+		deadline := hb.Deadline()
+		if now.Before(deadline) {
+			continue
+		}
+		if _, err := h.AlertStore.GetOpenAlert(ctx, hb.Fingerprint()); err == nil {
+			continue // Already alerted
+		}
+
+		last := "never"
+		if hb.LastPingAt != nil {
+			last = hb.LastPingAt.UTC().Format(time.RFC3339)
+		}
+		_, err := h.AlertStore.UpsertAlert(ctx, models.Alert{
+			Source:      "heartbeat",
+			Level:       hb.Level,
+			Title:       fmt.Sprintf("Missed heartbeat: %s", hb.Name),
+			Message:     fmt.Sprintf("%s expected a check-in every %s and was due at %s. Last check-in: %s.", hb.Name, time.Duration(hb.IntervalSeconds)*time.Second, deadline.UTC().Format(time.RFC3339), last),
+			Labels:      map[string]string{"heartbeat": hb.Name},
+			Fingerprint: hb.Fingerprint(),
+		})
+		if err != nil {
+			log.Printf("Failed to raise heartbeat alert for %q: %v", hb.Name, err)
+		}
+	}
+}
+
+func validateHeartbeat(hb *models.Heartbeat) error {
+	hb.Name = strings.TrimSpace(hb.Name)
+	if hb.Name == "" {
+		return errors.New("name is required")
+	}
+	if hb.IntervalSeconds < minHeartbeatInterval {
+		return fmt.Errorf("interval_seconds must be at least %d", minHeartbeatInterval)
+	}
+	if hb.GraceSeconds < 0 {
+		return errors.New("grace_seconds can't be negative")
+	}
+	if hb.Level == "" {
+		hb.Level = models.LevelCritical
+	}
+	hb.Level = models.NormalizeLevel(hb.Level)
+	switch hb.Level {
+	case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo:
+	default:
+		return fmt.Errorf("unknown level %q", hb.Level)
+	}
+	return nil
+}
+
+// === Heartbeat Management ===
+
+func (h *Handler) GetHeartbeatsHandler(w http.ResponseWriter, r *http.Request) {
+	heartbeats, err := h.AdminStore.GetHeartbeats(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get heartbeats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"heartbeats": heartbeats})
+}
+
+func (h *Handler) CreateHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	var hb models.Heartbeat
+	if err := json.NewDecoder(r.Body).Decode(&hb); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateHeartbeat(&hb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actorID, _, _ := GetCurrentUser(r)
+	hb.CreatedBy = actorID
+	hb, err := h.AdminStore.CreateHeartbeat(r.Context(), hb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": hb.Name, "interval_seconds": hb.IntervalSeconds})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_heartbeat", "heartbeat", hb.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "heartbeat": hb})
+}
+
+func (h *Handler) UpdateHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/heartbeats/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var hb models.Heartbeat
+	if err := json.NewDecoder(r.Body).Decode(&hb); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	hb.ID = id
+	if err := validateHeartbeat(&hb); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hb, err = h.AdminStore.UpdateHeartbeat(r.Context(), hb)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": hb.Name, "interval_seconds": hb.IntervalSeconds})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_heartbeat", "heartbeat", hb.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "heartbeat": hb})
+}
+
+func (h *Handler) DeleteHeartbeatHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/heartbeats/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteHeartbeat(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// Don't leave a missed check-in open for a monitor that no longer exists
+	if _, err := h.AlertStore.ResolveAlert(r.Context(), models.Heartbeat{ID: id}.Fingerprint()); err != nil && !errors.Is(err, store.ErrAlertNotFound) {
+		log.Printf("Failed to resolve heartbeat alert %d: %v", id, err)
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_heartbeat", "heartbeat", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
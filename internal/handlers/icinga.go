@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// IcingaWebhookHandler accepts notifications from an Icinga2 (or Nagios)
+// notification command, as JSON or form fields:
+//
+//	host=$host.name$ service=$service.name$ state=$service.state$
+//	output=$service.output$ notification_type=$notification.type$
+//
+// Host notifications omit service and use UP/DOWN/UNREACHABLE states.
+// Alerts are keyed by host!service, so an OK/UP state resolves the open problem.
+func (h *Handler) IcingaWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload map[string]any
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+	} else if err := r.ParseForm(); err == nil {
+		payload = make(map[string]any)
+		for k, v := range r.Form {
+			if len(v) > 0 {
+				payload[k] = v[0]
+			}
+		}
+	}
+
+	host := getString(payload["host"])
+	if host == "" {
+		http.Error(w, "host is required", http.StatusBadRequest)
+		return
+	}
+	service := getString(payload["service"])
+	state := strings.ToUpper(getString(payload["state"]))
+	notificationType := strings.ToUpper(getString(payload["notification_type"]))
+
+	title := host
+	if service != "" {
+		title = fmt.Sprintf("%s / %s", host, service)
+	}
+	title = fmt.Sprintf("%s is %s", title, state)
+
+	message := getString(payload["output"])
+	if message == "" {
+		message = "No output"
+	}
+
+	// Acknowledgements, downtimes and flapping don't change the problem state
+	switch notificationType {
+	case "ACKNOWLEDGEMENT", "DOWNTIMESTART", "DOWNTIMEEND", "DOWNTIMEREMOVED",
+		"FLAPPINGSTART", "FLAPPINGEND", "CUSTOM":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "ignored"})
+		return
+	}
+
+	fingerprint := "icinga:" + host + "!" + service
+
+	var (
+		a   models.Alert
+		err error
+	)
+	if state == "OK" || state == "UP" || notificationType == "RECOVERY" {
+		a, err = h.AlertStore.ResolveAlert(r.Context(), fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			a, err = h.AlertStore.AddAlert(r.Context(), "icinga", "success", title, message)
+		}
+	} else {
+		a, err = h.AlertStore.AddFingerprintedAlert(r.Context(), fingerprint, "icinga", icingaLevel(state), title, message)
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           a.ID,
+		"alert_status": a.Status,
+	})
+}
+
+// icingaLevel maps Icinga2/Nagios service and host states to levels
+func icingaLevel(state string) string {
+	switch state {
+	case "CRITICAL", "DOWN":
+		return "critical"
+	case "WARNING":
+		return "warning"
+	case "UNKNOWN", "UNREACHABLE":
+		return "error"
+	default:
+		return "info"
+	}
+}
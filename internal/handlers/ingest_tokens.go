@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+type ingestTokenKey struct{}
+
+// ingestTokenFromContext returns the bearer token a request authenticated with, if any
+func ingestTokenFromContext(ctx context.Context) (models.IngestToken, bool) {
+	t, ok := ctx.Value(ingestTokenKey{}).(models.IngestToken)
+	return t, ok
+}
+
+// IngestTokenMiddleware accepts "Authorization: Bearer <ingest token>" as an
+// alternative to the HMAC signature checked by fallback. Ingest and admin
+// API keys are accepted the same way, named after the key. Requests without
+// a bearer token, or with a read-only key or access token, go through
+// fallback unchanged.
+func (h *Handler) IngestTokenMiddleware(fallback func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		signed := fallback(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := jwtUserFromContext(r.Context()); ok {
+				signed.ServeHTTP(w, r)
+				return
+			}
+			if k, ok := apiKeyFromContext(r.Context()); ok {
+				if k.Scope == models.APIKeyScopeRead {
+					signed.ServeHTTP(w, r)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ingestTokenKey{}, models.IngestToken{Name: k.Name})))
+				return
+			}
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok {
+				signed.ServeHTTP(w, r)
+				return
+			}
+
+			t, err := h.AdminStore.AuthenticateIngestToken(r.Context(), strings.TrimSpace(bearer))
+			if err != nil {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			noteUsage(r.Context(), models.CredentialIngestToken, t.ID)
+			if !allowBotToken("ingest:"+strconv.Itoa(t.ID), t.RateLimit) {
+				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ingestTokenKey{}, t)))
+		})
+	}
+}
+
+// === Ingest Token Management ===
+
+func (h *Handler) GetIngestTokensHandler(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.AdminStore.GetIngestTokens(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get ingest tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"tokens": tokens})
+}
+
+func (h *Handler) CreateIngestTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name      string `json:"name"`
+		RateLimit int    `json:"rate_limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	actorID, _, _ := GetCurrentUser(r)
+	t, token, err := h.AdminStore.CreateIngestToken(r.Context(), req.Name, req.RateLimit, actorID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": t.Name, "prefix": t.Prefix, "rate_limit": t.RateLimit})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_ingest_token", "ingest_token", t.ID, string(meta))
+	}
+
+	// Only the hash is kept, so this is the one chance to copy the token
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "token": token, "ingest_token": t})
+}
+
+func (h *Handler) RevokeIngestTokenHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/admin/ingest-tokens/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.RevokeIngestToken(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "revoke_ingest_token", "ingest_token", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// IntegrationHealthHandler summarizes ingestion per integration endpoint:
+// request counts, error rate and latency over the last hours (default and
+// max 24, which is how long the hourly counters are kept).
+// GET /api/admin/integrations/health?hours=24
+func (h *Handler) IntegrationHealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	hours := 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 24 {
+			http.Error(w, "hours must be between 1 and 24", http.StatusBadRequest)
+			return
+		}
+		hours = n
+	}
+
+	stats, err := h.AlertStore.GetIngestStats(r.Context(), hours)
+	if err != nil {
+		http.Error(w, "Failed to get integration health", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"integrations": stats, "hours": hours})
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"incident-viewer-go/internal/email"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// tokenTypeInvitation marks invitation tokens
+const tokenTypeInvitation = "invitation"
+
+// Invitations lets admins invite people by email instead of choosing their
+// passwords: the invitee follows a signed link and sets their own username
+// and password, and gets the role and chats the admin picked. A link works
+// until TTL passes, the invitation is revoked or someone accepts it.
+type Invitations struct {
+	secret    []byte
+	publicURL string
+	TTL       time.Duration
+}
+
+// InvitationsFromEnv reads INVITE_SECRET and INVITE_TTL (default 72h).
+// Without the secret it returns nil, and admins create accounts with
+// passwords. The links point at publicURL (SENTINEL_PUBLIC_URL), so it is
+// required.
+func InvitationsFromEnv(publicURL string) (*Invitations, error) {
+	secret := os.Getenv("INVITE_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	if len(secret) < minJWTSecretLen {
+		return nil, fmt.Errorf("INVITE_SECRET must be at least %d characters", minJWTSecretLen)
+	}
+	if publicURL == "" {
+		return nil, fmt.Errorf("INVITE_SECRET needs SENTINEL_PUBLIC_URL for the links in invitations")
+	}
+	inv := &Invitations{secret: []byte(secret), publicURL: strings.TrimSuffix(publicURL, "/"), TTL: 72 * time.Hour}
+	if v := os.Getenv("INVITE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("INVITE_TTL: want a duration like 72h, got %q", v)
+		}
+		inv.TTL = d
+	}
+	return inv, nil
+}
+
+// link is the invitation's acceptance link; like reset links, it carries the
+// token in the fragment
+func (i *Invitations) link(inv models.Invitation) (string, error) {
+	token, err := signLinkToken(i.secret, jwtClaims{
+		Type:             tokenTypeInvitation,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.Itoa(inv.ID)},
+	}, time.Until(inv.ExpiresAt))
+	if err != nil {
+		return "", err
+	}
+	return i.publicURL + "/#invite=" + url.QueryEscape(token), nil
+}
+
+// GetInvitationsHandler lists invitations, pending and past
+// GET /api/admin/invitations
+func (h *Handler) GetInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
+	all, err := h.AdminStore.GetInvitations(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get invitations", http.StatusInternalServerError)
+		return
+	}
+	invitations := all[:0]
+	for _, inv := range all {
+		if scope.has(inv.OrgID) {
+			invitations = append(invitations, inv)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"invitations": invitations, "available": h.Invitations != nil})
+}
+
+// CreateInvitationHandler invites someone, emailing them the link when email
+// is configured. The link is in the answer either way, for the admin to pass
+// on. The account is made in the admin's organization unless org_id says
+// otherwise.
+// POST /api/admin/invitations {"email": "...", "role": "user", "chat_ids": [1], "org_id": 2}
+func (h *Handler) CreateInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if h.Invitations == nil {
+		http.Error(w, "Invitations are not configured: set INVITE_SECRET", http.StatusServiceUnavailable)
+		return
+	}
+	var req struct {
+		Email   string `json:"email"`
+		Role    string `json:"role"`
+		ChatIDs []int  `json:"chat_ids"`
+		OrgID   int    `json:"org_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	orgID, ok := h.newMemberOrg(w, r, req.OrgID)
+	if !ok {
+		return
+	}
+	addresses, err := parseRecipients([]string{req.Email})
+	if err != nil {
+		http.Error(w, "email must be an address like alice@example.com", http.StatusBadRequest)
+		return
+	}
+	if req.Role != "admin" && req.Role != "developer" && req.Role != "user" {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "admin" {
+		req.ChatIDs = nil // Admins see every chat
+	}
+	if !h.checkOrgChats(w, r, orgID, req.ChatIDs) {
+		return
+	}
+
+	actorID, _, _ := GetCurrentUser(r)
+	inv := models.Invitation{
+		Email:     addresses[0],
+		Role:      req.Role,
+		ChatIDs:   req.ChatIDs,
+		ExpiresAt: time.Now().Add(h.Invitations.TTL),
+		OrgID:     orgID,
+	}
+	if actorID != 0 {
+		inv.InvitedBy = &actorID
+	}
+	inv, err = h.AdminStore.CreateInvitation(r.Context(), inv)
+	if err != nil {
+		log.Printf("Failed to create invitation: %v", err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+	link, err := h.Invitations.link(inv)
+	if err != nil {
+		http.Error(w, "Failed to sign invitation", http.StatusInternalServerError)
+		return
+	}
+
+	emailed := false
+	if h.Email != nil {
+		msg := email.Message{
+			To:      []string{inv.Email},
+			Subject: "You're invited to " + h.Brand.Name,
+			Text: fmt.Sprintf("You've been invited to %s as a %s.\n\n"+
+				"Choose a username and password here to create your account; the link works once, until %s:\n%s\n",
+				h.Brand.Name, inv.Role, inv.ExpiresAt.UTC().Format("2006-01-02 15:04 UTC"), link),
+		}
+		if err := h.Email.SendQueued(r.Context(), msg); err != nil {
+			log.Printf("Failed to send invitation to %s: %v", inv.Email, err)
+		} else {
+			emailed = true
+		}
+	}
+
+	if actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"email": inv.Email, "role": inv.Role, "chat_ids": inv.ChatIDs, "org_id": inv.OrgID, "emailed": emailed})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_invitation", "invitation", inv.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "invitation": inv, "link": link, "emailed": emailed})
+}
+
+// DeleteInvitationHandler revokes a pending invitation; its link stops working
+// DELETE /api/admin/invitations/{id}
+func (h *Handler) DeleteInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/invitations/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
+	if inv, err := h.AdminStore.GetInvitation(r.Context(), id); err != nil || !scope.has(inv.OrgID) {
+		http.Error(w, "Invitation not found or already accepted", http.StatusNotFound)
+		return
+	}
+	if err := h.AdminStore.DeleteInvitation(r.Context(), id); err != nil {
+		if errors.Is(err, store.ErrInvitationNotPending) {
+			http.Error(w, "Invitation not found or already accepted", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "revoke_invitation", "invitation", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// AcceptInvitationHandler creates the invitee's account with the username and
+// password they chose, and signs them in
+// POST /api/invitations/accept {"token": "...", "username": "...", "password": "..."}
+func (h *Handler) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Invitations == nil {
+		http.Error(w, "Invitations are not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var req struct {
+		Token    string `json:"token"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	fail := func(status int, msg string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg})
+	}
+	const invalid = "This invitation is invalid, expired, revoked or already used; ask an admin for a new one"
+	_, id, err := parseLinkToken(h.Invitations.secret, req.Token, tokenTypeInvitation)
+	if err != nil {
+		fail(http.StatusBadRequest, invalid)
+		return
+	}
+	if inv, err := h.AdminStore.GetInvitation(r.Context(), id); err != nil || !inv.Pending(time.Now()) {
+		fail(http.StatusBadRequest, invalid)
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		fail(http.StatusBadRequest, "Username cannot be empty")
+		return
+	}
+	if _, err := h.AdminStore.GetUserByUsername(r.Context(), req.Username); err == nil {
+		fail(http.StatusConflict, "Username is taken; choose another")
+		return
+	}
+	if err := h.Passwords.Validate(req.Password); err != nil {
+		fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	hash, err := models.HashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := h.AdminStore.AcceptInvitation(r.Context(), id, req.Username, hash)
+	if errors.Is(err, store.ErrInvitationNotPending) {
+		fail(http.StatusBadRequest, invalid)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to accept invitation %d: %v", id, err)
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+	meta, _ := json.Marshal(map[string]any{"invitation_id": id, "username": user.Username, "role": user.Role})
+	_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "accept_invitation", "user", user.ID, string(meta))
+
+	h.completeLogin(w, r, user)
+}
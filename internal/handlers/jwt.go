@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"incident-viewer-go/internal/models"
+)
+
+// Token types, in the "typ" claim; a refresh token is never accepted as an
+// access token
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+const jwtIssuer = "sentinel"
+
+// minJWTSecretLen is the shortest JWT_SECRET accepted; HS256 wants at least
+// 256 bits
+const minJWTSecretLen = 32
+
+// JWTIssuer signs and checks the bearer tokens handed out on login for
+// clients that can't keep a cookie session, such as mobile apps and services
+type JWTIssuer struct {
+	secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// jwtClaims are the claims of both token types. Refresh tokens also carry a
+// fingerprint of the password hash, so changing the password ends them.
+type jwtClaims struct {
+	Username string `json:"name"`
+	Role     string `json:"role,omitempty"`
+	Type     string `json:"typ"`
+	Password string `json:"pwd,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWTFromEnv reads JWT_SECRET, JWT_ACCESS_TTL (default 15m) and
+// JWT_REFRESH_TTL (default 168h). Without JWT_SECRET it returns nil, which
+// leaves login to cookie sessions.
+func JWTFromEnv() (*JWTIssuer, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	if len(secret) < minJWTSecretLen {
+		return nil, fmt.Errorf("JWT_SECRET must be at least %d characters", minJWTSecretLen)
+	}
+	j := &JWTIssuer{secret: []byte(secret), AccessTTL: 15 * time.Minute, RefreshTTL: 7 * 24 * time.Hour}
+	for key, ttl := range map[string]*time.Duration{"JWT_ACCESS_TTL": &j.AccessTTL, "JWT_REFRESH_TTL": &j.RefreshTTL} {
+		if v := os.Getenv(key); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s: want a duration like 15m, got %q", key, v)
+			}
+			*ttl = d
+		}
+	}
+	if j.RefreshTTL < j.AccessTTL {
+		return nil, fmt.Errorf("JWT_REFRESH_TTL (%s) must not be shorter than JWT_ACCESS_TTL (%s)", j.RefreshTTL, j.AccessTTL)
+	}
+	return j, nil
+}
+
+// passwordFingerprint ties a refresh token to the password it was issued under
+func passwordFingerprint(u models.User) string {
+	sum := sha256.Sum256([]byte(u.PasswordHash))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (j *JWTIssuer) sign(u models.User, typ string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	c := jwtClaims{
+		Username: u.Username,
+		Type:     typ,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtIssuer,
+			Subject:   strconv.Itoa(u.ID),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	if typ == tokenTypeAccess {
+		c.Role = u.Role
+	} else {
+		c.Password = passwordFingerprint(u)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(j.secret)
+}
+
+// Tokens issues an access and a refresh token for u, as the fields of a
+// login response
+func (j *JWTIssuer) Tokens(u models.User) (map[string]any, error) {
+	access, err := j.sign(u, tokenTypeAccess, j.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := j.sign(u, tokenTypeRefresh, j.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"access_token":  access,
+		"refresh_token": refresh,
+		"token_type":    "Bearer",
+		"expires_in":    int(j.AccessTTL.Seconds()),
+	}, nil
+}
+
+// parse checks a token's signature, issuer, expiry and type
+func (j *JWTIssuer) parse(raw, typ string) (*jwtClaims, error) {
+	var c jwtClaims
+	_, err := jwt.ParseWithClaims(raw, &c, func(*jwt.Token) (any, error) {
+		return j.secret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithIssuer(jwtIssuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if c.Type != typ {
+		return nil, fmt.Errorf("not an %s token", typ)
+	}
+	return &c, nil
+}
+
+// looksLikeJWT tells JWTs apart from the other bearer credentials, none of
+// which contain dots
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+type jwtUserKey struct{}
+
+// jwtUser is the user an access token authenticated a request as
+type jwtUser struct {
+	ID       int
+	Username string
+	Role     string
+}
+
+func jwtUserFromContext(ctx context.Context) (jwtUser, bool) {
+	u, ok := ctx.Value(jwtUserKey{}).(jwtUser)
+	return u, ok
+}
+
+// JWTMiddleware accepts "Authorization: Bearer <access token>" in place of
+// the session cookie. Expired or forged tokens are 401; other bearer
+// credentials are left to the middleware that takes them.
+func (h *Handler) JWTMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		bearer = strings.TrimSpace(bearer)
+		if h.JWT == nil || !ok || !looksLikeJWT(bearer) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		c, err := h.JWT.parse(bearer, tokenTypeAccess)
+		if err != nil {
+			http.Error(w, "invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
+		id, err := strconv.Atoi(c.Subject)
+		if err != nil || id == 0 {
+			http.Error(w, "invalid or expired access token", http.StatusUnauthorized)
+			return
+		}
+		u := jwtUser{ID: id, Username: c.Username, Role: c.Role}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jwtUserKey{}, u)))
+	})
+}
+
+// RefreshTokenHandler trades a refresh token for a new pair. The user is
+// looked up again, so role changes apply and deleted users are refused, and
+// a password change since the token was issued ends it.
+// POST /api/token/refresh {"refresh_token": "..."}
+func (h *Handler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.JWT == nil {
+		http.Error(w, "Token login is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.JWT.parse(req.RefreshToken, tokenTypeRefresh)
+	if err != nil {
+		http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	id, _ := strconv.Atoi(c.Subject)
+	user, err := h.AdminStore.GetUser(r.Context(), id)
+	if err != nil || c.Password != passwordFingerprint(user) {
+		http.Error(w, "invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.JWT.Tokens(user)
+	if err != nil {
+		http.Error(w, "Failed to issue tokens", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// addLoginTokens adds an access and refresh token to a login response when
+// token login is enabled. A failure is logged and leaves the session login.
+func (h *Handler) addLoginTokens(resp map[string]any, u models.User) {
+	if h.JWT == nil {
+		return
+	}
+	tokens, err := h.JWT.Tokens(u)
+	if err != nil {
+		fmt.Println("Failed to issue login tokens:", err)
+		return
+	}
+	for k, v := range tokens {
+		resp[k] = v
+	}
+}
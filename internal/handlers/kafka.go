@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+const (
+	defaultKafkaGroup = "sentinel"
+	kafkaRetryDelay   = 5 * time.Second
+)
+
+// KafkaConfig describes the consumer group for Kafka ingestion
+type KafkaConfig struct {
+	Brokers  []string
+	Topics   []string
+	GroupID  string
+	TLS      bool
+	Username string // SASL/PLAIN when set
+	Password string
+}
+
+// KafkaConfigFromEnv reads KAFKA_BROKERS and KAFKA_TOPICS (comma separated),
+// KAFKA_GROUP_ID (default sentinel), KAFKA_TLS, KAFKA_USERNAME and
+// KAFKA_PASSWORD. ok is false unless both brokers and topics are set.
+func KafkaConfigFromEnv() (cfg KafkaConfig, ok bool) {
+	cfg = KafkaConfig{
+		Brokers:  splitCSV(os.Getenv("KAFKA_BROKERS")),
+		Topics:   splitCSV(os.Getenv("KAFKA_TOPICS")),
+		GroupID:  os.Getenv("KAFKA_GROUP_ID"),
+		TLS:      os.Getenv("KAFKA_TLS") == "true",
+		Username: os.Getenv("KAFKA_USERNAME"),
+		Password: os.Getenv("KAFKA_PASSWORD"),
+	}
+	if cfg.GroupID == "" {
+		cfg.GroupID = defaultKafkaGroup
+	}
+	return cfg, len(cfg.Brokers) > 0 && len(cfg.Topics) > 0
+}
+
+func splitCSV(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// RunKafka consumes alert events as part of a consumer group until ctx is
+// cancelled. Each record goes through the /webhook field mapping, and its
+// offset is only committed once the alert is stored, so a crash or Redis
+// outage means redelivery rather than lost alerts.
+func (h *Handler) RunKafka(ctx context.Context, cfg KafkaConfig) {
+	dialer := &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true}
+	if cfg.TLS {
+		dialer.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if cfg.Username != "" {
+		dialer.SASLMechanism = plain.Mechanism{Username: cfg.Username, Password: cfg.Password}
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.Brokers,
+		GroupID:     cfg.GroupID,
+		GroupTopics: cfg.Topics,
+		Dialer:      dialer,
+		StartOffset: kafka.LastOffset, // A new group starts at the tail, not years of history
+		MaxWait:     time.Second,
+	})
+	defer reader.Close()
+	log.Printf("Kafka consumer group %q reading %s", cfg.GroupID, strings.Join(cfg.Topics, ", "))
+
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+				return
+			}
+			log.Printf("Kafka fetch failed: %v", err)
+			select {
+			case <-time.After(kafkaRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		// Retry the same record until it is stored: fetching the next one
+		// and committing it would skip this one for good
+		for !h.storeKafkaMessage(ctx, msg) {
+			select {
+			case <-time.After(kafkaRetryDelay):
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("Kafka commit failed (record may be redelivered): %v", err)
+		}
+	}
+}
+
+func (h *Handler) storeKafkaMessage(ctx context.Context, msg kafka.Message) bool {
+	var payload map[string]any
+	if err := json.Unmarshal(msg.Value, &payload); err != nil {
+		payload = map[string]any{"message": string(msg.Value)}
+	}
+
+	source, level, title, message := extractAlertFields(payload)
+	if source == "unknown" {
+		source = "kafka:" + msg.Topic
+	}
+
+	if _, err := h.AlertStore.AddAlert(ctx, source, level, title, message); err != nil {
+		log.Println("Failed to add alert:", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// reportFormat renders timestamps and numbers in reports for a reader's
+// timezone and locale (?tz=Europe/Berlin&locale=de-DE); UTC by default
+type reportFormat struct {
+	Location   *time.Location
+	Locale     language.Tag
+	dateLayout string
+	printer    *message.Printer
+}
+
+func reportFormatFromRequest(r *http.Request) (reportFormat, error) {
+	f := newReportFormat(time.UTC, language.Und)
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return f, fmt.Errorf("unknown timezone %q: use an IANA name like Europe/Berlin", tz)
+		}
+		f.Location = loc
+	}
+	if v := r.URL.Query().Get("locale"); v != "" {
+		tag, err := language.Parse(v)
+		if err != nil {
+			return f, fmt.Errorf("invalid locale %q: use a language tag like de-DE", v)
+		}
+		f.Locale = tag
+	}
+	return newReportFormat(f.Location, f.Locale), nil
+}
+
+func newReportFormat(loc *time.Location, tag language.Tag) reportFormat {
+	return reportFormat{Location: loc, Locale: tag, dateLayout: dateLayoutFor(tag), printer: message.NewPrinter(tag)}
+}
+
+// dateLayoutFor picks the customary day/month order and clock for a locale
+func dateLayoutFor(tag language.Tag) string {
+	if tag == language.Und {
+		return "Jan 2 15:04 MST"
+	}
+	base, _ := tag.Base()
+	region, _ := tag.Region() // Guessed when the tag has none, e.g. en -> US
+	switch base.String() {
+	case "en":
+		if region.String() == "US" {
+			return "Jan 2, 2006 3:04 PM MST"
+		}
+		return "2 Jan 2006 15:04 MST"
+	case "de", "ru", "pl", "cs", "fi", "nb", "da", "tr":
+		return "02.01.2006 15:04 MST"
+	case "fr", "es", "it", "pt", "el", "vi":
+		return "02/01/2006 15:04 MST"
+	case "nl":
+		return "02-01-2006 15:04 MST"
+	case "ja", "zh", "ko":
+		return "2006/01/02 15:04 MST"
+	}
+	return "2006-01-02 15:04 MST"
+}
+
+// Time formats t in the report's timezone and locale
+func (f reportFormat) Time(t time.Time) string {
+	return t.In(f.Location).Format(f.dateLayout)
+}
+
+// Int formats n with the locale's digit grouping
+func (f reportFormat) Int(n int) string {
+	return f.printer.Sprintf("%d", n)
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// loginFailureWindow is how long a failed sign-in counts toward a lockout
+const loginFailureWindow = 15 * time.Minute
+
+// LoginLockout locks an account after repeated failed sign-ins, against
+// password guessing and credential stuffing. Wrong passwords and wrong 2FA
+// codes both count; passkeys and SSO still sign in while it is locked.
+type LoginLockout struct {
+	MaxFailures int           // Failures in a row that lock the account
+	Cooldown    time.Duration // The first lockout; each one after doubles it
+	MaxCooldown time.Duration
+}
+
+// LoginLockoutFromEnv reads LOGIN_MAX_FAILURES (default 5, 0 turns lockout
+// off), LOGIN_LOCKOUT (default 1m) and LOGIN_LOCKOUT_MAX (default 1h)
+func LoginLockoutFromEnv() (*LoginLockout, error) {
+	l := &LoginLockout{MaxFailures: 5, Cooldown: time.Minute, MaxCooldown: time.Hour}
+	if v := os.Getenv("LOGIN_MAX_FAILURES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("LOGIN_MAX_FAILURES: want a number of failures, got %q", v)
+		}
+		if n == 0 {
+			return nil, nil
+		}
+		l.MaxFailures = n
+	}
+	for key, d := range map[string]*time.Duration{"LOGIN_LOCKOUT": &l.Cooldown, "LOGIN_LOCKOUT_MAX": &l.MaxCooldown} {
+		if v := os.Getenv(key); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("%s: want a duration like 1m, got %q", key, v)
+			}
+			*d = parsed
+		}
+	}
+	if l.MaxCooldown < l.Cooldown {
+		return nil, fmt.Errorf("LOGIN_LOCKOUT_MAX (%s) must not be shorter than LOGIN_LOCKOUT (%s)", l.MaxCooldown, l.Cooldown)
+	}
+	return l, nil
+}
+
+// loginLocked answers 429 with Retry-After when the user's sign-in is
+// locked, even for the right password. If Redis can't say, sign-in goes on.
+func (h *Handler) loginLocked(w http.ResponseWriter, r *http.Request, userID int) bool {
+	if h.Lockout == nil {
+		return false
+	}
+	until, err := h.AlertStore.LoginLockedUntil(r.Context(), userID)
+	if err != nil {
+		log.Println("Failed to check login lockout:", err)
+		return false
+	}
+	wait := time.Until(until)
+	if wait <= 0 {
+		return false
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error": "Too many failed sign-ins; try again in " + wait.Round(time.Second).String(),
+	})
+	return true
+}
+
+// loginFailed counts a wrong password or 2FA code and locks the account once
+// the failures reach the limit
+func (h *Handler) loginFailed(r *http.Request, user models.User, via string) {
+	if h.Lockout == nil {
+		return
+	}
+	failures, err := h.AlertStore.RecordLoginFailure(r.Context(), user.ID, loginFailureWindow)
+	if err != nil {
+		log.Println("Failed to record login failure:", err)
+		return
+	}
+	if failures < h.Lockout.MaxFailures {
+		return
+	}
+	until, err := h.AlertStore.LockLogin(r.Context(), user.ID, h.Lockout.Cooldown, h.Lockout.MaxCooldown)
+	if err != nil {
+		log.Println("Failed to lock login:", err)
+		return
+	}
+	log.Printf("Locked sign-in for %s until %s after %d failed attempts", user.Username, until.Format(time.RFC3339), failures)
+	meta, _ := json.Marshal(map[string]any{"failures": failures, "via": via, "until": until, "remote_addr": r.RemoteAddr})
+	_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "lock_account", "user", user.ID, string(meta))
+}
+
+// loginSucceeded resets the user's failures once they are signed in
+func (h *Handler) loginSucceeded(r *http.Request, userID int) {
+	if h.Lockout == nil {
+		return
+	}
+	if err := h.AlertStore.ClearLoginFailures(r.Context(), userID); err != nil {
+		log.Println("Failed to clear login failures:", err)
+	}
+}
+
+// UnlockUserHandler lifts a user's sign-in lockout and forgets their failures
+// DELETE /api/admin/users/{id}/lockout
+func (h *Handler) UnlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/lockout"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedUser(w, r, id); !ok {
+		return
+	}
+	if err := h.AlertStore.ClearLoginFailures(r.Context(), id); err != nil {
+		http.Error(w, "Failed to unlock user", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "unlock_account", "user", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
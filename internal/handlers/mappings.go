@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+
+	"incident-viewer-go/internal/models"
+)
+
+// mappedAlert is what a mapping profile extracted from a payload
+type mappedAlert struct {
+	Source      string `json:"source"`
+	Level       string `json:"level"`
+	Title       string `json:"title"`
+	Message     string `json:"message"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// applyWebhookMapping evaluates a profile's gjson paths against body. Fields
+// the profile doesn't map, or whose path finds nothing, take the profile's
+// default and then the same fallbacks as unmapped webhooks.
+func applyWebhookMapping(m models.WebhookMapping, body []byte) mappedAlert {
+	get := func(field string) string {
+		if p := m.Fields[field]; p != "" {
+			if v := strings.TrimSpace(gjson.GetBytes(body, p).String()); v != "" {
+				return v
+			}
+		}
+		return m.Defaults[field]
+	}
+
+	out := mappedAlert{
+		Source:      get(models.MappingSource),
+		Title:       get(models.MappingTitle),
+		Message:     get(models.MappingMessage),
+		Fingerprint: get(models.MappingFingerprint),
+	}
+
+	level := get(models.MappingLevel)
+	for vendor, mapped := range m.LevelMap {
+		if strings.EqualFold(vendor, level) {
+			level = mapped
+			break
+		}
+	}
+	out.Level = models.NormalizeLevel(level)
+
+	if out.Source == "" {
+		out.Source = m.Name
+	}
+	if out.Level == "" {
+		out.Level = "info"
+	}
+	if out.Title == "" {
+		out.Title = "Alert"
+	}
+	if out.Message == "" {
+		out.Message = gjson.GetBytes(body, "@pretty").String()
+	}
+	if out.Message == "" {
+		out.Message = "No content"
+	}
+	return out
+}
+
+func validateWebhookMapping(m models.WebhookMapping) error {
+	if strings.TrimSpace(m.Name) == "" || strings.ContainsAny(m.Name, " /?&#") {
+		return errors.New("name is required and may not contain spaces or URL delimiters")
+	}
+	for field := range m.Fields {
+		if !slices.Contains(models.MappingFields, field) {
+			return fmt.Errorf("unknown field %q: use one of %s", field, strings.Join(models.MappingFields, ", "))
+		}
+	}
+	for field := range m.Defaults {
+		if !slices.Contains(models.MappingFields, field) {
+			return fmt.Errorf("unknown default %q: use one of %s", field, strings.Join(models.MappingFields, ", "))
+		}
+	}
+	for vendor, level := range m.LevelMap {
+		switch models.NormalizeLevel(level) {
+		case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo, models.LevelSuccess:
+		default:
+			return fmt.Errorf("level_map %q: unknown level %q", vendor, level)
+		}
+	}
+	return nil
+}
+
+// === Webhook Mapping Management ===
+
+func (h *Handler) GetWebhookMappingsHandler(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.AdminStore.GetWebhookMappings(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get webhook mappings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"mappings": mappings})
+}
+
+func (h *Handler) CreateWebhookMappingHandler(w http.ResponseWriter, r *http.Request) {
+	var m models.WebhookMapping
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateWebhookMapping(m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.AdminStore.CreateWebhookMapping(r.Context(), m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": m.Name, "fields": m.Fields})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_webhook_mapping", "webhook_mapping", m.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "mapping": m})
+}
+
+func (h *Handler) UpdateWebhookMappingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/webhook-mappings/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var m models.WebhookMapping
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	m.ID = id
+	if err := validateWebhookMapping(m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, err = h.AdminStore.UpdateWebhookMapping(r.Context(), m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": m.Name, "fields": m.Fields})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_webhook_mapping", "webhook_mapping", m.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "mapping": m})
+}
+
+func (h *Handler) DeleteWebhookMappingHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/webhook-mappings/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteWebhookMapping(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_webhook_mapping", "webhook_mapping", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// PreviewWebhookMappingHandler shows what a mapping would extract from a
+// sample payload, without storing anything.
+// POST /api/admin/webhook-mappings/preview {"mapping": {...}, "payload": {...}}
+func (h *Handler) PreviewWebhookMappingHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Mapping models.WebhookMapping `json:"mapping"`
+		Payload json.RawMessage       `json:"payload"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.Mapping.Name == "" {
+		req.Mapping.Name = "preview"
+	}
+	if err := validateWebhookMapping(req.Mapping); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"alert": applyWebhookMapping(req.Mapping, req.Payload)})
+}
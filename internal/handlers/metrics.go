@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const maxMetricSamples = 500
+
+type metricSample struct {
+	Source string  `json:"source"`
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+}
+
+// MetricsHandler evaluates numeric samples against the metric rules.
+// POST /api/metrics
+//
+//	{"source": "db01", "metric": "disk_used_pct", "value": 93.5}
+//	{"source": "db01", "samples": [{"metric": "cpu_pct", "value": 71}, ...]}
+//
+// A sample crossing a rule's threshold opens an alert (once, while it stays
+// crossed); the first sample back in range resolves it. Authenticated like
+// /webhook.
+func (h *Handler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		metricSample
+		Samples []metricSample `json:"samples"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	samples := req.Samples
+	if req.Metric != "" {
+		samples = append(samples, req.metricSample)
+	}
+	if len(samples) == 0 || len(samples) > maxMetricSamples {
+		http.Error(w, fmt.Sprintf("send between 1 and %d samples", maxMetricSamples), http.StatusBadRequest)
+		return
+	}
+
+	defaultSource := req.Source
+	if defaultSource == "" {
+		if t, ok := ingestTokenFromContext(r.Context()); ok {
+			defaultSource = t.Name
+		}
+	}
+	for i := range samples {
+		if samples[i].Source == "" {
+			samples[i].Source = defaultSource
+		}
+		if samples[i].Source == "" || samples[i].Metric == "" || math.IsNaN(samples[i].Value) {
+			http.Error(w, "each sample needs a source, metric and value", http.StatusBadRequest)
+			return
+		}
+	}
+
+	rules, err := h.AdminStore.GetMetricRules(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get metric rules", http.StatusInternalServerError)
+		return
+	}
+
+	firing, resolved := []string{}, []string{}
+	for _, s := range samples {
+		for _, rule := range rules {
+			if !rule.Matches(s.Source, s.Metric) {
+				continue
+			}
+			fp := rule.Fingerprint(s.Source)
+			if rule.Crossed(s.Value) {
+				if _, err := h.AlertStore.GetOpenAlert(r.Context(), fp); err == nil {
+					continue // Already firing
+				}
+				_, err = h.AlertStore.UpsertAlert(r.Context(), models.Alert{
+					Source:  s.Source,
+					Level:   rule.Level,
+					Title:   fmt.Sprintf("%s: %s %s %g", rule.Name, s.Metric, rule.Operator, rule.Threshold),
+					Message: fmt.Sprintf("%s on %s is %g (threshold %s %g)", s.Metric, s.Source, s.Value, rule.Operator, rule.Threshold),
+					Labels: map[string]string{
+						"metric":    s.Metric,
+						"value":     strconv.FormatFloat(s.Value, 'g', -1, 64),
+						"threshold": rule.Operator + " " + strconv.FormatFloat(rule.Threshold, 'g', -1, 64),
+					},
+					Fingerprint: fp,
+				})
+				if err != nil {
+					log.Println("Failed to add alert:", err)
+					http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+					return
+				}
+				firing = append(firing, rule.Name)
+			} else {
+				_, err := h.AlertStore.ResolveAlert(r.Context(), fp)
+				if errors.Is(err, store.ErrAlertNotFound) {
+					continue
+				}
+				if err != nil {
+					log.Println("Failed to resolve alert:", err)
+					http.Error(w, "Failed to resolve alert", http.StatusInternalServerError)
+					return
+				}
+				resolved = append(resolved, rule.Name)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":   "ok",
+		"samples":  len(samples),
+		"firing":   firing,
+		"resolved": resolved,
+	})
+}
+
+func validateMetricRule(rule *models.MetricRule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Metric = strings.TrimSpace(rule.Metric)
+	if rule.Name == "" || rule.Metric == "" {
+		return errors.New("name and metric are required")
+	}
+	if !slices.Contains(models.MetricOperators, rule.Operator) {
+		return fmt.Errorf("operator must be one of %s", strings.Join(models.MetricOperators, " "))
+	}
+	if math.IsNaN(rule.Threshold) || math.IsInf(rule.Threshold, 0) {
+		return errors.New("threshold must be a number")
+	}
+	if rule.Level == "" {
+		rule.Level = models.LevelError
+	}
+	rule.Level = models.NormalizeLevel(rule.Level)
+	switch rule.Level {
+	case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo:
+	default:
+		return fmt.Errorf("unknown level %q", rule.Level)
+	}
+	return nil
+}
+
+// === Metric Rule Management ===
+
+func (h *Handler) GetMetricRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.AdminStore.GetMetricRules(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get metric rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"rules": rules})
+}
+
+func (h *Handler) CreateMetricRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var rule models.MetricRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateMetricRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.AdminStore.CreateMetricRule(r.Context(), rule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(rule)
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_metric_rule", "metric_rule", rule.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "rule": rule})
+}
+
+func (h *Handler) UpdateMetricRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/metric-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var rule models.MetricRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+	if err := validateMetricRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err = h.AdminStore.UpdateMetricRule(r.Context(), rule)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(rule)
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_metric_rule", "metric_rule", rule.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "rule": rule})
+}
+
+func (h *Handler) DeleteMetricRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/metric-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteMetricRule(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_metric_rule", "metric_rule", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const defaultMQTTTopic = "sentinel/alerts/#"
+
+// MQTTConfig describes the broker connection for MQTT ingestion
+type MQTTConfig struct {
+	BrokerURL string // tcp://, ssl://, ws:// or wss://
+	Topics    []string
+	QoS       byte
+	ClientID  string
+	Username  string
+	Password  string
+}
+
+// MQTTConfigFromEnv reads MQTT_BROKER_URL, MQTT_TOPICS (comma separated,
+// default sentinel/alerts/#), MQTT_QOS (0-2, default 1), MQTT_CLIENT_ID,
+// MQTT_USERNAME and MQTT_PASSWORD. ok is false when no broker is configured.
+func MQTTConfigFromEnv() (cfg MQTTConfig, ok bool, err error) {
+	cfg.BrokerURL = os.Getenv("MQTT_BROKER_URL")
+	if cfg.BrokerURL == "" {
+		return cfg, false, nil
+	}
+
+	cfg.Topics = splitCSV(os.Getenv("MQTT_TOPICS"))
+	if len(cfg.Topics) == 0 {
+		cfg.Topics = []string{defaultMQTTTopic}
+	}
+
+	cfg.QoS = 1
+	if v := os.Getenv("MQTT_QOS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 2 {
+			return cfg, false, fmt.Errorf("invalid MQTT_QOS %q: use 0, 1 or 2", v)
+		}
+		cfg.QoS = byte(n)
+	}
+
+	cfg.ClientID = os.Getenv("MQTT_CLIENT_ID")
+	if cfg.ClientID == "" {
+		host, _ := os.Hostname()
+		cfg.ClientID = "sentinel-" + host
+	}
+	cfg.Username = os.Getenv("MQTT_USERNAME")
+	cfg.Password = os.Getenv("MQTT_PASSWORD")
+	return cfg, true, nil
+}
+
+// RunMQTT subscribes to the configured topics and stores each JSON message as
+// an alert, using the same field mapping as /webhook. The client reconnects
+// and resubscribes on its own until ctx is cancelled.
+func (h *Handler) RunMQTT(ctx context.Context, cfg MQTTConfig) {
+	filters := make(map[string]byte, len(cfg.Topics))
+	for _, t := range cfg.Topics {
+		filters[t] = cfg.QoS
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.BrokerURL).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		// Keep the session so QoS 1/2 messages published while we were away are delivered
+		SetCleanSession(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10 * time.Second).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			token := c.SubscribeMultiple(filters, func(_ mqtt.Client, msg mqtt.Message) {
+				h.handleMQTTMessage(ctx, msg)
+			})
+			if token.Wait() && token.Error() != nil {
+				log.Printf("MQTT subscribe failed: %v", token.Error())
+				return
+			}
+			log.Printf("MQTT subscribed to %s on %s", strings.Join(cfg.Topics, ", "), cfg.BrokerURL)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("MQTT connection lost: %v", err)
+		})
+
+	client := mqtt.NewClient(opts)
+	// With connect retry enabled this only fails on bad options
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		log.Printf("MQTT ingestion disabled: %v", token.Error())
+		return
+	}
+
+	<-ctx.Done()
+	client.Disconnect(250)
+}
+
+func (h *Handler) handleMQTTMessage(ctx context.Context, msg mqtt.Message) {
+	var payload map[string]any
+	if err := json.Unmarshal(msg.Payload(), &payload); err != nil {
+		// Plain-text payloads still make it in, as the message body
+		payload = map[string]any{"message": string(msg.Payload())}
+	}
+
+	source, level, title, message := extractAlertFields(payload)
+	if source == "unknown" {
+		source = "mqtt:" + msg.Topic()
+	}
+
+	if _, err := h.AlertStore.AddAlert(ctx, source, level, title, message); err != nil {
+		log.Println("Failed to add alert:", err)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+func validateMuteRule(r *models.MuteRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return validateAlertMatcher(&r.AlertMatcher)
+}
+
+func muteRuleAuditMeta(r models.MuteRule) string {
+	meta, _ := json.Marshal(map[string]any{
+		"name": r.Name, "chat_id": r.ChatID, "source": r.Source, "levels": r.Levels,
+		"labels": r.Labels, "enabled": r.Enabled,
+	})
+	return string(meta)
+}
+
+// === Mute Rules ===
+
+// GetMuteRulesHandler lists the mute rules with how many alerts each dropped
+// GET /api/admin/mute-rules
+func (h *Handler) GetMuteRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.AlertStore.GetMuteRules(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get mute rules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"mute_rules": rules})
+}
+
+// CreateMuteRuleHandler adds a rule dropping matching alerts at ingestion
+// POST /api/admin/mute-rules
+func (h *Handler) CreateMuteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := models.MuteRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateMuteRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	actorID, username, _ := GetCurrentUser(r)
+	rule.CreatedBy = username
+
+	rule, err := h.AlertStore.CreateMuteRule(r.Context(), rule)
+	if err != nil {
+		http.Error(w, "Failed to create mute rule", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_mute_rule", "mute_rule", rule.ID, muteRuleAuditMeta(rule))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "mute_rule": rule})
+}
+
+// UpdateMuteRuleHandler changes a rule's name, matchers or enabled flag; its
+// hit counter carries on
+// PUT /api/admin/mute-rules/{id}
+func (h *Handler) UpdateMuteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/mute-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	rule := models.MuteRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+	if err := validateMuteRule(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err = h.AlertStore.UpdateMuteRule(r.Context(), rule)
+	if errors.Is(err, store.ErrMuteRuleNotFound) {
+		http.Error(w, "Mute rule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update mute rule", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_mute_rule", "mute_rule", rule.ID, muteRuleAuditMeta(rule))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "mute_rule": rule})
+}
+
+// DeleteMuteRuleHandler removes a rule; matching alerts are stored again
+// DELETE /api/admin/mute-rules/{id}
+func (h *Handler) DeleteMuteRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/mute-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.AlertStore.DeleteMuteRule(r.Context(), id)
+	if errors.Is(err, store.ErrMuteRuleNotFound) {
+		http.Error(w, "Mute rule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete mute rule", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_mute_rule", "mute_rule", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/msgtemplate"
+)
+
+func validateNotificationTemplate(t *models.NotificationTemplate) error {
+	t.Channel = strings.ToLower(strings.TrimSpace(t.Channel))
+	if !slices.Contains(models.NotificationChannels, t.Channel) {
+		return fmt.Errorf("unknown channel %q (use %s)", t.Channel, strings.Join(models.NotificationChannels, ", "))
+	}
+	t.Level = strings.ToLower(strings.TrimSpace(t.Level))
+	if t.Level != "" && t.Level != models.TemplateLevelResolved {
+		t.Level = models.NormalizeLevel(t.Level)
+		switch t.Level {
+		case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo:
+		default:
+			return fmt.Errorf("unknown level %q (use critical, error, warning, info, resolved or leave it empty)", t.Level)
+		}
+	}
+	if err := msgtemplate.Parse(t.Body); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+	if t.Subject != "" {
+		if t.Channel != models.ChannelEmail && t.Channel != models.ChannelSlack {
+			return errors.New("subject only applies to email and slack templates")
+		}
+		if err := msgtemplate.Parse(t.Subject); err != nil {
+			return fmt.Errorf("invalid subject: %w", err)
+		}
+	}
+	return nil
+}
+
+// === Notification Template Management ===
+
+func (h *Handler) GetNotificationTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.AdminStore.GetNotificationTemplates(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get notification templates", http.StatusInternalServerError)
+		return
+	}
+	if templates == nil {
+		templates = []models.NotificationTemplate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"notification_templates": templates, "channels": models.NotificationChannels})
+}
+
+// SaveNotificationTemplateHandler sets the template for a channel and level,
+// replacing the one there was
+// PUT /api/admin/notification-templates
+func (h *Handler) SaveNotificationTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var t models.NotificationTemplate
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateNotificationTemplate(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.AdminStore.SaveNotificationTemplate(r.Context(), t)
+	if err != nil {
+		http.Error(w, "Failed to save notification template", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"channel": t.Channel, "level": t.Level})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "save_notification_template", "notification_template", t.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "notification_template": t})
+}
+
+func (h *Handler) DeleteNotificationTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/notification-templates/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteNotificationTemplate(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_notification_template", "notification_template", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// PreviewNotificationTemplateHandler renders a template without saving it,
+// for a stored alert or sample data
+// POST /api/admin/notification-templates/preview {"channel", "level", "subject", "body", "alert_id"}
+func (h *Handler) PreviewNotificationTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		models.NotificationTemplate
+		AlertID int `json:"alert_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	t := req.NotificationTemplate
+	if err := validateNotificationTemplate(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	level := t.Level
+	if level == "" {
+		level = models.LevelError
+	}
+	d := msgtemplate.Sample(level)
+	if req.AlertID != 0 {
+		a, err := h.AlertStore.GetAlert(r.Context(), req.AlertID)
+		if err != nil {
+			http.Error(w, "Alert not found", http.StatusNotFound)
+			return
+		}
+		d = msgtemplate.Data{Alert: a, Level: strings.ToUpper(a.Level), Headline: "Incident opened"}
+		if a.Status == models.AlertStatusResolved {
+			d.Headline = "Resolved"
+		}
+	}
+
+	subject, body, err := h.Templates.Preview(t, d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"subject": subject, "body": body})
+}
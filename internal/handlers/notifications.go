@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// NotificationQueueHandler shows how many notifications are queued, waiting
+// for a retry and dead-lettered, with the newest dead letters and the
+// delivery history, filtered by channel, target, status and time
+// GET /api/admin/notifications?limit=100&channel=sms&target=user%207&status=failed&since=...&until=...
+func (h *Handler) NotificationQueueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > store.MaxDeadLetters {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	filter := models.NotificationAttemptFilter{
+		Channel: q.Get("channel"),
+		Target:  q.Get("target"),
+		Status:  q.Get("status"),
+		Limit:   min(limit, store.MaxNotificationAttempts),
+	}
+	switch filter.Status {
+	case "", models.DeliveryDelivered, models.DeliveryRetrying, models.DeliveryFailed:
+	default:
+		http.Error(w, "Invalid status: use delivered, retrying or failed", http.StatusBadRequest)
+		return
+	}
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+param+": use RFC 3339", http.StatusBadRequest)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	stats, err := h.AlertStore.GetNotificationQueueStats(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get queue stats", http.StatusInternalServerError)
+		return
+	}
+	dead, err := h.AlertStore.GetDeadLetters(r.Context(), limit)
+	if err != nil {
+		http.Error(w, "Failed to get dead letters", http.StatusInternalServerError)
+		return
+	}
+	deliveries, err := h.AdminStore.GetNotificationAttempts(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to get delivery history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"stats": stats, "dead_letters": dead, "deliveries": deliveries})
+}
+
+// RetryDeadLetterHandler puts a dead-lettered notification back on the queue
+// POST /api/admin/notifications/dead-letters/{id}/retry
+func (h *Handler) RetryDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/notifications/dead-letters/"), "/retry")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AlertStore.RequeueDeadLetter(r.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotificationNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to requeue notification", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"id": id})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "retry_dead_letter", "system", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// ClearDeadLettersHandler drops every dead-lettered notification
+// DELETE /api/admin/notifications/dead-letters
+func (h *Handler) ClearDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	n, err := h.AlertStore.ClearDeadLetters(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to clear dead letters", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"count": n})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "clear_dead_letters", "system", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "cleared": n})
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// maxShiftDays keeps rotations to at most four weeks per shift
+const maxShiftDays = 28
+
+func (h *Handler) validateSchedule(ctx context.Context, sc *models.Schedule) error {
+	sc.Name = strings.TrimSpace(sc.Name)
+	if sc.Name == "" {
+		return errors.New("name is required")
+	}
+	if sc.Timezone == "" {
+		sc.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(sc.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", sc.Timezone)
+	}
+	if sc.ShiftDays == 0 {
+		sc.ShiftDays = 7
+	}
+	if sc.ShiftDays < 1 || sc.ShiftDays > maxShiftDays {
+		return fmt.Errorf("shift_days must be 1-%d", maxShiftDays)
+	}
+	if sc.HandoffTime == "" {
+		sc.HandoffTime = "09:00"
+	}
+	if _, err := time.Parse("15:04", sc.HandoffTime); err != nil {
+		return errors.New("handoff_time must be HH:MM")
+	}
+	if _, err := time.Parse("2006-01-02", sc.StartDate); err != nil {
+		return errors.New("start_date must be YYYY-MM-DD")
+	}
+	if len(sc.Participants) == 0 {
+		return errors.New("participants needs at least one user ID")
+	}
+	for _, id := range sc.Participants {
+		if _, err := h.AdminStore.GetUser(ctx, id); err != nil {
+			return fmt.Errorf("unknown user %d", id)
+		}
+	}
+	return nil
+}
+
+// scheduleID parses the ID in /api/admin/schedules/{id}[/overrides[/{override}]]
+func scheduleID(r *http.Request) (id, overrideID int, err error) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/admin/schedules/")
+	idPart, overridePart, _ := strings.Cut(rest, "/overrides")
+	if id, err = strconv.Atoi(idPart); err != nil {
+		return 0, 0, err
+	}
+	if overridePart = strings.TrimPrefix(overridePart, "/"); overridePart != "" {
+		overrideID, err = strconv.Atoi(overridePart)
+	}
+	return id, overrideID, err
+}
+
+func withOverrides(sc models.Schedule) models.Schedule {
+	if sc.Overrides == nil {
+		sc.Overrides = []models.ScheduleOverride{}
+	}
+	return sc
+}
+
+// === On-call Schedule Management ===
+
+func (h *Handler) GetSchedulesHandler(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.AdminStore.GetSchedules(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get schedules", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]models.Schedule, 0, len(schedules))
+	for _, sc := range schedules {
+		out = append(out, withOverrides(sc))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"schedules": out})
+}
+
+func (h *Handler) CreateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var sc models.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := h.validateSchedule(r.Context(), &sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sc, err := h.AdminStore.CreateSchedule(r.Context(), sc)
+	if err != nil {
+		http.Error(w, "Failed to create schedule (names must be unique)", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": sc.Name, "participants": sc.Participants})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_schedule", "schedule", sc.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "schedule": withOverrides(sc)})
+}
+
+func (h *Handler) UpdateScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, _, err := scheduleID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var sc models.Schedule
+	if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	sc.ID = id
+	if err := h.validateSchedule(r.Context(), &sc); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sc, err = h.AdminStore.UpdateSchedule(r.Context(), sc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": sc.Name, "participants": sc.Participants})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_schedule", "schedule", sc.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "schedule": withOverrides(sc)})
+}
+
+func (h *Handler) DeleteScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	id, _, err := scheduleID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteSchedule(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_schedule", "schedule", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// CreateScheduleOverrideHandler puts someone else on call for a period
+// POST /api/admin/schedules/{id}/overrides
+func (h *Handler) CreateScheduleOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	id, _, err := scheduleID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.AdminStore.GetSchedule(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var o models.ScheduleOverride
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	o.ScheduleID = id
+	if _, err := h.AdminStore.GetUser(r.Context(), o.UserID); err != nil {
+		http.Error(w, fmt.Sprintf("unknown user %d", o.UserID), http.StatusBadRequest)
+		return
+	}
+	if o.StartsAt.IsZero() || !o.EndsAt.After(o.StartsAt) {
+		http.Error(w, "starts_at and ends_at are required, ends_at after starts_at", http.StatusBadRequest)
+		return
+	}
+	if !o.EndsAt.After(time.Now()) {
+		http.Error(w, "ends_at is in the past", http.StatusBadRequest)
+		return
+	}
+	o.Reason = strings.TrimSpace(o.Reason)
+
+	o, err = h.AdminStore.CreateScheduleOverride(r.Context(), o)
+	if err != nil {
+		http.Error(w, "Failed to create override", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"user_id": o.UserID, "starts_at": o.StartsAt, "ends_at": o.EndsAt, "reason": o.Reason})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_schedule_override", "schedule", id, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "override": o})
+}
+
+// DeleteScheduleOverrideHandler removes an override
+// DELETE /api/admin/schedules/{id}/overrides/{override}
+func (h *Handler) DeleteScheduleOverrideHandler(w http.ResponseWriter, r *http.Request) {
+	id, overrideID, err := scheduleID(r)
+	if err != nil || overrideID == 0 {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteScheduleOverride(r.Context(), id, overrideID); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"override_id": overrideID})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_schedule_override", "schedule", id, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// OnCallNow lists who is on call right now in each schedule that has
+// someone, with usernames filled in
+func (h *Handler) OnCallNow(ctx context.Context) ([]models.OnCallShift, error) {
+	schedules, err := h.AdminStore.GetSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users, err := h.AdminStore.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+
+	now := time.Now()
+	shifts := []models.OnCallShift{}
+	for _, sc := range schedules {
+		shift, ok := sc.OnCallAt(now)
+		if !ok {
+			continue
+		}
+		shift.Username = names[shift.UserID]
+		shifts = append(shifts, shift)
+	}
+	return shifts, nil
+}
+
+// OnCallNowHandler shows who is currently on call
+// GET /api/oncall/now
+func (h *Handler) OnCallNowHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	shifts, err := h.OnCallNow(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get on-call schedules", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"oncall": shifts})
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/secrets"
+)
+
+// maskedSecret stands in for stored credentials in API responses; sending it
+// back on update keeps the stored value
+const maskedSecret = "********"
+
+func maskOpsgenieRule(rule models.OpsgenieRule) models.OpsgenieRule {
+	if rule.APIKey != "" {
+		rule.APIKey = maskedSecret
+	}
+	return rule
+}
+
+func validateOpsgenieRule(rule *models.OpsgenieRule, requireKey bool) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	rule.ChatID = strings.TrimSpace(rule.ChatID)
+	rule.Source = strings.TrimSpace(rule.Source)
+	if _, err := path.Match(rule.Source, ""); err != nil {
+		return fmt.Errorf("invalid source pattern %q", rule.Source)
+	}
+	if rule.MinLevel == "" {
+		rule.MinLevel = models.LevelError
+	}
+	rule.MinLevel = models.NormalizeLevel(rule.MinLevel)
+	switch rule.MinLevel {
+	case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo:
+	default:
+		return fmt.Errorf("unknown level %q", rule.MinLevel)
+	}
+	rule.Region = strings.ToLower(strings.TrimSpace(rule.Region))
+	if rule.Region == "" {
+		rule.Region = models.OpsgenieRegionUS
+	}
+	if rule.Region != models.OpsgenieRegionUS && rule.Region != models.OpsgenieRegionEU {
+		return errors.New("region must be us or eu")
+	}
+	rule.Team = strings.TrimSpace(rule.Team)
+	rule.APIKey = strings.TrimSpace(rule.APIKey)
+	if rule.APIKey == maskedSecret {
+		rule.APIKey = "" // Unchanged
+	}
+	if requireKey && rule.APIKey == "" {
+		return errors.New("api_key is required")
+	}
+	return nil
+}
+
+// writeSecretStoreError reports a missing encryption key as a configuration
+// problem rather than a server error
+func writeSecretStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, secrets.ErrNoKey) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// === Opsgenie Rule Management ===
+
+func (h *Handler) GetOpsgenieRulesHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.AdminStore.GetOpsgenieRules(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get Opsgenie rules", http.StatusInternalServerError)
+		return
+	}
+
+	masked := make([]models.OpsgenieRule, 0, len(rules))
+	for _, rule := range rules {
+		masked = append(masked, maskOpsgenieRule(rule))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"opsgenie_rules": masked})
+}
+
+func (h *Handler) CreateOpsgenieRuleHandler(w http.ResponseWriter, r *http.Request) {
+	rule := models.OpsgenieRule{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateOpsgenieRule(&rule, true); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateHoursRule(r.Context(), &rule.HoursRule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.AdminStore.CreateOpsgenieRule(r.Context(), rule)
+	if err != nil {
+		writeSecretStoreError(w, err)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": rule.Name, "region": rule.Region})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_opsgenie_rule", "opsgenie_rule", rule.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "opsgenie_rule": maskOpsgenieRule(rule)})
+}
+
+func (h *Handler) UpdateOpsgenieRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/opsgenie-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var rule models.OpsgenieRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	rule.ID = id
+	if err := validateOpsgenieRule(&rule, false); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateHoursRule(r.Context(), &rule.HoursRule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	keyChanged := rule.APIKey != ""
+
+	rule, err = h.AdminStore.UpdateOpsgenieRule(r.Context(), rule)
+	if err != nil {
+		writeSecretStoreError(w, err)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": rule.Name, "enabled": rule.Enabled, "api_key_changed": keyChanged})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_opsgenie_rule", "opsgenie_rule", rule.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "opsgenie_rule": maskOpsgenieRule(rule)})
+}
+
+func (h *Handler) DeleteOpsgenieRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/opsgenie-rules/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteOpsgenieRule(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_opsgenie_rule", "opsgenie_rule", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
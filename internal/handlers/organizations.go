@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// orgScope is what an admin manages: the users, bots, chats and invitations
+// of their organization or, for admins of the default organization, of
+// every organization
+type orgScope struct {
+	org int
+}
+
+// all reports whether the admin manages every organization
+func (s orgScope) all() bool {
+	return s.org == models.DefaultOrgID
+}
+
+// has reports whether the admin manages what belongs to orgID
+func (s orgScope) has(orgID int) bool {
+	return s.all() || s.org == orgID
+}
+
+// filter is the organization list queries are limited to; 0 for all
+func (s orgScope) filter() int {
+	if s.all() {
+		return 0
+	}
+	return s.org
+}
+
+// adminScope looks up the organization of the signed-in admin. When it
+// can't, it answers the request and returns false.
+func (h *Handler) adminScope(w http.ResponseWriter, r *http.Request) (orgScope, bool) {
+	userID, _, _ := GetCurrentUser(r)
+	user, err := h.AdminStore.GetUser(r.Context(), userID)
+	if errors.Is(err, store.ErrUserNotFound) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return orgScope{}, false
+	}
+	if err != nil {
+		log.Printf("Failed to get user %d: %v", userID, err)
+		http.Error(w, "Failed to get your organization", http.StatusServiceUnavailable)
+		return orgScope{}, false
+	}
+	return orgScope{org: user.OrgID}, true
+}
+
+// scopedUser looks up the user with ID id for an admin, answering 404 when
+// they don't exist or are in an organization the admin doesn't manage
+func (h *Handler) scopedUser(w http.ResponseWriter, r *http.Request, id int) (models.User, bool) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return models.User{}, false
+	}
+	user, err := h.AdminStore.GetUser(r.Context(), id)
+	if err != nil || !scope.has(user.OrgID) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// scopedBot is scopedUser for bots
+func (h *Handler) scopedBot(w http.ResponseWriter, r *http.Request, id int) (models.Bot, bool) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return models.Bot{}, false
+	}
+	bot, err := h.AdminStore.GetBot(r.Context(), id)
+	if err != nil || !scope.has(bot.OrgID) {
+		http.Error(w, "Bot not found", http.StatusNotFound)
+		return models.Bot{}, false
+	}
+	return bot, true
+}
+
+// inDefaultOrg reports whether the user is in the default organization
+func (h *Handler) inDefaultOrg(ctx context.Context, userID int) bool {
+	user, err := h.AdminStore.GetUser(ctx, userID)
+	return err == nil && user.OrgID == models.DefaultOrgID
+}
+
+// newMemberOrg is the organization a user, bot or invitation an admin
+// creates goes in: requested or, when it is 0, the admin's own. Only admins
+// of the default organization may pick another.
+func (h *Handler) newMemberOrg(w http.ResponseWriter, r *http.Request, requested int) (int, bool) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return 0, false
+	}
+	if requested == 0 || requested == scope.org {
+		return scope.org, true
+	}
+	if !scope.all() {
+		http.Error(w, "Forbidden: you manage your own organization only", http.StatusForbidden)
+		return 0, false
+	}
+	if _, err := h.AdminStore.GetOrganization(r.Context(), requested); err != nil {
+		http.Error(w, "Organization not found", http.StatusBadRequest)
+		return 0, false
+	}
+	return requested, true
+}
+
+// orgChats keeps the chats of the organization orgID; 0 keeps them all
+func orgChats(chats []models.Chat, orgID int) []models.Chat {
+	if orgID == 0 {
+		return chats
+	}
+	kept := chats[:0:0]
+	for _, c := range chats {
+		if c.OrgID == orgID {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// checkOrgChats answers 400 and returns false unless every chat in chatIDs
+// (their numeric IDs) is in the organization orgID
+func (h *Handler) checkOrgChats(w http.ResponseWriter, r *http.Request, orgID int, chatIDs []int) bool {
+	for _, id := range chatIDs {
+		chat, err := h.AdminStore.GetChat(r.Context(), id)
+		if err != nil || chat.OrgID != orgID {
+			http.Error(w, "chat "+strconv.Itoa(id)+" is not in the organization", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// InstanceAdminMiddleware lets through admins of the default organization
+// only: what it guards is shared by every organization, like integrations,
+// rules and feature flags. It checks the admin role itself, in place of
+// AdminMiddleware.
+func (h *Handler) InstanceAdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return AdminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := h.adminScope(w, r)
+		if !ok {
+			return
+		}
+		if !scope.all() {
+			http.Error(w, "Forbidden: only admins of the default organization manage this", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// GetOrganizationsHandler lists the organizations
+// GET /api/admin/organizations
+func (h *Handler) GetOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.AdminStore.GetOrganizations(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get organizations", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"organizations": orgs})
+}
+
+// CreateOrganizationHandler adds an organization. Its first admin is then
+// created or invited with its org_id.
+// POST /api/admin/organizations {"name": "Acme"}
+func (h *Handler) CreateOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.AdminStore.CreateOrganization(r.Context(), req.Name)
+	if err != nil {
+		log.Printf("Failed to create organization: %v", err)
+		http.Error(w, "Failed to create organization; is the name taken?", http.StatusConflict)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": org.Name})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_organization", "organization", org.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "organization": org})
+}
+
+// DeleteOrganizationHandler removes an organization without users or bots
+// DELETE /api/admin/organizations/{id}
+func (h *Handler) DeleteOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/organizations/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.AdminStore.GetOrganization(r.Context(), id); err != nil {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+	if err := h.AdminStore.DeleteOrganization(r.Context(), id); err != nil {
+		writeDeleteError(w, err)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_organization", "organization", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/webhooks"
+)
+
+func maskOutgoingWebhook(hook models.OutgoingWebhook) models.OutgoingWebhook {
+	if hook.Secret != "" {
+		hook.Secret = maskedSecret
+	}
+	if hook.Events == nil {
+		hook.Events = []string{}
+	}
+	return hook
+}
+
+func validateOutgoingWebhook(hook *models.OutgoingWebhook) error {
+	hook.Name = strings.TrimSpace(hook.Name)
+	if hook.Name == "" {
+		return errors.New("name is required")
+	}
+	hook.URL = strings.TrimSpace(hook.URL)
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	hook.ChatID = strings.TrimSpace(hook.ChatID)
+	hook.Source = strings.TrimSpace(hook.Source)
+	if _, err := path.Match(hook.Source, ""); err != nil {
+		return fmt.Errorf("invalid source pattern %q", hook.Source)
+	}
+	if hook.MinLevel == "" {
+		hook.MinLevel = models.LevelInfo
+	}
+	hook.MinLevel = models.NormalizeLevel(hook.MinLevel)
+	switch hook.MinLevel {
+	case models.LevelCritical, models.LevelError, models.LevelWarning, models.LevelInfo, models.LevelSuccess:
+	default:
+		return fmt.Errorf("unknown level %q", hook.MinLevel)
+	}
+	for _, e := range hook.Events {
+		if !slices.Contains(models.WebhookEvents, e) {
+			return fmt.Errorf("unknown event %q (use %s)", e, strings.Join(models.WebhookEvents, ", "))
+		}
+	}
+	if err := webhooks.ParseTemplate(hook.Template); err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	hook.Secret = strings.TrimSpace(hook.Secret)
+	if hook.Secret == maskedSecret {
+		hook.Secret = "" // Unchanged
+	}
+	return nil
+}
+
+// outgoingWebhookID parses the ID in /api/admin/outgoing-webhooks/{id}[/deliveries]
+func outgoingWebhookID(r *http.Request) (int, error) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/admin/outgoing-webhooks/")
+	return strconv.Atoi(strings.TrimSuffix(rest, "/deliveries"))
+}
+
+// === Outgoing Webhook Management ===
+
+func (h *Handler) GetOutgoingWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.AdminStore.GetOutgoingWebhooks(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get outgoing webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	masked := make([]models.OutgoingWebhook, 0, len(hooks))
+	for _, hook := range hooks {
+		masked = append(masked, maskOutgoingWebhook(hook))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"outgoing_webhooks": masked})
+}
+
+func (h *Handler) CreateOutgoingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	hook := models.OutgoingWebhook{Enabled: true}
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateOutgoingWebhook(&hook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateHoursRule(r.Context(), &hook.HoursRule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hook, err := h.AdminStore.CreateOutgoingWebhook(r.Context(), hook)
+	if err != nil {
+		writeSecretStoreError(w, err)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": hook.Name, "url": hook.URL, "signed": hook.Secret != ""})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_outgoing_webhook", "outgoing_webhook", hook.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "outgoing_webhook": maskOutgoingWebhook(hook)})
+}
+
+func (h *Handler) UpdateOutgoingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := outgoingWebhookID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var hook models.OutgoingWebhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	hook.ID = id
+	if err := validateOutgoingWebhook(&hook); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.validateHoursRule(r.Context(), &hook.HoursRule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secretChanged := hook.Secret != ""
+
+	hook, err = h.AdminStore.UpdateOutgoingWebhook(r.Context(), hook)
+	if err != nil {
+		writeSecretStoreError(w, err)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": hook.Name, "url": hook.URL, "enabled": hook.Enabled, "secret_changed": secretChanged})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_outgoing_webhook", "outgoing_webhook", hook.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "outgoing_webhook": maskOutgoingWebhook(hook)})
+}
+
+func (h *Handler) DeleteOutgoingWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := outgoingWebhookID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteOutgoingWebhook(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_outgoing_webhook", "outgoing_webhook", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// GetWebhookDeliveriesHandler lists a webhook's delivery attempts, newest
+// first (?limit=, default 50, max 500)
+func (h *Handler) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := outgoingWebhookID(r)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.AdminStore.GetOutgoingWebhook(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 500 {
+			http.Error(w, "limit must be 1-500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.AdminStore.GetWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		http.Error(w, "Failed to get deliveries", http.StatusInternalServerError)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []models.WebhookDelivery{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"deliveries": deliveries})
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/pagerduty"
+)
+
+// PagerDutyWebhookHandler accepts PagerDuty V3 webhook subscriptions, so work
+// done in PagerDuty shows up on the alert that triggered the incident:
+//   - incident.acknowledged adds the responder's 👀 reaction (the alert's ack)
+//   - incident.resolved resolves the alert
+//
+// Incidents are matched to alerts by the dedup key the forwarder sent.
+// When PAGERDUTY_WEBHOOK_SECRET is set, X-PagerDuty-Signature must match it.
+func (h *Handler) PagerDutyWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if secret := os.Getenv("PAGERDUTY_WEBHOOK_SECRET"); secret != "" {
+		if !pagerduty.ValidSignature(r.Header.Get("X-PagerDuty-Signature"), body, secret) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var payload struct {
+		Event struct {
+			EventType string `json:"event_type"`
+			Agent     *struct {
+				Summary string `json:"summary"`
+			} `json:"agent"`
+			Data struct {
+				IncidentKey string `json:"incident_key"`
+				HTMLURL     string `json:"html_url"`
+			} `json:"data"`
+		} `json:"event"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	ev := payload.Event
+
+	ignore := func(reason string) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"status": "ignored", "reason": reason})
+	}
+	if ev.EventType != "incident.acknowledged" && ev.EventType != "incident.resolved" {
+		ignore("event type not handled")
+		return
+	}
+	if ev.Data.IncidentKey == "" {
+		ignore("incident has no dedup key")
+		return
+	}
+
+	// Keys made from an alert ID name it; others are the alert's fingerprint
+	var alert models.Alert
+	if id, ok := pagerduty.AlertIDFromDedupKey(ev.Data.IncidentKey); ok {
+		alert, err = h.AlertStore.GetAlert(r.Context(), id)
+	} else {
+		alert, err = h.AlertStore.GetOpenAlert(r.Context(), ev.Data.IncidentKey)
+	}
+	if err != nil {
+		ignore("no matching alert")
+		return
+	}
+
+	switch ev.EventType {
+	case "incident.acknowledged":
+		responder := "PagerDuty"
+		if ev.Agent != nil && ev.Agent.Summary != "" {
+			responder = ev.Agent.Summary + " (PagerDuty)"
+		}
+		alert, err = h.AlertStore.SetReaction(r.Context(), alert.ID, models.ReactionLooking, responder, true)
+	case "incident.resolved":
+		if alert.Status == models.AlertStatusResolved {
+			ignore("alert already resolved")
+			return
+		}
+		alert, err = h.AlertStore.ResolveAlertByID(r.Context(), alert.ID)
+	}
+	if err != nil {
+		writeAlertError(w, "Failed to update alert from PagerDuty", err)
+		return
+	}
+	if ev.Data.HTMLURL != "" {
+		if _, err := h.AlertStore.SetAlertLabels(r.Context(), alert.ID, map[string]string{"pagerduty_incident": ev.Data.HTMLURL}); err != nil {
+			log.Printf("Failed to label alert %d with its PagerDuty incident: %v", alert.ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "alert_id": alert.ID, "event": ev.EventType})
+}
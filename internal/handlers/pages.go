@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+
+	"incident-viewer-go/internal/models"
+)
+
+// Branding names the instance in page titles and headers
+type Branding struct {
+	Name    string // SENTINEL_BRAND_NAME, default "Sentinel Ops"
+	LogoURL string // SENTINEL_BRAND_LOGO_URL; empty shows the shield icon
+}
+
+// BrandingFromEnv reads SENTINEL_BRAND_NAME and SENTINEL_BRAND_LOGO_URL
+func BrandingFromEnv() Branding {
+	b := Branding{Name: os.Getenv("SENTINEL_BRAND_NAME"), LogoURL: os.Getenv("SENTINEL_BRAND_LOGO_URL")}
+	if b.Name == "" {
+		b.Name = "Sentinel Ops"
+	}
+	return b
+}
+
+// pageUser is the signed-in user; ID is 0 when signed out
+type pageUser struct {
+	ID       int
+	Username string
+	Role     string
+}
+
+// pagePermissions is what the user's role allows, for templates to show or
+// hide controls by
+type pagePermissions struct {
+	SignedIn bool
+	Admin    bool // Manages users, chats, bots and integrations
+	AllChats bool // Sees every chat without being given it
+}
+
+// pageData is what every HTML page gets, built by newPageData: pages embed it
+// in their own view model next to their data
+type pageData struct {
+	User      pageUser
+	Can       pagePermissions
+	Features  map[string]bool // Feature flags as they apply to the user
+	CSRFToken string          // The session's token, for forms and fetch headers
+	Brand     Branding
+	SSO       bool // SAML sign-in is configured
+	Passkeys  bool // WebAuthn is configured
+}
+
+// newPageData builds the page data for the request's user. The CSRF token is
+// created on first use and stored in the session; CSRFMiddleware checks it.
+func (h *Handler) newPageData(w http.ResponseWriter, r *http.Request) pageData {
+	userID, username, role := GetCurrentUser(r)
+	p := pageData{
+		User: pageUser{ID: userID, Username: username, Role: role},
+		Can: pagePermissions{
+			SignedIn: userID != 0,
+			Admin:    userID != 0 && role == "admin",
+			AllChats: userID != 0 && canSeeAllChats(role),
+		},
+		Features:  map[string]bool{},
+		CSRFToken: csrfToken(w, r),
+		Brand:     h.Brand,
+		SSO:       h.SAML != nil,
+		Passkeys:  h.WebAuthn != nil,
+	}
+	if p.Brand.Name == "" {
+		p.Brand.Name = "Sentinel Ops"
+	}
+	if userID != 0 {
+		p.Features = h.userFeatureFlags(r.Context(), userID, role)
+	} else {
+		for key, on := range models.FeatureFlagDefaults {
+			p.Features[key] = on
+		}
+	}
+	return p
+}
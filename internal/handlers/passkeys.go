@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/webauthn"
+)
+
+// Session keys for the passkey ceremonies
+const (
+	sessionChallenge   = "webauthn_challenge"
+	sessionChallengeAt = "webauthn_challenge_at"
+	// sessionPending2FA is the user who gave the right password and still
+	// owes a second factor; a passkey without user verification answers it
+	sessionPending2FA = "pending_2fa_user_id"
+)
+
+// passkeyCredential is a PublicKeyCredential as its toJSON() sends it, with
+// binary fields base64url encoded
+type passkeyCredential struct {
+	ID       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"` // Registration
+		AuthenticatorData string `json:"authenticatorData"` // Sign-in
+		Signature         string `json:"signature"`         // Sign-in
+		UserHandle        string `json:"userHandle"`        // Sign-in
+	} `json:"response"`
+}
+
+// newChallenge stores a fresh challenge in the session and returns it
+func newChallenge(w http.ResponseWriter, r *http.Request) (string, error) {
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	session, _ := sessionStore.Get(r, sessionName)
+	session.Values[sessionChallenge] = challenge
+	session.Values[sessionChallengeAt] = time.Now().Unix()
+	return challenge, session.Save(r, w)
+}
+
+// takeChallenge returns the session's challenge, if it hasn't timed out, and
+// removes it so it answers one ceremony only
+func takeChallenge(w http.ResponseWriter, r *http.Request) string {
+	session, _ := sessionStore.Get(r, sessionName)
+	challenge, _ := session.Values[sessionChallenge].(string)
+	at, _ := session.Values[sessionChallengeAt].(int64)
+	delete(session.Values, sessionChallenge)
+	delete(session.Values, sessionChallengeAt)
+	session.Save(r, w)
+	if time.Since(time.Unix(at, 0)) > webauthn.ChallengeTimeout*time.Millisecond {
+		return ""
+	}
+	return challenge
+}
+
+func (h *Handler) passkeysEnabled(w http.ResponseWriter) bool {
+	if h.WebAuthn == nil {
+		http.Error(w, "Passkeys need SENTINEL_PUBLIC_URL", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// === Passkey Management ===
+
+// GetPasskeysHandler lists the signed-in user's passkeys
+// GET /api/user/passkeys
+func (h *Handler) GetPasskeysHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	passkeys, err := h.AdminStore.GetPasskeys(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get passkeys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"passkeys": passkeys, "enabled": h.WebAuthn != nil})
+}
+
+// BeginPasskeyRegistrationHandler returns the options for
+// navigator.credentials.create
+// POST /api/user/passkeys/register/begin
+func (h *Handler) BeginPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.passkeysEnabled(w) {
+		return
+	}
+	userID, username, _ := GetCurrentUser(r)
+	passkeys, err := h.AdminStore.GetPasskeys(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to get passkeys", http.StatusInternalServerError)
+		return
+	}
+	var exclude [][]byte
+	for _, p := range passkeys {
+		exclude = append(exclude, p.CredentialID)
+	}
+
+	challenge, err := newChallenge(w, r)
+	if err != nil {
+		http.Error(w, "Failed to start registration", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"publicKey": h.WebAuthn.CreationOptions(challenge, userID, username, exclude)})
+}
+
+// FinishPasskeyRegistrationHandler stores the passkey the browser created
+// POST /api/user/passkeys/register/finish {"name": "MacBook", "credential": {...}}
+func (h *Handler) FinishPasskeyRegistrationHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.passkeysEnabled(w) {
+		return
+	}
+	userID, _, _ := GetCurrentUser(r)
+	var req struct {
+		Name       string            `json:"name"`
+		Credential passkeyCredential `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		req.Name = "Passkey"
+	}
+	clientData, err1 := webauthn.Decode(req.Credential.Response.ClientDataJSON)
+	attestation, err2 := webauthn.Decode(req.Credential.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		http.Error(w, "Invalid credential encoding", http.StatusBadRequest)
+		return
+	}
+
+	cred, err := h.WebAuthn.VerifyRegistration(takeChallenge(w, r), clientData, attestation)
+	if err != nil {
+		http.Error(w, "Passkey registration failed: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	p, err := h.AdminStore.CreatePasskey(r.Context(), models.Passkey{
+		UserID:       userID,
+		Name:         req.Name,
+		CredentialID: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+	})
+	if err != nil {
+		http.Error(w, "Failed to save passkey", http.StatusInternalServerError)
+		return
+	}
+
+	meta, _ := json.Marshal(map[string]any{"name": p.Name})
+	_ = h.AdminStore.InsertAudit(r.Context(), userID, "create_passkey", "passkey", p.ID, string(meta))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "passkey": p})
+}
+
+// DeletePasskeyHandler removes one of the signed-in user's passkeys
+// DELETE /api/user/passkeys/{id}
+func (h *Handler) DeletePasskeyHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/user/passkeys/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if err := h.AdminStore.DeletePasskey(r.Context(), userID, id); err != nil {
+		http.Error(w, "Passkey not found", http.StatusNotFound)
+		return
+	}
+	_ = h.AdminStore.InsertAudit(r.Context(), userID, "delete_passkey", "passkey", id, "{}")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// === Passkey Sign-in ===
+
+// BeginPasskeyLoginHandler returns the options for navigator.credentials.get.
+// With a username only that user's passkeys are offered; without one the
+// browser offers any passkey it holds for Sentinel.
+// POST /api/login/passkey/begin {"username": "alice"}
+func (h *Handler) BeginPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.passkeysEnabled(w) {
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	var allow [][]byte
+	if req.Username != "" {
+		// Unknown users get an empty list like users without passkeys, so
+		// this doesn't tell which usernames exist
+		if user, err := h.AdminStore.GetUserByUsername(r.Context(), req.Username); err == nil {
+			passkeys, _ := h.AdminStore.GetPasskeys(r.Context(), user.ID)
+			for _, p := range passkeys {
+				allow = append(allow, p.CredentialID)
+			}
+		}
+	}
+
+	challenge, err := newChallenge(w, r)
+	if err != nil {
+		http.Error(w, "Failed to start sign-in", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"publicKey": h.WebAuthn.RequestOptions(challenge, allow)})
+}
+
+// FinishPasskeyLoginHandler checks the passkey's signature and signs the user
+// in. A passkey that verified the user (PIN or biometrics) replaces both
+// password and 2FA code; one that only confirmed presence, like a security
+// key without a PIN, counts as the second factor after the password.
+// POST /api/login/passkey/finish {"credential": {...}}
+func (h *Handler) FinishPasskeyLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.passkeysEnabled(w) {
+		return
+	}
+	var req struct {
+		Credential passkeyCredential `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	c := req.Credential.Response
+	credID, err1 := webauthn.Decode(req.Credential.ID)
+	clientData, err2 := webauthn.Decode(c.ClientDataJSON)
+	authData, err3 := webauthn.Decode(c.AuthenticatorData)
+	signature, err4 := webauthn.Decode(c.Signature)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		http.Error(w, "Invalid credential encoding", http.StatusBadRequest)
+		return
+	}
+	challenge := takeChallenge(w, r)
+
+	p, err := h.AdminStore.GetPasskeyByCredentialID(r.Context(), credID)
+	if err != nil {
+		http.Error(w, "Unknown passkey", http.StatusUnauthorized)
+		return
+	}
+	if c.UserHandle != "" {
+		handle, err := webauthn.Decode(c.UserHandle)
+		if id, ok := webauthn.UserIDFromHandle(handle); err != nil || !ok || id != p.UserID {
+			http.Error(w, "Passkey does not belong to this user", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	a, err := h.WebAuthn.VerifyAssertion(challenge, webauthn.Credential{ID: p.CredentialID, PublicKey: p.PublicKey, SignCount: p.SignCount}, clientData, authData, signature)
+	if err != nil {
+		log.Printf("Passkey sign-in for user %d failed: %v", p.UserID, err)
+		http.Error(w, "Passkey sign-in failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+	if !a.UserVerified {
+		session, _ := sessionStore.Get(r, sessionName)
+		if pending, _ := session.Values[sessionPending2FA].(int); pending != p.UserID {
+			http.Error(w, "This passkey didn't check a PIN or biometrics; sign in with your password first", http.StatusUnauthorized)
+			return
+		}
+	}
+	if err := h.AdminStore.RecordPasskeyUse(r.Context(), p.ID, a.SignCount); err != nil {
+		log.Println("Failed to record passkey use:", err)
+	}
+
+	user, err := h.AdminStore.GetUser(r.Context(), p.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+	h.completeLogin(w, r, user)
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// sessionPendingPassword is the user whose password was right but expired;
+// they sign in by setting a new one, within passwordChangeTimeout
+const (
+	sessionPendingPassword   = "pending_password_user_id"
+	sessionPendingPasswordAt = "pending_password_at"
+	passwordChangeTimeout    = 10 * time.Minute
+)
+
+// PasswordPolicy is what new passwords must satisfy and how long they last
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	History       int           // Recent passwords, the current one included, that can't be reused
+	MaxAge        time.Duration // 0: passwords don't expire
+}
+
+// PasswordPolicyFromEnv reads PASSWORD_MIN_LENGTH (default 8),
+// PASSWORD_REQUIRE (any of upper, lower, digit, symbol, comma separated),
+// PASSWORD_HISTORY (default 0) and PASSWORD_MAX_AGE_DAYS (default 0, no
+// expiry). The defaults are the 8 characters asked for before policies.
+func PasswordPolicyFromEnv() (PasswordPolicy, error) {
+	p := PasswordPolicy{MinLength: 8}
+	for key, n := range map[string]*int{"PASSWORD_MIN_LENGTH": &p.MinLength, "PASSWORD_HISTORY": &p.History} {
+		if v := os.Getenv(key); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 0 {
+				return PasswordPolicy{}, fmt.Errorf("%s: want a number, got %q", key, v)
+			}
+			*n = parsed
+		}
+	}
+	if p.History > store.MaxPasswordHistory+1 {
+		return PasswordPolicy{}, fmt.Errorf("PASSWORD_HISTORY: at most %d passwords are remembered", store.MaxPasswordHistory+1)
+	}
+	if v := os.Getenv("PASSWORD_REQUIRE"); v != "" {
+		for _, class := range strings.Split(v, ",") {
+			switch strings.TrimSpace(class) {
+			case "upper":
+				p.RequireUpper = true
+			case "lower":
+				p.RequireLower = true
+			case "digit":
+				p.RequireDigit = true
+			case "symbol":
+				p.RequireSymbol = true
+			case "":
+			default:
+				return PasswordPolicy{}, fmt.Errorf("PASSWORD_REQUIRE: unknown character class %q; want upper, lower, digit or symbol", class)
+			}
+		}
+	}
+	if v := os.Getenv("PASSWORD_MAX_AGE_DAYS"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return PasswordPolicy{}, fmt.Errorf("PASSWORD_MAX_AGE_DAYS: want a number of days, got %q", v)
+		}
+		p.MaxAge = time.Duration(days) * 24 * time.Hour
+	}
+	return p, nil
+}
+
+// Validate checks a new password's length and character classes, naming
+// everything it lacks
+func (p PasswordPolicy) Validate(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			symbol = true
+		}
+	}
+
+	var missing []string
+	if p.RequireUpper && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	short := len([]rune(password)) < p.MinLength
+
+	switch {
+	case short && len(missing) > 0:
+		return fmt.Errorf("Password must be at least %d characters and contain %s", p.MinLength, strings.Join(missing, ", "))
+	case short:
+		return fmt.Errorf("Password must be at least %d characters", p.MinLength)
+	case len(missing) > 0:
+		return fmt.Errorf("Password must contain %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Expired reports whether the user's password is older than MaxAge
+func (p PasswordPolicy) Expired(u models.User, now time.Time) bool {
+	return p.MaxAge > 0 && !u.LastPasswordChange.IsZero() && now.Sub(u.LastPasswordChange) > p.MaxAge
+}
+
+// errPasswordReused is returned for a password among the user's recent ones
+var errPasswordReused = errors.New("Password was used recently; choose a new one")
+
+// checkNewPassword applies the policy to a new password for an existing
+// user, including the reuse check
+func (h *Handler) checkNewPassword(ctx context.Context, u models.User, password string) error {
+	if err := h.Passwords.Validate(password); err != nil {
+		return err
+	}
+	if h.Passwords.History == 0 {
+		return nil
+	}
+	hashes := []string{u.PasswordHash}
+	if h.Passwords.History > 1 {
+		earlier, err := h.AdminStore.GetPasswordHistory(ctx, u.ID, h.Passwords.History-1)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, earlier...)
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return errPasswordReused
+		}
+	}
+	return nil
+}
+
+// passwordExpired answers a sign-in whose password was right but expired:
+// the session remembers the user, and ExpiredPasswordHandler takes the new
+// password
+func (h *Handler) passwordExpired(w http.ResponseWriter, r *http.Request, u models.User) bool {
+	if !h.Passwords.Expired(u, time.Now()) {
+		return false
+	}
+	session, _ := sessionStore.Get(r, sessionName)
+	session.Values[sessionPendingPassword] = u.ID
+	session.Values[sessionPendingPasswordAt] = time.Now().Unix()
+	session.Save(r, w)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":          true,
+		"password_expired": true,
+		"user_id":          u.ID,
+		"max_age_days":     int(h.Passwords.MaxAge.Hours() / 24),
+	})
+	return true
+}
+
+// ExpiredPasswordHandler sets a new password for a user whose sign-in
+// stopped at an expired one, then goes on with the sign-in: to the 2FA
+// prompt if they have 2FA, signed in otherwise
+// POST /api/login/change-password {"new_password": "..."}
+func (h *Handler) ExpiredPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	session, _ := sessionStore.Get(r, sessionName)
+	userID, _ := session.Values[sessionPendingPassword].(int)
+	at, _ := session.Values[sessionPendingPasswordAt].(int64)
+	if userID == 0 || time.Since(time.Unix(at, 0)) > passwordChangeTimeout {
+		http.Error(w, "Sign in with your current password first", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.AdminStore.GetUser(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+	if err := h.checkNewPassword(r.Context(), user, req.NewPassword); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	newHash, err := models.HashPassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+	if err := h.AdminStore.UpdateUserPassword(r.Context(), user.ID, newHash); err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+	user.PasswordHash, user.LastPasswordChange = newHash, time.Now()
+	if _, err := sessionStore.RevokeUserSessions(r.Context(), user.ID, ""); err != nil {
+		log.Printf("Failed to end sessions: %v", err)
+	}
+	_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "change_password", "user", user.ID, `{"reason":"expired"}`)
+
+	delete(session.Values, sessionPendingPassword)
+	delete(session.Values, sessionPendingPasswordAt)
+	if user.TOTPEnabled {
+		h.requireSecondFactor(w, r, user)
+		return
+	}
+	h.completeLogin(w, r, user)
+}
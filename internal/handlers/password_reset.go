@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"incident-viewer-go/internal/email"
+	"incident-viewer-go/internal/models"
+)
+
+// tokenTypePasswordReset marks reset tokens; they are signed with their own
+// secret, so they are never taken for access or refresh tokens either
+const tokenTypePasswordReset = "password_reset"
+
+// PasswordReset lets users who forgot their password set a new one through
+// a link sent to their email address. The link carries a signed token that
+// expires after TTL and, like a refresh token, holds a fingerprint of the
+// password hash: once the password changes, by the reset or otherwise, the
+// token is spent.
+type PasswordReset struct {
+	secret    []byte
+	publicURL string
+	TTL       time.Duration
+}
+
+// PasswordResetFromEnv reads PASSWORD_RESET_SECRET and PASSWORD_RESET_TTL
+// (default 1h). Without the secret it returns nil, which leaves resets to
+// admins. The links point at publicURL (SENTINEL_PUBLIC_URL), so it is
+// required.
+func PasswordResetFromEnv(publicURL string) (*PasswordReset, error) {
+	secret := os.Getenv("PASSWORD_RESET_SECRET")
+	if secret == "" {
+		return nil, nil
+	}
+	if len(secret) < minJWTSecretLen {
+		return nil, fmt.Errorf("PASSWORD_RESET_SECRET must be at least %d characters", minJWTSecretLen)
+	}
+	if publicURL == "" {
+		return nil, fmt.Errorf("PASSWORD_RESET_SECRET needs SENTINEL_PUBLIC_URL for the links in reset emails")
+	}
+	p := &PasswordReset{secret: []byte(secret), publicURL: strings.TrimSuffix(publicURL, "/"), TTL: time.Hour}
+	if v := os.Getenv("PASSWORD_RESET_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("PASSWORD_RESET_TTL: want a duration like 1h, got %q", v)
+		}
+		p.TTL = d
+	}
+	return p, nil
+}
+
+// signLinkToken signs c, a token for a link in an email, with secret; it
+// expires after ttl
+func signLinkToken(secret []byte, c jwtClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	c.Issuer = jwtIssuer
+	c.IssuedAt = jwt.NewNumericDate(now)
+	c.ExpiresAt = jwt.NewNumericDate(now.Add(ttl))
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString(secret)
+}
+
+// parseLinkToken checks a link token's signature, issuer, expiry and type and
+// returns its claims with the subject as a number
+func parseLinkToken(secret []byte, raw, typ string) (*jwtClaims, int, error) {
+	var c jwtClaims
+	_, err := jwt.ParseWithClaims(raw, &c, func(*jwt.Token) (any, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithIssuer(jwtIssuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Type != typ {
+		return nil, 0, fmt.Errorf("not a %s token", typ)
+	}
+	id, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad subject %q", c.Subject)
+	}
+	return &c, id, nil
+}
+
+// token signs a reset token for u
+func (p *PasswordReset) token(u models.User) (string, error) {
+	return signLinkToken(p.secret, jwtClaims{
+		Username:         u.Username,
+		Type:             tokenTypePasswordReset,
+		Password:         passwordFingerprint(u),
+		RegisteredClaims: jwt.RegisteredClaims{Subject: strconv.Itoa(u.ID)},
+	}, p.TTL)
+}
+
+// parse checks a reset token and returns the user ID and password
+// fingerprint it was issued for
+func (p *PasswordReset) parse(raw string) (int, string, error) {
+	c, id, err := parseLinkToken(p.secret, raw, tokenTypePasswordReset)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, c.Password, nil
+}
+
+// link is where the email sends the user; the token is in the fragment, so
+// it isn't sent to the server or to anything the page loads
+func (p *PasswordReset) link(token string) string {
+	return p.publicURL + "/#reset=" + url.QueryEscape(token)
+}
+
+// passwordResetAvailable reports whether users can reset their own password
+func (h *Handler) passwordResetAvailable() bool {
+	return h.PasswordReset != nil && h.Email != nil
+}
+
+// RequestPasswordResetHandler emails a reset link to the user, if they have
+// an email address. It answers the same whether or not they do, or exist,
+// so it doesn't tell which usernames are valid.
+// POST /api/password-reset/request {"username": "..."}
+func (h *Handler) RequestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.passwordResetAvailable() {
+		http.Error(w, "Password reset is not configured; ask an admin to reset your password", http.StatusServiceUnavailable)
+		return
+	}
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if user, err := h.AdminStore.GetUserByUsername(r.Context(), req.Username); err == nil && user.Email != "" {
+		token, err := h.PasswordReset.token(user)
+		if err != nil {
+			log.Printf("Failed to sign password reset token: %v", err)
+			http.Error(w, "Failed to send reset email", http.StatusInternalServerError)
+			return
+		}
+		msg := email.Message{
+			To:      []string{user.Email},
+			Subject: "Reset your " + h.Brand.Name + " password",
+			Text: fmt.Sprintf("Someone, hopefully you, asked to reset the password of %s on %s.\n\n"+
+				"Set a new password here; the link works once, for %s:\n%s\n\n"+
+				"If you didn't ask for this, ignore this email; your password stays as it is.\n",
+				user.Username, h.Brand.Name, h.PasswordReset.TTL, h.PasswordReset.link(token)),
+		}
+		// In the background, so the answer takes as long for unknown users
+		go func() {
+			if err := h.Email.SendQueued(context.Background(), msg); err != nil {
+				log.Printf("Failed to send password reset email to %s: %v", user.Username, err)
+			}
+		}()
+		meta, _ := json.Marshal(map[string]any{"remote_addr": r.RemoteAddr})
+		_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "request_password_reset", "user", user.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"message": "If the account has an email address, a reset link is on its way",
+	})
+}
+
+// ConfirmPasswordResetHandler sets a new password with the token from a reset
+// email. It ends the user's sessions and lifts a sign-in lockout; the user
+// then signs in with the new password, and 2FA if they have it.
+// POST /api/password-reset/confirm {"token": "...", "new_password": "..."}
+func (h *Handler) ConfirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.passwordResetAvailable() {
+		http.Error(w, "Password reset is not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var req struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	invalid := func() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "This reset link is invalid, expired or already used; ask for a new one"})
+	}
+	userID, fingerprint, err := h.PasswordReset.parse(req.Token)
+	if err != nil {
+		invalid()
+		return
+	}
+	user, err := h.AdminStore.GetUser(r.Context(), userID)
+	if err != nil || fingerprint != passwordFingerprint(user) {
+		invalid()
+		return
+	}
+	if err := h.checkNewPassword(r.Context(), user, req.NewPassword); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	newHash, err := models.HashPassword(req.NewPassword)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+	if err := h.AdminStore.UpdateUserPassword(r.Context(), user.ID, newHash); err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+	if _, err := sessionStore.RevokeUserSessions(r.Context(), user.ID, ""); err != nil {
+		log.Printf("Failed to end sessions: %v", err)
+	}
+	h.loginSucceeded(r, user.ID)
+	meta, _ := json.Marshal(map[string]any{"reason": "reset", "remote_addr": r.RemoteAddr})
+	_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "change_password", "user", user.ID, string(meta))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// UserEmailHandler shows and sets the current user's email address, where
+// password reset links go. An empty address removes it. Setting it takes the
+// current password, so a stolen session or API key can't redirect reset
+// links, and the old address is told of the change.
+// GET/PUT /api/user/email
+func (h *Handler) UserEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var address string
+	switch r.Method {
+	case http.MethodGet:
+		user, err := h.AdminStore.GetUser(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		address = user.Email
+	case http.MethodPut:
+		var req struct {
+			Email           string `json:"email"`
+			CurrentPassword string `json:"current_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		user, err := h.AdminStore.GetUser(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+			http.Error(w, "Incorrect password", http.StatusUnauthorized)
+			return
+		}
+		if address = strings.TrimSpace(req.Email); address != "" {
+			parsed, err := parseRecipients([]string{address})
+			if err != nil {
+				http.Error(w, "email must be an address like alice@example.com", http.StatusBadRequest)
+				return
+			}
+			address = parsed[0]
+		}
+		if err := h.AdminStore.UpdateUserEmail(r.Context(), userID, address); err != nil {
+			log.Printf("Failed to update email: %v", err)
+			http.Error(w, "Failed to save email", http.StatusInternalServerError)
+			return
+		}
+		notified := false
+		if user.Email != "" && user.Email != address && h.Email != nil {
+			h.notifyEmailChanged(user, address)
+			notified = true
+		}
+		meta, _ := json.Marshal(map[string]any{"notified_old_address": notified})
+		_ = h.AdminStore.InsertAudit(r.Context(), userID, "update_email", "user", userID, string(meta))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"email": address, "password_reset": h.passwordResetAvailable()})
+}
+
+// notifyEmailChanged tells the user's old address that reset links now go to
+// address, so someone who took over the account can't do it unnoticed
+func (h *Handler) notifyEmailChanged(user models.User, address string) {
+	change := "was removed"
+	if address != "" {
+		change = "was changed to " + address
+	}
+	msg := email.Message{
+		To:      []string{user.Email},
+		Subject: "Your " + h.Brand.Name + " email address changed",
+		Text: fmt.Sprintf("The email address of %s on %s, where password reset links go, %s.\n\n"+
+			"If you didn't do this, ask an admin to reset your password and sign you out everywhere.\n",
+			user.Username, h.Brand.Name, change),
+	}
+	go func() {
+		if err := h.Email.SendQueued(context.Background(), msg); err != nil {
+			log.Printf("Failed to tell %s their email changed: %v", user.Username, err)
+		}
+	}()
+}
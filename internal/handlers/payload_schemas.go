@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/payloadschema"
+)
+
+// checkPayloadSchema validates a webhook payload against the schema
+// registered for source, if there is one, and counts a violation when it
+// doesn't match. It returns the violations.
+func (h *Handler) checkPayloadSchema(ctx context.Context, source string, payload any) []string {
+	ps, err := h.AdminStore.GetPayloadSchemaBySource(ctx, source)
+	if err != nil {
+		return nil // No schema registered
+	}
+	schema, err := payloadschema.Compile(ps.Schema)
+	if err != nil {
+		log.Printf("Invalid payload schema for %q: %v", source, err)
+		return nil
+	}
+	violations := schema.Validate(payload)
+	if len(violations) > 0 {
+		if err := h.AdminStore.RecordPayloadSchemaViolation(ctx, ps.ID, strings.Join(violations, "; ")); err != nil {
+			log.Printf("Failed to record payload schema violation for %q: %v", source, err)
+		}
+	}
+	return violations
+}
+
+// flagSchemaViolations labels an alert whose payload failed its source's
+// schema, so drift shows up on the alert itself
+func (h *Handler) flagSchemaViolations(ctx context.Context, a models.Alert, violations []string) {
+	if len(violations) == 0 || a.ID == 0 {
+		return
+	}
+	if _, err := h.AlertStore.SetAlertLabels(ctx, a.ID, map[string]string{models.LabelSchemaViolation: violations[0]}); err != nil {
+		log.Printf("Failed to flag schema violation on alert %d: %v", a.ID, err)
+	}
+}
+
+func validatePayloadSchema(ps *models.PayloadSchema) error {
+	ps.Source = strings.TrimSpace(ps.Source)
+	if ps.Source == "" {
+		return errors.New("source is required")
+	}
+	if len(ps.Schema) == 0 {
+		return errors.New("schema is required")
+	}
+	if _, err := payloadschema.Compile(ps.Schema); err != nil {
+		return errors.New("invalid schema: " + err.Error())
+	}
+	return nil
+}
+
+// === Payload Schema Management ===
+
+func (h *Handler) GetPayloadSchemasHandler(w http.ResponseWriter, r *http.Request) {
+	schemas, err := h.AdminStore.GetPayloadSchemas(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get payload schemas", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"payload_schemas": schemas})
+}
+
+func (h *Handler) CreatePayloadSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	var ps models.PayloadSchema
+	if err := json.NewDecoder(r.Body).Decode(&ps); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validatePayloadSchema(&ps); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ps, err := h.AdminStore.CreatePayloadSchema(r.Context(), ps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"source": ps.Source})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_payload_schema", "payload_schema", ps.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "payload_schema": ps})
+}
+
+func (h *Handler) UpdatePayloadSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/payload-schemas/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var ps models.PayloadSchema
+	if err := json.NewDecoder(r.Body).Decode(&ps); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	ps.ID = id
+	if err := validatePayloadSchema(&ps); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ps, err = h.AdminStore.UpdatePayloadSchema(r.Context(), ps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"source": ps.Source})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_payload_schema", "payload_schema", ps.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "payload_schema": ps})
+}
+
+func (h *Handler) DeletePayloadSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/payload-schemas/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeletePayloadSchema(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_payload_schema", "payload_schema", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
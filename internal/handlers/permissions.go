@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+
+	"incident-viewer-go/internal/models"
+)
+
+// canSeeAllChats reports whether a role bypasses per-chat permissions
+func canSeeAllChats(role string) bool {
+	return role == "admin" || role == "developer"
+}
+
+// userCanAccessChat checks whether the user may see the chat with the given
+// public chat_id (as embedded in alert sources); "general" stands for alerts
+// without a chat
+func (h *Handler) userCanAccessChat(ctx context.Context, userID int, role, chatID string) bool {
+	v, err := h.chatVisibility(ctx, userID, role)
+	return err == nil && v.allowsChat(chatID)
+}
+
+// visibleChats lists the chats of the user's organization they may see:
+// all of them for admins and developers, the assigned ones for everyone
+// else
+func (h *Handler) visibleChats(ctx context.Context, user models.User) ([]models.Chat, error) {
+	if canSeeAllChats(user.Role) {
+		chats, err := h.AdminStore.GetChats(ctx)
+		return orgChats(chats, user.OrgID), err
+	}
+	chats, err := h.AdminStore.GetUserChats(ctx, user.ID)
+	return orgChats(chats, user.OrgID), err
+}
+
+// reportChats is what reports on the user's alerts go by: visibleChats,
+// after "general" for alerts without a chat when the user sees those
+func (h *Handler) reportChats(ctx context.Context, userID int, role string) ([]models.Chat, error) {
+	user, err := h.AdminStore.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.Role = role
+	chats, err := h.visibleChats(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	if user.OrgID == models.DefaultOrgID {
+		chats = append([]models.Chat{{ChatID: "general", Name: "General"}}, chats...)
+	}
+	return chats, nil
+}
+
+// chatVisibility is which alerts a user may see: those in the chats of
+// their organization that visibleChats lists and, in the default
+// organization, alerts without a chat. Admins and developers of the default
+// organization also see alerts of chats that no longer exist.
+type chatVisibility struct {
+	all      bool // Every alert: nothing belongs to another organization
+	general  bool
+	unlisted bool
+	chats    map[string]bool
+	others   map[string]bool // Chats of other organizations
+}
+
+// chatVisibility looks up the user's organization and chats. On error it
+// returns a visibility that shows no chat's alerts, with the error.
+func (h *Handler) chatVisibility(ctx context.Context, userID int, role string) (chatVisibility, error) {
+	v := chatVisibility{chats: map[string]bool{}, others: map[string]bool{}}
+	user, err := h.AdminStore.GetUser(ctx, userID)
+	if err != nil {
+		return v, err
+	}
+	user.Role = role // As the request carries it: read-only keys act as users
+	v.general = user.OrgID == models.DefaultOrgID
+	v.unlisted = v.general && canSeeAllChats(role)
+	if !canSeeAllChats(role) {
+		chats, err := h.visibleChats(ctx, user)
+		for _, c := range chats {
+			v.chats[c.ChatID] = true
+		}
+		return v, err
+	}
+	chats, err := h.AdminStore.GetChats(ctx)
+	if err != nil {
+		v.unlisted = false
+		return v, err
+	}
+	for _, c := range chats {
+		if c.OrgID == user.OrgID {
+			v.chats[c.ChatID] = true
+		} else {
+			v.others[c.ChatID] = true
+		}
+	}
+	v.all = v.unlisted && len(v.others) == 0
+	return v, nil
+}
+
+// allows reports whether the alert with source is visible
+func (v chatVisibility) allows(source string) bool {
+	return v.allowsChat(models.ChatIDFromSource(source))
+}
+
+// allowsChat reports whether the chat's alerts are visible; "" and "general"
+// stand for alerts without a chat
+func (v chatVisibility) allowsChat(chatID string) bool {
+	if chatID == "" || chatID == "general" {
+		return v.general
+	}
+	return v.all || v.chats[chatID] || (v.unlisted && !v.others[chatID])
+}
+
+// filter keeps the visible alerts
+func (v chatVisibility) filter(alerts []models.Alert) []models.Alert {
+	if v.all {
+		return alerts
+	}
+	visible := alerts[:0:0]
+	for _, a := range alerts {
+		if v.allows(a.Source) {
+			visible = append(visible, a)
+		}
+	}
+	return visible
+}
+
+// filterRollup keeps the visible chats of a rollup, with the totals of those
+func (v chatVisibility) filterRollup(r models.AlertRollup) models.AlertRollup {
+	if v.all {
+		return r
+	}
+	visible := models.AlertRollup{Since: r.Since, ByLevel: map[string]int{}}
+	for _, c := range r.Chats {
+		if !v.allowsChat(c.ChatID) {
+			continue
+		}
+		visible.Chats = append(visible.Chats, c)
+		visible.Total += c.Total
+		for level, n := range c.ByLevel {
+			visible.ByLevel[level] += n
+		}
+	}
+	return visible
+}
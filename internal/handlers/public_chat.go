@@ -2,17 +2,78 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
 )
 
-// GetChatsPublicHandler returns all chats (for main dashboard)
+// maxChatStatsDays matches how long the daily stats counters are kept
+const maxChatStatsDays = 90
+
+// GetChatsPublicHandler returns the chats of the user's organization, or
+// of the default one when signed out (for main dashboard)
 func (h *Handler) GetChatsPublicHandler(w http.ResponseWriter, r *http.Request) {
+	orgID := models.DefaultOrgID
+	if userID, _, _ := GetCurrentUser(r); userID != 0 {
+		user, err := h.AdminStore.GetUser(r.Context(), userID)
+		if err != nil {
+			http.Error(w, "Failed to get user", http.StatusInternalServerError)
+			return
+		}
+		orgID = user.OrgID
+	}
 	chats, err := h.AdminStore.GetChats(r.Context())
 	if err != nil {
 		http.Error(w, "Failed to get chats", http.StatusInternalServerError)
 		return
 	}
+	chats = orgChats(chats, orgID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"chats": chats})
 }
+
+// ChatStatsHandler returns activity statistics for one chat.
+// GET /api/chats/{chat_id}/stats?days=7 ("general" for alerts not bound to a chat)
+func (h *Handler) ChatStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/chats/")
+	chatID, ok := strings.CutSuffix(rest, "/stats")
+	if !ok || chatID == "" || strings.Contains(chatID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	userID, _, role := GetCurrentUser(r)
+	if !h.userCanAccessChat(r.Context(), userID, role, chatID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxChatStatsDays {
+			http.Error(w, "Invalid days: use 1-90", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	stats, err := h.AlertStore.GetChatStats(r.Context(), chatID, days)
+	if err != nil {
+		log.Printf("Failed to get chat stats: %v", err)
+		http.Error(w, "Failed to get chat stats", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"golang.org/x/crypto/bcrypt"
+
+	"incident-viewer-go/internal/models"
 )
 
 // PublicLoginHandler handles login for main dashboard (all users)
@@ -31,62 +33,83 @@ func (h *Handler) PublicLoginHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid credentials"})
 		return
 	}
+	if h.loginLocked(w, r, user.ID) {
+		return
+	}
 
 	// Check password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		h.loginFailed(r, user, "password")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid credentials"})
 		return
 	}
 
+	if h.passwordExpired(w, r, user) {
+		return
+	}
+
 	// Check if 2FA is enabled
 	if user.TOTPEnabled {
-		// Return 2FA required response
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{
-			"requires_2fa": true,
-			"user_id":      user.ID,
-			"totp_enabled": true,
-		})
+		h.requireSecondFactor(w, r, user)
 		return
 	}
 
-	// Get user's allowed chats
-	var allowedChats []any
-	if user.Role == "admin" || user.Role == "developer" {
-		// Admin/developer see all chats
-		chats, _ := h.AdminStore.GetChats(r.Context())
-		for _, chat := range chats {
-			allowedChats = append(allowedChats, map[string]any{
-				"id":      chat.ID,
-				"chat_id": chat.ChatID,
-				"name":    chat.Name,
-				"bot_id":  chat.BotID,
-			})
-		}
-	} else {
-		// Regular user sees only assigned chats
-		chats, _ := h.AdminStore.GetUserChats(r.Context(), user.ID)
-		for _, chat := range chats {
-			allowedChats = append(allowedChats, map[string]any{
-				"id":      chat.ID,
-				"chat_id": chat.ChatID,
-				"name":    chat.Name,
-				"bot_id":  chat.BotID,
-			})
-		}
-	}
+	h.completeLogin(w, r, user)
+}
 
-	// Create session
+// requireSecondFactor answers a sign-in that got the password right and
+// still needs the 2FA code, or a passkey instead
+func (h *Handler) requireSecondFactor(w http.ResponseWriter, r *http.Request, user models.User) {
 	session, _ := sessionStore.Get(r, sessionName)
-	session.Values["user_id"] = user.ID
-	session.Values["username"] = user.Username
-	session.Values["role"] = user.Role
+	session.Values[sessionPending2FA] = user.ID
 	session.Save(r, w)
+	passkeys, _ := h.AdminStore.GetPasskeys(r.Context(), user.ID)
 
-	// Return user info (without password hash)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
+		"success":      true,
+		"requires_2fa": true,
+		"user_id":      user.ID,
+		"totp_enabled": true,
+		"passkey":      h.WebAuthn != nil && len(passkeys) > 0,
+	})
+}
+
+// pendingSecondFactor is the user who gave the right password in this
+// session and owes a 2FA code; the code can't be checked against a user named
+// in the request alone. A user_id the client sends must be that user's. When
+// it returns false it has answered the request.
+func (h *Handler) pendingSecondFactor(w http.ResponseWriter, r *http.Request, claimedID int) (models.User, bool) {
+	session, _ := sessionStore.Get(r, sessionName)
+	pending, _ := session.Values[sessionPending2FA].(int)
+	if pending == 0 {
+		http.Error(w, "Sign in with your password first", http.StatusUnauthorized)
+		return models.User{}, false
+	}
+	if claimedID != 0 && claimedID != pending {
+		http.Error(w, "user_id is not the user signing in", http.StatusForbidden)
+		return models.User{}, false
+	}
+	user, err := h.AdminStore.GetUser(r.Context(), pending)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// completeLogin starts the session for a user who passed every sign-in step
+// and returns their profile, allowed chats and, with token login, tokens
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, user models.User) {
+	allowedChats := chatSummaries(h.userChats(r.Context(), user))
+
+	// Create session
+	csrf := startSession(w, r, user)
+	h.loginSucceeded(r, user.ID)
+
+	// Return user info (without password hash)
+	resp := map[string]any{
 		"success": true,
 		"user": map[string]any{
 			"id":           user.ID,
@@ -95,5 +118,9 @@ func (h *Handler) PublicLoginHandler(w http.ResponseWriter, r *http.Request) {
 			"totp_enabled": user.TOTPEnabled,
 		},
 		"allowed_chats": allowedChats,
-	})
+		"csrf_token":    csrf, // For X-CSRF-Token on requests with the session cookie
+	}
+	h.addLoginTokens(resp, user)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
@@ -4,55 +4,161 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
 )
 
+// purgeConfirmTTL is how long a full purge's confirm_token stays valid
+const purgeConfirmTTL = 5 * time.Minute
+
 // === Admin Purge Handler ===
 
+// PurgeAlertsHandler deletes alerts matching optional chat/level/source/before
+// filters. With dry_run it only returns the number of matching alerts; a dry run
+// of a full purge also returns the confirm_token that the same admin's real full
+// purge requires.
 func (h *Handler) PurgeAlertsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse optional chat_id from request body
 	var req struct {
-		ChatID string `json:"chat_id"` // Optional: specific chat to purge
+		ChatID       string `json:"chat_id"` // Optional: specific chat to purge
+		Level        string `json:"level"`
+		Source       string `json:"source"`
+		Before       string `json:"before"` // RFC3339 timestamp or age such as "168h"
+		DryRun       bool   `json:"dry_run"`
+		ConfirmToken string `json:"confirm_token"`
 	}
 
-	// Try to decode JSON body for chat_id parameter
+	// Body is optional (empty body = full purge)
 	_ = json.NewDecoder(r.Body).Decode(&req)
 
-	var err error
-	var purgedCount string
+	filter := store.PurgeFilter{ChatID: req.ChatID, Level: req.Level, Source: req.Source}
+	if req.Before != "" {
+		if t, err := time.Parse(time.RFC3339, req.Before); err == nil {
+			filter.Before = t
+		} else if d, err := time.ParseDuration(req.Before); err == nil && d > 0 {
+			filter.Before = time.Now().Add(-d)
+		} else {
+			http.Error(w, "Invalid before: use RFC3339 or a duration like 168h", http.StatusBadRequest)
+			return
+		}
+	}
 
-	if req.ChatID != "" {
-		// Purge alerts for specific chat
-		err = h.AlertStore.PurgeAlertsByChat(r.Context(), req.ChatID)
-		purgedCount = "chat-specific"
+	scope := "filtered"
+	if filter.IsEmpty() {
+		scope = "all"
+	} else if req.Level == "" && req.Source == "" && req.Before == "" {
+		scope = "chat-specific"
+	}
 
-		if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
-			meta, _ := json.Marshal(map[string]string{"chat_id": req.ChatID})
-			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "purge_alerts_by_chat", "system", 0, string(meta))
+	actorID, _, _ := GetCurrentUser(r)
+	resp := map[string]any{
+		"success": true,
+		"scope":   scope,
+		"dry_run": req.DryRun,
+	}
+
+	if req.DryRun {
+		count, err := h.AlertStore.PurgeAlerts(r.Context(), filter, true)
+		if err != nil {
+			log.Printf("Failed to preview purge: %v", err)
+			http.Error(w, "Failed to preview purge", http.StatusInternalServerError)
+			return
+		}
+		resp["count"] = count
+		if scope == "all" {
+			token, err := models.GenerateToken()
+			if err == nil {
+				err = h.AlertStore.SavePurgeConfirmToken(r.Context(), actorID, token, purgeConfirmTTL)
+			}
+			if err != nil {
+				log.Printf("Failed to issue purge confirmation token: %v", err)
+				http.Error(w, "Failed to issue confirmation token", http.StatusInternalServerError)
+				return
+			}
+			resp["confirm_token"] = token
+			resp["confirm_expires_in"] = int(purgeConfirmTTL.Seconds())
 		}
-	} else {
-		// Purge all alerts
-		err = h.AlertStore.PurgeAllAlerts(r.Context())
-		purgedCount = "all"
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
 
-		if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
-			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "purge_alerts", "system", 0, "{}")
+	if scope == "all" {
+		confirmed := false
+		if req.ConfirmToken != "" {
+			var err error
+			if confirmed, err = h.AlertStore.ConsumePurgeConfirmToken(r.Context(), actorID, req.ConfirmToken); err != nil {
+				log.Printf("Failed to check purge confirmation token: %v", err)
+				http.Error(w, "Failed to check confirmation token", http.StatusInternalServerError)
+				return
+			}
+		}
+		if !confirmed {
+			http.Error(w, "Full purge requires a confirm_token from your own dry_run preview", http.StatusPreconditionRequired)
+			return
 		}
 	}
 
+	count, err := h.AlertStore.PurgeAlerts(r.Context(), filter, false)
 	if err != nil {
 		log.Printf("Failed to purge alerts: %v", err)
 		http.Error(w, "Failed to purge alerts", http.StatusInternalServerError)
 		return
 	}
 
+	if actorID != 0 {
+		switch scope {
+		case "all":
+			meta, _ := json.Marshal(map[string]any{"count": count})
+			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "purge_alerts", "system", 0, string(meta))
+		case "chat-specific":
+			meta, _ := json.Marshal(map[string]any{"chat_id": req.ChatID, "count": count})
+			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "purge_alerts_by_chat", "system", 0, string(meta))
+		default:
+			meta, _ := json.Marshal(map[string]any{
+				"chat_id": req.ChatID,
+				"level":   req.Level,
+				"source":  req.Source,
+				"before":  req.Before,
+				"count":   count,
+			})
+			_ = h.AdminStore.InsertAudit(r.Context(), actorID, "purge_alerts_filtered", "system", 0, string(meta))
+		}
+	}
+
+	resp["count"] = count
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// NormalizeSourcesHandler rewrites stored alerts whose source predates source
+// normalization and moves them to the right index sets. The server does this
+// once at startup; this reruns it, e.g. after restoring an old Redis dump.
+// POST /api/admin/sources/normalize
+func (h *Handler) NormalizeSourcesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	count, err := h.AlertStore.NormalizeSources(r.Context())
+	if err != nil {
+		log.Printf("Failed to normalize sources: %v", err)
+		http.Error(w, "Failed to normalize sources", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"count": count})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "normalize_sources", "system", 0, string(meta))
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"success": true,
-		"scope":   purgedCount,
-	})
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "count": count})
 }
@@ -3,11 +3,17 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"slices"
 
 	"github.com/SherClockHolmes/webpush-go"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/sandbox"
 )
 
 var (
@@ -40,9 +46,10 @@ func (h *Handler) GetVAPIDKeyHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// SubscribePushHandler saves a push subscription
+// SubscribePushHandler saves a push subscription (POST) or removes one of
+// the user's (DELETE, with the same body or just the endpoint)
 func (h *Handler) SubscribePushHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
@@ -68,6 +75,20 @@ func (h *Handler) SubscribePushHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if r.Method == http.MethodDelete {
+		if req.Endpoint == "" {
+			http.Error(w, "endpoint is required", http.StatusBadRequest)
+			return
+		}
+		if err := h.AdminStore.DeletePushSubscription(r.Context(), userID, req.Endpoint); err != nil {
+			log.Printf("Failed to delete subscription: %v", err)
+			http.Error(w, "Failed to delete subscription", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
 	if err := h.AdminStore.SavePushSubscription(r.Context(), userID, req.Endpoint, req.Keys.P256dh, req.Keys.Auth); err != nil {
 		log.Printf("Failed to save subscription: %v", err)
 		http.Error(w, "Failed to save subscription", http.StatusInternalServerError)
@@ -77,34 +98,114 @@ func (h *Handler) SubscribePushHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// SendPushNotification sends a push notification to all subscribers
-func (h *Handler) SendPushNotification(message string) {
-	subs, err := h.AdminStore.GetPushSubscriptions(context.Background())
+// pushQueueKind names web push notifications in the notification queue
+const pushQueueKind = "push"
+
+// queuedPush is a push notification waiting in the notification queue, for
+// one device. Its keys are looked up on delivery, so an unsubscribed device
+// is skipped.
+type queuedPush struct {
+	UserID   int    `json:"user_id"`
+	Endpoint string `json:"endpoint"`
+	Message  string `json:"message"`
+}
+
+func (p queuedPush) NotificationTarget() string { return fmt.Sprintf("user %d", p.UserID) }
+
+// Queue sends push notifications through q, which retries failed sends and
+// keeps the ones that keep failing for inspection
+func (h *Handler) Queue(q *notifyqueue.Queue) {
+	h.queue = q
+	q.Register(pushQueueKind, h.deliverPush)
+}
+
+// SendPushNotification sends a push notification about a to the subscribers
+// allowed to see its chat
+func (h *Handler) SendPushNotification(a models.Alert, message string) {
+	h.sendChatPushNotification(models.ChatIDFromSource(a.Source), message)
+}
+
+// sendChatPushNotification sends a push notification to the subscribers
+// allowed to see the chat with public chat_id ("" for General)
+func (h *Handler) sendChatPushNotification(chatID, message string) {
+	subs, err := h.AdminStore.GetChatPushSubscriptions(context.Background(), chatID)
 	if err != nil {
 		log.Printf("Failed to get subscriptions: %v", err)
 		return
 	}
+	if h.Sandbox {
+		message = sandbox.Label + " " + message
+	}
+	h.sendPush(subs, message)
+}
 
+// SendUserPushNotification sends a push notification to one user's devices
+func (h *Handler) SendUserPushNotification(userID int, message string) {
+	subs, err := h.AdminStore.GetUserPushSubscriptions(context.Background(), userID)
+	if err != nil {
+		log.Printf("Failed to get subscriptions: %v", err)
+		return
+	}
+	if h.Sandbox {
+		message = sandbox.Label + " " + message
+	}
+	h.sendPush(subs, message)
+}
+
+func (h *Handler) sendPush(subs []models.PushSubscription, message string) {
 	for _, sub := range subs {
-		s := &webpush.Subscription{
-			Endpoint: sub.Endpoint,
-			Keys: webpush.Keys{
-				P256dh: sub.P256dh,
-				Auth:   sub.Auth,
-			},
+		p := queuedPush{UserID: sub.UserID, Endpoint: sub.Endpoint, Message: message}
+		if err := h.queue.Send(context.Background(), pushQueueKind, p, h.deliverPush); err != nil {
+			log.Printf("Failed to send push to %s: %v", sub.Endpoint, err)
 		}
+	}
+}
 
-		// Send Notification
-		resp, err := webpush.SendNotification([]byte(message), s, &webpush.Options{
-			Subscriber:      "mailto:admin@example.com", // Should be configurable
-			VAPIDPublicKey:  vapidPublicKey,
-			VAPIDPrivateKey: vapidPrivateKey,
-			TTL:             30,
-		})
-		if err != nil {
-			log.Printf("Failed to send push to %s: %v", sub.Endpoint, err)
-			continue
+func (h *Handler) deliverPush(ctx context.Context, payload json.RawMessage) error {
+	var p queuedPush
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return notifyqueue.Permanent(err)
+	}
+	subs, err := h.AdminStore.GetUserPushSubscriptions(ctx, p.UserID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(subs, func(s models.PushSubscription) bool { return s.Endpoint == p.Endpoint })
+	if i < 0 {
+		return nil // Unsubscribed since
+	}
+
+	resp, err := webpush.SendNotificationWithContext(ctx, []byte(p.Message), &webpush.Subscription{
+		Endpoint: subs[i].Endpoint,
+		Keys: webpush.Keys{
+			P256dh: subs[i].P256dh,
+			Auth:   subs[i].Auth,
+		},
+	}, &webpush.Options{
+		Subscriber:      "mailto:admin@example.com", // Should be configurable
+		VAPIDPublicKey:  vapidPublicKey,
+		VAPIDPrivateKey: vapidPrivateKey,
+		TTL:             30,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		// The browser dropped the subscription: stop sending to it. There's
+		// nothing left to retry, so it isn't dead-lettered either.
+		if err := h.AdminStore.DeletePushSubscription(ctx, p.UserID, p.Endpoint); err != nil {
+			return fmt.Errorf("push service returned %s, and removing the subscription failed: %w", resp.Status, err)
 		}
-		defer resp.Body.Close()
+		log.Printf("Removed expired push subscription %s of user %d", p.Endpoint, p.UserID)
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("push service returned %s", resp.Status)
+	default:
+		return notifyqueue.Permanent(fmt.Errorf("push service returned %s", resp.Status))
 	}
 }
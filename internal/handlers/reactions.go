@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// AlertActionsHandler routes the per-alert endpoints under /api/alerts/{id}/
+func (h *Handler) AlertActionsHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/alerts/")
+	idStr, action, _ := strings.Cut(rest, "/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	switch action {
+	case "reactions":
+		h.AlertReactionsHandler(w, r, id)
+	case "comments":
+		h.AlertCommentsHandler(w, r, id)
+	case "annotations":
+		h.AlertAnnotationsHandler(w, r, id)
+	case "roles":
+		h.AlertRolesHandler(w, r, id)
+	case "resolve":
+		h.AlertResolveHandler(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// writeAlertError maps alert store errors to responses
+func writeAlertError(w http.ResponseWriter, msg string, err error) {
+	if errors.Is(err, store.ErrAlertNotFound) {
+		http.Error(w, "Alert not found", http.StatusNotFound)
+		return
+	}
+	log.Printf("%s: %v", msg, err)
+	http.Error(w, msg, http.StatusInternalServerError)
+}
+
+// AlertReactionsHandler adds or removes the current user's reaction on an alert.
+// POST   /api/alerts/{id}/reactions  {"reaction": "looking"}
+// DELETE /api/alerts/{id}/reactions?reaction=looking
+func (h *Handler) AlertReactionsHandler(w http.ResponseWriter, r *http.Request, id int) {
+	userID, username, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var reaction string
+	var on bool
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			Reaction string `json:"reaction"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		reaction, on = req.Reaction, true
+	case http.MethodDelete:
+		reaction = r.URL.Query().Get("reaction")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reaction = normalizeReaction(reaction)
+	if reaction == "" {
+		http.Error(w, "Invalid reaction", http.StatusBadRequest)
+		return
+	}
+
+	alert, err := h.AlertStore.SetReaction(r.Context(), id, reaction, username, on)
+	if err != nil {
+		writeAlertError(w, "Failed to update reaction", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "reactions": alert.Reactions})
+}
+
+// AlertResolveHandler resolves an alert by hand.
+// POST /api/alerts/{id}/resolve
+func (h *Handler) AlertResolveHandler(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	alert, err := h.AlertStore.ResolveAlertByID(r.Context(), id)
+	if err != nil {
+		writeAlertError(w, "Failed to resolve alert", err)
+		return
+	}
+
+	_ = h.AdminStore.InsertAudit(r.Context(), userID, "resolve_alert", "alert", id, "{}")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "alert": alert})
+}
+
+// normalizeReaction accepts either the reaction name or its emoji
+func normalizeReaction(v string) string {
+	v = strings.TrimSpace(v)
+	for name, emoji := range models.ReactionEmoji {
+		if strings.EqualFold(v, name) || v == emoji {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+// AlertRolesHandler assigns or clears incident roles on an alert and notifies
+// the new assignee.
+// POST   /api/alerts/{id}/roles  {"role": "commander", "username": "alice"}
+// DELETE /api/alerts/{id}/roles?role=commander
+func (h *Handler) AlertRolesHandler(w http.ResponseWriter, r *http.Request, id int) {
+	userID, username, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var role, assignee string
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			Role     string `json:"role"`
+			Username string `json:"username"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		role, assignee = req.Role, strings.TrimSpace(req.Username)
+		if assignee == "" {
+			http.Error(w, "username is required", http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		role = r.URL.Query().Get("role")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	role = strings.ToLower(strings.TrimSpace(role))
+	if !slices.Contains(models.IncidentRoles, role) {
+		http.Error(w, fmt.Sprintf("Invalid role: use one of %s", strings.Join(models.IncidentRoles, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	var target models.User
+	if assignee != "" {
+		var err error
+		target, err = h.AdminStore.GetUserByUsername(r.Context(), assignee)
+		if err != nil {
+			http.Error(w, "Unknown user", http.StatusBadRequest)
+			return
+		}
+		assignee = target.Username
+	}
+
+	alert, err := h.AlertStore.SetIncidentRole(r.Context(), id, role, assignee)
+	if err != nil {
+		writeAlertError(w, "Failed to update role", err)
+		return
+	}
+
+	if target.ID != 0 && target.ID != userID {
+		go h.SendUserPushNotification(target.ID, fmt.Sprintf("🎖️ %s made you %s on: %s", username, role, alert.Title))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "roles": alert.Roles})
+}
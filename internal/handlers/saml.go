@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/saml"
+	"incident-viewer-go/internal/store"
+)
+
+// samlCookie carries the pending AuthnRequest ID from /saml/login to the
+// IdP's post to /saml/acs. The post is cross-site, so the session cookie
+// may not come along.
+const samlCookie = "sentinel-saml"
+
+// samlLoginTimeout is how long a user has to sign in at the IdP
+const samlLoginTimeout = 10 * time.Minute
+
+// maxSAMLResponseSize bounds the posted form
+const maxSAMLResponseSize = 1 << 20
+
+// SAMLMetadataHandler serves the SP metadata to register with the IdP
+// GET /saml/metadata
+func (h *Handler) SAMLMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	if h.SAML == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(h.SAML.Metadata())
+}
+
+// SAMLLoginHandler sends the user to the IdP to sign in; ?next= is where
+// they land afterwards
+// GET /saml/login
+func (h *Handler) SAMLLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if h.SAML == nil {
+		http.NotFound(w, r)
+		return
+	}
+	redirect, requestID, err := h.SAML.AuthnRequestURL(localPath(r.URL.Query().Get("next")))
+	if err != nil {
+		http.Error(w, "Failed to start SAML sign-in", http.StatusInternalServerError)
+		return
+	}
+
+	cookie := &http.Cookie{
+		Name:     samlCookie,
+		Value:    requestID,
+		Path:     "/saml/",
+		MaxAge:   int(samlLoginTimeout.Seconds()),
+		HttpOnly: true,
+	}
+	// Browsers only send a cookie with a cross-site POST when it is
+	// SameSite=None, which needs HTTPS
+	if strings.HasPrefix(h.SAML.ACSURL, "https://") {
+		cookie.Secure, cookie.SameSite = true, http.SameSiteNoneMode
+	}
+	http.SetCookie(w, cookie)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// SAMLACSHandler receives the IdP's response, signs the user in and creates
+// their account on first sign-in. It only signs in to accounts SAML created
+// or an admin linked, so an IdP username can't take over a local account,
+// and accounts with 2FA still need their code. With SAML_ROLE_ATTRIBUTE set,
+// the role follows the IdP on every sign-in.
+// POST /saml/acs
+func (h *Handler) SAMLACSHandler(w http.ResponseWriter, r *http.Request) {
+	if h.SAML == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxSAMLResponseSize)
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	requestID := ""
+	if c, err := r.Cookie(samlCookie); err == nil {
+		requestID = c.Value
+	}
+	http.SetCookie(w, &http.Cookie{Name: samlCookie, Path: "/saml/", MaxAge: -1})
+
+	a, err := h.SAML.ParseResponse(r.PostForm.Get("SAMLResponse"), requestID, time.Now())
+	if err != nil {
+		log.Println("SAML sign-in failed:", err)
+		http.Error(w, "SAML sign-in failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// An assertion signs in once, however often it is posted
+	ttl := time.Until(a.NotOnOrAfter) + saml.MaxClockSkew
+	fresh, err := h.AlertStore.ClaimNonce(r.Context(), "saml:"+a.ID, ttl)
+	if err != nil {
+		http.Error(w, "Failed to check SAML assertion", http.StatusServiceUnavailable)
+		return
+	}
+	if !fresh {
+		http.Error(w, "SAML sign-in failed: assertion was already used", http.StatusUnauthorized)
+		return
+	}
+
+	username := strings.TrimSpace(h.SAML.Username(a))
+	if username == "" {
+		log.Println("SAML sign-in failed: assertion has no username")
+		http.Error(w, "SAML sign-in failed: the IdP sent no username", http.StatusUnauthorized)
+		return
+	}
+	role := h.SAML.Role(a)
+
+	user, err := h.AdminStore.GetUserByUsername(r.Context(), username)
+	switch {
+	case errors.Is(err, store.ErrUserNotFound):
+		// The password is random and never shown: the account signs in
+		// through the IdP until an admin resets it
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+		user, err = h.AdminStore.CreateUser(r.Context(), username, hex.EncodeToString(b), role, models.DefaultOrgID)
+		if err != nil {
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+		meta, _ := json.Marshal(map[string]any{"username": username, "role": role, "via": "saml"})
+		_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "create_user", "user", user.ID, string(meta))
+		if err := h.AdminStore.SetUserSAMLLinked(r.Context(), user.ID, true); err != nil {
+			log.Printf("Failed to link user %d to SAML: %v", user.ID, err)
+			http.Error(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+	case err != nil:
+		http.Error(w, "Failed to get user", http.StatusInternalServerError)
+		return
+	case !user.SAMLLinked:
+		log.Printf("SAML sign-in refused: %q is a local account not linked to SSO", username)
+		http.Error(w, "SAML sign-in failed: an account named "+username+" exists but isn't linked to SSO; ask an admin to link it", http.StatusForbidden)
+		return
+	case h.SAML.RoleAttribute != "" && user.Role != role:
+		if err := h.AdminStore.UpdateUser(r.Context(), user.ID, user.Username, role); err != nil {
+			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+			return
+		}
+		meta, _ := json.Marshal(map[string]any{"from": user.Role, "to": role, "via": "saml"})
+		_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "update_user", "user", user.ID, string(meta))
+		user.Role = role
+		if err := sessionStore.UpdateUserSessions(r.Context(), user.ID, user.Username, role); err != nil {
+			log.Println("Failed to update sessions:", err)
+		}
+	}
+
+	// The IdP stands in for the password only: the code is asked on the
+	// login page, as after a password
+	if user.TOTPEnabled {
+		session, _ := sessionStore.Get(r, sessionName)
+		session.Values[sessionPending2FA] = user.ID
+		if err := session.Save(r, w); err != nil {
+			log.Println("Failed to save session:", err)
+		}
+		http.Redirect(w, r, "/#sso-2fa", http.StatusSeeOther)
+		return
+	}
+
+	startSession(w, r, user)
+
+	next := localPath(r.PostForm.Get("RelayState"))
+	if next == "" {
+		next = "/"
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// SAMLLinkHandler lets SAML sign-ins use a local account (PUT) or stops them
+// (DELETE). Accounts SAML created are linked already.
+// PUT|DELETE /api/admin/users/{id}/saml
+func (h *Handler) SAMLLinkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/saml"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedUser(w, r, id); !ok {
+		return
+	}
+	linked := r.Method == http.MethodPut
+	if err := h.AdminStore.SetUserSAMLLinked(r.Context(), id, linked); err != nil {
+		http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		action := "unlink_saml"
+		if linked {
+			action = "link_saml"
+		}
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, action, "user", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "saml_linked": linked})
+}
+
+// localPath returns p if it is a path on this site, so a sign-in can't be
+// used to send users elsewhere
+func localPath(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.HasPrefix(p, "/\\") {
+		return ""
+	}
+	return p
+}
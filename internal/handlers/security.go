@@ -1,92 +1,118 @@
 package handlers
 
 import (
-	"bytes"
-	"io"
-	"net/http"
-
-	// "os" // Commented out - not needed while signature validation is disabled
-	"sync"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
-)
-
-// validateSharedSecret checks X-Sentinel-Signature against HMAC-SHA256(body, secret).
-// If WEBHOOK_SECRET is empty, validation is skipped (returns true).
-// NOTE: Signature validation is currently disabled for internal Gatus webhook usage
-// since Gatus uptime monitor cannot calculate signatures for each webhook request.
-func validateSharedSecret(r *http.Request) bool {
-	// Temporarily skip signature validation for internal usage
-	return true
-
-	// Original validation logic (commented out for now)
-	// secret := os.Getenv("WEBHOOK_SECRET")
-	// if secret == "" {
-	// 	return true
-	// }
-	// return validateSignature(r, secret, r.Header.Get("X-Sentinel-Signature"))
-}
-
-// validateSignature validates HMAC for a given secret with timestamp and nonce checks.
-// NOTE: Signature validation is currently disabled for internal Gatus webhook usage
-func validateSignature(r *http.Request, secret, sig string) bool {
-	// Temporarily skip signature validation for internal usage
-	// Need to read and restore body for downstream handlers
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		return false
-	}
-	r.Body = io.NopCloser(bytes.NewBuffer(body)) // restore for downstream handlers
 
-	return true // Skip validation
+	"incident-viewer-go/internal/store"
+)
 
-	/* Original validation logic (commented out for now)
-	if sig == "" {
+// validHubSignature checks a GitHub-style X-Hub-Signature-256 header
+// ("sha256=" + hex HMAC-SHA256 of the raw body)
+func validHubSignature(header string, body []byte, secret string) bool {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok || secret == "" {
 		return false
 	}
-
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write(body)
 	expected := hex.EncodeToString(mac.Sum(nil))
-	ts := r.Header.Get("X-Sentinel-Timestamp")
-	nonce := r.Header.Get("X-Sentinel-Nonce")
-	if ts != "" && nonce != "" {
-		mac.Reset()
-		mac.Write([]byte(ts))
-		mac.Write([]byte("." + nonce + "."))
-		mac.Write(body)
-		expected = hex.EncodeToString(mac.Sum(nil))
-		if !withinSkew(ts) || isReplay(nonce) {
-			return false
-		}
-	}
 	return hmac.Equal([]byte(sig), []byte(expected))
-	*/
 }
 
+// Replay protection errors
 var (
-	nonceCache   = make(map[string]time.Time)
-	nonceCacheMu sync.Mutex
-	maxSkew      = 5 * time.Minute
+	ErrMissingNonce   = errors.New("signed requests must include X-Sentinel-Timestamp and X-Sentinel-Nonce")
+	ErrStaleRequest   = errors.New("request timestamp outside the allowed window")
+	ErrReplayedNonce  = errors.New("nonce already used")
+	ErrNonceUnchecked = errors.New("could not check nonce")
 )
 
-func withinSkew(ts string) bool {
+// ReplayGuard rejects signed requests that are stale or already seen: the
+// timestamp must be within MaxSkew of now, and each nonce is claimed in Redis
+// for NonceTTL, so a replay is caught on any replica and across restarts
+type ReplayGuard struct {
+	store    store.AlertStore
+	MaxSkew  time.Duration
+	NonceTTL time.Duration
+	Require  bool // Reject signatures that don't cover a timestamp and nonce
+}
+
+// ReplayGuardFromEnv reads WEBHOOK_MAX_SKEW (default 5m), WEBHOOK_NONCE_TTL
+// (default and minimum twice the skew: a timestamp stays acceptable that
+// long, so its nonce must be remembered that long) and WEBHOOK_REQUIRE_NONCE
+func ReplayGuardFromEnv(s store.AlertStore) (*ReplayGuard, error) {
+	g := &ReplayGuard{store: s, MaxSkew: 5 * time.Minute, Require: os.Getenv("WEBHOOK_REQUIRE_NONCE") == "true"}
+	if v := os.Getenv("WEBHOOK_MAX_SKEW"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("WEBHOOK_MAX_SKEW: want a duration like 5m, got %q", v)
+		}
+		g.MaxSkew = d
+	}
+	g.NonceTTL = 2 * g.MaxSkew
+	if v := os.Getenv("WEBHOOK_NONCE_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("WEBHOOK_NONCE_TTL: want a duration like 10m, got %q", v)
+		}
+		if d < 2*g.MaxSkew {
+			return nil, fmt.Errorf("WEBHOOK_NONCE_TTL (%s) must be at least twice WEBHOOK_MAX_SKEW (%s)", d, g.MaxSkew)
+		}
+		g.NonceTTL = d
+	}
+	return g, nil
+}
+
+// Check validates a signed request's timestamp (RFC 3339 or Unix seconds)
+// and claims its nonce. Without both headers it only passes when nonces
+// aren't required.
+func (g *ReplayGuard) Check(ctx context.Context, ts, nonce string) error {
+	if ts == "" || nonce == "" {
+		if g.Require {
+			return ErrMissingNonce
+		}
+		return nil
+	}
+	if !g.withinSkew(ts) {
+		return ErrStaleRequest
+	}
+	fresh, err := g.store.ClaimNonce(ctx, nonce, g.NonceTTL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNonceUnchecked, err)
+	}
+	if !fresh {
+		return ErrReplayedNonce
+	}
+	return nil
+}
+
+func (g *ReplayGuard) withinSkew(ts string) bool {
 	t, err := time.Parse(time.RFC3339, ts)
 	if err != nil {
-		return false
+		secs, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return false
+		}
+		t = time.Unix(secs, 0)
 	}
 	now := time.Now()
-	return t.After(now.Add(-maxSkew)) && t.Before(now.Add(maxSkew))
+	return t.After(now.Add(-g.MaxSkew)) && t.Before(now.Add(g.MaxSkew))
 }
 
-func isReplay(nonce string) bool {
-	nonceCacheMu.Lock()
-	defer nonceCacheMu.Unlock()
-	if nonce == "" {
-		return false
-	}
-	if exp, ok := nonceCache[nonce]; ok && exp.After(time.Now()) {
-		return true
+// SignedContent is what X-Sentinel-Signature covers: the body alone, or
+// "<timestamp>.<nonce>.<body>" when the request carries both
+func SignedContent(ts, nonce string, body []byte) []byte {
+	if ts == "" || nonce == "" {
+		return body
 	}
-	nonceCache[nonce] = time.Now().Add(maxSkew)
-	return false
+	return append([]byte(ts+"."+nonce+"."), body...)
 }
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"incident-viewer-go/internal/models"
+)
+
+// firstRunSetup guards creating the initial admin through the setup wizard
+type firstRunSetup struct {
+	mu    sync.Mutex
+	token string // Empty once setup is done or not needed
+}
+
+// InitAdmin makes sure a fresh install gets an admin without a well-known
+// password. With INITIAL_ADMIN_PASSWORD (and optionally
+// INITIAL_ADMIN_USERNAME, default "admin") the admin is created from the
+// environment. Otherwise the first-run setup at /admin/setup is unlocked
+// with SETUP_TOKEN, or a one-time token printed to the log.
+func (h *Handler) InitAdmin(ctx context.Context) {
+	users, err := h.AdminStore.GetUsers(ctx)
+	if err != nil {
+		log.Printf("Failed to check for users: %v", err)
+		return
+	}
+	if len(users) > 0 {
+		return
+	}
+
+	if password := os.Getenv("INITIAL_ADMIN_PASSWORD"); password != "" {
+		username := os.Getenv("INITIAL_ADMIN_USERNAME")
+		if username == "" {
+			username = "admin"
+		}
+		if err := h.Passwords.Validate(password); err != nil {
+			log.Printf("INITIAL_ADMIN_PASSWORD: %v; falling back to first-run setup", err)
+		} else if _, err := h.AdminStore.CreateUser(ctx, username, password, "admin", models.DefaultOrgID); err != nil {
+			log.Printf("Failed to create initial admin: %v", err)
+		} else {
+			log.Printf("Created initial admin %q from INITIAL_ADMIN_PASSWORD", username)
+			return
+		}
+	}
+
+	token := os.Getenv("SETUP_TOKEN")
+	if token == "" {
+		if token, err = models.GenerateToken(); err != nil {
+			log.Printf("Failed to generate setup token: %v", err)
+			return
+		}
+		log.Printf("No users yet. Create the first admin at /admin/setup with setup token: %s", token)
+	} else {
+		log.Println("No users yet. Create the first admin at /admin/setup with SETUP_TOKEN")
+	}
+
+	h.setup.mu.Lock()
+	h.setup.token = token
+	h.setup.mu.Unlock()
+}
+
+func (h *Handler) setupPending() bool {
+	h.setup.mu.Lock()
+	defer h.setup.mu.Unlock()
+	return h.setup.token != ""
+}
+
+// SetupPage serves the first-run setup wizard, or sends people to the login
+// page once there is an admin
+func (h *Handler) SetupPage(w http.ResponseWriter, r *http.Request) {
+	if !h.setupPending() {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+	h.RenderAdminPage(w, "setup", h.newPageData(w, r))
+}
+
+// SetupStatusHandler reports whether the first-run setup still has to be done
+// GET /api/setup/status
+func (h *Handler) SetupStatusHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"required": h.setupPending()})
+}
+
+// SetupHandler creates the initial admin, given the setup token, and logs
+// them in. It works once: afterwards the token is discarded.
+// POST /api/setup
+func (h *Handler) SetupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Token    string `json:"token"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+
+	h.setup.mu.Lock()
+	defer h.setup.mu.Unlock()
+
+	if h.setup.token == "" {
+		http.Error(w, "Setup has already been completed", http.StatusConflict)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Token), []byte(h.setup.token)) != 1 {
+		http.Error(w, "Invalid setup token", http.StatusUnauthorized)
+		return
+	}
+	if req.Username == "" {
+		http.Error(w, "Username cannot be empty", http.StatusBadRequest)
+		return
+	}
+	if err := h.Passwords.Validate(req.Password); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Another instance may have finished setup with the same SETUP_TOKEN
+	if users, err := h.AdminStore.GetUsers(r.Context()); err != nil || len(users) > 0 {
+		h.setup.token = ""
+		http.Error(w, "Setup has already been completed", http.StatusConflict)
+		return
+	}
+
+	user, err := h.AdminStore.CreateUser(r.Context(), req.Username, req.Password, "admin", models.DefaultOrgID)
+	if err != nil {
+		log.Printf("Failed to create initial admin: %v", err)
+		http.Error(w, "Failed to create admin", http.StatusInternalServerError)
+		return
+	}
+	h.setup.token = ""
+	_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "initial_setup", "user", user.ID, "{}")
+	log.Printf("First-run setup completed: created admin %q", user.Username)
+
+	startSession(w, r, user)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":  true,
+		"user":     user,
+		"redirect": "/admin/dashboard",
+	})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"incident-viewer-go/internal/shortlink"
+	"incident-viewer-go/internal/store"
+)
+
+// ShortLinkHandler redirects /a/{code} to the dashboard page it stands for.
+// The page itself still asks for a login, so a code only reveals an alert ID.
+func (h *Handler) ShortLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	code := strings.TrimPrefix(r.URL.Path, shortlink.PathPrefix)
+	if code == "" || strings.Contains(code, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	path, err := h.AlertStore.GetShortLink(r.Context(), code)
+	if errors.Is(err, store.ErrShortLinkNotFound) {
+		http.Error(w, "Link not found or expired", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Failed to resolve short link:", err)
+		http.Error(w, "Failed to resolve link", http.StatusInternalServerError)
+		return
+	}
+	if !shortlink.Valid(path) {
+		http.NotFound(w, r)
+		return
+	}
+	http.Redirect(w, r, path, http.StatusFound)
+}
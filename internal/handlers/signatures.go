@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+// SharedSecretEndpoints are the ingestion endpoints signed with
+// WEBHOOK_SECRET, by their names in the ingestion metrics. Bots sign with
+// their own secrets instead, and the other integrations with their
+// provider's scheme.
+var SharedSecretEndpoints = []string{"webhook", "deploys", "metrics", "slack", "discord", "datadog", "zabbix", "icinga", "uptimekuma"}
+
+// signatureExempt reports whether an admin exempted the endpoint from
+// signatures. If the exemptions can't be loaded, signatures are required.
+func (h *Handler) signatureExempt(ctx context.Context, endpoint string) bool {
+	exemptions, err := h.AdminStore.GetSignatureExemptions(ctx)
+	if err != nil {
+		log.Printf("Failed to get signature exemptions, requiring a signature on %s: %v", endpoint, err)
+		return false
+	}
+	return slices.ContainsFunc(exemptions, func(e models.SignatureExemption) bool { return e.Endpoint == endpoint })
+}
+
+// SharedSecret is the secret hmacMiddleware checks the endpoint's requests
+// with: WEBHOOK_SECRET, unless the endpoint is exempt. Without
+// WEBHOOK_SECRET it is nil and the endpoint takes unsigned requests.
+func (h *Handler) SharedSecret(endpoint string) func(*http.Request) string {
+	if h.WebhookSecret == "" {
+		return nil
+	}
+	return func(r *http.Request) string {
+		if h.signatureExempt(r.Context(), endpoint) {
+			return ""
+		}
+		return h.WebhookSecret
+	}
+}
+
+// GetSignatureExemptionsHandler lists the exempt endpoints, with the
+// endpoints that can be exempted
+// GET /api/admin/signature-exemptions
+func (h *Handler) GetSignatureExemptionsHandler(w http.ResponseWriter, r *http.Request) {
+	exemptions, err := h.AdminStore.GetSignatureExemptions(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get signature exemptions", http.StatusInternalServerError)
+		return
+	}
+	if exemptions == nil {
+		exemptions = []models.SignatureExemption{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"exemptions":        exemptions,
+		"endpoints":         SharedSecretEndpoints,
+		"secret_configured": h.WebhookSecret != "",
+	})
+}
+
+// SaveSignatureExemptionHandler lets an endpoint take unsigned requests
+// POST /api/admin/signature-exemptions {"endpoint": "uptimekuma", "reason": "Gatus can't sign"}
+func (h *Handler) SaveSignatureExemptionHandler(w http.ResponseWriter, r *http.Request) {
+	var e models.SignatureExemption
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(SharedSecretEndpoints, e.Endpoint) {
+		http.Error(w, "endpoint must be one of "+strings.Join(SharedSecretEndpoints, ", "), http.StatusBadRequest)
+		return
+	}
+	e.Reason = strings.TrimSpace(e.Reason)
+	e.CreatedBy = nil
+	actorID, _, _ := GetCurrentUser(r)
+	if actorID != 0 {
+		e.CreatedBy = &actorID
+	}
+
+	e, err := h.AdminStore.SaveSignatureExemption(r.Context(), e)
+	if err != nil {
+		log.Printf("Failed to save signature exemption: %v", err)
+		http.Error(w, "Failed to save signature exemption", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"endpoint": e.Endpoint, "reason": e.Reason})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "exempt_signature", "signature_exemption", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "exemption": e})
+}
+
+// DeleteSignatureExemptionHandler makes an endpoint require signatures again
+// DELETE /api/admin/signature-exemptions/{endpoint}
+func (h *Handler) DeleteSignatureExemptionHandler(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, "/api/admin/signature-exemptions/")
+	if err := h.AdminStore.DeleteSignatureExemption(r.Context(), endpoint); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"endpoint": endpoint})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "require_signature", "signature_exemption", 0, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// validateAlertMatcher normalizes the matchers shared by silences and mute
+// rules; at least one is required, as an empty matcher selects every alert
+func validateAlertMatcher(m *models.AlertMatcher) error {
+	m.ChatID = strings.TrimSpace(m.ChatID)
+	m.Source = strings.TrimSpace(m.Source)
+	if _, err := path.Match(m.Source, ""); err != nil {
+		return fmt.Errorf("invalid source pattern %q", m.Source)
+	}
+	for i, level := range m.Levels {
+		m.Levels[i] = models.NormalizeLevel(level)
+	}
+	if !m.HasMatchers() {
+		return errors.New("set at least one of chat_id, source, levels or labels")
+	}
+	return nil
+}
+
+// silenceRequest is a silence as sent to the API: ends_at, or a duration
+// from starts_at such as "2h"
+type silenceRequest struct {
+	models.Silence
+	Duration string `json:"duration,omitempty"`
+}
+
+func validateSilence(req silenceRequest) (models.Silence, error) {
+	s := req.Silence
+	s.Comment = strings.TrimSpace(s.Comment)
+	if s.Comment == "" {
+		return s, errors.New("comment is required: say why, e.g. the deploy it covers")
+	}
+	if err := validateAlertMatcher(&s.AlertMatcher); err != nil {
+		return s, err
+	}
+
+	if s.StartsAt.IsZero() {
+		s.StartsAt = time.Now().UTC()
+	}
+	if req.Duration != "" {
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return s, fmt.Errorf("invalid duration %q: want a duration like 2h", req.Duration)
+		}
+		s.EndsAt = s.StartsAt.Add(d)
+	}
+	switch {
+	case s.EndsAt.IsZero():
+		return s, errors.New("ends_at or duration is required")
+	case !s.EndsAt.After(s.StartsAt):
+		return s, errors.New("ends_at must be after starts_at")
+	case s.EndsAt.Sub(s.StartsAt) > store.MaxSilenceDuration:
+		return s, fmt.Errorf("a silence can last at most %d days", int(store.MaxSilenceDuration.Hours()/24))
+	}
+	return s, nil
+}
+
+func silenceAuditMeta(s models.Silence) string {
+	meta, _ := json.Marshal(map[string]any{
+		"comment": s.Comment, "chat_id": s.ChatID, "source": s.Source, "levels": s.Levels,
+		"labels": s.Labels, "starts_at": s.StartsAt, "ends_at": s.EndsAt,
+	})
+	return string(meta)
+}
+
+// silenceView is a silence with its current state, for the API
+type silenceView struct {
+	models.Silence
+	State string `json:"state"`
+}
+
+func newSilenceView(s models.Silence, now time.Time) silenceView {
+	return silenceView{Silence: s, State: s.State(now)}
+}
+
+// === Silences ===
+
+// GetSilencesHandler lists pending, active and recently expired silences
+// GET /api/admin/silences
+func (h *Handler) GetSilencesHandler(w http.ResponseWriter, r *http.Request) {
+	silences, err := h.AlertStore.GetSilences(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get silences", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	list := make([]silenceView, 0, len(silences))
+	for _, s := range silences {
+		list = append(list, newSilenceView(s, now))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"silences": list})
+}
+
+// CreateSilenceHandler silences matching alerts for a time window
+// POST /api/admin/silences
+func (h *Handler) CreateSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	var req silenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	s, err := validateSilence(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	actorID, username, _ := GetCurrentUser(r)
+	s.CreatedBy = username
+
+	s, err = h.AlertStore.CreateSilence(r.Context(), s)
+	if err != nil {
+		http.Error(w, "Failed to create silence", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_silence", "silence", s.ID, silenceAuditMeta(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "silence": newSilenceView(s, time.Now())})
+}
+
+// UpdateSilenceHandler changes a silence's matchers or window, e.g. to end it
+// early or extend an overrunning deploy
+// PUT /api/admin/silences/{id}
+func (h *Handler) UpdateSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/silences/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req silenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	s, err := validateSilence(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.ID = id
+
+	s, err = h.AlertStore.UpdateSilence(r.Context(), s)
+	if errors.Is(err, store.ErrSilenceNotFound) {
+		http.Error(w, "Silence not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to update silence", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_silence", "silence", s.ID, silenceAuditMeta(s))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "silence": newSilenceView(s, time.Now())})
+}
+
+// DeleteSilenceHandler removes a silence; matching alerts notify again
+// DELETE /api/admin/silences/{id}
+func (h *Handler) DeleteSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/silences/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.AlertStore.DeleteSilence(r.Context(), id)
+	if errors.Is(err, store.ErrSilenceNotFound) {
+		http.Error(w, "Silence not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to delete silence", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_silence", "silence", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+// maxSMSTestsPerHour keeps the test button from running up the bill
+const maxSMSTestsPerHour = 3
+
+// SMSPreferenceHandler shows and sets the current user's SMS paging opt-in.
+// GET/PUT /api/user/sms
+func (h *Handler) SMSPreferenceHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var pref models.SMSPreference
+	switch r.Method {
+	case http.MethodGet:
+		var err error
+		if pref, err = h.AdminStore.GetSMSPreference(r.Context(), userID); err != nil {
+			pref = models.SMSPreference{UserID: userID} // Not set up yet
+		}
+	case http.MethodPut:
+		var req struct {
+			Phone   string `json:"phone"`
+			Enabled bool   `json:"enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		phone, ok := models.NormalizePhone(req.Phone)
+		if !ok {
+			http.Error(w, "phone must be in international format, e.g. +14155550123", http.StatusBadRequest)
+			return
+		}
+
+		var err error
+		pref, err = h.AdminStore.SaveSMSPreference(r.Context(), models.SMSPreference{UserID: userID, Phone: phone, Enabled: req.Enabled})
+		if err != nil {
+			log.Printf("Failed to save SMS preference: %v", err)
+			http.Error(w, "Failed to save SMS preference", http.StatusInternalServerError)
+			return
+		}
+		meta, _ := json.Marshal(map[string]any{"enabled": pref.Enabled})
+		_ = h.AdminStore.InsertAudit(r.Context(), userID, "update_sms_preference", "user", userID, string(meta))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"sms": pref, "available": h.SMS != nil})
+}
+
+// SMSTestHandler sends a test page to the current user's saved number
+// POST /api/user/sms/test
+func (h *Handler) SMSTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.SMS == nil {
+		http.Error(w, "SMS is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	pref, err := h.AdminStore.GetSMSPreference(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Save a phone number first", http.StatusBadRequest)
+		return
+	}
+	count, err := h.AlertStore.IncrSMSCount(r.Context(), fmt.Sprintf("test:%d", userID))
+	if err != nil {
+		http.Error(w, "Failed to send", http.StatusInternalServerError)
+		return
+	}
+	if count > maxSMSTestsPerHour {
+		http.Error(w, "Too many test messages, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	msg := "Sentinel test page, sent " + time.Now().UTC().Format("2006-01-02 15:04 UTC") + ". Critical alerts will look like this."
+	if err := h.SMS.Send(r.Context(), pref.Phone, msg); err != nil {
+		http.Error(w, "Failed to send: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+func validateStakeholderList(l *models.StakeholderList) error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return errors.New("name is required")
+	}
+	if l.MinLevel == "" {
+		l.MinLevel = models.LevelCritical
+	}
+	l.MinLevel = models.NormalizeLevel(l.MinLevel)
+	if !slices.Contains(models.StakeholderLevels, l.MinLevel) {
+		return fmt.Errorf("min_level must be one of %s", strings.Join(models.StakeholderLevels, ", "))
+	}
+	l.ChatID = strings.TrimSpace(l.ChatID)
+
+	emails, err := parseRecipients(l.Emails)
+	if err != nil {
+		return err
+	}
+	l.Emails = emails
+
+	for i, u := range l.SlackWebhooks {
+		u = strings.TrimSpace(u)
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("invalid Slack webhook URL %q: must be https", u)
+		}
+		l.SlackWebhooks[i] = u
+	}
+
+	if len(l.Emails) == 0 && len(l.SlackWebhooks) == 0 {
+		return errors.New("add at least one email or Slack webhook")
+	}
+	return nil
+}
+
+// === Stakeholder List Management ===
+
+func (h *Handler) GetStakeholderListsHandler(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.AdminStore.GetStakeholderLists(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get stakeholder lists", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"stakeholder_lists": lists})
+}
+
+func (h *Handler) CreateStakeholderListHandler(w http.ResponseWriter, r *http.Request) {
+	var l models.StakeholderList
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if err := validateStakeholderList(&l); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err := h.AdminStore.CreateStakeholderList(r.Context(), l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": l.Name, "emails": len(l.Emails), "slack_webhooks": len(l.SlackWebhooks)})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_stakeholder_list", "stakeholder_list", l.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "stakeholder_list": l})
+}
+
+func (h *Handler) UpdateStakeholderListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/stakeholder-lists/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var l models.StakeholderList
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	l.ID = id
+	if err := validateStakeholderList(&l); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	l, err = h.AdminStore.UpdateStakeholderList(r.Context(), l)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": l.Name, "emails": len(l.Emails), "slack_webhooks": len(l.SlackWebhooks)})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_stakeholder_list", "stakeholder_list", l.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "stakeholder_list": l})
+}
+
+func (h *Handler) DeleteStakeholderListHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/stakeholder-lists/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteStakeholderList(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_stakeholder_list", "stakeholder_list", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+)
+
+const standupNotableLimit = 5
+
+// standupSection is one chat's part of the standup summary
+type standupSection struct {
+	ChatID   string         `json:"chat_id"`
+	Name     string         `json:"name"`
+	New      int            `json:"new"`
+	Resolved int            `json:"resolved"`
+	Critical int            `json:"critical"`
+	Open     int            `json:"open"` // Unresolved lifecycle alerts raised in the window
+	Notable  []models.Alert `json:"notable"`
+}
+
+// StandupSummaryHandler reports what happened per chat over the last hours
+// (default 24): new, resolved and critical counts plus notable incidents.
+// GET /api/summary/standup?chat_id=...&hours=24&format=text|json&tz=...&locale=...
+// The text format is Slack mrkdwn, which also reads fine pasted into email.
+// tz and locale set the timezone and date/number formatting (default UTC).
+func (h *Handler) StandupSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, _, role := GetCurrentUser(r)
+
+	hours := 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 24*7 {
+			http.Error(w, "Invalid hours: use 1-168", http.StatusBadRequest)
+			return
+		}
+		hours = n
+	}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+
+	rf, err := reportFormatFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Chats to report on: one requested chat, or every chat the user can see
+	chats, err := h.reportChats(r.Context(), userID, role)
+	if err != nil {
+		http.Error(w, "Failed to get chats", http.StatusInternalServerError)
+		return
+	}
+
+	if chatID := r.URL.Query().Get("chat_id"); chatID != "" {
+		var only []models.Chat
+		for _, c := range chats {
+			if c.ChatID == chatID {
+				only = append(only, c)
+			}
+		}
+		if len(only) == 0 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		chats = only
+	}
+
+	alerts, err := h.AlertStore.GetAlerts(r.Context())
+	if err != nil {
+		log.Println("Failed to get alerts:", err)
+		http.Error(w, "Failed to get alerts", http.StatusInternalServerError)
+		return
+	}
+
+	sections := buildStandupSections(chats, alerts, since)
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"since":    since.In(rf.Location),
+			"hours":    hours,
+			"timezone": rf.Location.String(),
+			"chats":    sections,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, formatStandupText(sections, hours, rf))
+}
+
+func buildStandupSections(chats []models.Chat, alerts []models.Alert, since time.Time) []standupSection {
+	index := make(map[string]*standupSection, len(chats))
+	sections := make([]*standupSection, 0, len(chats))
+	for _, c := range chats {
+		s := &standupSection{ChatID: c.ChatID, Name: c.Name}
+		index[c.ChatID] = s
+		sections = append(sections, s)
+	}
+
+	for _, a := range alerts {
+		chatID := models.ChatIDFromSource(a.Source)
+		if chatID == "" {
+			chatID = "general"
+		}
+		s, ok := index[chatID]
+		if !ok {
+			continue
+		}
+
+		isNew := !a.CreatedAt.Before(since)
+		if isNew {
+			s.New++
+			if models.LevelRank(a.Level) >= models.LevelRank("critical") {
+				s.Critical++
+			}
+			if a.Status == models.AlertStatusOpen {
+				s.Open++
+			}
+		}
+		if a.ResolvedAt != nil && !a.ResolvedAt.Before(since) {
+			s.Resolved++
+		}
+		if isNew && (a.Status == models.AlertStatusOpen || models.LevelRank(a.Level) >= models.LevelRank("critical")) {
+			s.Notable = append(s.Notable, a)
+		}
+	}
+
+	out := make([]standupSection, 0, len(sections))
+	for _, s := range sections {
+		sortNotable(s.Notable)
+		if len(s.Notable) > standupNotableLimit {
+			s.Notable = s.Notable[:standupNotableLimit]
+		}
+		out = append(out, *s)
+	}
+	return out
+}
+
+// sortNotable orders alerts still-open first, then most severe, then newest
+func sortNotable(alerts []models.Alert) {
+	sort.SliceStable(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if (a.Status == models.AlertStatusOpen) != (b.Status == models.AlertStatusOpen) {
+			return a.Status == models.AlertStatusOpen
+		}
+		if ra, rb := models.LevelRank(a.Level), models.LevelRank(b.Level); ra != rb {
+			return ra > rb
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+}
+
+func formatStandupText(sections []standupSection, hours int, rf reportFormat) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*Sentinel standup: last %dh*\n", hours)
+	quiet := 0
+	for _, s := range sections {
+		if s.New == 0 && s.Resolved == 0 {
+			quiet++
+			continue
+		}
+		fmt.Fprintf(&b, "\n*%s*: %s new, %s resolved, %s critical", s.Name, rf.Int(s.New), rf.Int(s.Resolved), rf.Int(s.Critical))
+		if s.Open > 0 {
+			fmt.Fprintf(&b, ", %s still open", rf.Int(s.Open))
+		}
+		b.WriteString("\n")
+		for _, a := range s.Notable {
+			state := ""
+			if a.Status == models.AlertStatusOpen {
+				state = " (open)"
+			} else if a.Status == models.AlertStatusResolved {
+				state = " (resolved)"
+			}
+			fmt.Fprintf(&b, "• [%s] %s%s, %s\n", strings.ToUpper(a.Level), a.Title, state, rf.Time(a.CreatedAt))
+		}
+	}
+	if quiet == len(sections) {
+		b.WriteString("\nAll quiet, no alerts.\n")
+	} else if quiet > 0 {
+		fmt.Fprintf(&b, "\n_%d quiet chat(s) not shown_\n", quiet)
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+// checkOrgUsers is checkOrgChats for users
+func (h *Handler) checkOrgUsers(w http.ResponseWriter, r *http.Request, orgID int, userIDs []int) bool {
+	for _, id := range userIDs {
+		user, err := h.AdminStore.GetUser(r.Context(), id)
+		if err != nil || user.OrgID != orgID {
+			http.Error(w, "user "+strconv.Itoa(id)+" is not in the organization", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+// scopedTeam is scopedUser for teams
+func (h *Handler) scopedTeam(w http.ResponseWriter, r *http.Request, id int) (models.Team, bool) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return models.Team{}, false
+	}
+	team, err := h.AdminStore.GetTeam(r.Context(), id)
+	if err != nil || !scope.has(team.OrgID) {
+		http.Error(w, "Team not found", http.StatusNotFound)
+		return models.Team{}, false
+	}
+	return team, true
+}
+
+// teamRequest is the body of team creates and updates
+type teamRequest struct {
+	Name    string `json:"name"`
+	UserIDs []int  `json:"user_ids"`
+	ChatIDs []int  `json:"chat_ids"`
+	OrgID   int    `json:"org_id"` // On create; the admin's own when 0
+}
+
+// decodeTeamRequest reads a teamRequest, answering 400 when it is invalid
+func decodeTeamRequest(w http.ResponseWriter, r *http.Request) (teamRequest, bool) {
+	var req teamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return teamRequest{}, false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return teamRequest{}, false
+	}
+	if req.UserIDs == nil {
+		req.UserIDs = []int{}
+	}
+	if req.ChatIDs == nil {
+		req.ChatIDs = []int{}
+	}
+	return req, true
+}
+
+// GetTeamsHandler lists the teams of the organizations the admin manages
+// GET /api/admin/teams
+func (h *Handler) GetTeamsHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := h.adminScope(w, r)
+	if !ok {
+		return
+	}
+	all, err := h.AdminStore.GetTeams(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get teams", http.StatusInternalServerError)
+		return
+	}
+	teams := all[:0]
+	for _, t := range all {
+		if scope.has(t.OrgID) {
+			teams = append(teams, t)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"teams": teams})
+}
+
+// CreateTeamHandler adds a team, to the admin's organization unless org_id
+// says otherwise. Its members see its chats besides their own.
+// POST /api/admin/teams {"name": "Payments", "user_ids": [3], "chat_ids": [1], "org_id": 2}
+func (h *Handler) CreateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeTeamRequest(w, r)
+	if !ok {
+		return
+	}
+	orgID, ok := h.newMemberOrg(w, r, req.OrgID)
+	if !ok {
+		return
+	}
+	if !h.checkOrgUsers(w, r, orgID, req.UserIDs) || !h.checkOrgChats(w, r, orgID, req.ChatIDs) {
+		return
+	}
+
+	team, err := h.AdminStore.CreateTeam(r.Context(), models.Team{Name: req.Name, OrgID: orgID, UserIDs: req.UserIDs, ChatIDs: req.ChatIDs})
+	if err != nil {
+		log.Printf("Failed to create team: %v", err)
+		http.Error(w, "Failed to create team; is the name taken?", http.StatusConflict)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": team.Name, "user_ids": team.UserIDs, "chat_ids": team.ChatIDs, "org_id": team.OrgID})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_team", "team", team.ID, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "team": team})
+}
+
+// UpdateTeamHandler renames a team and replaces its members and chats
+// PUT /api/admin/teams/{id} {"name": "Payments", "user_ids": [3, 4], "chat_ids": [1]}
+func (h *Handler) UpdateTeamHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/teams/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	req, ok := decodeTeamRequest(w, r)
+	if !ok {
+		return
+	}
+	team, ok := h.scopedTeam(w, r, id)
+	if !ok {
+		return
+	}
+	if !h.checkOrgUsers(w, r, team.OrgID, req.UserIDs) || !h.checkOrgChats(w, r, team.OrgID, req.ChatIDs) {
+		return
+	}
+
+	team.Name, team.UserIDs, team.ChatIDs = req.Name, req.UserIDs, req.ChatIDs
+	if err := h.AdminStore.UpdateTeam(r.Context(), team); err != nil {
+		log.Printf("Failed to update team %d: %v", id, err)
+		http.Error(w, "Failed to update team; is the name taken?", http.StatusConflict)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": team.Name, "user_ids": team.UserIDs, "chat_ids": team.ChatIDs})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "update_team", "team", id, string(meta))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// DeleteTeamHandler removes a team. Its members lose the team's chats but
+// keep those given to them directly.
+// DELETE /api/admin/teams/{id}
+func (h *Handler) DeleteTeamHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/api/admin/teams/"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.scopedTeam(w, r, id); !ok {
+		return
+	}
+	if err := h.AdminStore.DeleteTeam(r.Context(), id); err != nil {
+		http.Error(w, "Failed to delete team", http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_team", "team", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/tickets"
+)
+
+// ticketSecretKeys are connector config keys never echoed back by the API
+var ticketSecretKeys = map[string]bool{"api_token": true, "password": true, "token": true}
+
+func maskTicketConnector(c models.TicketConnector) models.TicketConnector {
+	masked := make(map[string]string, len(c.Config))
+	for k, v := range c.Config {
+		if ticketSecretKeys[k] && v != "" {
+			v = "********"
+		}
+		masked[k] = v
+	}
+	c.Config = masked
+	return c
+}
+
+// === Ticket Connector Management ===
+
+func (h *Handler) GetTicketConnectorsHandler(w http.ResponseWriter, r *http.Request) {
+	connectors, err := h.AdminStore.GetTicketConnectors(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get ticket connectors", http.StatusInternalServerError)
+		return
+	}
+
+	masked := make([]models.TicketConnector, 0, len(connectors))
+	for _, c := range connectors {
+		masked = append(masked, maskTicketConnector(c))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"connectors": masked})
+}
+
+func (h *Handler) CreateTicketConnectorHandler(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string            `json:"name"`
+		Kind     string            `json:"kind"`
+		ChatID   string            `json:"chat_id"`
+		MinLevel string            `json:"min_level"`
+		Config   map[string]string `json:"config"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	if req.MinLevel == "" {
+		req.MinLevel = "error"
+	}
+
+	c := models.TicketConnector{
+		Name:     req.Name,
+		Kind:     strings.ToLower(req.Kind),
+		ChatID:   req.ChatID,
+		MinLevel: req.MinLevel,
+		Config:   req.Config,
+		Enabled:  true,
+	}
+	if _, err := tickets.NewClient(c, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c, err := h.AdminStore.CreateTicketConnector(r.Context(), c)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		meta, _ := json.Marshal(map[string]any{"name": c.Name, "kind": c.Kind, "chat_id": c.ChatID})
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "create_ticket_connector", "ticket_connector", c.ID, string(meta))
+	}
+
+	// The webhook secret is only shown here and in the list, used to configure the tracker
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":     true,
+		"connector":   maskTicketConnector(c),
+		"webhook_url": "/api/tickets/" + strconv.Itoa(c.ID) + "/webhook?token=" + c.WebhookSecret,
+	})
+}
+
+func (h *Handler) DeleteTicketConnectorHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/admin/tickets/connectors/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.AdminStore.DeleteTicketConnector(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
+		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "delete_ticket_connector", "ticket_connector", id, "{}")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true})
+}
+
+// === Inbound Tracker Webhooks ===
+
+// TicketWebhookHandler receives status/comment changes from a tracker.
+// POST /api/tickets/{connectorID}/webhook
+// Authenticated by the connector's webhook secret, passed as ?token=, the
+// X-Sentinel-Token header, or (GitHub) as the X-Hub-Signature-256 HMAC key.
+func (h *Handler) TicketWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Tickets == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/tickets/")
+	idStr, ok := strings.CutSuffix(rest, "/webhook")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	connector, err := h.AdminStore.GetTicketConnector(r.Context(), id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !validTicketWebhook(r, body, connector.WebhookSecret) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ev, err := tickets.ParseWebhook(connector.Kind, r.Header, body)
+	if err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if ev.ExternalID != "" {
+		if err := h.Tickets.ApplyEvent(r.Context(), connector, ev); err != nil {
+			// Unknown tickets are common (issues not opened by Sentinel)
+			log.Printf("Ticket webhook %s: %v", connector.Name, err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func validTicketWebhook(r *http.Request, body []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.Header.Get("X-Sentinel-Token")
+	}
+	if token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+	return validHubSignature(r.Header.Get("X-Hub-Signature-256"), body, secret)
+}
+
+// === Alert Comments ===
+
+// AlertCommentsHandler adds a comment to an alert and forwards it to the
+// alert's open tickets.
+// POST /api/alerts/{id}/comments  {"text": "..."}
+func (h *Handler) AlertCommentsHandler(w http.ResponseWriter, r *http.Request, id int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, username, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Text) == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	comment := models.AlertComment{Author: username, Text: strings.TrimSpace(req.Text)}
+	alert, err := h.AlertStore.AddComment(r.Context(), id, comment)
+	if err != nil {
+		writeAlertError(w, "Failed to add comment", err)
+		return
+	}
+
+	if h.Tickets != nil {
+		go h.Tickets.SyncComment(context.Background(), id, comment)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "comments": alert.Comments})
+}
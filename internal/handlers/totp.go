@@ -23,10 +23,8 @@ func (h *Handler) Generate2FAHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user
-	user, err := h.AdminStore.GetUser(r.Context(), req.UserID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, ok := h.sessionUser(w, r, req.UserID)
+	if !ok {
 		return
 	}
 
@@ -71,6 +69,11 @@ func (h *Handler) Enable2FAHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, ok := h.sessionUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+
 	// Verify the code
 	if !models.VerifyTOTPCode(req.Secret, req.Code) {
 		http.Error(w, "Invalid verification code", http.StatusUnauthorized)
@@ -78,7 +81,7 @@ func (h *Handler) Enable2FAHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Enable 2FA
-	if err := h.AdminStore.UpdateUser2FA(r.Context(), req.UserID, req.Secret, true); err != nil {
+	if err := h.AdminStore.UpdateUser2FA(r.Context(), user.ID, req.Secret, true); err != nil {
 		log.Printf("Failed to enable 2FA: %v", err)
 		http.Error(w, "Failed to enable 2FA", http.StatusInternalServerError)
 		return
@@ -88,7 +91,8 @@ func (h *Handler) Enable2FAHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]any{"success": true, "message": "2FA enabled successfully"})
 }
 
-// Disable2FAHandler disables 2FA for a user (own or admin action)
+// Disable2FAHandler disables the signed-in user's 2FA; admins turn it off
+// for others with AdminDisable2FAHandler
 func (h *Handler) Disable2FAHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -104,35 +108,33 @@ func (h *Handler) Disable2FAHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if user is admin - they cannot disable their own 2FA
-	user, err := h.AdminStore.GetUser(r.Context(), req.UserID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, ok := h.sessionUser(w, r, req.UserID)
+	if !ok {
 		return
 	}
 
+	// Admins cannot disable their own 2FA
 	if user.Role == "admin" {
 		http.Error(w, "Admins cannot disable their own 2FA", http.StatusForbidden)
 		return
 	}
 
 	// Disable 2FA
-	if err := h.AdminStore.Disable2FA(r.Context(), req.UserID); err != nil {
+	if err := h.AdminStore.Disable2FA(r.Context(), user.ID); err != nil {
 		log.Printf("Failed to disable 2FA: %v", err)
 		http.Error(w, "Failed to disable 2FA", http.StatusInternalServerError)
 		return
 	}
 
-	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
-		meta, _ := json.Marshal(map[string]any{"user_id": req.UserID})
-		_ = h.AdminStore.InsertAudit(r.Context(), actorID, "disable_2fa", "user", req.UserID, string(meta))
-	}
+	meta, _ := json.Marshal(map[string]any{"user_id": user.ID})
+	_ = h.AdminStore.InsertAudit(r.Context(), user.ID, "disable_2fa", "user", user.ID, string(meta))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"success": true, "message": "2FA disabled successfully"})
 }
 
-// AdminDisable2FAHandler allows admins to disable 2FA for any user
+// AdminDisable2FAHandler allows admins to disable 2FA for any user of the
+// organizations they manage
 func (h *Handler) AdminDisable2FAHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -149,6 +151,9 @@ func (h *Handler) AdminDisable2FAHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Admin can disable any user's 2FA (for account recovery)
+	if _, ok := h.scopedUser(w, r, req.UserID); !ok {
+		return
+	}
 	if err := h.AdminStore.Disable2FA(r.Context(), req.UserID); err != nil {
 		log.Printf("Failed to disable 2FA: %v", err)
 		http.Error(w, "Failed to disable 2FA", http.StatusInternalServerError)
@@ -176,62 +181,21 @@ func (h *Handler) Verify2FALoginHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Get user
-	user, err := h.AdminStore.GetUser(r.Context(), req.UserID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, ok := h.pendingSecondFactor(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if h.loginLocked(w, r, user.ID) {
 		return
 	}
 
 	// Verify code
 	if !models.VerifyTOTPCode(user.TOTPSecret, req.Code) {
+		h.loginFailed(r, user, "2fa")
 		http.Error(w, "Invalid verification code", http.StatusUnauthorized)
 		return
 	}
 
-	// Get user's allowed chats
-	var allowedChats []any
-	if user.Role == "admin" || user.Role == "developer" {
-		// Admin/developer see all chats
-		chats, _ := h.AdminStore.GetChats(r.Context())
-		for _, chat := range chats {
-			allowedChats = append(allowedChats, map[string]any{
-				"id":      chat.ID,
-				"chat_id": chat.ChatID,
-				"name":    chat.Name,
-				"bot_id":  chat.BotID,
-			})
-		}
-	} else {
-		// Regular user sees only assigned chats
-		chats, _ := h.AdminStore.GetUserChats(r.Context(), user.ID)
-		for _, chat := range chats {
-			allowedChats = append(allowedChats, map[string]any{
-				"id":      chat.ID,
-				"chat_id": chat.ChatID,
-				"name":    chat.Name,
-				"bot_id":  chat.BotID,
-			})
-		}
-	}
-
 	// Create session after successful 2FA
-	session, _ := sessionStore.Get(r, sessionName)
-	session.Values["user_id"] = user.ID
-	session.Values["username"] = user.Username
-	session.Values["role"] = user.Role
-	session.Save(r, w)
-
-	// Return full login success
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"success": true,
-		"user": map[string]any{
-			"id":           user.ID,
-			"username":     user.Username,
-			"role":         user.Role,
-			"totp_enabled": user.TOTPEnabled,
-		},
-		"allowed_chats": allowedChats,
-	})
+	h.completeLogin(w, r, user)
 }
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// Uptime Kuma heartbeat statuses
+const (
+	kumaDown        = 0
+	kumaUp          = 1
+	kumaPending     = 2
+	kumaMaintenance = 3
+)
+
+// UptimeKumaWebhookHandler accepts Uptime Kuma's built-in webhook notification
+// ("application/json" body type). Down opens an alert keyed by monitor ID and
+// Up resolves it. Test notifications carry only msg and become info alerts.
+func (h *Handler) UptimeKumaWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload struct {
+		Heartbeat *struct {
+			Status int    `json:"status"`
+			Msg    string `json:"msg"`
+			Time   string `json:"time"`
+		} `json:"heartbeat"`
+		Monitor *struct {
+			ID       int    `json:"id"`
+			Name     string `json:"name"`
+			URL      string `json:"url"`
+			Hostname string `json:"hostname"`
+		} `json:"monitor"`
+		Msg string `json:"msg"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		a   models.Alert
+		err error
+	)
+	if payload.Heartbeat == nil || payload.Monitor == nil {
+		msg := payload.Msg
+		if msg == "" {
+			msg = "No content"
+		}
+		a, err = h.AlertStore.AddAlert(r.Context(), "uptimekuma", "info", "Uptime Kuma", msg)
+	} else {
+		mon, hb := payload.Monitor, payload.Heartbeat
+		fingerprint := fmt.Sprintf("uptimekuma:%d", mon.ID)
+
+		message := hb.Msg
+		if target := strings.TrimSpace(mon.URL + " " + mon.Hostname); target != "" && target != "https://" {
+			message = strings.TrimSpace(message + "\n" + target)
+		}
+		if message == "" {
+			message = "No content"
+		}
+
+		switch hb.Status {
+		case kumaUp:
+			a, err = h.AlertStore.ResolveAlert(r.Context(), fingerprint)
+			if errors.Is(err, store.ErrAlertNotFound) {
+				a, err = h.AlertStore.AddAlert(r.Context(), "uptimekuma", "success", mon.Name+" is up", message)
+			}
+		case kumaDown:
+			a, err = h.AlertStore.AddFingerprintedAlert(r.Context(), fingerprint, "uptimekuma", "critical", mon.Name+" is down", message)
+		case kumaPending:
+			a, err = h.AlertStore.AddFingerprintedAlert(r.Context(), fingerprint, "uptimekuma", "warning", mon.Name+" is pending", message)
+		case kumaMaintenance:
+			a, err = h.AlertStore.AddAlert(r.Context(), "uptimekuma", "info", mon.Name+" is in maintenance", message)
+		default:
+			a, err = h.AlertStore.AddAlert(r.Context(), "uptimekuma", "info", mon.Name, message)
+		}
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           a.ID,
+		"alert_status": a.Status,
+	})
+}
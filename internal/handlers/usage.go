@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"incident-viewer-go/internal/models"
+)
+
+// usageSlot is where a handler notes the bot or ingest token a request
+// authenticated with, for the middleware that records the outcome
+type usageSlot struct {
+	credential string
+}
+
+type usageSlotKey struct{}
+
+// WithUsageSlot returns r ready to have its credential noted, and a func
+// reporting the credential once the request is done ("" when it had none)
+func WithUsageSlot(r *http.Request) (*http.Request, func() string) {
+	slot := &usageSlot{}
+	return r.WithContext(context.WithValue(r.Context(), usageSlotKey{}, slot)), func() string { return slot.credential }
+}
+
+// noteUsage attributes the request to a credential; outside an endpoint
+// that records usage it does nothing
+func noteUsage(ctx context.Context, kind string, id int) {
+	if slot, ok := ctx.Value(usageSlotKey{}).(*usageSlot); ok {
+		slot.credential = models.APIUsage{Kind: kind, ID: id}.Credential()
+	}
+}
+
+// apiUsage lists the usage of the bots and active ingest tokens created by
+// createdBy, or of all of them when createdBy is 0
+func (h *Handler) apiUsage(ctx context.Context, createdBy, hours int) ([]models.APIUsage, error) {
+	bots, err := h.AdminStore.GetBots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := h.AdminStore.GetIngestTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := []models.APIUsage{}
+	for _, b := range bots {
+		if createdBy == 0 || b.CreatedBy == createdBy {
+			usage = append(usage, models.APIUsage{Kind: models.CredentialBot, ID: b.ID, Name: b.Name})
+		}
+	}
+	for _, t := range tokens {
+		if t.RevokedAt == nil && (createdBy == 0 || t.CreatedBy == createdBy) {
+			usage = append(usage, models.APIUsage{Kind: models.CredentialIngestToken, ID: t.ID, Name: t.Name})
+		}
+	}
+	return h.AlertStore.GetAPIUsage(ctx, usage, hours)
+}
+
+// usageHours reads ?hours= (default and max 24, how long the hourly
+// counters are kept); ok is false after an error response
+func usageHours(w http.ResponseWriter, r *http.Request) (int, bool) {
+	hours := 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 24 {
+			http.Error(w, "hours must be between 1 and 24", http.StatusBadRequest)
+			return 0, false
+		}
+		hours = n
+	}
+	return hours, true
+}
+
+// APIUsageHandler shows every bot's and active ingest token's requests,
+// failure rate and last use, so a sender that stopped working stands out
+// GET /api/admin/usage?hours=24
+func (h *Handler) APIUsageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hours, ok := usageHours(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := h.apiUsage(r.Context(), 0, hours)
+	if err != nil {
+		http.Error(w, "Failed to get API usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"usage": usage, "hours": hours})
+}
+
+// UserAPIUsageHandler is APIUsageHandler for the bots and ingest tokens the
+// signed-in user created, so integration owners can check their own senders
+// GET /api/user/usage?hours=24
+func (h *Handler) UserAPIUsageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	hours, ok := usageHours(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := h.apiUsage(r.Context(), userID, hours)
+	if err != nil {
+		http.Error(w, "Failed to get API usage", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"usage": usage, "hours": hours})
+}
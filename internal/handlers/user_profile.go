@@ -2,33 +2,50 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
 	"incident-viewer-go/internal/models"
 	"log"
 	"net/http"
+	"strconv"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-// GetCurrentUserHandler returns the currently logged-in user's info
-func (h *Handler) GetCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
-	// Get user ID from session (stored in localStorage on client)
-	// For now, we'll use a simple header-based approach
-	userIDStr := r.Header.Get("X-User-ID")
-	if userIDStr == "" {
+// sessionUser is the signed-in user (by session, access token or API key) a
+// request to the /api/user endpoints acts on. A user ID the client sends as
+// well, as older clients do, must be that user's: it is refused rather than
+// acted on. When it returns false it has answered the request.
+func (h *Handler) sessionUser(w http.ResponseWriter, r *http.Request, claimedID int) (models.User, bool) {
+	userID, _, _ := GetCurrentUser(r)
+	if userID == 0 {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
+		return models.User{}, false
 	}
-
-	var userID int
-	if _, err := fmt.Sscanf(userIDStr, "%d", &userID); err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
-		return
+	if claimedID != 0 && claimedID != userID {
+		http.Error(w, "user_id is not the signed-in user", http.StatusForbidden)
+		return models.User{}, false
 	}
-
 	user, err := h.AdminStore.GetUser(r.Context(), userID)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return models.User{}, false
+	}
+	return user, true
+}
+
+// GetCurrentUserHandler returns the signed-in user's info
+// GET /api/user/me
+func (h *Handler) GetCurrentUserHandler(w http.ResponseWriter, r *http.Request) {
+	var claimedID int
+	if v := r.Header.Get("X-User-ID"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+		claimedID = id
+	}
+	user, ok := h.sessionUser(w, r, claimedID)
+	if !ok {
 		return
 	}
 
@@ -60,17 +77,27 @@ func (h *Handler) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, ok := h.sessionUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+
 	// Validate username
 	if req.Username == "" {
 		http.Error(w, "Username cannot be empty", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.AdminStore.UpdateUserProfile(r.Context(), req.UserID, req.Username); err != nil {
+	if err := h.AdminStore.UpdateUserProfile(r.Context(), user.ID, req.Username); err != nil {
 		log.Printf("Failed to update profile: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if user, err := h.AdminStore.GetUser(r.Context(), user.ID); err == nil {
+		if err := sessionStore.UpdateUserSessions(r.Context(), user.ID, user.Username, user.Role); err != nil {
+			log.Printf("Failed to update sessions: %v", err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"success": true})
@@ -94,16 +121,8 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Validate new password strength
-	if len(req.NewPassword) < 8 {
-		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
-		return
-	}
-
-	// Get current user
-	user, err := h.AdminStore.GetUser(r.Context(), req.UserID)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, ok := h.sessionUser(w, r, req.UserID)
+	if !ok {
 		return
 	}
 
@@ -113,6 +132,12 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Validate new password against the policy
+	if err := h.checkNewPassword(r.Context(), user, req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Hash new password
 	newHash, err := models.HashPassword(req.NewPassword)
 	if err != nil {
@@ -121,12 +146,18 @@ func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Update password
-	if err := h.AdminStore.UpdateUserPassword(r.Context(), req.UserID, newHash); err != nil {
+	if err := h.AdminStore.UpdateUserPassword(r.Context(), user.ID, newHash); err != nil {
 		log.Printf("Failed to update password: %v", err)
 		http.Error(w, "Failed to update password", http.StatusInternalServerError)
 		return
 	}
 
+	// Sign out everywhere else
+	session, _ := sessionStore.Get(r, sessionName)
+	if _, err := sessionStore.RevokeUserSessions(r.Context(), user.ID, session.ID); err != nil {
+		log.Printf("Failed to end sessions: %v", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{"success": true})
 }
@@ -148,9 +179,13 @@ func (h *Handler) AdminResetPasswordHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// Validate new password
-	if len(req.NewPassword) < 8 {
-		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+	// Validate new password against the policy
+	user, ok := h.scopedUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if err := h.checkNewPassword(r.Context(), user, req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
@@ -167,6 +202,9 @@ func (h *Handler) AdminResetPasswordHandler(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
 		return
 	}
+	if _, err := sessionStore.RevokeUserSessions(r.Context(), req.UserID, ""); err != nil {
+		log.Printf("Failed to end sessions: %v", err)
+	}
 
 	if actorID, _, _ := GetCurrentUser(r); actorID != 0 {
 		meta, _ := json.Marshal(map[string]any{"user_id": req.UserID})
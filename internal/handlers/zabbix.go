@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// ZabbixWebhookHandler accepts Zabbix webhook media type deliveries. The media
+// type script should POST its parameters as JSON, e.g.:
+//
+//	{
+//	  "event_id": "{EVENT.ID}",
+//	  "event_value": "{EVENT.VALUE}",
+//	  "event_nseverity": "{EVENT.NSEVERITY}",
+//	  "event_severity": "{EVENT.SEVERITY}",
+//	  "event_name": "{EVENT.NAME}",
+//	  "host_name": "{HOST.NAME}",
+//	  "alert_message": "{ALERT.MESSAGE}",
+//	  "event_update_status": "{EVENT.UPDATE.STATUS}"
+//	}
+//
+// {EVENT.ID} is the problem event's ID in recovery messages too, so it pairs
+// a problem (event_value 1) with its resolution (event_value 0).
+func (h *Handler) ZabbixWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var payload map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	title := getString(payload["event_name"])
+	if title == "" {
+		title = getString(payload["alert_subject"])
+	}
+	if title == "" {
+		title = "Zabbix Problem"
+	}
+	if host := getString(payload["host_name"]); host != "" && !strings.Contains(title, host) {
+		title = host + ": " + title
+	}
+
+	message := getString(payload["alert_message"])
+	if message == "" {
+		message = "No content"
+	}
+
+	fingerprint := ""
+	if eventID := getString(payload["event_id"]); eventID != "" {
+		fingerprint = "zabbix:" + eventID
+	}
+
+	var (
+		a   models.Alert
+		err error
+	)
+	if getString(payload["event_value"]) == "0" {
+		a, err = h.AlertStore.ResolveAlert(r.Context(), fingerprint)
+		if errors.Is(err, store.ErrAlertNotFound) {
+			a, err = h.AlertStore.AddAlert(r.Context(), "zabbix", "success", title, message)
+		}
+	} else {
+		level := zabbixLevel(getString(payload["event_nseverity"]), getString(payload["event_severity"]))
+		a, err = h.AlertStore.AddFingerprintedAlert(r.Context(), fingerprint, "zabbix", level, title, message)
+	}
+	if err != nil {
+		log.Println("Failed to add alert:", err)
+		http.Error(w, "Failed to add alert", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":       "ok",
+		"id":           a.ID,
+		"alert_status": a.Status,
+	})
+}
+
+// zabbixLevel maps Zabbix severities ({EVENT.NSEVERITY} 0-5, falling back to
+// the {EVENT.SEVERITY} name) to levels
+func zabbixLevel(nseverity, severity string) string {
+	switch nseverity {
+	case "5":
+		return "critical"
+	case "4", "3":
+		return "error"
+	case "2":
+		return "warning"
+	case "1", "0":
+		return "info"
+	}
+
+	switch strings.ToLower(severity) {
+	case "disaster":
+		return "critical"
+	case "high", "average":
+		return "error"
+	case "warning":
+		return "warning"
+	default: // Information, Not classified
+		return "info"
+	}
+}
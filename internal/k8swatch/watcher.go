@@ -0,0 +1,298 @@
+// Package k8swatch streams Kubernetes Warning events and crashing pods into
+// the alert store.
+package k8swatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	source       = "kubernetes"
+	resyncPeriod = 10 * time.Minute
+	// Events older than this at startup are history, not news
+	startupGrace = 5 * time.Minute
+)
+
+// Pod container states that raise alerts
+const (
+	reasonCrashLoop = "CrashLoopBackOff"
+	reasonOOMKilled = "OOMKilled"
+)
+
+// Config selects what the watcher reports
+type Config struct {
+	Kubeconfig string   // Empty: in-cluster config, then $KUBECONFIG / ~/.kube/config
+	Namespaces []string // Empty: all namespaces
+	Reasons    []string // Event/pod reasons to report; empty: all Warning events plus CrashLoopBackOff and OOMKilled
+}
+
+// ConfigFromEnv reads K8S_KUBECONFIG, K8S_WATCH_NAMESPACES and K8S_WATCH_REASONS
+// (comma separated lists)
+func ConfigFromEnv() Config {
+	return Config{
+		Kubeconfig: os.Getenv("K8S_KUBECONFIG"),
+		Namespaces: splitList(os.Getenv("K8S_WATCH_NAMESPACES")),
+		Reasons:    splitList(os.Getenv("K8S_WATCH_REASONS")),
+	}
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type Watcher struct {
+	alerts  store.AlertStore
+	client  kubernetes.Interface
+	cfg     Config
+	reasons map[string]bool
+	started time.Time
+}
+
+func NewWatcher(alerts store.AlertStore, cfg Config) (*Watcher, error) {
+	restCfg, err := restConfig(cfg.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := kubernetes.NewForConfig(restCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	reasons := make(map[string]bool, len(cfg.Reasons))
+	for _, r := range cfg.Reasons {
+		reasons[strings.ToLower(r)] = true
+	}
+	return &Watcher{alerts: alerts, client: client, cfg: cfg, reasons: reasons}, nil
+}
+
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		if cfg, err := rest.InClusterConfig(); err == nil {
+			return cfg, nil
+		}
+		kubeconfig = os.Getenv("KUBECONFIG")
+		if kubeconfig == "" {
+			home, _ := os.UserHomeDir()
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// Run watches until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) {
+	w.started = time.Now()
+
+	namespaces := w.cfg.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{corev1.NamespaceAll}
+	}
+
+	for _, ns := range namespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(w.client, resyncPeriod, informers.WithNamespace(ns))
+
+		// Repeats of an event only bump its count (an update); the first
+		// occurrence is enough to open the alert
+		factory.Core().V1().Events().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj any) { w.onEvent(ctx, obj) },
+		})
+		factory.Core().V1().Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj any) { w.onPod(ctx, nil, obj) },
+			UpdateFunc: func(old, obj any) { w.onPod(ctx, old, obj) },
+			DeleteFunc: func(obj any) { w.onPodDeleted(ctx, obj) },
+		})
+
+		factory.Start(ctx.Done())
+		name := ns
+		if name == "" {
+			name = "all namespaces"
+		}
+		log.Printf("Kubernetes watcher started (%s)", name)
+	}
+
+	<-ctx.Done()
+}
+
+func (w *Watcher) wantReason(reason string) bool {
+	return len(w.reasons) == 0 || w.reasons[strings.ToLower(reason)]
+}
+
+func (w *Watcher) onEvent(ctx context.Context, obj any) {
+	ev, ok := obj.(*corev1.Event)
+	if !ok || ev.Type != corev1.EventTypeWarning || !w.wantReason(ev.Reason) {
+		return
+	}
+	last := ev.LastTimestamp.Time
+	if last.IsZero() {
+		last = ev.EventTime.Time
+	}
+	if last.Before(w.started.Add(-startupGrace)) {
+		return
+	}
+
+	involved := ev.InvolvedObject
+	title := fmt.Sprintf("%s %s/%s: %s", involved.Kind, ev.Namespace, involved.Name, ev.Reason)
+	message := ev.Message
+	if ev.Count > 1 {
+		message = fmt.Sprintf("%s (x%d)", message, ev.Count)
+	}
+
+	a := models.Alert{
+		Source:      source,
+		Level:       eventLevel(ev.Reason),
+		Title:       title,
+		Message:     message,
+		Fingerprint: fmt.Sprintf("k8s:event:%s/%s/%s/%s", ev.Namespace, involved.Kind, involved.Name, ev.Reason),
+		Labels: map[string]string{
+			"namespace": ev.Namespace,
+			"kind":      involved.Kind,
+			"name":      involved.Name,
+			"reason":    ev.Reason,
+		},
+	}
+	if _, err := w.alerts.UpsertAlert(ctx, a); err != nil {
+		log.Printf("k8swatch: failed to store event alert: %v", err)
+	}
+}
+
+// eventLevel rates Warning event reasons; most are warnings, a few mean an
+// outage in progress
+func eventLevel(reason string) string {
+	switch reason {
+	case "BackOff", "Failed", "FailedMount", "FailedAttachVolume", "Evicted", "NodeNotReady", "OOMKilling":
+		return "error"
+	default:
+		return "warning"
+	}
+}
+
+func podFingerprint(pod *corev1.Pod, container, reason string) string {
+	return fmt.Sprintf("k8s:pod:%s/%s/%s:%s", pod.Namespace, pod.Name, container, reason)
+}
+
+func (w *Watcher) onPod(ctx context.Context, oldObj, obj any) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	var old *corev1.Pod
+	if oldObj != nil {
+		old, _ = oldObj.(*corev1.Pod)
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if waiting := cs.State.Waiting; waiting != nil && waiting.Reason == reasonCrashLoop && w.wantReason(reasonCrashLoop) &&
+			!wasCrashLooping(old, cs.Name) {
+			w.upsert(ctx, models.Alert{
+				Level:       "critical",
+				Title:       fmt.Sprintf("Pod %s/%s: %s in CrashLoopBackOff", pod.Namespace, pod.Name, cs.Name),
+				Message:     fmt.Sprintf("%s\nRestarts: %d", waiting.Message, cs.RestartCount),
+				Fingerprint: podFingerprint(pod, cs.Name, reasonCrashLoop),
+				Labels:      podLabels(pod, cs.Name, reasonCrashLoop),
+			})
+		} else if cs.Ready && cs.State.Running != nil {
+			w.resolve(ctx, podFingerprint(pod, cs.Name, reasonCrashLoop))
+		}
+
+		// OOM kills show up as the last termination once the container restarts
+		if term := cs.LastTerminationState.Terminated; term != nil && term.Reason == reasonOOMKilled &&
+			w.wantReason(reasonOOMKilled) && restartedSince(old, cs) {
+			w.upsert(ctx, models.Alert{
+				Level:       "error",
+				Title:       fmt.Sprintf("Pod %s/%s: %s OOMKilled", pod.Namespace, pod.Name, cs.Name),
+				Message:     fmt.Sprintf("Exit code %d at %s\nRestarts: %d", term.ExitCode, term.FinishedAt.UTC().Format(time.RFC3339), cs.RestartCount),
+				Fingerprint: podFingerprint(pod, cs.Name, reasonOOMKilled),
+				Labels:      podLabels(pod, cs.Name, reasonOOMKilled),
+			})
+		}
+	}
+}
+
+// wasCrashLooping reports whether the container was in CrashLoopBackOff in the
+// previous pod state, so alerts fire on transitions rather than every resync
+func wasCrashLooping(old *corev1.Pod, container string) bool {
+	if old == nil {
+		return false
+	}
+	for _, prev := range old.Status.ContainerStatuses {
+		if prev.Name == container {
+			return prev.State.Waiting != nil && prev.State.Waiting.Reason == reasonCrashLoop
+		}
+	}
+	return false
+}
+
+// restartedSince reports whether the container restarted since the previous
+// pod state (or, on the initial list, whether the kill is recent)
+func restartedSince(old *corev1.Pod, cs corev1.ContainerStatus) bool {
+	if old == nil {
+		term := cs.LastTerminationState.Terminated
+		return time.Since(term.FinishedAt.Time) < startupGrace
+	}
+	for _, prev := range old.Status.ContainerStatuses {
+		if prev.Name == cs.Name {
+			return cs.RestartCount > prev.RestartCount
+		}
+	}
+	return true
+}
+
+func podLabels(pod *corev1.Pod, container, reason string) map[string]string {
+	return map[string]string{
+		"namespace": pod.Namespace,
+		"pod":       pod.Name,
+		"container": container,
+		"node":      pod.Spec.NodeName,
+		"reason":    reason,
+	}
+}
+
+func (w *Watcher) onPodDeleted(ctx context.Context, obj any) {
+	if tomb, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tomb.Obj
+	}
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	for _, cs := range pod.Status.ContainerStatuses {
+		w.resolve(ctx, podFingerprint(pod, cs.Name, reasonCrashLoop))
+		w.resolve(ctx, podFingerprint(pod, cs.Name, reasonOOMKilled))
+	}
+}
+
+func (w *Watcher) upsert(ctx context.Context, a models.Alert) {
+	a.Source = source
+	if _, err := w.alerts.UpsertAlert(ctx, a); err != nil {
+		log.Printf("k8swatch: failed to store pod alert: %v", err)
+	}
+}
+
+func (w *Watcher) resolve(ctx context.Context, fingerprint string) {
+	if _, err := w.alerts.ResolveAlert(ctx, fingerprint); err != nil && !errors.Is(err, store.ErrAlertNotFound) {
+		log.Printf("k8swatch: failed to resolve %s: %v", fingerprint, err)
+	}
+}
@@ -1,12 +1,121 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Alert lifecycle states
+const (
+	AlertStatusOpen     = "open"
+	AlertStatusResolved = "resolved"
+)
+
+// Alert reactions give lightweight triage state without a status change
+const (
+	ReactionLooking = "looking" // 👀
+	ReactionHandled = "handled" // ✅
+)
+
+// ReactionEmoji maps reaction names to the emoji shown in the UI
+var ReactionEmoji = map[string]string{
+	ReactionLooking: "👀",
+	ReactionHandled: "✅",
+}
 
 type Alert struct {
-	ID        int       `json:"id"`
+	ID          int        `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Source      string     `json:"source"`            // Normalized, see NormalizeSource
+	Bot         string     `json:"bot,omitempty"`     // From a bot:{name}:chat:{chatID} source
+	ChatID      string     `json:"chat_id,omitempty"` // From a ...:chat:{chatID} source
+	Level       string     `json:"level"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	Status      string     `json:"status,omitempty"`
+	Fingerprint string     `json:"fingerprint,omitempty"` // Upstream key pairing trigger/recovery events
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	AckedAt     *time.Time `json:"acked_at,omitempty"` // First reaction
+
+	Labels map[string]string `json:"labels,omitempty"` // Integration metadata (repo, branch, ...)
+
+	Reactions map[string][]string `json:"reactions,omitempty"` // reaction -> usernames
+	Comments  []AlertComment      `json:"comments,omitempty"`
+
+	Annotations []AlertAnnotation `json:"annotations,omitempty"` // Context attached by automation
+
+	Roles map[string]string `json:"roles,omitempty"` // Incident role -> username
+
+	Payload json.RawMessage `json:"payload,omitempty"` // JSON the alert was ingested from, for notification templates
+
+	RequestID string `json:"request_id,omitempty"` // X-Request-ID of the last request that changed it
+
+	SuppressedNotifications int `json:"suppressed_notifications,omitempty"` // Sends held back by flood control
+
+	SilenceID int  `json:"silence_id,omitempty"` // Silence holding back its notifications
+	Silenced  bool `json:"silenced,omitempty"`   // Shown as silenced, for silences that mark alerts
+}
+
+// Incident roles that can be assigned on an alert
+const (
+	RoleCommander = "commander" // Runs the response and makes the calls
+	RoleScribe    = "scribe"    // Keeps the timeline and notes
+	RoleComms     = "comms"     // Updates stakeholders
+)
+
+// IncidentRoles lists the assignable incident roles, in display order
+var IncidentRoles = []string{RoleCommander, RoleScribe, RoleComms}
+
+// AlertComment is a discussion entry on an alert, written in Sentinel or
+// synced from an external ticket
+type AlertComment struct {
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Origin    string    `json:"origin,omitempty"` // "" for Sentinel, else the ticket system (jira, github, ...)
 	CreatedAt time.Time `json:"created_at"`
-	Source    string    `json:"source"`
-	Level     string    `json:"level"`
-	Title     string    `json:"title"`
-	Message   string    `json:"message"`
+}
+
+// AlertAnnotation is structured context attached to an alert by automation
+// (deploy pipelines, diagnostics bots): key facts, links and a text block
+type AlertAnnotation struct {
+	Title     string            `json:"title"`
+	Fields    map[string]string `json:"fields,omitempty"` // e.g. "deploy_sha": "1a2b3c"
+	Links     []AnnotationLink  `json:"links,omitempty"`
+	Text      string            `json:"text,omitempty"` // Shown preformatted, e.g. query results
+	Author    string            `json:"author"`         // Ingest token or user that added it
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type AnnotationLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// LevelRank orders levels by severity so thresholds like "error and above"
+// can be compared. Aliases rank as their canonical level; unknown levels rank as info.
+func LevelRank(level string) int {
+	switch NormalizeLevel(level) {
+	case LevelSuccess:
+		return 0
+	case LevelWarning:
+		return 2
+	case LevelError:
+		return 3
+	case LevelCritical:
+		return 4
+	default:
+		return 1
+	}
+}
+
+// ChatIDFromSource extracts the chat ID from bot sources of the form
+// bot:{botname}:chat:{chatID}. Returns "" for alerts not bound to a chat.
+func ChatIDFromSource(source string) string {
+	_, after, ok := strings.Cut(source, ":chat:")
+	if !ok {
+		return ""
+	}
+	chatID, _, _ := strings.Cut(after, ":")
+	return chatID
 }
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// API key scopes, from narrowest to widest
+const (
+	APIKeyScopeIngest = "ingest" // Send alerts, deploys and metrics
+	APIKeyScopeRead   = "read"   // GET requests as the key's owner
+	APIKeyScopeAdmin  = "admin"  // Anything the owner can do, ingestion included
+)
+
+// APIKeyScopes lists the valid scopes
+var APIKeyScopes = []string{APIKeyScopeIngest, APIKeyScopeRead, APIKeyScopeAdmin}
+
+// APIKeyPrefix starts every API key, telling them apart from ingest tokens
+const APIKeyPrefix = "snt_"
+
+// APIKey is a user's bearer credential for scripts and CI, acting as the user
+// within its scope. Only its hash is stored.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"user_id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	Prefix     string     `json:"prefix"` // First characters of the key, to tell them apart
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+
+	// The owner as of the last authentication, so a key follows role changes
+	Username string `json:"-"`
+	Role     string `json:"-"`
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than an
+// ingest token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// HashAPIKey is the stored form of an API key
+func HashAPIKey(key string) string {
+	return HashIngestToken(key)
+}
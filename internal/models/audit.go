@@ -3,11 +3,30 @@ package models
 import "time"
 
 type AuditLog struct {
-	ID         int       `json:"id"`
-	ActorID    int       `json:"actor_id"`
-	Action     string    `json:"action"`
-	TargetType string    `json:"target_type"`
-	TargetID   int       `json:"target_id,omitempty"`
-	Metadata   string    `json:"metadata,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID            int       `json:"id"`
+	ActorID       int       `json:"actor_id"`
+	ActorUsername string    `json:"actor_username,omitempty"` // Kept after the user is deleted
+	Action        string    `json:"action"`
+	TargetType    string    `json:"target_type"`
+	TargetID      int       `json:"target_id,omitempty"`
+	Metadata      string    `json:"metadata,omitempty"`
+	RequestID     string    `json:"request_id,omitempty"` // X-Request-ID of the request that made the change
+	ClientIP      string    `json:"client_ip,omitempty"`  // Behind trusted proxies, the address they forwarded for
+	UserAgent     string    `json:"user_agent,omitempty"`
+	OrgID         int       `json:"org_id"` // The actor's
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AuditFilter narrows the audit log; zero fields don't filter. Entries come
+// newest first, and Before pages back: it is the ID of the oldest entry seen.
+type AuditFilter struct {
+	ActorID    int
+	Actor      string // Username, case-insensitive
+	Action     string
+	TargetType string
+	OrgID      int
+	Since      time.Time
+	Until      time.Time
+	Before     int
+	Limit      int
 }
@@ -2,18 +2,31 @@ package models
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"time"
 )
 
 type Bot struct {
-	ID         int       `json:"id"`
-	Token      string    `json:"token"`
-	Name       string    `json:"name"`
-	CreatedAt  time.Time `json:"created_at"`
-	CreatedBy  int       `json:"created_by"`
-	HMACSecret string    `json:"hmac_secret"`
-	RateLimit  int       `json:"rate_limit"`
+	ID    int    `json:"id"`
+	Token string `json:"token"`
+	// TokenHash is what the token is looked up by; the token itself may be
+	// stored encrypted
+	TokenHash string    `json:"-"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy int       `json:"created_by"`
+	RateLimit int       `json:"rate_limit"`
+	// SigningSecret, when set, is what the bot's requests must be signed
+	// with; it is only shown when it is set
+	SigningSecret string `json:"-"`
+	Signed        bool   `json:"signed"`
+	// PreviousTokenExpiresAt is set after a rotation while the token before
+	// it still works
+	PreviousTokenExpiresAt *time.Time `json:"previous_token_expires_at,omitempty"`
+	// AllowedIPs are the CIDRs the bot may post from; empty for anywhere
+	AllowedIPs []string `json:"allowed_ips"`
+	OrgID      int      `json:"org_id"`
 }
 
 type Chat struct {
@@ -21,9 +34,16 @@ type Chat struct {
 	ChatID    string    `json:"chat_id"`
 	Name      string    `json:"name"`
 	BotID     int       `json:"bot_id"`
+	OrgID     int       `json:"org_id"` // The bot's
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// HashBotToken is the form of a bot token that bots are looked up by
+func HashBotToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // GenerateToken creates a random bot token
 func GenerateToken() (string, error) {
 	b := make([]byte, 32)
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// When a routing rule sends, relative to its business-hours calendar
+const (
+	HoursAlways   = ""            // Whatever the time
+	HoursBusiness = "business"    // Only while the calendar is open
+	HoursAfter    = "after_hours" // Only while it is closed: nights, days off and holidays
+)
+
+// BusinessCalendar is a team's working week: open from Start to End on Days in
+// the calendar's time zone, except on Holidays
+type BusinessCalendar struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	TeamID    int       `json:"team_id,omitempty"` // Team whose hours these are; 0 = not a team's
+	Timezone  string    `json:"timezone"`          // IANA name, e.g. Europe/Berlin
+	Days      []int     `json:"days"`              // Weekdays, 0 = Sunday ... 6 = Saturday
+	Start     string    `json:"start"`             // HH:MM, local
+	End       string    `json:"end"`               // HH:MM, local; before Start for hours past midnight
+	Holidays  []string  `json:"holidays"`          // YYYY-MM-DD, local
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OpenAt reports whether t is within business hours. Hours past midnight
+// belong to the day they started on; Start equal to End is open all day.
+func (c BusinessCalendar) OpenAt(t time.Time) bool {
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err1 := time.Parse("15:04", c.Start)
+	end, err2 := time.Parse("15:04", c.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	day := local
+	switch {
+	case from < to:
+		if minute < from || minute >= to {
+			return false
+		}
+	case minute >= from:
+	case minute < to:
+		day = local.AddDate(0, 0, -1) // Still the previous day's hours
+	default:
+		return false
+	}
+	return slices.Contains(c.Days, int(day.Weekday())) && !slices.Contains(c.Holidays, day.Format("2006-01-02"))
+}
+
+// HoursRule branches a routing rule on a business-hours calendar
+type HoursRule struct {
+	CalendarID int    `json:"calendar_id,omitempty"`
+	Hours      string `json:"hours,omitempty"` // HoursBusiness or HoursAfter; "" = always
+}
+
+// AllowsAt reports whether the rule sends at t, looking its calendar up in
+// calendars. Resolutions always go through, to close what was opened, and a
+// rule whose calendar is gone sends at any time rather than not at all.
+func (r HoursRule) AllowsAt(a Alert, calendars map[int]BusinessCalendar, t time.Time) bool {
+	if r.Hours == HoursAlways || a.Status == AlertStatusResolved {
+		return true
+	}
+	cal, ok := calendars[r.CalendarID]
+	if !ok {
+		return true
+	}
+	return cal.OpenAt(t) == (r.Hours == HoursBusiness)
+}
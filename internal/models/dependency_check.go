@@ -0,0 +1,48 @@
+package models
+
+import (
+	"path"
+	"time"
+)
+
+// DependencyCheck is an external dependency of a service (an upstream API,
+// a status endpoint) that is probed when one of the service's alerts opens
+type DependencyCheck struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	ChatID    string    `json:"chat_id,omitempty"`    // Public chat_id to scope to; empty = all alerts
+	Source    string    `json:"source,omitempty"`     // Glob on the alert source; empty = any
+	TimeoutMs int       `json:"timeout_ms,omitempty"` // Default 5000
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches reports whether d is probed when a opens
+func (d DependencyCheck) Matches(a Alert) bool {
+	if !d.Enabled {
+		return false
+	}
+	if d.ChatID != "" && ChatIDFromSource(a.Source) != d.ChatID {
+		return false
+	}
+	if d.Source != "" {
+		if ok, _ := path.Match(d.Source, a.Source); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// DependencyResult is the outcome of one probe in a dependency snapshot
+type DependencyResult struct {
+	Name       string `json:"name"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Healthy reports whether the dependency answered with a 2xx or 3xx
+func (r DependencyResult) Healthy() bool {
+	return r.Error == "" && r.StatusCode >= 200 && r.StatusCode < 400
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// DeployEvent records a deploy of a service, reported by a CI/CD integration
+// (GitHub, GitLab) or posted to /api/deploys (Jenkins, scripts). Deploys are
+// not alerts; they are kept to flag alerts that start soon after one.
+type DeployEvent struct {
+	Service     string    `json:"service"` // e.g. "payments" or "acme/payments"
+	Environment string    `json:"environment,omitempty"`
+	Version     string    `json:"version,omitempty"` // SHA, tag or build number
+	URL         string    `json:"url,omitempty"`
+	Actor       string    `json:"actor,omitempty"`
+	Provider    string    `json:"provider"` // github, gitlab, or the API caller
+	DeployedAt  time.Time `json:"deployed_at"`
+}
+
+// MatchesService reports whether the deploy is of the named service. A
+// repository path like "acme/payments" also matches plain "payments".
+func (d DeployEvent) MatchesService(name string) bool {
+	if name == "" {
+		return false
+	}
+	if strings.EqualFold(d.Service, name) {
+		return true
+	}
+	_, short, ok := strings.Cut(d.Service, "/")
+	for ok {
+		if strings.EqualFold(short, name) {
+			return true
+		}
+		_, short, ok = strings.Cut(short, "/")
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"path"
+	"time"
+)
+
+// EmailRoute sends alerts from a chat and/or matching source to a set of
+// email recipients and/or whoever is on call in a schedule. With
+// EscalateAfter set, an alert nobody acknowledges in time is mailed to the
+// next responder of the schedule, and so on down the rotation.
+type EmailRoute struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	ChatID        string    `json:"chat_id,omitempty"` // Public chat_id to scope to; empty = all alerts
+	Source        string    `json:"source,omitempty"`  // Glob on the alert source; empty = any
+	MinLevel      string    `json:"min_level"`
+	Recipients    []string  `json:"recipients"`
+	ScheduleID    int       `json:"schedule_id,omitempty"`    // Also mail the schedule's current on-call
+	EscalateAfter int       `json:"escalate_after,omitempty"` // Minutes unacknowledged per responder; 0 = no escalation
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	HoursRule // Only during or only outside business hours
+}
+
+// Matches reports whether the route sends a
+func (r EmailRoute) Matches(a Alert) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.ChatID != "" && ChatIDFromSource(a.Source) != r.ChatID {
+		return false
+	}
+	if r.Source != "" {
+		if ok, _ := path.Match(r.Source, a.Source); !ok {
+			return false
+		}
+	}
+	return LevelRank(a.Level) >= LevelRank(r.MinLevel)
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"regexp"
+	"slices"
+	"time"
+)
+
+// Feature flags gating subsystems that roll out incrementally
+const (
+	FeatureOnCall = "oncall" // On-call schedules, /api/oncall/* and handover reports
+)
+
+// FeatureFlagDefaults is whether each known flag is on for everyone while
+// no admin has configured it
+var FeatureFlagDefaults = map[string]bool{
+	FeatureOnCall: true,
+}
+
+var featureFlagKey = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+
+// ValidFeatureFlagKey reports whether key is a lowercase flag name like
+// "oncall" or "status_pages"
+func ValidFeatureFlagKey(key string) bool {
+	return featureFlagKey.MatchString(key)
+}
+
+// FeatureFlag turns a feature on for everyone, or only for the listed users,
+// roles and organizations
+type FeatureFlag struct {
+	Key         string    `json:"key"`
+	Description string    `json:"description,omitempty"`
+	Enabled     bool      `json:"enabled"` // On for everyone
+	UserIDs     []int     `json:"user_ids"`
+	Roles       []string  `json:"roles"` // admin, developer, user
+	OrgIDs      []int     `json:"org_ids"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// EnabledFor reports whether the flag is on for a user with the given role
+// in the organization orgID (0 when unknown)
+func (f FeatureFlag) EnabledFor(userID int, role string, orgID int) bool {
+	return f.Enabled || (userID != 0 && slices.Contains(f.UserIDs, userID)) || (role != "" && slices.Contains(f.Roles, role)) ||
+		(orgID != 0 && slices.Contains(f.OrgIDs, orgID))
+}
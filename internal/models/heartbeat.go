@@ -0,0 +1,36 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Heartbeat is a dead-man's-switch monitor: a job checks in with
+// POST /api/heartbeat/{token} at least every Interval, and an alert is raised
+// when a check-in is more than Grace late
+type Heartbeat struct {
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Token           string     `json:"token"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	GraceSeconds    int        `json:"grace_seconds"`
+	Level           string     `json:"level"`
+	LastPingAt      *time.Time `json:"last_ping_at,omitempty"`
+	CreatedBy       int        `json:"created_by,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// Deadline is when the next check-in is due, grace included. A monitor that
+// never checked in is due one interval after it was created.
+func (hb Heartbeat) Deadline() time.Time {
+	last := hb.CreatedAt
+	if hb.LastPingAt != nil {
+		last = *hb.LastPingAt
+	}
+	return last.Add(time.Duration(hb.IntervalSeconds+hb.GraceSeconds) * time.Second)
+}
+
+// Fingerprint pairs the missed check-in alert with its recovery
+func (hb Heartbeat) Fingerprint() string {
+	return fmt.Sprintf("heartbeat:%d", hb.ID)
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Invitation lets someone create their own account, with the role and chats
+// an admin picked, by following a link sent to Email
+type Invitation struct {
+	ID         int        `json:"id"`
+	Email      string     `json:"email"`
+	Role       string     `json:"role"`
+	ChatIDs    []int      `json:"chat_ids"`
+	InvitedBy  *int       `json:"invited_by,omitempty"` // nil once the admin is deleted
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	UserID     *int       `json:"user_id,omitempty"` // The account it created
+	OrgID      int        `json:"org_id"`
+}
+
+// Pending reports whether the invitation can still be accepted
+func (i Invitation) Pending(now time.Time) bool {
+	return i.AcceptedAt == nil && now.Before(i.ExpiresAt)
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Canonical alert levels, most to least severe
+const (
+	LevelCritical = "critical"
+	LevelError    = "error"
+	LevelWarning  = "warning"
+	LevelInfo     = "info"
+	LevelSuccess  = "success"
+)
+
+// builtinLevelAliases maps upstream severity words, including non-English
+// ones, to canonical levels. Keys are lowercase.
+var builtinLevelAliases = map[string][]string{
+	LevelCritical: {
+		"crit", "fatal", "emergency", "emerg", "disaster", "p1", "sev1",
+		"crítico", "critico", "critique", "kritisch", "krytyczny", "критический",
+		"致命的", "重大", "緊急", "危険", "严重", "紧急", "심각", "치명적",
+	},
+	LevelError: {
+		"err", "high", "failure", "failed", "fail", "p2", "sev2",
+		"erreur", "fehler", "erro", "errore", "błąd", "ошибка",
+		"エラー", "異常", "错误", "오류", "에러",
+	},
+	LevelWarning: {
+		"warn", "medium", "average", "p3", "sev3",
+		"advertencia", "aviso", "avertissement", "warnung", "alerta", "avviso", "attenzione", "ostrzeżenie", "предупреждение",
+		"警告", "注意", "경고",
+	},
+	LevelInfo: {
+		"information", "informational", "notice", "low", "debug", "p4", "p5",
+		"información", "informacion", "informação", "informacao", "informazione", "hinweis", "informacja", "информация",
+		"情報", "信息", "提示", "정보",
+	},
+	LevelSuccess: {
+		"ok", "resolved", "recovered", "up",
+		"resuelto", "résolu", "resolu", "behoben", "resolvido", "risolto", "rozwiązany",
+		"正常", "復旧", "恢复", "정상", "복구",
+	},
+}
+
+var (
+	levelAliasesMu sync.RWMutex
+	levelAliases   = buildLevelAliases()
+)
+
+func buildLevelAliases() map[string]string {
+	m := make(map[string]string)
+	for level, aliases := range builtinLevelAliases {
+		m[level] = level
+		for _, a := range aliases {
+			m[a] = level
+		}
+	}
+	return m
+}
+
+// AddLevelAliases registers extra alias -> level mappings on top of the
+// built-in dictionary. Targets must be canonical levels.
+func AddLevelAliases(aliases map[string]string) error {
+	levelAliasesMu.Lock()
+	defer levelAliasesMu.Unlock()
+	for alias, level := range aliases {
+		level = strings.ToLower(strings.TrimSpace(level))
+		if _, ok := builtinLevelAliases[level]; !ok {
+			return fmt.Errorf("alias %q: unknown level %q", alias, level)
+		}
+		levelAliases[strings.ToLower(strings.TrimSpace(alias))] = level
+	}
+	return nil
+}
+
+// ParseLevelAliases parses "alias=level" pairs separated by commas
+func ParseLevelAliases(v string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		alias, level, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(alias) == "" {
+			return nil, fmt.Errorf("invalid level alias %q: want alias=level", pair)
+		}
+		out[alias] = level
+	}
+	return out, nil
+}
+
+// NormalizeLevel maps a level or any known alias to its canonical level.
+// Unknown levels are returned lowercased so they still group consistently.
+func NormalizeLevel(level string) string {
+	key := strings.ToLower(strings.TrimSpace(level))
+	levelAliasesMu.RLock()
+	canonical, ok := levelAliases[key]
+	levelAliasesMu.RUnlock()
+	if ok {
+		return canonical
+	}
+	return key
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Fields a webhook mapping can extract
+const (
+	MappingSource      = "source"
+	MappingLevel       = "level"
+	MappingTitle       = "title"
+	MappingMessage     = "message"
+	MappingFingerprint = "fingerprint"
+)
+
+// MappingFields lists the fields a mapping may set, in extraction order
+var MappingFields = []string{MappingSource, MappingLevel, MappingTitle, MappingMessage, MappingFingerprint}
+
+// WebhookMapping is a named profile telling /webhook where a vendor's payload
+// keeps each alert field, as gjson paths (e.g. "alerts.0.labels.severity")
+type WebhookMapping struct {
+	ID        int               `json:"id"`
+	Name      string            `json:"name"`                // Selected with /webhook?profile={name}
+	Fields    map[string]string `json:"fields"`              // Field -> gjson path
+	Defaults  map[string]string `json:"defaults,omitempty"`  // Field -> value when the path finds nothing
+	LevelMap  map[string]string `json:"level_map,omitempty"` // Vendor level value -> level
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"path"
+	"slices"
+)
+
+// AlertMatcher selects alerts for silences and mute rules. Empty fields match
+// any alert.
+type AlertMatcher struct {
+	ChatID string            `json:"chat_id,omitempty"` // Public chat_id to scope to
+	Source string            `json:"source,omitempty"`  // Glob on the alert source
+	Levels []string          `json:"levels,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"` // All must be on the alert with these values
+}
+
+// HasMatchers reports whether m is scoped at all; without matchers it would
+// match everything
+func (m AlertMatcher) HasMatchers() bool {
+	return m.ChatID != "" || m.Source != "" || len(m.Levels) > 0 || len(m.Labels) > 0
+}
+
+// Matches reports whether a is selected by m
+func (m AlertMatcher) Matches(a Alert) bool {
+	if m.ChatID != "" && ChatIDFromSource(a.Source) != m.ChatID {
+		return false
+	}
+	if m.Source != "" {
+		if ok, _ := path.Match(m.Source, a.Source); !ok {
+			return false
+		}
+	}
+	if len(m.Levels) > 0 && !slices.Contains(m.Levels, NormalizeLevel(a.Level)) {
+		return false
+	}
+	for k, v := range m.Labels {
+		if got, ok := a.Labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
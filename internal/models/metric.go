@@ -0,0 +1,55 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"time"
+)
+
+// MetricOperators are the comparisons a metric rule can use
+var MetricOperators = []string{">", ">=", "<", "<="}
+
+// MetricRule raises an alert when a sample of Metric from a matching source
+// crosses Threshold, and resolves it when a later sample is back in range
+type MetricRule struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"` // Glob on the sample source; empty = any
+	Metric    string    `json:"metric"`
+	Operator  string    `json:"operator"`
+	Threshold float64   `json:"threshold"`
+	Level     string    `json:"level"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches reports whether the rule applies to a sample of metric from source
+func (r MetricRule) Matches(source, metric string) bool {
+	if r.Metric != metric {
+		return false
+	}
+	if r.Source == "" {
+		return true
+	}
+	ok, _ := path.Match(r.Source, source)
+	return ok
+}
+
+// Crossed reports whether value breaches the threshold
+func (r MetricRule) Crossed(value float64) bool {
+	switch r.Operator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// Fingerprint pairs the alert a rule raises for a source with its recovery
+func (r MetricRule) Fingerprint(source string) string {
+	return fmt.Sprintf("metric:%d:%s", r.ID, source)
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// MuteRule drops matching alerts at ingestion, for good: they are not stored,
+// shown or notified about. For noise that is never worth seeing, such as info
+// alerts from staging; a Silence is the temporary kind.
+type MuteRule struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	AlertMatcher
+	Enabled   bool       `json:"enabled"`
+	Hits      int64      `json:"hits"` // Alerts dropped so far
+	LastHitAt *time.Time `json:"last_hit_at,omitempty"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// NotificationJob is one queued outbound notification: a web push, SMS,
+// email, Teams card, Telegram or Slack message, as Kind says
+type NotificationJob struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Target     string          `json:"target,omitempty"` // Who it's for, for the delivery history
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"last_error,omitempty"`
+	EnqueuedAt time.Time       `json:"enqueued_at"`
+	FailedAt   *time.Time      `json:"failed_at,omitempty"` // When it was dead-lettered
+
+	StreamID string `json:"-"` // Redis stream entry, while queued
+}
+
+// NotificationQueueStats counts the jobs in each stage of the queue
+type NotificationQueueStats struct {
+	Queued     int64 `json:"queued"`      // Waiting or being delivered
+	Retrying   int64 `json:"retrying"`    // Failed, waiting for their next attempt
+	DeadLetter int64 `json:"dead_letter"` // Gave up; kept for inspection
+}
+
+// Outcomes of a delivery attempt
+const (
+	DeliveryDelivered = "delivered"
+	DeliveryRetrying  = "retrying" // Failed, another attempt is scheduled
+	DeliveryFailed    = "failed"   // Failed for good: dead-lettered
+)
+
+// NotificationAttempt is one try at delivering a queued notification
+type NotificationAttempt struct {
+	ID        int       `json:"id"`
+	JobID     string    `json:"job_id"`
+	Channel   string    `json:"channel"` // The job's kind: push, sms, email, teams, telegram, stakeholder_slack
+	Target    string    `json:"target"`  // e.g. "user 7", a Teams target or Telegram chat
+	Attempt   int       `json:"attempt"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int       `json:"latency_ms"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NotificationAttemptFilter narrows the delivery history; zero fields don't
+// filter
+type NotificationAttemptFilter struct {
+	Channel string
+	Target  string // Substring, case-insensitive
+	Status  string
+	Since   time.Time
+	Until   time.Time
+	Limit   int
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Notification channels a message template can be set for
+const (
+	ChannelPush  = "push"
+	ChannelEmail = "email"
+	ChannelSlack = "slack" // Stakeholder list updates
+	ChannelSMS   = "sms"
+)
+
+// NotificationChannels lists the templatable channels
+var NotificationChannels = []string{ChannelPush, ChannelEmail, ChannelSlack, ChannelSMS}
+
+// TemplateLevelResolved is the Level of templates for resolutions
+const TemplateLevelResolved = "resolved"
+
+// NotificationTemplate replaces the built-in message of a channel, for one
+// severity or for all of them: a Go text/template rendered with the alert
+type NotificationTemplate struct {
+	ID        int       `json:"id"`
+	Channel   string    `json:"channel"`
+	Level     string    `json:"level,omitempty"`   // Severity, "resolved", or empty for any
+	Subject   string    `json:"subject,omitempty"` // Email subject / Slack headline; empty keeps the built-in one
+	Body      string    `json:"body"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+)
+
+// Schedule is an on-call rotation: its participants take turns in shifts of
+// ShiftDays days, handing off at HandoffTime in the schedule's time zone,
+// starting with the first participant on StartDate. Overrides put someone
+// else on call for a set period.
+type Schedule struct {
+	ID           int                `json:"id"`
+	Name         string             `json:"name"`
+	Timezone     string             `json:"timezone"`     // IANA name, e.g. Europe/Berlin
+	Participants []int              `json:"participants"` // User IDs in rotation order
+	ShiftDays    int                `json:"shift_days"`   // 1 = daily, 7 = weekly
+	HandoffTime  string             `json:"handoff_time"` // HH:MM, local
+	StartDate    string             `json:"start_date"`   // YYYY-MM-DD, local
+	Overrides    []ScheduleOverride `json:"overrides"`    // Current and upcoming
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// ScheduleOverride puts a user on call instead of the rotation
+type ScheduleOverride struct {
+	ID         int       `json:"id"`
+	ScheduleID int       `json:"schedule_id"`
+	UserID     int       `json:"user_id"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// OnCallShift is who is on call for a schedule, and from when until when
+type OnCallShift struct {
+	ScheduleID   int       `json:"schedule_id"`
+	ScheduleName string    `json:"schedule_name"`
+	UserID       int       `json:"user_id"`
+	Username     string    `json:"username,omitempty"`
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Override     bool      `json:"override"`
+}
+
+// OnCallAt returns the shift covering t: the override that covers it (the
+// newest, if several do), else the rotation's. ok is false before the
+// rotation starts or without participants.
+func (s Schedule) OnCallAt(t time.Time) (shift OnCallShift, ok bool) {
+	shift = OnCallShift{ScheduleID: s.ID, ScheduleName: s.Name}
+	var override *ScheduleOverride
+	for i, o := range s.Overrides {
+		if !t.Before(o.StartsAt) && t.Before(o.EndsAt) && (override == nil || o.ID > override.ID) {
+			override = &s.Overrides[i]
+		}
+	}
+	if override != nil {
+		shift.UserID, shift.Start, shift.End, shift.Override = override.UserID, override.StartsAt, override.EndsAt, true
+		return shift, true
+	}
+
+	start, end, turn, ok := s.ShiftAt(t)
+	if !ok || len(s.Participants) == 0 {
+		return shift, false
+	}
+	shift.UserID, shift.Start, shift.End = s.Participants[turn%len(s.Participants)], start, end
+	return shift, true
+}
+
+// RespondersAt lists who to escalate to at t, in order: whoever is on call,
+// then the rotation's participants from the current shift's on, each once
+func (s Schedule) RespondersAt(t time.Time) []int {
+	var responders []int
+	seen := map[int]bool{}
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			responders = append(responders, id)
+		}
+	}
+
+	if shift, ok := s.OnCallAt(t); ok {
+		add(shift.UserID)
+	}
+	turn := 0
+	if _, _, n, ok := s.ShiftAt(t); ok {
+		turn = n
+	}
+	for i := range s.Participants {
+		add(s.Participants[(turn+i)%len(s.Participants)])
+	}
+	return responders
+}
+
+// ShiftAt returns the rotation shift covering t and its number, counting the
+// first shift as 0. Shifts are counted in local calendar days, so handoffs stay
+// at HandoffTime across daylight saving changes.
+func (s Schedule) ShiftAt(t time.Time) (start, end time.Time, turn int, ok bool) {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil || s.ShiftDays < 1 {
+		return time.Time{}, time.Time{}, 0, false
+	}
+	first, err := time.ParseInLocation("2006-01-02 15:04", s.StartDate+" "+s.HandoffTime, loc)
+	if err != nil || t.Before(first) {
+		return time.Time{}, time.Time{}, 0, false
+	}
+
+	local := t.In(loc)
+	days := int(civilDate(local).Sub(civilDate(first)).Hours() / 24)
+	if local.Before(time.Date(local.Year(), local.Month(), local.Day(), first.Hour(), first.Minute(), 0, 0, loc)) {
+		days-- // Before today's handoff: still yesterday's shift
+	}
+	turn = days / s.ShiftDays
+	handoff := func(n int) time.Time {
+		return time.Date(first.Year(), first.Month(), first.Day()+n*s.ShiftDays, first.Hour(), first.Minute(), 0, 0, loc)
+	}
+	return handoff(turn), handoff(turn + 1), turn, true
+}
+
+// civilDate is t's calendar date as midnight UTC, for counting days
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"path"
+	"time"
+)
+
+// Opsgenie API regions
+const (
+	OpsgenieRegionUS = "us"
+	OpsgenieRegionEU = "eu"
+)
+
+// OpsgenieRule creates Opsgenie alerts for alerts from a chat and/or matching
+// source, and closes them when the alerts resolve
+type OpsgenieRule struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	ChatID    string    `json:"chat_id,omitempty"` // Public chat_id to scope to; empty = all alerts
+	Source    string    `json:"source,omitempty"`  // Glob on the alert source; empty = any
+	MinLevel  string    `json:"min_level"`
+	APIKey    string    `json:"api_key,omitempty"` // API integration key; stored encrypted, masked in responses
+	Region    string    `json:"region"`
+	Team      string    `json:"team,omitempty"` // Responder team; empty leaves routing to Opsgenie
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+
+	HoursRule // Only during or only outside business hours
+}
+
+// Matches reports whether the rule forwards a
+func (r OpsgenieRule) Matches(a Alert) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.ChatID != "" && ChatIDFromSource(a.Source) != r.ChatID {
+		return false
+	}
+	if r.Source != "" {
+		if ok, _ := path.Match(r.Source, a.Source); !ok {
+			return false
+		}
+	}
+	// Resolutions always go through, to close what was opened
+	return a.Status == AlertStatusResolved || LevelRank(a.Level) >= LevelRank(r.MinLevel)
+}
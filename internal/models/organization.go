@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DefaultOrgID is the organization everything belonged to before there were
+// others. Its admins run the instance: they manage organizations and the
+// settings every organization shares, and alerts outside a chat are its.
+const DefaultOrgID = 1
+
+// Organization is a tenant: its users see its chats' alerts, and its admins
+// manage its users, bots, chats and invitations
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"path"
+	"slices"
+	"time"
+)
+
+// Outgoing webhook events
+const (
+	WebhookEventCreated  = "alert.created"
+	WebhookEventUpdated  = "alert.updated" // Repeat or change of an open alert
+	WebhookEventResolved = "alert.resolved"
+	WebhookEventTest     = "test" // Sent by a channel test; not subscribable
+)
+
+// WebhookEvents lists the events an outgoing webhook can subscribe to
+var WebhookEvents = []string{WebhookEventCreated, WebhookEventUpdated, WebhookEventResolved}
+
+// OutgoingWebhook POSTs a JSON payload to an arbitrary URL on alert events
+// from a chat and/or matching source
+type OutgoingWebhook struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	ChatID    string    `json:"chat_id,omitempty"` // Public chat_id to scope to; empty = all alerts
+	Source    string    `json:"source,omitempty"`  // Glob on the alert source; empty = any
+	MinLevel  string    `json:"min_level"`
+	Events    []string  `json:"events"`             // Empty = all
+	Template  string    `json:"template,omitempty"` // text/template producing the JSON body; empty = default payload
+	Secret    string    `json:"secret,omitempty"`   // HMAC signing secret; stored encrypted, masked in responses
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+
+	HoursRule // Only during or only outside business hours
+}
+
+// Matches reports whether the webhook is sent event for a
+func (h OutgoingWebhook) Matches(event string, a Alert) bool {
+	if !h.Enabled {
+		return false
+	}
+	if len(h.Events) > 0 && !slices.Contains(h.Events, event) {
+		return false
+	}
+	if h.ChatID != "" && ChatIDFromSource(a.Source) != h.ChatID {
+		return false
+	}
+	if h.Source != "" {
+		if ok, _ := path.Match(h.Source, a.Source); !ok {
+			return false
+		}
+	}
+	return event == WebhookEventResolved || LevelRank(a.Level) >= LevelRank(h.MinLevel)
+}
+
+// WebhookDelivery is one attempt at sending an event to an outgoing webhook
+type WebhookDelivery struct {
+	ID         int       `json:"id"`
+	WebhookID  int       `json:"webhook_id"`
+	DeliveryID string    `json:"delivery_id"` // Shared by the retries of one event
+	AlertID    int       `json:"alert_id"`
+	Event      string    `json:"event"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"` // 0 when no response came back
+	Error      string    `json:"error,omitempty"`
+	Response   string    `json:"response,omitempty"` // Start of the response body
+	DurationMs int       `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	CreatedAt  time.Time `json:"created_at"`
+}
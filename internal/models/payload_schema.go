@@ -0,0 +1,22 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LabelSchemaViolation is set on alerts whose payload didn't match the schema
+// registered for their source; the value is the first violation
+const LabelSchemaViolation = "schema_violation"
+
+// PayloadSchema is the JSON Schema webhook payloads from Source are expected
+// to match, with a count of the payloads that didn't
+type PayloadSchema struct {
+	ID              int             `json:"id"`
+	Source          string          `json:"source"`
+	Schema          json.RawMessage `json:"schema"`
+	Violations      int64           `json:"violations"`
+	LastViolation   string          `json:"last_violation,omitempty"`
+	LastViolationAt *time.Time      `json:"last_violation_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
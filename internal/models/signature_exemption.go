@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SignatureExemption lets an ingestion endpoint take requests without an
+// X-Sentinel-Signature while WEBHOOK_SECRET is set, for senders that can't
+// sign (like Gatus)
+type SignatureExemption struct {
+	Endpoint  string    `json:"endpoint"` // As named in the ingestion metrics, e.g. "uptimekuma"
+	Reason    string    `json:"reason"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Silence states, from its window and the current time
+const (
+	SilenceStatePending = "pending"
+	SilenceStateActive  = "active"
+	SilenceStateExpired = "expired"
+)
+
+// Silence holds back notifications for matching alerts during a time window,
+// such as a planned deployment. The alerts are still stored and shown.
+type Silence struct {
+	ID      int    `json:"id"`
+	Comment string `json:"comment"`
+	AlertMatcher
+	MarkAlerts bool      `json:"mark_alerts"` // Show matched alerts as silenced on the dashboard
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// State is pending before the window, active in it and expired after it
+func (s Silence) State(now time.Time) string {
+	switch {
+	case now.Before(s.StartsAt):
+		return SilenceStatePending
+	case now.Before(s.EndsAt):
+		return SilenceStateActive
+	default:
+		return SilenceStateExpired
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SMSPreference is a user's opt-in to be paged by SMS for critical alerts in
+// the chats they can see
+type SMSPreference struct {
+	UserID    int       `json:"user_id"`
+	Phone     string    `json:"phone"` // E.164, e.g. +14155550123
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// NormalizePhone strips the spaces, dashes, dots and parentheses people type
+// into phone numbers and reports whether the rest is an E.164 number
+func NormalizePhone(phone string) (string, bool) {
+	phone = strings.NewReplacer(" ", "", "-", "", ".", "", "(", "", ")", "").Replace(strings.TrimSpace(phone))
+	return phone, e164.MatchString(phone)
+}
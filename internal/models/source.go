@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+)
+
+// MaxSourceLength caps a normalized alert source, in bytes
+const MaxSourceLength = 200
+
+// UnknownSource replaces a source with nothing usable left after normalizing
+const UnknownSource = "unknown"
+
+// NormalizeSource is the form alert sources are stored and indexed in:
+// lowercase, only a-z 0-9 and . _ - : / @ (anything else becomes "-", with
+// runs collapsed), at most MaxSourceLength bytes. The chat ID of a
+// "...:chat:{chatID}" source is kept whole so the alert stays in its chat;
+// what gets cut is the part before it.
+func NormalizeSource(source string) string {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return ""
+	}
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(source) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', strings.ContainsRune("._:/@", r):
+			b.WriteRune(r)
+			dash = false
+		case !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	normalized := strings.Trim(b.String(), "-:")
+	if normalized == "" {
+		return UnknownSource
+	}
+	if len(normalized) <= MaxSourceLength {
+		return normalized
+	}
+
+	prefix, chatID, ok := strings.Cut(normalized, ":chat:")
+	if !ok || len(chatID)+len(":chat:") >= MaxSourceLength {
+		return strings.TrimRight(normalized[:MaxSourceLength], "-:")
+	}
+	keep := MaxSourceLength - len(":chat:") - len(chatID)
+	return strings.TrimRight(prefix[:keep], "-:") + ":chat:" + chatID
+}
+
+// ParseSource splits the structured prefixes out of a source:
+// bot:{name}:chat:{chatID} (and federation:{sender}:chat:{chatID}) give the
+// bot name and chat ID; other sources have neither
+func ParseSource(source string) (bot, chatID string) {
+	chatID = ChatIDFromSource(source)
+	if rest, ok := strings.CutPrefix(source, "bot:"); ok {
+		bot, _, _ = strings.Cut(rest, ":chat:")
+	}
+	return bot, chatID
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// StakeholderLevels are the levels a stakeholder list can subscribe from:
+// stakeholders hear about high-severity incidents only
+var StakeholderLevels = []string{LevelError, LevelCritical}
+
+// StakeholderList is a group outside the responder rotation (management,
+// support, customer success) that gets incident updates: when an incident
+// opens, when its level changes and when it resolves
+type StakeholderList struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	MinLevel      string    `json:"min_level"`
+	ChatID        string    `json:"chat_id,omitempty"` // Public chat_id to scope to; empty = all alerts
+	Emails        []string  `json:"emails"`
+	SlackWebhooks []string  `json:"slack_webhooks"` // Slack incoming webhook URLs, one per channel
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Matches reports whether the list follows an alert
+func (l StakeholderList) Matches(a Alert) bool {
+	if l.ChatID != "" && ChatIDFromSource(a.Source) != l.ChatID {
+		return false
+	}
+	return LevelRank(a.Level) >= LevelRank(l.MinLevel)
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ChatStats summarizes a chat's alert activity over a window of days, built
+// from the per-day counters maintained as alerts are stored
+type ChatStats struct {
+	ChatID       string         `json:"chat_id"`
+	From         string         `json:"from"` // YYYY-MM-DD (UTC), inclusive
+	To           string         `json:"to"`
+	Total        int            `json:"total"`
+	Resolved     int            `json:"resolved"`
+	Acked        int            `json:"acked"`
+	AckRate      float64        `json:"ack_rate"` // Acked / Total
+	ByLevel      map[string]int `json:"by_level"`
+	ByDay        []DayCount     `json:"by_day"`
+	Hours        [24]int        `json:"hours"`         // Alerts per hour of day (UTC)
+	BusiestHours []int          `json:"busiest_hours"` // Top hours of day, busiest first
+	TopTitles    []TitleCount   `json:"top_titles"`
+}
+
+type DayCount struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+type TitleCount struct {
+	Title string `json:"title"`
+	Count int    `json:"count"`
+}
+
+// IngestLatencyBuckets are the upper bounds (ms) ingestion latency is counted
+// in, for percentiles
+var IngestLatencyBuckets = []int{10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// IngestStats summarizes one integration endpoint's requests over a window of
+// hours, built from hourly counters
+type IngestStats struct {
+	Integration string  `json:"integration"`
+	Requests    int     `json:"requests"`
+	Errors      int     `json:"errors"`   // 5xx responses
+	Rejected    int     `json:"rejected"` // 4xx responses (bad payload, auth, rate limit)
+	ErrorRate   float64 `json:"error_rate"`
+	AvgMS       float64 `json:"avg_ms"`
+	P95MS       int     `json:"p95_ms"`              // Upper bound of the bucket holding the 95th percentile; -1 above 10s
+	LastSeen    string  `json:"last_seen,omitempty"` // Hour (UTC, RFC 3339) of the latest request
+}
+
+// API credential kinds in APIUsage
+const (
+	CredentialBot         = "bot"
+	CredentialIngestToken = "ingest_token"
+)
+
+// APIUsage is how one bot or ingest token has been used: requests over the
+// last hours, and when it last sent anything and last failed. Rejected (4xx)
+// and errored (5xx) requests both count as failed: either way the sender's
+// alerts didn't arrive.
+type APIUsage struct {
+	Kind            string     `json:"kind"` // CredentialBot or CredentialIngestToken
+	ID              int        `json:"id"`
+	Name            string     `json:"name"`
+	Requests        int        `json:"requests"`
+	Errors          int        `json:"errors"`   // 5xx responses
+	Rejected        int        `json:"rejected"` // 4xx responses (bad payload, signature, rate limit)
+	ErrorRate       float64    `json:"error_rate"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	LastStatus      int        `json:"last_status,omitempty"`
+	LastErrorAt     *time.Time `json:"last_error_at,omitempty"`
+	LastErrorStatus int        `json:"last_error_status,omitempty"`
+}
+
+// Credential names the bot or token in usage counters
+func (u APIUsage) Credential() string {
+	return fmt.Sprintf("%s:%d", u.Kind, u.ID)
+}
+
+// AlertRollup summarizes the alerts created in the last 24 hours, kept up
+// to date as alerts are stored so the index page needs a single read
+type AlertRollup struct {
+	Since   time.Time      `json:"since"`
+	Total   int            `json:"total"`
+	ByLevel map[string]int `json:"by_level"`
+	Chats   []ChatRollup   `json:"chats"` // Busiest first
+}
+
+// ChatRollup is one chat's part of an AlertRollup; "general" for alerts
+// outside a chat
+type ChatRollup struct {
+	ChatID  string         `json:"chat_id"`
+	Total   int            `json:"total"`
+	ByLevel map[string]int `json:"by_level"`
+	Latest  []Alert        `json:"latest"` // Newest first, as they are now
+}
+
+// Latest merges the chats' latest alerts, newest first
+func (r AlertRollup) Latest() []Alert {
+	var alerts []Alert
+	for _, c := range r.Chats {
+		alerts = append(alerts, c.Latest...)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].CreatedAt.After(alerts[j].CreatedAt) })
+	return alerts
+}
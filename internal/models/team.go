@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Team gives its members the team's chats, so access is granted to a group
+// instead of user by user. Members and chats are in the team's organization.
+type Team struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	OrgID     int       `json:"org_id"`
+	UserIDs   []int     `json:"user_ids"`
+	ChatIDs   []int     `json:"chat_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Ticket connector kinds
+const (
+	TicketKindJira       = "jira"
+	TicketKindServiceNow = "servicenow"
+	TicketKindGitHub     = "github"
+)
+
+// TicketConnector opens a ticket in an external tracker when an alert opens
+// and keeps status/comments in sync in both directions
+type TicketConnector struct {
+	ID            int               `json:"id"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind"`
+	ChatID        string            `json:"chat_id,omitempty"` // Public chat_id to scope to; empty = all alerts
+	MinLevel      string            `json:"min_level"`
+	Config        map[string]string `json:"config"` // Kind-specific settings and credentials
+	WebhookSecret string            `json:"webhook_secret"`
+	Enabled       bool              `json:"enabled"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// AlertTicket links an alert to the ticket a connector opened for it
+type AlertTicket struct {
+	AlertID     int       `json:"alert_id"`
+	ConnectorID int       `json:"connector_id"`
+	ExternalID  string    `json:"external_id"`
+	URL         string    `json:"url"`
+	Status      string    `json:"status"` // "open" or "closed"
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -14,6 +14,9 @@ type User struct {
 	TOTPSecret         string    `json:"-"`
 	TOTPEnabled        bool      `json:"totp_enabled"`
 	LastPasswordChange time.Time `json:"last_password_change,omitempty"`
+	Email              string    `json:"email,omitempty"` // Where password reset links go
+	OrgID              int       `json:"org_id"`
+	SAMLLinked         bool      `json:"saml_linked"` // SAML sign-ins may use the account
 	CreatedAt          time.Time `json:"created_at"`
 }
 
@@ -0,0 +1,254 @@
+// Package msgtemplate lets admins decide what notifications say: Go
+// text/templates per channel and severity, rendered with the alert, a link
+// to it and the raw payload it was ingested from. Channels without a matching
+// template keep their built-in message.
+package msgtemplate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// cacheTTL is how long loaded templates are used before they are reloaded,
+// so edits apply within that time on every instance
+const cacheTTL = 30 * time.Second
+
+// Data is what a template renders
+type Data struct {
+	Alert    models.Alert
+	Level    string // Upper-case level, e.g. "CRITICAL"
+	Headline string // What happened, e.g. "Incident opened", "Resolved"
+	Link     string // To the alert, when a public URL is configured
+}
+
+var funcs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// truncate cuts s to n characters: {{truncate 100 .Alert.Message}}
+	"truncate": func(n int, s string) string {
+		r := []rune(s)
+		if n < 1 || len(r) <= n {
+			return s
+		}
+		return string(r[:n-1]) + "…"
+	},
+	// default falls back when a value is empty: {{default "n/a" (label "env")}}
+	"default": func(fallback, s string) string {
+		if s == "" {
+			return fallback
+		}
+		return s
+	},
+	"time": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+}
+
+// Parse checks a template: it must parse, and render for a sample alert
+func Parse(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("template is empty")
+	}
+	tmpl, err := parse(text)
+	if err != nil {
+		return err
+	}
+	_, err = execute(tmpl, Sample(models.LevelError))
+	return err
+}
+
+// Sample is example data for previews and checks
+func Sample(level string) Data {
+	a := models.Alert{
+		ID:        1,
+		CreatedAt: time.Now().UTC(),
+		Source:    "sample",
+		Level:     level,
+		Title:     "Sample alert",
+		Message:   "Line one\nLine two",
+		Status:    models.AlertStatusOpen,
+		Labels:    map[string]string{"env": "prod"},
+		Payload:   []byte(`{"service": "payments", "alerts": [{"annotations": {"summary": "Error rate above 5%"}}]}`),
+	}
+	headline := "Incident opened"
+	if level == models.TemplateLevelResolved {
+		a.Level, a.Status = models.LevelSuccess, models.AlertStatusResolved
+		headline = "Resolved"
+	}
+	return Data{Alert: a, Level: strings.ToUpper(a.Level), Headline: headline, Link: "https://sentinel.example.com/?alert=1"}
+}
+
+// parse adds the alert-bound functions: label and payload read from the
+// alert at execution time, so they are bound per call in execute
+func parse(text string) (*template.Template, error) {
+	return template.New("").Funcs(funcs).Funcs(template.FuncMap{
+		"label":   func(string) string { return "" },
+		"payload": func(string) string { return "" },
+	}).Option("missingkey=zero").Parse(text)
+}
+
+func execute(tmpl *template.Template, d Data) (string, error) {
+	t, err := tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	t.Funcs(template.FuncMap{
+		// label is one of the alert's labels: {{label "env"}}
+		"label": func(key string) string { return d.Alert.Labels[key] },
+		// payload picks a field out of the raw payload by gjson path:
+		// {{payload "alerts.0.annotations.summary"}}
+		"payload": func(path string) string {
+			if len(d.Alert.Payload) == 0 {
+				return ""
+			}
+			return gjson.GetBytes(d.Alert.Payload, path).String()
+		},
+	})
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+type compiled struct {
+	subject *template.Template // nil keeps the built-in subject
+	body    *template.Template
+}
+
+// Renderer renders the admin-defined templates. A nil Renderer renders
+// nothing, so every channel keeps its built-in messages.
+type Renderer struct {
+	admin     store.AdminStore
+	publicURL string
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	byKey    map[string]compiled // channel + "/" + level
+}
+
+// NewRenderer renders the templates in admin; publicURL (SENTINEL_PUBLIC_URL)
+// fills in the link to the alert
+func NewRenderer(admin store.AdminStore, publicURL string) *Renderer {
+	return &Renderer{admin: admin, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// Render renders channel's template for d.Alert: the one for its level
+// ("resolved" once resolved), else the channel's catch-all. ok is false when
+// there is no template or it fails; callers then use their built-in message.
+// subject is empty when the template doesn't set one.
+func (r *Renderer) Render(ctx context.Context, channel string, d Data) (subject, body string, ok bool) {
+	if r == nil {
+		return "", "", false
+	}
+	c, found := r.lookup(ctx, channel, d.Alert)
+	if !found {
+		return "", "", false
+	}
+	if d.Level == "" {
+		d.Level = strings.ToUpper(d.Alert.Level)
+	}
+	if d.Link == "" && r.publicURL != "" {
+		d.Link = fmt.Sprintf("%s/?alert=%d", r.publicURL, d.Alert.ID)
+	}
+
+	body, err := execute(c.body, d)
+	if err == nil && c.subject != nil {
+		subject, err = execute(c.subject, d)
+	}
+	if err != nil {
+		log.Printf("msgtemplate: %s template failed for alert %d, using the built-in message: %v", channel, d.Alert.ID, err)
+		return "", "", false
+	}
+	return subject, body, true
+}
+
+// Preview renders t with d as Render would, reporting template errors
+// instead of falling back
+func (r *Renderer) Preview(t models.NotificationTemplate, d Data) (subject, body string, err error) {
+	if r != nil && d.Link == "" && r.publicURL != "" {
+		d.Link = fmt.Sprintf("%s/?alert=%d", r.publicURL, d.Alert.ID)
+	}
+	tmpl, err := parse(t.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if body, err = execute(tmpl, d); err != nil {
+		return "", "", err
+	}
+	if t.Subject != "" {
+		if tmpl, err = parse(t.Subject); err != nil {
+			return "", "", err
+		}
+		if subject, err = execute(tmpl, d); err != nil {
+			return "", "", err
+		}
+	}
+	return subject, body, nil
+}
+
+// Message is Render for channels without a subject: the rendered body, or
+// fallback
+func (r *Renderer) Message(ctx context.Context, channel string, d Data, fallback string) string {
+	if _, body, ok := r.Render(ctx, channel, d); ok {
+		return body
+	}
+	return fallback
+}
+
+func (r *Renderer) lookup(ctx context.Context, channel string, a models.Alert) (compiled, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.byKey == nil || time.Since(r.loadedAt) > cacheTTL {
+		r.load(ctx)
+	}
+	level := models.NormalizeLevel(a.Level)
+	if a.Status == models.AlertStatusResolved || level == models.LevelSuccess {
+		level = models.TemplateLevelResolved
+	}
+	if c, ok := r.byKey[channel+"/"+level]; ok {
+		return c, true
+	}
+	c, ok := r.byKey[channel+"/"]
+	return c, ok
+}
+
+// load replaces the cached templates; on failure the old ones stay in use
+// until the next attempt
+func (r *Renderer) load(ctx context.Context) {
+	r.loadedAt = time.Now()
+	templates, err := r.admin.GetNotificationTemplates(ctx)
+	if err != nil {
+		log.Printf("msgtemplate: failed to load templates: %v", err)
+		if r.byKey == nil {
+			r.byKey = map[string]compiled{}
+		}
+		return
+	}
+	byKey := make(map[string]compiled, len(templates))
+	for _, t := range templates {
+		var c compiled
+		if c.body, err = parse(t.Body); err != nil {
+			log.Printf("msgtemplate: skipping %s/%s template: %v", t.Channel, t.Level, err)
+			continue
+		}
+		if t.Subject != "" {
+			if c.subject, err = parse(t.Subject); err != nil {
+				log.Printf("msgtemplate: skipping %s/%s template: %v", t.Channel, t.Level, err)
+				continue
+			}
+		}
+		byKey[t.Channel+"/"+t.Level] = c
+	}
+	r.byKey = byKey
+}
@@ -0,0 +1,226 @@
+// Package notifyqueue makes outbound notifications durable: sends are queued
+// in a Redis stream and delivered by a worker on every instance, failures are
+// retried with exponential backoff, and jobs that keep failing land on a
+// dead-letter list admins can inspect and requeue.
+package notifyqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	// MaxAttempts is how often a job is tried before it is dead-lettered
+	MaxAttempts = 8
+
+	firstBackoff = 30 * time.Second
+	maxBackoff   = 30 * time.Minute
+	readBatch    = 20
+	readBlock    = 5 * time.Second
+	sendTimeout  = 30 * time.Second
+
+	pruneInterval = time.Hour // How often old delivery history is deleted
+)
+
+// Sender delivers one job's payload. Return an error wrapped with Permanent
+// when retrying can't help (unknown recipient, subscription gone).
+type Sender func(ctx context.Context, payload json.RawMessage) error
+
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// Permanent marks err as not worth retrying: the job is dead-lettered
+// straight away
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+// Targeted payloads name who they are for in the delivery history, without
+// credentials: "user 7", a Teams target, a Telegram chat
+type Targeted interface {
+	NotificationTarget() string
+}
+
+type Queue struct {
+	store    store.AlertStore
+	history  store.AdminStore // nil keeps no delivery history
+	consumer string
+
+	mu      sync.RWMutex
+	senders map[string]Sender
+}
+
+// New queues jobs in s; each instance consumes as its own consumer
+func New(s store.AlertStore) *Queue {
+	host, _ := os.Hostname()
+	return &Queue{
+		store:    s,
+		consumer: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		senders:  make(map[string]Sender),
+	}
+}
+
+// History records every delivery attempt in admin, and prunes attempts
+// older than store.NotificationAttemptRetention
+func (q *Queue) History(admin store.AdminStore) {
+	q.history = admin
+}
+
+// Register delivers jobs of kind with send
+func (q *Queue) Register(kind string, send Sender) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.senders[kind] = send
+}
+
+// Enqueue queues payload for delivery by kind's sender
+func (q *Queue) Enqueue(ctx context.Context, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	job := models.NotificationJob{
+		ID:         hex.EncodeToString(id),
+		Kind:       kind,
+		Payload:    data,
+		EnqueuedAt: time.Now().UTC(),
+	}
+	if t, ok := payload.(Targeted); ok {
+		job.Target = t.NotificationTarget()
+	}
+	return q.store.EnqueueNotification(ctx, job)
+}
+
+// Run delivers queued jobs until ctx is done
+func (q *Queue) Run(ctx context.Context) {
+	var pruned time.Time
+	for ctx.Err() == nil {
+		if q.history != nil && time.Since(pruned) > pruneInterval {
+			pruned = time.Now()
+			if _, err := q.history.PruneNotificationAttempts(ctx, pruned.Add(-store.NotificationAttemptRetention)); err != nil {
+				log.Printf("notifyqueue: failed to prune delivery history: %v", err)
+			}
+		}
+		if _, err := q.store.PromoteNotifications(ctx, time.Now()); err != nil {
+			log.Printf("notifyqueue: failed to requeue due retries: %v", err)
+		}
+		jobs, err := q.store.ReadNotifications(ctx, q.consumer, readBatch, readBlock)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("notifyqueue: failed to read jobs: %v", err)
+				select {
+				case <-time.After(readBlock):
+				case <-ctx.Done():
+				}
+			}
+			continue
+		}
+		for _, job := range jobs {
+			q.deliver(ctx, job)
+		}
+	}
+}
+
+func (q *Queue) deliver(ctx context.Context, job models.NotificationJob) {
+	q.mu.RLock()
+	send, ok := q.senders[job.Kind]
+	q.mu.RUnlock()
+
+	var err error
+	start := time.Now()
+	if !ok {
+		err = Permanent(fmt.Errorf("no sender for %q", job.Kind))
+	} else {
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err = send(sendCtx, job.Payload)
+		cancel()
+	}
+	latency := time.Since(start)
+	if err == nil {
+		q.record(ctx, job, job.Attempts+1, models.DeliveryDelivered, nil, latency)
+		if err := q.store.AckNotification(ctx, job); err != nil {
+			log.Printf("notifyqueue: failed to ack %s job %s: %v", job.Kind, job.ID, err)
+		}
+		return
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+	if errors.As(err, new(permanentError)) || job.Attempts >= MaxAttempts {
+		now := time.Now().UTC()
+		job.FailedAt = &now
+		q.record(ctx, job, job.Attempts, models.DeliveryFailed, err, latency)
+		log.Printf("notifyqueue: giving up on %s job %s after %d attempt(s): %v", job.Kind, job.ID, job.Attempts, err)
+		if err := q.store.DeadLetterNotification(ctx, job); err != nil {
+			log.Printf("notifyqueue: failed to dead-letter %s job %s: %v", job.Kind, job.ID, err)
+		}
+		return
+	}
+
+	q.record(ctx, job, job.Attempts, models.DeliveryRetrying, err, latency)
+	wait := Backoff(job.Attempts)
+	log.Printf("notifyqueue: %s job %s failed (attempt %d), retrying in %s: %v", job.Kind, job.ID, job.Attempts, wait, err)
+	if err := q.store.RetryNotification(ctx, job, time.Now().Add(wait)); err != nil {
+		log.Printf("notifyqueue: failed to schedule retry of %s job %s: %v", job.Kind, job.ID, err)
+	}
+}
+
+// record adds an attempt to the delivery history; failing to is only logged
+func (q *Queue) record(ctx context.Context, job models.NotificationJob, attempt int, status string, err error, latency time.Duration) {
+	if q.history == nil {
+		return
+	}
+	a := models.NotificationAttempt{
+		JobID:     job.ID,
+		Channel:   job.Kind,
+		Target:    job.Target,
+		Attempt:   attempt,
+		Status:    status,
+		LatencyMs: int(latency.Milliseconds()),
+	}
+	if err != nil {
+		a.Error = err.Error()
+	}
+	if err := q.history.RecordNotificationAttempt(ctx, a); err != nil {
+		log.Printf("notifyqueue: failed to record %s job %s attempt: %v", job.Kind, job.ID, err)
+	}
+}
+
+// Backoff is how long to wait after a job's nth failed attempt: 30s,
+// doubling up to 30 minutes
+func Backoff(attempts int) time.Duration {
+	wait := firstBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+// Send queues payload when q is set, and otherwise delivers it once right
+// away with send, so notifiers work the same with and without a queue
+func (q *Queue) Send(ctx context.Context, kind string, payload any, send Sender) error {
+	if q != nil {
+		return q.Enqueue(ctx, kind, payload)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return send(ctx, data)
+}
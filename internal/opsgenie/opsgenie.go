@@ -0,0 +1,209 @@
+// Package opsgenie forwards alerts to Opsgenie through its Alert API: an
+// alert is created when a matching Sentinel alert opens, its priority follows
+// level changes, and it is closed when the Sentinel alert resolves.
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+var apiBase = map[string]string{
+	models.OpsgenieRegionUS: "https://api.opsgenie.com",
+	models.OpsgenieRegionEU: "https://api.eu.opsgenie.com",
+}
+
+// Priority maps a Sentinel level to an Opsgenie priority, P1 (critical) to
+// P5 (success)
+func Priority(level string) string {
+	switch models.NormalizeLevel(level) {
+	case models.LevelCritical:
+		return "P1"
+	case models.LevelError:
+		return "P2"
+	case models.LevelWarning:
+		return "P3"
+	case models.LevelSuccess:
+		return "P5"
+	}
+	return "P4"
+}
+
+// Alias is the Opsgenie alias for an alert: its fingerprint, so repeats
+// dedupe the way they do in Sentinel, else its ID
+func Alias(a models.Alert) string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	return "sentinel:alert:" + strconv.Itoa(a.ID)
+}
+
+type Forwarder struct {
+	admin     store.AdminStore
+	publicURL string
+	http      *http.Client
+
+	mu   sync.Mutex
+	open map[string]string // Rule ID + alias -> priority Opsgenie last got
+}
+
+// NewForwarder forwards alerts by the rules in admin; publicURL
+// (SENTINEL_PUBLIC_URL) adds a link to the alert when set
+func NewForwarder(admin store.AdminStore, publicURL string) *Forwarder {
+	return &Forwarder{
+		admin:     admin,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		http:      &http.Client{Timeout: 10 * time.Second},
+		open:      make(map[string]string),
+	}
+}
+
+// Run forwards alert lifecycle events until ch is closed
+func (f *Forwarder) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		f.Forward(ctx, a)
+	}
+}
+
+// Forward creates, reprioritizes or closes a's Opsgenie alert for every
+// matching rule
+func (f *Forwarder) Forward(ctx context.Context, a models.Alert) {
+	rules, err := f.admin.GetOpsgenieRules(ctx)
+	if err != nil {
+		log.Printf("opsgenie: failed to load rules: %v", err)
+		return
+	}
+	calendars := store.CalendarsByID(ctx, f.admin)
+	now := time.Now()
+	for _, rule := range rules {
+		if !rule.Matches(a) || !rule.AllowsAt(a, calendars, now) {
+			continue
+		}
+		if rule.APIKey == "" {
+			log.Printf("opsgenie: rule %s has no usable API key (check SENTINEL_ENCRYPTION_KEY)", rule.Name)
+			continue
+		}
+		if err := f.forward(ctx, rule, a); err != nil {
+			log.Printf("opsgenie: failed to forward alert %d for rule %s: %v", a.ID, rule.Name, err)
+		}
+	}
+}
+
+func (f *Forwarder) forward(ctx context.Context, rule models.OpsgenieRule, a models.Alert) error {
+	alias := Alias(a)
+	key := strconv.Itoa(rule.ID) + "\x00" + alias
+	priority := Priority(a.Level)
+
+	f.mu.Lock()
+	last, seen := f.open[key]
+	switch {
+	case a.Status == models.AlertStatusResolved:
+		delete(f.open, key)
+	case a.Status != "":
+		f.open[key] = priority
+	}
+	f.mu.Unlock()
+
+	aliasPath := "/v2/alerts/" + url.PathEscape(alias)
+	switch {
+	case a.Status == models.AlertStatusResolved:
+		return f.call(ctx, rule, http.MethodPost, aliasPath+"/close?identifierType=alias", map[string]any{
+			"source": "Sentinel",
+			"note":   "Resolved in Sentinel",
+		})
+	case !seen:
+		return f.call(ctx, rule, http.MethodPost, "/v2/alerts", f.createRequest(rule, a, alias, priority))
+	case last != priority:
+		return f.call(ctx, rule, http.MethodPut, aliasPath+"/priority?identifierType=alias", map[string]any{"priority": priority})
+	}
+	return nil // Already open at this priority
+}
+
+func (f *Forwarder) createRequest(rule models.OpsgenieRule, a models.Alert, alias, priority string) map[string]any {
+	details := map[string]string{"level": a.Level}
+	for k, v := range a.Labels {
+		details[k] = v
+	}
+	description := a.Message
+	if f.publicURL != "" {
+		details["sentinel_url"] = fmt.Sprintf("%s/?alert=%d", f.publicURL, a.ID)
+		description = strings.TrimSpace(description + "\n\n" + details["sentinel_url"])
+	}
+
+	req := map[string]any{
+		"message":     truncate(a.Title, 130),
+		"alias":       truncate(alias, 512),
+		"description": truncate(description, 15000),
+		"entity":      a.Source,
+		"source":      "Sentinel",
+		"priority":    priority,
+		"tags":        []string{"sentinel", models.NormalizeLevel(a.Level)},
+		"details":     details,
+	}
+	if rule.Team != "" {
+		req["responders"] = []map[string]string{{"name": rule.Team, "type": "team"}}
+	}
+	return req
+}
+
+// call sends a request to the Alert API. Requests are processed asynchronously on
+// Opsgenie's side; a 202 means accepted, not applied.
+func (f *Forwarder) call(ctx context.Context, rule models.OpsgenieRule, method, path string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	base, ok := apiBase[rule.Region]
+	if !ok {
+		base = apiBase[models.OpsgenieRegionUS]
+	}
+	req, err := http.NewRequestWithContext(ctx, method, base+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+rule.APIKey)
+
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("opsgenie returned %s: %s", resp.Status, apiErr.Message)
+	}
+	return nil
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
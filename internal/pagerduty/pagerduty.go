@@ -0,0 +1,352 @@
+// Package pagerduty feeds alerts into PagerDuty through the Events API v2:
+// trigger when an alert opens or changes level, acknowledge when someone
+// picks it up in Sentinel, resolve when it resolves. The dedup key is the
+// alert's fingerprint, so PagerDuty groups events the same way Sentinel does
+// and its webhooks can be matched back to the alert.
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	eventsURL   = "https://events.pagerduty.com/v2/enqueue"
+	maxAttempts = 4
+
+	// alertKeyPrefix makes dedup keys for alerts without a fingerprint
+	alertKeyPrefix = "sentinel:alert:"
+)
+
+// Event actions
+const (
+	ActionTrigger     = "trigger"
+	ActionAcknowledge = "acknowledge"
+	ActionResolve     = "resolve"
+)
+
+// Target is a PagerDuty service (by its Events API v2 integration key) and
+// the alerts it receives
+type Target struct {
+	Name       string   `json:"name"`
+	RoutingKey string   `json:"routing_key"`
+	MinLevel   string   `json:"min_level"` // Default error
+	Sources    []string `json:"sources"`   // Glob patterns on the alert source; empty = all
+	ChatID     string   `json:"chat_id"`   // Public chat_id to scope to; empty = all
+}
+
+func (t Target) matches(a models.Alert) bool {
+	minLevel := t.MinLevel
+	if minLevel == "" {
+		minLevel = models.LevelError
+	}
+	if models.LevelRank(a.Level) < models.LevelRank(minLevel) && a.Status != models.AlertStatusResolved {
+		return false
+	}
+	if t.ChatID != "" && models.ChatIDFromSource(a.Source) != t.ChatID {
+		return false
+	}
+	if len(t.Sources) == 0 {
+		return true
+	}
+	for _, pattern := range t.Sources {
+		if ok, _ := path.Match(pattern, a.Source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTargets reads the JSON array of targets in PAGERDUTY_TARGETS_FILE, or a
+// single target from PAGERDUTY_ROUTING_KEY and PAGERDUTY_MIN_LEVEL
+func LoadTargets() ([]Target, error) {
+	if file := os.Getenv("PAGERDUTY_TARGETS_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var targets []Target
+		if err := json.Unmarshal(data, &targets); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, t := range targets {
+			if t.RoutingKey == "" {
+				return nil, fmt.Errorf("%s: target %q needs a routing_key", file, t.Name)
+			}
+		}
+		return targets, nil
+	}
+	if key := os.Getenv("PAGERDUTY_ROUTING_KEY"); key != "" {
+		return []Target{{Name: "default", RoutingKey: key, MinLevel: os.Getenv("PAGERDUTY_MIN_LEVEL")}}, nil
+	}
+	return nil, nil
+}
+
+// DedupKey is the key PagerDuty groups an alert's events under
+func DedupKey(a models.Alert) string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	return alertKeyPrefix + strconv.Itoa(a.ID)
+}
+
+// AlertIDFromDedupKey returns the alert ID in a key made for an alert
+// without a fingerprint
+func AlertIDFromDedupKey(key string) (int, bool) {
+	rest, ok := strings.CutPrefix(key, alertKeyPrefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(rest)
+	return id, err == nil
+}
+
+// ValidSignature checks an X-PagerDuty-Signature header ("v1=<hex>", several
+// comma separated while a secret is being rotated) against body
+func ValidSignature(header string, body []byte, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "v1=" + hex.EncodeToString(mac.Sum(nil))
+	for _, sig := range strings.Split(header, ",") {
+		if hmac.Equal([]byte(strings.TrimSpace(sig)), []byte(expected)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sent is what a target last heard about an alert
+type sent struct {
+	action string
+	level  string
+}
+
+type Forwarder struct {
+	targets   []Target
+	publicURL string
+	http      *http.Client
+
+	mu   sync.Mutex
+	sent map[string]sent // Target name + dedup key -> last event
+}
+
+// NewForwarder sends events to targets; publicURL (SENTINEL_PUBLIC_URL) adds
+// a link back to the alert when set
+func NewForwarder(targets []Target, publicURL string) *Forwarder {
+	return &Forwarder{
+		targets:   targets,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		http:      &http.Client{Timeout: 10 * time.Second},
+		sent:      make(map[string]sent),
+	}
+}
+
+// Run forwards alert lifecycle events until ch is closed. Unlike other
+// notifiers it also follows updates, which is where acknowledgements are.
+func (f *Forwarder) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel && msg.Channel != store.AlertUpdatesChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		f.Forward(ctx, a)
+	}
+}
+
+// Forward sends every matching target the events a calls for
+func (f *Forwarder) Forward(ctx context.Context, a models.Alert) {
+	for _, t := range f.targets {
+		if !t.matches(a) {
+			continue
+		}
+		for _, action := range f.actions(t, a) {
+			if err := f.send(ctx, t, f.event(t, a, action)); err != nil {
+				log.Printf("pagerduty: failed to %s alert %d on %s: %v", action, a.ID, t.Name, err)
+				break
+			}
+		}
+	}
+}
+
+// actions works out which events move t's incident to a's state: repeats
+// and updates that don't change the level send nothing
+func (f *Forwarder) actions(t Target, a models.Alert) []string {
+	key := t.Name + "\x00" + DedupKey(a)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	last, seen := f.sent[key]
+	switch {
+	case a.Status == models.AlertStatusResolved:
+		delete(f.sent, key)
+		return []string{ActionResolve}
+	case a.Status == "":
+		// One-shot alert: nothing will resolve it, so only trigger
+		return []string{ActionTrigger}
+	}
+
+	var actions []string
+	if !seen || last.level != a.Level {
+		actions = append(actions, ActionTrigger)
+	}
+	next := sent{action: ActionTrigger, level: a.Level}
+	if a.AckedAt != nil {
+		if last.action != ActionAcknowledge {
+			actions = append(actions, ActionAcknowledge)
+		}
+		next.action = ActionAcknowledge
+	}
+	f.sent[key] = next
+	return actions
+}
+
+// severity maps Sentinel levels onto the Events API's four severities
+func severity(level string) string {
+	switch models.NormalizeLevel(level) {
+	case models.LevelCritical:
+		return "critical"
+	case models.LevelError:
+		return "error"
+	case models.LevelWarning:
+		return "warning"
+	}
+	return "info"
+}
+
+type event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *payload `json:"payload,omitempty"` // Trigger only
+	Client      string   `json:"client,omitempty"`
+	ClientURL   string   `json:"client_url,omitempty"`
+	Links       []link   `json:"links,omitempty"`
+}
+
+type payload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type link struct {
+	Href string `json:"href"`
+	Text string `json:"text"`
+}
+
+func (f *Forwarder) event(t Target, a models.Alert, action string) event {
+	e := event{RoutingKey: t.RoutingKey, EventAction: action, DedupKey: DedupKey(a)}
+	if action != ActionTrigger {
+		return e
+	}
+
+	source := a.Source
+	if source == "" {
+		source = "sentinel"
+	}
+	details := map[string]string{"level": a.Level}
+	if a.Message != "" {
+		details["message"] = a.Message
+	}
+	for k, v := range a.Labels {
+		details[k] = v
+	}
+	e.Payload = &payload{
+		Summary:       truncate(a.Title, 1024),
+		Source:        source,
+		Severity:      severity(a.Level),
+		Timestamp:     a.CreatedAt.UTC().Format(time.RFC3339),
+		CustomDetails: details,
+	}
+	e.Client = "Sentinel"
+	if f.publicURL != "" {
+		alertURL := fmt.Sprintf("%s/?alert=%d", f.publicURL, a.ID)
+		e.ClientURL = alertURL
+		e.Links = []link{{Href: alertURL, Text: "Open in Sentinel"}}
+	}
+	return e
+}
+
+func (f *Forwarder) send(ctx context.Context, t Target, e event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(1<<attempt) * time.Second):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, eventsURL, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := f.http.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var res struct {
+			Status  string   `json:"status"`
+			Message string   `json:"message"`
+			Errors  []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			// Throttled or PagerDuty trouble: back off and retry
+			lastErr = fmt.Errorf("%s: %s", resp.Status, res.Message)
+		default:
+			// Invalid event or routing key: retrying won't help
+			return fmt.Errorf("%s: %s %s", resp.Status, res.Message, strings.Join(res.Errors, "; "))
+		}
+	}
+	return lastErr
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,212 @@
+// Package payloadschema checks webhook payloads against the JSON Schema an
+// admin registered for their source. It supports the subset of JSON Schema
+// that describes payload shape: type, properties, required,
+// additionalProperties, items, enum, minLength/maxLength and pattern.
+package payloadschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// MaxErrors bounds how many violations Validate reports
+const MaxErrors = 10
+
+var knownTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// Schema is a compiled schema
+type Schema struct {
+	Types                []string
+	Properties           map[string]*Schema
+	Required             []string
+	AdditionalProperties *bool
+	Items                *Schema
+	Enum                 []any
+	MinLength            *int
+	MaxLength            *int
+	Pattern              *regexp.Regexp
+}
+
+type rawSchema struct {
+	Type                 json.RawMessage            `json:"type"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties *bool                      `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	Enum                 []any                      `json:"enum"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              string                     `json:"pattern"`
+}
+
+// Compile parses a JSON Schema document
+func Compile(data []byte) (*Schema, error) {
+	return compile(data, "")
+}
+
+func compile(data []byte, at string) (*Schema, error) {
+	var raw rawSchema
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", pointer(at), err)
+	}
+	s := &Schema{
+		Required:             raw.Required,
+		AdditionalProperties: raw.AdditionalProperties,
+		Enum:                 raw.Enum,
+		MinLength:            raw.MinLength,
+		MaxLength:            raw.MaxLength,
+	}
+
+	if len(raw.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(raw.Type, &one); err == nil {
+			s.Types = []string{one}
+		} else if err := json.Unmarshal(raw.Type, &s.Types); err != nil {
+			return nil, fmt.Errorf("%s: type must be a string or an array of strings", pointer(at))
+		}
+		for _, t := range s.Types {
+			if !slices.Contains(knownTypes, t) {
+				return nil, fmt.Errorf("%s: unknown type %q", pointer(at), t)
+			}
+		}
+	}
+	if raw.Pattern != "" {
+		re, err := regexp.Compile(raw.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern: %w", pointer(at), err)
+		}
+		s.Pattern = re
+	}
+	if len(raw.Properties) > 0 {
+		s.Properties = make(map[string]*Schema, len(raw.Properties))
+		for name, sub := range raw.Properties {
+			compiled, err := compile(sub, at+"/"+name)
+			if err != nil {
+				return nil, err
+			}
+			s.Properties[name] = compiled
+		}
+	}
+	if len(raw.Items) > 0 {
+		items, err := compile(raw.Items, at+"/[]")
+		if err != nil {
+			return nil, err
+		}
+		s.Items = items
+	}
+	return s, nil
+}
+
+// Validate checks a decoded JSON value (as from encoding/json into any) and
+// returns what's wrong with it, at most MaxErrors entries
+func (s *Schema) Validate(v any) []string {
+	var errs []string
+	s.validate(v, "", &errs)
+	return errs
+}
+
+func (s *Schema) validate(v any, at string, errs *[]string) {
+	if len(*errs) >= MaxErrors {
+		return
+	}
+	fail := func(format string, args ...any) {
+		if len(*errs) < MaxErrors {
+			*errs = append(*errs, pointer(at)+": "+fmt.Sprintf(format, args...))
+		}
+	}
+
+	if len(s.Types) > 0 && !slices.ContainsFunc(s.Types, func(t string) bool { return hasType(v, t) }) {
+		fail("expected %s, got %s", strings.Join(s.Types, " or "), typeOf(v))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return equal(e, v) }) {
+		fail("value not in enum")
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				fail("missing required property %q", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names) // Report in a stable order
+		for _, name := range names {
+			if sub, ok := s.Properties[name]; ok {
+				sub.validate(v[name], at+"/"+name, errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				fail("unexpected property %q", name)
+			}
+		}
+	case []any:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, fmt.Sprintf("%s/%d", at, i), errs)
+			}
+		}
+	case string:
+		n := len([]rune(v))
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("shorter than %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("longer than %d characters", *s.MaxLength)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			fail("does not match pattern %q", s.Pattern.String())
+		}
+	}
+}
+
+func hasType(v any, t string) bool {
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	}
+	return typeOf(v) == t
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func equal(a, b any) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// pointer renders a location as a JSON pointer, "/" for the root
+func pointer(at string) string {
+	if at == "" {
+		return "/"
+	}
+	return at
+}
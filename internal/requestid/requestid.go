@@ -0,0 +1,73 @@
+// Package requestid carries the X-Request-ID of an HTTP request through the
+// code it calls: logs, the audit trail, the alerts it changes and the
+// outbound calls those alerts cause, so one ID follows an event across
+// systems.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// Header is the request and response header holding the ID
+const Header = "X-Request-ID"
+
+// maxLength caps an ID taken from a client; longer ones are replaced
+const maxLength = 128
+
+type contextKey struct{}
+
+// New is a random ID for a request that came without one
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Valid reports whether an ID from a client can be used as is: at most 128
+// characters of letters, digits and - _ . : /, so it is safe in logs and
+// headers
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':' || c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// FromRequest is the request's X-Request-ID when it is valid, else a new one
+func FromRequest(r *http.Request) string {
+	if id := r.Header.Get(Header); Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// NewContext returns ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext is the ID ctx carries, or "" outside a request
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Set forwards id on an outbound request; an empty id is left out
+func Set(req *http.Request, id string) {
+	if id != "" {
+		req.Header.Set(Header, id)
+	}
+}
@@ -0,0 +1,443 @@
+// Package saml is a SAML 2.0 service provider for SP-initiated single sign-on:
+// it sends users to the IdP with an AuthnRequest (HTTP-Redirect binding),
+// checks the signed Response the IdP posts back (HTTP-POST binding) and maps
+// the assertion's attributes to a Sentinel role. Encrypted assertions,
+// IdP-initiated logins and single logout are not supported.
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	protocolNS    = "urn:oasis:names:tc:SAML:2.0:protocol"
+	assertionNS   = "urn:oasis:names:tc:SAML:2.0:assertion"
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	bearerMethod  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+	postBinding   = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	nameIDFormat  = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+)
+
+// MaxClockSkew is how far apart the IdP's clock and ours may be
+const MaxClockSkew = 2 * time.Minute
+
+// roleRank orders the roles, so a user matching several mappings gets the
+// most privileged one
+var roleRank = map[string]int{"user": 1, "developer": 2, "admin": 3}
+
+// ServiceProvider is Sentinel's side of the SAML trust with one IdP
+type ServiceProvider struct {
+	EntityID  string // Our entity ID; defaults to the metadata URL
+	ACSURL    string // Where the IdP posts responses
+	IdPSSOURL string
+	// IdPEntityID, when set, must be the Issuer of every response
+	IdPEntityID string
+	IdPCerts    []*x509.Certificate // Signing certificates; several during a rollover
+
+	// UsernameAttribute names the attribute holding the username; empty
+	// uses the NameID
+	UsernameAttribute string
+	// RoleAttribute names the attribute whose values RoleMap maps to
+	// roles; users without a mapped value get DefaultRole
+	RoleAttribute string
+	RoleMap       map[string]string
+	DefaultRole   string
+}
+
+// Assertion is what a verified response says about the user
+type Assertion struct {
+	ID           string
+	NameID       string
+	Attributes   map[string][]string // By Name and by FriendlyName
+	NotOnOrAfter time.Time           // When the assertion may no longer be used
+}
+
+// FromEnv configures the service provider from SAML_IDP_SSO_URL,
+// SAML_IDP_CERT or SAML_IDP_CERT_FILE, SAML_IDP_ENTITY_ID, SAML_SP_ENTITY_ID,
+// SAML_USERNAME_ATTRIBUTE, SAML_ROLE_ATTRIBUTE, SAML_ROLE_MAP
+// (value=role,...) and SAML_DEFAULT_ROLE. Without SAML_IDP_SSO_URL it
+// returns nil: SAML sign-in is off.
+func FromEnv(publicURL string) (*ServiceProvider, error) {
+	ssoURL := os.Getenv("SAML_IDP_SSO_URL")
+	if ssoURL == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(ssoURL); err != nil || u.Scheme != "https" && u.Scheme != "http" {
+		return nil, fmt.Errorf("SAML_IDP_SSO_URL: want an http(s) URL, got %q", ssoURL)
+	}
+	if publicURL == "" {
+		return nil, errors.New("SAML sign-in needs SENTINEL_PUBLIC_URL for the address the IdP posts back to")
+	}
+	publicURL = strings.TrimRight(publicURL, "/")
+
+	sp := &ServiceProvider{
+		EntityID:          os.Getenv("SAML_SP_ENTITY_ID"),
+		ACSURL:            publicURL + "/saml/acs",
+		IdPSSOURL:         ssoURL,
+		IdPEntityID:       os.Getenv("SAML_IDP_ENTITY_ID"),
+		UsernameAttribute: os.Getenv("SAML_USERNAME_ATTRIBUTE"),
+		RoleAttribute:     os.Getenv("SAML_ROLE_ATTRIBUTE"),
+		RoleMap:           map[string]string{},
+		DefaultRole:       os.Getenv("SAML_DEFAULT_ROLE"),
+	}
+	if sp.EntityID == "" {
+		sp.EntityID = publicURL + "/saml/metadata"
+	}
+	if sp.DefaultRole == "" {
+		sp.DefaultRole = "user"
+	}
+	if _, ok := roleRank[sp.DefaultRole]; !ok {
+		return nil, fmt.Errorf("SAML_DEFAULT_ROLE: want admin, developer or user, got %q", sp.DefaultRole)
+	}
+
+	certs := os.Getenv("SAML_IDP_CERT")
+	if path := os.Getenv("SAML_IDP_CERT_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("SAML_IDP_CERT_FILE: %w", err)
+		}
+		certs = string(data)
+	}
+	var err error
+	if sp.IdPCerts, err = parseCertificates(certs); err != nil {
+		return nil, fmt.Errorf("SAML_IDP_CERT: %w", err)
+	}
+
+	if m := os.Getenv("SAML_ROLE_MAP"); m != "" {
+		if sp.RoleAttribute == "" {
+			return nil, errors.New("SAML_ROLE_MAP needs SAML_ROLE_ATTRIBUTE")
+		}
+		for _, pair := range strings.Split(m, ",") {
+			value, role, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if _, known := roleRank[role]; !ok || value == "" || !known {
+				return nil, fmt.Errorf("SAML_ROLE_MAP: want value=admin|developer|user, got %q", pair)
+			}
+			sp.RoleMap[value] = role
+		}
+	}
+	return sp, nil
+}
+
+// parseCertificates reads PEM certificates, or a single base64 DER
+// certificate as IdPs show them in their metadata
+func parseCertificates(s string) ([]*x509.Certificate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("the IdP's signing certificate is required")
+	}
+	if !strings.Contains(s, "-----BEGIN") {
+		der, err := decodeBase64(s)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		return []*x509.Certificate{cert}, nil
+	}
+
+	var certs []*x509.Certificate
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
+
+// Metadata is the SP metadata document to register with the IdP
+func (sp *ServiceProvider) Metadata() []byte {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	fmt.Fprintf(&b, `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="%s">`, escape(sp.EntityID))
+	fmt.Fprintf(&b, `<md:SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="%s">`, protocolNS)
+	fmt.Fprintf(&b, `<md:NameIDFormat>%s</md:NameIDFormat>`, nameIDFormat)
+	fmt.Fprintf(&b, `<md:AssertionConsumerService Binding="%s" Location="%s" index="1" isDefault="true"/>`, postBinding, escape(sp.ACSURL))
+	b.WriteString(`</md:SPSSODescriptor></md:EntityDescriptor>`)
+	return b.Bytes()
+}
+
+// AuthnRequestURL builds the IdP URL that starts a sign-in. The request ID
+// must come back as the response's InResponseTo; relayState is returned
+// unchanged alongside it.
+func (sp *ServiceProvider) AuthnRequestURL(relayState string) (redirect, requestID string, err error) {
+	id := make([]byte, 20)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	requestID = "_" + hex.EncodeToString(id) // IDs can't start with a digit
+
+	var req bytes.Buffer
+	fmt.Fprintf(&req, `<samlp:AuthnRequest xmlns:samlp="%s" xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s" Destination="%s" AssertionConsumerServiceURL="%s" ProtocolBinding="%s">`,
+		protocolNS, assertionNS, requestID, time.Now().UTC().Format(time.RFC3339), escape(sp.IdPSSOURL), escape(sp.ACSURL), postBinding)
+	fmt.Fprintf(&req, `<saml:Issuer>%s</saml:Issuer>`, escape(sp.EntityID))
+	fmt.Fprintf(&req, `<samlp:NameIDPolicy Format="%s" AllowCreate="true"/>`, nameIDFormat)
+	req.WriteString(`</samlp:AuthnRequest>`)
+
+	// The redirect binding deflates the request before encoding it
+	var deflated bytes.Buffer
+	fw, _ := flate.NewWriter(&deflated, flate.BestCompression)
+	fw.Write(req.Bytes())
+	fw.Close()
+
+	u, err := url.Parse(sp.IdPSSOURL)
+	if err != nil {
+		return "", "", err
+	}
+	q := u.Query()
+	q.Set("SAMLRequest", base64.StdEncoding.EncodeToString(deflated.Bytes()))
+	if relayState != "" {
+		q.Set("RelayState", relayState)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), requestID, nil
+}
+
+// ParseResponse verifies a base64 SAMLResponse posted to the ACS URL in
+// answer to requestID, and returns its assertion. The response or its
+// assertion must be signed by the IdP, and everything returned is read from
+// the signed part.
+func (sp *ServiceProvider) ParseResponse(encoded, requestID string, now time.Time) (*Assertion, error) {
+	if requestID == "" {
+		return nil, errors.New("no sign-in in progress; start again from the login page")
+	}
+	data, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAMLResponse: %w", err)
+	}
+	resp, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAMLResponse: %w", err)
+	}
+	if !resp.is(protocolNS, "Response") {
+		return nil, errors.New("not a SAML response")
+	}
+	if err := checkUniqueIDs(resp); err != nil {
+		return nil, err
+	}
+
+	responseSigned := false
+	switch err := verifySignature(resp, sp.IdPCerts); {
+	case err == nil:
+		responseSigned = true
+	case !errors.Is(err, errUnsigned):
+		return nil, fmt.Errorf("response signature: %w", err)
+	}
+
+	if dest, ok := resp.attr("Destination"); ok && dest != sp.ACSURL {
+		return nil, fmt.Errorf("response is for %s, not %s", dest, sp.ACSURL)
+	}
+	if irt, _ := resp.attr("InResponseTo"); irt != requestID {
+		return nil, errors.New("response does not answer this sign-in")
+	}
+	if err := sp.checkIssuer(resp); err != nil {
+		return nil, err
+	}
+	status := resp.child(protocolNS, "Status")
+	if status == nil {
+		return nil, errors.New("response has no status")
+	}
+	if code := status.child(protocolNS, "StatusCode"); code == nil {
+		return nil, errors.New("response has no status code")
+	} else if v, _ := code.attr("Value"); v != statusSuccess {
+		msg := ""
+		if m := status.child(protocolNS, "StatusMessage"); m != nil {
+			msg = ": " + m.text()
+		}
+		return nil, fmt.Errorf("IdP refused the sign-in (%s)%s", v, msg)
+	}
+
+	if resp.child(assertionNS, "EncryptedAssertion") != nil {
+		return nil, errors.New("encrypted assertions are not supported; turn assertion encryption off at the IdP")
+	}
+	assertions := resp.all(assertionNS, "Assertion")
+	if len(assertions) != 1 {
+		return nil, errors.New("response must carry exactly one assertion")
+	}
+	a := assertions[0]
+	switch err := verifySignature(a, sp.IdPCerts); {
+	case errors.Is(err, errUnsigned) && !responseSigned:
+		return nil, errors.New("neither the response nor the assertion is signed")
+	case err != nil && !errors.Is(err, errUnsigned):
+		return nil, fmt.Errorf("assertion signature: %w", err)
+	}
+	return sp.readAssertion(a, requestID, now)
+}
+
+func (sp *ServiceProvider) checkIssuer(e *element) error {
+	issuer := e.child(assertionNS, "Issuer")
+	if issuer == nil || sp.IdPEntityID == "" {
+		return nil
+	}
+	if got := issuer.text(); got != sp.IdPEntityID {
+		return fmt.Errorf("issued by %s, not %s", got, sp.IdPEntityID)
+	}
+	return nil
+}
+
+// readAssertion checks a verified assertion's subject, conditions and
+// audience, and collects its attributes
+func (sp *ServiceProvider) readAssertion(a *element, requestID string, now time.Time) (*Assertion, error) {
+	out := &Assertion{Attributes: map[string][]string{}}
+	out.ID, _ = a.attr("ID")
+	if out.ID == "" {
+		return nil, errors.New("assertion has no ID")
+	}
+	if v, _ := a.attr("Version"); v != "2.0" {
+		return nil, fmt.Errorf("unsupported SAML version %q", v)
+	}
+	if a.child(assertionNS, "Issuer") == nil {
+		return nil, errors.New("assertion has no issuer")
+	}
+	if err := sp.checkIssuer(a); err != nil {
+		return nil, err
+	}
+
+	subject := a.child(assertionNS, "Subject")
+	if subject == nil {
+		return nil, errors.New("assertion has no subject")
+	}
+	if id := subject.child(assertionNS, "NameID"); id != nil {
+		out.NameID = id.text()
+	}
+	confirmed := false
+	for _, sc := range subject.all(assertionNS, "SubjectConfirmation") {
+		if m, _ := sc.attr("Method"); m != bearerMethod {
+			continue
+		}
+		data := sc.child(assertionNS, "SubjectConfirmationData")
+		if data == nil {
+			continue
+		}
+		recipient, _ := data.attr("Recipient")
+		irt, hasIRT := data.attr("InResponseTo")
+		until, err := timeAttr(data, "NotOnOrAfter")
+		if recipient != sp.ACSURL || hasIRT && irt != requestID || err != nil || until.IsZero() || !now.Before(until.Add(MaxClockSkew)) {
+			continue
+		}
+		confirmed = true
+		out.NotOnOrAfter = until
+		break
+	}
+	if !confirmed {
+		return nil, errors.New("assertion has no valid bearer confirmation for this service")
+	}
+
+	if cond := a.child(assertionNS, "Conditions"); cond != nil {
+		notBefore, err := timeAttr(cond, "NotBefore")
+		if err != nil {
+			return nil, err
+		}
+		if !notBefore.IsZero() && now.Add(MaxClockSkew).Before(notBefore) {
+			return nil, errors.New("assertion is not valid yet; check the clocks")
+		}
+		notOnOrAfter, err := timeAttr(cond, "NotOnOrAfter")
+		if err != nil {
+			return nil, err
+		}
+		if !notOnOrAfter.IsZero() {
+			if !now.Before(notOnOrAfter.Add(MaxClockSkew)) {
+				return nil, errors.New("assertion has expired")
+			}
+			if notOnOrAfter.Before(out.NotOnOrAfter) {
+				out.NotOnOrAfter = notOnOrAfter
+			}
+		}
+		for _, ar := range cond.all(assertionNS, "AudienceRestriction") {
+			ok := false
+			for _, aud := range ar.all(assertionNS, "Audience") {
+				ok = ok || aud.text() == sp.EntityID
+			}
+			if !ok {
+				return nil, fmt.Errorf("assertion is not meant for %s", sp.EntityID)
+			}
+		}
+	}
+
+	for _, st := range a.all(assertionNS, "AttributeStatement") {
+		for _, at := range st.all(assertionNS, "Attribute") {
+			var values []string
+			for _, v := range at.all(assertionNS, "AttributeValue") {
+				values = append(values, v.text())
+			}
+			for _, key := range []string{"Name", "FriendlyName"} {
+				if name, ok := at.attr(key); ok && name != "" {
+					out.Attributes[name] = append(out.Attributes[name], values...)
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+func timeAttr(e *element, name string) (time.Time, error) {
+	v, ok := e.attr(name)
+	if !ok {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q", name, v)
+	}
+	return t, nil
+}
+
+// Username is the Sentinel username for the assertion's subject
+func (sp *ServiceProvider) Username(a *Assertion) string {
+	if sp.UsernameAttribute != "" {
+		if v := a.Attributes[sp.UsernameAttribute]; len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+	return a.NameID
+}
+
+// Role maps the role attribute's values to the most privileged matching
+// role, or DefaultRole when none match
+func (sp *ServiceProvider) Role(a *Assertion) string {
+	role := ""
+	for _, v := range a.Attributes[sp.RoleAttribute] {
+		if r, ok := sp.RoleMap[v]; ok && roleRank[r] > roleRank[role] {
+			role = r
+		}
+	}
+	if role == "" {
+		return sp.DefaultRole
+	}
+	return role
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,439 @@
+package saml
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XML Signature algorithms. Only exclusive canonicalization without comments
+// and RSA with SHA-256 or SHA-512 are accepted; that is what current IdPs
+// sign with.
+const (
+	dsigNS       = "http://www.w3.org/2000/09/xmldsig#"
+	excC14N      = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	envelopedSig = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	xmlNS        = "http://www.w3.org/XML/1998/namespace"
+)
+
+var signatureMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512": crypto.SHA512,
+}
+
+var digestMethods = map[string]crypto.Hash{
+	"http://www.w3.org/2001/04/xmlenc#sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmlenc#sha512": crypto.SHA512,
+}
+
+// errUnsigned is returned for elements without a signature
+var errUnsigned = errors.New("not signed")
+
+// element is a parsed XML element. Prefixes are kept as written, with the
+// namespace declarations of each element, because canonicalization needs
+// both; encoding/xml's own unmarshalling resolves them away.
+type element struct {
+	prefix, local string
+	ns            map[string]string // Declarations on this element; "" is the default namespace
+	attrs         []attr            // Other attributes
+	children      []any             // *element or text
+	parent        *element
+}
+
+type attr struct{ prefix, local, value string }
+
+type text string
+
+// parseXML reads a document into an element tree. Document type
+// declarations are refused, which rules out entity expansion attacks.
+func parseXML(data []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var root, cur *element
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{prefix: t.Name.Space, local: t.Name.Local, ns: map[string]string{}, parent: cur}
+			for _, a := range t.Attr {
+				switch {
+				case a.Name.Space == "" && a.Name.Local == "xmlns":
+					e.ns[""] = a.Value
+				case a.Name.Space == "xmlns":
+					e.ns[a.Name.Local] = a.Value
+				default:
+					e.attrs = append(e.attrs, attr{a.Name.Space, a.Name.Local, a.Value})
+				}
+			}
+			if _, ok := e.lookup(e.prefix); !ok {
+				return nil, fmt.Errorf("undeclared prefix %q", e.prefix)
+			}
+			for _, a := range e.attrs {
+				if _, ok := e.lookup(a.prefix); a.prefix != "" && !ok {
+					return nil, fmt.Errorf("undeclared prefix %q", a.prefix)
+				}
+			}
+			if cur == nil {
+				if root != nil {
+					return nil, errors.New("more than one root element")
+				}
+				root = e
+			} else {
+				cur.children = append(cur.children, e)
+			}
+			cur = e
+		case xml.EndElement:
+			if cur == nil || t.Name.Space != cur.prefix || t.Name.Local != cur.local {
+				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
+			}
+			cur = cur.parent
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, text(t))
+			}
+		case xml.ProcInst:
+			if cur != nil {
+				return nil, errors.New("unexpected processing instruction")
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations are not allowed")
+		}
+	}
+	if root == nil || cur != nil {
+		return nil, errors.New("incomplete document")
+	}
+	return root, nil
+}
+
+// lookup resolves a prefix in scope at e; the default namespace is ""
+// unless declared
+func (e *element) lookup(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return xmlNS, true
+	}
+	for x := e; x != nil; x = x.parent {
+		if uri, ok := x.ns[prefix]; ok {
+			return uri, true
+		}
+	}
+	return "", prefix == ""
+}
+
+// is reports whether e is the element local in namespace space
+func (e *element) is(space, local string) bool {
+	uri, _ := e.lookup(e.prefix)
+	return e.local == local && uri == space
+}
+
+// attr returns an unprefixed attribute
+func (e *element) attr(local string) (string, bool) {
+	for _, a := range e.attrs {
+		if a.prefix == "" && a.local == local {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+// all returns the child elements local in namespace space
+func (e *element) all(space, local string) []*element {
+	var out []*element
+	for _, c := range e.children {
+		if c, ok := c.(*element); ok && c.is(space, local) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// child returns the first child element local in namespace space, or nil
+func (e *element) child(space, local string) *element {
+	if all := e.all(space, local); len(all) > 0 {
+		return all[0]
+	}
+	return nil
+}
+
+// text returns e's character data, without that of its children
+func (e *element) text() string {
+	var b strings.Builder
+	for _, c := range e.children {
+		if t, ok := c.(text); ok {
+			b.WriteString(string(t))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// checkUniqueIDs refuses documents where two elements share an ID, so a
+// signature reference can only mean one element
+func checkUniqueIDs(root *element) error {
+	seen := map[string]bool{}
+	var walk func(e *element) error
+	walk = func(e *element) error {
+		if id, ok := e.attr("ID"); ok {
+			if seen[id] {
+				return fmt.Errorf("duplicate ID %q", id)
+			}
+			seen[id] = true
+		}
+		for _, c := range e.children {
+			if c, ok := c.(*element); ok {
+				if err := walk(c); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+// canonicalize renders e with Exclusive XML Canonicalization 1.0, without
+// comments. inclusive lists the InclusiveNamespaces prefixes ("" for
+// #default); skip, the enveloped signature, is left out.
+func canonicalize(e *element, inclusive []string, skip *element) []byte {
+	var buf bytes.Buffer
+	writeCanonical(&buf, e, map[string]string{}, inclusive, skip)
+	return buf.Bytes()
+}
+
+func writeCanonical(buf *bytes.Buffer, e *element, rendered map[string]string, inclusive []string, skip *element) {
+	// Namespaces are output where they are visibly used and not already in
+	// effect from an output ancestor
+	used := map[string]bool{e.prefix: true}
+	for _, a := range e.attrs {
+		if a.prefix != "" {
+			used[a.prefix] = true
+		}
+	}
+	for _, p := range inclusive {
+		if _, ok := e.lookup(p); ok {
+			used[p] = true
+		}
+	}
+
+	var decls []string
+	scope := rendered
+	for p := range used {
+		if p == "xml" {
+			continue
+		}
+		uri, _ := e.lookup(p)
+		if cur, ok := rendered[p]; ok && cur == uri || !ok && p == "" && uri == "" {
+			continue
+		}
+		if len(decls) == 0 {
+			scope = make(map[string]string, len(rendered)+1)
+			for k, v := range rendered {
+				scope[k] = v
+			}
+		}
+		decls = append(decls, p)
+		scope[p] = uri
+	}
+	sort.Strings(decls)
+
+	buf.WriteString("<" + qualified(e.prefix, e.local))
+	for _, p := range decls {
+		if p == "" {
+			buf.WriteString(` xmlns="`)
+		} else {
+			buf.WriteString(` xmlns:` + p + `="`)
+		}
+		escapeAttr(buf, scope[p])
+		buf.WriteString(`"`)
+	}
+
+	attrs := append([]attr(nil), e.attrs...)
+	space := func(a attr) string {
+		if a.prefix == "" {
+			return ""
+		}
+		uri, _ := e.lookup(a.prefix)
+		return uri
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		si, sj := space(attrs[i]), space(attrs[j])
+		if si != sj {
+			return si < sj
+		}
+		return attrs[i].local < attrs[j].local
+	})
+	for _, a := range attrs {
+		buf.WriteString(" " + qualified(a.prefix, a.local) + `="`)
+		escapeAttr(buf, a.value)
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(">")
+
+	for _, c := range e.children {
+		switch c := c.(type) {
+		case *element:
+			if c != skip {
+				writeCanonical(buf, c, scope, inclusive, skip)
+			}
+		case text:
+			escapeText(buf, string(c))
+		}
+	}
+	buf.WriteString("</" + qualified(e.prefix, e.local) + ">")
+}
+
+func qualified(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(buf *bytes.Buffer, s string) { textEscaper.WriteString(buf, s) }
+func escapeAttr(buf *bytes.Buffer, s string) { attrEscaper.WriteString(buf, s) }
+
+// inclusivePrefixes reads the InclusiveNamespaces PrefixList of a
+// canonicalization method or transform
+func inclusivePrefixes(method *element) []string {
+	in := method.child(excC14N, "InclusiveNamespaces")
+	if in == nil {
+		return nil
+	}
+	list, _ := in.attr("PrefixList")
+	var prefixes []string
+	for _, p := range strings.Fields(list) {
+		if p == "#default" {
+			p = ""
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes
+}
+
+// verifySignature checks the enveloped signature of e against the IdP's
+// certificates. Only the signature that is a direct child of e and
+// references e itself counts, so content moved around a signed element
+// (signature wrapping) is never mistaken for signed content.
+func verifySignature(e *element, certs []*x509.Certificate) error {
+	sigs := e.all(dsigNS, "Signature")
+	if len(sigs) == 0 {
+		return errUnsigned
+	}
+	if len(sigs) > 1 {
+		return errors.New("more than one signature")
+	}
+	sig := sigs[0]
+	signedInfo := sig.child(dsigNS, "SignedInfo")
+	if signedInfo == nil {
+		return errors.New("signature has no SignedInfo")
+	}
+
+	cm := signedInfo.child(dsigNS, "CanonicalizationMethod")
+	if cm == nil {
+		return errors.New("signature has no canonicalization method")
+	}
+	if alg, _ := cm.attr("Algorithm"); alg != excC14N {
+		return fmt.Errorf("unsupported canonicalization %q", alg)
+	}
+	sm := signedInfo.child(dsigNS, "SignatureMethod")
+	if sm == nil {
+		return errors.New("signature has no signature method")
+	}
+	alg, _ := sm.attr("Algorithm")
+	sigHash, ok := signatureMethods[alg]
+	if !ok {
+		return fmt.Errorf("unsupported signature method %q", alg)
+	}
+
+	refs := signedInfo.all(dsigNS, "Reference")
+	if len(refs) != 1 {
+		return errors.New("signature must have exactly one reference")
+	}
+	ref := refs[0]
+	id, _ := e.attr("ID")
+	if uri, _ := ref.attr("URI"); id == "" || uri != "#"+id {
+		return errors.New("signature does not reference the signed element")
+	}
+
+	var enveloped, exclusive bool
+	var refInclusive []string
+	if transforms := ref.child(dsigNS, "Transforms"); transforms != nil {
+		for _, t := range transforms.all(dsigNS, "Transform") {
+			switch alg, _ := t.attr("Algorithm"); alg {
+			case envelopedSig:
+				enveloped = true
+			case excC14N:
+				exclusive = true
+				refInclusive = inclusivePrefixes(t)
+			default:
+				return fmt.Errorf("unsupported transform %q", alg)
+			}
+		}
+	}
+	if !enveloped || !exclusive {
+		return errors.New("signature must use the enveloped-signature and exclusive canonicalization transforms")
+	}
+
+	dm := ref.child(dsigNS, "DigestMethod")
+	if dm == nil {
+		return errors.New("reference has no digest method")
+	}
+	dalg, _ := dm.attr("Algorithm")
+	digestHash, ok := digestMethods[dalg]
+	if !ok {
+		return fmt.Errorf("unsupported digest method %q", dalg)
+	}
+	dv := ref.child(dsigNS, "DigestValue")
+	if dv == nil {
+		return errors.New("reference has no digest")
+	}
+	want, err := decodeBase64(dv.text())
+	if err != nil {
+		return fmt.Errorf("invalid digest: %w", err)
+	}
+	h := digestHash.New()
+	h.Write(canonicalize(e, refInclusive, sig))
+	if subtle.ConstantTimeCompare(h.Sum(nil), want) != 1 {
+		return errors.New("digest mismatch: the signed content was changed")
+	}
+
+	sv := sig.child(dsigNS, "SignatureValue")
+	if sv == nil {
+		return errors.New("signature has no value")
+	}
+	value, err := decodeBase64(sv.text())
+	if err != nil {
+		return fmt.Errorf("invalid signature value: %w", err)
+	}
+	h = sigHash.New()
+	h.Write(canonicalize(signedInfo, inclusivePrefixes(cm), nil))
+	sum := h.Sum(nil)
+	for _, cert := range certs {
+		if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(pub, sigHash, sum, value) == nil {
+			return nil
+		}
+	}
+	return errors.New("signature does not match the IdP certificate")
+}
+
+// decodeBase64 decodes base64 that may be wrapped over several lines
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
@@ -0,0 +1,94 @@
+// Package sandbox keeps staging instances that run production-like rules from
+// reaching real people. With SENTINEL_SANDBOX=true every outbound
+// notification goes to a designated test destination instead of its
+// configured one, labeled as a sandbox message; a channel without a test
+// destination is dropped. Paging integrations (PagerDuty, Opsgenie),
+// federation and ticket creation don't run at all.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+// Label marks every message sent in sandbox mode
+const Label = "[SANDBOX]"
+
+// Config holds the test destinations; an empty one drops that channel
+type Config struct {
+	Email           string // SANDBOX_EMAIL: all email
+	Phone           string // SANDBOX_PHONE: all SMS
+	TelegramChatID  string // SANDBOX_TELEGRAM_CHAT_ID: Telegram forwarding
+	TeamsWebhookURL string // SANDBOX_TEAMS_WEBHOOK_URL: Teams channels
+	SlackWebhookURL string // SANDBOX_SLACK_WEBHOOK_URL: stakeholder Slack posts
+	WebhookURL      string // SANDBOX_WEBHOOK_URL: outgoing webhooks
+}
+
+// FromEnv reads the sandbox settings. ok is false unless SENTINEL_SANDBOX is
+// true; destinations that are set must be valid.
+func FromEnv() (cfg Config, ok bool, err error) {
+	if os.Getenv("SENTINEL_SANDBOX") != "true" {
+		return Config{}, false, nil
+	}
+	cfg = Config{
+		Email:           strings.TrimSpace(os.Getenv("SANDBOX_EMAIL")),
+		Phone:           strings.TrimSpace(os.Getenv("SANDBOX_PHONE")),
+		TelegramChatID:  strings.TrimSpace(os.Getenv("SANDBOX_TELEGRAM_CHAT_ID")),
+		TeamsWebhookURL: strings.TrimSpace(os.Getenv("SANDBOX_TEAMS_WEBHOOK_URL")),
+		SlackWebhookURL: strings.TrimSpace(os.Getenv("SANDBOX_SLACK_WEBHOOK_URL")),
+		WebhookURL:      strings.TrimSpace(os.Getenv("SANDBOX_WEBHOOK_URL")),
+	}
+	if cfg.Phone != "" {
+		phone, ok := models.NormalizePhone(cfg.Phone)
+		if !ok {
+			return Config{}, false, errors.New("SANDBOX_PHONE must be an E.164 number like +14155550123")
+		}
+		cfg.Phone = phone
+	}
+	if cfg.Email != "" {
+		if _, err := mail.ParseAddress(cfg.Email); err != nil {
+			return Config{}, false, fmt.Errorf("SANDBOX_EMAIL: %w", err)
+		}
+	}
+	for name, u := range map[string]string{
+		"SANDBOX_TEAMS_WEBHOOK_URL": cfg.TeamsWebhookURL,
+		"SANDBOX_SLACK_WEBHOOK_URL": cfg.SlackWebhookURL,
+		"SANDBOX_WEBHOOK_URL":       cfg.WebhookURL,
+	} {
+		if u == "" {
+			continue
+		}
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return Config{}, false, errors.New(name + " must be an absolute http(s) URL")
+		}
+	}
+	return cfg, true, nil
+}
+
+// Describe lists where each channel goes, for the startup log
+func (c Config) Describe() string {
+	dest := func(v string) string {
+		if v == "" {
+			return "dropped"
+		}
+		return v
+	}
+	return fmt.Sprintf("email -> %s, sms -> %s, telegram -> %s, teams -> %s, slack -> %s, webhooks -> %s",
+		dest(c.Email), dest(c.Phone), dest(c.TelegramChatID), dest(redact(c.TeamsWebhookURL)),
+		dest(redact(c.SlackWebhookURL)), dest(redact(c.WebhookURL)))
+}
+
+// redact keeps the host of a webhook URL; the path is usually the secret
+func redact(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || u == "" {
+		return u
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/…"
+}
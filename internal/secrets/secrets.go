@@ -0,0 +1,226 @@
+// Package secrets encrypts credentials before they are stored, such as the
+// API keys of outbound integrations, TOTP secrets and bot tokens. Each value
+// is encrypted with AES-256-GCM under its own random data key, which is in
+// turn encrypted (wrapped) with the key from the environment and stored
+// alongside it. The wrapping key can be rotated: values are tagged with the
+// key they were wrapped with, and previous keys stay usable for reading.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The stored formats. v1 encrypted values with the key itself; v2 is
+// "v2:{key id}:{wrapped data key}:{ciphertext}", all base64 but the ID.
+const (
+	prefix   = "v1:"
+	prefixV2 = "v2:"
+)
+
+// ErrNoKey is returned when secrets need storing but no key is configured
+var ErrNoKey = errors.New("SENTINEL_ENCRYPTION_KEY is not set: can't store credentials")
+
+// ErrNoKeyToOpen is returned when a stored value is encrypted but no key is
+// configured to decrypt it
+var ErrNoKeyToOpen = errors.New("SENTINEL_ENCRYPTION_KEY is not set: can't decrypt stored credentials")
+
+// key is a key-encryption key, known by an ID derived from it
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+func newKey(k []byte) (*key, error) {
+	if len(k) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(k))
+	}
+	aead, err := newAEAD(k)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(k)
+	return &key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAEAD(k []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type Box struct {
+	current *key
+	keys    []*key // current first, then previous keys
+}
+
+// New returns a Box that seals with a 32-byte key and opens with it or any
+// of the previous keys
+func New(current []byte, previous ...[]byte) (*Box, error) {
+	k, err := newKey(current)
+	if err != nil {
+		return nil, err
+	}
+	b := &Box{current: k, keys: []*key{k}}
+	for _, p := range previous {
+		pk, err := newKey(p)
+		if err != nil {
+			return nil, fmt.Errorf("previous key: %w", err)
+		}
+		b.keys = append(b.keys, pk)
+	}
+	return b, nil
+}
+
+// FromEnv reads SENTINEL_ENCRYPTION_KEY, 32 bytes as base64 or hex (e.g.
+// from `openssl rand -base64 32`), and SENTINEL_ENCRYPTION_KEY_PREVIOUS,
+// comma separated keys that were replaced but may still have encrypted
+// stored values. ok is false when no key is set.
+func FromEnv() (box *Box, ok bool, err error) {
+	v := strings.TrimSpace(os.Getenv("SENTINEL_ENCRYPTION_KEY"))
+	if v == "" {
+		return nil, false, nil
+	}
+	current, err := decodeKey(v)
+	if err != nil {
+		return nil, false, fmt.Errorf("SENTINEL_ENCRYPTION_KEY %w", err)
+	}
+	var previous [][]byte
+	for _, p := range strings.Split(os.Getenv("SENTINEL_ENCRYPTION_KEY_PREVIOUS"), ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		k, err := decodeKey(p)
+		if err != nil {
+			return nil, false, fmt.Errorf("SENTINEL_ENCRYPTION_KEY_PREVIOUS %w", err)
+		}
+		previous = append(previous, k)
+	}
+	box, err = New(current, previous...)
+	return box, err == nil, err
+}
+
+func decodeKey(v string) ([]byte, error) {
+	k, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(k) != 32 {
+		if k, err = hex.DecodeString(v); err != nil || len(k) != 32 {
+			return nil, errors.New("must be 32 bytes, base64 or hex encoded")
+		}
+	}
+	return k, nil
+}
+
+// IsSealed reports whether stored was made by Seal, rather than being a
+// value stored before encryption was set up
+func IsSealed(stored string) bool {
+	return strings.HasPrefix(stored, prefix) || strings.HasPrefix(stored, prefixV2)
+}
+
+// Seal encrypts plaintext into a string safe to store in a text column
+func (b *Box) Seal(plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(b.current.aead, dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return prefixV2 + b.current.id + ":" + base64.StdEncoding.EncodeToString(wrapped) + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// Open decrypts a string made by Seal, with any of the box's keys
+func (b *Box) Open(stored string) (string, error) {
+	if encoded, ok := strings.CutPrefix(stored, prefix); ok {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", err
+		}
+		for _, k := range b.keys {
+			if plaintext, err := open(k.aead, data); err == nil {
+				return string(plaintext), nil
+			}
+		}
+		return "", errors.New("can't decrypt secret: wrong SENTINEL_ENCRYPTION_KEY?")
+	}
+
+	rest, ok := strings.CutPrefix(stored, prefixV2)
+	if !ok {
+		return "", errors.New("unknown secret format")
+	}
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return "", errors.New("unknown secret format")
+	}
+	var k *key
+	for _, candidate := range b.keys {
+		if candidate.id == parts[0] {
+			k = candidate
+		}
+	}
+	if k == nil {
+		return "", fmt.Errorf("can't decrypt secret: it was encrypted with key %s, which is neither SENTINEL_ENCRYPTION_KEY nor in SENTINEL_ENCRYPTION_KEY_PREVIOUS", parts[0])
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := open(k.aead, wrapped)
+	if err != nil {
+		return "", errors.New("can't decrypt secret: wrong SENTINEL_ENCRYPTION_KEY?")
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, ciphertext)
+	if err != nil {
+		return "", errors.New("can't decrypt secret: it is corrupted")
+	}
+	return string(plaintext), nil
+}
+
+// Current reports whether stored is sealed in the current format under the
+// current key, so re-encrypting it would change nothing
+func (b *Box) Current(stored string) bool {
+	return strings.HasPrefix(stored, prefixV2+b.current.id+":")
+}
+
+// seal encrypts with a random nonce, which goes in front of the ciphertext
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	n := aead.NonceSize()
+	if len(data) < n {
+		return nil, errors.New("secret is truncated")
+	}
+	return aead.Open(nil, data[:n], data[n:], nil)
+}
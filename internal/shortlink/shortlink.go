@@ -0,0 +1,52 @@
+// Package shortlink makes short /a/{code} links to dashboard pages, for
+// messages where a full URL is unwieldy (SMS, Telegram). Codes live in Redis;
+// /a/{code} only ever redirects within the dashboard.
+package shortlink
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"incident-viewer-go/internal/store"
+)
+
+// PathPrefix is where short links are served
+const PathPrefix = "/a/"
+
+type Shortener struct {
+	store     store.AlertStore
+	publicURL string
+}
+
+// New shortens links under publicURL (SENTINEL_PUBLIC_URL); without it
+// there is nothing to link to and every link is empty
+func New(s store.AlertStore, publicURL string) *Shortener {
+	return &Shortener{store: s, publicURL: strings.TrimRight(publicURL, "/")}
+}
+
+// AlertURL links to an alert in the dashboard
+func (s *Shortener) AlertURL(ctx context.Context, alertID int) string {
+	return s.URL(ctx, fmt.Sprintf("/?alert=%d", alertID))
+}
+
+// URL returns a short link to path (which must start with "/"), the full
+// URL when shortening fails, or "" without a public URL
+func (s *Shortener) URL(ctx context.Context, path string) string {
+	if s == nil || s.publicURL == "" {
+		return ""
+	}
+	code, err := s.store.CreateShortLink(ctx, path)
+	if err != nil {
+		log.Printf("shortlink: failed to shorten %s: %v", path, err)
+		return s.publicURL + path
+	}
+	return s.publicURL + PathPrefix + code
+}
+
+// Valid reports whether path is safe to redirect to: local to the dashboard,
+// never another host
+func Valid(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.Contains(path, "\\")
+}
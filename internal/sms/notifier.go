@@ -0,0 +1,253 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/floodcontrol"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/msgtemplate"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/shortlink"
+	"incident-viewer-go/internal/store"
+)
+
+// Limits cap how many messages go out per hour, in total and per user.
+// Pages over a cap are dropped (and logged), not queued.
+type Limits struct {
+	PerHour        int
+	PerUserPerHour int
+}
+
+// LimitsFromEnv reads SMS_MAX_PER_HOUR (default 30) and
+// SMS_MAX_PER_USER_PER_HOUR (default 5)
+func LimitsFromEnv() Limits {
+	l := Limits{PerHour: 30, PerUserPerHour: 5}
+	if n, err := strconv.Atoi(os.Getenv("SMS_MAX_PER_HOUR")); err == nil && n > 0 {
+		l.PerHour = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("SMS_MAX_PER_USER_PER_HOUR")); err == nil && n > 0 {
+		l.PerUserPerHour = n
+	}
+	return l
+}
+
+type Notifier struct {
+	alerts   store.AlertStore // Hourly counters, shared between instances
+	admin    store.AdminStore
+	provider Provider
+	limits   Limits
+	links    *shortlink.Shortener
+
+	mu    sync.Mutex
+	paged map[int]bool // Open critical alerts that were already paged
+
+	queue     *notifyqueue.Queue    // nil sends once, without retries
+	templates *msgtemplate.Renderer // nil sends the built-in page
+	throttle  *floodcontrol.Limiter // nil pages for every critical alert
+}
+
+// queueKind names SMS pages in the notification queue
+const queueKind = "sms"
+
+// queuedPage is a page waiting in the notification queue. The number is
+// looked up on delivery, so a user who opts out in the meantime isn't paged.
+type queuedPage struct {
+	UserID int    `json:"user_id"`
+	Text   string `json:"text"`
+}
+
+func (p queuedPage) NotificationTarget() string { return fmt.Sprintf("user %d", p.UserID) }
+
+// NewNotifier pages opted-in users; links adds a short link to the alert
+// when a public URL is configured
+func NewNotifier(alerts store.AlertStore, admin store.AdminStore, provider Provider, limits Limits, links *shortlink.Shortener) *Notifier {
+	return &Notifier{
+		alerts:   alerts,
+		admin:    admin,
+		provider: provider,
+		limits:   limits,
+		links:    links,
+		paged:    make(map[int]bool),
+	}
+}
+
+// Queue sends pages through q, which retries failed sends and keeps the ones
+// that keep failing for inspection
+func (n *Notifier) Queue(q *notifyqueue.Queue) {
+	n.queue = q
+	q.Register(queueKind, n.deliver)
+}
+
+// Templates lets an admin-defined SMS template replace the built-in page
+func (n *Notifier) Templates(r *msgtemplate.Renderer) {
+	n.templates = r
+}
+
+// Throttle holds back pages about an alert whose check already notified within
+// l's window
+func (n *Notifier) Throttle(l *floodcontrol.Limiter) {
+	n.throttle = l
+}
+
+// Run pages critical alerts until ch is closed
+func (n *Notifier) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		n.Notify(ctx, a)
+	}
+}
+
+// Notify pages every opted-in user who can see a, once per critical alert:
+// updates, repeats and the resolution don't cost another message
+func (n *Notifier) Notify(ctx context.Context, a models.Alert) {
+	if !n.firstPage(a) {
+		return
+	}
+
+	prefs, err := n.admin.GetEnabledSMSPreferences(ctx)
+	if err != nil {
+		log.Printf("sms: failed to load preferences: %v", err)
+		return
+	}
+	link := n.links.AlertURL(ctx, a.ID)
+	body := n.templates.Message(ctx, models.ChannelSMS, msgtemplate.Data{Alert: a, Headline: "Incident opened", Link: link}, n.body(a, link))
+	allowed := false
+	for _, p := range prefs {
+		if !n.canSee(ctx, p.UserID, a) {
+			continue
+		}
+		if !allowed {
+			if !n.throttle.Allow(ctx, floodcontrol.ChannelSMS, a) {
+				return
+			}
+			allowed = true
+		}
+		if !n.allow(ctx, p.UserID) {
+			continue
+		}
+		if err := n.queue.Send(ctx, queueKind, queuedPage{UserID: p.UserID, Text: body}, n.deliver); err != nil {
+			log.Printf("sms: failed to page user %d about alert %d: %v", p.UserID, a.ID, err)
+		}
+	}
+}
+
+func (n *Notifier) deliver(ctx context.Context, payload json.RawMessage) error {
+	var p queuedPage
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return notifyqueue.Permanent(err)
+	}
+	pref, err := n.admin.GetSMSPreference(ctx, p.UserID)
+	if err != nil || !pref.Enabled {
+		return nil // Opted out (or gone) since
+	}
+	return n.provider.Send(ctx, pref.Phone, p.Text)
+}
+
+func (n *Notifier) firstPage(a models.Alert) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if a.Status == models.AlertStatusResolved {
+		delete(n.paged, a.ID)
+		return false
+	}
+	if a.Level != models.LevelCritical || n.paged[a.ID] {
+		return false
+	}
+	if a.Status != "" {
+		n.paged[a.ID] = true
+	}
+	return true
+}
+
+// canSee applies the dashboard's rule: admins and developers see every chat,
+// other users the chats assigned to them
+func (n *Notifier) canSee(ctx context.Context, userID int, a models.Alert) bool {
+	u, err := n.admin.GetUser(ctx, userID)
+	if err != nil {
+		return false
+	}
+	if u.Role == "admin" || u.Role == "developer" {
+		return true
+	}
+	chatID := models.ChatIDFromSource(a.Source)
+	if chatID == "" {
+		return false
+	}
+	chats, err := n.admin.GetUserChats(ctx, userID)
+	if err != nil {
+		return false
+	}
+	for _, c := range chats {
+		if c.ChatID == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// allow counts a message against the hourly caps, user first so a user over
+// their own cap doesn't use up everyone else's budget
+func (n *Notifier) allow(ctx context.Context, userID int) bool {
+	count, err := n.alerts.IncrSMSCount(ctx, fmt.Sprintf("user:%d", userID))
+	if err != nil {
+		log.Printf("sms: failed to count messages: %v", err)
+		return false
+	}
+	if count > n.limits.PerUserPerHour {
+		if count == n.limits.PerUserPerHour+1 {
+			log.Printf("sms: user %d reached %d messages this hour; dropping further pages", userID, n.limits.PerUserPerHour)
+		}
+		return false
+	}
+
+	count, err = n.alerts.IncrSMSCount(ctx, "all")
+	if err != nil {
+		log.Printf("sms: failed to count messages: %v", err)
+		return false
+	}
+	if count > n.limits.PerHour {
+		if count == n.limits.PerHour+1 {
+			log.Printf("sms: reached %d messages this hour; dropping further pages", n.limits.PerHour)
+		}
+		return false
+	}
+	return true
+}
+
+// body fits a page in two SMS segments, cutting the title and source rather
+// than the link
+func (n *Notifier) body(a models.Alert, link string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[CRITICAL] %s", truncate(a.Title, 160))
+	if a.Source != "" {
+		fmt.Fprintf(&b, " (%s)", truncate(a.Source, 60))
+	}
+	if link != "" {
+		b.WriteString(" " + link)
+	}
+	return b.String()
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,174 @@
+// Package sms pages users by text message for critical alerts, through
+// Twilio or any SMS gateway that accepts a JSON webhook, with hourly caps to
+// keep the bill predictable.
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/sandbox"
+)
+
+// Provider sends one text message to an E.164 number
+type Provider interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// ProviderFromEnv picks the provider from SMS_PROVIDER ("twilio" or
+// "webhook"); without it, Twilio is used when TWILIO_ACCOUNT_SID is set and
+// the webhook when SMS_WEBHOOK_URL is. ok is false when neither is configured.
+func ProviderFromEnv() (p Provider, ok bool, err error) {
+	name := os.Getenv("SMS_PROVIDER")
+	if name == "" {
+		switch {
+		case os.Getenv("TWILIO_ACCOUNT_SID") != "":
+			name = "twilio"
+		case os.Getenv("SMS_WEBHOOK_URL") != "":
+			name = "webhook"
+		default:
+			return nil, false, nil
+		}
+	}
+
+	switch name {
+	case "twilio":
+		t := NewTwilio(os.Getenv("TWILIO_ACCOUNT_SID"), os.Getenv("TWILIO_AUTH_TOKEN"), os.Getenv("TWILIO_FROM"))
+		if t.accountSID == "" || t.authToken == "" || t.from == "" {
+			return nil, false, fmt.Errorf("twilio needs TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM")
+		}
+		return t, true, nil
+	case "webhook":
+		u := os.Getenv("SMS_WEBHOOK_URL")
+		if u == "" {
+			return nil, false, fmt.Errorf("webhook provider needs SMS_WEBHOOK_URL")
+		}
+		return NewWebhook(u, os.Getenv("SMS_WEBHOOK_TOKEN")), true, nil
+	}
+	return nil, false, fmt.Errorf("unknown SMS_PROVIDER %q", name)
+}
+
+const twilioAPI = "https://api.twilio.com/2010-04-01"
+
+// Twilio sends through the Programmable Messaging API
+type Twilio struct {
+	accountSID string
+	authToken  string
+	from       string // Sender number, or a Messaging Service SID (MG...)
+	http       *http.Client
+}
+
+func NewTwilio(accountSID, authToken, from string) *Twilio {
+	return &Twilio{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		http:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (t *Twilio) Send(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if strings.HasPrefix(t.from, "MG") {
+		form.Set("MessagingServiceSid", t.from)
+	} else {
+		form.Set("From", t.from)
+	}
+
+	endpoint := twilioAPI + "/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&apiErr)
+		return gatewayError(resp.StatusCode, fmt.Errorf("twilio returned %s: %d %s", resp.Status, apiErr.Code, apiErr.Message))
+	}
+	return nil
+}
+
+// Webhook hands messages to any other gateway as
+// {"to": "+14155550123", "body": "..."}, with the token as a bearer token
+type Webhook struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+func NewWebhook(url, token string) *Webhook {
+	return &Webhook{url: url, token: token, http: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (w *Webhook) Send(ctx context.Context, to, body string) error {
+	payload, err := json.Marshal(map[string]string{"to": to, "body": body})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return gatewayError(resp.StatusCode, fmt.Errorf("sms webhook returned %s", resp.Status))
+	}
+	return nil
+}
+
+// gatewayError marks client errors (invalid number, bad credentials) as not
+// worth retrying; timeouts, rate limits and server errors are
+func gatewayError(status int, err error) error {
+	if status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests {
+		return notifyqueue.Permanent(err)
+	}
+	return err
+}
+
+// Sandboxed wraps p so every message goes to phone instead, labeled with the
+// number it was meant for; an empty phone drops messages
+func Sandboxed(p Provider, phone string) Provider {
+	return sandboxed{provider: p, phone: phone}
+}
+
+type sandboxed struct {
+	provider Provider
+	phone    string
+}
+
+func (s sandboxed) Send(ctx context.Context, to, body string) error {
+	if s.phone == "" {
+		log.Printf("sms: sandbox: dropped message for %s", to)
+		return nil
+	}
+	return s.provider.Send(ctx, s.phone, fmt.Sprintf("%s for %s: %s", sandbox.Label, to, body))
+}
@@ -0,0 +1,352 @@
+// Package stakeholders keeps stakeholder lists informed about high-severity
+// incidents by email and Slack: one update when an incident opens, when its
+// level changes and when it resolves, without the responder noise in between.
+package stakeholders
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/email"
+	"incident-viewer-go/internal/floodcontrol"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/msgtemplate"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/sandbox"
+	"incident-viewer-go/internal/store"
+)
+
+type Notifier struct {
+	admin     store.AdminStore
+	mailer    *email.Sender // nil disables email
+	publicURL string
+	http      *http.Client
+
+	mu       sync.Mutex
+	notified map[int]string // Alert ID -> level stakeholders last heard about
+
+	sandboxed  bool
+	sandboxURL string // Where sandboxed Slack posts go; empty drops them
+
+	queue     *notifyqueue.Queue    // nil posts to Slack once, without retries
+	templates *msgtemplate.Renderer // nil sends the built-in updates
+	throttle  *floodcontrol.Limiter // nil sends every update
+}
+
+// queueKind names stakeholder Slack posts in the notification queue
+const queueKind = "stakeholder_slack"
+
+// queuedPost is a Slack update waiting in the notification queue. Webhook
+// URLs are credentials, so it names the list and a hash of the URL instead.
+type queuedPost struct {
+	ListID  int    `json:"list_id"`
+	Webhook string `json:"webhook"` // webhookRef of the URL; "sandbox" in sandbox mode
+	Text    string `json:"text"`
+}
+
+func (p queuedPost) NotificationTarget() string {
+	return fmt.Sprintf("list %d, webhook %s", p.ListID, p.Webhook)
+}
+
+// webhookRef identifies a Slack webhook URL without revealing it
+func webhookRef(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return hex.EncodeToString(sum[:6])
+}
+
+// NewNotifier sends updates for the lists in admin; publicURL
+// (SENTINEL_PUBLIC_URL) adds a link to the alert when set
+func NewNotifier(admin store.AdminStore, mailer *email.Sender, publicURL string) *Notifier {
+	return &Notifier{
+		admin:     admin,
+		mailer:    mailer,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		http:      &http.Client{Timeout: 10 * time.Second},
+		notified:  make(map[int]string),
+	}
+}
+
+// Sandbox posts Slack updates to url instead of the lists' webhooks,
+// labeled; an empty url drops them. Email is sandboxed by the mailer.
+func (n *Notifier) Sandbox(url string) {
+	n.sandboxed = true
+	n.sandboxURL = url
+}
+
+// Queue sends Slack updates through q, which retries failed posts and keeps
+// the ones that keep failing for inspection. Email is queued by the mailer.
+func (n *Notifier) Queue(q *notifyqueue.Queue) {
+	n.queue = q
+	q.Register(queueKind, n.deliver)
+}
+
+// Templates lets admin-defined email and Slack templates replace the
+// built-in updates
+func (n *Notifier) Templates(r *msgtemplate.Renderer) {
+	n.templates = r
+}
+
+// Throttle holds back updates about an alert whose check already notified within
+// l's window
+func (n *Notifier) Throttle(l *floodcontrol.Limiter) {
+	n.throttle = l
+}
+
+// Run sends stakeholder updates for alert lifecycle events until ch is closed
+func (n *Notifier) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		n.Notify(ctx, a)
+	}
+}
+
+// Notify sends an update to every list following a, if its status or level
+// changed since the last update
+func (n *Notifier) Notify(ctx context.Context, a models.Alert) {
+	headline, ok := n.transition(a)
+	if !ok {
+		return
+	}
+
+	lists, err := n.admin.GetStakeholderLists(ctx)
+	if err != nil {
+		log.Printf("stakeholders: failed to load lists: %v", err)
+		return
+	}
+
+	emailSubject, emailBody, slackText := n.render(ctx, a, headline)
+	allowed := false
+	for _, l := range lists {
+		if !l.Matches(a) {
+			continue
+		}
+		if !allowed {
+			if !n.throttle.Allow(ctx, floodcontrol.ChannelStakeholders, a) {
+				return
+			}
+			allowed = true
+		}
+		if len(l.Emails) > 0 {
+			if n.mailer == nil {
+				log.Printf("stakeholders: %s has email recipients but SMTP_HOST is not set", l.Name)
+			} else if err := n.mailer.SendQueued(ctx, email.Message{To: l.Emails, Subject: emailSubject, Text: emailBody}); err != nil {
+				log.Printf("stakeholders: failed to email %s about alert %d: %v", l.Name, a.ID, err)
+			}
+		}
+		if n.sandboxed && len(l.SlackWebhooks) > 0 {
+			if n.sandboxURL == "" {
+				log.Printf("stakeholders: sandbox: dropped Slack update for %s about alert %d", l.Name, a.ID)
+				continue
+			}
+			text := fmt.Sprintf("%s originally for %s\n%s", sandbox.Label, l.Name, slackText)
+			if err := n.queue.Send(ctx, queueKind, queuedPost{ListID: l.ID, Webhook: "sandbox", Text: text}, n.deliver); err != nil {
+				log.Printf("stakeholders: failed to post sandbox Slack update for %s about alert %d: %v", l.Name, a.ID, err)
+			}
+			continue
+		}
+		for _, u := range l.SlackWebhooks {
+			if err := n.queue.Send(ctx, queueKind, queuedPost{ListID: l.ID, Webhook: webhookRef(u), Text: slackText}, n.deliver); err != nil {
+				log.Printf("stakeholders: failed to post to Slack for %s about alert %d: %v", l.Name, a.ID, err)
+			}
+		}
+	}
+}
+
+// render builds the email and the Slack text of an update
+func (n *Notifier) render(ctx context.Context, a models.Alert, headline string) (emailSubject, emailBody, slackText string) {
+	subject := fmt.Sprintf("[%s] %s: %s", strings.ToUpper(a.Level), headline, a.Title)
+	body := n.body(a, headline)
+	emailSubject, emailBody = subject, body
+	if subj, text, ok := n.templates.Render(ctx, models.ChannelEmail, msgtemplate.Data{Alert: a, Headline: headline}); ok {
+		emailSubject, emailBody = cmp.Or(subj, subject), text
+	}
+	slackText = "*" + subject + "*\n" + body
+	if subj, text, ok := n.templates.Render(ctx, models.ChannelSlack, msgtemplate.Data{Alert: a, Headline: headline}); ok {
+		slackText = text
+		if subj != "" {
+			slackText = "*" + subj + "*\n" + text
+		}
+	}
+	return emailSubject, emailBody, slackText
+}
+
+// Targets lists the stakeholder lists by ID, for channel tests
+func (n *Notifier) Targets(ctx context.Context) ([]string, error) {
+	lists, err := n.admin.GetStakeholderLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(lists))
+	for _, l := range lists {
+		ids = append(ids, strconv.Itoa(l.ID))
+	}
+	return ids, nil
+}
+
+// Test sends a as an update to the list's emails and Slack webhooks right
+// away, whatever its filters, and returns what failed instead of queueing.
+// Sandbox mode applies as to any other update.
+func (n *Notifier) Test(ctx context.Context, target string, a models.Alert) error {
+	lists, err := n.admin.GetStakeholderLists(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(lists, func(l models.StakeholderList) bool { return strconv.Itoa(l.ID) == target })
+	if i < 0 {
+		return fmt.Errorf("unknown stakeholder list %q", target)
+	}
+	l := lists[i]
+	emailSubject, emailBody, slackText := n.render(ctx, a, "Test")
+
+	var errs []error
+	if len(l.Emails) > 0 {
+		if n.mailer == nil {
+			errs = append(errs, errors.New("email: SMTP_HOST is not set"))
+		} else if err := n.mailer.Send(email.Message{To: l.Emails, Subject: emailSubject, Text: emailBody}); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if n.sandboxed && len(l.SlackWebhooks) > 0 {
+		if n.sandboxURL == "" {
+			return errors.Join(append(errs, errors.New("sandbox mode drops Slack updates: set SANDBOX_SLACK_WEBHOOK_URL"))...)
+		}
+		if err := n.postSlack(ctx, n.sandboxURL, fmt.Sprintf("%s originally for %s\n%s", sandbox.Label, l.Name, slackText)); err != nil {
+			errs = append(errs, fmt.Errorf("slack: %w", err))
+		}
+		return errors.Join(errs...)
+	}
+	for _, u := range l.SlackWebhooks {
+		if err := n.postSlack(ctx, u, slackText); err != nil {
+			errs = append(errs, fmt.Errorf("slack webhook %s: %w", webhookRef(u), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// transition reports what changed for stakeholders since their last update,
+// if anything: repeats and cosmetic updates of an open incident aren't news
+func (n *Notifier) transition(a models.Alert) (string, bool) {
+	high := models.LevelRank(a.Level) >= models.LevelRank(models.LevelError)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	last, seen := n.notified[a.ID]
+	switch {
+	case a.Status == models.AlertStatusResolved:
+		delete(n.notified, a.ID)
+		return "Resolved", seen || high
+	case a.Status == "":
+		// One-shot alert without a lifecycle: there won't be further updates
+		return "Incident opened", high
+	case !seen && !high:
+		return "", false
+	}
+
+	n.notified[a.ID] = a.Level
+	switch {
+	case !seen:
+		return "Incident opened", true
+	case models.LevelRank(a.Level) > models.LevelRank(last):
+		return "Escalated to " + a.Level, true
+	case models.LevelRank(a.Level) < models.LevelRank(last):
+		return "Downgraded to " + a.Level, true
+	}
+	return "", false
+}
+
+func (n *Notifier) body(a models.Alert, headline string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", headline)
+	fmt.Fprintf(&b, "Level: %s\nSource: %s\nStarted: %s\n", a.Level, a.Source, a.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	if a.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved: %s\n", a.ResolvedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	if c := a.Roles[models.RoleCommander]; c != "" {
+		fmt.Fprintf(&b, "Incident commander: %s\n", c)
+	}
+	if a.Message != "" {
+		fmt.Fprintf(&b, "\n%s\n", a.Message)
+	}
+	if n.publicURL != "" {
+		fmt.Fprintf(&b, "\n%s/?alert=%d\n", n.publicURL, a.ID)
+	}
+	return b.String()
+}
+
+// deliver posts a queued update to the list's webhook, if the list still has it
+func (n *Notifier) deliver(ctx context.Context, payload json.RawMessage) error {
+	var p queuedPost
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return notifyqueue.Permanent(err)
+	}
+	if p.Webhook == "sandbox" {
+		if n.sandboxURL == "" {
+			return nil
+		}
+		return n.postSlack(ctx, n.sandboxURL, p.Text)
+	}
+
+	lists, err := n.admin.GetStakeholderLists(ctx)
+	if err != nil {
+		return err
+	}
+	for _, l := range lists {
+		if l.ID != p.ListID {
+			continue
+		}
+		for _, u := range l.SlackWebhooks {
+			if webhookRef(u) == p.Webhook {
+				return n.postSlack(ctx, u, p.Text)
+			}
+		}
+	}
+	return notifyqueue.Permanent(fmt.Errorf("list %d no longer has webhook %s", p.ListID, p.Webhook))
+}
+
+func (n *Notifier) postSlack(ctx context.Context, webhookURL, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		err := fmt.Errorf("slack returned %s", resp.Status)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return notifyqueue.Permanent(err) // Revoked webhook, archived channel, ...
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Key words (lowercased, singular) whose values never belong in the audit
+// trail. A key is split into words at '_', '-' and camelCase, and matches when
+// one of its words, or its singular, does: "api_key_encrypted", "tokens" and
+// "signingSecret" match, "author" and "tokenizer" don't.
+var sensitiveWords = map[string]bool{
+	"password": true, "passwd": true, "passphrase": true, "secret": true, "token": true,
+	"apikey": true, "privatekey": true, "credential": true,
+	"authorization": true, "cookie": true, "session": true, "signature": true,
+	"totp": true, "otp": true, "p256dh": true, "auth": true,
+}
+
+// Word pairs that name a credential together but not apart ("api" "key")
+var sensitivePairs = map[[2]string]bool{
+	{"api", "key"}: true, {"private", "key"}: true, {"recovery", "code"}: true, {"backup", "code"}: true,
+}
+
+// Keys that are only sensitive as a whole ("code" is a 2FA code, "key" a
+// credential; "status_code" and "flag_key" are not)
+var sensitiveKeys = map[string]bool{"key": true, "code": true}
+
+// Last words of keys that describe a secret without holding it, like
+// "secret_changed" or "session_count"
+var describingWords = map[string]bool{"changed": true, "count": true, "set": true, "configured": true}
+
+// keyWords splits a key into lowercase words at '_', '-', '.', spaces and
+// lower-to-upper case changes
+func keyWords(key string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	prevLower := false
+	for _, r := range key {
+		switch {
+		case r == '_' || r == '-' || r == '.' || unicode.IsSpace(r):
+			flush()
+			prevLower = false
+			continue
+		case unicode.IsUpper(r) && prevLower:
+			flush()
+		}
+		word = append(word, r)
+		prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+	}
+	flush()
+	return words
+}
+
+// singular drops a plural 's': "tokens" is "token", "address" stays
+func singular(word string) string {
+	if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func isSensitiveKey(key string) bool {
+	words := keyWords(key)
+	for i, w := range words {
+		words[i] = singular(w)
+	}
+	if len(words) == 0 {
+		return false
+	}
+	if len(words) == 1 && sensitiveKeys[words[0]] {
+		return true
+	}
+	if len(words) > 1 && describingWords[words[len(words)-1]] {
+		return false
+	}
+	for i, w := range words {
+		if sensitiveWords[w] {
+			return true
+		}
+		if i > 0 && sensitivePairs[[2]string{words[i-1], w}] {
+			return true
+		}
+	}
+	return false
+}
+
+// SanitizeAuditMetadata masks the values of secret-looking fields (tokens,
+// passwords, push keys, ...) anywhere in a JSON metadata blob, whole and
+// whatever their type, and records how many were hidden in "redacted". Keys
+// that describe a secret without holding it, like "secret_changed", are kept.
+// Input that isn't a JSON object is returned unchanged.
+func SanitizeAuditMetadata(metadata string) string {
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(metadata), &obj); err != nil {
+		return metadata
+	}
+	n := redactSecrets(obj)
+	if n == 0 {
+		return metadata
+	}
+	obj["redacted"] = n
+	out, err := json.Marshal(obj)
+	if err != nil {
+		return "{}"
+	}
+	return string(out)
+}
+
+// redactSecrets masks sensitive values in place and returns how many it masked
+func redactSecrets(v any) int {
+	n := 0
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if (val != nil && val != "" && isSensitiveKey(k)) || isBearer(val) {
+				v[k] = redactedValue
+				n++
+				continue
+			}
+			n += redactSecrets(val)
+		}
+	case []any:
+		for i, item := range v {
+			if isBearer(item) {
+				v[i] = redactedValue
+				n++
+				continue
+			}
+			n += redactSecrets(item)
+		}
+	}
+	return n
+}
+
+// isBearer catches credentials pasted into free-text values
+func isBearer(v any) bool {
+	s, ok := v.(string)
+	return ok && strings.HasPrefix(strings.ToLower(s), "bearer ")
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSanitizeAuditMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata string
+		want     string
+	}{
+		{
+			name:     "nothing sensitive",
+			metadata: `{"name":"ci","count":3}`,
+			want:     `{"name":"ci","count":3}`,
+		},
+		{
+			name:     "top-level secrets",
+			metadata: `{"name":"ci","password":"hunter2","signing_secret":"s3","apiKey":"k"}`,
+			want:     `{"name":"ci","password":"[REDACTED]","signing_secret":"[REDACTED]","apiKey":"[REDACTED]","redacted":3}`,
+		},
+		{
+			name:     "nested maps",
+			metadata: `{"subscription":{"endpoint":"https://push.example","keys":{"p256dh":"abc","auth":"def"}}}`,
+			want:     `{"subscription":{"endpoint":"https://push.example","keys":"[REDACTED]"},"redacted":1}`,
+		},
+		{
+			name:     "deeply nested maps",
+			metadata: `{"webhook":{"url":"https://hooks.example","config":{"signing_secret":"s","retries":3}}}`,
+			want:     `{"webhook":{"url":"https://hooks.example","config":{"signing_secret":"[REDACTED]","retries":3}},"redacted":1}`,
+		},
+		{
+			name:     "arrays",
+			metadata: `{"headers":[{"name":"x","token":"t"},"Bearer abc.def"],"emails":["a@example.com"]}`,
+			want:     `{"headers":[{"name":"x","token":"[REDACTED]"},"[REDACTED]"],"emails":["a@example.com"],"redacted":2}`,
+		},
+		{
+			name:     "describing keys are kept",
+			metadata: `{"session_count":4,"secret_changed":true,"api_key_changed":false,"token_count":2,"password":null}`,
+			want:     `{"session_count":4,"secret_changed":true,"api_key_changed":false,"token_count":2,"password":null}`,
+		},
+		{
+			name:     "sensitive keys are masked whatever the type",
+			metadata: `{"token":["abc","def"],"secret":{"value":"x"},"code":123456,"session":7}`,
+			want:     `{"token":"[REDACTED]","secret":"[REDACTED]","code":"[REDACTED]","session":"[REDACTED]","redacted":4}`,
+		},
+		{
+			name:     "plural keys",
+			metadata: `{"tokens":["a"],"passwords":"p","recovery_codes":["1","2"],"credentials":{"user":"u"}}`,
+			want:     `{"tokens":"[REDACTED]","passwords":"[REDACTED]","recovery_codes":"[REDACTED]","credentials":"[REDACTED]","redacted":4}`,
+		},
+		{
+			name:     "empty strings are kept",
+			metadata: `{"token":""}`,
+			want:     `{"token":""}`,
+		},
+		{
+			name:     "secret-looking keys that aren't",
+			metadata: `{"author":"alice","tokenizer":"bpe","flag":"new-ui","flag_key":"new-ui","status_code":"502","keyboard":"us","secret_changed":"yes"}`,
+			want:     `{"author":"alice","tokenizer":"bpe","flag":"new-ui","flag_key":"new-ui","status_code":"502","keyboard":"us","secret_changed":"yes"}`,
+		},
+		{
+			name:     "whole-key words",
+			metadata: `{"key":"abc","code":"123456","otp":"654321"}`,
+			want:     `{"key":"[REDACTED]","code":"[REDACTED]","otp":"[REDACTED]","redacted":3}`,
+		},
+		{
+			name:     "not an object",
+			metadata: `["password"]`,
+			want:     `["password"]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SanitizeAuditMetadata(tt.metadata)
+			var gotObj, wantObj any
+			if err := json.Unmarshal([]byte(got), &gotObj); err != nil {
+				t.Fatalf("SanitizeAuditMetadata returned invalid JSON %q: %v", got, err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &wantObj); err != nil {
+				t.Fatalf("bad want %q: %v", tt.want, err)
+			}
+			if !reflect.DeepEqual(gotObj, wantObj) {
+				t.Errorf("SanitizeAuditMetadata(%s) = %s, want %s", tt.metadata, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSensitiveKey(t *testing.T) {
+	tests := map[string]bool{
+		"password":             true,
+		"new_password":         true,
+		"signingSecret":        true,
+		"X-Sentinel-Signature": true,
+		"api_key":              true,
+		"APIKey":               true,
+		"private-key":          true,
+		"session_id":           true,
+		"Authorization":        true,
+		"key":                  true,
+		"tokens":               true,
+		"passwords":            true,
+		"recovery_codes":       true,
+		"BackupCodes":          true,
+		"sessions":             true,
+		"session_count":        false,
+		"address":              false,
+		"secret_changed":       false,
+		"token_count":          false,
+		"author":               false,
+		"tokenizer":            false,
+		"flag":                 false,
+		"monkey":               false,
+		"encoded":              false,
+		"status_code":          false,
+		"":                     false,
+	}
+	for key, want := range tests {
+		if got := isSensitiveKey(key); got != want {
+			t.Errorf("isSensitiveKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// What happens to a source or label over the cardinality limits
+const (
+	OverflowDrop      = "drop"      // Leave it out: the source isn't indexed, the label is removed
+	OverflowHash      = "hash"      // Shorten long values to a prefix and hash; spread extra sources over a fixed set of buckets
+	OverflowAggregate = "aggregate" // Fold it into one shared overflow source or label
+)
+
+const (
+	// sourceRegistryKey scores every source with its own index set by when
+	// it was last used, to count the distinct sources still within alertTTL
+	sourceRegistryKey = "alerts:sources"
+
+	// OverflowKey names the shared overflow source index and label
+	OverflowKey = "_overflow"
+
+	overflowBuckets = 64 // Shared source indexes under the hash policy
+	hashLength      = 12 // Hex characters of the hash kept in place of a value
+)
+
+// CardinalityLimits bound what a single alert can add to Redis: the source
+// index sets (alerts:source:*) and the labels stored on the alert. Sources
+// over a limit still show on the alert; only their index changes, and
+// searching by them filters a shared index. Zero disables a limit.
+type CardinalityLimits struct {
+	MaxSources      int    // Distinct sources with their own index set
+	MaxSourceLength int    // Bytes
+	MaxLabels       int    // Per alert
+	MaxLabelLength  int    // Bytes, of a key or a value
+	Policy          string // OverflowDrop, OverflowHash or OverflowAggregate
+}
+
+// CardinalityLimitsFromEnv reads ALERT_MAX_SOURCES (default 10000),
+// ALERT_MAX_SOURCE_LENGTH (default 256), ALERT_MAX_LABELS (default 32),
+// ALERT_MAX_LABEL_LENGTH (default 1024) and ALERT_OVERFLOW_POLICY (drop, hash
+// or aggregate, the default)
+func CardinalityLimitsFromEnv() (CardinalityLimits, error) {
+	l := CardinalityLimits{
+		MaxSources:      10000,
+		MaxSourceLength: 256,
+		MaxLabels:       32,
+		MaxLabelLength:  1024,
+		Policy:          OverflowAggregate,
+	}
+	for name, dst := range map[string]*int{
+		"ALERT_MAX_SOURCES":       &l.MaxSources,
+		"ALERT_MAX_SOURCE_LENGTH": &l.MaxSourceLength,
+		"ALERT_MAX_LABELS":        &l.MaxLabels,
+		"ALERT_MAX_LABEL_LENGTH":  &l.MaxLabelLength,
+	} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return CardinalityLimits{}, fmt.Errorf("%s must be a non-negative number (0 = no limit)", name)
+		}
+		*dst = n
+	}
+	if l.MaxSourceLength > 0 && l.MaxSourceLength <= hashLength+1 {
+		return CardinalityLimits{}, fmt.Errorf("ALERT_MAX_SOURCE_LENGTH must be more than %d", hashLength+1)
+	}
+	if l.MaxLabelLength > 0 && l.MaxLabelLength <= hashLength+1 {
+		return CardinalityLimits{}, fmt.Errorf("ALERT_MAX_LABEL_LENGTH must be more than %d", hashLength+1)
+	}
+	if v := os.Getenv("ALERT_OVERFLOW_POLICY"); v != "" {
+		l.Policy = strings.ToLower(v)
+	}
+	switch l.Policy {
+	case OverflowDrop, OverflowHash, OverflowAggregate:
+	default:
+		return CardinalityLimits{}, fmt.Errorf("ALERT_OVERFLOW_POLICY must be %s, %s or %s", OverflowDrop, OverflowHash, OverflowAggregate)
+	}
+	return l, nil
+}
+
+// SetCardinalityLimits applies l to alerts stored from now on
+func (s *RedisStore) SetCardinalityLimits(l CardinalityLimits) {
+	s.limits = l
+}
+
+func sourceKey(source string) string {
+	return "alerts:source:" + strings.ToLower(source)
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
+
+// sourceOverflowWarned keeps the "limit reached" log to once per process
+var sourceOverflowWarned atomic.Bool
+
+// sourceIndex picks the index set for a new alert from source, registering
+// the source when it gets a set of its own. "" means it isn't indexed.
+func (s *RedisStore) sourceIndex(ctx context.Context, source string) string {
+	if source == "" {
+		return ""
+	}
+	source = strings.ToLower(source)
+	l := s.limits
+	if l.MaxSourceLength > 0 && len(source) > l.MaxSourceLength {
+		return l.longSourceIndex(source)
+	}
+	if l.MaxSources <= 0 {
+		return sourceKey(source)
+	}
+
+	now := time.Now()
+	member := redis.Z{Score: float64(now.Unix()), Member: source}
+	if _, err := s.client.ZScore(ctx, sourceRegistryKey, source).Result(); err == nil {
+		s.client.ZAdd(ctx, sourceRegistryKey, member)
+		return sourceKey(source)
+	}
+	pipe := s.client.Pipeline()
+	pipe.ZRemRangeByScore(ctx, sourceRegistryKey, "-inf", strconv.FormatInt(now.Add(-alertTTL).Unix(), 10))
+	count := pipe.ZCard(ctx, sourceRegistryKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return sourceKey(source) // Fail open: an index too many beats a lost one
+	}
+	if count.Val() >= int64(l.MaxSources) {
+		if sourceOverflowWarned.CompareAndSwap(false, true) {
+			log.Printf("store: %d distinct alert sources indexed; applying the %s policy to new ones", l.MaxSources, l.Policy)
+		}
+		return l.extraSourceIndex(source)
+	}
+	s.client.ZAdd(ctx, sourceRegistryKey, member)
+	return sourceKey(source)
+}
+
+// sourceLookup finds the index set alerts from source were put in, for
+// searches and removals. Sources indexed before the limits applied still
+// have their own set.
+func (s *RedisStore) sourceLookup(ctx context.Context, source string) string {
+	if source == "" {
+		return ""
+	}
+	source = strings.ToLower(source)
+	l := s.limits
+	if l.MaxSourceLength > 0 && len(source) > l.MaxSourceLength {
+		return l.longSourceIndex(source)
+	}
+	if l.MaxSources <= 0 {
+		return sourceKey(source)
+	}
+	if n, err := s.client.Exists(ctx, sourceKey(source)).Result(); err != nil || n > 0 {
+		return sourceKey(source)
+	}
+	return l.extraSourceIndex(source)
+}
+
+// longSourceIndex is the index for a source over MaxSourceLength
+func (l CardinalityLimits) longSourceIndex(source string) string {
+	switch l.Policy {
+	case OverflowDrop:
+		return ""
+	case OverflowHash:
+		return sourceKey(cut(source, l.MaxSourceLength-hashLength-1) + "#" + shortHash(source))
+	}
+	return sourceKey(OverflowKey)
+}
+
+// extraSourceIndex is the index for a new source once MaxSources have one
+func (l CardinalityLimits) extraSourceIndex(source string) string {
+	switch l.Policy {
+	case OverflowDrop:
+		return ""
+	case OverflowHash:
+		sum := sha256.Sum256([]byte(source))
+		return sourceKey(fmt.Sprintf("%s:%02d", OverflowKey, int(sum[0])%overflowBuckets))
+	}
+	return sourceKey(OverflowKey)
+}
+
+// guardLabels applies the label limits. Keys are kept in sorted order; with
+// the hash or aggregate policy the ones that don't fit share a last
+// OverflowKey label (their hash, or "k=v, ..." cut to MaxLabelLength).
+func (l CardinalityLimits) guardLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 || (l.MaxLabels <= 0 && l.MaxLabelLength <= 0) {
+		return labels
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var kept, extra []string
+	values := make(map[string]string, len(labels))
+	for _, k := range keys {
+		v := labels[k]
+		switch {
+		case k == OverflowKey:
+			extra = append(extra, v) // An earlier overflow, folded in again
+			continue
+		case l.MaxLabelLength > 0 && len(k) > l.MaxLabelLength:
+			extra = append(extra, k+"="+v)
+			continue
+		case l.MaxLabelLength > 0 && len(v) > l.MaxLabelLength:
+			switch l.Policy {
+			case OverflowDrop:
+				continue
+			case OverflowHash:
+				v = cut(v, l.MaxLabelLength-hashLength-1) + "#" + shortHash(v)
+			default:
+				v = truncateBytes(v, l.MaxLabelLength)
+			}
+		}
+		kept = append(kept, k)
+		values[k] = v
+	}
+
+	if l.MaxLabels > 0 {
+		room := l.MaxLabels
+		if l.Policy != OverflowDrop && (len(extra) > 0 || len(kept) > room) {
+			room-- // For the overflow label
+		}
+		if len(kept) > room {
+			for _, k := range kept[room:] {
+				extra = append(extra, k+"="+values[k])
+			}
+			kept = kept[:room]
+		}
+	}
+
+	guarded := make(map[string]string, len(kept)+1)
+	for _, k := range kept {
+		guarded[k] = values[k]
+	}
+	if len(extra) == 0 || l.Policy == OverflowDrop {
+		return guarded
+	}
+	joined := strings.Join(extra, ", ")
+	switch {
+	case l.Policy == OverflowHash:
+		guarded[OverflowKey] = shortHash(joined)
+	case l.MaxLabelLength > 0:
+		guarded[OverflowKey] = truncateBytes(joined, l.MaxLabelLength)
+	default:
+		guarded[OverflowKey] = joined
+	}
+	return guarded
+}
+
+// cut returns the longest prefix of s up to n bytes that doesn't split a
+// character
+func cut(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// truncateBytes is cut with an ellipsis when s is too long
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return cut(s, n-len("…")) + "…"
+}
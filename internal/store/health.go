@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// healthInterval is how often MonitorHealth pings the database
+const healthInterval = 10 * time.Second
+
+// lastGood keeps the last successful result of a lookup per key
+type lastGood[T any] struct {
+	mu   sync.Mutex
+	vals map[string]T
+}
+
+func (c *lastGood[T]) get(key string) (T, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.vals[key]
+	return v, ok
+}
+
+func (c *lastGood[T]) put(key string, v T) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.vals == nil {
+		c.vals = map[string]T{}
+	}
+	c.vals[key] = v
+}
+
+func (c *lastGood[T]) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.vals, key)
+}
+
+// lastGoodLookup runs fetch and remembers its result under key. When fetch
+// fails, or the database is known to be down, the last result is returned
+// instead, so lookups that were answered before an outage keep being
+// answered during it. sql.ErrNoRows is an answer too: it drops the key, so
+// deleted users and revoked tokens stop working once the database has said so.
+func lastGoodLookup[T any](s *PostgresStore, c *lastGood[T], key string, fetch func() (T, error)) (T, error) {
+	if !s.Available() {
+		if v, ok := c.get(key); ok {
+			return v, nil
+		}
+	}
+	v, err := fetch()
+	switch {
+	case err == nil:
+		c.put(key, v)
+	case err == sql.ErrNoRows:
+		c.forget(key)
+	default:
+		if last, ok := c.get(key); ok {
+			return last, nil
+		}
+	}
+	return v, err
+}
+
+// Available reports whether the database answered the last ping
+func (s *PostgresStore) Available() bool {
+	return !s.down.Load()
+}
+
+// MonitorHealth pings the database until ctx is done, so Available follows
+// outages. On the first recovery after starting without a database it runs
+// the migrations that were skipped and then recovered, e.g. to create the
+// initial admin.
+func (s *PostgresStore) MonitorHealth(ctx context.Context, recovered func(context.Context)) {
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		wasUp := s.Available()
+		pingCtx, cancel := context.WithTimeout(ctx, healthInterval/2)
+		err := s.Ping(pingCtx)
+		cancel()
+		switch {
+		case err != nil && wasUp:
+			log.Printf("Database unreachable, admin features disabled: %v", err)
+		case err == nil && !wasUp:
+			log.Println("Database reachable again, admin features enabled")
+		}
+		if err != nil || s.migrated.Load() {
+			continue
+		}
+
+		if err := s.RunMigrations(ctx); err != nil {
+			log.Printf("Failed to run migrations: %v", err)
+			continue
+		}
+		log.Println("Database migrations completed")
+		if recovered != nil {
+			recovered(ctx)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+)
+
+const (
+	muteRulesKey  = "mute_rules" // Hash of rule ID -> JSON
+	muteRuleIDKey = "mute_rules:next_id"
+	// muteHitsKey counts each rule's drops: n|{id} is the count and at|{id}
+	// the unix time of the last one
+	muteHitsKey = "mute_rules:hits"
+)
+
+// ErrMuteRuleNotFound is returned for unknown mute rule IDs
+var ErrMuteRuleNotFound = errors.New("mute rule not found")
+
+// CreateMuteRule stores a new rule; it drops alerts from the next ingested one
+func (s *RedisStore) CreateMuteRule(ctx context.Context, r models.MuteRule) (models.MuteRule, error) {
+	id, err := s.client.Incr(ctx, muteRuleIDKey).Result()
+	if err != nil {
+		return models.MuteRule{}, err
+	}
+	r.ID = int(id)
+	r.CreatedAt = time.Now().UTC()
+	r.Hits, r.LastHitAt = 0, nil
+	return r, s.putMuteRule(ctx, r)
+}
+
+// UpdateMuteRule replaces an existing rule, keeping who created it, when, and
+// its hit counter
+func (s *RedisStore) UpdateMuteRule(ctx context.Context, r models.MuteRule) (models.MuteRule, error) {
+	rules, err := s.GetMuteRules(ctx)
+	if err != nil {
+		return models.MuteRule{}, err
+	}
+	for _, cur := range rules {
+		if cur.ID == r.ID {
+			r.CreatedBy, r.CreatedAt = cur.CreatedBy, cur.CreatedAt
+			r.Hits, r.LastHitAt = cur.Hits, cur.LastHitAt
+			return r, s.putMuteRule(ctx, r)
+		}
+	}
+	return models.MuteRule{}, ErrMuteRuleNotFound
+}
+
+func (s *RedisStore) putMuteRule(ctx context.Context, r models.MuteRule) error {
+	r.Hits, r.LastHitAt = 0, nil // Kept in muteHitsKey
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, muteRulesKey, strconv.Itoa(r.ID), data).Err()
+}
+
+// DeleteMuteRule removes a rule along with its hit counter
+func (s *RedisStore) DeleteMuteRule(ctx context.Context, id int) error {
+	n, err := s.client.HDel(ctx, muteRulesKey, strconv.Itoa(id)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrMuteRuleNotFound
+	}
+	s.client.HDel(ctx, muteHitsKey, fmt.Sprintf("n|%d", id), fmt.Sprintf("at|%d", id))
+	return nil
+}
+
+// GetMuteRules lists the mute rules with their hit counters, oldest first,
+// which is the order they are checked in
+func (s *RedisStore) GetMuteRules(ctx context.Context) ([]models.MuteRule, error) {
+	pipe := s.client.Pipeline()
+	rulesCmd := pipe.HGetAll(ctx, muteRulesKey)
+	hitsCmd := pipe.HGetAll(ctx, muteHitsKey)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	hits := hitsCmd.Val()
+
+	rules := []models.MuteRule{}
+	for _, val := range rulesCmd.Val() {
+		var r models.MuteRule
+		if err := json.Unmarshal([]byte(val), &r); err != nil {
+			continue
+		}
+		r.Hits, _ = strconv.ParseInt(hits[fmt.Sprintf("n|%d", r.ID)], 10, 64)
+		if at, err := strconv.ParseInt(hits[fmt.Sprintf("at|%d", r.ID)], 10, 64); err == nil {
+			t := time.Unix(at, 0).UTC()
+			r.LastHitAt = &t
+		}
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+// muted reports whether an enabled mute rule drops a, and counts the hit on
+// the first one that does. A failure to read the rules is logged and keeps
+// the alert: dropping one by mistake is worse than letting noise through.
+func (s *RedisStore) muted(ctx context.Context, a models.Alert) bool {
+	vals, err := s.client.HGetAll(ctx, muteRulesKey).Result()
+	if err != nil {
+		fmt.Println("Failed to get mute rules:", err)
+		return false
+	}
+	if len(vals) == 0 {
+		return false
+	}
+
+	rules := make([]models.MuteRule, 0, len(vals))
+	for _, val := range vals {
+		var r models.MuteRule
+		if err := json.Unmarshal([]byte(val), &r); err == nil && r.Enabled {
+			rules = append(rules, r)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+
+	a.Source = models.NormalizeSource(a.Source)
+	for _, r := range rules {
+		if !r.Matches(a) {
+			continue
+		}
+		pipe := s.client.Pipeline()
+		pipe.HIncrBy(ctx, muteHitsKey, fmt.Sprintf("n|%d", r.ID), 1)
+		pipe.HSet(ctx, muteHitsKey, fmt.Sprintf("at|%d", r.ID), time.Now().Unix())
+		if _, err := pipe.Exec(ctx); err != nil {
+			fmt.Println("Failed to count mute rule hit:", err)
+		}
+		return true
+	}
+	return false
+}
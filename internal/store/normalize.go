@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+)
+
+// sourceMigrationKey marks NormalizeSources as done for alerts stored before
+// sources were normalized on the way in
+const sourceMigrationKey = "migrations:normalize_sources"
+
+// MigrateSources runs NormalizeSources once per Redis database; later calls
+// return straight away
+func (s *RedisStore) MigrateSources(ctx context.Context) (int, error) {
+	if n, err := s.client.Exists(ctx, sourceMigrationKey).Result(); err != nil || n > 0 {
+		return 0, err
+	}
+	count, err := s.NormalizeSources(ctx)
+	if err != nil {
+		return count, err
+	}
+	return count, s.client.Set(ctx, sourceMigrationKey, 1, 0).Err()
+}
+
+// NormalizeSources rewrites stored alerts whose source isn't in normalized
+// form: the record gets the normalized source and its bot/chat fields and
+// moves from the old source index set to the new one. The timeline is walked
+// in batches of purgeBatchSize; returns how many alerts were rewritten.
+func (s *RedisStore) NormalizeSources(ctx context.Context) (int, error) {
+	total := 0
+	var cursor uint64
+	for {
+		// ZSCAN returns member/score pairs
+		pairs, next, err := s.client.ZScan(ctx, "alerts:timeline", cursor, "", purgeBatchSize).Result()
+		if err != nil {
+			return total, err
+		}
+		for i := 0; i < len(pairs); i += 2 {
+			changed, err := s.normalizeSource(ctx, pairs[i])
+			if err != nil {
+				return total, err
+			}
+			if changed {
+				total++
+			}
+		}
+		if next == 0 {
+			if total > 0 {
+				s.rebuildRollup(ctx)
+			}
+			return total, nil
+		}
+		cursor = next
+	}
+}
+
+// normalizeSource rewrites one alert record (keeping its TTL) and its index
+// entry. Expired records are skipped.
+func (s *RedisStore) normalizeSource(ctx context.Context, key string) (bool, error) {
+	var old models.Alert
+	changed := false
+	rewrite := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if err != nil {
+			return err
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(val), &a); err != nil {
+			return nil // Not ours to fix
+		}
+		source := models.NormalizeSource(a.Source)
+		bot, chatID := models.ParseSource(source)
+		if source == a.Source && bot == a.Bot && chatID == a.ChatID {
+			return nil
+		}
+		old = a
+		a.Source, a.Bot, a.ChatID = source, bot, chatID
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		changed = err == nil
+		return err
+	}
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		// Retry if the alert changed under us
+		if err = s.client.Watch(ctx, rewrite, key); !errors.Is(err, redis.TxFailedErr) {
+			break
+		}
+	}
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil || !changed || old.Source == models.NormalizeSource(old.Source) {
+		return changed, err
+	}
+
+	// Move the index entry
+	from := s.sourceLookup(ctx, old.Source)
+	to := s.sourceIndex(ctx, models.NormalizeSource(old.Source))
+	if from == to {
+		return true, nil
+	}
+	pipe := s.client.Pipeline()
+	if from != "" {
+		pipe.SRem(ctx, from, key)
+	}
+	if to != "" {
+		pipe.SAdd(ctx, to, key)
+		pipe.Expire(ctx, to, alertTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return true, err
+}
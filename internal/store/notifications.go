@@ -0,0 +1,235 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+)
+
+// Outbound notification queue: jobs wait in a stream read by a consumer
+// group, failed ones wait in a sorted set scored by their next attempt, and
+// the ones that keep failing end up on a capped dead-letter list
+const (
+	notifyStreamKey = "notify:queue"
+	notifyGroup     = "notifiers"
+	notifyRetryKey  = "notify:retry"
+	notifyDeadKey   = "notify:dead"
+
+	// MaxDeadLetters caps the dead-letter list; the oldest entries are dropped
+	MaxDeadLetters = 1000
+
+	// notifyClaimIdle is how long a job can sit unacknowledged with a
+	// consumer before another one takes it over (the first one crashed)
+	notifyClaimIdle = 5 * time.Minute
+)
+
+// ErrNotificationNotFound is returned for unknown dead-letter IDs
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// promoteScript moves a due retry back onto the stream, once: only the
+// replica whose ZREM succeeds re-adds it
+var promoteScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 1 then
+	redis.call('XADD', KEYS[2], '*', 'job', ARGV[1])
+	return 1
+end
+return 0
+`)
+
+// EnqueueNotification adds a job to the queue
+func (s *RedisStore) EnqueueNotification(ctx context.Context, job models.NotificationJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{Stream: notifyStreamKey, Values: map[string]any{"job": data}}).Err()
+}
+
+// ReadNotifications hands consumer up to count jobs: first ones abandoned by
+// a consumer that went away, then new ones, waiting up to block for those
+func (s *RedisStore) ReadNotifications(ctx context.Context, consumer string, count int, block time.Duration) ([]models.NotificationJob, error) {
+	claimed, _, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   notifyStreamKey,
+		Group:    notifyGroup,
+		Consumer: consumer,
+		MinIdle:  notifyClaimIdle,
+		Start:    "0-0",
+		Count:    int64(count),
+	}).Result()
+	if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+		// First run: set up the stream and group, then read as usual
+		err = s.client.XGroupCreateMkStream(ctx, notifyStreamKey, notifyGroup, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+	if len(claimed) > 0 {
+		return decodeNotifications(claimed), nil
+	}
+
+	streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    notifyGroup,
+		Consumer: consumer,
+		Streams:  []string{notifyStreamKey, ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var jobs []models.NotificationJob
+	for _, st := range streams {
+		jobs = append(jobs, decodeNotifications(st.Messages)...)
+	}
+	return jobs, nil
+}
+
+// AckNotification removes a delivered job from the queue
+func (s *RedisStore) AckNotification(ctx context.Context, job models.NotificationJob) error {
+	pipe := s.client.TxPipeline()
+	pipe.XAck(ctx, notifyStreamKey, notifyGroup, job.StreamID)
+	pipe.XDel(ctx, notifyStreamKey, job.StreamID)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RetryNotification takes a failed job off the queue until at
+func (s *RedisStore) RetryNotification(ctx context.Context, job models.NotificationJob, at time.Time) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.ZAdd(ctx, notifyRetryKey, redis.Z{Score: float64(at.Unix()), Member: data})
+	pipe.XAck(ctx, notifyStreamKey, notifyGroup, job.StreamID)
+	pipe.XDel(ctx, notifyStreamKey, job.StreamID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// DeadLetterNotification takes a job that keeps failing off the queue and
+// keeps it for inspection
+func (s *RedisStore) DeadLetterNotification(ctx context.Context, job models.NotificationJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	pipe := s.client.TxPipeline()
+	pipe.LPush(ctx, notifyDeadKey, data)
+	pipe.LTrim(ctx, notifyDeadKey, 0, MaxDeadLetters-1)
+	pipe.XAck(ctx, notifyStreamKey, notifyGroup, job.StreamID)
+	pipe.XDel(ctx, notifyStreamKey, job.StreamID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// PromoteNotifications puts retries that are due by now back on the queue
+func (s *RedisStore) PromoteNotifications(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.client.ZRangeByScore(ctx, notifyRetryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	promoted := 0
+	for _, member := range due {
+		n, err := promoteScript.Run(ctx, s.client, []string{notifyRetryKey, notifyStreamKey}, member).Int()
+		if err != nil {
+			return promoted, err
+		}
+		promoted += n
+	}
+	return promoted, nil
+}
+
+func (s *RedisStore) GetNotificationQueueStats(ctx context.Context) (models.NotificationQueueStats, error) {
+	pipe := s.client.Pipeline()
+	queued := pipe.XLen(ctx, notifyStreamKey)
+	retrying := pipe.ZCard(ctx, notifyRetryKey)
+	dead := pipe.LLen(ctx, notifyDeadKey)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return models.NotificationQueueStats{}, err
+	}
+	return models.NotificationQueueStats{Queued: queued.Val(), Retrying: retrying.Val(), DeadLetter: dead.Val()}, nil
+}
+
+// GetDeadLetters lists up to limit dead-lettered jobs, newest first
+func (s *RedisStore) GetDeadLetters(ctx context.Context, limit int) ([]models.NotificationJob, error) {
+	raw, err := s.client.LRange(ctx, notifyDeadKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]models.NotificationJob, 0, len(raw))
+	for _, r := range raw {
+		var job models.NotificationJob
+		if err := json.Unmarshal([]byte(r), &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RequeueDeadLetter moves a dead-lettered job back onto the queue with a
+// fresh set of attempts
+func (s *RedisStore) RequeueDeadLetter(ctx context.Context, id string) error {
+	raw, err := s.client.LRange(ctx, notifyDeadKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for _, r := range raw {
+		var job models.NotificationJob
+		if err := json.Unmarshal([]byte(r), &job); err != nil || job.ID != id {
+			continue
+		}
+		removed, err := s.client.LRem(ctx, notifyDeadKey, 1, r).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return ErrNotificationNotFound // Requeued concurrently
+		}
+		job.Attempts, job.LastError, job.FailedAt = 0, "", nil
+		return s.EnqueueNotification(ctx, job)
+	}
+	return ErrNotificationNotFound
+}
+
+// ClearDeadLetters drops every dead-lettered job and returns how many
+func (s *RedisStore) ClearDeadLetters(ctx context.Context) (int, error) {
+	pipe := s.client.TxPipeline()
+	n := pipe.LLen(ctx, notifyDeadKey)
+	pipe.Del(ctx, notifyDeadKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(n.Val()), nil
+}
+
+func decodeNotifications(msgs []redis.XMessage) []models.NotificationJob {
+	jobs := make([]models.NotificationJob, 0, len(msgs))
+	for _, m := range msgs {
+		data, _ := m.Values["job"].(string)
+		var job models.NotificationJob
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			job = models.NotificationJob{Kind: "invalid", LastError: "undecodable job"}
+		}
+		job.StreamID = m.ID
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
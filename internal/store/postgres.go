@@ -7,8 +7,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"incident-viewer-go/internal/clientinfo"
 	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/requestid"
+	"incident-viewer-go/internal/secrets"
 
 	_ "github.com/lib/pq"
 )
@@ -17,24 +25,105 @@ import (
 var schemaSQL string
 
 type PostgresStore struct {
-	db *sql.DB
+	db      *sql.DB
+	replica *sql.DB      // Optional read replica for dashboard listings; nil reads from db
+	secrets *secrets.Box // Encrypts stored credentials; nil refuses to store them
+
+	down     atomic.Bool // The last ping failed
+	migrated atomic.Bool // RunMigrations has succeeded
+
+	// Last results of the lookups behind viewing and ingesting alerts,
+	// served while the database is down
+	users        lastGood[models.User]
+	userChats    lastGood[[]models.Chat]
+	chats        lastGood[[]models.Chat]
+	bots         lastGood[models.Bot]
+	botChats     lastGood[[]models.Chat]
+	exemptions   lastGood[[]models.SignatureExemption]
+	ingestTokens lastGood[models.IngestToken]
+	apiKeys      lastGood[models.APIKey]
 }
 
 func NewPostgresStore(databaseURL string) (*PostgresStore, error) {
+	s, err := OpenPostgresStore(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return s, nil
+}
+
+// OpenPostgresStore is NewPostgresStore without the initial ping, for a
+// start while the database is unreachable. Connections are made on first use.
+func OpenPostgresStore(databaseURL string) (*PostgresStore, error) {
 	db, err := sql.Open("postgres", databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	return &PostgresStore{db: db}, nil
+}
 
+// UseReadReplica routes read-only listing queries (users, bots, chats, audit
+// log, ...) to a replica. Lookups that back authentication and permission
+// checks stay on the primary so they never see stale data.
+func (s *PostgresStore) UseReadReplica(replicaURL string) error {
+	db, err := sql.Open("postgres", replicaURL)
+	if err != nil {
+		return fmt.Errorf("failed to open read replica: %w", err)
+	}
 	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+		db.Close()
+		return fmt.Errorf("failed to ping read replica: %w", err)
+	}
+	s.replica = db
+	return nil
+}
+
+// UseEncryption encrypts credentials (such as Opsgenie API keys, TOTP
+// secrets and bot tokens) with box before they are stored
+func (s *PostgresStore) UseEncryption(box *secrets.Box) {
+	s.secrets = box
+}
+
+// sealOptional encrypts credentials that work without an encryption key,
+// like TOTP secrets and bot tokens, when there is one; without it they are
+// stored as they are
+func (s *PostgresStore) sealOptional(plaintext string) (string, error) {
+	if plaintext == "" || s.secrets == nil {
+		return plaintext, nil
 	}
+	return s.secrets.Seal(plaintext)
+}
 
-	return &PostgresStore{db: db}, nil
+// openOptional decrypts a value stored by sealOptional; values stored
+// without encryption are returned as they are
+func (s *PostgresStore) openOptional(stored string) (string, error) {
+	if !secrets.IsSealed(stored) {
+		return stored, nil
+	}
+	if s.secrets == nil {
+		return "", secrets.ErrNoKeyToOpen
+	}
+	return s.secrets.Open(stored)
 }
 
+// reader is the connection for queries that tolerate replication lag
+func (s *PostgresStore) reader() *sql.DB {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.db
+}
+
+// Ping checks the primary and records the result for Available
 func (s *PostgresStore) Ping(ctx context.Context) error {
-	return s.db.PingContext(ctx)
+	err := s.db.PingContext(ctx)
+	s.down.Store(err != nil)
+	return err
 }
 
 // RunMigrations creates tables if they don't exist and applies schema updates
@@ -51,6 +140,7 @@ func (s *PostgresStore) RunMigrations(ctx context.Context) error {
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(255);`,
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN DEFAULT FALSE;`,
 		`ALTER TABLE users ADD COLUMN IF NOT EXISTS last_password_change TIMESTAMP WITH TIME ZONE DEFAULT NOW();`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255);`,
 		`CREATE TABLE IF NOT EXISTS audit_logs (
 			id SERIAL PRIMARY KEY,
 			actor_id INT,
@@ -70,12 +160,14 @@ func (s *PostgresStore) RunMigrations(ctx context.Context) error {
 		}
 	}
 
+	s.migrated.Store(true)
 	return nil
 }
 
 // User methods
 
-func (s *PostgresStore) CreateUser(ctx context.Context, username, password, role string) (models.User, error) {
+// CreateUser adds a user to the organization orgID
+func (s *PostgresStore) CreateUser(ctx context.Context, username, password, role string, orgID int) (models.User, error) {
 	passwordHash, err := models.HashPassword(password)
 	if err != nil {
 		return models.User{}, err
@@ -83,11 +175,11 @@ func (s *PostgresStore) CreateUser(ctx context.Context, username, password, role
 
 	var user models.User
 	err = s.db.QueryRowContext(ctx,
-		`INSERT INTO users (username, password_hash, role, created_at) 
-		 VALUES ($1, $2, $3, NOW()) 
-		 RETURNING id, username, password_hash, role, created_at`,
-		username, passwordHash, role,
-	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.CreatedAt)
+		`INSERT INTO users (username, password_hash, role, org_id, created_at) 
+		 VALUES ($1, $2, $3, $4, NOW()) 
+		 RETURNING id, username, password_hash, role, org_id, created_at`,
+		username, passwordHash, role, orgID,
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.OrgID, &user.CreatedAt)
 
 	if err != nil {
 		return models.User{}, err
@@ -96,25 +188,34 @@ func (s *PostgresStore) CreateUser(ctx context.Context, username, password, role
 	return user, nil
 }
 
+// GetUser backs session checks, so while the database is down it answers
+// from the last successful lookup
 func (s *PostgresStore) GetUser(ctx context.Context, id int) (models.User, error) {
+	user, err := lastGoodLookup(s, &s.users, strconv.Itoa(id), func() (models.User, error) { return s.getUser(ctx, id) })
+	if err == sql.ErrNoRows {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, err
+}
+
+func (s *PostgresStore) getUser(ctx context.Context, id int) (models.User, error) {
 	var user models.User
 	var totpSecret sql.NullString
 	var lastPasswordChange sql.NullTime
 
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, username, password_hash, role, totp_secret, totp_enabled, last_password_change, created_at FROM users WHERE id = $1`,
+		`SELECT id, username, password_hash, role, totp_secret, totp_enabled, last_password_change, COALESCE(email, ''), org_id, saml_linked_at IS NOT NULL, created_at FROM users WHERE id = $1`,
 		id,
-	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &totpSecret, &user.TOTPEnabled, &lastPasswordChange, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &totpSecret, &user.TOTPEnabled, &lastPasswordChange, &user.Email, &user.OrgID, &user.SAMLLinked, &user.CreatedAt)
 
-	if err == sql.ErrNoRows {
-		return models.User{}, errors.New("user not found")
-	}
 	if err != nil {
 		return models.User{}, err
 	}
 
-	if totpSecret.Valid {
-		user.TOTPSecret = totpSecret.String
+	// A secret that can't be decrypted fails the lookup rather than leave
+	// 2FA checking codes against an empty secret
+	if user.TOTPSecret, err = s.openOptional(totpSecret.String); err != nil {
+		return models.User{}, fmt.Errorf("user %d: TOTP secret: %w", user.ID, err)
 	}
 	if lastPasswordChange.Valid {
 		user.LastPasswordChange = lastPasswordChange.Time
@@ -129,19 +230,21 @@ func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string)
 	var lastPasswordChange sql.NullTime
 
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, username, password_hash, role, totp_secret, totp_enabled, last_password_change, created_at FROM users WHERE username = $1`,
+		`SELECT id, username, password_hash, role, totp_secret, totp_enabled, last_password_change, COALESCE(email, ''), org_id, saml_linked_at IS NOT NULL, created_at FROM users WHERE username = $1`,
 		username,
-	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &totpSecret, &user.TOTPEnabled, &lastPasswordChange, &user.CreatedAt)
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &totpSecret, &user.TOTPEnabled, &lastPasswordChange, &user.Email, &user.OrgID, &user.SAMLLinked, &user.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		return models.User{}, errors.New("user not found")
+		return models.User{}, ErrUserNotFound
 	}
 	if err != nil {
 		return models.User{}, err
 	}
 
-	if totpSecret.Valid {
-		user.TOTPSecret = totpSecret.String
+	// A secret that can't be decrypted fails the lookup rather than leave
+	// 2FA checking codes against an empty secret
+	if user.TOTPSecret, err = s.openOptional(totpSecret.String); err != nil {
+		return models.User{}, fmt.Errorf("user %d: TOTP secret: %w", user.ID, err)
 	}
 	if lastPasswordChange.Valid {
 		user.LastPasswordChange = lastPasswordChange.Time
@@ -151,8 +254,8 @@ func (s *PostgresStore) GetUserByUsername(ctx context.Context, username string)
 }
 
 func (s *PostgresStore) GetUsers(ctx context.Context) ([]models.User, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, username, password_hash, role, totp_secret, totp_enabled, last_password_change, created_at FROM users ORDER BY created_at DESC`,
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT id, username, password_hash, role, totp_secret, totp_enabled, last_password_change, COALESCE(email, ''), org_id, saml_linked_at IS NOT NULL, created_at FROM users ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -165,12 +268,14 @@ func (s *PostgresStore) GetUsers(ctx context.Context) ([]models.User, error) {
 		var totpSecret sql.NullString
 		var lastPasswordChange sql.NullTime
 
-		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &totpSecret, &user.TOTPEnabled, &lastPasswordChange, &user.CreatedAt); err != nil {
+		if err := rows.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &totpSecret, &user.TOTPEnabled, &lastPasswordChange, &user.Email, &user.OrgID, &user.SAMLLinked, &user.CreatedAt); err != nil {
 			continue
 		}
 
-		if totpSecret.Valid {
-			user.TOTPSecret = totpSecret.String
+		if secret, err := s.openOptional(totpSecret.String); err == nil {
+			user.TOTPSecret = secret
+		} else {
+			log.Printf("User %d: can't decrypt TOTP secret: %v", user.ID, err)
 		}
 		if lastPasswordChange.Valid {
 			user.LastPasswordChange = lastPasswordChange.Time
@@ -182,6 +287,21 @@ func (s *PostgresStore) GetUsers(ctx context.Context) ([]models.User, error) {
 	return users, nil
 }
 
+// SetUserSAMLLinked lets SAML sign-ins use the account, or stops them
+func (s *PostgresStore) SetUserSAMLLinked(ctx context.Context, id int, linked bool) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE users SET saml_linked_at = CASE WHEN $2 THEN COALESCE(saml_linked_at, NOW()) END WHERE id = $1`,
+		id, linked,
+	)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
 func (s *PostgresStore) UpdateUser(ctx context.Context, id int, username, role string) error {
 	result, err := s.db.ExecContext(ctx,
 		`UPDATE users SET username = $1, role = $2 WHERE id = $3`,
@@ -199,19 +319,135 @@ func (s *PostgresStore) UpdateUser(ctx context.Context, id int, username, role s
 	return nil
 }
 
-func (s *PostgresStore) DeleteUser(ctx context.Context, id int) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
-	return err
+// DeleteUser removes a user along with their chat permissions and push
+// subscriptions. Bots they created move to reassignTo (or are left without an
+// owner when it is 0), who must be in the same organization; audit entries
+// keep the username. Deleting the last admin of the default organization, the
+// last who can run the instance, is refused with ErrInUse.
+func (s *PostgresStore) DeleteUser(ctx context.Context, id, reassignTo int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the default organization's admins before the user, always in ID
+	// order, so two concurrent deletes of different admins can't both count
+	// the other one and can't deadlock
+	var admins int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM (SELECT id FROM users WHERE role = 'admin' AND org_id = $1 ORDER BY id FOR UPDATE) admins`, models.DefaultOrgID,
+	).Scan(&admins); err != nil {
+		return err
+	}
+
+	var role string
+	var orgID int
+	err = tx.QueryRowContext(ctx, `SELECT role, org_id FROM users WHERE id = $1 FOR UPDATE`, id).Scan(&role, &orgID)
+	if err == sql.ErrNoRows {
+		return errors.New("user not found")
+	}
+	if err != nil {
+		return err
+	}
+	if role == "admin" && orgID == models.DefaultOrgID && admins <= 1 {
+		return fmt.Errorf("%w: user is the last admin", ErrInUse)
+	}
+
+	if reassignTo != 0 {
+		if reassignTo == id {
+			return fmt.Errorf("%w: cannot reassign to the user being deleted", ErrInvalidReassign)
+		}
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND org_id = $2)`, reassignTo, orgID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: user %d not found in the organization", ErrInvalidReassign, reassignTo)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE bots SET created_by = $1 WHERE created_by = $2`, reassignTo, id); err != nil {
+			return err
+		}
+	}
+
+	// Take the user out of on-call rotations (overrides go with the user) and
+	// feature flag targeting
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE oncall_schedules
+		 SET participants = (SELECT COALESCE(jsonb_agg(p ORDER BY n), '[]'::jsonb) FROM jsonb_array_elements(participants) WITH ORDINALITY AS e(p, n) WHERE p <> to_jsonb($1::int))
+		 WHERE participants @> to_jsonb(ARRAY[$1::int])`, id,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE feature_flags
+		 SET user_ids = (SELECT COALESCE(jsonb_agg(u ORDER BY n), '[]'::jsonb) FROM jsonb_array_elements(user_ids) WITH ORDINALITY AS e(u, n) WHERE u <> to_jsonb($1::int))
+		 WHERE user_ids @> to_jsonb(ARRAY[$1::int])`, id,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // User profile & password management
 
+// MaxPasswordHistory is how many earlier passwords are kept per user
+const MaxPasswordHistory = 24
+
+// UpdateUserPassword sets a new password hash and moves the old one into the
+// user's password history
 func (s *PostgresStore) UpdateUserPassword(ctx context.Context, userID int, newPasswordHash string) error {
-	_, err := s.db.ExecContext(ctx,
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO password_history (user_id, password_hash) SELECT id, password_hash FROM users WHERE id = $1`, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
 		`UPDATE users SET password_hash = $1, last_password_change = NOW() WHERE id = $2`,
 		newPasswordHash, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM password_history WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2)`,
+		userID, MaxPasswordHistory,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetPasswordHistory returns the user's earlier password hashes, newest
+// first, at most limit
+func (s *PostgresStore) GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT password_hash FROM password_history WHERE user_id = $1 ORDER BY id DESC LIMIT $2`, userID, limit,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
 }
 
 func (s *PostgresStore) UpdateUserProfile(ctx context.Context, userID int, username string) error {
@@ -231,12 +467,35 @@ func (s *PostgresStore) UpdateUserProfile(ctx context.Context, userID int, usern
 	return nil
 }
 
+// UpdateUserEmail sets the address password reset links go to; empty
+// removes it
+func (s *PostgresStore) UpdateUserEmail(ctx context.Context, userID int, email string) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE users SET email = NULLIF($1, '') WHERE id = $2`,
+		email, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.New("user not found")
+	}
+
+	return nil
+}
+
 // 2FA methods
 
 func (s *PostgresStore) UpdateUser2FA(ctx context.Context, userID int, totpSecret string, enabled bool) error {
-	_, err := s.db.ExecContext(ctx,
+	sealed, err := s.sealOptional(totpSecret)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
 		`UPDATE users SET totp_secret = $1, totp_enabled = $2 WHERE id = $3`,
-		totpSecret, enabled, userID,
+		sealed, enabled, userID,
 	)
 	return err
 }
@@ -249,35 +508,143 @@ func (s *PostgresStore) Disable2FA(ctx context.Context, userID int) error {
 	return err
 }
 
+// Organization methods
+
+func (s *PostgresStore) CreateOrganization(ctx context.Context, name string) (models.Organization, error) {
+	var org models.Organization
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO organizations (name, created_at) VALUES ($1, NOW()) RETURNING id, name, created_at`,
+		name,
+	).Scan(&org.ID, &org.Name, &org.CreatedAt)
+	return org, err
+}
+
+func (s *PostgresStore) GetOrganization(ctx context.Context, id int) (models.Organization, error) {
+	var org models.Organization
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id, name, created_at FROM organizations WHERE id = $1`,
+		id,
+	).Scan(&org.ID, &org.Name, &org.CreatedAt)
+	if err == sql.ErrNoRows {
+		return models.Organization{}, errors.New("organization not found")
+	}
+	return org, err
+}
+
+func (s *PostgresStore) GetOrganizations(ctx context.Context) ([]models.Organization, error) {
+	rows, err := s.reader().QueryContext(ctx, `SELECT id, name, created_at FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// DeleteOrganization removes an organization with its pending invitations.
+// The default organization, and one that still has users or bots, is
+// refused with ErrInUse.
+func (s *PostgresStore) DeleteOrganization(ctx context.Context, id int) error {
+	if id == models.DefaultOrgID {
+		return fmt.Errorf("%w: the default organization can't be deleted", ErrInUse)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM organizations WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("organization not found")
+	}
+	var users, bots int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM users WHERE org_id = $1), (SELECT COUNT(*) FROM bots WHERE org_id = $1)`, id,
+	).Scan(&users, &bots); err != nil {
+		return err
+	}
+	if users > 0 || bots > 0 {
+		return fmt.Errorf("%w: organization has %d user(s) and %d bot(s); delete them first", ErrInUse, users, bots)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // Bot methods
 
-func (s *PostgresStore) CreateBot(ctx context.Context, name string, createdBy int) (models.Bot, error) {
+const botColumns = `id, token, token_hash, name, COALESCE(signing_secret, ''), rate_limit, created_by, created_at, previous_token_expires_at, COALESCE(allowed_ips, ''), org_id`
+
+// scanBot decrypts the bot's token and signing secret. A token that can't be
+// decrypted is left empty; a signing secret that can't fails the scan, so the
+// bot isn't let through unsigned.
+func (s *PostgresStore) scanBot(row interface{ Scan(...any) error }) (models.Bot, error) {
+	var bot models.Bot
+	var token, signingSecret, allowedIPs string
+	if err := row.Scan(&bot.ID, &token, &bot.TokenHash, &bot.Name, &signingSecret, &bot.RateLimit, &bot.CreatedBy, &bot.CreatedAt, &bot.PreviousTokenExpiresAt, &allowedIPs, &bot.OrgID); err != nil {
+		return models.Bot{}, err
+	}
+	var err error
+	if bot.Token, err = s.openOptional(token); err != nil {
+		log.Printf("Bot %d: can't decrypt token: %v", bot.ID, err)
+	}
+	if bot.SigningSecret, err = s.openOptional(signingSecret); err != nil {
+		return models.Bot{}, fmt.Errorf("bot %d: signing secret: %w", bot.ID, err)
+	}
+	if allowedIPs != "" {
+		bot.AllowedIPs = strings.Split(allowedIPs, ",")
+	}
+	bot.Signed = bot.SigningSecret != ""
+	if bot.PreviousTokenExpiresAt != nil && time.Now().After(*bot.PreviousTokenExpiresAt) {
+		bot.PreviousTokenExpiresAt = nil
+	}
+	return bot, nil
+}
+
+// CreateBot adds a bot; signingSecret may be empty for a bot that posts
+// unsigned
+// CreateBot adds a bot to the organization orgID
+func (s *PostgresStore) CreateBot(ctx context.Context, name string, createdBy, orgID int, signingSecret string) (models.Bot, error) {
 	token, err := models.GenerateToken()
 	if err != nil {
 		return models.Bot{}, err
 	}
-	secret, err := models.GenerateToken()
+	sealedToken, err := s.sealOptional(token)
+	if err != nil {
+		return models.Bot{}, err
+	}
+	sealedSecret, err := s.sealOptional(signingSecret)
 	if err != nil {
 		return models.Bot{}, err
 	}
 
-	var bot models.Bot
-	err = s.db.QueryRowContext(ctx,
-		`INSERT INTO bots (token, name, hmac_secret, rate_limit, created_by, created_at) 
-		 VALUES ($1, $2, $3, 60, $4, NOW()) 
-		 RETURNING id, token, name, hmac_secret, rate_limit, created_by, created_at`,
-		token, name, secret, createdBy,
-	).Scan(&bot.ID, &bot.Token, &bot.Name, &bot.HMACSecret, &bot.RateLimit, &bot.CreatedBy, &bot.CreatedAt)
-
-	return bot, err
+	return s.scanBot(s.db.QueryRowContext(ctx,
+		`INSERT INTO bots (token, token_hash, name, signing_secret, rate_limit, created_by, org_id, created_at) 
+		 VALUES ($1, $2, $3, NULLIF($4, ''), 60, $5, $6, NOW()) 
+		 RETURNING `+botColumns,
+		sealedToken, models.HashBotToken(token), name, sealedSecret, createdBy, orgID,
+	))
 }
 
 func (s *PostgresStore) GetBot(ctx context.Context, id int) (models.Bot, error) {
-	var bot models.Bot
-	err := s.db.QueryRowContext(ctx,
-		`SELECT id, token, name, hmac_secret, rate_limit, created_by, created_at FROM bots WHERE id = $1`,
+	bot, err := s.scanBot(s.db.QueryRowContext(ctx,
+		`SELECT `+botColumns+` FROM bots WHERE id = $1`,
 		id,
-	).Scan(&bot.ID, &bot.Token, &bot.Name, &bot.HMACSecret, &bot.RateLimit, &bot.CreatedBy, &bot.CreatedAt)
+	))
 
 	if err == sql.ErrNoRows {
 		return models.Bot{}, errors.New("bot not found")
@@ -285,22 +652,94 @@ func (s *PostgresStore) GetBot(ctx context.Context, id int) (models.Bot, error)
 	return bot, err
 }
 
+// GetBotByToken authenticates bot ingestion, by the bot's token or, during
+// the grace period after a rotation, its previous one; while the database is
+// down it answers from the last successful lookup
 func (s *PostgresStore) GetBotByToken(ctx context.Context, token string) (models.Bot, error) {
-	var bot models.Bot
-	err := s.db.QueryRowContext(ctx,
-		`SELECT id, token, name, hmac_secret, rate_limit, created_by, created_at FROM bots WHERE token = $1`,
-		token,
-	).Scan(&bot.ID, &bot.Token, &bot.Name, &bot.HMACSecret, &bot.RateLimit, &bot.CreatedBy, &bot.CreatedAt)
+	hash := models.HashBotToken(token)
+	bot, err := lastGoodLookup(s, &s.bots, hash, func() (models.Bot, error) {
+		return s.scanBot(s.db.QueryRowContext(ctx,
+			`SELECT `+botColumns+` FROM bots
+			 WHERE token_hash = $1 OR (previous_token_hash = $1 AND previous_token_expires_at > NOW())`,
+			hash,
+		))
+	})
 
 	if err == sql.ErrNoRows {
 		return models.Bot{}, errors.New("bot not found")
 	}
+	// A previous token remembered from before an outage still runs out
+	if err == nil && bot.TokenHash != hash && (bot.PreviousTokenExpiresAt == nil || time.Now().After(*bot.PreviousTokenExpiresAt)) {
+		return models.Bot{}, errors.New("bot not found")
+	}
 	return bot, err
 }
 
+// SetBotAllowedIPs sets the CIDRs the bot may post from; none lets it post
+// from anywhere
+func (s *PostgresStore) SetBotAllowedIPs(ctx context.Context, id int, cidrs []string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE bots SET allowed_ips = NULLIF($2, '') WHERE id = $1`, id, strings.Join(cidrs, ","))
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("bot not found")
+	}
+	return nil
+}
+
+// RotateBotToken gives the bot a new token. The current one keeps working
+// for grace, replacing any previous token still in its grace period; with
+// grace 0 it stops working at once.
+func (s *PostgresStore) RotateBotToken(ctx context.Context, id int, grace time.Duration) (models.Bot, error) {
+	token, err := models.GenerateToken()
+	if err != nil {
+		return models.Bot{}, err
+	}
+	sealedToken, err := s.sealOptional(token)
+	if err != nil {
+		return models.Bot{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Bot{}, err
+	}
+	defer tx.Rollback()
+
+	var oldHash string
+	if err := tx.QueryRowContext(ctx, `SELECT token_hash FROM bots WHERE id = $1 FOR UPDATE`, id).Scan(&oldHash); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Bot{}, errors.New("bot not found")
+		}
+		return models.Bot{}, err
+	}
+	previous, expires := sql.NullString{}, sql.NullTime{}
+	if grace > 0 {
+		previous = sql.NullString{String: oldHash, Valid: true}
+		expires = sql.NullTime{Time: time.Now().Add(grace), Valid: true}
+	}
+	bot, err := s.scanBot(tx.QueryRowContext(ctx,
+		`UPDATE bots SET token = $2, token_hash = $3, previous_token_hash = $4, previous_token_expires_at = $5
+		 WHERE id = $1
+		 RETURNING `+botColumns,
+		id, sealedToken, models.HashBotToken(token), previous, expires,
+	))
+	if err != nil {
+		return models.Bot{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Bot{}, err
+	}
+	if grace <= 0 {
+		s.bots.forget(oldHash)
+	}
+	return bot, nil
+}
+
 func (s *PostgresStore) GetBots(ctx context.Context) ([]models.Bot, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, token, name, hmac_secret, rate_limit, created_by, created_at FROM bots ORDER BY created_at DESC`,
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+botColumns+` FROM bots ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -309,8 +748,9 @@ func (s *PostgresStore) GetBots(ctx context.Context) ([]models.Bot, error) {
 
 	var bots []models.Bot
 	for rows.Next() {
-		var bot models.Bot
-		if err := rows.Scan(&bot.ID, &bot.Token, &bot.Name, &bot.HMACSecret, &bot.RateLimit, &bot.CreatedBy, &bot.CreatedAt); err != nil {
+		bot, err := s.scanBot(rows)
+		if err != nil {
+			log.Printf("Failed to read bot: %v", err)
 			continue
 		}
 		bots = append(bots, bot)
@@ -319,31 +759,97 @@ func (s *PostgresStore) GetBots(ctx context.Context) ([]models.Bot, error) {
 	return bots, nil
 }
 
-func (s *PostgresStore) DeleteBot(ctx context.Context, id int) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM bots WHERE id = $1`, id)
-	return err
+// SetBotSigningSecret sets the secret the bot's requests are signed with; an
+// empty secret lets it post unsigned
+func (s *PostgresStore) SetBotSigningSecret(ctx context.Context, id int, secret string) error {
+	sealed, err := s.sealOptional(secret)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE bots SET signing_secret = NULLIF($2, '') WHERE id = $1`, id, sealed)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("bot not found")
+	}
+	return nil
+}
+
+// DeleteBot removes a bot. Its chats move to reassignTo, a bot of the same
+// organization; with reassignTo 0 a
+// bot that still has chats is refused with ErrInUse rather than silently
+// dropping the chats and everyone's access to them.
+func (s *PostgresStore) DeleteBot(ctx context.Context, id, reassignTo int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var orgID int
+	err = tx.QueryRowContext(ctx, `SELECT org_id FROM bots WHERE id = $1`, id).Scan(&orgID)
+	if err == sql.ErrNoRows {
+		return errors.New("bot not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	if reassignTo != 0 {
+		if reassignTo == id {
+			return fmt.Errorf("%w: cannot reassign to the bot being deleted", ErrInvalidReassign)
+		}
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM bots WHERE id = $1 AND org_id = $2)`, reassignTo, orgID).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("%w: bot %d not found in the organization", ErrInvalidReassign, reassignTo)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE chats SET bot_id = $1 WHERE bot_id = $2`, reassignTo, id); err != nil {
+			return err
+		}
+	} else {
+		var chats int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM chats WHERE bot_id = $1`, id).Scan(&chats); err != nil {
+			return err
+		}
+		if chats > 0 {
+			return fmt.Errorf("%w: bot has %d chat(s); reassign them to another bot or delete them first", ErrInUse, chats)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM bots WHERE id = $1`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 // Chat methods
 
+// CreateChat adds a chat to the bot's organization
 func (s *PostgresStore) CreateChat(ctx context.Context, chatID, name string, botID int) (models.Chat, error) {
 	var chat models.Chat
 	err := s.db.QueryRowContext(ctx,
-		`INSERT INTO chats (chat_id, name, bot_id, created_at) 
-		 VALUES ($1, $2, $3, NOW()) 
-		 RETURNING id, chat_id, name, bot_id, created_at`,
+		`INSERT INTO chats (chat_id, name, bot_id, org_id, created_at) 
+		 SELECT $1, $2, $3, org_id, NOW() FROM bots WHERE id = $3
+		 RETURNING id, chat_id, name, bot_id, org_id, created_at`,
 		chatID, name, botID,
-	).Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.CreatedAt)
+	).Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.OrgID, &chat.CreatedAt)
 
+	if err == sql.ErrNoRows {
+		return models.Chat{}, errors.New("bot not found")
+	}
 	return chat, err
 }
 
 func (s *PostgresStore) GetChat(ctx context.Context, id int) (models.Chat, error) {
 	var chat models.Chat
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, chat_id, name, bot_id, created_at FROM chats WHERE id = $1`,
+		`SELECT id, chat_id, name, bot_id, org_id, created_at FROM chats WHERE id = $1`,
 		id,
-	).Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.CreatedAt)
+	).Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.OrgID, &chat.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return models.Chat{}, errors.New("chat not found")
@@ -351,9 +857,15 @@ func (s *PostgresStore) GetChat(ctx context.Context, id int) (models.Chat, error
 	return chat, err
 }
 
+// GetChats lists every chat; while the database is down it answers from the
+// last successful listing
 func (s *PostgresStore) GetChats(ctx context.Context) ([]models.Chat, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, chat_id, name, bot_id, created_at FROM chats ORDER BY created_at DESC`,
+	return lastGoodLookup(s, &s.chats, "", func() ([]models.Chat, error) { return s.getChats(ctx) })
+}
+
+func (s *PostgresStore) getChats(ctx context.Context) ([]models.Chat, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT id, chat_id, name, bot_id, org_id, created_at FROM chats ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -363,7 +875,7 @@ func (s *PostgresStore) GetChats(ctx context.Context) ([]models.Chat, error) {
 	var chats []models.Chat
 	for rows.Next() {
 		var chat models.Chat
-		if err := rows.Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.CreatedAt); err != nil {
+		if err := rows.Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.OrgID, &chat.CreatedAt); err != nil {
 			continue
 		}
 		chats = append(chats, chat)
@@ -397,12 +909,33 @@ func (s *PostgresStore) RemoveChatFromUser(ctx context.Context, userID, chatID i
 	return err
 }
 
+// GetUserChats returns the chats the user may see: those given to them and
+// those of their teams. It backs permission checks on alerts, so while the
+// database is down it answers from the last successful lookup.
 func (s *PostgresStore) GetUserChats(ctx context.Context, userID int) ([]models.Chat, error) {
+	return lastGoodLookup(s, &s.userChats, strconv.Itoa(userID), func() ([]models.Chat, error) {
+		return s.queryUserChats(ctx, userID,
+			`SELECT chat_id FROM user_chat_permissions WHERE user_id = $1
+			 UNION
+			 SELECT tc.chat_id FROM team_chats tc
+			 INNER JOIN team_members tm ON tm.team_id = tc.team_id
+			 WHERE tm.user_id = $1`)
+	})
+}
+
+// GetUserAssignedChats returns the chats given to the user themselves, not
+// through a team
+func (s *PostgresStore) GetUserAssignedChats(ctx context.Context, userID int) ([]models.Chat, error) {
+	return s.queryUserChats(ctx, userID, `SELECT chat_id FROM user_chat_permissions WHERE user_id = $1`)
+}
+
+// queryUserChats returns the chats whose IDs chatIDs, a query on $1 (the
+// user's ID), selects
+func (s *PostgresStore) queryUserChats(ctx context.Context, userID int, chatIDs string) ([]models.Chat, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT c.id, c.chat_id, c.name, c.bot_id, c.created_at 
+		`SELECT c.id, c.chat_id, c.name, c.bot_id, c.org_id, c.created_at 
 		 FROM chats c
-		 INNER JOIN user_chat_permissions ucp ON c.id = ucp.chat_id
-		 WHERE ucp.user_id = $1
+		 WHERE c.id IN (`+chatIDs+`)
 		 ORDER BY c.created_at DESC`,
 		userID,
 	)
@@ -414,7 +947,7 @@ func (s *PostgresStore) GetUserChats(ctx context.Context, userID int) ([]models.
 	var chats []models.Chat
 	for rows.Next() {
 		var chat models.Chat
-		if err := rows.Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.CreatedAt); err != nil {
+		if err := rows.Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.OrgID, &chat.CreatedAt); err != nil {
 			continue
 		}
 		chats = append(chats, chat)
@@ -423,15 +956,74 @@ func (s *PostgresStore) GetUserChats(ctx context.Context, userID int) ([]models.
 	return chats, nil
 }
 
-func (s *PostgresStore) GetChatUsers(ctx context.Context, chatID int) ([]models.User, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT u.id, u.username, u.password_hash, u.role, u.created_at
-		 FROM users u
-		 INNER JOIN user_chat_permissions ucp ON u.id = ucp.user_id
-		 WHERE ucp.chat_id = $1
-		 ORDER BY u.username ASC`,
-		chatID,
-	)
+// Bot-Chat Permission methods
+
+// AssignChatToBot lets the bot post into a chat besides its own
+func (s *PostgresStore) AssignChatToBot(ctx context.Context, botID, chatID int) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO bot_chat_permissions (bot_id, chat_id, created_at)
+		 VALUES ($1, $2, NOW())
+		 ON CONFLICT (bot_id, chat_id) DO NOTHING`,
+		botID, chatID,
+	)
+	return err
+}
+
+func (s *PostgresStore) RemoveChatFromBot(ctx context.Context, botID, chatID int) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM bot_chat_permissions WHERE bot_id = $1 AND chat_id = $2`,
+		botID, chatID,
+	)
+	return err
+}
+
+// GetBotChats returns the chats the bot may post into: its own and those
+// assigned to it. It backs bot ingestion, so while the database is down it
+// answers from the last successful lookup.
+func (s *PostgresStore) GetBotChats(ctx context.Context, botID int) ([]models.Chat, error) {
+	return lastGoodLookup(s, &s.botChats, strconv.Itoa(botID), func() ([]models.Chat, error) { return s.getBotChats(ctx, botID) })
+}
+
+func (s *PostgresStore) getBotChats(ctx context.Context, botID int) ([]models.Chat, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT c.id, c.chat_id, c.name, c.bot_id, c.org_id, c.created_at
+		 FROM chats c
+		 WHERE c.bot_id = $1
+		    OR c.id IN (SELECT chat_id FROM bot_chat_permissions WHERE bot_id = $1)
+		 ORDER BY c.created_at DESC`,
+		botID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []models.Chat
+	for rows.Next() {
+		var chat models.Chat
+		if err := rows.Scan(&chat.ID, &chat.ChatID, &chat.Name, &chat.BotID, &chat.OrgID, &chat.CreatedAt); err != nil {
+			continue
+		}
+		chats = append(chats, chat)
+	}
+
+	return chats, rows.Err()
+}
+
+func (s *PostgresStore) GetChatUsers(ctx context.Context, chatID int) ([]models.User, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT u.id, u.username, u.password_hash, u.role, u.created_at
+		 FROM users u
+		 WHERE u.id IN (
+			SELECT user_id FROM user_chat_permissions WHERE chat_id = $1
+			UNION
+			SELECT tm.user_id FROM team_members tm
+			INNER JOIN team_chats tc ON tc.team_id = tm.team_id
+			WHERE tc.chat_id = $1
+		 )
+		 ORDER BY u.username ASC`,
+		chatID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -449,6 +1041,146 @@ func (s *PostgresStore) GetChatUsers(ctx context.Context, chatID int) ([]models.
 	return users, nil
 }
 
+// Team methods
+
+const teamColumns = `t.id, t.name, t.org_id, t.created_at,
+	COALESCE((SELECT json_agg(user_id ORDER BY user_id) FROM team_members WHERE team_id = t.id), '[]'),
+	COALESCE((SELECT json_agg(chat_id ORDER BY chat_id) FROM team_chats WHERE team_id = t.id), '[]')`
+
+func scanTeam(row interface{ Scan(...any) error }) (models.Team, error) {
+	var t models.Team
+	var userIDs, chatIDs []byte
+	if err := row.Scan(&t.ID, &t.Name, &t.OrgID, &t.CreatedAt, &userIDs, &chatIDs); err != nil {
+		return models.Team{}, err
+	}
+	if err := json.Unmarshal(userIDs, &t.UserIDs); err != nil {
+		return models.Team{}, err
+	}
+	if err := json.Unmarshal(chatIDs, &t.ChatIDs); err != nil {
+		return models.Team{}, err
+	}
+	return t, nil
+}
+
+// CreateTeam stores a team with its members and chats
+func (s *PostgresStore) CreateTeam(ctx context.Context, t models.Team) (models.Team, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Team{}, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx,
+		`INSERT INTO teams (name, org_id, created_at) VALUES ($1, $2, NOW()) RETURNING id`,
+		t.Name, t.OrgID,
+	).Scan(&t.ID); err != nil {
+		return models.Team{}, err
+	}
+	if err := setTeamMembership(ctx, tx, t); err != nil {
+		return models.Team{}, err
+	}
+	t, err = scanTeam(tx.QueryRowContext(ctx, `SELECT `+teamColumns+` FROM teams t WHERE t.id = $1`, t.ID))
+	if err != nil {
+		return models.Team{}, err
+	}
+	return t, tx.Commit()
+}
+
+func (s *PostgresStore) GetTeam(ctx context.Context, id int) (models.Team, error) {
+	t, err := scanTeam(s.db.QueryRowContext(ctx, `SELECT `+teamColumns+` FROM teams t WHERE t.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return models.Team{}, errors.New("team not found")
+	}
+	return t, err
+}
+
+// GetTeams lists the teams of every organization by name
+func (s *PostgresStore) GetTeams(ctx context.Context) ([]models.Team, error) {
+	rows, err := s.reader().QueryContext(ctx, `SELECT `+teamColumns+` FROM teams t ORDER BY t.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	teams := []models.Team{}
+	for rows.Next() {
+		t, err := scanTeam(rows)
+		if err != nil {
+			continue
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// UpdateTeam renames the team and replaces its members and chats with t's,
+// all at once
+func (s *PostgresStore) UpdateTeam(ctx context.Context, t models.Team) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx,
+		`UPDATE teams SET name = $2 WHERE id = $1 RETURNING org_id`, t.ID, t.Name,
+	).Scan(&t.OrgID); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("team not found")
+		}
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM team_members WHERE team_id = $1`, t.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM team_chats WHERE team_id = $1`, t.ID); err != nil {
+		return err
+	}
+	if err := setTeamMembership(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setTeamMembership adds t's members and chats to the team. Users and chats
+// of another organization are left out.
+func setTeamMembership(ctx context.Context, tx *sql.Tx, t models.Team) error {
+	for _, userID := range t.UserIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO team_members (team_id, user_id)
+			 SELECT $1, id FROM users WHERE id = $2 AND org_id = $3
+			 ON CONFLICT (team_id, user_id) DO NOTHING`,
+			t.ID, userID, t.OrgID,
+		); err != nil {
+			return err
+		}
+	}
+	for _, chatID := range t.ChatIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO team_chats (team_id, chat_id)
+			 SELECT $1, id FROM chats WHERE id = $2 AND org_id = $3
+			 ON CONFLICT (team_id, chat_id) DO NOTHING`,
+			t.ID, chatID, t.OrgID,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteTeam removes a team; its members keep the chats given to them
+// directly
+func (s *PostgresStore) DeleteTeam(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM teams WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("team not found")
+	}
+	return nil
+}
+
 // Push Notification methods
 
 func (s *PostgresStore) SavePushSubscription(ctx context.Context, userID int, endpoint, p256dh, auth string) error {
@@ -469,6 +1201,76 @@ func (s *PostgresStore) GetPushSubscriptions(ctx context.Context) ([]models.Push
 	if err != nil {
 		return nil, err
 	}
+	return scanPushSubscriptions(rows)
+}
+
+// GetUserPushSubscriptions returns one user's push subscriptions, for
+// notifications meant only for them
+func (s *PostgresStore) GetUserPushSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, endpoint, p256dh, auth, created_at FROM push_subscriptions WHERE user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanPushSubscriptions(rows)
+}
+
+// DeletePushSubscription removes one of a user's push subscriptions; it's
+// not an error if it's already gone
+func (s *PostgresStore) DeletePushSubscription(ctx context.Context, userID int, endpoint string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM push_subscriptions WHERE user_id = $1 AND endpoint = $2`,
+		userID, endpoint,
+	)
+	return err
+}
+
+// GetChatPushSubscriptions returns the push subscriptions of the users who
+// may see alerts in the chat with public chat_id: admins and developers of
+// its organization, and users given the chat or in a team that has it. An
+// empty chatID is General,
+// which everyone in the default organization sees.
+func (s *PostgresStore) GetChatPushSubscriptions(ctx context.Context, chatID string) ([]models.PushSubscription, error) {
+	if chatID == "" {
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT ps.id, ps.user_id, ps.endpoint, ps.p256dh, ps.auth, ps.created_at
+			 FROM push_subscriptions ps
+			 INNER JOIN users u ON u.id = ps.user_id
+			 WHERE u.org_id = $1`,
+			models.DefaultOrgID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return scanPushSubscriptions(rows)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ps.id, ps.user_id, ps.endpoint, ps.p256dh, ps.auth, ps.created_at
+		 FROM push_subscriptions ps
+		 INNER JOIN users u ON u.id = ps.user_id
+		 WHERE (u.role IN ('admin', 'developer') AND u.org_id = COALESCE((SELECT org_id FROM chats WHERE chat_id = $1), $2))
+		    OR EXISTS (
+			SELECT 1 FROM user_chat_permissions ucp
+			INNER JOIN chats c ON c.id = ucp.chat_id
+			WHERE ucp.user_id = ps.user_id AND c.chat_id = $1
+		 )
+		    OR EXISTS (
+			SELECT 1 FROM team_members tm
+			INNER JOIN team_chats tc ON tc.team_id = tm.team_id
+			INNER JOIN chats c ON c.id = tc.chat_id
+			WHERE tm.user_id = ps.user_id AND c.chat_id = $1
+		 )`,
+		chatID, models.DefaultOrgID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanPushSubscriptions(rows)
+}
+
+func scanPushSubscriptions(rows *sql.Rows) ([]models.PushSubscription, error) {
 	defer rows.Close()
 
 	var subs []models.PushSubscription
@@ -482,43 +1284,1953 @@ func (s *PostgresStore) GetPushSubscriptions(ctx context.Context) ([]models.Push
 	return subs, nil
 }
 
+// MaxAuditPage is the most audit entries ListAudit returns at once
+const MaxAuditPage = 500
+
 // Audit logs
+
+// InsertAudit records an action with the request ID, client address and user
+// agent of the request in ctx
 func (s *PostgresStore) InsertAudit(ctx context.Context, actorID int, action, targetType string, targetID int, metadata string) error {
 	var target sql.NullInt64
 	if targetID != 0 {
 		target = sql.NullInt64{Int64: int64(targetID), Valid: true}
 	}
+	client := clientinfo.FromContext(ctx)
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO audit_logs (actor_id, action, target_type, target_id, metadata, created_at)
-		 VALUES ($1, $2, $3, $4, $5, NOW())`,
-		actorID, action, targetType, target, metadata,
+		`INSERT INTO audit_logs (actor_id, actor_username, org_id, action, target_type, target_id, metadata, request_id, client_ip, user_agent, created_at)
+		 VALUES ($1, (SELECT username FROM users WHERE id = $1), COALESCE((SELECT org_id FROM users WHERE id = $1), $9), $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NOW())`,
+		actorID, action, targetType, target, SanitizeAuditMetadata(metadata), requestid.FromContext(ctx), client.IP, client.UserAgent, models.DefaultOrgID,
 	)
 	return err
 }
 
-func (s *PostgresStore) ListAudit(ctx context.Context, limit int) ([]models.AuditLog, error) {
-	if limit <= 0 {
-		limit = 50
+// ListAudit returns the audit entries matching f, newest first
+func (s *PostgresStore) ListAudit(ctx context.Context, f models.AuditFilter) ([]models.AuditLog, error) {
+	if f.Limit <= 0 || f.Limit > MaxAuditPage {
+		f.Limit = 50
 	}
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, COALESCE(actor_id,0), action, COALESCE(target_type,''), COALESCE(target_id,0), COALESCE(metadata,'{}'::jsonb), created_at
+	var since, until sql.NullTime
+	if !f.Since.IsZero() {
+		since = sql.NullTime{Time: f.Since, Valid: true}
+	}
+	if !f.Until.IsZero() {
+		until = sql.NullTime{Time: f.Until, Valid: true}
+	}
+	rows, err := s.reader().QueryContext(ctx, `
+		SELECT id, COALESCE(actor_id,0), COALESCE(actor_username,''), action, COALESCE(target_type,''), COALESCE(target_id,0), COALESCE(metadata,'{}'::jsonb),
+		       COALESCE(request_id,''), COALESCE(client_ip,''), COALESCE(user_agent,''), org_id, created_at
 		FROM audit_logs
-		ORDER BY created_at DESC
-		LIMIT $1`, limit)
+		WHERE ($1 = 0 OR actor_id = $1)
+		  AND ($2 = '' OR LOWER(actor_username) = LOWER($2))
+		  AND ($3 = '' OR action = $3)
+		  AND ($4 = '' OR target_type = $4)
+		  AND ($5::timestamptz IS NULL OR created_at >= $5)
+		  AND ($6::timestamptz IS NULL OR created_at < $6)
+		  AND ($7 = 0 OR id < $7)
+		  AND ($9 = 0 OR org_id = $9)
+		ORDER BY id DESC
+		LIMIT $8`,
+		f.ActorID, f.Actor, f.Action, f.TargetType, since, until, f.Before, f.Limit, f.OrgID,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var logs []models.AuditLog
+	logs := []models.AuditLog{}
 	for rows.Next() {
 		var l models.AuditLog
 		var meta json.RawMessage
-		if err := rows.Scan(&l.ID, &l.ActorID, &l.Action, &l.TargetType, &l.TargetID, &meta, &l.CreatedAt); err != nil {
+		if err := rows.Scan(&l.ID, &l.ActorID, &l.ActorUsername, &l.Action, &l.TargetType, &l.TargetID, &meta, &l.RequestID, &l.ClientIP, &l.UserAgent, &l.OrgID, &l.CreatedAt); err != nil {
 			return nil, err
 		}
 		l.Metadata = string(meta)
 		logs = append(logs, l)
 	}
-	return logs, nil
+	return logs, rows.Err()
+}
+
+// Ticket connectors
+
+func (s *PostgresStore) CreateTicketConnector(ctx context.Context, c models.TicketConnector) (models.TicketConnector, error) {
+	secret, err := models.GenerateToken()
+	if err != nil {
+		return models.TicketConnector{}, err
+	}
+	config, err := json.Marshal(c.Config)
+	if err != nil {
+		return models.TicketConnector{}, err
+	}
+
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO ticket_connectors (name, kind, chat_id, min_level, config, webhook_secret, enabled, created_at)
+		 VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, NOW())
+		 RETURNING id, webhook_secret, created_at`,
+		c.Name, c.Kind, c.ChatID, c.MinLevel, config, secret, c.Enabled,
+	).Scan(&c.ID, &c.WebhookSecret, &c.CreatedAt)
+
+	return c, err
+}
+
+func (s *PostgresStore) GetTicketConnector(ctx context.Context, id int) (models.TicketConnector, error) {
+	c, err := scanTicketConnector(s.db.QueryRowContext(ctx,
+		`SELECT id, name, kind, COALESCE(chat_id, ''), min_level, config, webhook_secret, enabled, created_at
+		 FROM ticket_connectors WHERE id = $1`,
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return models.TicketConnector{}, errors.New("ticket connector not found")
+	}
+	return c, err
+}
+
+func (s *PostgresStore) GetTicketConnectors(ctx context.Context) ([]models.TicketConnector, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, name, kind, COALESCE(chat_id, ''), min_level, config, webhook_secret, enabled, created_at
+		 FROM ticket_connectors ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var connectors []models.TicketConnector
+	for rows.Next() {
+		c, err := scanTicketConnector(rows)
+		if err != nil {
+			continue
+		}
+		connectors = append(connectors, c)
+	}
+	return connectors, nil
+}
+
+func (s *PostgresStore) DeleteTicketConnector(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM ticket_connectors WHERE id = $1`, id)
+	return err
+}
+
+func scanTicketConnector(row interface{ Scan(...any) error }) (models.TicketConnector, error) {
+	var c models.TicketConnector
+	var config []byte
+	if err := row.Scan(&c.ID, &c.Name, &c.Kind, &c.ChatID, &c.MinLevel, &config, &c.WebhookSecret, &c.Enabled, &c.CreatedAt); err != nil {
+		return models.TicketConnector{}, err
+	}
+	if err := json.Unmarshal(config, &c.Config); err != nil {
+		return models.TicketConnector{}, err
+	}
+	return c, nil
+}
+
+// Alert tickets
+
+func (s *PostgresStore) SaveAlertTicket(ctx context.Context, t models.AlertTicket) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO alert_tickets (alert_id, connector_id, external_id, url, status, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 ON CONFLICT (alert_id, connector_id) DO UPDATE
+		 SET external_id = $3, url = $4, status = $5`,
+		t.AlertID, t.ConnectorID, t.ExternalID, t.URL, t.Status,
+	)
+	return err
+}
+
+func (s *PostgresStore) GetAlertTickets(ctx context.Context, alertID int) ([]models.AlertTicket, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT alert_id, connector_id, external_id, COALESCE(url, ''), status, created_at
+		 FROM alert_tickets WHERE alert_id = $1`,
+		alertID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []models.AlertTicket
+	for rows.Next() {
+		var t models.AlertTicket
+		if err := rows.Scan(&t.AlertID, &t.ConnectorID, &t.ExternalID, &t.URL, &t.Status, &t.CreatedAt); err != nil {
+			continue
+		}
+		tickets = append(tickets, t)
+	}
+	return tickets, nil
+}
+
+func (s *PostgresStore) GetAlertTicketByExternalID(ctx context.Context, connectorID int, externalID string) (models.AlertTicket, error) {
+	var t models.AlertTicket
+	err := s.db.QueryRowContext(ctx,
+		`SELECT alert_id, connector_id, external_id, COALESCE(url, ''), status, created_at
+		 FROM alert_tickets WHERE connector_id = $1 AND external_id = $2`,
+		connectorID, externalID,
+	).Scan(&t.AlertID, &t.ConnectorID, &t.ExternalID, &t.URL, &t.Status, &t.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return models.AlertTicket{}, errors.New("ticket not found")
+	}
+	return t, err
+}
+
+func (s *PostgresStore) UpdateAlertTicketStatus(ctx context.Context, alertID, connectorID int, status string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE alert_tickets SET status = $1 WHERE alert_id = $2 AND connector_id = $3`,
+		status, alertID, connectorID,
+	)
+	return err
+}
+
+// Ingest tokens
+
+const ingestTokenPrefixLen = 8
+
+// CreateIngestToken returns the new token's record and its plaintext, which is
+// not stored and can't be shown again
+func (s *PostgresStore) CreateIngestToken(ctx context.Context, name string, rateLimit, createdBy int) (models.IngestToken, string, error) {
+	token, err := models.GenerateToken()
+	if err != nil {
+		return models.IngestToken{}, "", err
+	}
+	if rateLimit <= 0 {
+		rateLimit = 60
+	}
+	var creator sql.NullInt64
+	if createdBy != 0 {
+		creator = sql.NullInt64{Int64: int64(createdBy), Valid: true}
+	}
+
+	t := models.IngestToken{Name: name, Prefix: token[:ingestTokenPrefixLen], RateLimit: rateLimit, CreatedBy: createdBy}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO ingest_tokens (name, token_hash, prefix, rate_limit, created_by, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 RETURNING id, created_at`,
+		name, models.HashIngestToken(token), t.Prefix, rateLimit, creator,
+	).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return models.IngestToken{}, "", err
+	}
+	return t, token, nil
+}
+
+func (s *PostgresStore) GetIngestTokens(ctx context.Context) ([]models.IngestToken, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT id, name, prefix, rate_limit, COALESCE(created_by, 0), created_at, last_used_at, revoked_at
+		 FROM ingest_tokens ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []models.IngestToken
+	for rows.Next() {
+		var t models.IngestToken
+		if err := rows.Scan(&t.ID, &t.Name, &t.Prefix, &t.RateLimit, &t.CreatedBy, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt); err != nil {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// AuthenticateIngestToken looks up an active token by its plaintext and
+// records the use. While the database is down it answers from the last
+// successful lookup, without recording the use.
+func (s *PostgresStore) AuthenticateIngestToken(ctx context.Context, token string) (models.IngestToken, error) {
+	hash := models.HashIngestToken(token)
+	t, err := lastGoodLookup(s, &s.ingestTokens, hash, func() (models.IngestToken, error) {
+		var t models.IngestToken
+		err := s.db.QueryRowContext(ctx,
+			`UPDATE ingest_tokens SET last_used_at = NOW()
+			 WHERE token_hash = $1 AND revoked_at IS NULL
+			 RETURNING id, name, prefix, rate_limit, COALESCE(created_by, 0), created_at, last_used_at`,
+			hash,
+		).Scan(&t.ID, &t.Name, &t.Prefix, &t.RateLimit, &t.CreatedBy, &t.CreatedAt, &t.LastUsedAt)
+		return t, err
+	})
+
+	if err == sql.ErrNoRows {
+		return models.IngestToken{}, errors.New("ingest token not found")
+	}
+	return t, err
+}
+
+// RevokeIngestToken disables a token; the record stays for the audit trail
+func (s *PostgresStore) RevokeIngestToken(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE ingest_tokens SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("ingest token not found")
+	}
+	return nil
+}
+
+// API keys
+
+// apiKeyPrefixLen is how much of a key is kept in the clear: "snt_" and 8
+// characters
+const apiKeyPrefixLen = 12
+
+// CreateAPIKey issues a key for userID and returns it in plaintext, which is
+// the only time it is available
+func (s *PostgresStore) CreateAPIKey(ctx context.Context, userID int, name, scope string) (models.APIKey, string, error) {
+	token, err := models.GenerateToken()
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	key := models.APIKeyPrefix + token
+
+	k := models.APIKey{UserID: userID, Name: name, Scope: scope, Prefix: key[:apiKeyPrefixLen]}
+	err = s.db.QueryRowContext(ctx,
+		`INSERT INTO api_keys (user_id, name, scope, token_hash, prefix, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 RETURNING id, created_at`,
+		userID, name, scope, models.HashAPIKey(key), k.Prefix,
+	).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	return k, key, nil
+}
+
+// GetAPIKeys lists a user's keys, newest first
+func (s *PostgresStore) GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, user_id, name, scope, prefix, created_at, last_used_at
+		 FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var k models.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Scope, &k.Prefix, &k.CreatedAt, &k.LastUsedAt); err != nil {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// DeleteAPIKey revokes one of userID's keys
+func (s *PostgresStore) DeleteAPIKey(ctx context.Context, userID, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM api_keys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("API key not found")
+	}
+	return nil
+}
+
+// AuthenticateAPIKey looks up a key by its plaintext, with its owner's
+// current name and role, and records the use. While the database is down it
+// answers from the last successful lookup, without recording the use.
+func (s *PostgresStore) AuthenticateAPIKey(ctx context.Context, key string) (models.APIKey, error) {
+	hash := models.HashAPIKey(key)
+	k, err := lastGoodLookup(s, &s.apiKeys, hash, func() (models.APIKey, error) {
+		var k models.APIKey
+		err := s.db.QueryRowContext(ctx,
+			`UPDATE api_keys k SET last_used_at = NOW()
+			 FROM users u
+			 WHERE k.token_hash = $1 AND u.id = k.user_id
+			 RETURNING k.id, k.user_id, k.name, k.scope, k.prefix, k.created_at, k.last_used_at, u.username, u.role`,
+			hash,
+		).Scan(&k.ID, &k.UserID, &k.Name, &k.Scope, &k.Prefix, &k.CreatedAt, &k.LastUsedAt, &k.Username, &k.Role)
+		return k, err
+	})
+
+	if err == sql.ErrNoRows {
+		return models.APIKey{}, errors.New("API key not found")
+	}
+	return k, err
+}
+
+// Passkeys
+
+const passkeyColumns = `id, user_id, name, credential_id, public_key, sign_count, created_at, last_used_at`
+
+func scanPasskey(row interface{ Scan(...any) error }) (models.Passkey, error) {
+	var p models.Passkey
+	var signCount int64
+	err := row.Scan(&p.ID, &p.UserID, &p.Name, &p.CredentialID, &p.PublicKey, &signCount, &p.CreatedAt, &p.LastUsedAt)
+	p.SignCount = uint32(signCount)
+	return p, err
+}
+
+// CreatePasskey stores a newly registered passkey
+func (s *PostgresStore) CreatePasskey(ctx context.Context, p models.Passkey) (models.Passkey, error) {
+	return scanPasskey(s.db.QueryRowContext(ctx,
+		`INSERT INTO passkeys (user_id, name, credential_id, public_key, sign_count)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+passkeyColumns,
+		p.UserID, p.Name, p.CredentialID, p.PublicKey, int64(p.SignCount),
+	))
+}
+
+// GetPasskeys lists a user's passkeys, oldest first
+func (s *PostgresStore) GetPasskeys(ctx context.Context, userID int) ([]models.Passkey, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+passkeyColumns+` FROM passkeys WHERE user_id = $1 ORDER BY created_at`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []models.Passkey{}
+	for rows.Next() {
+		p, err := scanPasskey(rows)
+		if err != nil {
+			continue
+		}
+		passkeys = append(passkeys, p)
+	}
+	return passkeys, nil
+}
+
+// GetPasskeyByCredentialID looks up the passkey a sign-in was signed with
+func (s *PostgresStore) GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (models.Passkey, error) {
+	p, err := scanPasskey(s.db.QueryRowContext(ctx,
+		`SELECT `+passkeyColumns+` FROM passkeys WHERE credential_id = $1`,
+		credentialID,
+	))
+	if err == sql.ErrNoRows {
+		return models.Passkey{}, errors.New("passkey not found")
+	}
+	return p, err
+}
+
+// RecordPasskeyUse stores a passkey's signature counter after a sign-in
+func (s *PostgresStore) RecordPasskeyUse(ctx context.Context, id int, signCount uint32) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE passkeys SET sign_count = $2, last_used_at = NOW() WHERE id = $1`,
+		id, int64(signCount),
+	)
+	return err
+}
+
+// DeletePasskey removes one of userID's passkeys
+func (s *PostgresStore) DeletePasskey(ctx context.Context, userID, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM passkeys WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("passkey not found")
+	}
+	return nil
+}
+
+// Invitations
+
+const invitationColumns = `id, email, role, chat_ids, invited_by, created_at, expires_at, accepted_at, user_id, org_id`
+
+func scanInvitation(row interface{ Scan(...any) error }) (models.Invitation, error) {
+	var inv models.Invitation
+	var chatIDs []byte
+	var invitedBy, userID sql.NullInt64
+	if err := row.Scan(&inv.ID, &inv.Email, &inv.Role, &chatIDs, &invitedBy, &inv.CreatedAt, &inv.ExpiresAt, &inv.AcceptedAt, &userID, &inv.OrgID); err != nil {
+		return models.Invitation{}, err
+	}
+	if err := json.Unmarshal(chatIDs, &inv.ChatIDs); err != nil {
+		return models.Invitation{}, err
+	}
+	if invitedBy.Valid {
+		id := int(invitedBy.Int64)
+		inv.InvitedBy = &id
+	}
+	if userID.Valid {
+		id := int(userID.Int64)
+		inv.UserID = &id
+	}
+	return inv, nil
+}
+
+// CreateInvitation stores a new invitation
+func (s *PostgresStore) CreateInvitation(ctx context.Context, inv models.Invitation) (models.Invitation, error) {
+	if inv.ChatIDs == nil {
+		inv.ChatIDs = []int{}
+	}
+	chatIDs, err := json.Marshal(inv.ChatIDs)
+	if err != nil {
+		return models.Invitation{}, err
+	}
+	return scanInvitation(s.db.QueryRowContext(ctx,
+		`INSERT INTO invitations (email, role, chat_ids, invited_by, expires_at, org_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING `+invitationColumns,
+		inv.Email, inv.Role, chatIDs, inv.InvitedBy, inv.ExpiresAt, inv.OrgID,
+	))
+}
+
+// GetInvitations lists invitations, newest first
+func (s *PostgresStore) GetInvitations(ctx context.Context) ([]models.Invitation, error) {
+	rows, err := s.reader().QueryContext(ctx, `SELECT `+invitationColumns+` FROM invitations ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			continue
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, nil
+}
+
+func (s *PostgresStore) GetInvitation(ctx context.Context, id int) (models.Invitation, error) {
+	inv, err := scanInvitation(s.db.QueryRowContext(ctx, `SELECT `+invitationColumns+` FROM invitations WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return models.Invitation{}, ErrInvitationNotPending
+	}
+	return inv, err
+}
+
+// DeleteInvitation revokes an invitation that wasn't accepted yet
+func (s *PostgresStore) DeleteInvitation(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM invitations WHERE id = $1 AND accepted_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return ErrInvitationNotPending
+	}
+	return nil
+}
+
+// AcceptInvitation creates the invited account, with the invitation's email,
+// role and chats, and marks the invitation used, all at once. It returns
+// ErrInvitationNotPending when the invitation was accepted, revoked or has
+// expired meanwhile.
+func (s *PostgresStore) AcceptInvitation(ctx context.Context, id int, username, passwordHash string) (models.User, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	inv, err := scanInvitation(tx.QueryRowContext(ctx, `SELECT `+invitationColumns+` FROM invitations WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return models.User{}, ErrInvitationNotPending
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	if !inv.Pending(time.Now()) {
+		return models.User{}, ErrInvitationNotPending
+	}
+
+	var user models.User
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO users (username, password_hash, role, email, org_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 RETURNING id, username, password_hash, role, email, last_password_change, org_id, created_at`,
+		username, passwordHash, inv.Role, inv.Email, inv.OrgID,
+	).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.Email, &user.LastPasswordChange, &user.OrgID, &user.CreatedAt)
+	if err != nil {
+		return models.User{}, err
+	}
+	if inv.Role != "admin" {
+		for _, chatID := range inv.ChatIDs {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO user_chat_permissions (user_id, chat_id, created_at)
+				 SELECT $1, id, NOW() FROM chats WHERE id = $2 AND org_id = $3
+				 ON CONFLICT (user_id, chat_id) DO NOTHING`,
+				user.ID, chatID, inv.OrgID,
+			); err != nil {
+				return models.User{}, err
+			}
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE invitations SET accepted_at = NOW(), user_id = $2 WHERE id = $1`, id, user.ID,
+	); err != nil {
+		return models.User{}, err
+	}
+	return user, tx.Commit()
+}
+
+// Webhook mapping profiles
+
+const webhookMappingColumns = `id, name, fields, defaults, level_map, created_at`
+
+func (s *PostgresStore) CreateWebhookMapping(ctx context.Context, m models.WebhookMapping) (models.WebhookMapping, error) {
+	fields, defaults, levelMap, err := marshalMapping(m)
+	if err != nil {
+		return models.WebhookMapping{}, err
+	}
+	return scanWebhookMapping(s.db.QueryRowContext(ctx,
+		`INSERT INTO webhook_mappings (name, fields, defaults, level_map, created_at)
+		 VALUES ($1, $2, $3, $4, NOW())
+		 RETURNING `+webhookMappingColumns,
+		m.Name, fields, defaults, levelMap,
+	))
+}
+
+func (s *PostgresStore) UpdateWebhookMapping(ctx context.Context, m models.WebhookMapping) (models.WebhookMapping, error) {
+	fields, defaults, levelMap, err := marshalMapping(m)
+	if err != nil {
+		return models.WebhookMapping{}, err
+	}
+	m, err = scanWebhookMapping(s.db.QueryRowContext(ctx,
+		`UPDATE webhook_mappings SET name = $1, fields = $2, defaults = $3, level_map = $4
+		 WHERE id = $5
+		 RETURNING `+webhookMappingColumns,
+		m.Name, fields, defaults, levelMap, m.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.WebhookMapping{}, errors.New("webhook mapping not found")
+	}
+	return m, err
+}
+
+func (s *PostgresStore) GetWebhookMappings(ctx context.Context) ([]models.WebhookMapping, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+webhookMappingColumns+` FROM webhook_mappings ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []models.WebhookMapping
+	for rows.Next() {
+		m, err := scanWebhookMapping(rows)
+		if err != nil {
+			continue
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+func (s *PostgresStore) GetWebhookMappingByName(ctx context.Context, name string) (models.WebhookMapping, error) {
+	m, err := scanWebhookMapping(s.db.QueryRowContext(ctx,
+		`SELECT `+webhookMappingColumns+` FROM webhook_mappings WHERE name = $1`,
+		name,
+	))
+	if err == sql.ErrNoRows {
+		return models.WebhookMapping{}, errors.New("webhook mapping not found")
+	}
+	return m, err
+}
+
+func (s *PostgresStore) DeleteWebhookMapping(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM webhook_mappings WHERE id = $1`, id)
+	return err
+}
+
+// Metric threshold rules
+
+const metricRuleColumns = `id, name, source, metric, operator, threshold, level, created_at`
+
+func (s *PostgresStore) CreateMetricRule(ctx context.Context, rule models.MetricRule) (models.MetricRule, error) {
+	return scanMetricRule(s.db.QueryRowContext(ctx,
+		`INSERT INTO metric_rules (name, source, metric, operator, threshold, level, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		 RETURNING `+metricRuleColumns,
+		rule.Name, rule.Source, rule.Metric, rule.Operator, rule.Threshold, rule.Level,
+	))
+}
+
+func (s *PostgresStore) UpdateMetricRule(ctx context.Context, rule models.MetricRule) (models.MetricRule, error) {
+	updated, err := scanMetricRule(s.db.QueryRowContext(ctx,
+		`UPDATE metric_rules SET name = $1, source = $2, metric = $3, operator = $4, threshold = $5, level = $6
+		 WHERE id = $7
+		 RETURNING `+metricRuleColumns,
+		rule.Name, rule.Source, rule.Metric, rule.Operator, rule.Threshold, rule.Level, rule.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.MetricRule{}, errors.New("metric rule not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetMetricRules(ctx context.Context) ([]models.MetricRule, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+metricRuleColumns+` FROM metric_rules ORDER BY metric, name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.MetricRule
+	for rows.Next() {
+		rule, err := scanMetricRule(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (s *PostgresStore) DeleteMetricRule(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM metric_rules WHERE id = $1`, id)
+	return err
+}
+
+func scanMetricRule(row interface{ Scan(...any) error }) (models.MetricRule, error) {
+	var r models.MetricRule
+	err := row.Scan(&r.ID, &r.Name, &r.Source, &r.Metric, &r.Operator, &r.Threshold, &r.Level, &r.CreatedAt)
+	return r, err
+}
+
+// Heartbeat monitors
+
+const heartbeatColumns = `id, name, token, interval_seconds, grace_seconds, level, last_ping_at, COALESCE(created_by, 0), created_at`
+
+func (s *PostgresStore) CreateHeartbeat(ctx context.Context, hb models.Heartbeat) (models.Heartbeat, error) {
+	token, err := models.GenerateToken()
+	if err != nil {
+		return models.Heartbeat{}, err
+	}
+	return scanHeartbeat(s.db.QueryRowContext(ctx,
+		`INSERT INTO heartbeats (name, token, interval_seconds, grace_seconds, level, created_by, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NOW())
+		 RETURNING `+heartbeatColumns,
+		hb.Name, token, hb.IntervalSeconds, hb.GraceSeconds, hb.Level, hb.CreatedBy,
+	))
+}
+
+func (s *PostgresStore) UpdateHeartbeat(ctx context.Context, hb models.Heartbeat) (models.Heartbeat, error) {
+	updated, err := scanHeartbeat(s.db.QueryRowContext(ctx,
+		`UPDATE heartbeats SET name = $1, interval_seconds = $2, grace_seconds = $3, level = $4
+		 WHERE id = $5
+		 RETURNING `+heartbeatColumns,
+		hb.Name, hb.IntervalSeconds, hb.GraceSeconds, hb.Level, hb.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.Heartbeat{}, errors.New("heartbeat not found")
+	}
+	return updated, err
+}
+
+// GetHeartbeats reads from the primary: the heartbeat worker compares
+// check-in times and can't afford replica lag
+func (s *PostgresStore) GetHeartbeats(ctx context.Context) ([]models.Heartbeat, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+heartbeatColumns+` FROM heartbeats ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var heartbeats []models.Heartbeat
+	for rows.Next() {
+		hb, err := scanHeartbeat(rows)
+		if err != nil {
+			continue
+		}
+		heartbeats = append(heartbeats, hb)
+	}
+	return heartbeats, nil
+}
+
+func (s *PostgresStore) DeleteHeartbeat(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM heartbeats WHERE id = $1`, id)
+	return err
+}
+
+// CheckInHeartbeat records a check-in for the monitor with token
+func (s *PostgresStore) CheckInHeartbeat(ctx context.Context, token string) (models.Heartbeat, error) {
+	hb, err := scanHeartbeat(s.db.QueryRowContext(ctx,
+		`UPDATE heartbeats SET last_ping_at = NOW() WHERE token = $1
+		 RETURNING `+heartbeatColumns,
+		token,
+	))
+	if err == sql.ErrNoRows {
+		return models.Heartbeat{}, errors.New("heartbeat not found")
+	}
+	return hb, err
+}
+
+func scanHeartbeat(row interface{ Scan(...any) error }) (models.Heartbeat, error) {
+	var hb models.Heartbeat
+	err := row.Scan(&hb.ID, &hb.Name, &hb.Token, &hb.IntervalSeconds, &hb.GraceSeconds, &hb.Level, &hb.LastPingAt, &hb.CreatedBy, &hb.CreatedAt)
+	return hb, err
+}
+
+// Stakeholder subscription lists
+
+const stakeholderListColumns = `id, name, min_level, chat_id, emails, slack_webhooks, created_at`
+
+func (s *PostgresStore) CreateStakeholderList(ctx context.Context, l models.StakeholderList) (models.StakeholderList, error) {
+	emails, webhooks, err := marshalStakeholderList(l)
+	if err != nil {
+		return models.StakeholderList{}, err
+	}
+	return scanStakeholderList(s.db.QueryRowContext(ctx,
+		`INSERT INTO stakeholder_lists (name, min_level, chat_id, emails, slack_webhooks, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 RETURNING `+stakeholderListColumns,
+		l.Name, l.MinLevel, l.ChatID, emails, webhooks,
+	))
+}
+
+func (s *PostgresStore) UpdateStakeholderList(ctx context.Context, l models.StakeholderList) (models.StakeholderList, error) {
+	emails, webhooks, err := marshalStakeholderList(l)
+	if err != nil {
+		return models.StakeholderList{}, err
+	}
+	updated, err := scanStakeholderList(s.db.QueryRowContext(ctx,
+		`UPDATE stakeholder_lists SET name = $1, min_level = $2, chat_id = $3, emails = $4, slack_webhooks = $5
+		 WHERE id = $6
+		 RETURNING `+stakeholderListColumns,
+		l.Name, l.MinLevel, l.ChatID, emails, webhooks, l.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.StakeholderList{}, errors.New("stakeholder list not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetStakeholderLists(ctx context.Context) ([]models.StakeholderList, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+stakeholderListColumns+` FROM stakeholder_lists ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []models.StakeholderList
+	for rows.Next() {
+		l, err := scanStakeholderList(rows)
+		if err != nil {
+			continue
+		}
+		lists = append(lists, l)
+	}
+	return lists, nil
+}
+
+func (s *PostgresStore) DeleteStakeholderList(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM stakeholder_lists WHERE id = $1`, id)
+	return err
+}
+
+func marshalStakeholderList(l models.StakeholderList) (emails, webhooks []byte, err error) {
+	if emails, err = json.Marshal(nonNilSlice(l.Emails)); err != nil {
+		return
+	}
+	webhooks, err = json.Marshal(nonNilSlice(l.SlackWebhooks))
+	return
+}
+
+func nonNilSlice(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
+
+func scanStakeholderList(row interface{ Scan(...any) error }) (models.StakeholderList, error) {
+	var l models.StakeholderList
+	var emails, webhooks []byte
+	if err := row.Scan(&l.ID, &l.Name, &l.MinLevel, &l.ChatID, &emails, &webhooks, &l.CreatedAt); err != nil {
+		return models.StakeholderList{}, err
+	}
+	if err := json.Unmarshal(emails, &l.Emails); err != nil {
+		return models.StakeholderList{}, err
+	}
+	if err := json.Unmarshal(webhooks, &l.SlackWebhooks); err != nil {
+		return models.StakeholderList{}, err
+	}
+	return l, nil
+}
+
+// Payload schema registry
+
+const payloadSchemaColumns = `id, source, schema, violations, last_violation, last_violation_at, created_at`
+
+func (s *PostgresStore) CreatePayloadSchema(ctx context.Context, ps models.PayloadSchema) (models.PayloadSchema, error) {
+	return scanPayloadSchema(s.db.QueryRowContext(ctx,
+		`INSERT INTO payload_schemas (source, schema, created_at)
+		 VALUES ($1, $2, NOW())
+		 RETURNING `+payloadSchemaColumns,
+		ps.Source, []byte(ps.Schema),
+	))
+}
+
+// UpdatePayloadSchema replaces the schema and starts counting violations
+// against it from zero
+func (s *PostgresStore) UpdatePayloadSchema(ctx context.Context, ps models.PayloadSchema) (models.PayloadSchema, error) {
+	updated, err := scanPayloadSchema(s.db.QueryRowContext(ctx,
+		`UPDATE payload_schemas SET source = $1, schema = $2, violations = 0, last_violation = '', last_violation_at = NULL
+		 WHERE id = $3
+		 RETURNING `+payloadSchemaColumns,
+		ps.Source, []byte(ps.Schema), ps.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.PayloadSchema{}, errors.New("payload schema not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetPayloadSchemas(ctx context.Context) ([]models.PayloadSchema, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+payloadSchemaColumns+` FROM payload_schemas ORDER BY source`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schemas []models.PayloadSchema
+	for rows.Next() {
+		ps, err := scanPayloadSchema(rows)
+		if err != nil {
+			continue
+		}
+		schemas = append(schemas, ps)
+	}
+	return schemas, nil
+}
+
+func (s *PostgresStore) GetPayloadSchemaBySource(ctx context.Context, source string) (models.PayloadSchema, error) {
+	ps, err := scanPayloadSchema(s.reader().QueryRowContext(ctx,
+		`SELECT `+payloadSchemaColumns+` FROM payload_schemas WHERE source = $1`,
+		source,
+	))
+	if err == sql.ErrNoRows {
+		return models.PayloadSchema{}, errors.New("payload schema not found")
+	}
+	return ps, err
+}
+
+func (s *PostgresStore) DeletePayloadSchema(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM payload_schemas WHERE id = $1`, id)
+	return err
+}
+
+func (s *PostgresStore) RecordPayloadSchemaViolation(ctx context.Context, id int, violation string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE payload_schemas SET violations = violations + 1, last_violation = $1, last_violation_at = NOW() WHERE id = $2`,
+		violation, id,
+	)
+	return err
+}
+
+func scanPayloadSchema(row interface{ Scan(...any) error }) (models.PayloadSchema, error) {
+	var ps models.PayloadSchema
+	var schema []byte
+	if err := row.Scan(&ps.ID, &ps.Source, &schema, &ps.Violations, &ps.LastViolation, &ps.LastViolationAt, &ps.CreatedAt); err != nil {
+		return models.PayloadSchema{}, err
+	}
+	ps.Schema = schema
+	return ps, nil
+}
+
+// Email routes
+
+const emailRouteColumns = `id, name, chat_id, source, min_level, recipients, COALESCE(schedule_id, 0), escalate_after, COALESCE(calendar_id, 0), hours, enabled, created_at`
+
+func (s *PostgresStore) CreateEmailRoute(ctx context.Context, route models.EmailRoute) (models.EmailRoute, error) {
+	recipients, err := json.Marshal(nonNilSlice(route.Recipients))
+	if err != nil {
+		return models.EmailRoute{}, err
+	}
+	return scanEmailRoute(s.db.QueryRowContext(ctx,
+		`INSERT INTO email_routes (name, chat_id, source, min_level, recipients, schedule_id, escalate_after, calendar_id, hours, enabled, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7, NULLIF($8, 0), $9, $10, NOW())
+		 RETURNING `+emailRouteColumns,
+		route.Name, route.ChatID, route.Source, route.MinLevel, recipients, route.ScheduleID, route.EscalateAfter, route.CalendarID, route.Hours, route.Enabled,
+	))
+}
+
+func (s *PostgresStore) UpdateEmailRoute(ctx context.Context, route models.EmailRoute) (models.EmailRoute, error) {
+	recipients, err := json.Marshal(nonNilSlice(route.Recipients))
+	if err != nil {
+		return models.EmailRoute{}, err
+	}
+	updated, err := scanEmailRoute(s.db.QueryRowContext(ctx,
+		`UPDATE email_routes SET name = $1, chat_id = $2, source = $3, min_level = $4, recipients = $5, schedule_id = NULLIF($6, 0), escalate_after = $7,
+		 calendar_id = NULLIF($8, 0), hours = $9, enabled = $10
+		 WHERE id = $11
+		 RETURNING `+emailRouteColumns,
+		route.Name, route.ChatID, route.Source, route.MinLevel, recipients, route.ScheduleID, route.EscalateAfter, route.CalendarID, route.Hours, route.Enabled, route.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.EmailRoute{}, errors.New("email route not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetEmailRoutes(ctx context.Context) ([]models.EmailRoute, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+emailRouteColumns+` FROM email_routes ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var routes []models.EmailRoute
+	for rows.Next() {
+		route, err := scanEmailRoute(rows)
+		if err != nil {
+			continue
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+func (s *PostgresStore) DeleteEmailRoute(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM email_routes WHERE id = $1`, id)
+	return err
+}
+
+func scanEmailRoute(row interface{ Scan(...any) error }) (models.EmailRoute, error) {
+	var r models.EmailRoute
+	var recipients []byte
+	if err := row.Scan(&r.ID, &r.Name, &r.ChatID, &r.Source, &r.MinLevel, &recipients, &r.ScheduleID, &r.EscalateAfter, &r.CalendarID, &r.Hours, &r.Enabled, &r.CreatedAt); err != nil {
+		return models.EmailRoute{}, err
+	}
+	if err := json.Unmarshal(recipients, &r.Recipients); err != nil {
+		return models.EmailRoute{}, err
+	}
+	return r, nil
+}
+
+// Opsgenie forwarding rules
+
+const opsgenieRuleColumns = `id, name, chat_id, source, min_level, api_key_encrypted, region, team, COALESCE(calendar_id, 0), hours, enabled, created_at`
+
+func (s *PostgresStore) sealSecret(plaintext string) (string, error) {
+	if s.secrets == nil {
+		return "", secrets.ErrNoKey
+	}
+	return s.secrets.Seal(plaintext)
+}
+
+func (s *PostgresStore) CreateOpsgenieRule(ctx context.Context, rule models.OpsgenieRule) (models.OpsgenieRule, error) {
+	apiKey, err := s.sealSecret(rule.APIKey)
+	if err != nil {
+		return models.OpsgenieRule{}, err
+	}
+	return s.scanOpsgenieRule(s.db.QueryRowContext(ctx,
+		`INSERT INTO opsgenie_rules (name, chat_id, source, min_level, api_key_encrypted, region, team, calendar_id, hours, enabled, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0), $9, $10, NOW())
+		 RETURNING `+opsgenieRuleColumns,
+		rule.Name, rule.ChatID, rule.Source, rule.MinLevel, apiKey, rule.Region, rule.Team, rule.CalendarID, rule.Hours, rule.Enabled,
+	))
+}
+
+// UpdateOpsgenieRule changes a rule; an empty APIKey keeps the stored one
+func (s *PostgresStore) UpdateOpsgenieRule(ctx context.Context, rule models.OpsgenieRule) (models.OpsgenieRule, error) {
+	apiKey := ""
+	if rule.APIKey != "" {
+		var err error
+		if apiKey, err = s.sealSecret(rule.APIKey); err != nil {
+			return models.OpsgenieRule{}, err
+		}
+	}
+	updated, err := s.scanOpsgenieRule(s.db.QueryRowContext(ctx,
+		`UPDATE opsgenie_rules SET name = $1, chat_id = $2, source = $3, min_level = $4,
+		 api_key_encrypted = COALESCE(NULLIF($5, ''), api_key_encrypted), region = $6, team = $7, calendar_id = NULLIF($8, 0), hours = $9, enabled = $10
+		 WHERE id = $11
+		 RETURNING `+opsgenieRuleColumns,
+		rule.Name, rule.ChatID, rule.Source, rule.MinLevel, apiKey, rule.Region, rule.Team, rule.CalendarID, rule.Hours, rule.Enabled, rule.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.OpsgenieRule{}, errors.New("opsgenie rule not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetOpsgenieRules(ctx context.Context) ([]models.OpsgenieRule, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+opsgenieRuleColumns+` FROM opsgenie_rules ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []models.OpsgenieRule
+	for rows.Next() {
+		rule, err := s.scanOpsgenieRule(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (s *PostgresStore) DeleteOpsgenieRule(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM opsgenie_rules WHERE id = $1`, id)
+	return err
+}
+
+// scanOpsgenieRule decrypts the API key on the way out; without the key to
+// do so the rule is returned with an empty APIKey
+func (s *PostgresStore) scanOpsgenieRule(row interface{ Scan(...any) error }) (models.OpsgenieRule, error) {
+	var r models.OpsgenieRule
+	var apiKey string
+	if err := row.Scan(&r.ID, &r.Name, &r.ChatID, &r.Source, &r.MinLevel, &apiKey, &r.Region, &r.Team, &r.CalendarID, &r.Hours, &r.Enabled, &r.CreatedAt); err != nil {
+		return models.OpsgenieRule{}, err
+	}
+	if s.secrets != nil {
+		if plaintext, err := s.secrets.Open(apiKey); err == nil {
+			r.APIKey = plaintext
+		}
+	}
+	return r, nil
+}
+
+// Outgoing webhooks
+
+const outgoingWebhookColumns = `id, name, url, chat_id, source, min_level, events, template, secret_encrypted, COALESCE(calendar_id, 0), hours, enabled, created_at`
+
+// maxWebhookDeliveries is how many delivery attempts are kept per webhook
+const maxWebhookDeliveries = 500
+
+// CreateOutgoingWebhook stores a webhook; a signing secret needs the
+// encryption key, an unsigned webhook doesn't
+func (s *PostgresStore) CreateOutgoingWebhook(ctx context.Context, hook models.OutgoingWebhook) (models.OutgoingWebhook, error) {
+	events, err := json.Marshal(nonNilSlice(hook.Events))
+	if err != nil {
+		return models.OutgoingWebhook{}, err
+	}
+	secret := ""
+	if hook.Secret != "" {
+		if secret, err = s.sealSecret(hook.Secret); err != nil {
+			return models.OutgoingWebhook{}, err
+		}
+	}
+	return s.scanOutgoingWebhook(s.db.QueryRowContext(ctx,
+		`INSERT INTO outgoing_webhooks (name, url, chat_id, source, min_level, events, template, secret_encrypted, calendar_id, hours, enabled, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, 0), $10, $11, NOW())
+		 RETURNING `+outgoingWebhookColumns,
+		hook.Name, hook.URL, hook.ChatID, hook.Source, hook.MinLevel, events, hook.Template, secret, hook.CalendarID, hook.Hours, hook.Enabled,
+	))
+}
+
+// UpdateOutgoingWebhook changes a webhook; an empty Secret keeps the stored one
+func (s *PostgresStore) UpdateOutgoingWebhook(ctx context.Context, hook models.OutgoingWebhook) (models.OutgoingWebhook, error) {
+	events, err := json.Marshal(nonNilSlice(hook.Events))
+	if err != nil {
+		return models.OutgoingWebhook{}, err
+	}
+	secret := ""
+	if hook.Secret != "" {
+		if secret, err = s.sealSecret(hook.Secret); err != nil {
+			return models.OutgoingWebhook{}, err
+		}
+	}
+	updated, err := s.scanOutgoingWebhook(s.db.QueryRowContext(ctx,
+		`UPDATE outgoing_webhooks SET name = $1, url = $2, chat_id = $3, source = $4, min_level = $5, events = $6,
+		 template = $7, secret_encrypted = COALESCE(NULLIF($8, ''), secret_encrypted), calendar_id = NULLIF($9, 0), hours = $10, enabled = $11
+		 WHERE id = $12
+		 RETURNING `+outgoingWebhookColumns,
+		hook.Name, hook.URL, hook.ChatID, hook.Source, hook.MinLevel, events, hook.Template, secret, hook.CalendarID, hook.Hours, hook.Enabled, hook.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.OutgoingWebhook{}, errors.New("outgoing webhook not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetOutgoingWebhooks(ctx context.Context) ([]models.OutgoingWebhook, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+outgoingWebhookColumns+` FROM outgoing_webhooks ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []models.OutgoingWebhook
+	for rows.Next() {
+		hook, err := s.scanOutgoingWebhook(rows)
+		if err != nil {
+			continue
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, nil
+}
+
+func (s *PostgresStore) GetOutgoingWebhook(ctx context.Context, id int) (models.OutgoingWebhook, error) {
+	hook, err := s.scanOutgoingWebhook(s.reader().QueryRowContext(ctx,
+		`SELECT `+outgoingWebhookColumns+` FROM outgoing_webhooks WHERE id = $1`, id,
+	))
+	if err == sql.ErrNoRows {
+		return models.OutgoingWebhook{}, errors.New("outgoing webhook not found")
+	}
+	return hook, err
+}
+
+func (s *PostgresStore) DeleteOutgoingWebhook(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM outgoing_webhooks WHERE id = $1`, id)
+	return err
+}
+
+// RecordWebhookDelivery logs one delivery attempt, keeping the newest
+// maxWebhookDeliveries per webhook
+func (s *PostgresStore) RecordWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, delivery_id, alert_id, event, attempt, status_code, error, response, duration_ms, success, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())`,
+		d.WebhookID, d.DeliveryID, d.AlertID, d.Event, d.Attempt, d.StatusCode, d.Error, d.Response, d.DurationMs, d.Success,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE webhook_id = $1 AND id < (
+			SELECT id FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC OFFSET $2 LIMIT 1
+		 )`,
+		d.WebhookID, maxWebhookDeliveries-1,
+	)
+	return err
+}
+
+// GetWebhookDeliveries returns a webhook's delivery attempts, newest first
+func (s *PostgresStore) GetWebhookDeliveries(ctx context.Context, webhookID, limit int) ([]models.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT id, webhook_id, delivery_id, alert_id, event, attempt, status_code, error, response, duration_ms, success, created_at
+		 FROM webhook_deliveries WHERE webhook_id = $1
+		 ORDER BY id DESC
+		 LIMIT $2`,
+		webhookID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.DeliveryID, &d.AlertID, &d.Event, &d.Attempt, &d.StatusCode, &d.Error, &d.Response, &d.DurationMs, &d.Success, &d.CreatedAt); err != nil {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, nil
+}
+
+// NotificationAttemptRetention is how long notification delivery attempts
+// are kept
+const NotificationAttemptRetention = 30 * 24 * time.Hour
+
+// MaxNotificationAttempts caps one history query
+const MaxNotificationAttempts = 1000
+
+func (s *PostgresStore) RecordNotificationAttempt(ctx context.Context, a models.NotificationAttempt) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO notification_attempts (job_id, channel, target, attempt, status, error, latency_ms, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())`,
+		a.JobID, a.Channel, truncateBytes(a.Target, 255), a.Attempt, a.Status, a.Error, a.LatencyMs,
+	)
+	return err
+}
+
+// GetNotificationAttempts returns the delivery attempts matching f, newest
+// first
+func (s *PostgresStore) GetNotificationAttempts(ctx context.Context, f models.NotificationAttemptFilter) ([]models.NotificationAttempt, error) {
+	if f.Limit <= 0 || f.Limit > MaxNotificationAttempts {
+		f.Limit = 100
+	}
+	var since, until sql.NullTime
+	if !f.Since.IsZero() {
+		since = sql.NullTime{Time: f.Since, Valid: true}
+	}
+	if !f.Until.IsZero() {
+		until = sql.NullTime{Time: f.Until, Valid: true}
+	}
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT id, job_id, channel, target, attempt, status, error, latency_ms, created_at
+		 FROM notification_attempts
+		 WHERE ($1 = '' OR channel = $1)
+		   AND ($2 = '' OR target ILIKE '%' || $2 || '%')
+		   AND ($3 = '' OR status = $3)
+		   AND ($4::timestamptz IS NULL OR created_at >= $4)
+		   AND ($5::timestamptz IS NULL OR created_at < $5)
+		 ORDER BY created_at DESC, id DESC
+		 LIMIT $6`,
+		f.Channel, f.Target, f.Status, since, until, f.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.NotificationAttempt{}
+	for rows.Next() {
+		var a models.NotificationAttempt
+		if err := rows.Scan(&a.ID, &a.JobID, &a.Channel, &a.Target, &a.Attempt, &a.Status, &a.Error, &a.LatencyMs, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// PruneNotificationAttempts deletes the attempts made before before
+func (s *PostgresStore) PruneNotificationAttempts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM notification_attempts WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// scanOutgoingWebhook decrypts the signing secret on the way out; without
+// the key to do so the webhook is returned with an empty Secret
+func (s *PostgresStore) scanOutgoingWebhook(row interface{ Scan(...any) error }) (models.OutgoingWebhook, error) {
+	var h models.OutgoingWebhook
+	var events []byte
+	var secret string
+	if err := row.Scan(&h.ID, &h.Name, &h.URL, &h.ChatID, &h.Source, &h.MinLevel, &events, &h.Template, &secret, &h.CalendarID, &h.Hours, &h.Enabled, &h.CreatedAt); err != nil {
+		return models.OutgoingWebhook{}, err
+	}
+	if err := json.Unmarshal(events, &h.Events); err != nil {
+		return models.OutgoingWebhook{}, err
+	}
+	if secret != "" && s.secrets != nil {
+		if plaintext, err := s.secrets.Open(secret); err == nil {
+			h.Secret = plaintext
+		}
+	}
+	return h, nil
+}
+
+// Notification templates
+
+const notificationTemplateColumns = `id, channel, level, subject, body, updated_at`
+
+// SaveNotificationTemplate creates the template for t's channel and level,
+// or replaces it
+func (s *PostgresStore) SaveNotificationTemplate(ctx context.Context, t models.NotificationTemplate) (models.NotificationTemplate, error) {
+	var saved models.NotificationTemplate
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO notification_templates (channel, level, subject, body, updated_at)
+		 VALUES ($1, $2, $3, $4, NOW())
+		 ON CONFLICT (channel, level) DO UPDATE SET subject = $3, body = $4, updated_at = NOW()
+		 RETURNING `+notificationTemplateColumns,
+		t.Channel, t.Level, t.Subject, t.Body,
+	).Scan(&saved.ID, &saved.Channel, &saved.Level, &saved.Subject, &saved.Body, &saved.UpdatedAt)
+	return saved, err
+}
+
+func (s *PostgresStore) GetNotificationTemplates(ctx context.Context) ([]models.NotificationTemplate, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+notificationTemplateColumns+` FROM notification_templates ORDER BY channel, level`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []models.NotificationTemplate
+	for rows.Next() {
+		var t models.NotificationTemplate
+		if err := rows.Scan(&t.ID, &t.Channel, &t.Level, &t.Subject, &t.Body, &t.UpdatedAt); err != nil {
+			continue
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+func (s *PostgresStore) DeleteNotificationTemplate(ctx context.Context, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM notification_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("notification template not found")
+	}
+	return nil
+}
+
+// Dependency checks
+
+const dependencyCheckColumns = `id, name, url, chat_id, source, timeout_ms, enabled, created_at`
+
+func (s *PostgresStore) CreateDependencyCheck(ctx context.Context, d models.DependencyCheck) (models.DependencyCheck, error) {
+	return scanDependencyCheck(s.db.QueryRowContext(ctx,
+		`INSERT INTO dependency_checks (name, url, chat_id, source, timeout_ms, enabled, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		 RETURNING `+dependencyCheckColumns,
+		d.Name, d.URL, d.ChatID, d.Source, d.TimeoutMs, d.Enabled,
+	))
+}
+
+func (s *PostgresStore) UpdateDependencyCheck(ctx context.Context, d models.DependencyCheck) (models.DependencyCheck, error) {
+	updated, err := scanDependencyCheck(s.db.QueryRowContext(ctx,
+		`UPDATE dependency_checks SET name = $1, url = $2, chat_id = $3, source = $4, timeout_ms = $5, enabled = $6
+		 WHERE id = $7
+		 RETURNING `+dependencyCheckColumns,
+		d.Name, d.URL, d.ChatID, d.Source, d.TimeoutMs, d.Enabled, d.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.DependencyCheck{}, errors.New("dependency check not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetDependencyChecks(ctx context.Context) ([]models.DependencyCheck, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+dependencyCheckColumns+` FROM dependency_checks ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []models.DependencyCheck
+	for rows.Next() {
+		d, err := scanDependencyCheck(rows)
+		if err != nil {
+			continue
+		}
+		checks = append(checks, d)
+	}
+	return checks, nil
+}
+
+func (s *PostgresStore) DeleteDependencyCheck(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM dependency_checks WHERE id = $1`, id)
+	return err
+}
+
+func scanDependencyCheck(row interface{ Scan(...any) error }) (models.DependencyCheck, error) {
+	var d models.DependencyCheck
+	err := row.Scan(&d.ID, &d.Name, &d.URL, &d.ChatID, &d.Source, &d.TimeoutMs, &d.Enabled, &d.CreatedAt)
+	return d, err
+}
+
+// On-call schedules
+
+const scheduleColumns = `id, name, timezone, participants, shift_days, handoff_time, start_date, created_at`
+
+const scheduleOverrideColumns = `id, schedule_id, user_id, starts_at, ends_at, reason, created_at`
+
+func (s *PostgresStore) CreateSchedule(ctx context.Context, sc models.Schedule) (models.Schedule, error) {
+	participants, err := json.Marshal(nonNilInts(sc.Participants))
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	return scanSchedule(s.db.QueryRowContext(ctx,
+		`INSERT INTO oncall_schedules (name, timezone, participants, shift_days, handoff_time, start_date, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		 RETURNING `+scheduleColumns,
+		sc.Name, sc.Timezone, participants, sc.ShiftDays, sc.HandoffTime, sc.StartDate,
+	))
+}
+
+func (s *PostgresStore) UpdateSchedule(ctx context.Context, sc models.Schedule) (models.Schedule, error) {
+	participants, err := json.Marshal(nonNilInts(sc.Participants))
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	updated, err := scanSchedule(s.db.QueryRowContext(ctx,
+		`UPDATE oncall_schedules SET name = $1, timezone = $2, participants = $3, shift_days = $4, handoff_time = $5, start_date = $6
+		 WHERE id = $7
+		 RETURNING `+scheduleColumns,
+		sc.Name, sc.Timezone, participants, sc.ShiftDays, sc.HandoffTime, sc.StartDate, sc.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.Schedule{}, errors.New("schedule not found")
+	}
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	updated.Overrides, err = s.scheduleOverrides(ctx, updated.ID)
+	return updated, err
+}
+
+// GetSchedules lists the schedules with their current and upcoming overrides
+func (s *PostgresStore) GetSchedules(ctx context.Context) ([]models.Schedule, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+scheduleColumns+` FROM oncall_schedules ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []models.Schedule
+	byID := make(map[int]int)
+	for rows.Next() {
+		sc, err := scanSchedule(rows)
+		if err != nil {
+			continue
+		}
+		byID[sc.ID] = len(schedules)
+		schedules = append(schedules, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := s.reader().QueryContext(ctx,
+		`SELECT `+scheduleOverrideColumns+` FROM oncall_overrides WHERE ends_at > NOW() ORDER BY starts_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer overrides.Close()
+	for overrides.Next() {
+		o, err := scanScheduleOverride(overrides)
+		if err != nil {
+			continue
+		}
+		if i, ok := byID[o.ScheduleID]; ok {
+			schedules[i].Overrides = append(schedules[i].Overrides, o)
+		}
+	}
+	return schedules, overrides.Err()
+}
+
+func (s *PostgresStore) GetSchedule(ctx context.Context, id int) (models.Schedule, error) {
+	sc, err := scanSchedule(s.reader().QueryRowContext(ctx,
+		`SELECT `+scheduleColumns+` FROM oncall_schedules WHERE id = $1`, id,
+	))
+	if err == sql.ErrNoRows {
+		return models.Schedule{}, errors.New("schedule not found")
+	}
+	if err != nil {
+		return models.Schedule{}, err
+	}
+	sc.Overrides, err = s.scheduleOverrides(ctx, id)
+	return sc, err
+}
+
+func (s *PostgresStore) DeleteSchedule(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM oncall_schedules WHERE id = $1`, id)
+	return err
+}
+
+// CreateScheduleOverride adds an override, dropping the schedule's overrides
+// that ended more than 90 days ago
+func (s *PostgresStore) CreateScheduleOverride(ctx context.Context, o models.ScheduleOverride) (models.ScheduleOverride, error) {
+	created, err := scanScheduleOverride(s.db.QueryRowContext(ctx,
+		`INSERT INTO oncall_overrides (schedule_id, user_id, starts_at, ends_at, reason, created_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 RETURNING `+scheduleOverrideColumns,
+		o.ScheduleID, o.UserID, o.StartsAt, o.EndsAt, o.Reason,
+	))
+	if err != nil {
+		return models.ScheduleOverride{}, err
+	}
+	_, _ = s.db.ExecContext(ctx,
+		`DELETE FROM oncall_overrides WHERE schedule_id = $1 AND ends_at < NOW() - INTERVAL '90 days'`, o.ScheduleID,
+	)
+	return created, nil
+}
+
+func (s *PostgresStore) DeleteScheduleOverride(ctx context.Context, scheduleID, id int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM oncall_overrides WHERE id = $1 AND schedule_id = $2`, id, scheduleID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("override not found")
+	}
+	return nil
+}
+
+// scheduleOverrides lists a schedule's current and upcoming overrides
+func (s *PostgresStore) scheduleOverrides(ctx context.Context, scheduleID int) ([]models.ScheduleOverride, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+scheduleOverrideColumns+` FROM oncall_overrides WHERE schedule_id = $1 AND ends_at > NOW() ORDER BY starts_at`, scheduleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []models.ScheduleOverride
+	for rows.Next() {
+		o, err := scanScheduleOverride(rows)
+		if err != nil {
+			continue
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+func nonNilInts(v []int) []int {
+	if v == nil {
+		return []int{}
+	}
+	return v
+}
+
+func scanSchedule(row interface{ Scan(...any) error }) (models.Schedule, error) {
+	var sc models.Schedule
+	var participants []byte
+	var startDate time.Time
+	if err := row.Scan(&sc.ID, &sc.Name, &sc.Timezone, &participants, &sc.ShiftDays, &sc.HandoffTime, &startDate, &sc.CreatedAt); err != nil {
+		return models.Schedule{}, err
+	}
+	if err := json.Unmarshal(participants, &sc.Participants); err != nil {
+		return models.Schedule{}, err
+	}
+	sc.StartDate = startDate.Format("2006-01-02")
+	return sc, nil
+}
+
+func scanScheduleOverride(row interface{ Scan(...any) error }) (models.ScheduleOverride, error) {
+	var o models.ScheduleOverride
+	err := row.Scan(&o.ID, &o.ScheduleID, &o.UserID, &o.StartsAt, &o.EndsAt, &o.Reason, &o.CreatedAt)
+	return o, err
+}
+
+// Business-hours calendars
+
+const businessCalendarColumns = `id, name, COALESCE(team_id, 0), timezone, days, start_time, end_time, holidays, created_at`
+
+func (s *PostgresStore) CreateBusinessCalendar(ctx context.Context, c models.BusinessCalendar) (models.BusinessCalendar, error) {
+	days, holidays, err := marshalCalendarLists(c)
+	if err != nil {
+		return models.BusinessCalendar{}, err
+	}
+	return scanBusinessCalendar(s.db.QueryRowContext(ctx,
+		`INSERT INTO business_calendars (name, team_id, timezone, days, start_time, end_time, holidays, created_at)
+		 VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6, $7, NOW())
+		 RETURNING `+businessCalendarColumns,
+		c.Name, c.TeamID, c.Timezone, days, c.Start, c.End, holidays,
+	))
+}
+
+func (s *PostgresStore) UpdateBusinessCalendar(ctx context.Context, c models.BusinessCalendar) (models.BusinessCalendar, error) {
+	days, holidays, err := marshalCalendarLists(c)
+	if err != nil {
+		return models.BusinessCalendar{}, err
+	}
+	updated, err := scanBusinessCalendar(s.db.QueryRowContext(ctx,
+		`UPDATE business_calendars SET name = $1, team_id = NULLIF($2, 0), timezone = $3, days = $4, start_time = $5, end_time = $6, holidays = $7
+		 WHERE id = $8
+		 RETURNING `+businessCalendarColumns,
+		c.Name, c.TeamID, c.Timezone, days, c.Start, c.End, holidays, c.ID,
+	))
+	if err == sql.ErrNoRows {
+		return models.BusinessCalendar{}, errors.New("calendar not found")
+	}
+	return updated, err
+}
+
+func (s *PostgresStore) GetBusinessCalendars(ctx context.Context) ([]models.BusinessCalendar, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+businessCalendarColumns+` FROM business_calendars ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var calendars []models.BusinessCalendar
+	for rows.Next() {
+		c, err := scanBusinessCalendar(rows)
+		if err != nil {
+			continue
+		}
+		calendars = append(calendars, c)
+	}
+	return calendars, rows.Err()
+}
+
+func (s *PostgresStore) GetBusinessCalendar(ctx context.Context, id int) (models.BusinessCalendar, error) {
+	c, err := scanBusinessCalendar(s.reader().QueryRowContext(ctx,
+		`SELECT `+businessCalendarColumns+` FROM business_calendars WHERE id = $1`, id,
+	))
+	if err == sql.ErrNoRows {
+		return models.BusinessCalendar{}, errors.New("calendar not found")
+	}
+	return c, err
+}
+
+// DeleteBusinessCalendar removes a calendar; rules that branched on it send
+// at any time again
+func (s *PostgresStore) DeleteBusinessCalendar(ctx context.Context, id int) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM business_calendars WHERE id = $1`, id)
+	return err
+}
+
+// CalendarsByID loads the business-hours calendars, for HoursRule.AllowsAt.
+// When they can't be loaded the map is empty, and rules send at any time.
+func CalendarsByID(ctx context.Context, admin AdminStore) map[int]models.BusinessCalendar {
+	calendars, err := admin.GetBusinessCalendars(ctx)
+	if err != nil {
+		log.Printf("Failed to load business-hours calendars: %v", err)
+	}
+	byID := make(map[int]models.BusinessCalendar, len(calendars))
+	for _, c := range calendars {
+		byID[c.ID] = c
+	}
+	return byID
+}
+
+func marshalCalendarLists(c models.BusinessCalendar) (days, holidays []byte, err error) {
+	if days, err = json.Marshal(nonNilInts(c.Days)); err != nil {
+		return nil, nil, err
+	}
+	holidays, err = json.Marshal(nonNilSlice(c.Holidays))
+	return days, holidays, err
+}
+
+func scanBusinessCalendar(row interface{ Scan(...any) error }) (models.BusinessCalendar, error) {
+	var c models.BusinessCalendar
+	var days, holidays []byte
+	if err := row.Scan(&c.ID, &c.Name, &c.TeamID, &c.Timezone, &days, &c.Start, &c.End, &holidays, &c.CreatedAt); err != nil {
+		return models.BusinessCalendar{}, err
+	}
+	if err := json.Unmarshal(days, &c.Days); err != nil {
+		return models.BusinessCalendar{}, err
+	}
+	if err := json.Unmarshal(holidays, &c.Holidays); err != nil {
+		return models.BusinessCalendar{}, err
+	}
+	return c, nil
+}
+
+// Signature exemptions
+
+const signatureExemptionColumns = `endpoint, reason, created_by, created_at`
+
+// GetSignatureExemptions decides whether ingestion needs signatures, so
+// while the database is down it answers from the last successful lookup
+func (s *PostgresStore) GetSignatureExemptions(ctx context.Context) ([]models.SignatureExemption, error) {
+	return lastGoodLookup(s, &s.exemptions, "", func() ([]models.SignatureExemption, error) {
+		rows, err := s.db.QueryContext(ctx,
+			`SELECT `+signatureExemptionColumns+` FROM signature_exemptions ORDER BY endpoint`,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var exemptions []models.SignatureExemption
+		for rows.Next() {
+			var e models.SignatureExemption
+			if err := rows.Scan(&e.Endpoint, &e.Reason, &e.CreatedBy, &e.CreatedAt); err != nil {
+				return nil, err
+			}
+			exemptions = append(exemptions, e)
+		}
+		return exemptions, rows.Err()
+	})
+}
+
+// SaveSignatureExemption adds an exemption or updates its reason
+func (s *PostgresStore) SaveSignatureExemption(ctx context.Context, e models.SignatureExemption) (models.SignatureExemption, error) {
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO signature_exemptions (endpoint, reason, created_by, created_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT (endpoint) DO UPDATE SET reason = EXCLUDED.reason
+		 RETURNING `+signatureExemptionColumns,
+		e.Endpoint, e.Reason, e.CreatedBy,
+	).Scan(&e.Endpoint, &e.Reason, &e.CreatedBy, &e.CreatedAt)
+	return e, err
+}
+
+func (s *PostgresStore) DeleteSignatureExemption(ctx context.Context, endpoint string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM signature_exemptions WHERE endpoint = $1`, endpoint)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("exemption not found")
+	}
+	return nil
+}
+
+// Feature flags
+
+const featureFlagColumns = `key, description, enabled, user_ids, roles, org_ids, updated_at`
+
+func (s *PostgresStore) GetFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+featureFlagColumns+` FROM feature_flags ORDER BY key`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var flags []models.FeatureFlag
+	for rows.Next() {
+		f, err := scanFeatureFlag(rows)
+		if err != nil {
+			continue
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+func (s *PostgresStore) GetFeatureFlag(ctx context.Context, key string) (models.FeatureFlag, error) {
+	f, err := scanFeatureFlag(s.reader().QueryRowContext(ctx,
+		`SELECT `+featureFlagColumns+` FROM feature_flags WHERE key = $1`, key,
+	))
+	if err == sql.ErrNoRows {
+		return models.FeatureFlag{}, errors.New("feature flag not found")
+	}
+	return f, err
+}
+
+func (s *PostgresStore) SaveFeatureFlag(ctx context.Context, f models.FeatureFlag) (models.FeatureFlag, error) {
+	userIDs, err := json.Marshal(nonNilInts(f.UserIDs))
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	roles, err := json.Marshal(nonNilSlice(f.Roles))
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	orgIDs, err := json.Marshal(nonNilInts(f.OrgIDs))
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	return scanFeatureFlag(s.db.QueryRowContext(ctx,
+		`INSERT INTO feature_flags (key, description, enabled, user_ids, roles, org_ids, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		 ON CONFLICT (key) DO UPDATE SET description = EXCLUDED.description, enabled = EXCLUDED.enabled,
+		   user_ids = EXCLUDED.user_ids, roles = EXCLUDED.roles, org_ids = EXCLUDED.org_ids, updated_at = NOW()
+		 RETURNING `+featureFlagColumns,
+		f.Key, f.Description, f.Enabled, userIDs, roles, orgIDs,
+	))
+}
+
+func (s *PostgresStore) DeleteFeatureFlag(ctx context.Context, key string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("feature flag not found")
+	}
+	return nil
+}
+
+func scanFeatureFlag(row interface{ Scan(...any) error }) (models.FeatureFlag, error) {
+	var f models.FeatureFlag
+	var userIDs, roles, orgIDs []byte
+	if err := row.Scan(&f.Key, &f.Description, &f.Enabled, &userIDs, &roles, &orgIDs, &f.UpdatedAt); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	if err := json.Unmarshal(orgIDs, &f.OrgIDs); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	if err := json.Unmarshal(userIDs, &f.UserIDs); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	if err := json.Unmarshal(roles, &f.Roles); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	return f, nil
+}
+
+// SMS paging preferences
+
+const smsPreferenceColumns = `user_id, phone, enabled, updated_at`
+
+func (s *PostgresStore) GetSMSPreference(ctx context.Context, userID int) (models.SMSPreference, error) {
+	p, err := scanSMSPreference(s.db.QueryRowContext(ctx,
+		`SELECT `+smsPreferenceColumns+` FROM sms_preferences WHERE user_id = $1`, userID,
+	))
+	if err == sql.ErrNoRows {
+		return models.SMSPreference{}, errors.New("sms preference not found")
+	}
+	return p, err
+}
+
+func (s *PostgresStore) SaveSMSPreference(ctx context.Context, p models.SMSPreference) (models.SMSPreference, error) {
+	return scanSMSPreference(s.db.QueryRowContext(ctx,
+		`INSERT INTO sms_preferences (user_id, phone, enabled, updated_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT (user_id) DO UPDATE SET phone = EXCLUDED.phone, enabled = EXCLUDED.enabled, updated_at = NOW()
+		 RETURNING `+smsPreferenceColumns,
+		p.UserID, p.Phone, p.Enabled,
+	))
+}
+
+func (s *PostgresStore) GetEnabledSMSPreferences(ctx context.Context) ([]models.SMSPreference, error) {
+	rows, err := s.reader().QueryContext(ctx,
+		`SELECT `+smsPreferenceColumns+` FROM sms_preferences WHERE enabled ORDER BY user_id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []models.SMSPreference
+	for rows.Next() {
+		p, err := scanSMSPreference(rows)
+		if err != nil {
+			continue
+		}
+		prefs = append(prefs, p)
+	}
+	return prefs, nil
+}
+
+func scanSMSPreference(row interface{ Scan(...any) error }) (models.SMSPreference, error) {
+	var p models.SMSPreference
+	err := row.Scan(&p.UserID, &p.Phone, &p.Enabled, &p.UpdatedAt)
+	return p, err
+}
+
+func marshalMapping(m models.WebhookMapping) (fields, defaults, levelMap []byte, err error) {
+	if fields, err = json.Marshal(nonNilMap(m.Fields)); err != nil {
+		return
+	}
+	if defaults, err = json.Marshal(nonNilMap(m.Defaults)); err != nil {
+		return
+	}
+	levelMap, err = json.Marshal(nonNilMap(m.LevelMap))
+	return
+}
+
+func nonNilMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func scanWebhookMapping(row interface{ Scan(...any) error }) (models.WebhookMapping, error) {
+	var m models.WebhookMapping
+	var fields, defaults, levelMap []byte
+	if err := row.Scan(&m.ID, &m.Name, &fields, &defaults, &levelMap, &m.CreatedAt); err != nil {
+		return models.WebhookMapping{}, err
+	}
+	if err := json.Unmarshal(fields, &m.Fields); err != nil {
+		return models.WebhookMapping{}, err
+	}
+	if err := json.Unmarshal(defaults, &m.Defaults); err != nil {
+		return models.WebhookMapping{}, err
+	}
+	if err := json.Unmarshal(levelMap, &m.LevelMap); err != nil {
+		return models.WebhookMapping{}, err
+	}
+	return m, nil
 }
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"testing"
+
+	"incident-viewer-go/internal/models"
+)
+
+func TestPurgeFilterMatchesWholeChatID(t *testing.T) {
+	sources := []string{
+		"bot:ci:chat:1",
+		"bot:ci:chat:12",
+		"bot:ci:chat:100",
+		"bot:ci:chat:-1001",
+		"bot:ci:chat:-1001234",
+		"bot:deploy:chat:21",
+		"github",
+	}
+	tests := []struct {
+		filter PurgeFilter
+		want   []string
+	}{
+		{PurgeFilter{ChatID: "1"}, []string{"bot:ci:chat:1"}},
+		{PurgeFilter{ChatID: "12"}, []string{"bot:ci:chat:12"}},
+		{PurgeFilter{ChatID: "-1001"}, []string{"bot:ci:chat:-1001"}},
+		{PurgeFilter{ChatID: "2"}, nil},
+		{PurgeFilter{NoChat: true}, []string{"github"}},
+	}
+	for _, tt := range tests {
+		want := map[string]bool{}
+		for _, src := range tt.want {
+			want[src] = true
+		}
+		for _, src := range sources {
+			if got := tt.filter.matches(models.Alert{Source: src}); got != want[src] {
+				t.Errorf("%+v matches %q = %v, want %v", tt.filter, src, got, want[src])
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"incident-viewer-go/internal/secrets"
+)
+
+// encryptedColumns are the columns that hold credentials. The first three
+// may still hold values stored before there was an encryption key.
+var encryptedColumns = []struct{ table, column string }{
+	{"users", "totp_secret"},
+	{"bots", "token"},
+	{"bots", "signing_secret"},
+	{"opsgenie_rules", "api_key_encrypted"},
+	{"outgoing_webhooks", "secret_encrypted"},
+}
+
+// ReencryptResult counts, for one column, the values ReencryptSecrets sealed
+// again and those it couldn't open
+type ReencryptResult struct {
+	Table, Column string
+	Reencrypted   int
+	Failed        int
+}
+
+// ReencryptSecrets seals every stored credential that isn't yet sealed
+// under the current key in the current format: values stored before the key
+// was set, under a previous key or in the v1 format. It is safe to run again
+// and while Sentinel runs; a value changed in between is left for the next
+// run.
+func (s *PostgresStore) ReencryptSecrets(ctx context.Context) ([]ReencryptResult, error) {
+	if s.secrets == nil {
+		return nil, secrets.ErrNoKey
+	}
+	var results []ReencryptResult
+	for _, c := range encryptedColumns {
+		result, err := s.reencryptColumn(ctx, c.table, c.column)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("%s.%s: %w", c.table, c.column, err)
+		}
+	}
+	return results, nil
+}
+
+func (s *PostgresStore) reencryptColumn(ctx context.Context, table, column string) (ReencryptResult, error) {
+	result := ReencryptResult{Table: table, Column: column}
+	rows, err := s.db.QueryContext(ctx,
+		fmt.Sprintf(`SELECT id, %s FROM %s WHERE %s IS NOT NULL AND %s <> ''`, column, table, column, column),
+	)
+	if err != nil {
+		return result, err
+	}
+	stored := map[int]string{}
+	for rows.Next() {
+		var id int
+		var value string
+		if err := rows.Scan(&id, &value); err != nil {
+			rows.Close()
+			return result, err
+		}
+		if !s.secrets.Current(value) {
+			stored[id] = value
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return result, err
+	}
+
+	for id, value := range stored {
+		plaintext, err := s.openOptional(value)
+		if err != nil {
+			result.Failed++
+			continue
+		}
+		sealed, err := s.secrets.Seal(plaintext)
+		if err != nil {
+			return result, err
+		}
+		res, err := s.db.ExecContext(ctx,
+			fmt.Sprintf(`UPDATE %s SET %s = $2 WHERE id = $1 AND %s = $3`, table, column, column),
+			id, sealed, value,
+		)
+		if err != nil {
+			return result, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Reencrypted++
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+)
+
+// mirrorBacklog is how many alert changes can wait for the mirror; past it
+// changes are dropped (and counted), the alert is mirrored on its next change
+const mirrorBacklog = 4096
+
+// Replication mirrors alerts to a Redis in another region and reads from it
+// when the primary is down. Each region writing alerts gets its own Region
+// index out of Regions, and alert IDs are striped by it, so two regions
+// mirroring into each other never hand out the same ID.
+type Replication struct {
+	Secondary *redis.Options
+	Region    int // 0-based index of this region
+	Regions   int // Regions writing alerts; 1 when only this one does
+}
+
+// ReplicationFromEnv reads REDIS_SECONDARY_ADDR (with REDIS_SECONDARY_PASSWORD
+// and REDIS_SECONDARY_DB), REDIS_REGION (default 0) and REDIS_REGIONS (default
+// 1). ok is false without a secondary.
+func ReplicationFromEnv() (r Replication, ok bool, err error) {
+	addr := os.Getenv("REDIS_SECONDARY_ADDR")
+	if addr == "" {
+		return Replication{}, false, nil
+	}
+	r = Replication{Secondary: &redis.Options{Addr: addr, Password: os.Getenv("REDIS_SECONDARY_PASSWORD")}, Regions: 1}
+	for key, dst := range map[string]*int{"REDIS_SECONDARY_DB": &r.Secondary.DB, "REDIS_REGION": &r.Region, "REDIS_REGIONS": &r.Regions} {
+		v := os.Getenv(key)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return r, false, fmt.Errorf("invalid %s %q", key, v)
+		}
+		*dst = n
+	}
+	if r.Regions < 1 || r.Region >= r.Regions {
+		return r, false, fmt.Errorf("REDIS_REGION must be below REDIS_REGIONS (%d)", r.Regions)
+	}
+	return r, true, nil
+}
+
+// replica is the secondary Redis and the queue of alerts to copy to it
+type replica struct {
+	client  *redis.Client
+	region  int
+	regions int
+	pending chan mirrorOp
+	dropped atomic.Int64
+}
+
+// mirrorOp names an alert to copy, with the index sets it was added to
+type mirrorOp struct {
+	id      int
+	indexes []string
+}
+
+// EnableReplication starts mirroring alerts stored from now on to r's
+// secondary and reading from it when the primary fails. RunMirror does the
+// copying.
+func (s *RedisStore) EnableReplication(r Replication) {
+	s.replica = &replica{
+		client:  redis.NewClient(r.Secondary),
+		region:  r.Region,
+		regions: max(r.Regions, 1),
+		pending: make(chan mirrorOp, mirrorBacklog),
+	}
+}
+
+// nextAlertID allocates an alert ID. With several regions the counter is
+// striped: region i of n only hands out IDs equal to i modulo n.
+func (s *RedisStore) nextAlertID(ctx context.Context) (int, error) {
+	n, err := s.client.Incr(ctx, "alert:next_id").Result()
+	if err != nil {
+		return 0, err
+	}
+	if s.replica == nil || s.replica.regions == 1 {
+		return int(n), nil
+	}
+	return int(n)*s.replica.regions + s.replica.region, nil
+}
+
+// mirror queues an alert for copying; it never blocks the write
+func (s *RedisStore) mirror(id int, indexes ...string) {
+	if s.replica == nil {
+		return
+	}
+	select {
+	case s.replica.pending <- mirrorOp{id: id, indexes: indexes}:
+	default:
+		if s.replica.dropped.Add(1)%100 == 1 {
+			log.Printf("store: mirror backlog full, %d alert change(s) not mirrored so far", s.replica.dropped.Load())
+		}
+	}
+}
+
+// RunMirror copies queued alerts to the secondary until ctx is done. Each
+// copy is the alert as it is in the primary at that moment, so a late or
+// repeated copy never puts back an older version.
+func (s *RedisStore) RunMirror(ctx context.Context) {
+	if s.replica == nil {
+		return
+	}
+	failing := false
+	for {
+		select {
+		case op := <-s.replica.pending:
+			err := s.copyAlert(ctx, op)
+			switch {
+			case err != nil:
+				if !failing {
+					log.Printf("store: failed to mirror alert %d to the secondary Redis, will keep trying: %v", op.id, err)
+					failing = true
+				}
+				// Back in the queue for when the secondary is back; a full
+				// queue drops it
+				s.mirror(op.id, op.indexes...)
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+					return
+				}
+			case failing:
+				log.Printf("store: mirroring to the secondary Redis again")
+				failing = false
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *RedisStore) copyAlert(ctx context.Context, op mirrorOp) error {
+	key := fmt.Sprintf("alert:%d", op.id)
+	val, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return s.replica.client.ZRem(ctx, "alerts:timeline", key).Err() // Gone in the meantime
+	} else if err != nil {
+		return err
+	}
+	ttl, err := s.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		ttl = alertTTL
+	}
+	var a models.Alert
+	if err := json.Unmarshal([]byte(val), &a); err != nil {
+		return err
+	}
+
+	pipe := s.replica.client.Pipeline()
+	pipe.Set(ctx, key, val, ttl)
+	pipe.ZAdd(ctx, "alerts:timeline", redis.Z{Score: float64(a.CreatedAt.Unix()), Member: key})
+	for _, index := range op.indexes {
+		pipe.SAdd(ctx, index, key)
+		pipe.Expire(ctx, index, alertTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// fallback reports whether a failed primary read should be retried on the
+// secondary
+func (s *RedisStore) fallback(err error) bool {
+	if s.replica == nil || err == nil || errors.Is(err, redis.Nil) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	log.Printf("store: primary Redis read failed, reading from the secondary: %v", err)
+	return true
+}
+
+// levelIndex is the search index set for an alert level
+func levelIndex(level string) string {
+	return fmt.Sprintf("alerts:level:%s", strings.ToLower(level))
+}
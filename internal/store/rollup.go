@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+)
+
+const (
+	// rollupKey is the hash behind GetAlertRollup. Its fields:
+	//   n|{hour}|{chat}|{level}  alerts created in that hour (unix seconds)
+	//   seq|{chat}               alerts added to the chat's ring
+	//   slot|{chat}|{i}          an alert in the ring of RollupLatest, as JSON
+	//   owner|{chat}|{i}         the ID of the alert in that slot
+	//   at|{id}                  the slot holding an alert, for updates
+	rollupKey = "alerts:rollup"
+
+	// RollupLatest is how many alerts the rollup keeps per chat
+	RollupLatest = 50
+
+	rollupWindow = 24 * time.Hour
+	rollupTTL    = rollupWindow + time.Hour // Refreshed on every new alert
+)
+
+// rollupAddScript counts a new alert and puts it in its chat's ring,
+// dropping the one it replaces
+var rollupAddScript = redis.NewScript(`
+local key, count, chat, id, alert, size, ttl = KEYS[1], ARGV[1], ARGV[2], ARGV[3], ARGV[4], tonumber(ARGV[5]), ARGV[6]
+redis.call('HINCRBY', key, count, 1)
+local i = redis.call('HINCRBY', key, 'seq|' .. chat, 1) % size
+local slot, owner = 'slot|' .. chat .. '|' .. i, 'owner|' .. chat .. '|' .. i
+local old = redis.call('HGET', key, owner)
+if old then
+	redis.call('HDEL', key, 'at|' .. old)
+end
+redis.call('HSET', key, slot, alert, owner, id, 'at|' .. id, slot)
+redis.call('PEXPIRE', key, ttl)
+return 1
+`)
+
+// rollupUpdateScript replaces an alert still in a ring with its new version
+var rollupUpdateScript = redis.NewScript(`
+local slot = redis.call('HGET', KEYS[1], 'at|' .. ARGV[1])
+if slot then
+	redis.call('HSET', KEYS[1], slot, ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// rollupJSON is the alert as kept in the rollup, without its raw payload
+func rollupJSON(a models.Alert) ([]byte, error) {
+	a.Payload = nil
+	return json.Marshal(a)
+}
+
+func rollupCountField(a models.Alert) string {
+	hour := a.CreatedAt.Truncate(time.Hour).Unix()
+	return fmt.Sprintf("n|%d|%s|%s", hour, statsChatID(a.Source), strings.ToLower(a.Level))
+}
+
+// addToRollup counts a newly stored alert; a failure only leaves the index
+// page behind until the next rebuild, so it is logged rather than returned
+func (s *RedisStore) addToRollup(ctx context.Context, a models.Alert) {
+	data, err := rollupJSON(a)
+	if err == nil {
+		err = rollupAddScript.Run(ctx, s.client, []string{rollupKey},
+			rollupCountField(a), statsChatID(a.Source), a.ID, data, RollupLatest, rollupTTL.Milliseconds()).Err()
+	}
+	if err != nil {
+		fmt.Println("Failed to update alert rollup:", err)
+	}
+}
+
+// updateRollup refreshes a changed alert if the rollup still lists it
+func (s *RedisStore) updateRollup(ctx context.Context, a models.Alert) {
+	data, err := rollupJSON(a)
+	if err == nil {
+		err = rollupUpdateScript.Run(ctx, s.client, []string{rollupKey}, a.ID, data).Err()
+	}
+	if err != nil {
+		fmt.Println("Failed to update alert rollup:", err)
+	}
+}
+
+// GetAlertRollup summarizes the last 24 hours from the rollup hash, in one
+// read: counts per chat and level, and each chat's latest alerts. Counts are
+// kept per hour, so the window starts at the top of the hour 23 hours ago.
+func (s *RedisStore) GetAlertRollup(ctx context.Context) (models.AlertRollup, error) {
+	fields, err := s.client.HGetAll(ctx, rollupKey).Result()
+	if err != nil {
+		return models.AlertRollup{}, err
+	}
+	since := time.Now().UTC().Truncate(time.Hour).Add(-(rollupWindow - time.Hour))
+	r := models.AlertRollup{Since: since, ByLevel: map[string]int{}}
+	chats := map[string]*models.ChatRollup{}
+	chat := func(id string) *models.ChatRollup {
+		if chats[id] == nil {
+			chats[id] = &models.ChatRollup{ChatID: id, ByLevel: map[string]int{}, Latest: []models.Alert{}}
+		}
+		return chats[id]
+	}
+
+	var stale []string
+	for field, val := range fields {
+		switch {
+		case strings.HasPrefix(field, "n|"):
+			parts := strings.SplitN(field, "|", 4)
+			if len(parts) != 4 {
+				continue
+			}
+			hour, _ := strconv.ParseInt(parts[1], 10, 64)
+			if time.Unix(hour, 0).Before(since) {
+				stale = append(stale, field)
+				continue
+			}
+			n, _ := strconv.Atoi(val)
+			c := chat(parts[2])
+			c.Total += n
+			c.ByLevel[parts[3]] += n
+			r.Total += n
+			r.ByLevel[parts[3]] += n
+		case strings.HasPrefix(field, "slot|"):
+			var a models.Alert
+			if err := json.Unmarshal([]byte(val), &a); err != nil || a.CreatedAt.Before(since) {
+				continue
+			}
+			c := chat(statsChatID(a.Source))
+			c.Latest = append(c.Latest, a)
+		}
+	}
+	if len(stale) > 0 {
+		s.client.HDel(ctx, rollupKey, stale...) // Hours that left the window
+	}
+
+	for _, c := range chats {
+		sort.Slice(c.Latest, func(i, j int) bool { return c.Latest[i].CreatedAt.After(c.Latest[j].CreatedAt) })
+		r.Chats = append(r.Chats, *c)
+	}
+	sort.Slice(r.Chats, func(i, j int) bool {
+		if r.Chats[i].Total != r.Chats[j].Total {
+			return r.Chats[i].Total > r.Chats[j].Total
+		}
+		return r.Chats[i].ChatID < r.Chats[j].ChatID
+	})
+	return r, nil
+}
+
+// RebuildAlertRollup recomputes the rollup from the timeline, for a start
+// without one and after purges
+func (s *RedisStore) RebuildAlertRollup(ctx context.Context) error {
+	since := time.Now().UTC().Truncate(time.Hour).Add(-(rollupWindow - time.Hour))
+	alerts, err := s.GetAlertsSince(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]any{}
+	counts := map[string]int{}
+	seq := map[string]int{}
+	for _, a := range alerts { // Oldest first, as they were added
+		counts[rollupCountField(a)]++
+		chatID := statsChatID(a.Source)
+		seq[chatID]++
+		i := seq[chatID] % RollupLatest
+		data, err := rollupJSON(a)
+		if err != nil {
+			continue
+		}
+		slot, owner := fmt.Sprintf("slot|%s|%d", chatID, i), fmt.Sprintf("owner|%s|%d", chatID, i)
+		if old, ok := fields[owner]; ok {
+			delete(fields, fmt.Sprintf("at|%v", old))
+		}
+		fields[slot], fields[owner], fields[fmt.Sprintf("at|%d", a.ID)] = string(data), a.ID, slot
+	}
+	for field, n := range counts {
+		fields[field] = n
+	}
+	for chatID, n := range seq {
+		fields["seq|"+chatID] = n
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, rollupKey)
+		if len(fields) > 0 {
+			pipe.HSet(ctx, rollupKey, fields)
+			pipe.Expire(ctx, rollupKey, rollupTTL)
+		}
+		return nil
+	})
+	return err
+}
+
+// EnsureAlertRollup builds the rollup when there is none yet, e.g. on the
+// first start of a version that keeps one
+func (s *RedisStore) EnsureAlertRollup(ctx context.Context) error {
+	n, err := s.client.Exists(ctx, rollupKey).Result()
+	if err != nil || n > 0 {
+		return err
+	}
+	return s.RebuildAlertRollup(ctx)
+}
+
+// rebuildRollup follows a purge; the purge itself has succeeded, so a failure
+// is only logged
+func (s *RedisStore) rebuildRollup(ctx context.Context) {
+	if err := s.RebuildAlertRollup(ctx); err != nil {
+		fmt.Println("Failed to rebuild alert rollup:", err)
+	}
+}
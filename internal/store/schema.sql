@@ -1,3 +1,15 @@
+-- Organizations: tenants with their own users, bots and chats. The default
+-- organization holds what existed before there were others, and its admins
+-- run the instance
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO organizations (id, name) VALUES (1, 'Default') ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('organizations', 'id'), GREATEST((SELECT MAX(id) FROM organizations), 1));
+
 -- Users table
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
@@ -11,6 +23,10 @@ CREATE TABLE IF NOT EXISTS users (
 );
 
 CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);
+-- Encrypted TOTP secrets are longer than the plaintext ones
+ALTER TABLE users ALTER COLUMN totp_secret TYPE TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id);
+CREATE INDEX IF NOT EXISTS idx_users_org ON users(org_id);
 
 -- Bots table
 CREATE TABLE IF NOT EXISTS bots (
@@ -21,19 +37,48 @@ CREATE TABLE IF NOT EXISTS bots (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
-CREATE INDEX IF NOT EXISTS idx_bots_token ON bots(token);
-ALTER TABLE bots ADD COLUMN IF NOT EXISTS hmac_secret VARCHAR(255);
+-- hmac_secret was generated for every bot but never checked; signing is
+-- opt-in per bot through signing_secret
+ALTER TABLE bots DROP COLUMN IF EXISTS hmac_secret;
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS signing_secret VARCHAR(255);
+-- Bots are looked up by the SHA-256 of their token; the token itself is
+-- encrypted when SENTINEL_ENCRYPTION_KEY is set, as are signing secrets
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS token_hash CHAR(64);
+UPDATE bots SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex') WHERE token_hash IS NULL;
+ALTER TABLE bots ALTER COLUMN token_hash SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bots_token_hash ON bots(token_hash);
+DROP INDEX IF EXISTS idx_bots_token;
+DROP INDEX IF EXISTS idx_bots_previous_token;
+ALTER TABLE bots ALTER COLUMN token TYPE TEXT;
+ALTER TABLE bots ALTER COLUMN signing_secret TYPE TEXT;
+-- The hash of the token before the last rotation, valid until
+-- previous_token_expires_at
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS previous_token_hash CHAR(64);
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS previous_token_expires_at TIMESTAMP WITH TIME ZONE;
+-- previous_token held the previous token itself
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS previous_token VARCHAR(255);
+UPDATE bots SET previous_token_hash = encode(sha256(convert_to(previous_token, 'UTF8')), 'hex') WHERE previous_token IS NOT NULL;
+ALTER TABLE bots DROP COLUMN previous_token;
+CREATE INDEX IF NOT EXISTS idx_bots_previous_token_hash ON bots(previous_token_hash) WHERE previous_token_hash IS NOT NULL;
 ALTER TABLE bots ADD COLUMN IF NOT EXISTS rate_limit INTEGER;
+-- Comma separated CIDRs the bot may post from; NULL for anywhere
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS allowed_ips TEXT;
+ALTER TABLE bots ADD COLUMN IF NOT EXISTS org_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id);
+CREATE INDEX IF NOT EXISTS idx_bots_org ON bots(org_id);
 
 -- Chats table
 CREATE TABLE IF NOT EXISTS chats (
     id SERIAL PRIMARY KEY,
     chat_id VARCHAR(255) UNIQUE NOT NULL,
     name VARCHAR(255) NOT NULL,
-    bot_id INTEGER REFERENCES bots(id) ON DELETE CASCADE,
+    bot_id INTEGER REFERENCES bots(id) ON DELETE RESTRICT,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+-- A chat is in its bot's organization
+ALTER TABLE chats ADD COLUMN IF NOT EXISTS org_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id);
+CREATE INDEX IF NOT EXISTS idx_chats_org ON chats(org_id);
+
 -- User-Chat Permissions (many-to-many)
 CREATE TABLE IF NOT EXISTS user_chat_permissions (
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -47,6 +92,42 @@ CREATE INDEX IF NOT EXISTS idx_user_chat_permissions_chat ON user_chat_permissio
 
 CREATE INDEX IF NOT EXISTS idx_chats_bot_id ON chats(bot_id);
 
+-- Teams: users see the chats given to a team they are in, besides those
+-- given to them in user_chat_permissions
+CREATE TABLE IF NOT EXISTS teams (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (org_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members(user_id);
+
+CREATE TABLE IF NOT EXISTS team_chats (
+    team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+    chat_id INTEGER NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    PRIMARY KEY (team_id, chat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_chats_chat ON team_chats(chat_id);
+
+-- Bot-Chat Permissions: chats a bot may post into besides its own
+CREATE TABLE IF NOT EXISTS bot_chat_permissions (
+    bot_id INTEGER NOT NULL REFERENCES bots(id) ON DELETE CASCADE,
+    chat_id INTEGER NOT NULL REFERENCES chats(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (bot_id, chat_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bot_chat_permissions_chat ON bot_chat_permissions(chat_id);
+
 -- Push Subscriptions table
 CREATE TABLE IF NOT EXISTS push_subscriptions (
     id SERIAL PRIMARY KEY,
@@ -69,3 +150,379 @@ CREATE TABLE IF NOT EXISTS audit_logs (
     metadata JSONB,
     created_at TIMESTAMPTZ DEFAULT NOW()
 );
+
+-- Referential integrity. A bot can't be deleted while chats still use it
+-- (the API reassigns them or refuses); deleting a user keeps their audit
+-- trail, with the username captured at the time. Both run once, while the
+-- constraints don't have their ON DELETE action yet.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint
+                   WHERE conrelid = 'chats'::regclass AND conname = 'chats_bot_id_fkey' AND confdeltype = 'r') THEN
+        ALTER TABLE chats DROP CONSTRAINT IF EXISTS chats_bot_id_fkey;
+        ALTER TABLE chats ADD CONSTRAINT chats_bot_id_fkey FOREIGN KEY (bot_id) REFERENCES bots(id) ON DELETE RESTRICT;
+    END IF;
+END $$;
+
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS actor_username VARCHAR(255);
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint
+                   WHERE conrelid = 'audit_logs'::regclass AND conname = 'audit_logs_actor_id_fkey' AND confdeltype = 'n') THEN
+        UPDATE audit_logs a SET actor_username = u.username FROM users u WHERE a.actor_id = u.id AND a.actor_username IS NULL;
+        UPDATE audit_logs SET actor_id = NULL WHERE actor_id IS NOT NULL AND actor_id NOT IN (SELECT id FROM users);
+        ALTER TABLE audit_logs DROP CONSTRAINT IF EXISTS audit_logs_actor_id_fkey;
+        ALTER TABLE audit_logs ADD CONSTRAINT audit_logs_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL;
+    END IF;
+END $$;
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS request_id VARCHAR(128);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS client_ip VARCHAR(64);
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action, id);
+
+-- Accounts SAML sign-ins may use: those SAML created and those an admin
+-- linked. Accounts SAML created before this are found in the audit log, once.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+                   WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = 'saml_linked_at') THEN
+        ALTER TABLE users ADD COLUMN saml_linked_at TIMESTAMP WITH TIME ZONE;
+        UPDATE users SET saml_linked_at = created_at
+        WHERE id IN (SELECT target_id FROM audit_logs WHERE action = 'create_user' AND target_type = 'user' AND metadata->>'via' = 'saml');
+    END IF;
+END $$;
+-- The actor's organization at the time; the entries of a deleted
+-- organization stay with the default one
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS org_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE SET DEFAULT;
+CREATE INDEX IF NOT EXISTS idx_audit_logs_org ON audit_logs(org_id, id);
+
+-- Ticket connectors (Jira / ServiceNow / GitHub Issues)
+CREATE TABLE IF NOT EXISTS ticket_connectors (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(50) NOT NULL CHECK (kind IN ('jira', 'servicenow', 'github')),
+    chat_id VARCHAR(255),
+    min_level VARCHAR(50) NOT NULL DEFAULT 'error',
+    config JSONB NOT NULL DEFAULT '{}'::jsonb,
+    webhook_secret VARCHAR(255) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Tickets opened for alerts (alerts live in Redis, so no FK on alert_id)
+CREATE TABLE IF NOT EXISTS alert_tickets (
+    alert_id INTEGER NOT NULL,
+    connector_id INTEGER NOT NULL REFERENCES ticket_connectors(id) ON DELETE CASCADE,
+    external_id VARCHAR(255) NOT NULL,
+    url TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (alert_id, connector_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_tickets_external ON alert_tickets(connector_id, external_id);
+
+-- Static bearer tokens for /webhook (only the SHA-256 of the token is stored)
+CREATE TABLE IF NOT EXISTS ingest_tokens (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    rate_limit INTEGER NOT NULL DEFAULT 60,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Field mapping profiles for /webhook?profile={name}
+CREATE TABLE IF NOT EXISTS webhook_mappings (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    fields JSONB NOT NULL DEFAULT '{}'::jsonb,
+    defaults JSONB NOT NULL DEFAULT '{}'::jsonb,
+    level_map JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Threshold rules for samples posted to /api/metrics
+CREATE TABLE IF NOT EXISTS metric_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    metric VARCHAR(255) NOT NULL,
+    operator VARCHAR(2) NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    level VARCHAR(20) NOT NULL DEFAULT 'error',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_metric_rules_metric ON metric_rules(metric);
+
+-- Dead-man's-switch monitors checked in via /api/heartbeat/{token}
+CREATE TABLE IF NOT EXISTS heartbeats (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    token VARCHAR(255) UNIQUE NOT NULL,
+    interval_seconds INTEGER NOT NULL,
+    grace_seconds INTEGER NOT NULL DEFAULT 0,
+    level VARCHAR(20) NOT NULL DEFAULT 'critical',
+    last_ping_at TIMESTAMP WITH TIME ZONE,
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Stakeholders who get high-severity incident updates by email and Slack
+CREATE TABLE IF NOT EXISTS stakeholder_lists (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    min_level VARCHAR(20) NOT NULL DEFAULT 'critical',
+    chat_id VARCHAR(255) NOT NULL DEFAULT '',
+    emails JSONB NOT NULL DEFAULT '[]'::jsonb,
+    slack_webhooks JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Expected JSON Schema per webhook source, with a count of payloads that failed it
+CREATE TABLE IF NOT EXISTS payload_schemas (
+    id SERIAL PRIMARY KEY,
+    source VARCHAR(255) UNIQUE NOT NULL,
+    schema JSONB NOT NULL,
+    violations BIGINT NOT NULL DEFAULT 0,
+    last_violation TEXT NOT NULL DEFAULT '',
+    last_violation_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Email recipients for alerts per chat and source
+CREATE TABLE IF NOT EXISTS email_routes (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    chat_id VARCHAR(255) NOT NULL DEFAULT '',
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    min_level VARCHAR(20) NOT NULL DEFAULT 'error',
+    recipients JSONB NOT NULL DEFAULT '[]'::jsonb,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Admin-defined notification messages per channel and severity ('' = any)
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id SERIAL PRIMARY KEY,
+    channel VARCHAR(20) NOT NULL,
+    level VARCHAR(20) NOT NULL DEFAULT '',
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (channel, level)
+);
+
+-- Per-user SMS paging opt-in for critical alerts
+CREATE TABLE IF NOT EXISTS sms_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    phone VARCHAR(20) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Opsgenie forwarding rules; API keys are encrypted with SENTINEL_ENCRYPTION_KEY
+CREATE TABLE IF NOT EXISTS opsgenie_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    chat_id VARCHAR(255) NOT NULL DEFAULT '',
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    min_level VARCHAR(20) NOT NULL DEFAULT 'error',
+    api_key_encrypted TEXT NOT NULL,
+    region VARCHAR(10) NOT NULL DEFAULT 'us',
+    team VARCHAR(255) NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Outgoing webhooks; signing secrets are encrypted with SENTINEL_ENCRYPTION_KEY
+CREATE TABLE IF NOT EXISTS outgoing_webhooks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    chat_id VARCHAR(255) NOT NULL DEFAULT '',
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    min_level VARCHAR(20) NOT NULL DEFAULT 'info',
+    events JSONB NOT NULL DEFAULT '[]'::jsonb,
+    template TEXT NOT NULL DEFAULT '',
+    secret_encrypted TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- One row per outgoing webhook delivery attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL REFERENCES outgoing_webhooks(id) ON DELETE CASCADE,
+    delivery_id VARCHAR(64) NOT NULL,
+    alert_id INTEGER NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    attempt INTEGER NOT NULL,
+    status_code INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    response TEXT NOT NULL DEFAULT '',
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    success BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- Delivery attempts of queued notifications (push, SMS, email, Teams,
+-- Telegram, stakeholder Slack), kept for NotificationAttemptRetention
+CREATE TABLE IF NOT EXISTS notification_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    job_id VARCHAR(64) NOT NULL,
+    channel VARCHAR(50) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    attempt INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('delivered', 'retrying', 'failed')),
+    error TEXT NOT NULL DEFAULT '',
+    latency_ms INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_attempts_created ON notification_attempts(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notification_attempts_channel ON notification_attempts(channel, created_at DESC);
+
+-- External dependencies probed when a matching alert opens
+CREATE TABLE IF NOT EXISTS dependency_checks (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    chat_id VARCHAR(255) NOT NULL DEFAULT '',
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    timeout_ms INTEGER NOT NULL DEFAULT 5000,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- On-call rotations; participants is the ordered list of user IDs
+CREATE TABLE IF NOT EXISTS oncall_schedules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    participants JSONB NOT NULL DEFAULT '[]'::jsonb,
+    shift_days INTEGER NOT NULL DEFAULT 7,
+    handoff_time VARCHAR(5) NOT NULL DEFAULT '09:00',
+    start_date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Periods when someone other than the rotation is on call
+CREATE TABLE IF NOT EXISTS oncall_overrides (
+    id SERIAL PRIMARY KEY,
+    schedule_id INTEGER NOT NULL REFERENCES oncall_schedules(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oncall_overrides_schedule ON oncall_overrides(schedule_id, ends_at);
+
+-- Email routes can also mail a schedule's on-call, escalating down the
+-- rotation every escalate_after minutes while the alert is unacknowledged
+ALTER TABLE email_routes ADD COLUMN IF NOT EXISTS schedule_id INTEGER REFERENCES oncall_schedules(id) ON DELETE SET NULL;
+ALTER TABLE email_routes ADD COLUMN IF NOT EXISTS escalate_after INTEGER NOT NULL DEFAULT 0;
+
+-- Business-hours calendars; days are weekdays (0 = Sunday), holidays local
+-- YYYY-MM-DD dates
+CREATE TABLE IF NOT EXISTS business_calendars (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) UNIQUE NOT NULL,
+    team_id INTEGER REFERENCES teams(id) ON DELETE SET NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    days JSONB NOT NULL DEFAULT '[1,2,3,4,5]'::jsonb,
+    start_time VARCHAR(5) NOT NULL DEFAULT '09:00',
+    end_time VARCHAR(5) NOT NULL DEFAULT '17:00',
+    holidays JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Routing rules can send only during or only outside a calendar's hours
+ALTER TABLE email_routes ADD COLUMN IF NOT EXISTS calendar_id INTEGER REFERENCES business_calendars(id) ON DELETE SET NULL;
+ALTER TABLE email_routes ADD COLUMN IF NOT EXISTS hours VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE opsgenie_rules ADD COLUMN IF NOT EXISTS calendar_id INTEGER REFERENCES business_calendars(id) ON DELETE SET NULL;
+ALTER TABLE opsgenie_rules ADD COLUMN IF NOT EXISTS hours VARCHAR(20) NOT NULL DEFAULT '';
+ALTER TABLE outgoing_webhooks ADD COLUMN IF NOT EXISTS calendar_id INTEGER REFERENCES business_calendars(id) ON DELETE SET NULL;
+ALTER TABLE outgoing_webhooks ADD COLUMN IF NOT EXISTS hours VARCHAR(20) NOT NULL DEFAULT '';
+
+-- Feature flags; a flag is on for everyone (enabled) or the listed users and roles
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key VARCHAR(64) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    user_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    roles JSONB NOT NULL DEFAULT '[]'::jsonb,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+-- ...or the users of the listed organizations
+ALTER TABLE feature_flags ADD COLUMN IF NOT EXISTS org_ids JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Ingestion endpoints that take unsigned requests despite WEBHOOK_SECRET
+CREATE TABLE IF NOT EXISTS signature_exemptions (
+    endpoint VARCHAR(64) PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Personal API keys for scripts and CI; a key acts as its user within its scope
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    scope VARCHAR(16) NOT NULL CHECK (scope IN ('ingest','read','admin')),
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+
+CREATE TABLE IF NOT EXISTS passkeys (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    credential_id BYTEA UNIQUE NOT NULL,
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_passkeys_user ON passkeys(user_id);
+
+-- Earlier password hashes, for the password policy's reuse check
+CREATE TABLE IF NOT EXISTS password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user ON password_history(user_id, id);
+
+-- Invitations to create an account with a preset role and chats
+CREATE TABLE IF NOT EXISTS invitations (
+    id SERIAL PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL,
+    chat_ids JSONB NOT NULL DEFAULT '[]'::jsonb,
+    invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    user_id INTEGER REFERENCES users(id) ON DELETE SET NULL
+);
+-- The organization the account is created in
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS org_id INTEGER NOT NULL DEFAULT 1 REFERENCES organizations(id) ON DELETE CASCADE;
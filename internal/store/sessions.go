@@ -0,0 +1,200 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/redis/go-redis/v9"
+)
+
+// sessionTTL is how long a session lasts after it was last saved;
+// anonymousSessionTTL applies until someone signs in with it
+const (
+	sessionTTL          = 30 * 24 * time.Hour
+	anonymousSessionTTL = 24 * time.Hour
+)
+
+func sessionKey(id string) string       { return "session:" + id }
+func userSessionsKey(userID int) string { return fmt.Sprintf("sessions:user:%d", userID) }
+
+// RedisSessions is a gorilla sessions.Store that keeps session values in
+// Redis; the cookie only carries a random ID. Sessions are indexed by their
+// "user_id" value, so a user's sessions can be ended or updated at once.
+type RedisSessions struct {
+	client  *redis.Client
+	Options *sessions.Options // Cookie options; MaxAge is also the TTL in Redis
+}
+
+// Sessions returns the session store on this Redis
+func (s *RedisStore) Sessions() *RedisSessions {
+	return &RedisSessions{
+		client: s.client,
+		Options: &sessions.Options{
+			Path:     "/",
+			MaxAge:   int(sessionTTL.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+	}
+}
+
+// Get returns the named session, loaded once per request
+func (s *RedisSessions) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session the cookie points at. An unknown, expired or revoked
+// ID gives a new, empty session.
+func (s *RedisSessions) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	c, err := r.Cookie(name)
+	if err != nil || c.Value == "" {
+		return session, nil
+	}
+	data, err := s.client.Get(r.Context(), sessionKey(c.Value)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return session, nil
+	}
+	if err != nil {
+		return session, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&session.Values); err != nil {
+		return session, err
+	}
+	session.ID = c.Value
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session and sets its cookie. A negative MaxAge deletes it.
+func (s *RedisSessions) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	ctx := r.Context()
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := s.client.Del(ctx, sessionKey(session.ID)).Err(); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		session.ID = base64.RawURLEncoding.EncodeToString(b)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(session.Values); err != nil {
+		return err
+	}
+	ttl := sessionTTL
+	if session.Options.MaxAge > 0 {
+		ttl = time.Duration(session.Options.MaxAge) * time.Second
+	}
+	userID, _ := session.Values["user_id"].(int)
+	if userID == 0 {
+		ttl = min(ttl, anonymousSessionTTL)
+	}
+
+	pipe := s.client.TxPipeline()
+	pipe.Set(ctx, sessionKey(session.ID), buf.Bytes(), ttl)
+	if userID != 0 {
+		pipe.SAdd(ctx, userSessionsKey(userID), session.ID)
+		pipe.Expire(ctx, userSessionsKey(userID), ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), session.ID, session.Options))
+	return nil
+}
+
+// Renew drops the session's ID, keeping its values; the next Save stores
+// them under a new one. Called on sign-in, so an ID known before it is
+// useless after.
+func (s *RedisSessions) Renew(ctx context.Context, session *sessions.Session) error {
+	if session.ID == "" {
+		return nil
+	}
+	err := s.client.Del(ctx, sessionKey(session.ID)).Err()
+	session.ID = ""
+	return err
+}
+
+// RevokeUserSessions ends the user's sessions except the one with ID except,
+// which may be empty, and returns how many it ended
+func (s *RedisSessions) RevokeUserSessions(ctx context.Context, userID int, except string) (int, error) {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	var revoked []any
+	for _, id := range ids {
+		if id != except {
+			keys = append(keys, sessionKey(id))
+			revoked = append(revoked, id)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	pipe := s.client.TxPipeline()
+	del := pipe.Del(ctx, keys...)
+	pipe.SRem(ctx, userSessionsKey(userID), revoked...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(del.Val()), nil
+}
+
+// UpdateUserSessions sets the username and role in all of the user's
+// sessions, so a rename or role change applies to them right away
+func (s *RedisSessions) UpdateUserSessions(ctx context.Context, userID int, username, role string) error {
+	ids, err := s.client.SMembers(ctx, userSessionsKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		data, err := s.client.Get(ctx, sessionKey(id)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// Expired or signed out
+			s.client.SRem(ctx, userSessionsKey(userID), id)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		values := map[interface{}]interface{}{}
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+			return err
+		}
+		values["username"] = username
+		values["role"] = role
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+			return err
+		}
+		// XX: a session that ended meanwhile stays ended
+		if err := s.client.SetXX(ctx, sessionKey(id), buf.Bytes(), redis.KeepTTL).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	shortLinkTTL     = 90 * 24 * time.Hour
+	shortCodeLength  = 7
+	shortCodeSymbols = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789" // No 0/O, 1/l/I
+)
+
+// CreateShortLink returns the code of a short link to path, reusing the one
+// made earlier for the same path. Links expire shortLinkTTL after they were
+// last handed out.
+func (s *RedisStore) CreateShortLink(ctx context.Context, path string) (string, error) {
+	byPath := "short:path:" + path
+	if code, err := s.client.Get(ctx, byPath).Result(); err == nil {
+		// Keep both directions alive as long as the link is in use
+		pipe := s.client.Pipeline()
+		pipe.Expire(ctx, byPath, shortLinkTTL)
+		pipe.Expire(ctx, "short:"+code, shortLinkTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return "", err
+		}
+		return code, nil
+	} else if !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+
+	for range 5 {
+		code, err := newShortCode()
+		if err != nil {
+			return "", err
+		}
+		ok, err := s.client.SetNX(ctx, "short:"+code, path, shortLinkTTL).Result()
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			continue // Taken; draw again
+		}
+		if err := s.client.Set(ctx, byPath, code, shortLinkTTL).Err(); err != nil {
+			return "", err
+		}
+		return code, nil
+	}
+	return "", errors.New("could not find a free short link code")
+}
+
+// GetShortLink returns the path behind code, or ErrShortLinkNotFound
+func (s *RedisStore) GetShortLink(ctx context.Context, code string) (string, error) {
+	path, err := s.client.Get(ctx, "short:"+code).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrShortLinkNotFound
+	}
+	return path, err
+}
+
+func newShortCode() (string, error) {
+	b := make([]byte, shortCodeLength)
+	max := big.NewInt(int64(len(shortCodeSymbols)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = shortCodeSymbols[n.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+)
+
+const (
+	silencesKey  = "silences" // Hash of silence ID -> JSON
+	silenceIDKey = "silences:next_id"
+	silencesKept = 7 * 24 * time.Hour // How long expired silences stay listed
+
+	// MaxSilenceDuration is the longest window a silence may cover, so a
+	// forgotten one can't mute an integration for good
+	MaxSilenceDuration = 30 * 24 * time.Hour
+)
+
+// ErrSilenceNotFound is returned for unknown silence IDs
+var ErrSilenceNotFound = errors.New("silence not found")
+
+// CreateSilence stores a new silence; it applies to alerts arriving from its
+// start
+func (s *RedisStore) CreateSilence(ctx context.Context, sil models.Silence) (models.Silence, error) {
+	id, err := s.client.Incr(ctx, silenceIDKey).Result()
+	if err != nil {
+		return models.Silence{}, err
+	}
+	sil.ID = int(id)
+	sil.CreatedAt = time.Now().UTC()
+	return sil, s.putSilence(ctx, sil)
+}
+
+// UpdateSilence replaces an existing silence, keeping who created it and when
+func (s *RedisStore) UpdateSilence(ctx context.Context, sil models.Silence) (models.Silence, error) {
+	cur, err := s.GetSilence(ctx, sil.ID)
+	if err != nil {
+		return models.Silence{}, err
+	}
+	sil.CreatedBy, sil.CreatedAt = cur.CreatedBy, cur.CreatedAt
+	return sil, s.putSilence(ctx, sil)
+}
+
+func (s *RedisStore) putSilence(ctx context.Context, sil models.Silence) error {
+	data, err := json.Marshal(sil)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, silencesKey, strconv.Itoa(sil.ID), data).Err()
+}
+
+// GetSilence returns a silence by ID, or ErrSilenceNotFound
+func (s *RedisStore) GetSilence(ctx context.Context, id int) (models.Silence, error) {
+	val, err := s.client.HGet(ctx, silencesKey, strconv.Itoa(id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return models.Silence{}, ErrSilenceNotFound
+		}
+		return models.Silence{}, err
+	}
+	var sil models.Silence
+	if err := json.Unmarshal([]byte(val), &sil); err != nil {
+		return models.Silence{}, err
+	}
+	return sil, nil
+}
+
+// DeleteSilence removes a silence. Alerts it already covers stay silenced;
+// later ones notify again.
+func (s *RedisStore) DeleteSilence(ctx context.Context, id int) error {
+	n, err := s.client.HDel(ctx, silencesKey, strconv.Itoa(id)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSilenceNotFound
+	}
+	return nil
+}
+
+// GetSilences lists the silences, newest first. Silences that expired more
+// than a week ago are dropped here.
+func (s *RedisStore) GetSilences(ctx context.Context) ([]models.Silence, error) {
+	fields, err := s.client.HGetAll(ctx, silencesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-silencesKept)
+	silences := []models.Silence{}
+	var stale []string
+	for field, val := range fields {
+		var sil models.Silence
+		if err := json.Unmarshal([]byte(val), &sil); err != nil {
+			continue
+		}
+		if sil.EndsAt.Before(cutoff) {
+			stale = append(stale, field)
+			continue
+		}
+		silences = append(silences, sil)
+	}
+	if len(stale) > 0 {
+		s.client.HDel(ctx, silencesKey, stale...)
+	}
+	sort.Slice(silences, func(i, j int) bool { return silences[i].ID > silences[j].ID })
+	return silences, nil
+}
+
+// activeSilences is the silences in effect now, for stamping alerts. A
+// failure is logged and silences nothing: a notification too many beats a
+// lost page.
+func (s *RedisStore) activeSilences(ctx context.Context) []models.Silence {
+	silences, err := s.GetSilences(ctx)
+	if err != nil {
+		fmt.Println("Failed to get silences:", err)
+		return nil
+	}
+	now := time.Now()
+	active := silences[:0]
+	for _, sil := range silences {
+		if sil.State(now) == models.SilenceStateActive {
+			active = append(active, sil)
+		}
+	}
+	return active
+}
+
+// applySilence stamps a with the silence covering it, preferring the one
+// that lasts longest, or clears the stamp when none does
+func applySilence(a *models.Alert, silences []models.Silence) {
+	a.SilenceID, a.Silenced = 0, false
+	var match *models.Silence
+	for i, sil := range silences {
+		if sil.Matches(*a) && (match == nil || sil.EndsAt.After(match.EndsAt)) {
+			match = &silences[i]
+		}
+	}
+	if match != nil {
+		a.SilenceID, a.Silenced = match.ID, match.MarkAlerts
+	}
+}
@@ -0,0 +1,362 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"incident-viewer-go/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Per-chat daily counters, kept a while longer than the alerts themselves so
+// review meetings can look back further than the retention window
+const (
+	statsTTL      = 90 * 24 * time.Hour
+	statsTopLimit = 10
+)
+
+// statsChatID is the chat an alert counts towards ("general" for alerts not
+// bound to a chat)
+func statsChatID(source string) string {
+	if chatID := models.ChatIDFromSource(source); chatID != "" {
+		return chatID
+	}
+	return "general"
+}
+
+// statsKey is the daily counter hash: total, resolved, acked, level:{level}, hour:{HH}
+func statsKey(chatID string, day time.Time) string {
+	return fmt.Sprintf("stats:chat:%s:%s", chatID, day.UTC().Format("2006-01-02"))
+}
+
+func statsTitlesKey(chatID string, day time.Time) string {
+	return statsKey(chatID, day) + ":titles"
+}
+
+// recordAlertStats queues the counter updates for a newly stored alert
+func recordAlertStats(ctx context.Context, pipe redis.Pipeliner, a models.Alert) {
+	chatID := statsChatID(a.Source)
+	key := statsKey(chatID, a.CreatedAt)
+	titles := statsTitlesKey(chatID, a.CreatedAt)
+
+	pipe.HIncrBy(ctx, key, "total", 1)
+	pipe.HIncrBy(ctx, key, "level:"+strings.ToLower(a.Level), 1)
+	pipe.HIncrBy(ctx, key, fmt.Sprintf("hour:%02d", a.CreatedAt.UTC().Hour()), 1)
+	pipe.Expire(ctx, key, statsTTL)
+	pipe.ZIncrBy(ctx, titles, 1, a.Title)
+	pipe.Expire(ctx, titles, statsTTL)
+}
+
+// incrAlertStat bumps a lifecycle counter on the alert's creation day
+func (s *RedisStore) incrAlertStat(ctx context.Context, a models.Alert, field string) {
+	key := statsKey(statsChatID(a.Source), a.CreatedAt)
+	if err := s.client.HIncrBy(ctx, key, field, 1).Err(); err != nil {
+		fmt.Println("Failed to update stats:", err)
+	}
+}
+
+// GetChatStats aggregates a chat's daily counters over the last days days
+func (s *RedisStore) GetChatStats(ctx context.Context, chatID string, days int) (models.ChatStats, error) {
+	now := time.Now().UTC()
+	stats := models.ChatStats{
+		ChatID:  chatID,
+		From:    now.AddDate(0, 0, -(days - 1)).Format("2006-01-02"),
+		To:      now.Format("2006-01-02"),
+		ByLevel: make(map[string]int),
+	}
+
+	pipe := s.client.Pipeline()
+	hashes := make([]*redis.MapStringStringCmd, days)
+	titleKeys := make([]string, days)
+	for i := 0; i < days; i++ {
+		day := now.AddDate(0, 0, -(days - 1 - i))
+		hashes[i] = pipe.HGetAll(ctx, statsKey(chatID, day))
+		titleKeys[i] = statsTitlesKey(chatID, day)
+	}
+	titles := pipe.ZUnionWithScores(ctx, redis.ZStore{Keys: titleKeys, Aggregate: "SUM"})
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return models.ChatStats{}, err
+	}
+
+	for i, cmd := range hashes {
+		dayTotal := 0
+		for field, val := range cmd.Val() {
+			n, _ := strconv.Atoi(val)
+			switch {
+			case field == "total":
+				dayTotal = n
+			case field == "resolved":
+				stats.Resolved += n
+			case field == "acked":
+				stats.Acked += n
+			case strings.HasPrefix(field, "level:"):
+				stats.ByLevel[strings.TrimPrefix(field, "level:")] += n
+			case strings.HasPrefix(field, "hour:"):
+				if h, err := strconv.Atoi(strings.TrimPrefix(field, "hour:")); err == nil && h >= 0 && h < 24 {
+					stats.Hours[h] += n
+				}
+			}
+		}
+		stats.Total += dayTotal
+		stats.ByDay = append(stats.ByDay, models.DayCount{
+			Date:  now.AddDate(0, 0, -(days - 1 - i)).Format("2006-01-02"),
+			Count: dayTotal,
+		})
+	}
+	if stats.Total > 0 {
+		stats.AckRate = float64(stats.Acked) / float64(stats.Total)
+	}
+
+	hours := make([]int, 0, 24)
+	for h, n := range stats.Hours {
+		if n > 0 {
+			hours = append(hours, h)
+		}
+	}
+	sort.SliceStable(hours, func(i, j int) bool { return stats.Hours[hours[i]] > stats.Hours[hours[j]] })
+	if len(hours) > 3 {
+		hours = hours[:3]
+	}
+	stats.BusiestHours = hours
+
+	top := titles.Val()
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Score > top[j].Score })
+	for i, z := range top {
+		if i == statsTopLimit {
+			break
+		}
+		title, _ := z.Member.(string)
+		stats.TopTitles = append(stats.TopTitles, models.TitleCount{Title: title, Count: int(z.Score)})
+	}
+	return stats, nil
+}
+
+// Per-integration hourly ingestion counters, for the admin integration health
+// summary
+const ingestStatsTTL = 25 * time.Hour
+
+const ingestIntegrationsKey = "stats:ingest:integrations"
+
+// ingestStatsKey is the hourly hash: requests, errors, rejected, ms_sum and
+// le:{ms} latency buckets
+func ingestStatsKey(integration string, hour time.Time) string {
+	return fmt.Sprintf("stats:ingest:%s:%s", integration, hour.UTC().Format("2006-01-02T15"))
+}
+
+// RecordIngest counts a request to an integration endpoint
+func (s *RedisStore) RecordIngest(ctx context.Context, integration string, status int, d time.Duration) error {
+	key := ingestStatsKey(integration, time.Now())
+	ms := d.Milliseconds()
+
+	pipe := s.client.Pipeline()
+	pipe.SAdd(ctx, ingestIntegrationsKey, integration)
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	pipe.HIncrBy(ctx, key, "ms_sum", ms)
+	switch {
+	case status >= 500:
+		pipe.HIncrBy(ctx, key, "errors", 1)
+	case status >= 400:
+		pipe.HIncrBy(ctx, key, "rejected", 1)
+	}
+	bucket := "inf"
+	for _, le := range models.IngestLatencyBuckets {
+		if ms <= int64(le) {
+			bucket = strconv.Itoa(le)
+			break
+		}
+	}
+	pipe.HIncrBy(ctx, key, "le:"+bucket, 1)
+	pipe.Expire(ctx, key, ingestStatsTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetIngestStats aggregates every integration's hourly counters over the last
+// hours hours, busiest first
+func (s *RedisStore) GetIngestStats(ctx context.Context, hours int) ([]models.IngestStats, error) {
+	integrations, err := s.client.SMembers(ctx, ingestIntegrationsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	pipe := s.client.Pipeline()
+	hashes := make(map[string][]*redis.MapStringStringCmd, len(integrations))
+	for _, name := range integrations {
+		for i := 0; i < hours; i++ {
+			hashes[name] = append(hashes[name], pipe.HGetAll(ctx, ingestStatsKey(name, now.Add(-time.Duration(i)*time.Hour))))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	var stats []models.IngestStats
+	for _, name := range integrations {
+		st := models.IngestStats{Integration: name}
+		var msSum int
+		buckets := make(map[string]int)
+		for i, cmd := range hashes[name] {
+			vals := cmd.Val()
+			n, _ := strconv.Atoi(vals["requests"])
+			if n > 0 && st.LastSeen == "" {
+				// Hours are newest first
+				st.LastSeen = now.Add(-time.Duration(i) * time.Hour).Truncate(time.Hour).Format(time.RFC3339)
+			}
+			for field, val := range vals {
+				n, _ := strconv.Atoi(val)
+				switch {
+				case field == "requests":
+					st.Requests += n
+				case field == "errors":
+					st.Errors += n
+				case field == "rejected":
+					st.Rejected += n
+				case field == "ms_sum":
+					msSum += n
+				case strings.HasPrefix(field, "le:"):
+					buckets[strings.TrimPrefix(field, "le:")] += n
+				}
+			}
+		}
+		if st.Requests == 0 {
+			continue
+		}
+		st.ErrorRate = float64(st.Errors) / float64(st.Requests)
+		st.AvgMS = float64(msSum) / float64(st.Requests)
+		st.P95MS = -1 // Slower than the largest bucket
+		seen, target := 0, (st.Requests*95+99)/100
+		for _, le := range models.IngestLatencyBuckets {
+			seen += buckets[strconv.Itoa(le)]
+			if seen >= target {
+				st.P95MS = le
+				break
+			}
+		}
+		stats = append(stats, st)
+	}
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Requests > stats[j].Requests })
+	return stats, nil
+}
+
+// Per-credential hourly request counters, kept like the ingest ones, and
+// the last use and last failure of each credential, kept until it is deleted
+const apiUsageLastKey = "stats:usage:last"
+
+// apiUsageKey is the hourly hash: requests, errors and rejected
+func apiUsageKey(credential string, hour time.Time) string {
+	return fmt.Sprintf("stats:usage:%s:%s", credential, hour.UTC().Format("2006-01-02T15"))
+}
+
+// RecordAPIUsage counts a request made with a bot or ingest token, named by
+// models.APIUsage.Credential
+func (s *RedisStore) RecordAPIUsage(ctx context.Context, credential string, status int) error {
+	now := time.Now().UTC()
+	key := apiUsageKey(credential, now)
+	at := now.Format(time.RFC3339)
+
+	pipe := s.client.Pipeline()
+	pipe.HIncrBy(ctx, key, "requests", 1)
+	switch {
+	case status >= 500:
+		pipe.HIncrBy(ctx, key, "errors", 1)
+	case status >= 400:
+		pipe.HIncrBy(ctx, key, "rejected", 1)
+	}
+	pipe.Expire(ctx, key, ingestStatsTTL)
+	pipe.HSet(ctx, apiUsageLastKey, credential+"|at", at, credential+"|status", status)
+	if status >= 400 {
+		pipe.HSet(ctx, apiUsageLastKey, credential+"|error_at", at, credential+"|error_status", status)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// GetAPIUsage fills in the counters of the last hours hours and the last use
+// of each credential in usage, which names them by Kind and ID
+func (s *RedisStore) GetAPIUsage(ctx context.Context, usage []models.APIUsage, hours int) ([]models.APIUsage, error) {
+	now := time.Now().UTC()
+	pipe := s.client.Pipeline()
+	hashes := make([][]*redis.MapStringStringCmd, len(usage))
+	last := make([]*redis.SliceCmd, len(usage))
+	for i, u := range usage {
+		c := u.Credential()
+		for h := 0; h < hours; h++ {
+			hashes[i] = append(hashes[i], pipe.HGetAll(ctx, apiUsageKey(c, now.Add(-time.Duration(h)*time.Hour))))
+		}
+		last[i] = pipe.HMGet(ctx, apiUsageLastKey, c+"|at", c+"|status", c+"|error_at", c+"|error_status")
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	parseTime := func(v any) *time.Time {
+		str, _ := v.(string)
+		t, err := time.Parse(time.RFC3339, str)
+		if err != nil {
+			return nil
+		}
+		return &t
+	}
+	parseInt := func(v any) int {
+		str, _ := v.(string)
+		n, _ := strconv.Atoi(str)
+		return n
+	}
+	for i := range usage {
+		u := &usage[i]
+		for _, cmd := range hashes[i] {
+			vals := cmd.Val()
+			n, _ := strconv.Atoi(vals["requests"])
+			u.Requests += n
+			n, _ = strconv.Atoi(vals["errors"])
+			u.Errors += n
+			n, _ = strconv.Atoi(vals["rejected"])
+			u.Rejected += n
+		}
+		if u.Requests > 0 {
+			u.ErrorRate = float64(u.Errors+u.Rejected) / float64(u.Requests)
+		}
+		if vals := last[i].Val(); len(vals) == 4 {
+			u.LastUsedAt, u.LastStatus = parseTime(vals[0]), parseInt(vals[1])
+			u.LastErrorAt, u.LastErrorStatus = parseTime(vals[2]), parseInt(vals[3])
+		}
+	}
+	return usage, nil
+}
+
+// ForgetAPIUsage drops a deleted credential's last use
+func (s *RedisStore) ForgetAPIUsage(ctx context.Context, credential string) error {
+	return s.client.HDel(ctx, apiUsageLastKey, credential+"|at", credential+"|status", credential+"|error_at", credential+"|error_status").Err()
+}
+
+// IncrSMSCount counts an SMS against scope (e.g. "all" or "user:42") for the
+// current hour and returns the count so far, for the hourly SMS caps
+func (s *RedisStore) IncrSMSCount(ctx context.Context, scope string) (int, error) {
+	key := fmt.Sprintf("sms:sent:%s:%s", scope, time.Now().UTC().Format("2006-01-02T15"))
+	pipe := s.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, time.Hour)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return int(incr.Val()), nil
+}
+
+// ClaimNonce records a signed request's nonce for ttl and reports whether it
+// was new. Kept in Redis so a replay is caught by any replica, and after a
+// restart.
+func (s *RedisStore) ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, "nonce:"+nonce, 1, ttl).Result()
+}
+
+// ClaimNotification takes the flood-control slot for key on channel for
+// window and reports whether it was free, i.e. whether to send
+func (s *RedisStore) ClaimNotification(ctx context.Context, channel, key string, window time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, "notify:throttle:"+channel+":"+key, 1, window).Result()
+}
@@ -3,27 +3,121 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
 	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/requestid"
 
 	"github.com/redis/go-redis/v9"
 )
 
 const (
 	alertTTL = 30 * 24 * time.Hour // 30 days
+
+	purgeBatchSize = 500 // Keys per SCAN/UNLINK round trip
+
+	// MaxAnnotations caps the annotations kept per alert; older ones are dropped
+	MaxAnnotations = 50
+
+	// DeployRetention is how long deploy events are kept for correlation
+	DeployRetention = 24 * time.Hour
+)
+
+// Pub/Sub channels. New alerts and lifecycle changes go to AlertEventsChannel and
+// trigger notifications; cosmetic updates (reactions) only refresh SSE clients.
+const (
+	AlertEventsChannel  = "alert_events"
+	AlertUpdatesChannel = "alert_updates"
 )
 
+// ErrAlertNotFound is returned when no alert matches a lookup
+var ErrAlertNotFound = errors.New("alert not found")
+
+// ErrShortLinkNotFound is returned for unknown or expired short link codes
+var ErrShortLinkNotFound = errors.New("short link not found")
+
+// ErrUserNotFound is returned for unknown user IDs and usernames
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrInvitationNotPending is returned for invitations that were accepted,
+// revoked or have expired
+var ErrInvitationNotPending = errors.New("invitation is no longer valid")
+
+// ErrInUse is returned when a delete would leave dependent rows behind
+var ErrInUse = errors.New("still in use")
+
+// ErrInvalidReassign is returned when dependents can't be moved to the given target
+var ErrInvalidReassign = errors.New("invalid reassign target")
+
 // AlertStore handles alert operations (Redis)
 type AlertStore interface {
 	AddAlert(ctx context.Context, source, level, title, message string) (models.Alert, error)
+	AddFingerprintedAlert(ctx context.Context, fingerprint, source, level, title, message string) (models.Alert, error)
+	UpsertAlert(ctx context.Context, a models.Alert) (models.Alert, error)
+	ResolveAlert(ctx context.Context, fingerprint string) (models.Alert, error)
+	ResolveAlertByID(ctx context.Context, id int) (models.Alert, error)
+	GetOpenAlert(ctx context.Context, fingerprint string) (models.Alert, error)
+	GetAlert(ctx context.Context, id int) (models.Alert, error)
 	GetAlerts(ctx context.Context) ([]models.Alert, error)
+	GetAlertsSince(ctx context.Context, since time.Time) ([]models.Alert, error)
+	GetAlertRollup(ctx context.Context) (models.AlertRollup, error)
+	ExpiringAlerts(ctx context.Context, within time.Duration) ([]models.Alert, error)
+	ExtendAlertRetention(ctx context.Context, a models.Alert, d time.Duration) error
+	GetChatStats(ctx context.Context, chatID string, days int) (models.ChatStats, error)
+	RecordIngest(ctx context.Context, integration string, status int, d time.Duration) error
+	GetIngestStats(ctx context.Context, hours int) ([]models.IngestStats, error)
+	RecordAPIUsage(ctx context.Context, credential string, status int) error
+	GetAPIUsage(ctx context.Context, usage []models.APIUsage, hours int) ([]models.APIUsage, error)
+	ForgetAPIUsage(ctx context.Context, credential string) error
+	IncrSMSCount(ctx context.Context, scope string) (int, error)
+	ClaimNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+	ClaimNotification(ctx context.Context, channel, key string, window time.Duration) (bool, error)
+	RecordLoginFailure(ctx context.Context, userID int, window time.Duration) (int, error)
+	LockLogin(ctx context.Context, userID int, base, max time.Duration) (time.Time, error)
+	LoginLockedUntil(ctx context.Context, userID int) (time.Time, error)
+	ClearLoginFailures(ctx context.Context, userID int) error
+	CountSuppressedNotification(ctx context.Context, alertID int) error
+	CreateMuteRule(ctx context.Context, r models.MuteRule) (models.MuteRule, error)
+	UpdateMuteRule(ctx context.Context, r models.MuteRule) (models.MuteRule, error)
+	DeleteMuteRule(ctx context.Context, id int) error
+	GetMuteRules(ctx context.Context) ([]models.MuteRule, error)
+	CreateSilence(ctx context.Context, sil models.Silence) (models.Silence, error)
+	UpdateSilence(ctx context.Context, sil models.Silence) (models.Silence, error)
+	GetSilence(ctx context.Context, id int) (models.Silence, error)
+	GetSilences(ctx context.Context) ([]models.Silence, error)
+	DeleteSilence(ctx context.Context, id int) error
+	CreateShortLink(ctx context.Context, path string) (string, error)
+	GetShortLink(ctx context.Context, code string) (string, error)
+	EnqueueNotification(ctx context.Context, job models.NotificationJob) error
+	ReadNotifications(ctx context.Context, consumer string, count int, block time.Duration) ([]models.NotificationJob, error)
+	AckNotification(ctx context.Context, job models.NotificationJob) error
+	RetryNotification(ctx context.Context, job models.NotificationJob, at time.Time) error
+	DeadLetterNotification(ctx context.Context, job models.NotificationJob) error
+	PromoteNotifications(ctx context.Context, now time.Time) (int, error)
+	GetNotificationQueueStats(ctx context.Context) (models.NotificationQueueStats, error)
+	GetDeadLetters(ctx context.Context, limit int) ([]models.NotificationJob, error)
+	RequeueDeadLetter(ctx context.Context, id string) error
+	ClearDeadLetters(ctx context.Context) (int, error)
+	SetReaction(ctx context.Context, alertID int, reaction, username string, on bool) (models.Alert, error)
+	AddComment(ctx context.Context, alertID int, c models.AlertComment) (models.Alert, error)
+	AddAnnotation(ctx context.Context, alertID int, an models.AlertAnnotation) (models.Alert, error)
+	SetAlertLabels(ctx context.Context, alertID int, labels map[string]string) (models.Alert, error)
+	SetIncidentRole(ctx context.Context, alertID int, role, username string) (models.Alert, error)
+	RecordDeploy(ctx context.Context, d models.DeployEvent) error
+	GetDeploys(ctx context.Context, from, to time.Time) ([]models.DeployEvent, error)
 	SearchAlerts(ctx context.Context, query, level, source string) ([]models.Alert, error)
 	ClearAlerts(ctx context.Context) error
+	ClearAlertsFiltered(ctx context.Context, f PurgeFilter) (int, error)
 	PurgeAllAlerts(ctx context.Context) error
 	PurgeAlertsByChat(ctx context.Context, chatID string) error
+	PurgeAlerts(ctx context.Context, f PurgeFilter, dryRun bool) (int, error)
+	SavePurgeConfirmToken(ctx context.Context, userID int, token string, ttl time.Duration) error
+	ConsumePurgeConfirmToken(ctx context.Context, userID int, token string) (bool, error)
+	NormalizeSources(ctx context.Context) (int, error)
 	Subscribe(ctx context.Context) *redis.PubSub
 }
 
@@ -31,27 +125,39 @@ type AlertStore interface {
 type AdminStore interface {
 	Ping(ctx context.Context) error
 	// User methods
-	CreateUser(ctx context.Context, username, password, role string) (models.User, error)
+	CreateUser(ctx context.Context, username, password, role string, orgID int) (models.User, error)
 	GetUser(ctx context.Context, id int) (models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (models.User, error)
 	GetUsers(ctx context.Context) ([]models.User, error)
 	UpdateUser(ctx context.Context, id int, username, role string) error
-	DeleteUser(ctx context.Context, id int) error
+	SetUserSAMLLinked(ctx context.Context, id int, linked bool) error
+	DeleteUser(ctx context.Context, id, reassignTo int) error
 
 	// User profile & password management
 	UpdateUserPassword(ctx context.Context, userID int, newPasswordHash string) error
+	GetPasswordHistory(ctx context.Context, userID, limit int) ([]string, error)
 	UpdateUserProfile(ctx context.Context, userID int, username string) error
+	UpdateUserEmail(ctx context.Context, userID int, email string) error
 
 	// 2FA methods
 	UpdateUser2FA(ctx context.Context, userID int, totpSecret string, enabled bool) error
 	Disable2FA(ctx context.Context, userID int) error
 
+	// Organizations
+	CreateOrganization(ctx context.Context, name string) (models.Organization, error)
+	GetOrganization(ctx context.Context, id int) (models.Organization, error)
+	GetOrganizations(ctx context.Context) ([]models.Organization, error)
+	DeleteOrganization(ctx context.Context, id int) error
+
 	// Bot methods
-	CreateBot(ctx context.Context, name string, createdBy int) (models.Bot, error)
+	CreateBot(ctx context.Context, name string, createdBy, orgID int, signingSecret string) (models.Bot, error)
 	GetBot(ctx context.Context, id int) (models.Bot, error)
 	GetBotByToken(ctx context.Context, token string) (models.Bot, error)
 	GetBots(ctx context.Context) ([]models.Bot, error)
-	DeleteBot(ctx context.Context, id int) error
+	DeleteBot(ctx context.Context, id, reassignTo int) error
+	SetBotSigningSecret(ctx context.Context, id int, secret string) error
+	RotateBotToken(ctx context.Context, id int, grace time.Duration) (models.Bot, error)
+	SetBotAllowedIPs(ctx context.Context, id int, cidrs []string) error
 
 	// Chat methods
 	CreateChat(ctx context.Context, chatID, name string, botID int) (models.Chat, error)
@@ -63,19 +169,176 @@ type AdminStore interface {
 	AssignChatToUser(ctx context.Context, userID, chatID int) error
 	RemoveChatFromUser(ctx context.Context, userID, chatID int) error
 	GetUserChats(ctx context.Context, userID int) ([]models.Chat, error)
+	GetUserAssignedChats(ctx context.Context, userID int) ([]models.Chat, error)
 	GetChatUsers(ctx context.Context, chatID int) ([]models.User, error)
 
+	// Teams
+	CreateTeam(ctx context.Context, t models.Team) (models.Team, error)
+	GetTeam(ctx context.Context, id int) (models.Team, error)
+	GetTeams(ctx context.Context) ([]models.Team, error)
+	UpdateTeam(ctx context.Context, t models.Team) error
+	DeleteTeam(ctx context.Context, id int) error
+
+	// Bot-Chat Permission methods
+	AssignChatToBot(ctx context.Context, botID, chatID int) error
+	RemoveChatFromBot(ctx context.Context, botID, chatID int) error
+	GetBotChats(ctx context.Context, botID int) ([]models.Chat, error)
+
 	// Push Notification methods
 	SavePushSubscription(ctx context.Context, userID int, endpoint, p256dh, auth string) error
 	GetPushSubscriptions(ctx context.Context) ([]models.PushSubscription, error)
+	GetUserPushSubscriptions(ctx context.Context, userID int) ([]models.PushSubscription, error)
+	GetChatPushSubscriptions(ctx context.Context, chatID string) ([]models.PushSubscription, error)
+	DeletePushSubscription(ctx context.Context, userID int, endpoint string) error
+
+	// Ticket connectors
+	CreateTicketConnector(ctx context.Context, c models.TicketConnector) (models.TicketConnector, error)
+	GetTicketConnector(ctx context.Context, id int) (models.TicketConnector, error)
+	GetTicketConnectors(ctx context.Context) ([]models.TicketConnector, error)
+	DeleteTicketConnector(ctx context.Context, id int) error
+	SaveAlertTicket(ctx context.Context, t models.AlertTicket) error
+	GetAlertTickets(ctx context.Context, alertID int) ([]models.AlertTicket, error)
+	GetAlertTicketByExternalID(ctx context.Context, connectorID int, externalID string) (models.AlertTicket, error)
+	UpdateAlertTicketStatus(ctx context.Context, alertID, connectorID int, status string) error
+
+	// Ingest tokens
+	CreateIngestToken(ctx context.Context, name string, rateLimit, createdBy int) (models.IngestToken, string, error)
+	GetIngestTokens(ctx context.Context) ([]models.IngestToken, error)
+	AuthenticateIngestToken(ctx context.Context, token string) (models.IngestToken, error)
+	RevokeIngestToken(ctx context.Context, id int) error
+
+	// API keys
+	CreateAPIKey(ctx context.Context, userID int, name, scope string) (models.APIKey, string, error)
+	GetAPIKeys(ctx context.Context, userID int) ([]models.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID, id int) error
+	AuthenticateAPIKey(ctx context.Context, key string) (models.APIKey, error)
+
+	// Invitations
+	CreateInvitation(ctx context.Context, inv models.Invitation) (models.Invitation, error)
+	GetInvitations(ctx context.Context) ([]models.Invitation, error)
+	GetInvitation(ctx context.Context, id int) (models.Invitation, error)
+	DeleteInvitation(ctx context.Context, id int) error
+	AcceptInvitation(ctx context.Context, id int, username, passwordHash string) (models.User, error)
+
+	// Passkeys
+	CreatePasskey(ctx context.Context, p models.Passkey) (models.Passkey, error)
+	GetPasskeys(ctx context.Context, userID int) ([]models.Passkey, error)
+	GetPasskeyByCredentialID(ctx context.Context, credentialID []byte) (models.Passkey, error)
+	RecordPasskeyUse(ctx context.Context, id int, signCount uint32) error
+	DeletePasskey(ctx context.Context, userID, id int) error
+
+	// Webhook mapping profiles
+	CreateWebhookMapping(ctx context.Context, m models.WebhookMapping) (models.WebhookMapping, error)
+	UpdateWebhookMapping(ctx context.Context, m models.WebhookMapping) (models.WebhookMapping, error)
+	GetWebhookMappings(ctx context.Context) ([]models.WebhookMapping, error)
+	GetWebhookMappingByName(ctx context.Context, name string) (models.WebhookMapping, error)
+	DeleteWebhookMapping(ctx context.Context, id int) error
+
+	// Metric threshold rules
+	CreateMetricRule(ctx context.Context, rule models.MetricRule) (models.MetricRule, error)
+	UpdateMetricRule(ctx context.Context, rule models.MetricRule) (models.MetricRule, error)
+	GetMetricRules(ctx context.Context) ([]models.MetricRule, error)
+	DeleteMetricRule(ctx context.Context, id int) error
+
+	// Heartbeat monitors
+	CreateHeartbeat(ctx context.Context, hb models.Heartbeat) (models.Heartbeat, error)
+	UpdateHeartbeat(ctx context.Context, hb models.Heartbeat) (models.Heartbeat, error)
+	GetHeartbeats(ctx context.Context) ([]models.Heartbeat, error)
+	DeleteHeartbeat(ctx context.Context, id int) error
+	CheckInHeartbeat(ctx context.Context, token string) (models.Heartbeat, error)
+
+	// Stakeholder subscription lists
+	CreateStakeholderList(ctx context.Context, l models.StakeholderList) (models.StakeholderList, error)
+	UpdateStakeholderList(ctx context.Context, l models.StakeholderList) (models.StakeholderList, error)
+	GetStakeholderLists(ctx context.Context) ([]models.StakeholderList, error)
+	DeleteStakeholderList(ctx context.Context, id int) error
+
+	// Payload schema registry
+	CreatePayloadSchema(ctx context.Context, ps models.PayloadSchema) (models.PayloadSchema, error)
+	UpdatePayloadSchema(ctx context.Context, ps models.PayloadSchema) (models.PayloadSchema, error)
+	GetPayloadSchemas(ctx context.Context) ([]models.PayloadSchema, error)
+	GetPayloadSchemaBySource(ctx context.Context, source string) (models.PayloadSchema, error)
+	DeletePayloadSchema(ctx context.Context, id int) error
+	RecordPayloadSchemaViolation(ctx context.Context, id int, violation string) error
+
+	// Email routes
+	CreateEmailRoute(ctx context.Context, route models.EmailRoute) (models.EmailRoute, error)
+	UpdateEmailRoute(ctx context.Context, route models.EmailRoute) (models.EmailRoute, error)
+	GetEmailRoutes(ctx context.Context) ([]models.EmailRoute, error)
+	DeleteEmailRoute(ctx context.Context, id int) error
+
+	// Opsgenie forwarding rules
+	CreateOpsgenieRule(ctx context.Context, rule models.OpsgenieRule) (models.OpsgenieRule, error)
+	UpdateOpsgenieRule(ctx context.Context, rule models.OpsgenieRule) (models.OpsgenieRule, error)
+	GetOpsgenieRules(ctx context.Context) ([]models.OpsgenieRule, error)
+	DeleteOpsgenieRule(ctx context.Context, id int) error
+
+	// Outgoing webhooks
+	CreateOutgoingWebhook(ctx context.Context, hook models.OutgoingWebhook) (models.OutgoingWebhook, error)
+	UpdateOutgoingWebhook(ctx context.Context, hook models.OutgoingWebhook) (models.OutgoingWebhook, error)
+	GetOutgoingWebhooks(ctx context.Context) ([]models.OutgoingWebhook, error)
+	GetOutgoingWebhook(ctx context.Context, id int) (models.OutgoingWebhook, error)
+	DeleteOutgoingWebhook(ctx context.Context, id int) error
+	RecordWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error
+	GetWebhookDeliveries(ctx context.Context, webhookID, limit int) ([]models.WebhookDelivery, error)
+
+	// Notification delivery history
+	RecordNotificationAttempt(ctx context.Context, a models.NotificationAttempt) error
+	GetNotificationAttempts(ctx context.Context, f models.NotificationAttemptFilter) ([]models.NotificationAttempt, error)
+	PruneNotificationAttempts(ctx context.Context, before time.Time) (int64, error)
+
+	// Notification templates
+	SaveNotificationTemplate(ctx context.Context, t models.NotificationTemplate) (models.NotificationTemplate, error)
+	GetNotificationTemplates(ctx context.Context) ([]models.NotificationTemplate, error)
+	DeleteNotificationTemplate(ctx context.Context, id int) error
+
+	// Dependency checks
+	CreateDependencyCheck(ctx context.Context, d models.DependencyCheck) (models.DependencyCheck, error)
+	UpdateDependencyCheck(ctx context.Context, d models.DependencyCheck) (models.DependencyCheck, error)
+	GetDependencyChecks(ctx context.Context) ([]models.DependencyCheck, error)
+	DeleteDependencyCheck(ctx context.Context, id int) error
+
+	// On-call schedules
+	CreateSchedule(ctx context.Context, sc models.Schedule) (models.Schedule, error)
+	UpdateSchedule(ctx context.Context, sc models.Schedule) (models.Schedule, error)
+	GetSchedules(ctx context.Context) ([]models.Schedule, error)
+	GetSchedule(ctx context.Context, id int) (models.Schedule, error)
+	DeleteSchedule(ctx context.Context, id int) error
+	CreateScheduleOverride(ctx context.Context, o models.ScheduleOverride) (models.ScheduleOverride, error)
+	DeleteScheduleOverride(ctx context.Context, scheduleID, id int) error
+
+	// Business-hours calendars
+	CreateBusinessCalendar(ctx context.Context, c models.BusinessCalendar) (models.BusinessCalendar, error)
+	UpdateBusinessCalendar(ctx context.Context, c models.BusinessCalendar) (models.BusinessCalendar, error)
+	GetBusinessCalendars(ctx context.Context) ([]models.BusinessCalendar, error)
+	GetBusinessCalendar(ctx context.Context, id int) (models.BusinessCalendar, error)
+	DeleteBusinessCalendar(ctx context.Context, id int) error
+
+	// Signature exemptions
+	GetSignatureExemptions(ctx context.Context) ([]models.SignatureExemption, error)
+	SaveSignatureExemption(ctx context.Context, e models.SignatureExemption) (models.SignatureExemption, error)
+	DeleteSignatureExemption(ctx context.Context, endpoint string) error
+
+	// Feature flags
+	GetFeatureFlags(ctx context.Context) ([]models.FeatureFlag, error)
+	GetFeatureFlag(ctx context.Context, key string) (models.FeatureFlag, error)
+	SaveFeatureFlag(ctx context.Context, f models.FeatureFlag) (models.FeatureFlag, error)
+	DeleteFeatureFlag(ctx context.Context, key string) error
+
+	// SMS paging preferences
+	GetSMSPreference(ctx context.Context, userID int) (models.SMSPreference, error)
+	SaveSMSPreference(ctx context.Context, p models.SMSPreference) (models.SMSPreference, error)
+	GetEnabledSMSPreferences(ctx context.Context) ([]models.SMSPreference, error)
 
 	// Audit
 	InsertAudit(ctx context.Context, actorID int, action, targetType string, targetID int, metadata string) error
-	ListAudit(ctx context.Context, limit int) ([]models.AuditLog, error)
+	ListAudit(ctx context.Context, f models.AuditFilter) ([]models.AuditLog, error)
 }
 
 type RedisStore struct {
-	client *redis.Client
+	client  *redis.Client
+	limits  CardinalityLimits
+	replica *replica // nil without a secondary region
 }
 
 func NewRedisStore(opts *redis.Options) *RedisStore {
@@ -83,31 +346,167 @@ func NewRedisStore(opts *redis.Options) *RedisStore {
 	return &RedisStore{client: rdb}
 }
 
+// AddHook installs a go-redis hook on every command the store sends
+func (s *RedisStore) AddHook(hook redis.Hook) {
+	s.client.AddHook(hook)
+}
+
 func (s *RedisStore) Ping(ctx context.Context) error {
 	return s.client.Ping(ctx).Err()
 }
 
+// AddAlert stores a new alert. An alert dropped by a mute rule isn't stored
+// and comes back with ID 0 and no error, so senders don't retry it.
 func (s *RedisStore) AddAlert(ctx context.Context, source, level, title, message string) (models.Alert, error) {
-	// Generate ID
-	id, err := s.client.Incr(ctx, "alert:next_id").Result()
+	a := models.Alert{
+		Source:  source,
+		Level:   level,
+		Title:   title,
+		Message: message,
+	}
+	if s.muted(ctx, a) {
+		return a, nil
+	}
+	return s.storeAlert(ctx, a)
+}
+
+// AddFingerprintedAlert stores an open alert that a later ResolveAlert call with
+// the same fingerprint can close. If an alert with that fingerprint is still open
+// it is updated in place instead of creating a duplicate.
+func (s *RedisStore) AddFingerprintedAlert(ctx context.Context, fingerprint, source, level, title, message string) (models.Alert, error) {
+	return s.UpsertAlert(ctx, models.Alert{
+		Source:      source,
+		Level:       level,
+		Title:       title,
+		Message:     message,
+		Fingerprint: fingerprint,
+	})
+}
+
+// UpsertAlert is AddFingerprintedAlert for callers that also set labels. Without
+// a fingerprint it behaves like AddAlert. Like there, a muted alert or repeat
+// isn't stored and comes back with ID 0.
+func (s *RedisStore) UpsertAlert(ctx context.Context, a models.Alert) (models.Alert, error) {
+	if s.muted(ctx, a) {
+		a.ID = 0
+		return a, nil
+	}
+	if a.Fingerprint == "" {
+		return s.storeAlert(ctx, models.Alert{
+			Source:  a.Source,
+			Level:   a.Level,
+			Title:   a.Title,
+			Message: a.Message,
+			Labels:  a.Labels,
+			Payload: a.Payload,
+		})
+	}
+
+	if existing, err := s.getOpenAlert(ctx, a.Fingerprint); err == nil {
+		silences := s.activeSilences(ctx)
+		return s.mutateAlert(ctx, existing.ID, AlertEventsChannel, func(cur *models.Alert) error {
+			cur.Level = a.Level
+			cur.Title = a.Title
+			cur.Message = a.Message
+			if a.Labels != nil {
+				cur.Labels = s.limits.guardLabels(a.Labels)
+			}
+			if a.Payload != nil {
+				cur.Payload = fitPayload(a.Payload)
+			}
+			// A repeat is silenced by the silences in effect now, so one
+			// that fired during a deploy notifies again once it is over
+			applySilence(cur, silences)
+			return nil
+		})
+	}
+
+	return s.storeAlert(ctx, models.Alert{
+		Source:      a.Source,
+		Level:       a.Level,
+		Title:       a.Title,
+		Message:     a.Message,
+		Labels:      a.Labels,
+		Status:      models.AlertStatusOpen,
+		Fingerprint: a.Fingerprint,
+		Payload:     a.Payload,
+	})
+}
+
+// MaxAlertPayload caps the raw payload kept on an alert; bigger ones aren't
+// kept at all, as cutting them would leave invalid JSON
+const MaxAlertPayload = 8 << 10
+
+func fitPayload(p json.RawMessage) json.RawMessage {
+	if len(p) > MaxAlertPayload {
+		return nil
+	}
+	return p
+}
+
+// ResolveAlert marks the open alert for fingerprint as resolved.
+// Returns ErrAlertNotFound if no open alert matches.
+func (s *RedisStore) ResolveAlert(ctx context.Context, fingerprint string) (models.Alert, error) {
+	a, err := s.getOpenAlert(ctx, fingerprint)
 	if err != nil {
 		return models.Alert{}, err
 	}
+	return s.ResolveAlertByID(ctx, a.ID)
+}
 
-	a := models.Alert{
-		ID:        int(id),
-		CreatedAt: time.Now().UTC(),
-		Source:    source,
-		Level:     level,
-		Title:     title,
-		Message:   message,
+// GetOpenAlert returns the open alert for fingerprint, or ErrAlertNotFound
+func (s *RedisStore) GetOpenAlert(ctx context.Context, fingerprint string) (models.Alert, error) {
+	return s.getOpenAlert(ctx, fingerprint)
+}
+
+// ResolveAlertByID marks an alert as resolved (a no-op if it already is)
+func (s *RedisStore) ResolveAlertByID(ctx context.Context, id int) (models.Alert, error) {
+	resolved := false
+	a, err := s.mutateAlert(ctx, id, AlertEventsChannel, func(a *models.Alert) error {
+		resolved = false
+		if a.Status == models.AlertStatusResolved {
+			return errUnchanged
+		}
+		resolved = true
+		now := time.Now().UTC()
+		a.Status = models.AlertStatusResolved
+		a.ResolvedAt = &now
+		return nil
+	})
+	if err != nil {
+		return models.Alert{}, err
+	}
+	if a.Fingerprint != "" {
+		s.client.Del(ctx, fingerprintKey(a.Fingerprint))
+	}
+	if resolved {
+		s.incrAlertStat(ctx, a, "resolved")
 	}
+	return a, nil
+}
+
+func (s *RedisStore) storeAlert(ctx context.Context, a models.Alert) (models.Alert, error) {
+	// Generate ID
+	id, err := s.nextAlertID(ctx)
+	if err != nil {
+		return models.Alert{}, err
+	}
+
+	a.ID = id
+	a.CreatedAt = time.Now().UTC()
+	a.Source = models.NormalizeSource(a.Source)
+	a.Bot, a.ChatID = models.ParseSource(a.Source)
+	a.Labels = s.limits.guardLabels(a.Labels)
+	a.Payload = fitPayload(a.Payload)
+	a.RequestID = requestid.FromContext(ctx)
+	applySilence(&a, s.activeSilences(ctx))
 	data, err := json.Marshal(a)
 	if err != nil {
 		return models.Alert{}, err
 	}
 
 	key := fmt.Sprintf("alert:%d", a.ID)
+	sourceIndex := s.sourceIndex(ctx, a.Source)
 
 	// Store alert as hash with TTL
 	pipe := s.client.Pipeline()
@@ -120,41 +519,316 @@ func (s *RedisStore) AddAlert(ctx context.Context, source, level, title, message
 	})
 
 	// Add to search indices
-	if level != "" {
-		pipe.SAdd(ctx, fmt.Sprintf("alerts:level:%s", strings.ToLower(level)), key)
-		pipe.Expire(ctx, fmt.Sprintf("alerts:level:%s", strings.ToLower(level)), alertTTL)
+	var indexes []string
+	if a.Level != "" {
+		indexes = append(indexes, levelIndex(a.Level))
+	}
+	if sourceIndex != "" {
+		indexes = append(indexes, sourceIndex)
 	}
-	if source != "" {
-		pipe.SAdd(ctx, fmt.Sprintf("alerts:source:%s", strings.ToLower(source)), key)
-		pipe.Expire(ctx, fmt.Sprintf("alerts:source:%s", strings.ToLower(source)), alertTTL)
+	for _, index := range indexes {
+		pipe.SAdd(ctx, index, key)
+		pipe.Expire(ctx, index, alertTTL)
 	}
+	if a.Fingerprint != "" {
+		pipe.Set(ctx, fingerprintKey(a.Fingerprint), key, alertTTL)
+	}
+	recordAlertStats(ctx, pipe, a)
 
 	_, err = pipe.Exec(ctx)
 	if err != nil {
 		return models.Alert{}, err
 	}
+	s.mirror(a.ID, indexes...)
+	s.addToRollup(ctx, a)
 
 	// Publish event for SSE
-	if err := s.client.Publish(ctx, "alert_events", data).Err(); err != nil {
+	if err := s.client.Publish(ctx, AlertEventsChannel, data).Err(); err != nil {
 		fmt.Println("Failed to publish event:", err)
 	}
 
 	return a, nil
 }
 
+// errUnchanged lets a mutateAlert callback skip the write and publish
+var errUnchanged = errors.New("unchanged")
+
+// mutateAlert applies fn to the stored alert inside a WATCH transaction (so
+// concurrent updates are not lost), keeps its TTL and publishes the result on
+// channel so SSE clients can replace their copy.
+func (s *RedisStore) mutateAlert(ctx context.Context, id int, channel string, fn func(a *models.Alert) error) (models.Alert, error) {
+	key := fmt.Sprintf("alert:%d", id)
+	var updated models.Alert
+	unchanged := false
+
+	txf := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return ErrAlertNotFound
+		} else if err != nil {
+			return err
+		}
+
+		var a models.Alert
+		if err := json.Unmarshal([]byte(val), &a); err != nil {
+			return err
+		}
+		if err := fn(&a); err == errUnchanged {
+			updated, unchanged = a, true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if id := requestid.FromContext(ctx); id != "" {
+			a.RequestID = id // Background jobs keep the last request's
+		}
+
+		data, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, data, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		if err == nil {
+			updated = a
+		}
+		return err
+	}
+
+	for i := 0; i < 5; i++ {
+		err := s.client.Watch(ctx, txf, key)
+		if err == redis.TxFailedErr {
+			continue // Concurrent update, retry
+		}
+		if err != nil {
+			return models.Alert{}, err
+		}
+		if unchanged {
+			return updated, nil
+		}
+		s.mirror(id)
+		s.updateRollup(ctx, updated)
+
+		data, _ := json.Marshal(updated)
+		if err := s.client.Publish(ctx, channel, data).Err(); err != nil {
+			fmt.Println("Failed to publish event:", err)
+		}
+		return updated, nil
+	}
+	return models.Alert{}, errors.New("alert update conflicted, try again")
+}
+
+func (s *RedisStore) getOpenAlert(ctx context.Context, fingerprint string) (models.Alert, error) {
+	key, err := s.client.Get(ctx, fingerprintKey(fingerprint)).Result()
+	if err == redis.Nil {
+		return models.Alert{}, ErrAlertNotFound
+	} else if err != nil {
+		return models.Alert{}, err
+	}
+
+	val, err := s.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		// Alert expired, drop the stale pointer
+		s.client.Del(ctx, fingerprintKey(fingerprint))
+		return models.Alert{}, ErrAlertNotFound
+	} else if err != nil {
+		return models.Alert{}, err
+	}
+
+	var a models.Alert
+	if err := json.Unmarshal([]byte(val), &a); err != nil {
+		return models.Alert{}, err
+	}
+	return a, nil
+}
+
+func fingerprintKey(fingerprint string) string {
+	return "alerts:fingerprint:" + fingerprint
+}
+
+func (s *RedisStore) GetAlert(ctx context.Context, id int) (models.Alert, error) {
+	val, err := s.client.Get(ctx, fmt.Sprintf("alert:%d", id)).Result()
+	if s.fallback(err) {
+		val, err = s.replica.client.Get(ctx, fmt.Sprintf("alert:%d", id)).Result()
+	}
+	if err == redis.Nil {
+		return models.Alert{}, ErrAlertNotFound
+	} else if err != nil {
+		return models.Alert{}, err
+	}
+
+	var a models.Alert
+	if err := json.Unmarshal([]byte(val), &a); err != nil {
+		return models.Alert{}, err
+	}
+	return a, nil
+}
+
+// SetReaction adds (on=true) or removes a user's reaction on an alert
+func (s *RedisStore) SetReaction(ctx context.Context, alertID int, reaction, username string, on bool) (models.Alert, error) {
+	firstAck := false
+	a, err := s.mutateAlert(ctx, alertID, AlertUpdatesChannel, func(a *models.Alert) error {
+		firstAck = false
+		users := make([]string, 0, len(a.Reactions[reaction])+1)
+		for _, u := range a.Reactions[reaction] {
+			if u != username {
+				users = append(users, u)
+			}
+		}
+		if on {
+			users = append(users, username)
+		}
+		if a.Reactions == nil {
+			a.Reactions = make(map[string][]string)
+		}
+		if len(users) > 0 {
+			a.Reactions[reaction] = users
+		} else {
+			delete(a.Reactions, reaction)
+		}
+		if on && a.AckedAt == nil {
+			now := time.Now().UTC()
+			a.AckedAt = &now
+			firstAck = true
+		}
+		return nil
+	})
+	if err == nil && firstAck {
+		s.incrAlertStat(ctx, a, "acked")
+	}
+	return a, err
+}
+
+// AddComment appends a comment to an alert's discussion
+func (s *RedisStore) AddComment(ctx context.Context, alertID int, c models.AlertComment) (models.Alert, error) {
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now().UTC()
+	}
+	return s.mutateAlert(ctx, alertID, AlertUpdatesChannel, func(a *models.Alert) error {
+		a.Comments = append(a.Comments, c)
+		return nil
+	})
+}
+
+// AddAnnotation attaches automation context to an alert, keeping the newest
+// MaxAnnotations
+func (s *RedisStore) AddAnnotation(ctx context.Context, alertID int, an models.AlertAnnotation) (models.Alert, error) {
+	if an.CreatedAt.IsZero() {
+		an.CreatedAt = time.Now().UTC()
+	}
+	return s.mutateAlert(ctx, alertID, AlertUpdatesChannel, func(a *models.Alert) error {
+		a.Annotations = append(a.Annotations, an)
+		if n := len(a.Annotations) - MaxAnnotations; n > 0 {
+			a.Annotations = a.Annotations[n:]
+		}
+		return nil
+	})
+}
+
+// SetAlertLabels merges labels into an alert's labels
+func (s *RedisStore) SetAlertLabels(ctx context.Context, alertID int, labels map[string]string) (models.Alert, error) {
+	return s.mutateAlert(ctx, alertID, AlertUpdatesChannel, func(a *models.Alert) error {
+		if a.Labels == nil {
+			a.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			a.Labels[k] = v
+		}
+		a.Labels = s.limits.guardLabels(a.Labels)
+		return nil
+	})
+}
+
+// CountSuppressedNotification adds a send held back by flood control to the
+// alert's count, published as a silent update like reactions
+func (s *RedisStore) CountSuppressedNotification(ctx context.Context, alertID int) error {
+	_, err := s.mutateAlert(ctx, alertID, AlertUpdatesChannel, func(a *models.Alert) error {
+		a.SuppressedNotifications++
+		return nil
+	})
+	return err
+}
+
+// SetIncidentRole assigns an incident role on an alert to username, or
+// unassigns it when username is empty
+func (s *RedisStore) SetIncidentRole(ctx context.Context, alertID int, role, username string) (models.Alert, error) {
+	return s.mutateAlert(ctx, alertID, AlertUpdatesChannel, func(a *models.Alert) error {
+		if a.Roles[role] == username {
+			return errUnchanged
+		}
+		if username == "" {
+			delete(a.Roles, role)
+			return nil
+		}
+		if a.Roles == nil {
+			a.Roles = make(map[string]string, 1)
+		}
+		a.Roles[role] = username
+		return nil
+	})
+}
+
+// RecordDeploy stores a deploy event, dropping those older than DeployRetention
+func (s *RedisStore) RecordDeploy(ctx context.Context, d models.DeployEvent) error {
+	if d.DeployedAt.IsZero() {
+		d.DeployedAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	pipe := s.client.Pipeline()
+	pipe.ZAdd(ctx, "deploys", redis.Z{Score: float64(d.DeployedAt.Unix()), Member: data})
+	pipe.ZRemRangeByScore(ctx, "deploys", "-inf", fmt.Sprintf("(%d", time.Now().Add(-DeployRetention).Unix()))
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// GetDeploys returns the deploys between from and to, oldest first
+func (s *RedisStore) GetDeploys(ctx context.Context, from, to time.Time) ([]models.DeployEvent, error) {
+	vals, err := s.client.ZRangeByScore(ctx, "deploys", &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.Unix(), 10),
+		Max: strconv.FormatInt(to.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	deploys := make([]models.DeployEvent, 0, len(vals))
+	for _, v := range vals {
+		var d models.DeployEvent
+		if err := json.Unmarshal([]byte(v), &d); err != nil {
+			continue
+		}
+		deploys = append(deploys, d)
+	}
+	return deploys, nil
+}
+
 func (s *RedisStore) GetAlerts(ctx context.Context) ([]models.Alert, error) {
+	alerts, err := s.getAlerts(ctx, s.client)
+	if s.fallback(err) {
+		return s.getAlerts(ctx, s.replica.client)
+	}
+	return alerts, err
+}
+
+func (s *RedisStore) getAlerts(ctx context.Context, client *redis.Client) ([]models.Alert, error) {
 	// Get alert keys from sorted set (newest first)
-	keys, err := s.client.ZRevRange(ctx, "alerts:timeline", 0, -1).Result()
+	keys, err := client.ZRevRange(ctx, "alerts:timeline", 0, -1).Result()
 	if err != nil {
 		return nil, err
 	}
 
 	var alerts []models.Alert
 	for _, key := range keys {
-		val, err := s.client.Get(ctx, key).Result()
+		val, err := client.Get(ctx, key).Result()
 		if err == redis.Nil {
 			// Alert expired, remove from sorted set
-			s.client.ZRem(ctx, "alerts:timeline", key)
+			client.ZRem(ctx, "alerts:timeline", key)
 			continue
 		} else if err != nil {
 			continue
@@ -168,16 +842,123 @@ func (s *RedisStore) GetAlerts(ctx context.Context) ([]models.Alert, error) {
 	return alerts, nil
 }
 
+// GetAlertsSince returns timeline alerts created at or after since, oldest first
+func (s *RedisStore) GetAlertsSince(ctx context.Context, since time.Time) ([]models.Alert, error) {
+	alerts, err := s.getAlertsSince(ctx, s.client, since)
+	if s.fallback(err) {
+		return s.getAlertsSince(ctx, s.replica.client, since)
+	}
+	return alerts, err
+}
+
+func (s *RedisStore) getAlertsSince(ctx context.Context, client *redis.Client, since time.Time) ([]models.Alert, error) {
+	keys, err := client.ZRangeByScore(ctx, "alerts:timeline", &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.Unix(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	vals, err := client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []models.Alert
+	for _, v := range vals {
+		str, ok := v.(string)
+		if !ok {
+			continue // expired
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(str), &a); err == nil {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+// ExpiringAlerts returns open alerts whose retention runs out within the given
+// window. Each alert is returned once per window, so callers can warn about it
+// on every sweep without repeating themselves.
+func (s *RedisStore) ExpiringAlerts(ctx context.Context, within time.Duration) ([]models.Alert, error) {
+	// Without extensions an alert's TTL ends alertTTL after creation
+	cutoff := time.Now().Add(within - alertTTL)
+	keys, err := s.client.ZRangeByScore(ctx, "alerts:timeline", &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(cutoff.Unix(), 10),
+	}).Result()
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	pipe := s.client.Pipeline()
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		ttls[i] = pipe.TTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	var alerts []models.Alert
+	for i, key := range keys {
+		ttl := ttls[i].Val()
+		if ttl <= 0 || ttl > within {
+			continue // gone, or retention already extended
+		}
+		id, err := strconv.Atoi(strings.TrimPrefix(key, "alert:"))
+		if err != nil {
+			continue
+		}
+		a, err := s.GetAlert(ctx, id)
+		if err != nil || a.Status != models.AlertStatusOpen {
+			continue
+		}
+		first, err := s.client.SetNX(ctx, fmt.Sprintf("alert:%d:expiry_warned", a.ID), 1, within).Result()
+		if err != nil || !first {
+			continue
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, nil
+}
+
+// ExtendAlertRetention resets an alert's TTL (and its fingerprint pointer) to d
+// and keeps it on the timeline and in the search indexes for that long
+func (s *RedisStore) ExtendAlertRetention(ctx context.Context, a models.Alert, d time.Duration) error {
+	key := fmt.Sprintf("alert:%d", a.ID)
+	pipe := s.client.Pipeline()
+	pipe.Expire(ctx, key, d)
+	if a.Fingerprint != "" {
+		pipe.Expire(ctx, fingerprintKey(a.Fingerprint), d)
+	}
+	if a.Level != "" {
+		pipe.ExpireGT(ctx, fmt.Sprintf("alerts:level:%s", strings.ToLower(a.Level)), d)
+	}
+	if sourceIndex := s.sourceLookup(ctx, a.Source); sourceIndex != "" {
+		pipe.ExpireGT(ctx, sourceIndex, d)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	s.mirror(a.ID) // Copies the new TTL
+	return nil
+}
+
 func (s *RedisStore) SearchAlerts(ctx context.Context, query, level, source string) ([]models.Alert, error) {
 	var keys []string
+	source = models.NormalizeSource(source)
 
 	// Build intersection of search criteria
 	var setKeys []string
 	if level != "" {
 		setKeys = append(setKeys, fmt.Sprintf("alerts:level:%s", strings.ToLower(level)))
 	}
-	if source != "" {
-		setKeys = append(setKeys, fmt.Sprintf("alerts:source:%s", strings.ToLower(source)))
+	if sourceIndex := s.sourceLookup(ctx, source); sourceIndex != "" {
+		// May be an overflow index shared with other sources
+		setKeys = append(setKeys, sourceIndex)
 	}
 
 	if len(setKeys) > 0 {
@@ -221,6 +1002,10 @@ func (s *RedisStore) SearchAlerts(ctx context.Context, query, level, source stri
 			continue
 		}
 
+		if source != "" && !strings.EqualFold(a.Source, source) {
+			continue
+		}
+
 		// Text search in title and message
 		if query != "" {
 			searchText := strings.ToLower(a.Title + " " + a.Message + " " + a.Source)
@@ -235,128 +1020,259 @@ func (s *RedisStore) SearchAlerts(ctx context.Context, query, level, source stri
 	return alerts, nil
 }
 
+// ClearAlerts empties the timeline and search indices without deleting the
+// alert records themselves; they stay readable by ID until their TTL expires.
+// Use PurgeAllAlerts to delete the records.
 func (s *RedisStore) ClearAlerts(ctx context.Context) error {
-	return s.client.Del(ctx, "alerts").Err()
+	for _, pattern := range []string{"alerts:level:*", "alerts:source:*"} {
+		if err := s.unlinkPattern(ctx, pattern); err != nil {
+			return err
+		}
+	}
+	return s.client.Unlink(ctx, "alerts:timeline", sourceRegistryKey, rollupKey).Err()
 }
 
-func (s *RedisStore) PurgeAllAlerts(ctx context.Context) error {
-	// Delete all keys matching alert:*
-	iter := s.client.Scan(ctx, 0, "alert:*", 0).Iterator()
-	keys := []string{}
-
-	for iter.Next(ctx) {
-		keys = append(keys, iter.Val())
+// ClearAlertsFiltered removes alerts matching f from the timeline and indices
+// (keeping the records, like ClearAlerts) and returns how many were cleared.
+func (s *RedisStore) ClearAlertsFiltered(ctx context.Context, f PurgeFilter) (int, error) {
+	if f.IsEmpty() {
+		count, err := s.client.ZCard(ctx, "alerts:timeline").Result()
+		if err != nil {
+			return 0, err
+		}
+		return int(count), s.ClearAlerts(ctx)
 	}
+	return s.sweepAlerts(ctx, f, sweepClear)
+}
 
-	if err := iter.Err(); err != nil {
-		return err
+func (s *RedisStore) PurgeAllAlerts(ctx context.Context) error {
+	// Alert records (and the ID counter), then timeline and index sets
+	for _, pattern := range []string{"alert:*", "alerts:level:*", "alerts:source:*", "alerts:fingerprint:*"} {
+		if err := s.unlinkPattern(ctx, pattern); err != nil {
+			return err
+		}
 	}
+	return s.client.Unlink(ctx, "alerts:timeline", sourceRegistryKey, rollupKey).Err()
+}
 
-	if len(keys) > 0 {
-		s.client.Del(ctx, keys...)
-	}
+func (s *RedisStore) PurgeAlertsByChat(ctx context.Context, chatID string) error {
+	_, err := s.PurgeAlerts(ctx, PurgeFilter{ChatID: chatID}, false)
+	return err
+}
+
+// SavePurgeConfirmToken keeps a full purge's confirmation token for ttl, for
+// the user who previewed the purge. Kept in Redis so the confirmation can
+// reach any replica.
+func (s *RedisStore) SavePurgeConfirmToken(ctx context.Context, userID int, token string, ttl time.Duration) error {
+	return s.client.Set(ctx, purgeConfirmKey(userID, token), 1, ttl).Err()
+}
+
+// ConsumePurgeConfirmToken invalidates the user's confirmation token and
+// reports whether it was valid. Another user's token is not.
+func (s *RedisStore) ConsumePurgeConfirmToken(ctx context.Context, userID int, token string) (bool, error) {
+	n, err := s.client.Del(ctx, purgeConfirmKey(userID, token)).Result()
+	return n == 1, err
+}
+
+func purgeConfirmKey(userID int, token string) string {
+	return fmt.Sprintf("purge:confirm:%d:%s", userID, token)
+}
 
-	// Clear timeline
-	s.client.Del(ctx, "alerts:timeline")
+// PurgeFilter narrows PurgeAlerts. Zero-value fields match everything.
+type PurgeFilter struct {
+	ChatID string    // Matches sources of the form bot:{name}:chat:{chatID}
+	NoChat bool      // Only alerts not bound to any chat (the "general" channel)
+	Level  string    // Case-insensitive exact level
+	Source string    // Case-insensitive exact source
+	Before time.Time // Only alerts created before this instant
+}
+
+// IsEmpty reports whether the filter matches every alert
+func (f PurgeFilter) IsEmpty() bool {
+	return f.ChatID == "" && !f.NoChat && f.Level == "" && f.Source == "" && f.Before.IsZero()
+}
 
-	// Clear index sets (use SCAN to find them)
-	iter = s.client.Scan(ctx, 0, "alerts:level:*", 0).Iterator()
-	indexKeys := []string{}
-	for iter.Next(ctx) {
-		indexKeys = append(indexKeys, iter.Val())
+func (f PurgeFilter) matches(a models.Alert) bool {
+	// The whole chat ID: chat 1 mustn't take chats 12 or -1001 with it
+	chatID := models.ChatIDFromSource(a.Source)
+	if f.ChatID != "" && chatID != f.ChatID {
+		return false
 	}
-	if err := iter.Err(); err != nil {
-		return err
+	if f.NoChat && chatID != "" {
+		return false
+	}
+	if f.Level != "" && !strings.EqualFold(a.Level, f.Level) {
+		return false
 	}
-	if len(indexKeys) > 0 {
-		s.client.Del(ctx, indexKeys...)
+	if f.Source != "" && !strings.EqualFold(a.Source, f.Source) {
+		return false
 	}
+	if !f.Before.IsZero() && !a.CreatedAt.Before(f.Before) {
+		return false
+	}
+	return true
+}
 
-	iter = s.client.Scan(ctx, 0, "alerts:source:*", 0).Iterator()
-	sourceKeys := []string{}
-	for iter.Next(ctx) {
-		sourceKeys = append(sourceKeys, iter.Val())
+// PurgeAlerts deletes alerts matching f and returns how many were removed.
+// With dryRun set nothing is deleted and the count is a preview. Candidates are
+// walked with SSCAN/ZSCAN and deleted with UNLINK in batches of purgeBatchSize
+// so large purges never block Redis with a single huge command.
+func (s *RedisStore) PurgeAlerts(ctx context.Context, f PurgeFilter, dryRun bool) (int, error) {
+	if f.IsEmpty() {
+		count, err := s.client.ZCard(ctx, "alerts:timeline").Result()
+		if err != nil || dryRun {
+			return int(count), err
+		}
+		return int(count), s.PurgeAllAlerts(ctx)
 	}
-	if err := iter.Err(); err != nil {
-		return err
+
+	mode := sweepDelete
+	if dryRun {
+		mode = sweepCount
 	}
-	if len(sourceKeys) > 0 {
-		s.client.Del(ctx, sourceKeys...)
+	return s.sweepAlerts(ctx, f, mode)
+}
+
+// sweepMode selects what sweepAlerts does with matching alerts
+type sweepMode int
+
+const (
+	sweepCount  sweepMode = iota // Count only
+	sweepClear                   // Drop from timeline and indices, keep the record
+	sweepDelete                  // Delete the record too
+)
+
+func (s *RedisStore) sweepAlerts(ctx context.Context, f PurgeFilter, mode sweepMode) (int, error) {
+	f.Source = models.NormalizeSource(f.Source)
+
+	// Walk the narrowest index available (a source without an index of its
+	// own falls back to the timeline)
+	sourceIndex := s.sourceLookup(ctx, f.Source)
+	var scan func(cursor uint64) ([]string, uint64, error)
+	switch {
+	case f.Level != "":
+		setKey := fmt.Sprintf("alerts:level:%s", strings.ToLower(f.Level))
+		scan = func(cursor uint64) ([]string, uint64, error) {
+			return s.client.SScan(ctx, setKey, cursor, "", purgeBatchSize).Result()
+		}
+	case sourceIndex != "":
+		scan = func(cursor uint64) ([]string, uint64, error) {
+			return s.client.SScan(ctx, sourceIndex, cursor, "", purgeBatchSize).Result()
+		}
+	default:
+		scan = func(cursor uint64) ([]string, uint64, error) {
+			// ZSCAN returns member/score pairs
+			pairs, next, err := s.client.ZScan(ctx, "alerts:timeline", cursor, "", purgeBatchSize).Result()
+			keys := make([]string, 0, len(pairs)/2)
+			for i := 0; i < len(pairs); i += 2 {
+				keys = append(keys, pairs[i])
+			}
+			return keys, next, err
+		}
 	}
 
-	return nil
+	total := 0
+	var cursor uint64
+	for {
+		keys, next, err := scan(cursor)
+		if err != nil {
+			return total, err
+		}
+		n, err := s.sweepBatch(ctx, keys, f, mode)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if next == 0 {
+			if total > 0 && mode != sweepCount {
+				s.rebuildRollup(ctx)
+			}
+			return total, nil
+		}
+		cursor = next
+	}
 }
 
-func (s *RedisStore) PurgeAlertsByChat(ctx context.Context, chatID string) error {
-	// Get all alert keys from timeline
-	keys, err := s.client.ZRevRange(ctx, "alerts:timeline", 0, -1).Result()
+// sweepBatch loads one batch of alert keys and applies mode to those matching f
+func (s *RedisStore) sweepBatch(ctx context.Context, keys []string, f PurgeFilter, mode sweepMode) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	vals, err := s.client.MGet(ctx, keys...).Result()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Track keys to delete
-	keysToDelete := []string{}
-	sourceIndexesToUpdate := make(map[string][]string) // source -> [keys to remove]
-
-	// Filter alerts by chat ID in source
-	for _, key := range keys {
-		val, err := s.client.Get(ctx, key).Result()
-		if err == redis.Nil {
-			continue
-		} else if err != nil {
+	pipe := s.client.Pipeline()
+	matched := 0
+	sourceIndexes := map[string]string{} // Source lookups in this batch
+	for i, v := range vals {
+		key := keys[i]
+		raw, ok := v.(string)
+		if !ok {
+			// Expired alert, drop the dangling index entry while we're here
+			if mode != sweepCount {
+				pipe.ZRem(ctx, "alerts:timeline", key)
+			}
 			continue
 		}
 
 		var a models.Alert
-		if err := json.Unmarshal([]byte(val), &a); err != nil {
+		if err := json.Unmarshal([]byte(raw), &a); err != nil || !f.matches(a) {
+			continue
+		}
+		matched++
+		if mode == sweepCount {
 			continue
 		}
 
-		// Check if source contains the chat ID
-		// Format: bot:{botname}:chat:{chatID}
-		if strings.Contains(a.Source, fmt.Sprintf("chat:%s", chatID)) {
-			keysToDelete = append(keysToDelete, key)
-
-			// Track source indexes to update
-			if a.Source != "" {
-				sourceKey := fmt.Sprintf("alerts:source:%s", strings.ToLower(a.Source))
-				sourceIndexesToUpdate[sourceKey] = append(sourceIndexesToUpdate[sourceKey], key)
-			}
-
-			// Track level indexes to update
-			if a.Level != "" {
-				levelKey := fmt.Sprintf("alerts:level:%s", strings.ToLower(a.Level))
-				sourceIndexesToUpdate[levelKey] = append(sourceIndexesToUpdate[levelKey], key)
+		pipe.ZRem(ctx, "alerts:timeline", key)
+		if a.Level != "" {
+			pipe.SRem(ctx, fmt.Sprintf("alerts:level:%s", strings.ToLower(a.Level)), key)
+		}
+		sourceIndex, ok := sourceIndexes[a.Source]
+		if !ok {
+			sourceIndex = s.sourceLookup(ctx, a.Source)
+			sourceIndexes[a.Source] = sourceIndex
+		}
+		if sourceIndex != "" {
+			pipe.SRem(ctx, sourceIndex, key)
+		}
+		if mode == sweepDelete {
+			pipe.Unlink(ctx, key)
+			if a.Fingerprint != "" && a.Status == models.AlertStatusOpen {
+				pipe.Del(ctx, fingerprintKey(a.Fingerprint))
 			}
 		}
 	}
 
-	// Delete the alerts
-	if len(keysToDelete) > 0 {
-		pipe := s.client.Pipeline()
-
-		// Delete alert keys
-		pipe.Del(ctx, keysToDelete...)
+	if mode == sweepCount || pipe.Len() == 0 {
+		return matched, nil
+	}
+	_, err = pipe.Exec(ctx)
+	return matched, err
+}
 
-		// Remove from timeline
-		for _, key := range keysToDelete {
-			pipe.ZRem(ctx, "alerts:timeline", key)
+// unlinkPattern removes every key matching pattern using SCAN + batched UNLINK
+func (s *RedisStore) unlinkPattern(ctx context.Context, pattern string) error {
+	var cursor uint64
+	for {
+		keys, next, err := s.client.Scan(ctx, cursor, pattern, purgeBatchSize).Result()
+		if err != nil {
+			return err
 		}
-
-		// Remove from index sets
-		for indexKey, members := range sourceIndexesToUpdate {
-			for _, member := range members {
-				pipe.SRem(ctx, indexKey, member)
+		if len(keys) > 0 {
+			if err := s.client.Unlink(ctx, keys...).Err(); err != nil {
+				return err
 			}
 		}
-
-		_, err := pipe.Exec(ctx)
-		return err
+		if next == 0 {
+			return nil
+		}
+		cursor = next
 	}
-
-	return nil
 }
 
 func (s *RedisStore) Subscribe(ctx context.Context) *redis.PubSub {
-	return s.client.Subscribe(ctx, "alert_events")
+	return s.client.Subscribe(ctx, AlertEventsChannel, AlertUpdatesChannel)
 }
@@ -0,0 +1,294 @@
+// Package syslog receives syslog messages over UDP and TCP and turns the
+// severe ones into alerts.
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	source       = "syslog"
+	defaultAddr  = ":5514"
+	maxMessage   = 64 * 1024
+	maxTitle     = 120
+	dedupeWindow = time.Minute
+	tcpIdle      = 5 * time.Minute
+)
+
+// Syslog severities (RFC 5424 section 6.2.1)
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// Config selects where the listener binds and what it reports
+type Config struct {
+	UDPAddr     string // Empty: no UDP listener
+	TCPAddr     string // Empty: no TCP listener
+	MinSeverity int    // Messages less severe than this (numerically higher) are dropped
+}
+
+// ConfigFromEnv reads SYSLOG_UDP_ADDR, SYSLOG_TCP_ADDR and SYSLOG_MIN_SEVERITY.
+// Without either address both protocols listen on :5514; the minimum
+// severity defaults to warning.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		UDPAddr:     os.Getenv("SYSLOG_UDP_ADDR"),
+		TCPAddr:     os.Getenv("SYSLOG_TCP_ADDR"),
+		MinSeverity: 4,
+	}
+	if cfg.UDPAddr == "" && cfg.TCPAddr == "" {
+		cfg.UDPAddr, cfg.TCPAddr = defaultAddr, defaultAddr
+	}
+	if v := os.Getenv("SYSLOG_MIN_SEVERITY"); v != "" {
+		sev, err := ParseSeverity(v)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.MinSeverity = sev
+	}
+	return cfg, nil
+}
+
+// ParseSeverity accepts a syslog severity number (0-7), keyword (err,
+// warning, ...) or alert level (critical, error, ...)
+func ParseSeverity(v string) (int, error) {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 && n <= 7 {
+		return n, nil
+	}
+	for i, name := range severityNames {
+		if v == name {
+			return i, nil
+		}
+	}
+	switch models.NormalizeLevel(v) {
+	case models.LevelCritical:
+		return 2, nil
+	case models.LevelError:
+		return 3, nil
+	case models.LevelWarning:
+		return 4, nil
+	case models.LevelInfo:
+		return 6, nil
+	}
+	return 0, fmt.Errorf("unknown syslog severity %q", v)
+}
+
+// severityLevel maps a syslog severity to an alert level
+func severityLevel(sev int) string {
+	switch {
+	case sev <= 2: // emerg, alert, crit
+		return models.LevelCritical
+	case sev == 3:
+		return models.LevelError
+	case sev == 4:
+		return models.LevelWarning
+	default:
+		return models.LevelInfo
+	}
+}
+
+type Listener struct {
+	alerts store.AlertStore
+	cfg    Config
+
+	mu    sync.Mutex
+	seen  map[string]time.Time // host/app/content -> last alert, to absorb log storms
+	swept time.Time
+}
+
+func NewListener(alerts store.AlertStore, cfg Config) *Listener {
+	return &Listener{alerts: alerts, cfg: cfg, seen: make(map[string]time.Time)}
+}
+
+// Run listens until ctx is cancelled. It fails fast if a socket can't be bound.
+func (l *Listener) Run(ctx context.Context) error {
+	var (
+		udp net.PacketConn
+		tcp net.Listener
+		err error
+	)
+	if l.cfg.UDPAddr != "" {
+		if udp, err = net.ListenPacket("udp", l.cfg.UDPAddr); err != nil {
+			return err
+		}
+		defer udp.Close()
+		go l.serveUDP(ctx, udp)
+		log.Printf("Syslog listening on udp %s", l.cfg.UDPAddr)
+	}
+	if l.cfg.TCPAddr != "" {
+		if tcp, err = net.Listen("tcp", l.cfg.TCPAddr); err != nil {
+			return err
+		}
+		defer tcp.Close()
+		go l.serveTCP(ctx, tcp)
+		log.Printf("Syslog listening on tcp %s", l.cfg.TCPAddr)
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func (l *Listener) serveUDP(ctx context.Context, conn net.PacketConn) {
+	buf := make([]byte, maxMessage)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("syslog: udp read: %v", err)
+			}
+			return
+		}
+		l.handle(ctx, string(buf[:n]), addr)
+	}
+}
+
+func (l *Listener) serveTCP(ctx context.Context, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("syslog: tcp accept: %v", err)
+			}
+			return
+		}
+		go l.serveConn(ctx, conn)
+	}
+}
+
+// serveConn reads RFC 6587 framed messages: octet counting ("LEN MSG") or
+// newline delimited, detected per message
+func (l *Listener) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReaderSize(conn, maxMessage)
+	for {
+		conn.SetReadDeadline(time.Now().Add(tcpIdle))
+		raw, err := readFrame(r)
+		if raw != "" {
+			l.handle(ctx, raw, conn.RemoteAddr())
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				log.Printf("syslog: tcp %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+func readFrame(r *bufio.Reader) (string, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		lenStr, err := r.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+		if err != nil || n > maxMessage {
+			return "", fmt.Errorf("invalid frame length %q", lenStr)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	line, err := r.ReadString('\n')
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+func (l *Listener) handle(ctx context.Context, raw string, addr net.Addr) {
+	m, err := Parse(raw)
+	if err != nil || m.Severity > l.cfg.MinSeverity {
+		return
+	}
+
+	host := m.Hostname
+	if host == "" {
+		host, _, _ = net.SplitHostPort(addr.String())
+	}
+	if !l.firstInWindow(host + "\x00" + m.AppName + "\x00" + m.Content) {
+		return
+	}
+
+	title := host
+	if m.AppName != "" {
+		title += " " + m.AppName
+	}
+	title += ": " + firstLine(m.Content)
+
+	labels := map[string]string{
+		"host":     host,
+		"severity": severityNames[m.Severity],
+		"facility": strconv.Itoa(m.Facility),
+		"remote":   addr.String(),
+	}
+	if m.AppName != "" {
+		labels["app"] = m.AppName
+	}
+	if m.ProcID != "" {
+		labels["pid"] = m.ProcID
+	}
+	if m.MsgID != "" {
+		labels["msgid"] = m.MsgID
+	}
+
+	message := m.Content
+	if message == "" {
+		message = "No content"
+	}
+	a := models.Alert{
+		Source:  source,
+		Level:   severityLevel(m.Severity),
+		Title:   title,
+		Message: message,
+		Labels:  labels,
+	}
+	if _, err := l.alerts.UpsertAlert(ctx, a); err != nil {
+		log.Printf("syslog: failed to store alert: %v", err)
+	}
+}
+
+// firstInWindow reports whether key hasn't produced an alert within the
+// dedupe window, and records it
+func (l *Listener) firstInWindow(key string) bool {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > dedupeWindow {
+		for k, t := range l.seen {
+			if now.Sub(t) > dedupeWindow {
+				delete(l.seen, k)
+			}
+		}
+		l.swept = now
+	}
+	if t, ok := l.seen[key]; ok && now.Sub(t) <= dedupeWindow {
+		return false
+	}
+	l.seen[key] = now
+	return true
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	if r := []rune(s); len(r) > maxTitle {
+		s = string(r[:maxTitle]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,134 @@
+package syslog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is a parsed RFC 5424 or RFC 3164 syslog message
+type Message struct {
+	Facility  int
+	Severity  int
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Content   string
+}
+
+var errNoPriority = errors.New("missing <PRI>")
+
+// Parse reads a single syslog message. RFC 5424 is detected by the version
+// after the priority; anything else is treated as RFC 3164, which in practice
+// means being lenient about missing timestamps and hostnames.
+func Parse(raw string) (Message, error) {
+	raw = strings.TrimRight(raw, "\r\n\x00")
+	pri, rest, err := parsePriority(raw)
+	if err != nil {
+		return Message{}, err
+	}
+	m := Message{Facility: pri / 8, Severity: pri % 8}
+
+	if strings.HasPrefix(rest, "1 ") {
+		parse5424(&m, rest[2:])
+	} else {
+		parse3164(&m, rest)
+	}
+	return m, nil
+}
+
+func parsePriority(raw string) (int, string, error) {
+	if !strings.HasPrefix(raw, "<") {
+		return 0, "", errNoPriority
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 2 || end > 4 {
+		return 0, "", errNoPriority
+	}
+	pri, err := strconv.Atoi(raw[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, "", errNoPriority
+	}
+	return pri, raw[end+1:], nil
+}
+
+// parse5424 handles "TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG"
+func parse5424(m *Message, rest string) {
+	fields := make([]string, 5)
+	for i := range fields {
+		var f string
+		f, rest, _ = strings.Cut(rest, " ")
+		if f != "-" {
+			fields[i] = f
+		}
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err == nil {
+		m.Timestamp = ts
+	}
+	m.Hostname, m.AppName, m.ProcID, m.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	rest = skipStructuredData(rest)
+	m.Content = strings.TrimPrefix(rest, "\ufeff")
+}
+
+// skipStructuredData drops the SD-ELEMENTs ("-" or "[id k=\"v\"]...") before MSG
+func skipStructuredData(s string) string {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimPrefix(s[1:], " ")
+	}
+	for strings.HasPrefix(s, "[") {
+		i, escaped, inQuote := 1, false, false
+		for ; i < len(s); i++ {
+			c := s[i]
+			if escaped {
+				escaped = false
+				continue
+			}
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inQuote = !inQuote
+			} else if c == ']' && !inQuote {
+				break
+			}
+		}
+		if i >= len(s) {
+			return ""
+		}
+		s = s[i+1:]
+	}
+	return strings.TrimPrefix(s, " ")
+}
+
+// parse3164 handles "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG"
+func parse3164(m *Message, rest string) {
+	if len(rest) >= 16 {
+		if ts, err := time.ParseInLocation(time.Stamp, rest[:15], time.Local); err == nil && rest[15] == ' ' {
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			// Messages stamped in the "future" are from last year (Dec 31 -> Jan 1)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			m.Timestamp = ts
+			host, after, _ := strings.Cut(rest[16:], " ")
+			m.Hostname = host
+			rest = after
+		}
+	}
+
+	// TAG is alphanumeric up to 32 chars, optionally followed by [PID], then ':'
+	if tag, msg, ok := strings.Cut(rest, ":"); ok && len(tag) <= 48 && !strings.ContainsAny(tag, " \t") {
+		if name, pid, ok := strings.Cut(tag, "["); ok {
+			m.AppName = name
+			m.ProcID = strings.TrimSuffix(pid, "]")
+		} else {
+			m.AppName = tag
+		}
+		rest = msg
+	}
+	m.Content = strings.TrimSpace(rest)
+}
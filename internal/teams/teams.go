@@ -0,0 +1,386 @@
+// Package teams posts alerts to Microsoft Teams channels as Adaptive Cards,
+// through incoming webhooks or Workflows ("When a Teams webhook request is
+// received") URLs.
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/floodcontrol"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/sandbox"
+	"incident-viewer-go/internal/store"
+	"incident-viewer-go/internal/webhooks"
+)
+
+// Target is a Teams channel and the alerts it receives
+type Target struct {
+	Name     string   `json:"name"`
+	URL      string   `json:"url"` // Incoming webhook or Workflows URL
+	MinLevel string   `json:"min_level"`
+	Sources  []string `json:"sources"` // Glob patterns on the alert source; empty = all
+	ChatID   string   `json:"chat_id"` // Public chat_id to scope to; empty = all
+	Secret   string   `json:"secret"`  // Signs posts with X-Sentinel-Signature; empty = unsigned
+}
+
+func (t Target) matches(a models.Alert) bool {
+	if t.MinLevel != "" && models.LevelRank(a.Level) < models.LevelRank(t.MinLevel) && a.Status != models.AlertStatusResolved {
+		return false
+	}
+	if t.ChatID != "" && models.ChatIDFromSource(a.Source) != t.ChatID {
+		return false
+	}
+	if len(t.Sources) == 0 {
+		return true
+	}
+	for _, pattern := range t.Sources {
+		if ok, _ := path.Match(pattern, a.Source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTargets reads the JSON array of targets in TEAMS_TARGETS_FILE, or a
+// single target from TEAMS_WEBHOOK_URL, TEAMS_MIN_LEVEL and TEAMS_WEBHOOK_SECRET
+func LoadTargets() ([]Target, error) {
+	if file := os.Getenv("TEAMS_TARGETS_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var targets []Target
+		if err := json.Unmarshal(data, &targets); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for _, t := range targets {
+			if t.URL == "" {
+				return nil, fmt.Errorf("%s: target %q needs a url", file, t.Name)
+			}
+		}
+		return targets, nil
+	}
+	if u := os.Getenv("TEAMS_WEBHOOK_URL"); u != "" {
+		return []Target{{Name: "default", URL: u, MinLevel: os.Getenv("TEAMS_MIN_LEVEL"), Secret: os.Getenv("TEAMS_WEBHOOK_SECRET")}}, nil
+	}
+	return nil, nil
+}
+
+type Notifier struct {
+	targets   []Target
+	publicURL string // Dashboard base URL for deep links; empty leaves them out
+	http      *http.Client
+
+	sandboxed  bool
+	sandboxURL string // Where sandboxed cards go; empty drops them
+
+	queue    *notifyqueue.Queue    // nil posts once, without retries
+	throttle *floodcontrol.Limiter // nil sends every card
+}
+
+// queueKind names Teams posts in the notification queue
+const queueKind = "teams"
+
+// queuedPost is a card waiting in the notification queue. It names the
+// target rather than carrying its URL, which is a credential.
+type queuedPost struct {
+	Target   string          `json:"target"`
+	Delivery string          `json:"delivery,omitempty"` // X-Sentinel-Delivery, the same on every retry
+	Body     json.RawMessage `json:"body"`
+}
+
+func (p queuedPost) NotificationTarget() string { return p.Target }
+
+// NewNotifier posts to targets; publicURL (SENTINEL_PUBLIC_URL) is where
+// the dashboard is reachable, for the cards' ack/resolve links
+func NewNotifier(targets []Target, publicURL string) *Notifier {
+	return &Notifier{
+		targets:   targets,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		http:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Run posts alert lifecycle events until ch is closed
+func (n *Notifier) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		n.Notify(ctx, a)
+	}
+}
+
+// Sandbox posts one labeled card per alert to url instead of the matching
+// targets; an empty url drops them
+func (n *Notifier) Sandbox(url string) {
+	n.sandboxed = true
+	n.sandboxURL = url
+}
+
+// Queue sends cards through q, which retries failed posts and keeps the
+// ones that keep failing for inspection
+func (n *Notifier) Queue(q *notifyqueue.Queue) {
+	n.queue = q
+	q.Register(queueKind, n.deliver)
+}
+
+// Throttle holds back cards about an alert whose check already notified within
+// l's window
+func (n *Notifier) Throttle(l *floodcontrol.Limiter) {
+	n.throttle = l
+}
+
+// Notify posts a to every matching target
+func (n *Notifier) Notify(ctx context.Context, a models.Alert) {
+	if !slices.ContainsFunc(n.targets, func(t Target) bool { return t.matches(a) }) || !n.throttle.Allow(ctx, floodcontrol.ChannelTeams, a) {
+		return
+	}
+	if n.sandboxed {
+		n.notifySandbox(ctx, a)
+		return
+	}
+	var body []byte
+	for _, t := range n.targets {
+		if !t.matches(a) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(n.message(a, "")); err != nil {
+				log.Printf("teams: failed to build card for alert %d: %v", a.ID, err)
+				return
+			}
+		}
+		delivery, err := webhooks.NewDeliveryID()
+		if err != nil {
+			log.Printf("teams: failed to notify %s of alert %d: %v", t.Name, a.ID, err)
+			continue
+		}
+		if err := n.queue.Send(ctx, queueKind, queuedPost{Target: t.Name, Delivery: delivery, Body: body}, n.deliver); err != nil {
+			log.Printf("teams: failed to notify %s of alert %d: %v", t.Name, a.ID, err)
+		}
+	}
+}
+
+func (n *Notifier) notifySandbox(ctx context.Context, a models.Alert) {
+	var names []string
+	for _, t := range n.targets {
+		if t.matches(a) {
+			names = append(names, t.Name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	if n.sandboxURL == "" {
+		log.Printf("teams: sandbox: dropped alert %d for %s", a.ID, strings.Join(names, ", "))
+		return
+	}
+	body, err := json.Marshal(n.message(a, "Sandbox copy; originally for: "+strings.Join(names, ", ")))
+	if err != nil {
+		log.Printf("teams: failed to build card for alert %d: %v", a.ID, err)
+		return
+	}
+	if err := n.queue.Send(ctx, queueKind, queuedPost{Target: "sandbox", Body: body}, n.deliver); err != nil {
+		log.Printf("teams: failed to post sandbox card for alert %d: %v", a.ID, err)
+	}
+}
+
+// Targets names the configured targets, for channel tests
+func (n *Notifier) Targets(ctx context.Context) ([]string, error) {
+	names := make([]string, 0, len(n.targets))
+	for _, t := range n.targets {
+		names = append(names, t.Name)
+	}
+	return names, nil
+}
+
+// Test posts a to the named target right away, whatever its filters, and
+// returns the result instead of queueing the card. In sandbox mode it goes
+// to the sandbox URL like any other card.
+func (n *Notifier) Test(ctx context.Context, name string, a models.Alert) error {
+	i := slices.IndexFunc(n.targets, func(t Target) bool { return t.Name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown target %q", name)
+	}
+	t, note := n.targets[i], ""
+	if n.sandboxed {
+		if n.sandboxURL == "" {
+			return errors.New("sandbox mode drops Teams cards: set SANDBOX_TEAMS_WEBHOOK_URL")
+		}
+		t, note = Target{Name: "sandbox", URL: n.sandboxURL}, "Sandbox copy; originally for: "+name
+	}
+	body, err := json.Marshal(n.message(a, note))
+	if err != nil {
+		return err
+	}
+	delivery, err := webhooks.NewDeliveryID()
+	if err != nil {
+		return err
+	}
+	return n.post(ctx, t, delivery, body)
+}
+
+// deliver posts a queued card to its target, as configured now
+func (n *Notifier) deliver(ctx context.Context, payload json.RawMessage) error {
+	var p queuedPost
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return notifyqueue.Permanent(err)
+	}
+	if n.sandboxed {
+		if n.sandboxURL == "" {
+			return nil
+		}
+		return n.post(ctx, Target{Name: "sandbox", URL: n.sandboxURL}, p.Delivery, p.Body)
+	}
+	for _, t := range n.targets {
+		if t.Name == p.Target {
+			return n.post(ctx, t, p.Delivery, p.Body)
+		}
+	}
+	return notifyqueue.Permanent(fmt.Errorf("unknown target %q", p.Target))
+}
+
+// post sends a card to t, signed when t has a secret. delivery is empty for
+// posts queued before they carried one; they get a new ID per attempt.
+func (n *Notifier) post(ctx context.Context, t Target, delivery string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if delivery == "" {
+		if delivery, err = webhooks.NewDeliveryID(); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	webhooks.SignRequest(req, t.Secret, delivery, body)
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return notifyqueue.Permanent(err) // Bad URL or card; retrying won't help
+		}
+		return err
+	}
+	return nil
+}
+
+// message wraps the alert's Adaptive Card the way both incoming webhooks and
+// Workflows accept it
+// message wraps a's card for the webhook; note, when set, is added under it
+func (n *Notifier) message(a models.Alert, note string) map[string]any {
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"contentUrl":  nil,
+			"content":     n.card(a, note),
+		}},
+	}
+}
+
+func (n *Notifier) card(a models.Alert, note string) map[string]any {
+	resolved := a.Status == models.AlertStatusResolved
+	title := a.Title
+	color := "Default"
+	switch {
+	case resolved:
+		title = "✅ Resolved: " + a.Title
+		color = "Good"
+	case models.LevelRank(a.Level) >= models.LevelRank(models.LevelError):
+		color = "Attention"
+	case models.LevelRank(a.Level) >= models.LevelRank(models.LevelWarning):
+		color = "Warning"
+	}
+
+	if n.sandboxed {
+		title = sandbox.Label + " " + title
+	}
+
+	facts := []map[string]string{
+		{"title": "Level", "value": strings.ToUpper(a.Level)},
+		{"title": "Source", "value": a.Source},
+		{"title": "Started", "value": a.CreatedAt.UTC().Format("2006-01-02 15:04 UTC")},
+	}
+	if a.Status != "" {
+		facts = append(facts, map[string]string{"title": "Status", "value": a.Status})
+	}
+	for _, role := range models.IncidentRoles {
+		if u := a.Roles[role]; u != "" {
+			facts = append(facts, map[string]string{"title": strings.ToUpper(role[:1]) + role[1:], "value": u})
+		}
+	}
+
+	message := a.Message
+	if r := []rune(message); len(r) > 1000 {
+		message = string(r[:1000]) + "…"
+	}
+
+	body := []map[string]any{
+		{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+		{"type": "FactSet", "facts": facts},
+		{"type": "TextBlock", "text": message, "wrap": true},
+	}
+	if note != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": note, "isSubtle": true, "wrap": true})
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]any{"width": "Full"},
+		"body":    body,
+	}
+
+	if n.publicURL != "" {
+		actions := []map[string]any{}
+		if !resolved {
+			actions = append(actions,
+				map[string]any{"type": "Action.OpenUrl", "title": "Acknowledge", "url": n.link(a.ID, "ack")},
+				map[string]any{"type": "Action.OpenUrl", "title": "Resolve", "url": n.link(a.ID, "resolve")},
+			)
+		}
+		actions = append(actions, map[string]any{"type": "Action.OpenUrl", "title": "Open in Sentinel", "url": n.link(a.ID, "")})
+		card["actions"] = actions
+	}
+	return card
+}
+
+// link deep-links into the dashboard, which asks the signed-in user to
+// confirm the action
+func (n *Notifier) link(alertID int, action string) string {
+	q := url.Values{"alert": {fmt.Sprint(alertID)}}
+	if action != "" {
+		q.Set("action", action)
+	}
+	return n.publicURL + "/?" + q.Encode()
+}
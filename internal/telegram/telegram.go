@@ -0,0 +1,363 @@
+// Package telegram forwards alerts to real Telegram chats through the Bot API.
+// (The /telegram/ endpoint goes the other way: it accepts what bots would
+// send to Telegram and turns it into alerts.)
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/floodcontrol"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/sandbox"
+	"incident-viewer-go/internal/shortlink"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	apiBase      = "https://api.telegram.org"
+	sendAttempts = 4
+	maxRetryWait = time.Minute
+	maxMessage   = 4096 // Telegram's limit, in characters after entity parsing
+)
+
+// Target is a Telegram chat and the alerts it receives
+type Target struct {
+	ChatID   string   `json:"chat_id"` // Numeric ID or @channelusername
+	MinLevel string   `json:"min_level"`
+	Sources  []string `json:"sources"` // Glob patterns on the alert source; empty = all
+}
+
+func (t Target) matches(a models.Alert) bool {
+	if t.MinLevel != "" && models.LevelRank(a.Level) < models.LevelRank(t.MinLevel) && a.Status != models.AlertStatusResolved {
+		return false
+	}
+	if len(t.Sources) == 0 {
+		return true
+	}
+	for _, pattern := range t.Sources {
+		if ok, _ := path.Match(pattern, a.Source); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTargets reads the JSON array of targets in TELEGRAM_TARGETS_FILE, or
+// one target per chat in TELEGRAM_CHAT_IDS (comma separated) at
+// TELEGRAM_MIN_LEVEL
+func LoadTargets() ([]Target, error) {
+	if file := os.Getenv("TELEGRAM_TARGETS_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var targets []Target
+		if err := json.Unmarshal(data, &targets); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for i, t := range targets {
+			if t.ChatID == "" {
+				return nil, fmt.Errorf("%s: target %d needs a chat_id", file, i)
+			}
+		}
+		return targets, nil
+	}
+	var targets []Target
+	for _, id := range strings.Split(os.Getenv("TELEGRAM_CHAT_IDS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			targets = append(targets, Target{ChatID: id, MinLevel: os.Getenv("TELEGRAM_MIN_LEVEL")})
+		}
+	}
+	return targets, nil
+}
+
+type Forwarder struct {
+	token   string
+	targets []Target
+	links   *shortlink.Shortener
+	http    *http.Client
+
+	sandboxed   bool
+	sandboxChat string // Where sandboxed messages go; empty drops them
+
+	queue    *notifyqueue.Queue    // nil sends once, with only the in-call retries
+	throttle *floodcontrol.Limiter // nil sends every message
+}
+
+// queueKind names Telegram messages in the notification queue
+const queueKind = "telegram"
+
+// queuedMessage is a message waiting in the notification queue
+type queuedMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+func (m queuedMessage) NotificationTarget() string { return m.ChatID }
+
+// NewForwarder sends as the bot with the given Bot API token
+// (TELEGRAM_BOT_TOKEN); links adds a short link to the alert when a public
+// URL is configured
+func NewForwarder(token string, targets []Target, links *shortlink.Shortener) *Forwarder {
+	return &Forwarder{
+		token:   token,
+		targets: targets,
+		links:   links,
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Run forwards alert lifecycle events until ch is closed
+func (f *Forwarder) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		f.Forward(ctx, a)
+	}
+}
+
+// Sandbox sends one labeled message per alert to chatID instead of the
+// matching chats; an empty chatID drops them
+func (f *Forwarder) Sandbox(chatID string) {
+	f.sandboxed = true
+	f.sandboxChat = chatID
+}
+
+// Queue sends messages through q, which retries failed sends and keeps the
+// ones that keep failing for inspection
+func (f *Forwarder) Queue(q *notifyqueue.Queue) {
+	f.queue = q
+	q.Register(queueKind, f.deliver)
+}
+
+// Throttle holds back messages about an alert whose check already notified within
+// l's window
+func (f *Forwarder) Throttle(l *floodcontrol.Limiter) {
+	f.throttle = l
+}
+
+// Forward sends a to every matching chat
+func (f *Forwarder) Forward(ctx context.Context, a models.Alert) {
+	if !slices.ContainsFunc(f.targets, func(t Target) bool { return t.matches(a) }) || !f.throttle.Allow(ctx, floodcontrol.ChannelTelegram, a) {
+		return
+	}
+	text := Format(a)
+	if link := f.links.AlertURL(ctx, a.ID); link != "" {
+		text += "\n\n[View in Sentinel](" + escapeLink(link) + ")"
+	}
+	if f.sandboxed {
+		var chats []string
+		for _, t := range f.targets {
+			if t.matches(a) {
+				chats = append(chats, t.ChatID)
+			}
+		}
+		if len(chats) == 0 {
+			return
+		}
+		if f.sandboxChat == "" {
+			log.Printf("telegram: sandbox: dropped alert %d for %s", a.ID, strings.Join(chats, ", "))
+			return
+		}
+		text = Escape(sandbox.Label+" originally for "+strings.Join(chats, ", ")) + "\n" + text
+		if err := f.queue.Send(ctx, queueKind, queuedMessage{ChatID: f.sandboxChat, Text: text}, f.deliver); err != nil {
+			log.Printf("telegram: failed to forward alert %d to sandbox chat %s: %v", a.ID, f.sandboxChat, err)
+		}
+		return
+	}
+	for _, t := range f.targets {
+		if !t.matches(a) {
+			continue
+		}
+		if err := f.queue.Send(ctx, queueKind, queuedMessage{ChatID: t.ChatID, Text: text}, f.deliver); err != nil {
+			log.Printf("telegram: failed to forward alert %d to chat %s: %v", a.ID, t.ChatID, err)
+		}
+	}
+}
+
+// Targets lists the configured chats, for channel tests
+func (f *Forwarder) Targets(ctx context.Context) ([]string, error) {
+	chats := make([]string, 0, len(f.targets))
+	for _, t := range f.targets {
+		chats = append(chats, t.ChatID)
+	}
+	return chats, nil
+}
+
+// Test sends a to the chat right away, whatever its filters, and returns the
+// result instead of queueing the message. In sandbox mode it goes to the
+// sandbox chat like any other message.
+func (f *Forwarder) Test(ctx context.Context, chatID string, a models.Alert) error {
+	if !slices.ContainsFunc(f.targets, func(t Target) bool { return t.ChatID == chatID }) {
+		return fmt.Errorf("unknown chat %q", chatID)
+	}
+	text := Format(a)
+	if f.sandboxed {
+		if f.sandboxChat == "" {
+			return errors.New("sandbox mode drops Telegram messages: set SANDBOX_TELEGRAM_CHAT_ID")
+		}
+		text = Escape(sandbox.Label+" originally for "+chatID) + "\n" + text
+		chatID = f.sandboxChat
+	}
+	return f.send(ctx, chatID, text)
+}
+
+// apiResponse is the Bot API's reply envelope
+type apiResponse struct {
+	OK          bool   `json:"ok"`
+	ErrorCode   int    `json:"error_code"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+func (f *Forwarder) deliver(ctx context.Context, payload json.RawMessage) error {
+	var m queuedMessage
+	if err := json.Unmarshal(payload, &m); err != nil {
+		return notifyqueue.Permanent(err)
+	}
+	return f.send(ctx, m.ChatID, m.Text)
+}
+
+func (f *Forwarder) send(ctx context.Context, chatID, text string) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"parse_mode":               "MarkdownV2",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	var wait time.Duration
+	for attempt := 0; attempt < sendAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiBase+"/bot"+f.token+"/sendMessage", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := f.http.Do(req)
+		if err != nil {
+			// The URL holds the token; don't let it reach the logs
+			lastErr = fmt.Errorf("request failed: %v", strings.ReplaceAll(err.Error(), f.token, "<token>"))
+			wait = time.Duration(attempt+1) * time.Second
+			continue
+		}
+		var res apiResponse
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300 && res.OK:
+			return nil
+		case resp.StatusCode == http.StatusTooManyRequests:
+			// Flood control: Telegram says how long to back off
+			lastErr = fmt.Errorf("%s: %s", resp.Status, res.Description)
+			wait = time.Duration(res.Parameters.RetryAfter) * time.Second
+			if wait <= 0 {
+				wait = time.Duration(attempt+1) * time.Second
+			}
+			if wait > maxRetryWait {
+				return lastErr
+			}
+		case resp.StatusCode < 500:
+			// Bad token, unknown chat, bot kicked, ...: retrying won't help
+			return notifyqueue.Permanent(fmt.Errorf("%s: %s", resp.Status, res.Description))
+		default:
+			lastErr = fmt.Errorf("%s: %s", resp.Status, res.Description)
+			wait = time.Duration(attempt+1) * time.Second
+		}
+	}
+	return lastErr
+}
+
+// levelEmoji leads the message so the level shows in chat previews
+var levelEmoji = map[string]string{
+	models.LevelCritical: "🔴",
+	models.LevelError:    "🟠",
+	models.LevelWarning:  "🟡",
+	models.LevelInfo:     "🔵",
+	models.LevelSuccess:  "🟢",
+}
+
+// Format renders a as a MarkdownV2 message
+func Format(a models.Alert) string {
+	var b strings.Builder
+	if a.Status == models.AlertStatusResolved {
+		b.WriteString("✅ *Resolved: " + Escape(a.Title) + "*\n")
+	} else {
+		emoji := levelEmoji[models.NormalizeLevel(a.Level)]
+		if emoji == "" {
+			emoji = "⚪"
+		}
+		b.WriteString(emoji + " *" + Escape(a.Title) + "*\n")
+	}
+	b.WriteString("_" + Escape(strings.ToUpper(a.Level)) + "_ · `" + escapeCode(a.Source) + "`\n")
+
+	// Leave room for the markup around the message
+	message := a.Message
+	if r := []rune(message); len(r) > maxMessage/2 {
+		message = string(r[:maxMessage/2]) + "…"
+	}
+	if message != "" {
+		b.WriteString("\n" + Escape(message))
+	}
+	if u := a.Labels["url"]; strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") {
+		b.WriteString("\n\n[Open](" + escapeLink(u) + ")")
+	}
+	return b.String()
+}
+
+// Escape escapes text for MarkdownV2, where every special character outside
+// entities must be backslashed
+func Escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune("_*[]()~`>#+-=|{}.!\\", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapeCode escapes the inside of a `code` entity
+func escapeCode(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(s)
+}
+
+// escapeLink escapes the URL part of an inline link
+func escapeLink(s string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(s)
+}
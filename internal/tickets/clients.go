@@ -0,0 +1,264 @@
+package tickets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"incident-viewer-go/internal/models"
+)
+
+// Client talks to one external ticketing system
+type Client interface {
+	CreateTicket(ctx context.Context, a models.Alert) (externalID, url string, err error)
+	CloseTicket(ctx context.Context, externalID string) error
+	AddComment(ctx context.Context, externalID, text string) error
+}
+
+// NewClient builds the client for a connector from its kind-specific config
+func NewClient(c models.TicketConnector, httpClient *http.Client) (Client, error) {
+	cfg := c.Config
+	switch c.Kind {
+	case models.TicketKindJira:
+		if cfg["base_url"] == "" || cfg["project_key"] == "" {
+			return nil, fmt.Errorf("jira connector %q needs base_url and project_key", c.Name)
+		}
+		return &jiraClient{http: httpClient, cfg: cfg}, nil
+	case models.TicketKindServiceNow:
+		if cfg["instance_url"] == "" {
+			return nil, fmt.Errorf("servicenow connector %q needs instance_url", c.Name)
+		}
+		return &serviceNowClient{http: httpClient, cfg: cfg}, nil
+	case models.TicketKindGitHub:
+		if cfg["repo"] == "" || cfg["token"] == "" {
+			return nil, fmt.Errorf("github connector %q needs repo and token", c.Name)
+		}
+		return &githubClient{http: httpClient, cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown ticket connector kind %q", c.Kind)
+	}
+}
+
+// doJSON sends body as JSON and decodes a JSON response into out (if non-nil)
+func doJSON(ctx context.Context, client *http.Client, method, url string, body, out any, setAuth func(*http.Request)) error {
+	var rdr io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rdr = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, rdr)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	setAuth(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func ticketDescription(a models.Alert) string {
+	return fmt.Sprintf("%s\n\nSource: %s\nLevel: %s\nSentinel alert #%d (%s)",
+		a.Message, a.Source, a.Level, a.ID, a.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+}
+
+// === Jira (REST API v2) ===
+// Config: base_url, email, api_token, project_key, issue_type (default Task),
+// done_transition (default Done)
+
+type jiraClient struct {
+	http *http.Client
+	cfg  map[string]string
+}
+
+func (c *jiraClient) url(path string) string {
+	return strings.TrimSuffix(c.cfg["base_url"], "/") + path
+}
+
+func (c *jiraClient) auth(req *http.Request) {
+	req.SetBasicAuth(c.cfg["email"], c.cfg["api_token"])
+}
+
+func (c *jiraClient) CreateTicket(ctx context.Context, a models.Alert) (string, string, error) {
+	issueType := c.cfg["issue_type"]
+	if issueType == "" {
+		issueType = "Task"
+	}
+	body := map[string]any{"fields": map[string]any{
+		"project":     map[string]string{"key": c.cfg["project_key"]},
+		"summary":     a.Title,
+		"description": ticketDescription(a),
+		"issuetype":   map[string]string{"name": issueType},
+	}}
+	var resp struct {
+		Key string `json:"key"`
+	}
+	if err := doJSON(ctx, c.http, http.MethodPost, c.url("/rest/api/2/issue"), body, &resp, c.auth); err != nil {
+		return "", "", err
+	}
+	return resp.Key, c.url("/browse/" + resp.Key), nil
+}
+
+func (c *jiraClient) CloseTicket(ctx context.Context, key string) error {
+	want := c.cfg["done_transition"]
+	if want == "" {
+		want = "Done"
+	}
+	var resp struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"transitions"`
+	}
+	if err := doJSON(ctx, c.http, http.MethodGet, c.url("/rest/api/2/issue/"+key+"/transitions"), nil, &resp, c.auth); err != nil {
+		return err
+	}
+	for _, t := range resp.Transitions {
+		if strings.EqualFold(t.Name, want) {
+			body := map[string]any{"transition": map[string]string{"id": t.ID}}
+			return doJSON(ctx, c.http, http.MethodPost, c.url("/rest/api/2/issue/"+key+"/transitions"), body, nil, c.auth)
+		}
+	}
+	return fmt.Errorf("jira issue %s has no %q transition", key, want)
+}
+
+func (c *jiraClient) AddComment(ctx context.Context, key, text string) error {
+	body := map[string]string{"body": text}
+	return doJSON(ctx, c.http, http.MethodPost, c.url("/rest/api/2/issue/"+key+"/comment"), body, nil, c.auth)
+}
+
+// === ServiceNow (Table API, incident table) ===
+// Config: instance_url, username, password, close_code (default "Solved (Permanently)")
+
+type serviceNowClient struct {
+	http *http.Client
+	cfg  map[string]string
+}
+
+func (c *serviceNowClient) url(path string) string {
+	return strings.TrimSuffix(c.cfg["instance_url"], "/") + path
+}
+
+func (c *serviceNowClient) auth(req *http.Request) {
+	req.SetBasicAuth(c.cfg["username"], c.cfg["password"])
+}
+
+// serviceNowUrgency maps alert levels to incident urgency/impact (1 = high)
+func serviceNowUrgency(level string) string {
+	switch rank := models.LevelRank(level); {
+	case rank >= 4:
+		return "1"
+	case rank == 3:
+		return "2"
+	default:
+		return "3"
+	}
+}
+
+func (c *serviceNowClient) CreateTicket(ctx context.Context, a models.Alert) (string, string, error) {
+	urgency := serviceNowUrgency(a.Level)
+	body := map[string]string{
+		"short_description": a.Title,
+		"description":       ticketDescription(a),
+		"urgency":           urgency,
+		"impact":            urgency,
+	}
+	var resp struct {
+		Result struct {
+			SysID string `json:"sys_id"`
+		} `json:"result"`
+	}
+	if err := doJSON(ctx, c.http, http.MethodPost, c.url("/api/now/table/incident"), body, &resp, c.auth); err != nil {
+		return "", "", err
+	}
+	return resp.Result.SysID, c.url("/nav_to.do?uri=incident.do?sys_id=" + resp.Result.SysID), nil
+}
+
+func (c *serviceNowClient) CloseTicket(ctx context.Context, sysID string) error {
+	closeCode := c.cfg["close_code"]
+	if closeCode == "" {
+		closeCode = "Solved (Permanently)"
+	}
+	body := map[string]string{
+		"state":       "6", // Resolved
+		"close_code":  closeCode,
+		"close_notes": "Alert resolved in Sentinel",
+	}
+	return doJSON(ctx, c.http, http.MethodPatch, c.url("/api/now/table/incident/"+sysID), body, nil, c.auth)
+}
+
+func (c *serviceNowClient) AddComment(ctx context.Context, sysID, text string) error {
+	body := map[string]string{"comments": text}
+	return doJSON(ctx, c.http, http.MethodPatch, c.url("/api/now/table/incident/"+sysID), body, nil, c.auth)
+}
+
+// === GitHub Issues ===
+// Config: repo (owner/name), token, labels (comma separated, default "sentinel")
+
+type githubClient struct {
+	http *http.Client
+	cfg  map[string]string
+}
+
+func (c *githubClient) url(path string) string {
+	base := c.cfg["api_url"]
+	if base == "" {
+		base = "https://api.github.com"
+	}
+	return strings.TrimSuffix(base, "/") + "/repos/" + c.cfg["repo"] + path
+}
+
+func (c *githubClient) auth(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+c.cfg["token"])
+	req.Header.Set("Accept", "application/vnd.github+json")
+}
+
+func (c *githubClient) CreateTicket(ctx context.Context, a models.Alert) (string, string, error) {
+	labels := []string{"sentinel"}
+	if v := c.cfg["labels"]; v != "" {
+		labels = strings.Split(v, ",")
+	}
+	body := map[string]any{
+		"title":  a.Title,
+		"body":   ticketDescription(a),
+		"labels": labels,
+	}
+	var resp struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := doJSON(ctx, c.http, http.MethodPost, c.url("/issues"), body, &resp, c.auth); err != nil {
+		return "", "", err
+	}
+	return strconv.Itoa(resp.Number), resp.HTMLURL, nil
+}
+
+func (c *githubClient) CloseTicket(ctx context.Context, number string) error {
+	body := map[string]string{"state": "closed", "state_reason": "completed"}
+	return doJSON(ctx, c.http, http.MethodPatch, c.url("/issues/"+number), body, nil, c.auth)
+}
+
+func (c *githubClient) AddComment(ctx context.Context, number, text string) error {
+	body := map[string]string{"body": text}
+	return doJSON(ctx, c.http, http.MethodPost, c.url("/issues/"+number+"/comments"), body, nil, c.auth)
+}
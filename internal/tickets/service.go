@@ -0,0 +1,288 @@
+// Package tickets opens tickets in Jira, ServiceNow and GitHub Issues for
+// new alerts and keeps resolution and comments in sync both ways.
+package tickets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/store"
+)
+
+// commentMarker prefixes comments Sentinel posts to trackers so they are not
+// imported again when the tracker echoes them back through its webhook
+const commentMarker = "[Sentinel]"
+
+// Ticket link states
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+type Service struct {
+	Alerts store.AlertStore
+	Admin  store.AdminStore
+	http   *http.Client
+}
+
+func NewService(alerts store.AlertStore, admin store.AdminStore) *Service {
+	return &Service{
+		Alerts: alerts,
+		Admin:  admin,
+		http:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Run consumes alert lifecycle events until ch is closed
+func (s *Service) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		s.HandleAlert(ctx, a)
+	}
+}
+
+// HandleAlert opens tickets for a new alert on every matching connector, or
+// closes the alert's open tickets once it is resolved
+func (s *Service) HandleAlert(ctx context.Context, a models.Alert) {
+	if a.Status == models.AlertStatusResolved {
+		s.closeTickets(ctx, a)
+		return
+	}
+
+	connectors, err := s.Admin.GetTicketConnectors(ctx)
+	if err != nil {
+		log.Printf("tickets: failed to load connectors: %v", err)
+		return
+	}
+	existing, _ := s.Admin.GetAlertTickets(ctx, a.ID)
+
+	for _, c := range connectors {
+		if !c.Enabled || !matches(c, a) || hasTicket(existing, c.ID) {
+			continue
+		}
+		client, err := NewClient(c, s.http)
+		if err != nil {
+			log.Printf("tickets: %v", err)
+			continue
+		}
+		externalID, url, err := client.CreateTicket(ctx, a)
+		if err != nil {
+			log.Printf("tickets: %s failed to open ticket for alert %d: %v", c.Name, a.ID, err)
+			continue
+		}
+		t := models.AlertTicket{AlertID: a.ID, ConnectorID: c.ID, ExternalID: externalID, URL: url, Status: StatusOpen}
+		if err := s.Admin.SaveAlertTicket(ctx, t); err != nil {
+			log.Printf("tickets: failed to save ticket %s for alert %d: %v", externalID, a.ID, err)
+		}
+	}
+}
+
+// matches reports whether a connector wants tickets for an alert
+func matches(c models.TicketConnector, a models.Alert) bool {
+	if c.ChatID != "" && models.ChatIDFromSource(a.Source) != c.ChatID {
+		return false
+	}
+	return models.LevelRank(a.Level) >= models.LevelRank(c.MinLevel)
+}
+
+func hasTicket(tickets []models.AlertTicket, connectorID int) bool {
+	for _, t := range tickets {
+		if t.ConnectorID == connectorID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) closeTickets(ctx context.Context, a models.Alert) {
+	s.eachOpenTicket(ctx, a.ID, func(c Client, t models.AlertTicket) {
+		// Mark closed first: a tracker webhook for this close must not resolve again
+		if err := s.Admin.UpdateAlertTicketStatus(ctx, t.AlertID, t.ConnectorID, StatusClosed); err != nil {
+			log.Printf("tickets: failed to update ticket %s: %v", t.ExternalID, err)
+			return
+		}
+		if err := c.CloseTicket(ctx, t.ExternalID); err != nil {
+			log.Printf("tickets: failed to close ticket %s: %v", t.ExternalID, err)
+		}
+	})
+}
+
+// SyncComment forwards a comment written in Sentinel to the alert's open tickets
+func (s *Service) SyncComment(ctx context.Context, alertID int, comment models.AlertComment) {
+	if comment.Origin != "" {
+		return // came from a tracker
+	}
+	text := fmt.Sprintf("%s %s: %s", commentMarker, comment.Author, comment.Text)
+	s.eachOpenTicket(ctx, alertID, func(c Client, t models.AlertTicket) {
+		if err := c.AddComment(ctx, t.ExternalID, text); err != nil {
+			log.Printf("tickets: failed to comment on ticket %s: %v", t.ExternalID, err)
+		}
+	})
+}
+
+func (s *Service) eachOpenTicket(ctx context.Context, alertID int, fn func(Client, models.AlertTicket)) {
+	tickets, err := s.Admin.GetAlertTickets(ctx, alertID)
+	if err != nil {
+		log.Printf("tickets: failed to load tickets for alert %d: %v", alertID, err)
+		return
+	}
+	for _, t := range tickets {
+		if t.Status != StatusOpen {
+			continue
+		}
+		c, err := s.Admin.GetTicketConnector(ctx, t.ConnectorID)
+		if err != nil || !c.Enabled {
+			continue
+		}
+		client, err := NewClient(c, s.http)
+		if err != nil {
+			log.Printf("tickets: %v", err)
+			continue
+		}
+		fn(client, t)
+	}
+}
+
+// Event is a tracker-side change relevant to Sentinel
+type Event struct {
+	ExternalID    string
+	Closed        bool
+	CommentAuthor string
+	CommentText   string
+}
+
+// ParseWebhook extracts the ticket change from a tracker's webhook payload.
+// A zero ExternalID means the event is irrelevant.
+func ParseWebhook(kind string, header http.Header, body []byte) (Event, error) {
+	var ev Event
+	switch kind {
+	case models.TicketKindJira:
+		var p struct {
+			WebhookEvent string `json:"webhookEvent"`
+			Issue        struct {
+				Key    string `json:"key"`
+				Fields struct {
+					Status struct {
+						StatusCategory struct {
+							Key string `json:"key"`
+						} `json:"statusCategory"`
+					} `json:"status"`
+				} `json:"fields"`
+			} `json:"issue"`
+			Comment *struct {
+				Body   string `json:"body"`
+				Author struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+			} `json:"comment"`
+		}
+		if err := json.Unmarshal(body, &p); err != nil {
+			return ev, err
+		}
+		ev.ExternalID = p.Issue.Key
+		switch p.WebhookEvent {
+		case "jira:issue_updated":
+			ev.Closed = p.Issue.Fields.Status.StatusCategory.Key == "done"
+		case "comment_created":
+			if p.Comment != nil {
+				ev.CommentAuthor, ev.CommentText = p.Comment.Author.DisplayName, p.Comment.Body
+			}
+		}
+
+	case models.TicketKindServiceNow:
+		// Sent by an outbound REST message / business rule on the incident table
+		var p struct {
+			SysID   string `json:"sys_id"`
+			State   string `json:"state"`
+			Comment string `json:"comment"`
+			Author  string `json:"author"`
+		}
+		if err := json.Unmarshal(body, &p); err != nil {
+			return ev, err
+		}
+		ev.ExternalID = p.SysID
+		switch strings.ToLower(p.State) {
+		case "6", "7", "resolved", "closed":
+			ev.Closed = true
+		}
+		ev.CommentAuthor, ev.CommentText = p.Author, p.Comment
+
+	case models.TicketKindGitHub:
+		var p struct {
+			Action string `json:"action"`
+			Issue  struct {
+				Number int `json:"number"`
+			} `json:"issue"`
+			Comment struct {
+				Body string `json:"body"`
+				User struct {
+					Login string `json:"login"`
+				} `json:"user"`
+			} `json:"comment"`
+		}
+		if err := json.Unmarshal(body, &p); err != nil {
+			return ev, err
+		}
+		if p.Issue.Number == 0 {
+			return ev, nil
+		}
+		ev.ExternalID = strconv.Itoa(p.Issue.Number)
+		switch header.Get("X-GitHub-Event") {
+		case "issues":
+			ev.Closed = p.Action == "closed"
+		case "issue_comment":
+			if p.Action == "created" {
+				ev.CommentAuthor, ev.CommentText = p.Comment.User.Login, p.Comment.Body
+			}
+		}
+
+	default:
+		return ev, fmt.Errorf("unknown ticket connector kind %q", kind)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(ev.CommentText), commentMarker) {
+		ev.CommentText = "" // our own comment echoed back
+	}
+	return ev, nil
+}
+
+// ApplyEvent mirrors a tracker change onto the linked alert
+func (s *Service) ApplyEvent(ctx context.Context, c models.TicketConnector, ev Event) error {
+	t, err := s.Admin.GetAlertTicketByExternalID(ctx, c.ID, ev.ExternalID)
+	if err != nil {
+		return err
+	}
+
+	if ev.CommentText != "" {
+		comment := models.AlertComment{Author: ev.CommentAuthor, Text: ev.CommentText, Origin: c.Kind}
+		if _, err := s.Alerts.AddComment(ctx, t.AlertID, comment); err != nil {
+			return err
+		}
+	}
+
+	if ev.Closed && t.Status == StatusOpen {
+		// Mark closed before resolving so the resolve event doesn't close it again
+		if err := s.Admin.UpdateAlertTicketStatus(ctx, t.AlertID, c.ID, StatusClosed); err != nil {
+			return err
+		}
+		if _, err := s.Alerts.ResolveAlertByID(ctx, t.AlertID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// COSE algorithms offered in CreationOptions
+const (
+	algES256 = -7
+	algEdDSA = -8
+	algRS256 = -257
+)
+
+// COSE key types and parameters
+const (
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+type publicKey struct {
+	alg int
+	key crypto.PublicKey
+}
+
+// parsePublicKey reads a COSE_Key for one of the offered algorithms
+func parsePublicKey(raw []byte) (publicKey, error) {
+	var m map[int]any
+	if err := cbor.Unmarshal(raw, &m); err != nil {
+		return publicKey{}, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	kty, _ := coseInt(m[1])
+	alg, _ := coseInt(m[3])
+
+	switch {
+	case kty == ktyEC2 && alg == algES256:
+		crv, _ := coseInt(m[-1])
+		x, _ := m[-2].([]byte)
+		y, _ := m[-3].([]byte)
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return publicKey{}, errors.New("unsupported EC2 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return publicKey{}, errors.New("EC2 key is not on the curve")
+		}
+		return publicKey{alg: alg, key: pub}, nil
+	case kty == ktyOKP && alg == algEdDSA:
+		crv, _ := coseInt(m[-1])
+		x, _ := m[-2].([]byte)
+		if crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return publicKey{}, errors.New("unsupported OKP key")
+		}
+		return publicKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case kty == ktyRSA && alg == algRS256:
+		n, _ := m[-1].([]byte)
+		e, _ := m[-2].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return publicKey{}, errors.New("unsupported RSA key")
+		}
+		return publicKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}}, nil
+	}
+	return publicKey{}, fmt.Errorf("unsupported key type %d with algorithm %d", kty, alg)
+}
+
+func (k publicKey) verify(signed, sig []byte) error {
+	ok := false
+	switch pub := k.key.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(signed)
+		ok = ecdsa.VerifyASN1(pub, sum[:], sig)
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, signed, sig)
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(signed)
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil
+	}
+	if !ok {
+		return errors.New("signature does not match the passkey")
+	}
+	return nil
+}
+
+// coseInt reads a CBOR integer, which decodes as int64 or uint64
+func coseInt(v any) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	}
+	return 0, false
+}
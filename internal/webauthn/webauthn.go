@@ -0,0 +1,267 @@
+// Package webauthn is the relying party side of WebAuthn for passkey sign-in:
+// it builds the options for navigator.credentials.create/get and verifies
+// what the browser returns. Attestation isn't requested, so any
+// authenticator is accepted; what counts is that later sign-ins are signed
+// by the key registered here.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04 // PIN or biometrics, on top of presence
+	flagAttested     = 0x40
+)
+
+// ChallengeTimeout is how long, in milliseconds, the browser waits for the
+// user; challenges are accepted for as long
+const ChallengeTimeout = 5 * 60 * 1000
+
+// RelyingParty identifies Sentinel to authenticators. Passkeys are bound to
+// ID, so changing the host invalidates them.
+type RelyingParty struct {
+	ID     string // The host name, e.g. sentinel.example.com
+	Name   string // Shown by the browser
+	Origin string // e.g. https://sentinel.example.com
+}
+
+// Credential is a registered public key
+type Credential struct {
+	ID        []byte
+	PublicKey []byte // COSE_Key
+	SignCount uint32
+}
+
+// FromEnv derives the relying party from the public URL; WEBAUTHN_RP_ID
+// overrides the ID, e.g. to share passkeys with a parent domain. Without a
+// public URL it returns nil: passkeys are off.
+func FromEnv(publicURL, name string) (*RelyingParty, error) {
+	if publicURL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("SENTINEL_PUBLIC_URL: want a URL like https://sentinel.example.com, got %q", publicURL)
+	}
+	rp := &RelyingParty{ID: u.Hostname(), Name: name, Origin: u.Scheme + "://" + u.Host}
+	if id := os.Getenv("WEBAUTHN_RP_ID"); id != "" {
+		if rp.ID != id && !strings.HasSuffix(rp.ID, "."+id) {
+			return nil, fmt.Errorf("WEBAUTHN_RP_ID %q must be %s or a parent domain of it", id, rp.ID)
+		}
+		rp.ID = id
+	}
+	return rp, nil
+}
+
+// NewChallenge returns a random challenge, base64url encoded as it goes into
+// the options
+func NewChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// CreationOptions are the options for registering a passkey, in the JSON form
+// PublicKeyCredential.parseCreationOptionsFromJSON takes. exclude lists the
+// user's passkeys, so an authenticator isn't registered twice.
+func (rp *RelyingParty) CreationOptions(challenge string, userID int, username string, exclude [][]byte) map[string]any {
+	excluded := []map[string]any{}
+	for _, id := range exclude {
+		excluded = append(excluded, map[string]any{"type": "public-key", "id": encode(id)})
+	}
+	return map[string]any{
+		"challenge": challenge,
+		"rp":        map[string]any{"id": rp.ID, "name": rp.Name},
+		"user": map[string]any{
+			"id":          encode(UserHandle(userID)),
+			"name":        username,
+			"displayName": username,
+		},
+		"pubKeyCredParams": []map[string]any{
+			{"type": "public-key", "alg": algES256},
+			{"type": "public-key", "alg": algEdDSA},
+			{"type": "public-key", "alg": algRS256},
+		},
+		"timeout":            ChallengeTimeout,
+		"attestation":        "none",
+		"excludeCredentials": excluded,
+		"authenticatorSelection": map[string]any{
+			"residentKey":      "preferred",
+			"userVerification": "preferred",
+		},
+	}
+}
+
+// RequestOptions are the options for signing in, in the JSON form
+// PublicKeyCredential.parseRequestOptionsFromJSON takes. With no allowed
+// credentials the browser offers every passkey it has for Sentinel.
+func (rp *RelyingParty) RequestOptions(challenge string, allow [][]byte) map[string]any {
+	allowed := []map[string]any{}
+	for _, id := range allow {
+		allowed = append(allowed, map[string]any{"type": "public-key", "id": encode(id)})
+	}
+	return map[string]any{
+		"challenge":        challenge,
+		"rpId":             rp.ID,
+		"timeout":          ChallengeTimeout,
+		"allowCredentials": allowed,
+		"userVerification": "preferred",
+	}
+}
+
+// UserHandle is the opaque user ID stored with a passkey
+func UserHandle(userID int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+// UserIDFromHandle reverses UserHandle
+func UserIDFromHandle(handle []byte) (int, bool) {
+	if len(handle) != 8 {
+		return 0, false
+	}
+	return int(binary.BigEndian.Uint64(handle)), true
+}
+
+// VerifyRegistration checks the response to CreationOptions and returns the
+// new credential
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (Credential, error) {
+	if err := rp.checkClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return Credential{}, err
+	}
+
+	var att struct {
+		Fmt      string `cbor:"fmt"`
+		AuthData []byte `cbor:"authData"`
+	}
+	if err := cbor.Unmarshal(attestationObject, &att); err != nil {
+		return Credential{}, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	ad, err := rp.parseAuthData(att.AuthData)
+	if err != nil {
+		return Credential{}, err
+	}
+	if ad.flags&flagAttested == 0 {
+		return Credential{}, errors.New("authenticator returned no credential")
+	}
+
+	rest := ad.rest
+	if len(rest) < 18 {
+		return Credential{}, errors.New("authenticator data is truncated")
+	}
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLen {
+		return Credential{}, errors.New("authenticator data is truncated")
+	}
+	cred := Credential{ID: rest[:idLen], SignCount: ad.signCount}
+	var key cbor.RawMessage
+	if _, err := cbor.UnmarshalFirst(rest[idLen:], &key); err != nil {
+		return Credential{}, fmt.Errorf("invalid credential public key: %w", err)
+	}
+	if _, err := parsePublicKey(key); err != nil {
+		return Credential{}, err
+	}
+	cred.PublicKey = key
+	return cred, nil
+}
+
+// Assertion is a verified sign-in
+type Assertion struct {
+	SignCount    uint32 // To store for the next sign-in
+	UserVerified bool   // The authenticator checked a PIN or biometrics
+}
+
+// VerifyAssertion checks a sign-in response against a registered credential
+func (rp *RelyingParty) VerifyAssertion(challenge string, cred Credential, clientDataJSON, authenticatorData, signature []byte) (Assertion, error) {
+	if err := rp.checkClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return Assertion{}, err
+	}
+	ad, err := rp.parseAuthData(authenticatorData)
+	if err != nil {
+		return Assertion{}, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return Assertion{}, err
+	}
+	clientHash := sha256.Sum256(clientDataJSON)
+	if err := key.verify(append(append([]byte{}, authenticatorData...), clientHash[:]...), signature); err != nil {
+		return Assertion{}, err
+	}
+
+	// A counter that doesn't move forward means the key was copied; counters
+	// that stay at 0 belong to authenticators that don't keep one
+	if (ad.signCount != 0 || cred.SignCount != 0) && ad.signCount <= cred.SignCount {
+		return Assertion{}, errors.New("signature counter went backwards; the passkey may have been cloned")
+	}
+	return Assertion{SignCount: ad.signCount, UserVerified: ad.flags&flagUserVerified != 0}, nil
+}
+
+func (rp *RelyingParty) checkClientData(raw []byte, typ, challenge string) error {
+	var cd struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return fmt.Errorf("invalid client data: %w", err)
+	}
+	if cd.Type != typ {
+		return fmt.Errorf("client data is %q, not %q", cd.Type, typ)
+	}
+	if challenge == "" || cd.Challenge != challenge {
+		return errors.New("challenge does not match; start again")
+	}
+	if cd.Origin != rp.Origin {
+		return fmt.Errorf("origin %s is not %s", cd.Origin, rp.Origin)
+	}
+	return nil
+}
+
+type authData struct {
+	flags     byte
+	signCount uint32
+	rest      []byte // Attested credential data and extensions
+}
+
+func (rp *RelyingParty) parseAuthData(b []byte) (authData, error) {
+	if len(b) < 37 {
+		return authData{}, errors.New("authenticator data is truncated")
+	}
+	rpHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(b[:32], rpHash[:]) {
+		return authData{}, errors.New("passkey is for another site")
+	}
+	ad := authData{flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37]), rest: b[37:]}
+	if ad.flags&flagUserPresent == 0 {
+		return authData{}, errors.New("user presence was not confirmed")
+	}
+	return ad, nil
+}
+
+// Decode reads a base64url value from the browser, padded or not
+func Decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
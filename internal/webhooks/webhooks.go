@@ -0,0 +1,408 @@
+// Package webhooks delivers alert events to admin-defined outgoing webhooks:
+// a JSON payload (the default one or a template) POSTed to the webhook URL,
+// HMAC-signed when the webhook has a secret, retried with exponential backoff,
+// with every attempt written to the delivery log.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"incident-viewer-go/internal/floodcontrol"
+	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/requestid"
+	"incident-viewer-go/internal/store"
+)
+
+const (
+	maxAttempts     = 5
+	maxBackoff      = time.Minute
+	maxResponseLog  = 1024 // Bytes of the response body kept in the delivery log
+	maxInFlight     = 16   // Concurrent deliveries, retries included
+	userAgent       = "Sentinel-Webhook/1"
+	signatureHeader = "X-Sentinel-Signature"
+)
+
+// Payload is what a webhook template renders, and the default body as is
+type Payload struct {
+	Event     string       `json:"event"`
+	Alert     models.Alert `json:"alert"`
+	URL       string       `json:"url,omitempty"` // Link to the alert when SENTINEL_PUBLIC_URL is set
+	Timestamp time.Time    `json:"timestamp"`
+	Sandbox   bool         `json:"sandbox,omitempty"` // Sent by an instance in sandbox mode
+}
+
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON, so templates can place strings safely:
+	// {"text": {{json .Alert.Title}}}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// ParseTemplate checks a webhook template: it must parse, and render valid
+// JSON for a sample alert
+func ParseTemplate(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	_, err := Render(text, Payload{
+		Event: models.WebhookEventCreated,
+		Alert: models.Alert{
+			ID:        1,
+			CreatedAt: time.Now().UTC(),
+			Source:    "sample",
+			Level:     models.LevelError,
+			Title:     `Sample "alert"`,
+			Message:   "Line one\nLine two",
+			Status:    models.AlertStatusOpen,
+			Labels:    map[string]string{"env": "prod"},
+		},
+		URL:       "https://sentinel.example.com/?alert=1",
+		Timestamp: time.Now().UTC(),
+	})
+	return err
+}
+
+// Render builds the request body for p: p itself as JSON, or the output of
+// text, which must be valid JSON
+func Render(text string, p Payload) ([]byte, error) {
+	if strings.TrimSpace(text) == "" {
+		return json.Marshal(p)
+	}
+	tmpl, err := template.New("webhook").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, errors.New("template output is not valid JSON")
+	}
+	return buf.Bytes(), nil
+}
+
+// Sign is the X-Sentinel-Signature for body: hex HMAC-SHA256 over
+// "<timestamp>.<delivery id>.<body>", the same scheme Sentinel checks on
+// signed inbound webhooks
+func Sign(secret, timestamp, deliveryID string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("." + deliveryID + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest sets the delivery headers on an outbound request: its
+// X-Sentinel-Delivery and X-Sentinel-Timestamp, and with a secret the
+// X-Sentinel-Signature over them and body. Other senders use it so every
+// destination can verify Sentinel's calls the same way.
+func SignRequest(req *http.Request, secret, deliveryID string, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Sentinel-Delivery", deliveryID)
+	req.Header.Set("X-Sentinel-Timestamp", timestamp)
+	if secret != "" {
+		req.Header.Set(signatureHeader, Sign(secret, timestamp, deliveryID, body))
+	}
+}
+
+type Dispatcher struct {
+	admin     store.AdminStore
+	publicURL string
+	http      *http.Client
+	slots     chan struct{}
+
+	mu   sync.Mutex
+	open map[int]bool // Lifecycle alerts announced as created and not yet resolved
+
+	sandboxed  bool
+	sandboxURL string // Where sandboxed deliveries go; empty drops them
+
+	throttle *floodcontrol.Limiter // nil delivers every event
+}
+
+// NewDispatcher sends events to the webhooks in admin; publicURL
+// (SENTINEL_PUBLIC_URL) adds a link to the alert when set
+func NewDispatcher(admin store.AdminStore, publicURL string) *Dispatcher {
+	return &Dispatcher{
+		admin:     admin,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		http:      &http.Client{Timeout: 10 * time.Second},
+		slots:     make(chan struct{}, maxInFlight),
+		open:      make(map[int]bool),
+	}
+}
+
+// Sandbox sends every delivery to url instead of the webhook's own, with
+// X-Sentinel-Sandbox naming the webhook; an empty url drops them
+func (d *Dispatcher) Sandbox(url string) {
+	d.sandboxed = true
+	d.sandboxURL = url
+}
+
+// Throttle holds back events about an alert whose check already notified
+// within l's window
+func (d *Dispatcher) Throttle(l *floodcontrol.Limiter) {
+	d.throttle = l
+}
+
+// Run delivers alert lifecycle events until ch is closed
+func (d *Dispatcher) Run(ctx context.Context, ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel != store.AlertEventsChannel {
+			continue
+		}
+		var a models.Alert
+		if err := json.Unmarshal([]byte(msg.Payload), &a); err != nil {
+			continue
+		}
+		d.Dispatch(ctx, d.event(a), a)
+	}
+}
+
+// event classifies a lifecycle message. Alerts without a lifecycle are only
+// ever published once, when created.
+func (d *Dispatcher) event(a models.Alert) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case a.Status == models.AlertStatusResolved:
+		delete(d.open, a.ID)
+		return models.WebhookEventResolved
+	case a.Status == "":
+		return models.WebhookEventCreated
+	case d.open[a.ID]:
+		return models.WebhookEventUpdated
+	}
+	d.open[a.ID] = true
+	return models.WebhookEventCreated
+}
+
+// Dispatch queues event for a on every matching webhook. Deliveries run in
+// the background; this only blocks while all delivery slots are busy.
+func (d *Dispatcher) Dispatch(ctx context.Context, event string, a models.Alert) {
+	hooks, err := d.admin.GetOutgoingWebhooks(ctx)
+	if err != nil {
+		log.Printf("webhooks: failed to load webhooks: %v", err)
+		return
+	}
+	p := Payload{Event: event, Alert: a, Timestamp: time.Now().UTC(), Sandbox: d.sandboxed}
+	if d.publicURL != "" {
+		p.URL = fmt.Sprintf("%s/?alert=%d", d.publicURL, a.ID)
+	}
+	calendars := store.CalendarsByID(ctx, d.admin)
+	allowed := false
+	for _, hook := range hooks {
+		if !hook.Matches(event, a) || !hook.AllowsAt(a, calendars, p.Timestamp) {
+			continue
+		}
+		if !allowed {
+			if !d.throttle.Allow(ctx, floodcontrol.ChannelWebhook, a) {
+				return
+			}
+			allowed = true
+		}
+		if d.sandboxed {
+			if d.sandboxURL == "" {
+				log.Printf("webhooks: sandbox: dropped %s for %s", event, hook.Name)
+				continue
+			}
+			hook.URL = d.sandboxURL
+		}
+		body, err := Render(hook.Template, p)
+		if err != nil {
+			log.Printf("webhooks: failed to render %s for alert %d: %v", hook.Name, a.ID, err)
+			continue
+		}
+		select {
+		case d.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		go func(hook models.OutgoingWebhook) {
+			defer func() { <-d.slots }()
+			d.deliver(requestid.NewContext(ctx, a.RequestID), hook, event, a.ID, body)
+		}(hook)
+	}
+}
+
+// Targets lists the webhooks by ID, for channel tests
+func (d *Dispatcher) Targets(ctx context.Context) ([]string, error) {
+	hooks, err := d.admin.GetOutgoingWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(hooks))
+	for _, hook := range hooks {
+		ids = append(ids, strconv.Itoa(hook.ID))
+	}
+	return ids, nil
+}
+
+// Test sends a as a "test" event to the webhook right away, whatever its
+// filters and even when disabled, once and without retries. The attempt goes
+// to the delivery log like any other.
+func (d *Dispatcher) Test(ctx context.Context, target string, a models.Alert) error {
+	id, err := strconv.Atoi(target)
+	if err != nil {
+		return fmt.Errorf("invalid webhook id %q", target)
+	}
+	hook, err := d.admin.GetOutgoingWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d.sandboxed {
+		if d.sandboxURL == "" {
+			return errors.New("sandbox mode drops webhook deliveries: set SANDBOX_WEBHOOK_URL")
+		}
+		hook.URL = d.sandboxURL
+	}
+	p := Payload{Event: models.WebhookEventTest, Alert: a, Timestamp: time.Now().UTC(), Sandbox: d.sandboxed}
+	body, err := Render(hook.Template, p)
+	if err != nil {
+		return err
+	}
+	deliveryID, err := NewDeliveryID()
+	if err != nil {
+		return err
+	}
+
+	res := d.attempt(ctx, hook, p.Event, deliveryID, body)
+	res.WebhookID, res.DeliveryID, res.AlertID, res.Event, res.Attempt = hook.ID, deliveryID, a.ID, p.Event, 1
+	if err := d.admin.RecordWebhookDelivery(ctx, res.WebhookDelivery); err != nil {
+		log.Printf("webhooks: failed to log delivery to %s: %v", hook.Name, err)
+	}
+	if res.Success {
+		return nil
+	}
+	if reply := strings.TrimSpace(res.Response); reply != "" {
+		return fmt.Errorf("%s: %s", res.Error, reply)
+	}
+	return errors.New(res.Error)
+}
+
+// deliver POSTs body until it is accepted, the receiver rejects it for good
+// (a 4xx other than 408/429) or maxAttempts run out
+func (d *Dispatcher) deliver(ctx context.Context, hook models.OutgoingWebhook, event string, alertID int, body []byte) {
+	deliveryID, err := NewDeliveryID()
+	if err != nil {
+		log.Printf("webhooks: %v", err)
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		res := d.attempt(ctx, hook, event, deliveryID, body)
+		res.WebhookID = hook.ID
+		res.DeliveryID = deliveryID
+		res.AlertID = alertID
+		res.Event = event
+		res.Attempt = attempt
+
+		recordCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := d.admin.RecordWebhookDelivery(recordCtx, res.WebhookDelivery); err != nil {
+			log.Printf("webhooks: failed to log delivery to %s: %v", hook.Name, err)
+		}
+		cancel()
+
+		if res.Success || !res.retry || attempt == maxAttempts {
+			if !res.Success {
+				log.Printf("webhooks: giving up on %s to %s after %d attempt(s): %s", event, hook.Name, attempt, res.Error)
+			}
+			return
+		}
+
+		wait := backoff
+		if res.retryAfter > wait {
+			wait = res.retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(min(wait, maxBackoff)):
+		}
+		backoff *= 2
+	}
+}
+
+type attemptResult struct {
+	models.WebhookDelivery
+	retry      bool
+	retryAfter time.Duration
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, hook models.OutgoingWebhook, event, deliveryID string, body []byte) attemptResult {
+	var res attemptResult
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("X-Sentinel-Event", event)
+	if d.sandboxed {
+		req.Header.Set("X-Sentinel-Sandbox", hook.Name)
+	}
+	SignRequest(req, hook.Secret, deliveryID, body)
+	requestid.Set(req, requestid.FromContext(ctx))
+
+	start := time.Now()
+	resp, err := d.http.Do(req)
+	res.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		res.Error = err.Error()
+		res.retry = true // Network trouble or timeout
+		return res
+	}
+	defer resp.Body.Close()
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseLog))
+
+	res.StatusCode = resp.StatusCode
+	res.Response = string(snippet)
+	switch {
+	case resp.StatusCode < 300:
+		res.Success = true
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode >= 500:
+		res.Error = resp.Status
+		res.retry = true
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			res.retryAfter = time.Duration(secs) * time.Second
+		}
+	default:
+		res.Error = resp.Status
+	}
+	return res
+}
+
+// NewDeliveryID is a random ID for one event sent to one destination, kept
+// across its retries so receivers can drop duplicates
+func NewDeliveryID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate delivery id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
-	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -26,9 +26,35 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 
+	"incident-viewer-go/internal/chaos"
+	"incident-viewer-go/internal/clientinfo"
+	"incident-viewer-go/internal/depcheck"
+	"incident-viewer-go/internal/deploys"
+	"incident-viewer-go/internal/doctor"
+	"incident-viewer-go/internal/email"
+	"incident-viewer-go/internal/federation"
+	"incident-viewer-go/internal/floodcontrol"
 	"incident-viewer-go/internal/handlers"
+	"incident-viewer-go/internal/k8swatch"
 	"incident-viewer-go/internal/models"
+	"incident-viewer-go/internal/msgtemplate"
+	"incident-viewer-go/internal/notifyqueue"
+	"incident-viewer-go/internal/opsgenie"
+	"incident-viewer-go/internal/pagerduty"
+	"incident-viewer-go/internal/requestid"
+	"incident-viewer-go/internal/saml"
+	"incident-viewer-go/internal/sandbox"
+	"incident-viewer-go/internal/secrets"
+	"incident-viewer-go/internal/shortlink"
+	"incident-viewer-go/internal/sms"
+	"incident-viewer-go/internal/stakeholders"
 	"incident-viewer-go/internal/store"
+	"incident-viewer-go/internal/syslog"
+	"incident-viewer-go/internal/teams"
+	"incident-viewer-go/internal/telegram"
+	"incident-viewer-go/internal/tickets"
+	"incident-viewer-go/internal/webauthn"
+	"incident-viewer-go/internal/webhooks"
 )
 
 var (
@@ -47,10 +73,25 @@ var (
 		},
 		[]string{"path", "method"},
 	)
+	ingestCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sentinel_ingest_requests_total",
+			Help: "Ingestion requests per integration and outcome (ok, rejected, error)",
+		},
+		[]string{"integration", "outcome"},
+	)
+	ingestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sentinel_ingest_duration_seconds",
+			Help:    "Ingestion request durations per integration",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"integration"},
+	)
 )
 
 func init() {
-	prometheus.MustRegister(reqCount, reqDuration)
+	prometheus.MustRegister(reqCount, reqDuration, ingestCount, ingestDuration)
 }
 
 type statusRecorder struct {
@@ -58,10 +99,6 @@ type statusRecorder struct {
 	status int
 }
 
-type contextKey string
-
-const traceKey contextKey = "trace_id"
-
 func (r *statusRecorder) WriteHeader(code int) {
 	r.status = code
 	r.ResponseWriter.WriteHeader(code)
@@ -74,15 +111,28 @@ func (r *statusRecorder) Flush() {
 	}
 }
 
-func tracingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		traceID := fmt.Sprintf("%x", rand.Int63())
-		ctx := context.WithValue(r.Context(), traceKey, traceID)
-		start := time.Now()
-		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(rec, r.WithContext(ctx))
-		log.Printf("[trace=%s] %s %s %d %s", traceID, r.Method, r.URL.Path, rec.status, time.Since(start))
-	})
+// tracingMiddleware takes the request's X-Request-ID (or makes one up),
+// returns it on the response and puts it in the context, from where it
+// reaches the audit trail, the alerts the request changes and their outbound
+// calls. The client's address, as allowlist sees it behind trusted proxies,
+// and user agent go in the context for the audit trail too.
+func tracingMiddleware(allowlist *handlers.IPAllowlist) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := requestid.FromRequest(r)
+			w.Header().Set(requestid.Header, id)
+			ctx := requestid.NewContext(r.Context(), id)
+			client := clientinfo.Info{UserAgent: r.UserAgent()}
+			if addr := allowlist.ClientIP(r); addr.IsValid() {
+				client.IP = addr.WithZone("").String()
+			}
+			ctx = clientinfo.NewContext(ctx, client)
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			log.Printf("[request=%s] %s %s %d %s", id, r.Method, r.URL.Path, rec.status, time.Since(start))
+		})
+	}
 }
 
 func metricsMiddleware(next http.Handler) http.Handler {
@@ -95,6 +145,89 @@ func metricsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// integrationMiddleware measures an ingestion endpoint under a stable name
+// (paths like /bot/{token}/... would make poor labels), for the Prometheus
+// metrics and the 24h integration health summary
+func integrationMiddleware(name string, stats store.AlertStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			r, credential := handlers.WithUsageSlot(r)
+			next.ServeHTTP(rec, r)
+			d := time.Since(start)
+
+			outcome := "ok"
+			switch {
+			case rec.status >= 500:
+				outcome = "error"
+			case rec.status >= 400:
+				outcome = "rejected"
+			}
+			ingestCount.WithLabelValues(name, outcome).Inc()
+			ingestDuration.WithLabelValues(name).Observe(d.Seconds())
+			go func() {
+				if err := stats.RecordIngest(context.Background(), name, rec.status, d); err != nil {
+					log.Printf("Failed to record ingest stats for %s: %v", name, err)
+				}
+				if c := credential(); c != "" {
+					if err := stats.RecordAPIUsage(context.Background(), c, rec.status); err != nil {
+						log.Printf("Failed to record API usage of %s: %v", c, err)
+					}
+				}
+			}()
+		})
+	}
+}
+
+// unsilenced passes on the alert events of ch except those about alerts under
+// a silence, so the notifiers reading it stay quiet about them
+func unsilenced(ch <-chan *redis.Message) <-chan *redis.Message {
+	out := make(chan *redis.Message)
+	go func() {
+		defer close(out)
+		for msg := range ch {
+			var a models.Alert
+			if json.Unmarshal([]byte(msg.Payload), &a) == nil && a.SilenceID != 0 {
+				continue
+			}
+			out <- msg
+		}
+	}()
+	return out
+}
+
+// databaseMiddleware answers requests that can't be served without the
+// database with a 503 while it is down, so admin pages and logins fail with a
+// clear message instead of a generic error. Viewing and ingesting alerts
+// only need Redis and the store's cached lookups, and keep working.
+func databaseMiddleware(db *store.PostgresStore) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !db.Available() && needsDatabase(r.URL.Path) {
+				http.Error(w, "Admin features are unavailable while the database is down; alerts can still be viewed and sent", http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// needsDatabase reports whether path is an admin, login, setup or account
+// endpoint; /api/user/me is answered from the cached session user
+func needsDatabase(path string) bool {
+	switch {
+	case path == "/admin/logout", path == "/api/user/me":
+		return false
+	case strings.HasPrefix(path, "/admin/"), strings.HasPrefix(path, "/api/admin/"),
+		strings.HasPrefix(path, "/api/user/"), strings.HasPrefix(path, "/api/setup"),
+		path == "/api/login", strings.HasPrefix(path, "/api/login/"), path == "/saml/acs",
+		strings.HasPrefix(path, "/api/password-reset/"), strings.HasPrefix(path, "/api/invitations/"):
+		return true
+	}
+	return false
+}
+
 func rateLimitMiddleware(rl *rateLimiter) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -108,6 +241,44 @@ func rateLimitMiddleware(rl *rateLimiter) func(http.Handler) http.Handler {
 	}
 }
 
+// maxLoginBodySize bounds the login body read to find the username
+const maxLoginBodySize = 64 << 10
+
+// loginThrottleMiddleware limits sign-in attempts per username, wherever they
+// come from, so rotating addresses doesn't help guessing one account's
+// password; on password reset requests it limits the emails. Usernames are
+// compared case-insensitively.
+func loginThrottleMiddleware(rl *rateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxLoginBodySize))
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, "Invalid request", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var req struct {
+				Username string `json:"username"`
+			}
+			_ = json.Unmarshal(body, &req)
+			if username := strings.ToLower(strings.TrimSpace(req.Username)); username != "" && !rl.allow(username) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Retry-After", strconv.Itoa(int(rl.refill.Seconds()/rl.rate)+1))
+				w.WriteHeader(http.StatusTooManyRequests)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Too many attempts for this account; try again later"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func idempotencyMiddleware(store *idempotencyStore) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -121,12 +292,30 @@ func idempotencyMiddleware(store *idempotencyStore) func(http.Handler) http.Hand
 	}
 }
 
-func hmacMiddleware(secret string) func(http.Handler) http.Handler {
+// staticSecret is a signing secret shared by every caller of an endpoint;
+// empty, it leaves the endpoint unsigned
+func staticSecret(secret string) func(*http.Request) string {
+	if secret == "" {
+		return nil
+	}
+	return func(*http.Request) string { return secret }
+}
+
+// hmacMiddleware enforces X-Sentinel-Signature for requests that secret
+// gives a secret for, so each integration can have its own. Requests that
+// also send X-Sentinel-Timestamp and X-Sentinel-Nonce sign
+// "<timestamp>.<nonce>.<body>" and go through the replay guard.
+func hmacMiddleware(secretFor func(*http.Request) string, replay *handlers.ReplayGuard) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
-		if secret == "" {
+		if secretFor == nil {
 			return next
 		}
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret := secretFor(r)
+			if secret == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
 			sig := r.Header.Get("X-Sentinel-Signature")
 			if sig == "" {
 				http.Error(w, "missing signature", http.StatusUnauthorized)
@@ -138,13 +327,25 @@ func hmacMiddleware(secret string) func(http.Handler) http.Handler {
 				return
 			}
 			r.Body = io.NopCloser(bytes.NewBuffer(body)) // restore for downstream
+			ts := r.Header.Get("X-Sentinel-Timestamp")
+			nonce := r.Header.Get("X-Sentinel-Nonce")
 			mac := hmac.New(sha256.New, []byte(secret))
-			mac.Write(body)
+			mac.Write(handlers.SignedContent(ts, nonce, body))
 			expected := hex.EncodeToString(mac.Sum(nil))
 			if !hmac.Equal([]byte(sig), []byte(expected)) {
 				http.Error(w, "invalid signature", http.StatusUnauthorized)
 				return
 			}
+			// Only after the signature checks out, so forged requests can't burn nonces
+			if err := replay.Check(r.Context(), ts, nonce); err != nil {
+				if errors.Is(err, handlers.ErrNonceUnchecked) {
+					log.Printf("Replay check failed: %v", err)
+					http.Error(w, "replay check unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
@@ -191,14 +392,37 @@ func (rl *rateLimiter) allow(key string) bool {
 
 	elapsed := now.Sub(bucket.last)
 	bucket.tokens = minFloat(rl.burst, bucket.tokens+rl.rate*elapsed.Seconds()/rl.refill.Seconds())
+	bucket.last = now
 	if bucket.tokens < 1 {
 		return false
 	}
 	bucket.tokens--
-	bucket.last = now
 	return true
 }
 
+// cleanupLoop drops the buckets that have refilled, so keys seen once, like
+// made-up usernames, don't pile up
+func (rl *rateLimiter) cleanupLoop(ctx context.Context) {
+	full := time.Duration(rl.burst / rl.rate * float64(rl.refill))
+	t := time.NewTicker(full)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			now := time.Now()
+			rl.mu.Lock()
+			for k, b := range rl.tokens {
+				if now.Sub(b.last) > full {
+					delete(rl.tokens, k)
+				}
+			}
+			rl.mu.Unlock()
+		}
+	}
+}
+
 func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
 	return &idempotencyStore{items: make(map[string]time.Time), ttl: ttl}
 }
@@ -244,6 +468,92 @@ func minFloat(a, b float64) float64 {
 	return b
 }
 
+// redisOptionsFromEnv reads REDIS_ADDR (default localhost:6379),
+// REDIS_PASSWORD and REDIS_DB
+func redisOptionsFromEnv() *redis.Options {
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		redisAddr = "localhost:6379"
+	}
+	redisDB := 0
+	if redisDBStr := os.Getenv("REDIS_DB"); redisDBStr != "" {
+		if db, err := strconv.Atoi(redisDBStr); err == nil {
+			redisDB = db
+		}
+	}
+	return &redis.Options{
+		Addr:     redisAddr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       redisDB,
+	}
+}
+
+// runDoctor prints the configuration checks and returns the exit code: 1
+// when any failed
+func runDoctor() int {
+	deps := doctor.Deps{Redis: store.NewRedisStore(redisOptionsFromEnv())}
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		db, err := store.OpenPostgresStore(databaseURL)
+		if err != nil {
+			fmt.Println("Invalid DATABASE_URL:", err)
+			return 1
+		}
+		deps.Database = db
+	}
+	if doctor.Print(os.Stdout, doctor.Run(context.Background(), deps)) {
+		return 1
+	}
+	return 0
+}
+
+// runReencryptSecrets seals the stored credentials again under the current
+// encryption key and returns the exit code: 1 when any couldn't be
+func runReencryptSecrets() int {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		fmt.Println("DATABASE_URL is required")
+		return 1
+	}
+	box, ok, err := secrets.FromEnv()
+	if err != nil {
+		fmt.Println("Invalid encryption key:", err)
+		return 1
+	}
+	if !ok {
+		fmt.Println("SENTINEL_ENCRYPTION_KEY is required")
+		return 1
+	}
+	db, err := store.OpenPostgresStore(databaseURL)
+	if err != nil {
+		fmt.Println("Invalid DATABASE_URL:", err)
+		return 1
+	}
+	db.UseEncryption(box)
+
+	ctx := context.Background()
+	if err := db.RunMigrations(ctx); err != nil {
+		fmt.Println("Failed to run migrations:", err)
+		return 1
+	}
+	results, err := db.ReencryptSecrets(ctx)
+	failed := err != nil
+	for _, r := range results {
+		fmt.Printf("%s.%s: %d re-encrypted", r.Table, r.Column, r.Reencrypted)
+		if r.Failed > 0 {
+			fmt.Printf(", %d can't be decrypted with the configured keys", r.Failed)
+			failed = true
+		}
+		fmt.Println()
+	}
+	if err != nil {
+		fmt.Println("Re-encryption stopped:", err)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
 func wrap(h http.Handler, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	for i := len(middlewares) - 1; i >= 0; i-- {
 		h = middlewares[i](h)
@@ -257,26 +567,38 @@ func main() {
 		log.Println("No .env file found, using defaults")
 	}
 
-	// Redis Configuration
-	redisAddr := os.Getenv("REDIS_ADDR")
-	if redisAddr == "" {
-		redisAddr = "localhost:6379"
+	// `sentinel doctor` checks the configuration and exits
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor())
 	}
-	redisPassword := os.Getenv("REDIS_PASSWORD")
-	redisDBStr := os.Getenv("REDIS_DB")
-	redisDB := 0
-	if redisDBStr != "" {
-		if db, err := strconv.Atoi(redisDBStr); err == nil {
-			redisDB = db
-		}
+	// `sentinel reencrypt-secrets` seals stored credentials under the current
+	// key, after setting one or rotating it, and exits
+	if len(os.Args) > 1 && os.Args[1] == "reencrypt-secrets" {
+		os.Exit(runReencryptSecrets())
 	}
 
 	// Initialize Redis store (for alerts)
-	redisStore := store.NewRedisStore(&redis.Options{
-		Addr:     redisAddr,
-		Password: redisPassword,
-		DB:       redisDB,
-	})
+	redisStore := store.NewRedisStore(redisOptionsFromEnv())
+	cardinality, err := store.CardinalityLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid alert cardinality limits: %v", err)
+	}
+	redisStore.SetCardinalityLimits(cardinality)
+	if repl, ok, err := store.ReplicationFromEnv(); err != nil {
+		log.Fatalf("Invalid Redis replication settings: %v", err)
+	} else if ok {
+		redisStore.EnableReplication(repl)
+		go redisStore.RunMirror(context.Background())
+		log.Printf("Mirroring alerts to %s as region %d of %d", repl.Secondary.Addr, repl.Region, repl.Regions)
+	}
+	// The index page's 24h rollup, built from the timeline when there is none
+	go func() {
+		if err := redisStore.EnsureAlertRollup(context.Background()); err != nil {
+			log.Printf("Failed to build the alert rollup: %v", err)
+		}
+	}()
+	// Sessions live in Redis too, so they can be ended server-side
+	handlers.UseSessions(redisStore.Sessions())
 
 	// PostgreSQL Configuration
 	databaseURL := os.Getenv("DATABASE_URL")
@@ -284,22 +606,51 @@ func main() {
 		log.Fatal("DATABASE_URL environment variable is required")
 	}
 
-	// Initialize Admin store (PostgreSQL)
-	adminStore, err := store.NewPostgresStore(databaseURL)
+	// Initialize Admin store (PostgreSQL). An unreachable database doesn't
+	// stop the start: alerts are viewed and ingested from Redis, and admin
+	// features come up once it is back.
+	adminStore, err := store.OpenPostgresStore(databaseURL)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	if box, ok, err := secrets.FromEnv(); err != nil {
+		log.Fatalf("Invalid encryption key: %v", err)
+	} else if ok {
+		adminStore.UseEncryption(box)
+	}
+	if replicaURL := os.Getenv("DATABASE_REPLICA_URL"); replicaURL != "" {
+		if err := adminStore.UseReadReplica(replicaURL); err != nil {
+			log.Printf("Read replica disabled, reading from primary: %v", err)
+		} else {
+			log.Println("Routing dashboard reads to the read replica")
+		}
+	}
 
 	// Run database migrations
 	ctx := context.Background()
-	if err := adminStore.RunMigrations(ctx); err != nil {
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	err = adminStore.Ping(pingCtx)
+	cancel()
+	if err != nil {
+		log.Printf("Database unreachable, starting with admin features disabled: %v", err)
+	} else if err := adminStore.RunMigrations(ctx); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
+	} else {
+		log.Println("Database migrations completed")
 	}
-	log.Println("Database migrations completed")
 
-	// Seed admin user
-	if err := seedAdmin(ctx, adminStore); err != nil {
-		log.Printf("Failed to seed admin user: %v", err)
+	// Alerts stored before sources were normalized move to the new form once
+	go func() {
+		if n, err := redisStore.MigrateSources(ctx); err != nil {
+			log.Printf("Source normalization stopped after %d alert(s): %v", n, err)
+		} else if n > 0 {
+			log.Printf("Normalized the source of %d stored alert(s)", n)
+		}
+	}()
+
+	// Extra level aliases for upstreams that report severities in other languages
+	if err := loadLevelAliases(); err != nil {
+		log.Fatalf("Failed to load level aliases: %v", err)
 	}
 
 	// Parse templates
@@ -314,6 +665,7 @@ func main() {
 	adminTemplates := map[string]string{
 		"login":     filepath.Join("web", "templates", "admin", "login.html"),
 		"dashboard": filepath.Join("web", "templates", "admin", "dashboard.html"),
+		"setup":     filepath.Join("web", "templates", "admin", "setup.html"),
 	}
 	for name, path := range adminTemplates {
 		t, err := template.ParseFiles(path)
@@ -326,37 +678,175 @@ func main() {
 
 	// Initialize handlers with both stores
 	h := handlers.NewHandler(redisStore, adminStore, tmpl, adminTmpl)
+	h.Tickets = tickets.NewService(redisStore, adminStore)
+	if cfg, ok := email.ConfigFromEnv(); ok {
+		h.Email = email.NewSender(cfg)
+	}
+	if provider, ok, err := sms.ProviderFromEnv(); err != nil {
+		log.Printf("SMS paging disabled: %v", err)
+	} else if ok {
+		h.SMS = provider
+	}
+
+	// When open alerts count as aging, for the aging report and daily nudges
+	h.Aging, err = handlers.AgingThresholdsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	// Initialize default admin user
-	h.InitSession(ctx)
+	// Sandbox mode for staging: notifications go to test destinations only
+	sandboxCfg, sandboxed, err := sandbox.FromEnv()
+	if err != nil {
+		log.Fatalf("Invalid sandbox config: %v", err)
+	}
+	if sandboxed {
+		h.Sandbox = true
+		if h.Email != nil {
+			h.Email.Sandbox(sandboxCfg.Email)
+		}
+		if h.SMS != nil {
+			h.SMS = sms.Sandboxed(h.SMS, sandboxCfg.Phone)
+		}
+		log.Printf("Sandbox mode: %s; PagerDuty, Opsgenie, federation and tickets are off", sandboxCfg.Describe())
+	}
+
+	// Push, SMS, email, Teams, Telegram and stakeholder Slack notifications
+	// go through a queue that retries failed sends; started once every
+	// sender has registered, below
+	notifyQueue := notifyqueue.New(redisStore)
+	notifyQueue.History(adminStore)
+	h.Queue(notifyQueue)
+	if h.Email != nil {
+		h.Email.Queue(notifyQueue)
+	}
+
+	// At most one notification per channel and alert fingerprint per window
+	throttle, err := floodcontrol.FromEnv(redisStore)
+	if err != nil {
+		log.Fatalf("Invalid flood control settings: %v", err)
+	}
+
+	// Admin-defined message templates for push, email, Slack and SMS
+	h.Templates = msgtemplate.NewRenderer(adminStore, os.Getenv("SENTINEL_PUBLIC_URL"))
+	h.Brand = handlers.BrandingFromEnv()
+	if h.JWT, err = handlers.JWTFromEnv(); err != nil {
+		log.Fatalf("Invalid JWT settings: %v", err)
+	}
+	if h.Lockout, err = handlers.LoginLockoutFromEnv(); err != nil {
+		log.Fatalf("Invalid login lockout settings: %v", err)
+	}
+	if h.Passwords, err = handlers.PasswordPolicyFromEnv(); err != nil {
+		log.Fatalf("Invalid password policy: %v", err)
+	}
+	if h.PasswordReset, err = handlers.PasswordResetFromEnv(os.Getenv("SENTINEL_PUBLIC_URL")); err != nil {
+		log.Fatalf("Invalid password reset settings: %v", err)
+	}
+	if h.PasswordReset != nil && h.Email == nil {
+		log.Println("Password reset is off: it emails links, so it needs SMTP_HOST")
+	}
+	if h.Invitations, err = handlers.InvitationsFromEnv(os.Getenv("SENTINEL_PUBLIC_URL")); err != nil {
+		log.Fatalf("Invalid invitation settings: %v", err)
+	}
+	if h.Allowlist, err = handlers.IPAllowlistFromEnv(); err != nil {
+		log.Fatalf("Invalid IP allowlist settings: %v", err)
+	}
+	if h.SAML, err = saml.FromEnv(os.Getenv("SENTINEL_PUBLIC_URL")); err != nil {
+		log.Fatalf("Invalid SAML settings: %v", err)
+	}
+	rpName := h.Brand.Name
+	if rpName == "" {
+		rpName = "Sentinel"
+	}
+	if h.WebAuthn, err = webauthn.FromEnv(os.Getenv("SENTINEL_PUBLIC_URL"), rpName); err != nil {
+		log.Fatalf("Invalid passkey settings: %v", err)
+	}
+	h.Channels = map[string]handlers.ChannelTester{}
+
+	// Fault injection for staging; faults stay off until set via /api/admin/chaos
+	if os.Getenv("CHAOS_ENABLED") == "true" {
+		h.Chaos = chaos.NewInjector()
+		redisStore.AddHook(h.Chaos.RedisHook())
+		log.Println("Fault injection enabled: do not run this in production")
+	}
+
+	// Initial admin from the environment, or the first-run setup wizard; after
+	// a start without the database, once it is reachable
+	if adminStore.Available() {
+		h.InitAdmin(ctx)
+	}
+	go adminStore.MonitorHealth(ctx, h.InitAdmin)
 
 	// Observability helpers
 	rl := newRateLimiter(60, 30, time.Second)
+	// 10 attempts, then 5 a minute, per username
+	loginRL := newRateLimiter(5, 10, time.Minute)
+	go loginRL.cleanupLoop(ctx)
+	// 3 reset emails, then one every 20 minutes, per username
+	resetRL := newRateLimiter(1, 3, 20*time.Minute)
+	go resetRL.cleanupLoop(ctx)
 	idStore := newIdempotencyStore(10 * time.Minute)
 	go idStore.cleanupLoop(ctx)
-	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+	h.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	replay, err := handlers.ReplayGuardFromEnv(redisStore)
+	if err != nil {
+		log.Fatalf("Invalid replay protection config: %v", err)
+	}
+	// signed checks the endpoint's requests against WEBHOOK_SECRET, unless an
+	// admin exempted it
+	signed := func(name string) func(http.Handler) http.Handler { return hmacMiddleware(h.SharedSecret(name), replay) }
 
 	mux := http.NewServeMux()
 
 	// Public routes
 	mux.HandleFunc("/", h.IndexHandler)
-	mux.Handle("/webhook", wrap(http.HandlerFunc(h.WebhookHandler), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), hmacMiddleware(webhookSecret)))
-	mux.Handle("/telegram/", wrap(http.HandlerFunc(h.TelegramHandler), rateLimitMiddleware(rl)))
+	// ingest names an integration endpoint in the ingestion metrics and applies
+	// its IP allowlist; rejected addresses count as rejected requests
+	ingest := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return integrationMiddleware(name, redisStore)(h.Allowlist.Middleware(name)(next))
+		}
+	}
+	mux.Handle("/webhook", wrap(http.HandlerFunc(h.WebhookHandler), ingest("webhook"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), h.IngestTokenMiddleware(signed("webhook"))))
+	mux.Handle("/api/heartbeat/", wrap(http.HandlerFunc(h.HeartbeatCheckInHandler), ingest("heartbeat"), rateLimitMiddleware(rl)))
+	mux.Handle("/api/deploys", wrap(http.HandlerFunc(h.DeploysHandler), ingest("deploys"), rateLimitMiddleware(rl), h.IngestTokenMiddleware(signed("deploys"))))
+	mux.Handle("/api/metrics", wrap(http.HandlerFunc(h.MetricsHandler), ingest("metrics"), rateLimitMiddleware(rl), h.IngestTokenMiddleware(signed("metrics"))))
+	mux.Handle("/telegram/", wrap(http.HandlerFunc(h.TelegramHandler), ingest("telegram"), rateLimitMiddleware(rl)))
+	mux.Handle(shortlink.PathPrefix, wrap(http.HandlerFunc(h.ShortLinkHandler), rateLimitMiddleware(rl)))
 	mux.Handle("/clear", http.HandlerFunc(h.ClearHandler))
 	mux.Handle("/events", http.HandlerFunc(h.SSEHandler))
-	mux.Handle("/api/login", http.HandlerFunc(h.PublicLoginHandler))
+	mux.Handle("/api/login", wrap(http.HandlerFunc(h.PublicLoginHandler), rateLimitMiddleware(rl), loginThrottleMiddleware(loginRL)))
 	mux.Handle("/api/login/verify-2fa", http.HandlerFunc(h.Verify2FALoginHandler))
+	mux.Handle("/api/login/change-password", wrap(http.HandlerFunc(h.ExpiredPasswordHandler), rateLimitMiddleware(rl)))
+	mux.Handle("/api/password-reset/request", wrap(http.HandlerFunc(h.RequestPasswordResetHandler), rateLimitMiddleware(rl), loginThrottleMiddleware(resetRL)))
+	mux.Handle("/api/password-reset/confirm", wrap(http.HandlerFunc(h.ConfirmPasswordResetHandler), rateLimitMiddleware(rl)))
+	mux.Handle("/api/login/passkey/begin", wrap(http.HandlerFunc(h.BeginPasskeyLoginHandler), rateLimitMiddleware(rl)))
+	mux.Handle("/api/login/passkey/finish", wrap(http.HandlerFunc(h.FinishPasskeyLoginHandler), rateLimitMiddleware(rl)))
+	mux.Handle("/api/token/refresh", wrap(http.HandlerFunc(h.RefreshTokenHandler), rateLimitMiddleware(rl)))
+	mux.HandleFunc("/saml/metadata", h.SAMLMetadataHandler)
+	mux.HandleFunc("/saml/login", h.SAMLLoginHandler)
+	mux.Handle("/saml/acs", wrap(http.HandlerFunc(h.SAMLACSHandler), rateLimitMiddleware(rl)))
 	mux.Handle("/api/search", http.HandlerFunc(h.SearchHandler))
 	mux.Handle("/api/chats", http.HandlerFunc(h.GetChatsPublicHandler))
+	mux.Handle("/api/chats/", handlers.AuthMiddleware(http.HandlerFunc(h.ChatStatsHandler)))
+	mux.Handle("/api/bootstrap", http.HandlerFunc(h.BootstrapHandler))
+	mux.Handle("/api/summary/standup", handlers.AuthMiddleware(http.HandlerFunc(h.StandupSummaryHandler)))
+	mux.Handle("/api/summary/aging", handlers.AuthMiddleware(http.HandlerFunc(h.AlertAgingHandler)))
+	// Ingest tokens let automation annotate alerts; everything else needs a session
+	mux.Handle("/api/alerts/", wrap(http.HandlerFunc(h.AlertActionsHandler), h.IngestTokenMiddleware(func(next http.Handler) http.Handler {
+		return handlers.AuthMiddleware(next.ServeHTTP)
+	})))
 
 	// Admin routes (login/logout)
-	mux.HandleFunc("/admin/login", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/admin/login", wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			h.AdminLoginPage(w, r)
 		} else {
 			h.LoginHandler(w, r)
 		}
-	})
+	}), rateLimitMiddleware(rl), loginThrottleMiddleware(loginRL)))
+	mux.HandleFunc("/admin/setup", h.SetupPage)
+	mux.Handle("/api/setup", wrap(http.HandlerFunc(h.SetupHandler), rateLimitMiddleware(rl)))
+	mux.HandleFunc("/api/setup/status", h.SetupStatusHandler)
 	mux.HandleFunc("/admin/verify-2fa", h.VerifyAdmin2FAHandler)
 	mux.HandleFunc("/admin/logout", h.LogoutHandler)
 	mux.Handle("/admin/dashboard", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.AdminDashboardPage))))
@@ -373,65 +863,562 @@ func main() {
 		}
 	}))))
 	mux.Handle("/api/admin/users/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/lockout"):
+			h.UnlockUserHandler(w, r)
+		case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/sessions"):
+			h.RevokeUserSessionsHandler(w, r)
+		case (r.Method == http.MethodPut || r.Method == http.MethodDelete) && strings.HasSuffix(r.URL.Path, "/saml"):
+			h.SAMLLinkHandler(w, r)
+		case r.Method == http.MethodPut:
+			h.UpdateUserHandler(w, r)
+		case r.Method == http.MethodDelete:
+			h.DeleteUserHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/invitations", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetInvitationsHandler(w, r)
+		case http.MethodPost:
+			h.CreateInvitationHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/invitations/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.DeleteInvitationHandler(w, r)
+	}))))
+	mux.Handle("/api/admin/teams", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetTeamsHandler(w, r)
+		case http.MethodPost:
+			h.CreateTeamHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/teams/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodPut:
-			h.UpdateUserHandler(w, r)
+			h.UpdateTeamHandler(w, r)
 		case http.MethodDelete:
-			h.DeleteUserHandler(w, r)
+			h.DeleteTeamHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))))
-
-	// Bot management
-	mux.Handle("/api/admin/bots", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/admin/organizations", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			h.GetBotsHandler(w, r)
+			h.GetOrganizationsHandler(w, r)
 		case http.MethodPost:
-			h.CreateBotHandler(w, r)
+			h.CreateOrganizationHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))))
-	mux.Handle("/api/admin/bots/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			h.DeleteBotHandler(w, r)
-		} else {
+	mux.Handle("/api/admin/organizations/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
+		h.DeleteOrganizationHandler(w, r)
 	}))))
+	mux.Handle("/api/invitations/accept", wrap(http.HandlerFunc(h.AcceptInvitationHandler), rateLimitMiddleware(rl)))
 
-	// Chat management
-	mux.Handle("/api/admin/chats", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// On-call schedules
+	mux.Handle("/api/oncall/now", handlers.AuthMiddleware(h.FeatureMiddleware(models.FeatureOnCall, h.OnCallNowHandler)))
+	mux.Handle("/api/oncall/handover", handlers.AuthMiddleware(h.FeatureMiddleware(models.FeatureOnCall, h.HandoverReportHandler)))
+	mux.Handle("/api/admin/schedules", handlers.AuthMiddleware(h.InstanceAdminMiddleware(h.FeatureMiddleware(models.FeatureOnCall, func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			h.GetChatsHandler(w, r)
+			h.GetSchedulesHandler(w, r)
 		case http.MethodPost:
-			h.CreateChatHandler(w, r)
+			h.CreateScheduleHandler(w, r)
 		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))))
-	mux.Handle("/api/admin/chats/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
-			h.DeleteChatHandler(w, r)
-		} else {
+	mux.Handle("/api/admin/schedules/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(h.FeatureMiddleware(models.FeatureOnCall, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/overrides"):
+			h.CreateScheduleOverrideHandler(w, r)
+		case r.Method == http.MethodDelete && strings.Contains(r.URL.Path, "/overrides/"):
+			h.DeleteScheduleOverrideHandler(w, r)
+		case r.Method == http.MethodPut:
+			h.UpdateScheduleHandler(w, r)
+		case r.Method == http.MethodDelete:
+			h.DeleteScheduleHandler(w, r)
+		default:
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	}))))
-	mux.Handle("/api/admin/purge", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.PurgeAlertsHandler))))
-
-	// User management routes
-	mux.Handle("/api/user/profile", http.HandlerFunc(h.UpdateProfileHandler))
-	mux.Handle("/api/user/change-password", http.HandlerFunc(h.ChangePasswordHandler))
-	mux.Handle("/api/user/me", http.HandlerFunc(h.GetCurrentUserHandler))
 
-	// Admin user management
-	mux.Handle("/api/admin/reset-password", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.AdminResetPasswordHandler))))
-	mux.Handle("/api/admin/audit", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.GetAuditLogs))))
+	// Business-hours calendars routing rules branch on
+	mux.Handle("/api/admin/business-calendars", handlers.AuthMiddleware(h.InstanceAdminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetBusinessCalendarsHandler(w, r)
+		case http.MethodPost:
+			h.CreateBusinessCalendarHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/admin/business-calendars/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateBusinessCalendarHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteBusinessCalendarHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
 
-	// Serve sw.js at root for Service Worker scope
+	// Notification queue
+	mux.Handle("/api/admin/notifications", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.NotificationQueueHandler))))
+	mux.Handle("/api/admin/notifications/dead-letters", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.ClearDeadLettersHandler))))
+	mux.Handle("/api/admin/notifications/dead-letters/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasSuffix(r.URL.Path, "/retry") {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.RetryDeadLetterHandler(w, r)
+	}))))
+
+	// Feature flags
+	mux.Handle("/api/admin/features", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.GetFeatureFlagsHandler))))
+	mux.Handle("/api/admin/features/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.SaveFeatureFlagHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteFeatureFlagHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+
+	// Endpoints exempt from WEBHOOK_SECRET signatures
+	mux.Handle("/api/admin/signature-exemptions", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetSignatureExemptionsHandler(w, r)
+		case http.MethodPost:
+			h.SaveSignatureExemptionHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/signature-exemptions/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			h.DeleteSignatureExemptionHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+
+	// Bot management
+	mux.Handle("/api/admin/bots", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetBotsHandler(w, r)
+		case http.MethodPost:
+			h.CreateBotHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/bots/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/secret") {
+			h.BotSigningSecretHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/allowed-ips") {
+			h.BotAllowedIPsHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/chats") {
+			h.BotChatsHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/rotate") && r.Method == http.MethodPost {
+			h.RotateBotTokenHandler(w, r)
+		} else if r.Method == http.MethodDelete {
+			h.DeleteBotHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+
+	// Chat management
+	mux.Handle("/api/admin/chats", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetChatsHandler(w, r)
+		case http.MethodPost:
+			h.CreateChatHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/chats/", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			h.DeleteChatHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+
+	// Ticket connectors (Jira, ServiceNow, GitHub Issues)
+	mux.Handle("/api/admin/tickets/connectors", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetTicketConnectorsHandler(w, r)
+		case http.MethodPost:
+			h.CreateTicketConnectorHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/tickets/connectors/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			h.DeleteTicketConnectorHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/ingest-tokens", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetIngestTokensHandler(w, r)
+		case http.MethodPost:
+			h.CreateIngestTokenHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/ingest-tokens/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete {
+			h.RevokeIngestTokenHandler(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/webhook-mappings", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetWebhookMappingsHandler(w, r)
+		case http.MethodPost:
+			h.CreateWebhookMappingHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/webhook-mappings/preview", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.PreviewWebhookMappingHandler))))
+	mux.Handle("/api/admin/webhook-mappings/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateWebhookMappingHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteWebhookMappingHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/metric-rules", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetMetricRulesHandler(w, r)
+		case http.MethodPost:
+			h.CreateMetricRuleHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/metric-rules/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateMetricRuleHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteMetricRuleHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/heartbeats", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetHeartbeatsHandler(w, r)
+		case http.MethodPost:
+			h.CreateHeartbeatHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/heartbeats/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateHeartbeatHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteHeartbeatHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/stakeholder-lists", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetStakeholderListsHandler(w, r)
+		case http.MethodPost:
+			h.CreateStakeholderListHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/stakeholder-lists/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateStakeholderListHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteStakeholderListHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/mute-rules", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetMuteRulesHandler(w, r)
+		case http.MethodPost:
+			h.CreateMuteRuleHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/mute-rules/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateMuteRuleHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteMuteRuleHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/silences", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetSilencesHandler(w, r)
+		case http.MethodPost:
+			h.CreateSilenceHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/silences/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateSilenceHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteSilenceHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/payload-schemas", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetPayloadSchemasHandler(w, r)
+		case http.MethodPost:
+			h.CreatePayloadSchemaHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/payload-schemas/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdatePayloadSchemaHandler(w, r)
+		case http.MethodDelete:
+			h.DeletePayloadSchemaHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/email-routes", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetEmailRoutesHandler(w, r)
+		case http.MethodPost:
+			h.CreateEmailRouteHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/email-routes/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateEmailRouteHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteEmailRouteHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/outgoing-webhooks", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetOutgoingWebhooksHandler(w, r)
+		case http.MethodPost:
+			h.CreateOutgoingWebhookHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/outgoing-webhooks/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/deliveries"):
+			h.GetWebhookDeliveriesHandler(w, r)
+		case r.Method == http.MethodPut:
+			h.UpdateOutgoingWebhookHandler(w, r)
+		case r.Method == http.MethodDelete:
+			h.DeleteOutgoingWebhookHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/notification-templates", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetNotificationTemplatesHandler(w, r)
+		case http.MethodPut:
+			h.SaveNotificationTemplateHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/notification-templates/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/admin/notification-templates/preview":
+			h.PreviewNotificationTemplateHandler(w, r)
+		case r.Method == http.MethodDelete:
+			h.DeleteNotificationTemplateHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/dependency-checks", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetDependencyChecksHandler(w, r)
+		case http.MethodPost:
+			h.CreateDependencyCheckHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/dependency-checks/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateDependencyCheckHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteDependencyCheckHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/opsgenie-rules", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetOpsgenieRulesHandler(w, r)
+		case http.MethodPost:
+			h.CreateOpsgenieRuleHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/opsgenie-rules/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			h.UpdateOpsgenieRuleHandler(w, r)
+		case http.MethodDelete:
+			h.DeleteOpsgenieRuleHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	}))))
+	mux.Handle("/api/admin/email/test", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.EmailTestHandler))))
+	mux.Handle("/api/admin/channels", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.GetChannelsHandler))))
+	mux.Handle("/api/admin/channels/", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.ChannelTestHandler))))
+	mux.Handle("/api/admin/integrations/health", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.IntegrationHealthHandler))))
+	mux.Handle("/api/admin/usage", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.APIUsageHandler))))
+	mux.Handle("/api/admin/chaos", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.ChaosHandler))))
+	mux.Handle("/api/admin/purge", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.PurgeAlertsHandler))))
+	mux.Handle("/api/admin/sources/normalize", handlers.AuthMiddleware(h.InstanceAdminMiddleware(http.HandlerFunc(h.NormalizeSourcesHandler))))
+
+	// User management routes
+	mux.Handle("/api/user/profile", http.HandlerFunc(h.UpdateProfileHandler))
+	mux.Handle("/api/user/change-password", http.HandlerFunc(h.ChangePasswordHandler))
+	mux.Handle("/api/user/me", http.HandlerFunc(h.GetCurrentUserHandler))
+	mux.Handle("/api/user/sms", http.HandlerFunc(h.SMSPreferenceHandler))
+	mux.Handle("/api/user/email", http.HandlerFunc(h.UserEmailHandler))
+	mux.Handle("/api/user/sms/test", http.HandlerFunc(h.SMSTestHandler))
+	mux.Handle("/api/user/usage", http.HandlerFunc(h.UserAPIUsageHandler))
+	mux.Handle("/api/user/apikeys", handlers.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.GetAPIKeysHandler(w, r)
+		case http.MethodPost:
+			h.CreateAPIKeyHandler(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.Handle("/api/user/passkeys", handlers.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.GetPasskeysHandler(w, r)
+	})))
+	mux.Handle("/api/user/passkeys/register/begin", handlers.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.BeginPasskeyRegistrationHandler(w, r)
+	})))
+	mux.Handle("/api/user/passkeys/register/finish", handlers.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.FinishPasskeyRegistrationHandler(w, r)
+	})))
+	mux.Handle("/api/user/passkeys/", handlers.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.DeletePasskeyHandler(w, r)
+	})))
+	mux.Handle("/api/user/apikeys/", handlers.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h.DeleteAPIKeyHandler(w, r)
+	})))
+
+	// Admin user management
+	mux.Handle("/api/admin/reset-password", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.AdminResetPasswordHandler))))
+	mux.Handle("/api/admin/audit", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.GetAuditLogs))))
+
+	// Serve sw.js at root for Service Worker scope
 	mux.HandleFunc("/sw.js", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/javascript")
 		http.ServeFile(w, r, "web/static/sw.js")
@@ -444,16 +1431,35 @@ func main() {
 	mux.Handle("/api/admin/disable-2fa", handlers.AuthMiddleware(handlers.AdminMiddleware(http.HandlerFunc(h.AdminDisable2FAHandler))))
 
 	// Bot webhook (public)
-	// NOTE: HMAC middleware removed for internal Gatus webhook usage
-	mux.Handle("/bot/", wrap(http.HandlerFunc(h.BotWebhookHandler), rateLimitMiddleware(rl), idempotencyMiddleware(idStore)))
+	// Signed with the bot's own signing secret; bots without one stay unsigned
+	mux.Handle("/bot/", wrap(http.HandlerFunc(h.BotWebhookHandler), ingest("bot"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), hmacMiddleware(h.BotSigningSecret, replay)))
 
 	// Push Notification routes
 	mux.Handle("/api/push/vapid-public-key", http.HandlerFunc(h.GetVAPIDKeyHandler))
 	mux.Handle("/api/push/subscribe", http.HandlerFunc(h.SubscribePushHandler))
 
 	// New Webhook Integrations
-	mux.Handle("/api/slack/webhook", wrap(http.HandlerFunc(h.SlackWebhookHandler), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), hmacMiddleware(webhookSecret)))
-	mux.Handle("/api/discord/webhook", wrap(http.HandlerFunc(h.DiscordWebhookHandler), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), hmacMiddleware(webhookSecret)))
+	mux.Handle("/api/slack/webhook", wrap(http.HandlerFunc(h.SlackWebhookHandler), ingest("slack"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), signed("slack")))
+	mux.Handle("/api/discord/webhook", wrap(http.HandlerFunc(h.DiscordWebhookHandler), ingest("discord"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), signed("discord")))
+	// Datadog can't sign its requests: with WEBHOOK_SECRET set, exempt the
+	// endpoint in the signature settings and limit it with its IP allowlist
+	mux.Handle("/api/datadog/webhook", wrap(http.HandlerFunc(h.DatadogWebhookHandler), ingest("datadog"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), signed("datadog")))
+	mux.Handle("/api/zabbix/webhook", wrap(http.HandlerFunc(h.ZabbixWebhookHandler), ingest("zabbix"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), signed("zabbix")))
+	mux.Handle("/api/icinga/webhook", wrap(http.HandlerFunc(h.IcingaWebhookHandler), ingest("icinga"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), signed("icinga")))
+	mux.Handle("/api/uptimekuma/webhook", wrap(http.HandlerFunc(h.UptimeKumaWebhookHandler), ingest("uptimekuma"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore), signed("uptimekuma")))
+	// GitHub signs deliveries itself (X-Hub-Signature-256 with GITHUB_WEBHOOK_SECRET)
+	mux.Handle("/api/github/webhook", wrap(http.HandlerFunc(h.GitHubWebhookHandler), ingest("github"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore)))
+	// GitLab sends a static secret token (X-Gitlab-Token, GITLAB_WEBHOOK_TOKEN)
+	mux.Handle("/api/gitlab/webhook", wrap(http.HandlerFunc(h.GitLabWebhookHandler), ingest("gitlab"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore)))
+	// PagerDuty V3 webhooks acknowledge and resolve the alerts behind incidents
+	mux.Handle("/api/pagerduty/webhook", wrap(http.HandlerFunc(h.PagerDutyWebhookHandler), ingest("pagerduty"), rateLimitMiddleware(rl), idempotencyMiddleware(idStore)))
+	// Alerts forwarded by other Sentinel instances (signed with FEDERATION_SECRET)
+	mux.Handle(federation.IngestPath, wrap(http.HandlerFunc(h.FederationIngestHandler), ingest("federation"), rateLimitMiddleware(rl), hmacMiddleware(staticSecret(os.Getenv("FEDERATION_SECRET")), replay)))
+	// Tracker webhooks are authenticated by the per-connector webhook secret
+	mux.Handle("/api/tickets/", wrap(http.HandlerFunc(h.TicketWebhookHandler), ingest("tickets"), rateLimitMiddleware(rl)))
+	// Pub/Sub push is authenticated by the Google-signed OIDC token; without
+	// GCP_PUBSUB_AUDIENCE the endpoint answers 503
+	mux.Handle("/api/gcp/pubsub", wrap(http.HandlerFunc(h.PubSubHandler), ingest("gcp_pubsub"), rateLimitMiddleware(rl)))
 
 	// Swagger UI
 	mux.HandleFunc("/swagger/", func(w http.ResponseWriter, r *http.Request) {
@@ -470,15 +1476,234 @@ func main() {
 			http.Error(w, "redis not ready", http.StatusServiceUnavailable)
 			return
 		}
-		if err := adminStore.Ping(context.Background()); err != nil {
-			http.Error(w, "db not ready", http.StatusServiceUnavailable)
+		// Alerts are served from Redis alone, so a database outage degrades
+		// the instance rather than taking it out of rotation
+		w.WriteHeader(http.StatusOK)
+		if !adminStore.Available() {
+			w.Write([]byte("ready (degraded: db unavailable)"))
 			return
 		}
-		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ready"))
 	})
 	mux.Handle("/metrics", promhttp.Handler())
 
+	// Short /a/{code} alert links for SMS and Telegram messages
+	links := shortlink.New(redisStore, os.Getenv("SENTINEL_PUBLIC_URL"))
+
+	// Forward matching alerts to other Sentinel instances
+	fedTargets, err := federation.LoadTargets()
+	if err != nil {
+		log.Fatalf("Failed to load federation targets: %v", err)
+	}
+	if len(fedTargets) > 0 && !sandboxed {
+		forwarder := federation.NewForwarder(federation.InstanceID(), fedTargets)
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			forwarder.Run(context.Background(), pubsub.Channel())
+		}()
+	}
+
+	// Flag alerts that start shortly after a deploy of the same service
+	deployWindow, err := deploys.WindowFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if deployWindow > 0 {
+		correlator := deploys.NewCorrelator(redisStore, deployWindow)
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			correlator.Run(context.Background(), pubsub.Channel())
+		}()
+	}
+
+	// Attach a snapshot of registered dependencies' health to new alerts
+	prober := depcheck.NewProber(redisStore, adminStore)
+	go func() {
+		pubsub := redisStore.Subscribe(context.Background())
+		defer pubsub.Close()
+		prober.Run(context.Background(), pubsub.Channel())
+	}()
+
+	// Post alert lifecycle events to Microsoft Teams channels
+	teamsTargets, err := teams.LoadTargets()
+	if err != nil {
+		log.Fatalf("Failed to load Teams targets: %v", err)
+	}
+	if len(teamsTargets) > 0 {
+		notifier := teams.NewNotifier(teamsTargets, os.Getenv("SENTINEL_PUBLIC_URL"))
+		notifier.Queue(notifyQueue)
+		notifier.Throttle(throttle)
+		if sandboxed {
+			notifier.Sandbox(sandboxCfg.TeamsWebhookURL)
+		}
+		h.Channels["teams"] = notifier
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			notifier.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+	}
+
+	// Forward alert lifecycle events to Telegram chats through the Bot API
+	if botToken := os.Getenv("TELEGRAM_BOT_TOKEN"); botToken != "" {
+		telegramTargets, err := telegram.LoadTargets()
+		if err != nil {
+			log.Fatalf("Failed to load Telegram targets: %v", err)
+		}
+		forwarder := telegram.NewForwarder(botToken, telegramTargets, links)
+		forwarder.Queue(notifyQueue)
+		forwarder.Throttle(throttle)
+		if sandboxed {
+			forwarder.Sandbox(sandboxCfg.TelegramChatID)
+		}
+		h.Channels["telegram"] = forwarder
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			forwarder.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+	}
+
+	// Trigger, acknowledge and resolve PagerDuty incidents with the alerts
+	pdTargets, err := pagerduty.LoadTargets()
+	if err != nil {
+		log.Printf("PagerDuty forwarding disabled: %v", err)
+	} else if len(pdTargets) > 0 && !sandboxed {
+		forwarder := pagerduty.NewForwarder(pdTargets, os.Getenv("SENTINEL_PUBLIC_URL"))
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			forwarder.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+	}
+
+	// Create and close Opsgenie alerts by the admin-defined rules
+	if !sandboxed {
+		opsgenieForwarder := opsgenie.NewForwarder(adminStore, os.Getenv("SENTINEL_PUBLIC_URL"))
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			opsgenieForwarder.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+	}
+
+	// POST alert events to the admin-defined outgoing webhooks
+	webhookDispatcher := webhooks.NewDispatcher(adminStore, os.Getenv("SENTINEL_PUBLIC_URL"))
+	webhookDispatcher.Throttle(throttle)
+	if sandboxed {
+		webhookDispatcher.Sandbox(sandboxCfg.WebhookURL)
+	}
+	h.Channels["webhook"] = webhookDispatcher
+	go func() {
+		pubsub := redisStore.Subscribe(context.Background())
+		defer pubsub.Close()
+		webhookDispatcher.Run(context.Background(), unsilenced(pubsub.Channel()))
+	}()
+
+	// Mail alerts to the recipients of matching email routes
+	if h.Email != nil {
+		emailNotifier := email.NewNotifier(adminStore, h.Email, os.Getenv("SENTINEL_PUBLIC_URL"))
+		emailNotifier.Templates(h.Templates)
+		emailNotifier.Throttle(throttle)
+		h.Channels["email-route"] = emailNotifier
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			emailNotifier.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+		// Mail the next responder of a route's schedule while alerts go unacknowledged
+		go emailNotifier.RunEscalations(context.Background(), redisStore)
+	}
+
+	// Page opted-in users by SMS for critical alerts
+	if h.SMS != nil {
+		smsNotifier := sms.NewNotifier(redisStore, adminStore, h.SMS, sms.LimitsFromEnv(), links)
+		smsNotifier.Queue(notifyQueue)
+		smsNotifier.Templates(h.Templates)
+		smsNotifier.Throttle(throttle)
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			smsNotifier.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+	}
+
+	// Keep stakeholder lists posted on high-severity incidents
+	stakeholderNotifier := stakeholders.NewNotifier(adminStore, h.Email, os.Getenv("SENTINEL_PUBLIC_URL"))
+	stakeholderNotifier.Queue(notifyQueue)
+	stakeholderNotifier.Templates(h.Templates)
+	stakeholderNotifier.Throttle(throttle)
+	if sandboxed {
+		stakeholderNotifier.Sandbox(sandboxCfg.SlackWebhookURL)
+	}
+	h.Channels["stakeholders"] = stakeholderNotifier
+	go func() {
+		pubsub := redisStore.Subscribe(context.Background())
+		defer pubsub.Close()
+		stakeholderNotifier.Run(context.Background(), unsilenced(pubsub.Channel()))
+	}()
+
+	// Open/close/comment external tickets on alert lifecycle events
+	if !sandboxed {
+		go func() {
+			pubsub := redisStore.Subscribe(context.Background())
+			defer pubsub.Close()
+			h.Tickets.Run(context.Background(), unsilenced(pubsub.Channel()))
+		}()
+	}
+
+	// Optional Kubernetes event watcher
+	if os.Getenv("K8S_WATCH_ENABLED") == "true" {
+		watcher, err := k8swatch.NewWatcher(redisStore, k8swatch.ConfigFromEnv())
+		if err != nil {
+			log.Printf("Kubernetes watcher disabled: %v", err)
+		} else {
+			go watcher.Run(context.Background())
+		}
+	}
+
+	// Optional syslog receiver for network gear and legacy systems
+	if os.Getenv("SYSLOG_ENABLED") == "true" {
+		cfg, err := syslog.ConfigFromEnv()
+		if err != nil {
+			log.Fatalf("Invalid syslog config: %v", err)
+		}
+		go func() {
+			if err := syslog.NewListener(redisStore, cfg).Run(context.Background()); err != nil {
+				log.Printf("Syslog listener stopped: %v", err)
+			}
+		}()
+	}
+
+	// Optional MQTT ingestion for IoT/edge deployments
+	if mqttCfg, ok, err := handlers.MQTTConfigFromEnv(); err != nil {
+		log.Fatalf("Invalid MQTT config: %v", err)
+	} else if ok {
+		go h.RunMQTT(context.Background(), mqttCfg)
+	}
+
+	// Optional Kafka consumer group for high-volume pipelines
+	if kafkaCfg, ok := handlers.KafkaConfigFromEnv(); ok {
+		go h.RunKafka(context.Background(), kafkaCfg)
+	}
+
+	// Deliver queued notifications, retrying failures
+	go notifyQueue.Run(context.Background())
+
+	// Warn before unresolved alerts expire; keep open criticals around longer
+	go h.RunExpiryWarnings(context.Background())
+
+	// Nudge owners and chats about alerts left open too long
+	go h.RunAgingNudges(context.Background())
+
+	// Alert on heartbeat monitors that missed their check-in
+	go h.RunHeartbeats(context.Background())
+
+	// Report to the outgoing and incoming on-call when a rotation hands off
+	go h.RunHandoverReports(context.Background())
+
 	// Start background listener for push notifications
 	go func() {
 		pubsub := redisStore.Subscribe(context.Background())
@@ -486,12 +1711,23 @@ func main() {
 		ch := pubsub.Channel()
 
 		for msg := range ch {
+			if msg.Channel != store.AlertEventsChannel {
+				continue // Silent updates (e.g. reactions) don't notify
+			}
 			var alert models.Alert
-			if err := json.Unmarshal([]byte(msg.Payload), &alert); err == nil {
-				h.SendPushNotification(fmt.Sprintf("🚨 %s: %s", alert.Title, alert.Message))
-			} else {
-				h.SendPushNotification("New Incident Alert Received!")
+			if err := json.Unmarshal([]byte(msg.Payload), &alert); err != nil {
+				continue // Without its chat there's no telling who may see it
+			}
+			if alert.SilenceID != 0 || !throttle.Allow(context.Background(), floodcontrol.ChannelPush, alert) {
+				continue
 			}
+			if alert.Status == models.AlertStatusResolved {
+				d := msgtemplate.Data{Alert: alert, Headline: "Resolved"}
+				h.SendPushNotification(alert, h.Templates.Message(context.Background(), models.ChannelPush, d, fmt.Sprintf("✅ Resolved: %s", alert.Title)))
+				continue
+			}
+			d := msgtemplate.Data{Alert: alert, Headline: "Incident opened"}
+			h.SendPushNotification(alert, h.Templates.Message(context.Background(), models.ChannelPush, d, fmt.Sprintf("🚨 %s: %s", alert.Title, alert.Message)))
 		}
 	}()
 
@@ -504,29 +1740,42 @@ func main() {
 		port = "8080"
 	}
 
-	rootHandler := wrap(mux, tracingMiddleware, metricsMiddleware)
+	rootHandler := wrap(mux, tracingMiddleware(h.Allowlist), metricsMiddleware, databaseMiddleware(adminStore), h.JWTMiddleware, h.APIKeyMiddleware, handlers.CSRFMiddleware)
+
+	// Preflight: log configuration problems before serving traffic
+	doctor.Log(doctor.Run(ctx, doctor.Deps{Redis: redisStore, Database: adminStore}))
 
 	log.Println("Listening on :" + port)
-	log.Println("Default admin: admin / admin123")
 	log.Println("Admin dashboard: http://localhost:" + port + "/admin/login")
 	if err := http.ListenAndServe(":"+port, rootHandler); err != nil {
 		log.Fatal(err)
 	}
 }
 
-// seedAdmin creates a default admin user if one doesn't exist
-func seedAdmin(ctx context.Context, s store.AdminStore) error {
-	// Check if admin exists
-	_, err := s.GetUserByUsername(ctx, "admin")
-	if err == nil {
-		return nil // Admin already exists
+// loadLevelAliases registers custom level aliases from LEVEL_ALIASES_FILE
+// (JSON object of alias -> level) and LEVEL_ALIASES ("alias=level,...")
+func loadLevelAliases() error {
+	if path := os.Getenv("LEVEL_ALIASES_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var aliases map[string]string
+		if err := json.Unmarshal(data, &aliases); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := models.AddLevelAliases(aliases); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
 	}
-
-	log.Println("Seeding default admin user...")
-	_, err = s.CreateUser(ctx, "admin", "admin123", "admin")
-	if err != nil {
-		return err
+	if v := os.Getenv("LEVEL_ALIASES"); v != "" {
+		aliases, err := models.ParseLevelAliases(v)
+		if err != nil {
+			return err
+		}
+		if err := models.AddLevelAliases(aliases); err != nil {
+			return err
+		}
 	}
-	log.Println("Default admin user created: admin / admin123")
 	return nil
 }